@@ -3,11 +3,16 @@ package caplets
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/evilsocket/islazy/fs"
 )
 
+var (
+	reParamName = regexp.MustCompile(`^[a-zA-Z0-9_\.\-]+$`)
+)
+
 type Caplet struct {
 	Name string   `json:"name"`
 	Path string   `json:"path"`
@@ -24,24 +29,76 @@ func NewCaplet(name string, path string, size int64) Caplet {
 	}
 }
 
-func (cap *Caplet) Eval(argv []string, lineCb func(line string) error) error {
-	if argv == nil {
-		argv = []string{}
+// ParseParams parses the NAME=VALUE list of an include directive, values
+// containing spaces can be quoted.
+func ParseParams(s string) (map[string]string, error) {
+	params := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("expected NAME=VALUE, got '%s'", s)
+		}
+
+		name := s[:eq]
+		if !reParamName.MatchString(name) {
+			return nil, fmt.Errorf("invalid parameter name '%s'", name)
+		}
+
+		value := ""
+		s = s[eq+1:]
+		if s != "" && (s[0] == '"' || s[0] == '\'') {
+			end := strings.IndexByte(s[1:], s[0])
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated value of parameter %s", name)
+			}
+			value = s[1 : end+1]
+			s = s[end+2:]
+		} else if sp := strings.IndexAny(s, " \t"); sp != -1 {
+			value = s[:sp]
+			s = s[sp:]
+		} else {
+			value = s
+			s = ""
+		}
+
+		params[name] = value
 	}
+	return params, nil
+}
+
+func (cap *Caplet) Eval(argv []string, lineCb func(line string) error) error {
+	return cap.EvalWith(argv, nil, lineCb)
+}
+
+// EvalWith evaluates the caplet replacing $0, $1 ... with the elements of
+// argv and every {{NAME}} with the value of the NAME parameter.
+func (cap *Caplet) EvalWith(argv []string, params map[string]string, lineCb func(line string) error) error {
 	// the caplet might include other files (include directive, proxy modules, etc),
 	// temporarily change the working directory
 	return fs.Chdir(filepath.Dir(cap.Path), func() error {
-		for _, line := range cap.Code {
-			// replace $0 with argv[0], $1 with argv[1] and so on
-			for i, arg := range argv {
-				what := fmt.Sprintf("$%d", i)
-				line = strings.Replace(line, what, arg, -1)
-			}
+		return cap.EvalHere(argv, params, lineCb)
+	})
+}
 
-			if err := lineCb(line); err != nil {
-				return err
-			}
+// EvalHere is like EvalWith but doesn't change the working directory, it's
+// used for caplets included by another caplet while it's being evaluated.
+func (cap *Caplet) EvalHere(argv []string, params map[string]string, lineCb func(line string) error) error {
+	if argv == nil {
+		argv = []string{}
+	}
+	for _, line := range cap.Code {
+		// replace $0 with argv[0], $1 with argv[1] and so on
+		for i, arg := range argv {
+			what := fmt.Sprintf("$%d", i)
+			line = strings.Replace(line, what, arg, -1)
 		}
-		return nil
-	})
+		for name, value := range params {
+			line = strings.Replace(line, "{{"+name+"}}", value, -1)
+		}
+
+		if err := lineCb(line); err != nil {
+			return err
+		}
+	}
+	return nil
 }
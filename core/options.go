@@ -16,6 +16,8 @@ type Options struct {
 	Commands      *string
 	CpuProfile    *string
 	MemProfile    *string
+	Journal       *string
+	PcapInject    *bool
 }
 
 func ParseOptions() (Options, error) {
@@ -33,6 +35,8 @@ func ParseOptions() (Options, error) {
 		Commands:      flag.String("eval", "", "Run one or more commands separated by ; in the interactive session, used to set variables via command line."),
 		CpuProfile:    flag.String("cpu-profile", "", "Write cpu profile `file`."),
 		MemProfile:    flag.String("mem-profile", "", "Write memory profile to `file`."),
+		PcapInject:    flag.Bool("pcap-inject", false, "Send packets with libpcap instead of native AF_PACKET sockets or BPF devices."),
+		Journal:       flag.String("journal", "~/.bettercap.journal", "Keep track of the changes to the network state in this file in order to revert them if bettercap crashes, set to empty to disable."),
 	}
 
 	flag.Parse()
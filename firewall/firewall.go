@@ -1,8 +1,17 @@
 package firewall
 
+import "errors"
+
+// ErrTTLNotSupported is returned by the firewalls that can't rewrite the TTL
+// of forwarded packets.
+var ErrTTLNotSupported = errors.New("TTL normalization is not supported on this OS")
+
 type FirewallManager interface {
 	IsForwardingEnabled() bool
 	EnableForwarding(enabled bool) error
 	EnableRedirection(r *Redirection, enabled bool) error
+	// EnableTTLNormalization makes the packets forwarded from the interface
+	// keep their TTL (or hop limit), hiding the hop we add while in the middle.
+	EnableTTLNormalization(enabled bool) error
 	Restore()
 }
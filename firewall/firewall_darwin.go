@@ -168,6 +168,14 @@ func (f PfFirewall) EnableRedirection(r *Redirection, enabled bool) error {
 	return nil
 }
 
+// pf can only enforce a minimum TTL, not undo the decrement
+func (f PfFirewall) EnableTTLNormalization(enabled bool) error {
+	if enabled {
+		return ErrTTLNotSupported
+	}
+	return nil
+}
+
 func (f PfFirewall) Restore() {
 	f.EnableForwarding(f.forwarding)
 	if f.enabled {
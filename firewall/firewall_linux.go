@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/bettercap/bettercap/core"
+	"github.com/bettercap/bettercap/journal"
 	"github.com/bettercap/bettercap/network"
 
 	"github.com/evilsocket/islazy/str"
@@ -15,6 +16,7 @@ type LinuxFirewall struct {
 	iface        *network.Endpoint
 	forwarding   bool
 	redirections map[string]*Redirection
+	ttlRules     [][]string
 }
 
 const (
@@ -30,6 +32,28 @@ func Make(iface *network.Endpoint) FirewallManager {
 
 	firewall.forwarding = firewall.IsForwardingEnabled()
 
+	journal.Register("firewall.forwarding", func(args map[string]string) error {
+		// this is the state before the session that changed it, not the current one
+		firewall.forwarding = args["enabled"] == "true"
+		return firewall.EnableForwarding(firewall.forwarding)
+	})
+
+	journal.Register("firewall.redirection", func(args map[string]string) error {
+		r, err := redirectionFromArgs(args)
+		if err != nil {
+			return err
+		}
+		firewall.redirections[r.String()] = r
+		return firewall.EnableRedirection(r, false)
+	})
+
+	journal.Register("firewall.ttl", func(args map[string]string) error {
+		for _, rule := range ttlCommandLines(args["interface"], false) {
+			core.Exec(rule[0], rule[1:])
+		}
+		return nil
+	})
+
 	return firewall
 }
 
@@ -61,7 +85,19 @@ func (f LinuxFirewall) IsForwardingEnabled() bool {
 }
 
 func (f LinuxFirewall) EnableForwarding(enabled bool) error {
-	return f.enableFeature(IPV4ForwardingFile, enabled)
+	if err := f.enableFeature(IPV4ForwardingFile, enabled); err != nil {
+		return err
+	}
+
+	// keep track of the original state until we restore it
+	if enabled == f.forwarding {
+		return journal.Untrack("firewall.forwarding", IPV4ForwardingFile)
+	} else if _, found := journal.Get("firewall.forwarding", IPV4ForwardingFile); !found {
+		return journal.Track("firewall.forwarding", IPV4ForwardingFile, map[string]string{
+			"enabled": fmt.Sprintf("%v", f.forwarding),
+		})
+	}
+	return nil
 }
 
 func (f *LinuxFirewall) getCommandLine(r *Redirection, enabled bool) (cmdLine []string) {
@@ -113,6 +149,8 @@ func (f *LinuxFirewall) EnableRedirection(r *Redirection, enabled bool) error {
 			return err
 		} else if _, err := core.Exec("iptables", cmdLine); err != nil {
 			return err
+		} else if err := journal.Track("firewall.redirection", rkey, r.args()); err != nil {
+			return err
 		}
 	} else {
 		if !found {
@@ -123,12 +161,67 @@ func (f *LinuxFirewall) EnableRedirection(r *Redirection, enabled bool) error {
 
 		if _, err := core.Exec("iptables", cmdLine); err != nil {
 			return err
+		} else if err := journal.Untrack("firewall.redirection", rkey); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// ttlCommandLines returns the mangle rules incrementing the TTL and the hop
+// limit of the packets forwarded from iface, the FORWARD chain is traversed
+// after the kernel decremented them so the packets leave as they arrived.
+func ttlCommandLines(iface string, enabled bool) [][]string {
+	action := "-A"
+	if !enabled {
+		action = "-D"
+	}
+
+	return [][]string{
+		{"iptables", "-t", "mangle", action, "FORWARD", "-i", iface, "-j", "TTL", "--ttl-inc", "1"},
+		{"ip6tables", "-t", "mangle", action, "FORWARD", "-i", iface, "-j", "HL", "--hl-inc", "1"},
+	}
+}
+
+func (f *LinuxFirewall) EnableTTLNormalization(enabled bool) error {
+	iface := f.iface.Name()
+
+	if !enabled {
+		if len(f.ttlRules) == 0 {
+			return nil
+		}
+		for _, rule := range f.ttlRules {
+			core.Exec(rule[0], rule[1:])
+		}
+		f.ttlRules = nil
+		return journal.Untrack("firewall.ttl", iface)
+	} else if len(f.ttlRules) > 0 {
+		return nil
+	}
+
+	// the IPv6 rule is optional, ip6tables or the HL target might be missing
+	var err6 error
+	enable, disable := ttlCommandLines(iface, true), ttlCommandLines(iface, false)
+	for i, rule := range enable {
+		if _, err := core.Exec(rule[0], rule[1:]); err != nil {
+			if i == 0 {
+				return err
+			}
+			err6 = fmt.Errorf("could not normalize the IPv6 hop limit: %v", err)
+		} else {
+			f.ttlRules = append(f.ttlRules, disable[i])
+		}
+	}
+
+	if err := journal.Track("firewall.ttl", iface, map[string]string{
+		"interface": iface,
+	}); err != nil {
+		return err
+	}
+	return err6
+}
+
 func (f LinuxFirewall) Restore() {
 	for _, r := range f.redirections {
 		if err := f.EnableRedirection(r, false); err != nil {
@@ -136,6 +229,10 @@ func (f LinuxFirewall) Restore() {
 		}
 	}
 
+	if err := f.EnableTTLNormalization(false); err != nil {
+		fmt.Printf("%s", err)
+	}
+
 	if err := f.EnableForwarding(f.forwarding); err != nil {
 		fmt.Printf("%s", err)
 	}
@@ -2,12 +2,14 @@ package firewall
 
 import (
 	"fmt"
-	"strings"
+	"regexp"
 
 	"github.com/bettercap/bettercap/core"
 	"github.com/bettercap/bettercap/network"
 )
 
+var forwardingParser = regexp.MustCompile(`(?mi)^\s*Forwarding\s*:\s*enabled`)
+
 type WindowsFirewall struct {
 	iface        *network.Endpoint
 	forwarding   bool
@@ -27,21 +29,30 @@ func Make(iface *network.Endpoint) FirewallManager {
 }
 
 func (f WindowsFirewall) IsForwardingEnabled() bool {
-	if out, err := core.Exec("netsh", []string{"interface", "ipv4", "dump"}); err != nil {
+	if row, err := getIPInterface(f.iface.Index); err == nil {
+		return row.ForwardingEnabled != 0
+	}
+
+	// the IP helper API is not available, check the configuration of our interface only
+	if out, err := core.Exec("netsh", []string{"interface", "ipv4", "show", "interface", fmt.Sprintf("%d", f.iface.Index)}); err != nil {
 		fmt.Printf("%s\n", err)
 		return false
 	} else {
-		return strings.Contains(out, "forwarding=enabled")
+		return forwardingParser.MatchString(out)
 	}
 }
 
 func (f WindowsFirewall) EnableForwarding(enabled bool) error {
+	if err := setIPInterfaceForwarding(f.iface.Index, enabled); err == nil {
+		return nil
+	}
+
 	v := "enabled"
 	if enabled == false {
 		v = "disabled"
 	}
 
-	if _, err := core.Exec("netsh", []string{"interface", "ipv4", "set", "interface", fmt.Sprintf("%d", f.iface.Index), fmt.Sprintf("forwarding=\"%s\"", v)}); err != nil {
+	if _, err := core.Exec("netsh", []string{"interface", "ipv4", "set", "interface", fmt.Sprintf("%d", f.iface.Index), fmt.Sprintf("forwarding=%s", v)}); err != nil {
 		return err
 	}
 
@@ -106,6 +117,13 @@ func (f *WindowsFirewall) EnableRedirection(r *Redirection, enabled bool) error
 	return nil
 }
 
+func (f WindowsFirewall) EnableTTLNormalization(enabled bool) error {
+	if enabled {
+		return ErrTTLNotSupported
+	}
+	return nil
+}
+
 func (f WindowsFirewall) Restore() {
 	for _, r := range f.redirections {
 		if err := f.EnableRedirection(r, false); err != nil {
@@ -0,0 +1,89 @@
+package firewall
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	iphlpapi                = syscall.NewLazyDLL("iphlpapi.dll")
+	procGetIpInterfaceEntry = iphlpapi.NewProc("GetIpInterfaceEntry")
+	procSetIpInterfaceEntry = iphlpapi.NewProc("SetIpInterfaceEntry")
+)
+
+// mibIPInterfaceRow mirrors MIB_IPINTERFACE_ROW from netioapi.h.
+type mibIPInterfaceRow struct {
+	Family                               uint16
+	_                                    [6]byte
+	InterfaceLuid                        uint64
+	InterfaceIndex                       uint32
+	MaxReassemblySize                    uint32
+	InterfaceIdentifier                  uint64
+	MinRouterAdvertisementInterval       uint32
+	MaxRouterAdvertisementInterval       uint32
+	AdvertisingEnabled                   uint8
+	ForwardingEnabled                    uint8
+	WeakHostSend                         uint8
+	WeakHostReceive                      uint8
+	UseAutomaticMetric                   uint8
+	UseNeighborUnreachabilityDetection   uint8
+	ManagedAddressConfigurationSupported uint8
+	OtherStatefulConfigurationSupported  uint8
+	AdvertiseDefaultRoute                uint8
+	_                                    [3]byte
+	RouterDiscoveryBehavior              int32
+	DadTransmits                         uint32
+	BaseReachableTime                    uint32
+	RetransmitTime                       uint32
+	PathMtuDiscoveryTimeout              uint32
+	LinkLocalAddressBehavior             int32
+	LinkLocalAddressTimeout              uint32
+	ZoneIndices                          [16]uint32
+	SitePrefixLength                     uint32
+	Metric                               uint32
+	NlMtu                                uint32
+	Connected                            uint8
+	SupportsWakeUpPatterns               uint8
+	SupportsNeighborDiscovery            uint8
+	SupportsRouterDiscovery              uint8
+	ReachableTime                        uint32
+	TransmitOffload                      uint8
+	ReceiveOffload                       uint8
+	DisableDefaultRoutes                 uint8
+	_                                    [1]byte
+}
+
+func getIPInterface(index int) (*mibIPInterfaceRow, error) {
+	if err := procGetIpInterfaceEntry.Find(); err != nil {
+		return nil, err
+	}
+
+	row := &mibIPInterfaceRow{
+		Family:         syscall.AF_INET,
+		InterfaceIndex: uint32(index),
+	}
+	if ret, _, _ := procGetIpInterfaceEntry.Call(uintptr(unsafe.Pointer(row))); ret != 0 {
+		return nil, fmt.Errorf("GetIpInterfaceEntry(%d) failed: %s", index, syscall.Errno(ret))
+	}
+	return row, nil
+}
+
+func setIPInterfaceForwarding(index int, enabled bool) error {
+	row, err := getIPInterface(index)
+	if err != nil {
+		return err
+	}
+
+	row.ForwardingEnabled = 0
+	if enabled {
+		row.ForwardingEnabled = 1
+	}
+	// must be zero for IPv4 interfaces or the call fails with ERROR_INVALID_PARAMETER
+	row.SitePrefixLength = 0
+
+	if ret, _, _ := procSetIpInterfaceEntry.Call(uintptr(unsafe.Pointer(row))); ret != 0 {
+		return fmt.Errorf("SetIpInterfaceEntry(%d) failed: %s", index, syscall.Errno(ret))
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package firewall
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+)
 
 type Redirection struct {
 	Interface  string
@@ -25,3 +28,30 @@ func NewRedirection(iface string, proto string, port_from int, addr_to string, p
 func (r Redirection) String() string {
 	return fmt.Sprintf("[%s] (%s) %s:%d -> %s:%d", r.Interface, r.Protocol, r.SrcAddress, r.SrcPort, r.DstAddress, r.DstPort)
 }
+
+func (r Redirection) args() map[string]string {
+	return map[string]string{
+		"interface":   r.Interface,
+		"protocol":    r.Protocol,
+		"src_address": r.SrcAddress,
+		"src_port":    strconv.Itoa(r.SrcPort),
+		"dst_address": r.DstAddress,
+		"dst_port":    strconv.Itoa(r.DstPort),
+	}
+}
+
+func redirectionFromArgs(args map[string]string) (*Redirection, error) {
+	srcPort, err := strconv.Atoi(args["src_port"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirection source port '%s'", args["src_port"])
+	}
+
+	dstPort, err := strconv.Atoi(args["dst_port"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirection destination port '%s'", args["dst_port"])
+	}
+
+	r := NewRedirection(args["interface"], args["protocol"], srcPort, args["dst_address"], dstPort)
+	r.SrcAddress = args["src_address"]
+	return r, nil
+}
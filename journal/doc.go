@@ -0,0 +1,2 @@
+// Package journal keeps track of the changes made to the network and system state, persisting them so that they can be reverted even if the process is killed.
+package journal
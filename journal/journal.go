@@ -0,0 +1,210 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// Action is a change to the network or system state that has to be reverted
+// before exiting, Kind selects the restorer and ID identifies the action.
+type Action struct {
+	Kind string            `json:"kind"`
+	ID   string            `json:"id"`
+	Args map[string]string `json:"args"`
+	Time time.Time         `json:"time"`
+}
+
+// Restorer reverts an action given its arguments.
+type Restorer func(args map[string]string) error
+
+var (
+	lock      = sync.Mutex{}
+	fileName  = ""
+	actions   = make([]Action, 0)
+	restorers = make(map[string]Restorer)
+)
+
+// Register sets the function used to revert the actions of a given kind.
+func Register(kind string, restorer Restorer) {
+	lock.Lock()
+	defer lock.Unlock()
+	restorers[kind] = restorer
+}
+
+// Open sets the file the journal is persisted to and loads the actions left
+// there by a previous session, which are returned and kept for Restore.
+func Open(file string) (error, []Action) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	fileName = file
+	if fileName == "" {
+		return nil, nil
+	}
+
+	raw, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return err, nil
+	}
+
+	leftovers := make([]Action, 0)
+	if err := json.Unmarshal(raw, &leftovers); err != nil {
+		return fmt.Errorf("could not parse journal %s: %s", fileName, err), nil
+	}
+
+	// leftovers come before anything tracked so far
+	actions = append(leftovers, actions...)
+	return nil, leftovers
+}
+
+func indexOf(kind, id string) int {
+	for i, a := range actions {
+		if a.Kind == kind && a.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func save() error {
+	if fileName == "" {
+		return nil
+	} else if len(actions) == 0 {
+		if err := os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	raw, err := json.MarshalIndent(actions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// write and rename so that a crash never leaves a truncated journal
+	tmp := fileName + ".tmp"
+	fd, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = fd.Write(raw); err == nil {
+		err = fd.Sync()
+	}
+	fd.Close()
+
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, fileName)
+}
+
+// Track adds an action to the journal, or updates its arguments if already tracked.
+func Track(kind, id string, args map[string]string) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	action := Action{
+		Kind: kind,
+		ID:   id,
+		Args: args,
+		Time: time.Now(),
+	}
+
+	if idx := indexOf(kind, id); idx >= 0 {
+		actions[idx] = action
+	} else {
+		actions = append(actions, action)
+	}
+
+	return save()
+}
+
+// Untrack removes an action from the journal once it has been reverted.
+func Untrack(kind, id string) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if idx := indexOf(kind, id); idx >= 0 {
+		actions = append(actions[:idx], actions[idx+1:]...)
+		return save()
+	}
+	return nil
+}
+
+// Get returns a tracked action.
+func Get(kind, id string) (Action, bool) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if idx := indexOf(kind, id); idx >= 0 {
+		return actions[idx], true
+	}
+	return Action{}, false
+}
+
+// Actions returns the list of tracked actions.
+func Actions() []Action {
+	lock.Lock()
+	defer lock.Unlock()
+	return append([]Action{}, actions...)
+}
+
+// Restore reverts every tracked action, the most recent first, and returns the
+// errors of those which could not be reverted and are kept in the journal.
+func Restore() []error {
+	pending := Actions()
+	errs := make([]error, 0)
+	restored := make([]Action, 0)
+
+	for i := len(pending) - 1; i >= 0; i-- {
+		a := pending[i]
+
+		lock.Lock()
+		restorer, found := restorers[a.Kind]
+		lock.Unlock()
+
+		// restorers are called without holding the lock since they are
+		// likely to untrack the action themselves
+		if !found {
+			errs = append(errs, fmt.Errorf("don't know how to restore %s %s", a.Kind, a.ID))
+		} else if err := restorer(a.Args); err != nil {
+			errs = append(errs, fmt.Errorf("could not restore %s %s: %s", a.Kind, a.ID, err))
+		} else {
+			restored = append(restored, a)
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, a := range restored {
+		// only remove the action if it has not been tracked again meanwhile
+		if idx := indexOf(a.Kind, a.ID); idx >= 0 && actions[idx].Time.Equal(a.Time) {
+			actions = append(actions[:idx], actions[idx+1:]...)
+		}
+	}
+
+	if err := save(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// Reset forgets every tracked action and restorer.
+func Reset() {
+	lock.Lock()
+	defer lock.Unlock()
+
+	fileName = ""
+	actions = make([]Action, 0)
+	restorers = make(map[string]Restorer)
+}
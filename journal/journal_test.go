@@ -0,0 +1,107 @@
+package journal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempJournal(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "bettercap.journal")
+}
+
+func TestJournalTrackUntrack(t *testing.T) {
+	Reset()
+	fileName := tempJournal(t)
+	defer os.RemoveAll(filepath.Dir(fileName))
+
+	if err, leftovers := Open(fileName); err != nil {
+		t.Fatal(err)
+	} else if len(leftovers) != 0 {
+		t.Fatalf("expected no leftovers, got %d", len(leftovers))
+	}
+
+	if err := Track("test", "a", map[string]string{"v": "1"}); err != nil {
+		t.Fatal(err)
+	} else if err := Track("test", "a", map[string]string{"v": "2"}); err != nil {
+		t.Fatal(err)
+	} else if err := Track("test", "b", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if actions := Actions(); len(actions) != 2 {
+		t.Fatalf("expected 2 actions, got %d", len(actions))
+	} else if a, found := Get("test", "a"); !found || a.Args["v"] != "2" {
+		t.Fatalf("unexpected action %+v", a)
+	}
+
+	if err := Untrack("test", "a"); err != nil {
+		t.Fatal(err)
+	} else if err := Untrack("test", "b"); err != nil {
+		t.Fatal(err)
+	} else if _, err := os.Stat(fileName); !os.IsNotExist(err) {
+		t.Fatalf("expected the journal file to be removed once empty")
+	}
+}
+
+func TestJournalLeftovers(t *testing.T) {
+	Reset()
+	fileName := tempJournal(t)
+	defer os.RemoveAll(filepath.Dir(fileName))
+
+	Open(fileName)
+	Track("first", "1", map[string]string{"id": "1"})
+	Track("second", "2", map[string]string{"id": "2"})
+
+	// simulate a crash and a new session
+	Reset()
+	order := []string{}
+	Register("first", func(args map[string]string) error {
+		order = append(order, args["id"])
+		return nil
+	})
+	Register("second", func(args map[string]string) error {
+		order = append(order, args["id"])
+		// restorers usually untrack their own action
+		return Untrack("second", "2")
+	})
+
+	err, leftovers := Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(leftovers) != 2 {
+		t.Fatalf("expected 2 leftovers, got %d", len(leftovers))
+	}
+
+	if errs := Restore(); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	} else if fmt.Sprintf("%v", order) != "[2 1]" {
+		t.Fatalf("expected actions to be restored in reverse order, got %v", order)
+	} else if len(Actions()) != 0 {
+		t.Fatalf("expected no actions left")
+	} else if _, err := os.Stat(fileName); !os.IsNotExist(err) {
+		t.Fatalf("expected the journal file to be removed")
+	}
+}
+
+func TestJournalRestoreFailures(t *testing.T) {
+	Reset()
+	Register("broken", func(args map[string]string) error {
+		return fmt.Errorf("nope")
+	})
+
+	Track("broken", "x", nil)
+	Track("unknown", "y", nil)
+
+	if errs := Restore(); len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	} else if len(Actions()) != 2 {
+		t.Fatalf("expected failed actions to be kept")
+	}
+}
@@ -0,0 +1,2 @@
+// Package loot encrypts the sensitive artifacts saved during a session, like handshakes, cookie jars and reports, with a passphrase or the RSA public key of a recipient.
+package loot
@@ -0,0 +1,365 @@
+package loot
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Extension is added to the name of the encrypted files.
+const Extension = ".enc"
+
+const (
+	modePassphrase = 1
+	modeRecipient  = 2
+
+	keySize    = 32
+	saltSize   = 16
+	iterations = 100000
+)
+
+var (
+	magic = []byte("BCAPLOOT")
+
+	ErrNoKey = errors.New("no passphrase or private key to decrypt the file with")
+
+	lock       = sync.Mutex{}
+	passphrase = ""
+	recipient  = (*rsa.PublicKey)(nil)
+)
+
+// SetPassphrase sets the passphrase to encrypt the artifacts with, an empty
+// one disables the passphrase encryption.
+func SetPassphrase(p string) {
+	lock.Lock()
+	defer lock.Unlock()
+	passphrase = p
+}
+
+// Passphrase returns the passphrase set with SetPassphrase.
+func Passphrase() string {
+	lock.Lock()
+	defer lock.Unlock()
+	return passphrase
+}
+
+// SetRecipient loads the RSA public key, or the certificate, of the recipient
+// the artifacts are encrypted for, this takes precedence over the passphrase
+// and the key to decrypt them is never on this machine. An empty file name
+// removes the recipient.
+func SetRecipient(fileName string) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if fileName == "" {
+		recipient = nil
+		return nil
+	}
+
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return fmt.Errorf("%s is not PEM encoded", fileName)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return err
+		}
+		key = cert.PublicKey
+	case "RSA PUBLIC KEY":
+		if key, err = x509.ParsePKCS1PublicKey(block.Bytes); err != nil {
+			return err
+		}
+	default:
+		if key, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return err
+		}
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%s is not an RSA public key", fileName)
+	}
+	recipient = rsaKey
+	return nil
+}
+
+// LoadPrivateKey loads the RSA private key of a recipient to decrypt files.
+func LoadPrivateKey(fileName string) (*rsa.PrivateKey, error) {
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not PEM encoded", fileName)
+	} else if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	} else if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+		return rsaKey, nil
+	}
+	return nil, fmt.Errorf("%s is not an RSA private key", fileName)
+}
+
+// Enabled returns true if the artifacts have to be encrypted.
+func Enabled() bool {
+	lock.Lock()
+	defer lock.Unlock()
+	return passphrase != "" || recipient != nil
+}
+
+// FileName returns the name an artifact is saved with, the Extension is
+// added when encryption is enabled.
+func FileName(fileName string) string {
+	if Enabled() && !strings.HasSuffix(fileName, Extension) {
+		return fileName + Extension
+	}
+	return fileName
+}
+
+// pbkdf2 derives a key from the passphrase as in RFC 8018 with HMAC-SHA256.
+func pbkdf2(password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	numBlocks := (keyLen + prf.Size() - 1) / prf.Size()
+
+	var buf [4]byte
+	key := make([]byte, 0, numBlocks*prf.Size())
+	u := make([]byte, prf.Size())
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf[:], uint32(block))
+		prf.Write(buf[:])
+		t := prf.Sum(nil)
+		copy(u, t)
+
+		for n := 2; n <= iter; n++ {
+			u = hmacSum(prf, u)
+			for i := range t {
+				t[i] ^= u[i]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+func hmacSum(prf hash.Hash, data []byte) []byte {
+	prf.Reset()
+	prf.Write(data)
+	return prf.Sum(data[:0])
+}
+
+func seal(key, plain []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := gcm.Seal(nil, nonce, plain, nil)
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(sealed)))
+
+	return append(append(nonce, size...), sealed...), nil
+}
+
+// Encrypt returns a record with the data encrypted for the recipient, or with
+// the passphrase. Records can be concatenated and are decrypted in order.
+func Encrypt(data []byte) ([]byte, error) {
+	lock.Lock()
+	pass, rcpt := passphrase, recipient
+	lock.Unlock()
+
+	record := bytes.NewBuffer(nil)
+	record.Write(magic)
+
+	key := make([]byte, keySize)
+	if rcpt != nil {
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+
+		wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, rcpt, key, magic)
+		if err != nil {
+			return nil, err
+		}
+
+		record.WriteByte(modeRecipient)
+		binary.Write(record, binary.BigEndian, uint16(len(wrapped)))
+		record.Write(wrapped)
+	} else if pass != "" {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		key = pbkdf2([]byte(pass), salt, iterations, keySize)
+
+		record.WriteByte(modePassphrase)
+		record.Write(salt)
+		binary.Write(record, binary.BigEndian, uint32(iterations))
+	} else {
+		return nil, fmt.Errorf("no passphrase or recipient to encrypt with")
+	}
+
+	sealed, err := seal(key, data)
+	if err != nil {
+		return nil, err
+	}
+	record.Write(sealed)
+
+	return record.Bytes(), nil
+}
+
+// IsEncrypted returns true if data starts with an encrypted record.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Decrypt decrypts every record of data with the passphrase or the private
+// key of the recipient, depending on how each one was encrypted.
+func Decrypt(data []byte, pass string, key *rsa.PrivateKey) ([]byte, error) {
+	plain := bytes.NewBuffer(nil)
+	for num := 0; len(data) > 0; num++ {
+		if !IsEncrypted(data) || len(data) < len(magic)+1 {
+			return nil, fmt.Errorf("record #%d is not encrypted or is corrupted", num)
+		}
+
+		var recordKey []byte
+		off := len(magic) + 1
+		switch data[len(magic)] {
+		case modeRecipient:
+			if key == nil {
+				return nil, ErrNoKey
+			} else if len(data) < off+2 {
+				return nil, fmt.Errorf("record #%d is truncated", num)
+			}
+			size := int(binary.BigEndian.Uint16(data[off:]))
+			if off += 2; len(data) < off+size {
+				return nil, fmt.Errorf("record #%d is truncated", num)
+			}
+
+			var err error
+			if recordKey, err = rsa.DecryptOAEP(sha256.New(), rand.Reader, key, data[off:off+size], magic); err != nil {
+				return nil, fmt.Errorf("could not decrypt the key of record #%d: %v", num, err)
+			}
+			off += size
+
+		case modePassphrase:
+			if pass == "" {
+				return nil, ErrNoKey
+			} else if len(data) < off+saltSize+4 {
+				return nil, fmt.Errorf("record #%d is truncated", num)
+			}
+			salt := data[off : off+saltSize]
+			iter := int(binary.BigEndian.Uint32(data[off+saltSize:]))
+			recordKey = pbkdf2([]byte(pass), salt, iter, keySize)
+			off += saltSize + 4
+
+		default:
+			return nil, fmt.Errorf("record #%d has an unknown encryption mode %d", num, data[len(magic)])
+		}
+
+		block, err := aes.NewCipher(recordKey)
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(data) < off+gcm.NonceSize()+4 {
+			return nil, fmt.Errorf("record #%d is truncated", num)
+		}
+		nonce := data[off : off+gcm.NonceSize()]
+		off += gcm.NonceSize()
+		size := int(binary.BigEndian.Uint32(data[off:]))
+		if off += 4; len(data) < off+size {
+			return nil, fmt.Errorf("record #%d is truncated", num)
+		}
+
+		opened, err := gcm.Open(nil, nonce, data[off:off+size], nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not decrypt record #%d, wrong passphrase or key?", num)
+		}
+		plain.Write(opened)
+		data = data[off+size:]
+	}
+
+	return plain.Bytes(), nil
+}
+
+// WriteFile saves data to FileName(fileName), encrypted if enabled, and
+// returns the name of the file it was saved to.
+func WriteFile(fileName string, data []byte, perm os.FileMode) (string, error) {
+	if !Enabled() {
+		return fileName, ioutil.WriteFile(fileName, data, perm)
+	}
+
+	fileName = FileName(fileName)
+	record, err := Encrypt(data)
+	if err != nil {
+		return fileName, err
+	}
+	return fileName, ioutil.WriteFile(fileName, record, perm)
+}
+
+// AppendFile appends data to FileName(fileName), as a new record if the
+// encryption is enabled, and returns the name of the file it was saved to.
+func AppendFile(fileName string, data []byte, perm os.FileMode) (string, error) {
+	var err error
+
+	fileName = FileName(fileName)
+	if Enabled() {
+		if data, err = Encrypt(data); err != nil {
+			return fileName, err
+		}
+	}
+
+	fp, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+	if err != nil {
+		return fileName, err
+	}
+	defer fp.Close()
+
+	_, err = fp.Write(data)
+	return fileName, err
+}
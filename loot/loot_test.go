@@ -0,0 +1,167 @@
+package loot
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func reset() {
+	SetPassphrase("")
+	SetRecipient("")
+}
+
+func TestPBKDF2(t *testing.T) {
+	vectors := []struct {
+		iter int
+		want string
+	}{
+		{1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+	}
+	for _, v := range vectors {
+		got := hex.EncodeToString(pbkdf2([]byte("password"), []byte("salt"), v.iter, 32))
+		if got != v.want {
+			t.Fatalf("c=%d: expected %s, got %s", v.iter, v.want, got)
+		}
+	}
+}
+
+func TestFileName(t *testing.T) {
+	defer reset()
+
+	if got := FileName("file.json"); got != "file.json" {
+		t.Fatalf("expected file.json, got %s", got)
+	}
+
+	SetPassphrase("secret")
+	if got := FileName("file.json"); got != "file.json"+Extension {
+		t.Fatalf("expected file.json%s, got %s", Extension, got)
+	}
+	if got := FileName("file.json" + Extension); got != "file.json"+Extension {
+		t.Fatalf("expected file.json%s, got %s", Extension, got)
+	}
+}
+
+func TestPassphraseAppend(t *testing.T) {
+	defer reset()
+
+	dir, err := ioutil.TempDir("", "loot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	SetPassphrase("secret")
+	fileName := filepath.Join(dir, "shakes.pcap")
+	for _, chunk := range []string{"first", "second"} {
+		if saved, err := AppendFile(fileName, []byte(chunk), 0600); err != nil {
+			t.Fatal(err)
+		} else if saved != fileName+Extension {
+			t.Fatalf("expected %s, got %s", fileName+Extension, saved)
+		}
+	}
+
+	raw, err := ioutil.ReadFile(fileName + Extension)
+	if err != nil {
+		t.Fatal(err)
+	} else if !IsEncrypted(raw) {
+		t.Fatal("expected the file to be encrypted")
+	} else if bytes.Contains(raw, []byte("first")) {
+		t.Fatal("plaintext found in the encrypted file")
+	}
+
+	plain, err := Decrypt(raw, "secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(plain) != "firstsecond" {
+		t.Fatalf("expected 'firstsecond', got '%s'", plain)
+	}
+
+	if _, err = Decrypt(raw, "wrong", nil); err == nil {
+		t.Fatal("expected an error with the wrong passphrase")
+	} else if _, err = Decrypt(raw, "", nil); err != ErrNoKey {
+		t.Fatalf("expected ErrNoKey, got %v", err)
+	}
+}
+
+func TestRecipient(t *testing.T) {
+	defer reset()
+
+	dir, err := ioutil.TempDir("", "loot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubFile := filepath.Join(dir, "pub.pem")
+	if err = ioutil.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "key.pem")
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = SetRecipient(pubFile); err != nil {
+		t.Fatal(err)
+	}
+	// the recipient takes precedence over the passphrase
+	SetPassphrase("secret")
+
+	fileName, err := WriteFile(filepath.Join(dir, "report.html"), []byte("<html></html>"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err = Decrypt(raw, "secret", nil); err != ErrNoKey {
+		t.Fatalf("expected ErrNoKey, got %v", err)
+	}
+
+	priv, err := LoadPrivateKey(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := Decrypt(raw, "", priv)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(plain) != "<html></html>" {
+		t.Fatalf("unexpected plaintext '%s'", plain)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	dir, err := ioutil.TempDir("", "loot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileName, err := WriteFile(filepath.Join(dir, "cookies.json"), []byte("[]"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	} else if filepath.Base(fileName) != "cookies.json" {
+		t.Fatalf("expected cookies.json, got %s", fileName)
+	} else if raw, _ := ioutil.ReadFile(fileName); string(raw) != "[]" {
+		t.Fatalf("expected plaintext, got '%s'", raw)
+	}
+}
@@ -82,6 +82,7 @@ func main() {
 			if err == io.EOF || err.Error() == "Interrupt" {
 				if exitPrompt() {
 					sess.Run("exit")
+					sess.Close()
 					os.Exit(0)
 				}
 				continue
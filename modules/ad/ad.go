@@ -0,0 +1,167 @@
+package ad
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+type ADModule struct {
+	session.SessionModule
+}
+
+func NewADModule(s *session.Session) *ADModule {
+	mod := &ADModule{
+		SessionModule: session.NewSessionModule("ad", s),
+	}
+
+	mod.AddHandler(session.NewModuleHandler("ad.show", "",
+		"Show the Active Directory domains and domain controllers seen by net.sniff.",
+		func(args []string) error {
+			return mod.Show()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("ad.show DOMAIN", `ad\.show\s+([^\s]+)`,
+		"Show the domain controllers and the computer accounts of the domain with the given DNS or NetBIOS name.",
+		func(args []string) error {
+			return mod.ShowDomain(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("ad.clear", "",
+		"Clear the Active Directory inventory.",
+		func(args []string) error {
+			mod.Session.AD.Clear()
+			return nil
+		}))
+
+	return mod
+}
+
+func (mod *ADModule) Name() string {
+	return "ad"
+}
+
+func (mod *ADModule) Description() string {
+	return "Passive Active Directory inventory of the domains, domain controllers and computer accounts seen in the Kerberos, LDAP, DNS and NetBIOS traffic."
+}
+
+func (mod *ADModule) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *ADModule) Configure() error {
+	return nil
+}
+
+func (mod *ADModule) Stop() error {
+	return nil
+}
+
+func (mod *ADModule) Start() error {
+	return nil
+}
+
+func controllerRow(dc *network.ADController) []string {
+	address := dc.Address
+	if address == "" {
+		address = tui.Dim("?")
+	}
+
+	role := tui.Dim("dc")
+	if dc.PDC {
+		role = tui.Red("pdc")
+	}
+
+	return []string{
+		address,
+		tui.Bold(dc.Hostname),
+		role,
+		dc.Site,
+		tui.Dim(strings.Join(dc.Sources, ", ")),
+		dc.LastSeen.Format("15:04:05"),
+	}
+}
+
+func domainTitle(d *network.ADDomain) string {
+	title := tui.Yellow(d.ID())
+	if d.Name != "" && d.NetBIOS != "" {
+		title += fmt.Sprintf(" (%s)", tui.Bold(d.NetBIOS))
+	}
+	if d.Forest != "" && d.Forest != d.Name {
+		title += tui.Dim(" forest ") + d.Forest
+	}
+	return title
+}
+
+func (mod *ADModule) Show() error {
+	domains := mod.Session.AD.Domains()
+	if len(domains) == 0 {
+		return fmt.Errorf("no Active Directory domains seen yet, start net.sniff")
+	}
+
+	rows := make([][]string, 0)
+	for _, d := range domains {
+		dcs := make([]string, 0)
+		for _, dc := range d.Controllers {
+			if dc.Hostname != "" {
+				dcs = append(dcs, dc.Hostname)
+			} else {
+				dcs = append(dcs, dc.Address)
+			}
+		}
+
+		rows = append(rows, []string{
+			tui.Yellow(d.Name),
+			tui.Bold(d.NetBIOS),
+			d.Forest,
+			strings.Join(dcs, ", "),
+			fmt.Sprintf("%d", len(d.Machines)),
+			d.LastSeen.Format("15:04:05"),
+		})
+	}
+
+	mod.Session.Println()
+	mod.Session.Table([]string{"Domain", "NetBIOS", "Forest", "Domain Controllers", "Computers", "Seen"}, rows)
+	mod.Session.Println()
+
+	return nil
+}
+
+func (mod *ADModule) ShowDomain(name string) error {
+	d, found := mod.Session.AD.Get(name)
+	if !found {
+		return fmt.Errorf("domain %s not seen yet", name)
+	}
+
+	mod.Session.Println()
+	mod.Session.Printf("%s\n\n", domainTitle(&d))
+
+	if len(d.Controllers) > 0 {
+		rows := make([][]string, 0)
+		for _, dc := range d.Controllers {
+			rows = append(rows, controllerRow(dc))
+		}
+		mod.Session.Table([]string{"Address", "Hostname", "Role", "Site", "Sources", "Seen"}, rows)
+		mod.Session.Println()
+	}
+
+	if len(d.Machines) > 0 {
+		rows := make([][]string, 0)
+		for _, m := range d.Machines {
+			rows = append(rows, []string{
+				tui.Bold(m.Name),
+				m.Address,
+				m.FirstSeen.Format("15:04:05"),
+				m.LastSeen.Format("15:04:05"),
+			})
+		}
+		mod.Session.Table([]string{"Computer", "Address", "First Seen", "Last Seen"}, rows)
+		mod.Session.Println()
+	}
+
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/bettercap/bettercap/session"
@@ -13,6 +15,7 @@ import (
 	"github.com/gorilla/websocket"
 
 	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
 )
 
 type RestAPI struct {
@@ -20,12 +23,24 @@ type RestAPI struct {
 	server       *http.Server
 	username     string
 	password     string
+	tokens       []apiToken
 	certFile     string
 	keyFile      string
 	allowOrigin  string
 	useWebsocket bool
+	readOnly     bool
+	compress     bool
+	pipeLock     sync.Mutex
 	upgrader     websocket.Upgrader
 	quit         chan bool
+	streams      chan struct{}
+
+	confirmLevel   int
+	confirmToken   string
+	confirmTimeout time.Duration
+	pending        map[int]*PendingCommands
+	pendingID      int
+	pendingLock    sync.Mutex
 }
 
 func NewRestAPI(s *session.Session) *RestAPI {
@@ -33,8 +48,11 @@ func NewRestAPI(s *session.Session) *RestAPI {
 		SessionModule: session.NewSessionModule("api.rest", s),
 		server:        &http.Server{},
 		quit:          make(chan bool),
+		streams:       make(chan struct{}),
 		useWebsocket:  false,
 		allowOrigin:   "*",
+		compress:      true,
+		pending:       make(map[int]*PendingCommands),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
@@ -65,12 +83,18 @@ func NewRestAPI(s *session.Session) *RestAPI {
 		"",
 		"API authentication password."))
 
+	mod.AddParam(session.NewStringParameter("api.rest.tokens",
+		"",
+		"",
+		"Comma separated list of NAME:TOKEN:SCOPES API tokens, sent by clients in the Authorization: Bearer header (or the token parameter for websockets), with the scopes separated by + among read (GET routes), commands (running commands and changing the session), files (the /api/file route) and all, read if omitted."))
+
 	mod.AddParam(session.NewStringParameter("api.rest.certificate",
 		"",
 		"",
 		"API TLS certificate."))
 
 	tls.CertConfigToModule("api.rest", &mod.SessionModule, tls.DefaultLegitConfig)
+	tls.SignedCertConfigToModule("api.rest", &mod.SessionModule)
 
 	mod.AddParam(session.NewStringParameter("api.rest.key",
 		"",
@@ -81,6 +105,28 @@ func NewRestAPI(s *session.Session) *RestAPI {
 		"false",
 		"If true the /api/events route will be available as a websocket endpoint instead of HTTPS."))
 
+	mod.AddParam(session.NewBoolParameter("api.rest.readonly",
+		"false",
+		"If true, only GET routes will be available: commands can't be executed, events can't be cleared and the /api/file route is disabled."))
+
+	mod.AddParam(session.NewBoolParameter("api.rest.compress",
+		"true",
+		"If true, /api/session responses will be gzip or deflate compressed when the client supports it."))
+
+	mod.AddParam(session.NewStringParameter("api.rest.confirm.level",
+		"off",
+		"^(off|probe|spoof|inject)$",
+		"Commands sent to the API with this risk level or higher are held until confirmed from the console or with api.rest.confirm.token: off, probe (active scans), spoof (spoofing and proxies) or inject (injection, denial of service, caplets and shell commands)."))
+
+	mod.AddParam(session.NewStringParameter("api.rest.confirm.token",
+		"",
+		"",
+		"Token a second operator can confirm or deny the held commands with by sending it in the X-Confirm-Token header to /api/confirm/ID, if empty they can only be confirmed from the console."))
+
+	mod.AddParam(session.NewIntParameter("api.rest.confirm.timeout",
+		"300",
+		"Seconds after which the held commands that were not confirmed are discarded."))
+
 	mod.AddHandler(session.NewModuleHandler("api.rest on", "",
 		"Start REST API server.",
 		func(args []string) error {
@@ -93,6 +139,35 @@ func NewRestAPI(s *session.Session) *RestAPI {
 			return mod.Stop()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("api.rest.pending", "",
+		"Show the API commands waiting for confirmation.",
+		func(args []string) error {
+			return mod.showPending()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("api.rest.confirm ID", `api\.rest\.confirm (\d+)`,
+		"Run the API commands waiting for confirmation with the given id.",
+		func(args []string) error {
+			id, _ := strconv.Atoi(args[0])
+			resp, err := mod.confirmPending(id, session.OriginConsole)
+			if err != nil {
+				return err
+			}
+			for _, res := range resp.Results {
+				if res.Error != "" {
+					return fmt.Errorf("%s: %s", res.Command, res.Error)
+				}
+			}
+			return nil
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("api.rest.deny ID", `api\.rest\.deny (\d+)`,
+		"Discard the API commands waiting for confirmation with the given id.",
+		func(args []string) error {
+			id, _ := strconv.Atoi(args[0])
+			return mod.denyPending(id, session.OriginConsole)
+		}))
+
 	return mod
 }
 
@@ -124,6 +199,9 @@ func (mod *RestAPI) Configure() error {
 	var err error
 	var ip string
 	var port int
+	var level string
+	var tokens string
+	var timeout int
 
 	if mod.Running() {
 		return session.ErrAlreadyStarted
@@ -145,8 +223,31 @@ func (mod *RestAPI) Configure() error {
 		return err
 	} else if err, mod.password = mod.StringParam("api.rest.password"); err != nil {
 		return err
+	} else if err, tokens = mod.StringParam("api.rest.tokens"); err != nil {
+		return err
+	} else if mod.tokens, err = parseTokens(tokens); err != nil {
+		return err
 	} else if err, mod.useWebsocket = mod.BoolParam("api.rest.websocket"); err != nil {
 		return err
+	} else if err, mod.readOnly = mod.BoolParam("api.rest.readonly"); err != nil {
+		return err
+	} else if err, mod.compress = mod.BoolParam("api.rest.compress"); err != nil {
+		return err
+	} else if err, level = mod.StringParam("api.rest.confirm.level"); err != nil {
+		return err
+	} else if err, mod.confirmToken = mod.StringParam("api.rest.confirm.token"); err != nil {
+		return err
+	} else if err, timeout = mod.IntParam("api.rest.confirm.timeout"); err != nil {
+		return err
+	} else if timeout <= 0 {
+		return fmt.Errorf("api.rest.confirm.timeout must be greater than 0")
+	}
+
+	mod.confirmTimeout = time.Duration(timeout) * time.Second
+	for risk, name := range riskNames {
+		if name == level {
+			mod.confirmLevel = risk
+		}
 	}
 
 	if mod.isTLS() {
@@ -169,34 +270,28 @@ func (mod *RestAPI) Configure() error {
 	}
 
 	mod.server.Addr = fmt.Sprintf("%s:%d", ip, port)
+	mod.streams = make(chan struct{})
 
 	router := mux.NewRouter()
 
 	router.Methods("OPTIONS").HandlerFunc(mod.corsRoute)
 
-	router.HandleFunc("/api/events", mod.eventsRoute)
-	router.HandleFunc("/api/session", mod.sessionRoute)
-	router.HandleFunc("/api/session/ble", mod.sessionRoute)
-	router.HandleFunc("/api/session/ble/{mac}", mod.sessionRoute)
-	router.HandleFunc("/api/session/hid", mod.sessionRoute)
-	router.HandleFunc("/api/session/hid/{mac}", mod.sessionRoute)
-	router.HandleFunc("/api/session/env", mod.sessionRoute)
-	router.HandleFunc("/api/session/gateway", mod.sessionRoute)
-	router.HandleFunc("/api/session/interface", mod.sessionRoute)
-	router.HandleFunc("/api/session/modules", mod.sessionRoute)
-	router.HandleFunc("/api/session/lan", mod.sessionRoute)
-	router.HandleFunc("/api/session/lan/{mac}", mod.sessionRoute)
-	router.HandleFunc("/api/session/options", mod.sessionRoute)
-	router.HandleFunc("/api/session/packets", mod.sessionRoute)
-	router.HandleFunc("/api/session/started-at", mod.sessionRoute)
-	router.HandleFunc("/api/session/wifi", mod.sessionRoute)
-	router.HandleFunc("/api/session/wifi/{mac}", mod.sessionRoute)
-	router.HandleFunc("/api/file", mod.fileRoute)
+	for _, route := range mod.apiRoutes() {
+		router.HandleFunc(route.path, route.handler)
+	}
 
 	mod.server.Handler = router
 
-	if mod.username == "" || mod.password == "" {
-		mod.Warning("api.rest.username and/or api.rest.password parameters are empty, authentication is disabled.")
+	if !mod.authEnabled() {
+		mod.Warning("api.rest.username and/or api.rest.password parameters are empty and there are no api.rest.tokens, authentication is disabled.")
+	} else if len(mod.tokens) > 0 {
+		mod.Info("%d API tokens loaded.", len(mod.tokens))
+	}
+
+	if mod.readOnly {
+		mod.Info("read-only mode enabled, commands execution and file routes are disabled.")
+	} else if mod.confirmLevel != riskNone {
+		mod.Info("commands with %s risk or higher must be confirmed before running.", tui.Bold(level))
 	}
 
 	return nil
@@ -232,6 +327,10 @@ func (mod *RestAPI) Stop() error {
 			mod.quit <- true
 		}()
 
+		mod.clearPending()
+		// the shutdown waits for the packet streams, which never end by themselves
+		close(mod.streams)
+
 		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 		defer cancel()
 		mod.server.Shutdown(ctx)
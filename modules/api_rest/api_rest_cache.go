@@ -0,0 +1,113 @@
+package api_rest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// responses smaller than this are not worth compressing
+const compressMinSize = 1024
+
+// bufferedResponse collects the response of a handler so that an ETag can
+// be computed and the body compressed before sending it to the client.
+type bufferedResponse struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	return b.body.Write(data)
+}
+
+func acceptedEncoding(r *http.Request) string {
+	accepted := make(map[string]bool)
+	for _, token := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(token, ";")
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		enabled := true
+		for _, p := range parts[1:] {
+			if p = strings.TrimSpace(p); strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil && q == 0 {
+					enabled = false
+				}
+			}
+		}
+		accepted[name] = enabled
+	}
+
+	for _, enc := range []string{"gzip", "deflate"} {
+		if enabled, found := accepted[enc]; enabled || (!found && accepted["*"]) {
+			return enc
+		}
+	}
+	return ""
+}
+
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sendCached sends a buffered response honoring If-None-Match and, if
+// enabled, compressing the body with the best encoding the client accepts.
+func (mod *RestAPI) sendCached(w http.ResponseWriter, r *http.Request, b *bufferedResponse) {
+	if b.status != 0 && b.status != http.StatusOK {
+		w.WriteHeader(b.status)
+		w.Write(b.body.Bytes())
+		return
+	}
+
+	// weak since the same entity can be sent with different encodings
+	etag := fmt.Sprintf(`W/"%x"`, sha1.Sum(b.body.Bytes()))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	encoding := ""
+	if mod.compress && b.body.Len() >= compressMinSize {
+		encoding = acceptedEncoding(r)
+	}
+
+	var out io.WriteCloser
+	switch encoding {
+	case "gzip":
+		out = gzip.NewWriter(w)
+	case "deflate":
+		// HTTP deflate is the zlib format, not raw deflate
+		out, _ = zlib.NewWriterLevel(w, zlib.DefaultCompression)
+	default:
+		w.Write(b.body.Bytes())
+		return
+	}
+
+	w.Header().Set("Content-Encoding", encoding)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+	if _, err := out.Write(b.body.Bytes()); err != nil {
+		mod.Debug("error while sending %s response: %v", encoding, err)
+	}
+	out.Close()
+}
@@ -0,0 +1,360 @@
+package api_rest
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/caplets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/gorilla/mux"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// risk levels of the commands, the ones sent to the API with a risk equal or
+// higher than api.rest.confirm.level are held until they're confirmed.
+const (
+	riskNone = iota
+	riskProbe
+	riskSpoof
+	riskInject
+)
+
+const confirmTokenHeader = "X-Confirm-Token"
+
+var riskNames = []string{"off", "probe", "spoof", "inject"}
+
+var commandRisks = map[string]int{
+	// active probing and scanning
+	"ble.enum":          riskProbe,
+	"ble.poll":          riskProbe,
+	"cast.apps":         riskProbe,
+	"net.hygiene":       riskProbe,
+	"net.hygiene.check": riskProbe,
+	"net.probe":         riskProbe,
+	"net.scan.arp":      riskProbe,
+	"syn.scan":          riskProbe,
+	"upnp.enum":         riskProbe,
+	"upnp.portmaps":     riskProbe,
+	"wol.eth":           riskProbe,
+	"wol.udp":           riskProbe,
+	// spoofing and man in the middle
+	"any.proxy":    riskSpoof,
+	"arp.spoof":    riskSpoof,
+	"dhcp6.spoof":  riskSpoof,
+	"dns.spoof":    riskSpoof,
+	"dtp.spoof":    riskSpoof,
+	"http.proxy":   riskSpoof,
+	"https.proxy":  riskSpoof,
+	"mac.changer":  riskSpoof,
+	"mysql.server": riskSpoof,
+	"packet.proxy": riskSpoof,
+	"rdp.proxy":    riskSpoof,
+	"ssh.proxy":    riskSpoof,
+	"tcp.proxy":    riskSpoof,
+	"wifi.ap":      riskSpoof,
+	// injection, denial of service and remote control
+	"arp.ban":          riskInject,
+	"ble.write":        riskInject,
+	"can.inject":       riskInject,
+	"can.replay":       riskInject,
+	"cast.launch":      riskInject,
+	"cast.play":        riskInject,
+	"cast.stop":        riskInject,
+	"hid.inject":       riskInject,
+	"hid.inject.ble":   riskInject,
+	"net.fuzz":         riskInject,
+	"ticker":           riskInject,
+	"upnp.portmap.add": riskInject,
+	"upnp.portmap.del": riskInject,
+	"upnp.soap":        riskInject,
+	"wifi.assoc":       riskInject,
+	"wifi.deauth":      riskInject,
+	"wifi.gtk.inject":  riskInject,
+	"wifi.replay":      riskInject,
+}
+
+var (
+	reParallelBlock   = regexp.MustCompile(`^parallel\s*\{(.+)\}$`)
+	reConfirmCommands = regexp.MustCompile(`^api\.rest\.(confirm|deny)(\s|$)`)
+)
+
+// commandRisk returns the risk level of a command line.
+func commandRisk(cmd string) int {
+	// matched like the parallel handler does, which doesn't need a space
+	// before the block
+	if m := reParallelBlock.FindStringSubmatch(strings.TrimSpace(cmd)); m != nil {
+		risk := riskNone
+		for _, inner := range session.ParseCommands(m[1]) {
+			if r := commandRisk(inner); r > risk {
+				risk = r
+			}
+		}
+		return risk
+	}
+
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return riskNone
+	}
+
+	name := fields[0]
+	switch {
+	case strings.HasPrefix(name, "!"):
+		return riskInject
+	case name == "on" || name == "events.on" || name == "phase.add":
+		// these run their commands later on, without being checked again
+		return riskInject
+	case name == "include" || name == "phase.next" || (name == "phase" && len(fields) > 1 && fields[1] == "on"):
+		// like running a caplet by name, switching phases includes their caplets
+		return riskInject
+	case name == "profile" && len(fields) > 1 && fields[1] == "load":
+		return riskInject
+	case name == "set" && len(fields) > 1 && strings.HasPrefix(fields[1], "api.rest."):
+		// otherwise whoever is held could change the confirmation settings
+		return riskInject
+	}
+
+	if last := fields[len(fields)-1]; len(fields) > 1 && (last == "off" || last == "stop") {
+		return riskNone
+	} else if risk, found := commandRisks[name]; found {
+		return risk
+	} else if err, _ := caplets.Load(name); err == nil {
+		return riskInject
+	}
+	return riskNone
+}
+
+// PendingCommands are commands received by the API that wait for a second
+// operator, or the console, to confirm them before running.
+type PendingCommands struct {
+	ID       int       `json:"id"`
+	Who      string    `json:"who"`
+	Commands []string  `json:"cmds"`
+	Risk     string    `json:"risk"`
+	Time     time.Time `json:"time"`
+	Expires  time.Time `json:"expires"`
+
+	timer *time.Timer
+}
+
+// ConfirmEvent is emitted when commands are held, confirmed, denied or expire.
+type ConfirmEvent struct {
+	PendingCommands
+	By string `json:"by"`
+}
+
+func (mod *RestAPI) confirmEvent(action string, p *PendingCommands, by string) {
+	mod.Session.Events.Add("api.rest.confirm."+action, ConfirmEvent{
+		PendingCommands: *p,
+		By:              by,
+	})
+}
+
+// holdCommands returns true if the commands have to be confirmed, in which
+// case they're stored until then and the client is told how to confirm them.
+func (mod *RestAPI) holdCommands(w http.ResponseWriter, cmds []string, who string) bool {
+	for _, cmd := range cmds {
+		if reConfirmCommands.MatchString(cmd) {
+			http.Error(w, "commands can only be confirmed from the console or with the "+confirmTokenHeader+" header", 403)
+			return true
+		}
+	}
+
+	if mod.confirmLevel == riskNone {
+		return false
+	}
+
+	risk := riskNone
+	for _, cmd := range cmds {
+		if err := mod.Session.Validate(cmd); err != nil {
+			http.Error(w, ansiEscapes.ReplaceAllString(err.Error(), ""), 400)
+			return true
+		} else if r := commandRisk(cmd); r > risk {
+			risk = r
+		}
+	}
+
+	if risk < mod.confirmLevel {
+		return false
+	}
+
+	mod.pendingLock.Lock()
+	mod.pendingID++
+	p := &PendingCommands{
+		ID:       mod.pendingID,
+		Who:      who,
+		Commands: cmds,
+		Risk:     riskNames[risk],
+		Time:     time.Now(),
+		Expires:  time.Now().Add(mod.confirmTimeout),
+	}
+	p.timer = time.AfterFunc(mod.confirmTimeout, func() {
+		if mod.takePending(p.ID) != nil {
+			mod.Info("commands #%d of %s expired without being confirmed", p.ID, p.Who)
+			mod.confirmEvent("expired", p, "")
+		}
+	})
+	mod.pending[p.ID] = p
+	mod.pendingLock.Unlock()
+
+	mod.Warning("%s wants to run (%s risk) %s, type %s or %s",
+		tui.Bold(who),
+		tui.Red(p.Risk),
+		tui.Yellow(strings.Join(cmds, "; ")),
+		tui.Bold(fmt.Sprintf("api.rest.confirm %d", p.ID)),
+		tui.Bold(fmt.Sprintf("api.rest.deny %d", p.ID)))
+	mod.confirmEvent("pending", p, "")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	mod.toJSON(w, p)
+	return true
+}
+
+// takePending removes the pending commands with the given id and returns
+// them, nil if there's none.
+func (mod *RestAPI) takePending(id int) *PendingCommands {
+	mod.pendingLock.Lock()
+	defer mod.pendingLock.Unlock()
+
+	p, found := mod.pending[id]
+	if !found {
+		return nil
+	}
+	p.timer.Stop()
+	delete(mod.pending, id)
+	return p
+}
+
+func (mod *RestAPI) clearPending() {
+	mod.pendingLock.Lock()
+	defer mod.pendingLock.Unlock()
+
+	for id, p := range mod.pending {
+		p.timer.Stop()
+		delete(mod.pending, id)
+	}
+}
+
+func (mod *RestAPI) pendingList() []*PendingCommands {
+	mod.pendingLock.Lock()
+	defer mod.pendingLock.Unlock()
+
+	list := make([]*PendingCommands, 0, len(mod.pending))
+	for _, p := range mod.pending {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// confirmPending runs the pending commands on behalf of whoever sent them.
+func (mod *RestAPI) confirmPending(id int, by string) (PipelineResponse, error) {
+	p := mod.takePending(id)
+	if p == nil {
+		return PipelineResponse{}, fmt.Errorf("no pending commands with id %d", id)
+	}
+
+	mod.Info("%s confirmed commands #%d of %s", tui.Bold(by), id, p.Who)
+	mod.confirmEvent("confirmed", p, by)
+
+	mod.pipeLock.Lock()
+	defer mod.pipeLock.Unlock()
+	return mod.execPipeline(p.Commands, p.Who), nil
+}
+
+func (mod *RestAPI) denyPending(id int, by string) error {
+	p := mod.takePending(id)
+	if p == nil {
+		return fmt.Errorf("no pending commands with id %d", id)
+	}
+
+	mod.Info("%s denied commands #%d of %s", tui.Bold(by), id, p.Who)
+	mod.confirmEvent("denied", p, by)
+	return nil
+}
+
+func (mod *RestAPI) checkConfirmToken(r *http.Request) bool {
+	token := r.Header.Get(confirmTokenHeader)
+	return mod.confirmToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(mod.confirmToken)) == 1
+}
+
+func (mod *RestAPI) confirmRoute(w http.ResponseWriter, r *http.Request) {
+	mod.setSecurityHeaders(w)
+
+	if !mod.checkAuth(r) {
+		mod.setAuthFailed(w, r)
+		return
+	} else if r.Method == "GET" {
+		mod.toJSON(w, mod.pendingList())
+		return
+	} else if r.Method != "POST" && r.Method != "DELETE" {
+		http.Error(w, "Bad Request", 400)
+		return
+	} else if mod.readOnly {
+		mod.setReadOnly(w, r)
+		return
+	} else if !mod.checkConfirmToken(r) {
+		mod.Warning("invalid confirmation token from %s to %s", r.RemoteAddr, r.URL.String())
+		http.Error(w, "Forbidden", 403)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	by := strings.Replace(mod.apiOrigin(r), "api.rest ", "api.rest token@", 1)
+	if r.Method == "DELETE" {
+		if err = mod.denyPending(id, by); err != nil {
+			http.Error(w, err.Error(), 404)
+		} else {
+			mod.toJSON(w, APIResponse{Success: true})
+		}
+		return
+	}
+
+	resp, err := mod.confirmPending(id, by)
+	if err != nil {
+		http.Error(w, err.Error(), 404)
+		return
+	} else if !resp.Success {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(400)
+	}
+	mod.toJSON(w, resp)
+}
+
+func (mod *RestAPI) showPending() error {
+	list := mod.pendingList()
+	if len(list) == 0 {
+		mod.Session.Println("no commands waiting for confirmation.")
+		return nil
+	}
+
+	rows := make([][]string, 0, len(list))
+	for _, p := range list {
+		rows = append(rows, []string{
+			strconv.Itoa(p.ID),
+			p.Who,
+			p.Risk,
+			strings.Join(p.Commands, "; "),
+			time.Until(p.Expires).Round(time.Second).String(),
+		})
+	}
+
+	mod.Session.Table([]string{"ID", "From", "Risk", "Commands", "Expires In"}, rows)
+	return nil
+}
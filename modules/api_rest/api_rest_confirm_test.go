@@ -0,0 +1,44 @@
+package api_rest
+
+import (
+	"testing"
+)
+
+func TestCommandRisk(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		risk int
+	}{
+		{"", riskNone},
+		{"net.show", riskNone},
+		{"net.probe on", riskProbe},
+		{"arp.spoof on", riskSpoof},
+		{"arp.spoof off", riskNone},
+		{"wifi.deauth all", riskInject},
+		{"!id", riskInject},
+		{"set arp.spoof.targets 192.168.1.10", riskNone},
+		{"set api.rest.confirm.level off", riskInject},
+		{"on endpoint.new net.probe on", riskInject},
+		{"events.on endpoint.new net.probe on", riskInject},
+		{"phase.add recon recon.cap", riskInject},
+		{"profile load stealth", riskInject},
+		// the blocks are rated with their riskiest command
+		{"parallel { net.show; arp.spoof on }", riskSpoof},
+		{"parallel{arp.spoof on}", riskSpoof},
+		{"  parallel   {net.probe on; wifi.deauth all}  ", riskInject},
+		{"parallel {net.show}", riskNone},
+		// including caplets and switching phases runs arbitrary commands
+		{"include /tmp/evil.cap", riskInject},
+		{"include evil with target=10.0.0.1", riskInject},
+		{"phase on", riskInject},
+		{"phase.next", riskInject},
+		{"phase off", riskNone},
+		{"phase.show", riskNone},
+	}
+
+	for _, tt := range tests {
+		if got := commandRisk(tt.cmd); got != tt.risk {
+			t.Errorf("'%s': expected %s risk, got %s", tt.cmd, riskNames[tt.risk], riskNames[got])
+		}
+	}
+}
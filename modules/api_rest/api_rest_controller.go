@@ -1,18 +1,22 @@
 package api_rest
 
 import (
-	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/session"
 
+	"github.com/bettercap/bettercap/modules/net_sniff"
+
 	"github.com/gorilla/mux"
 )
 
@@ -23,9 +27,17 @@ type CommandRequest struct {
 type APIResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"msg"`
+	// what the show commands printed if output.format is json
+	Tables []json.RawMessage `json:"tables,omitempty"`
 }
 
 func (mod *RestAPI) setAuthFailed(w http.ResponseWriter, r *http.Request) {
+	if ok, _ := mod.authenticate(r); ok {
+		mod.Warning("%s is missing the %s scope for %s %s", mod.apiOrigin(r), requiredScope(r), r.Method, r.URL.Path)
+		http.Error(w, "Forbidden", 403)
+		return
+	}
+
 	mod.Warning("Unauthorized authentication attempt from %s to %s", r.RemoteAddr, r.URL.String())
 
 	w.Header().Set("WWW-Authenticate", `Basic realm="auth"`)
@@ -47,21 +59,25 @@ func (mod *RestAPI) setSecurityHeaders(w http.ResponseWriter) {
 	w.Header().Add("Referrer-Policy", "same-origin")
 
 	w.Header().Set("Access-Control-Allow-Origin", mod.allowOrigin)
-	w.Header().Add("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-	w.Header().Add("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+	w.Header().Add("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, If-None-Match, "+confirmTokenHeader)
+	w.Header().Add("Access-Control-Expose-Headers", "ETag")
+	if mod.readOnly {
+		w.Header().Add("Access-Control-Allow-Methods", "GET, OPTIONS")
+	} else {
+		w.Header().Add("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+	}
+}
+
+func (mod *RestAPI) setReadOnly(w http.ResponseWriter, r *http.Request) {
+	mod.Warning("Refusing %s %s from %s, api.rest.readonly is enabled", r.Method, r.URL.String(), r.RemoteAddr)
+	http.Error(w, "Forbidden", 403)
 }
 
 func (mod *RestAPI) checkAuth(r *http.Request) bool {
-	if mod.username != "" && mod.password != "" {
-		user, pass, _ := r.BasicAuth()
-		// timing attack my ass
-		if subtle.ConstantTimeCompare([]byte(user), []byte(mod.username)) != 1 {
-			return false
-		} else if subtle.ConstantTimeCompare([]byte(pass), []byte(mod.password)) != 1 {
-			return false
-		}
+	if ok, allows := mod.authenticate(r); ok {
+		return allows(requiredScope(r))
 	}
-	return true
+	return false
 }
 
 func (mod *RestAPI) showSession(w http.ResponseWriter, r *http.Request) {
@@ -81,6 +97,19 @@ func (mod *RestAPI) showBLE(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (mod *RestAPI) showBT(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	mac := strings.ToLower(params["mac"])
+
+	if mac == "" {
+		mod.toJSON(w, session.I.BT)
+	} else if dev, found := session.I.BT.Get(mac); found {
+		mod.toJSON(w, dev)
+	} else {
+		http.Error(w, "Not Found", 404)
+	}
+}
+
 func (mod *RestAPI) showHID(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	mac := strings.ToLower(params["mac"])
@@ -94,6 +123,63 @@ func (mod *RestAPI) showHID(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (mod *RestAPI) showZigbee(w http.ResponseWriter, r *http.Request) {
+	mod.toJSON(w, session.I.Zigbee)
+}
+
+func (mod *RestAPI) showCAN(w http.ResponseWriter, r *http.Request) {
+	mod.toJSON(w, session.I.CAN)
+}
+
+func (mod *RestAPI) showSDR(w http.ResponseWriter, r *http.Request) {
+	mod.toJSON(w, session.I.SDR)
+}
+
+func (mod *RestAPI) showDNS(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	ip := params["ip"]
+
+	if ip == "" {
+		mod.toJSON(w, session.I.DNS)
+	} else if client, found := session.I.DNS.Get(ip); found {
+		mod.toJSON(w, client)
+	} else {
+		http.Error(w, "Not Found", 404)
+	}
+}
+
+func (mod *RestAPI) showFlows(w http.ResponseWriter, r *http.Request) {
+	if expr := r.URL.Query().Get("filter"); expr == "" {
+		mod.toJSON(w, session.I.Flows)
+	} else if filter, err := network.ParseFlowFilter(expr); err != nil {
+		http.Error(w, err.Error(), 400)
+	} else {
+		flows := make([]*network.Flow, 0)
+		for _, flow := range session.I.Flows.List() {
+			if filter.Matches(flow) {
+				flows = append(flows, flow)
+			}
+		}
+		mod.toJSON(w, flows)
+	}
+}
+
+func (mod *RestAPI) showAD(w http.ResponseWriter, r *http.Request) {
+	mod.toJSON(w, session.I.AD)
+}
+
+func (mod *RestAPI) showBus(w http.ResponseWriter, r *http.Request) {
+	mod.toJSON(w, session.I.Bus)
+}
+
+func (mod *RestAPI) showArp(w http.ResponseWriter, r *http.Request) {
+	if entries, err := network.ArpEntries(); err != nil {
+		http.Error(w, err.Error(), 500)
+	} else {
+		mod.toJSON(w, entries)
+	}
+}
+
 func (mod *RestAPI) showEnv(w http.ResponseWriter, r *http.Request) {
 	mod.toJSON(w, session.I.Env)
 }
@@ -102,6 +188,10 @@ func (mod *RestAPI) showGateway(w http.ResponseWriter, r *http.Request) {
 	mod.toJSON(w, session.I.Gateway)
 }
 
+func (mod *RestAPI) showHealth(w http.ResponseWriter, r *http.Request) {
+	mod.toJSON(w, session.I.ModulesHealth())
+}
+
 func (mod *RestAPI) showInterface(w http.ResponseWriter, r *http.Request) {
 	mod.toJSON(w, session.I.Interface)
 }
@@ -131,10 +221,30 @@ func (mod *RestAPI) showPackets(w http.ResponseWriter, r *http.Request) {
 	mod.toJSON(w, session.I.Queue)
 }
 
+func (mod *RestAPI) showRoutes(w http.ResponseWriter, r *http.Request) {
+	if routes, err := network.Routes(); err != nil {
+		http.Error(w, err.Error(), 500)
+	} else {
+		mod.toJSON(w, routes)
+	}
+}
+
+func (mod *RestAPI) showSniffer(w http.ResponseWriter, r *http.Request) {
+	mod.toJSON(w, net_sniff.Parsers.Stats())
+}
+
 func (mod *RestAPI) showStartedAt(w http.ResponseWriter, r *http.Request) {
 	mod.toJSON(w, session.I.StartedAt)
 }
 
+func (mod *RestAPI) showTimeline(w http.ResponseWriter, r *http.Request) {
+	mod.toJSON(w, session.I.Timeline())
+}
+
+func (mod *RestAPI) showTopology(w http.ResponseWriter, r *http.Request) {
+	mod.toJSON(w, session.I.Topology)
+}
+
 func (mod *RestAPI) showWiFi(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	mac := strings.ToLower(params["mac"])
@@ -150,24 +260,65 @@ func (mod *RestAPI) showWiFi(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// apiOrigin identifies the client running a command for the audit events.
+func (mod *RestAPI) apiOrigin(r *http.Request) string {
+	client := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(client); err == nil {
+		client = host
+	}
+	if user, _, ok := r.BasicAuth(); ok && user != "" {
+		client = user + "@" + client
+	} else if t := mod.tokenOf(r); t != nil {
+		client = t.name + "@" + client
+	}
+	return "api.rest " + client
+}
+
 func (mod *RestAPI) runSessionCommand(w http.ResponseWriter, r *http.Request) {
 	var err error
 	var cmd CommandRequest
+	var body []byte
 
 	if r.Body == nil {
 		http.Error(w, "Bad Request", 400)
-	} else if err = json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+		return
+	} else if body, err = ioutil.ReadAll(r.Body); err != nil {
 		http.Error(w, "Bad Request", 400)
+		return
+	} else if isPipeline(body) {
+		var cmds []string
+		if err = json.Unmarshal(body, &cmds); err != nil {
+			http.Error(w, "Bad Request", 400)
+		} else {
+			mod.runPipeline(w, cmds, mod.apiOrigin(r))
+		}
+		return
+	} else if err = json.Unmarshal(body, &cmd); err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
 	}
 
-	for _, aCommand := range session.ParseCommands(cmd.Command) {
-		if err = mod.Session.Run(aCommand); err != nil {
+	cmds := session.ParseCommands(cmd.Command)
+	if mod.holdCommands(w, cmds, mod.apiOrigin(r)) {
+		return
+	}
+
+	mod.pipeLock.Lock()
+	defer mod.pipeLock.Unlock()
+
+	resp := APIResponse{Success: true}
+	for _, aCommand := range cmds {
+		tables, err := mod.Session.CaptureTables(func() error {
+			return mod.Session.RunAs(aCommand, mod.apiOrigin(r))
+		})
+		if err != nil {
 			http.Error(w, err.Error(), 400)
 			return
 		}
+		resp.Tables = append(resp.Tables, tables...)
 	}
 
-	mod.toJSON(w, APIResponse{Success: true})
+	mod.toJSON(w, resp)
 }
 
 func (mod *RestAPI) showEvents(w http.ResponseWriter, r *http.Request) {
@@ -198,8 +349,42 @@ func (mod *RestAPI) showEvents(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func parseEventTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	} else if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
 func (mod *RestAPI) clearEvents(w http.ResponseWriter, r *http.Request) {
-	session.I.Events.Clear()
+	var err error
+
+	q := r.URL.Query()
+	filter := session.EventFilter{}
+	for _, tags := range q["tag"] {
+		for _, tag := range strings.Split(tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+
+	if filter.From, err = parseEventTime(q.Get("from")); err != nil {
+		http.Error(w, fmt.Sprintf("invalid from parameter: %v", err), 400)
+		return
+	} else if filter.To, err = parseEventTime(q.Get("to")); err != nil {
+		http.Error(w, fmt.Sprintf("invalid to parameter: %v", err), 400)
+		return
+	}
+
+	removed := session.I.Events.ClearFiltered(filter)
+
+	mod.toJSON(w, APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("%d events cleared", removed),
+	})
 }
 
 func (mod *RestAPI) corsRoute(w http.ResponseWriter, r *http.Request) {
@@ -213,6 +398,9 @@ func (mod *RestAPI) sessionRoute(w http.ResponseWriter, r *http.Request) {
 	if !mod.checkAuth(r) {
 		mod.setAuthFailed(w, r)
 		return
+	} else if r.Method == "POST" && mod.readOnly {
+		mod.setReadOnly(w, r)
+		return
 	} else if r.Method == "POST" {
 		mod.runSessionCommand(w, r)
 		return
@@ -224,17 +412,39 @@ func (mod *RestAPI) sessionRoute(w http.ResponseWriter, r *http.Request) {
 	session.I.Lock()
 	defer session.I.Unlock()
 
-	path := r.URL.String()
+	buf := &bufferedResponse{ResponseWriter: w}
+	defer mod.sendCached(w, r, buf)
+	w = buf
+
+	path := r.URL.Path
 	switch {
 	case path == "/api/session":
 		mod.showSession(w, r)
 
+	case path == "/api/session/ad":
+		mod.showAD(w, r)
+
+	case path == "/api/session/arp":
+		mod.showArp(w, r)
+
+	case path == "/api/session/bus":
+		mod.showBus(w, r)
+
+	case strings.HasPrefix(path, "/api/session/dns"):
+		mod.showDNS(w, r)
+
 	case path == "/api/session/env":
 		mod.showEnv(w, r)
 
+	case path == "/api/session/flows":
+		mod.showFlows(w, r)
+
 	case path == "/api/session/gateway":
 		mod.showGateway(w, r)
 
+	case path == "/api/session/health":
+		mod.showHealth(w, r)
+
 	case path == "/api/session/interface":
 		mod.showInterface(w, r)
 
@@ -250,18 +460,42 @@ func (mod *RestAPI) sessionRoute(w http.ResponseWriter, r *http.Request) {
 	case path == "/api/session/packets":
 		mod.showPackets(w, r)
 
+	case path == "/api/session/routes":
+		mod.showRoutes(w, r)
+
+	case path == "/api/session/sniffer":
+		mod.showSniffer(w, r)
+
 	case path == "/api/session/started-at":
 		mod.showStartedAt(w, r)
 
+	case path == "/api/session/timeline":
+		mod.showTimeline(w, r)
+
+	case path == "/api/session/topology":
+		mod.showTopology(w, r)
+
 	case strings.HasPrefix(path, "/api/session/ble"):
 		mod.showBLE(w, r)
 
+	case strings.HasPrefix(path, "/api/session/bt"):
+		mod.showBT(w, r)
+
 	case strings.HasPrefix(path, "/api/session/hid"):
 		mod.showHID(w, r)
 
 	case strings.HasPrefix(path, "/api/session/wifi"):
 		mod.showWiFi(w, r)
 
+	case path == "/api/session/zigbee":
+		mod.showZigbee(w, r)
+
+	case path == "/api/session/can":
+		mod.showCAN(w, r)
+
+	case path == "/api/session/sdr":
+		mod.showSDR(w, r)
+
 	default:
 		http.Error(w, "Not Found", 404)
 	}
@@ -315,6 +549,8 @@ func (mod *RestAPI) eventsRoute(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method == "GET" {
 		mod.showEvents(w, r)
+	} else if r.Method == "DELETE" && mod.readOnly {
+		mod.setReadOnly(w, r)
 	} else if r.Method == "DELETE" {
 		mod.clearEvents(w, r)
 	} else {
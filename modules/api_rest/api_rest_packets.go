@@ -0,0 +1,241 @@
+package api_rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// packets buffered for each client before the oldest are dropped
+	packetsStreamBuffer = 4096
+
+	packetsFormatJSON = "json"
+	packetsFormatPcap = "pcap"
+)
+
+// PacketSummary is a captured packet streamed with format=json.
+type PacketSummary struct {
+	Time     time.Time `json:"time"`
+	Length   int       `json:"length"`
+	Captured int       `json:"captured"`
+	Summary  string    `json:"summary"`
+}
+
+// packetStream is a client of /api/packets/stream, it receives the packets
+// captured by the session queue matching its BPF filter.
+type packetStream struct {
+	format   string
+	filter   string
+	bpf      *pcap.BPF
+	linkType layers.LinkType
+	packets  chan gopacket.Packet
+	done     chan struct{}
+	sub      *packets.Subscriber
+}
+
+func (mod *RestAPI) newPacketStream(r *http.Request) (*packetStream, error) {
+	q := r.URL.Query()
+	s := &packetStream{
+		format:   q.Get("format"),
+		filter:   q.Get("filter"),
+		linkType: session.I.Queue.LinkType(),
+		packets:  make(chan gopacket.Packet),
+		done:     make(chan struct{}),
+	}
+
+	if s.format == "" {
+		s.format = packetsFormatJSON
+	} else if s.format != packetsFormatJSON && s.format != packetsFormatPcap {
+		return nil, fmt.Errorf("unknown format '%s', use %s or %s", s.format, packetsFormatJSON, packetsFormatPcap)
+	}
+
+	if s.filter != "" {
+		var err error
+		if s.bpf, err = pcap.NewBPF(s.linkType, packets.CaptureSnapLen, s.filter); err != nil {
+			return nil, fmt.Errorf("invalid filter '%s': %v", s.filter, err)
+		}
+	}
+
+	return s, nil
+}
+
+// onPacket runs on the goroutine of the queue subscriber, blocking it just
+// makes its buffer drop the oldest packets.
+func (s *packetStream) onPacket(pkt gopacket.Packet) {
+	if s.bpf != nil && !s.bpf.Matches(pkt.Metadata().CaptureInfo, pkt.Data()) {
+		return
+	}
+
+	select {
+	case s.packets <- pkt:
+	case <-s.done:
+	}
+}
+
+func (mod *RestAPI) subscribePackets(s *packetStream, r *http.Request) {
+	s.sub = session.I.Queue.Subscribe(fmt.Sprintf("api.rest %s", r.RemoteAddr), packetsStreamBuffer, s.onPacket)
+	mod.Debug("streaming packets to %s (format=%s filter='%s')", r.RemoteAddr, s.format, s.filter)
+}
+
+func (mod *RestAPI) unsubscribePackets(s *packetStream, r *http.Request) {
+	// the callback must be unblocked before waiting for it to return
+	close(s.done)
+	session.I.Queue.Unsubscribe(s.sub)
+	mod.Debug("stopped streaming packets to %s", r.RemoteAddr)
+}
+
+// encode returns the packet as a line of JSON or as a pcap record.
+func (s *packetStream) encode(buf *bytes.Buffer, w *pcapgo.Writer, pkt gopacket.Packet) error {
+	if s.format == packetsFormatPcap {
+		return w.WritePacket(pkt.Metadata().CaptureInfo, pkt.Data())
+	}
+
+	return json.NewEncoder(buf).Encode(PacketSummary{
+		Time:     pkt.Metadata().Timestamp,
+		Length:   pkt.Metadata().Length,
+		Captured: pkt.Metadata().CaptureLength,
+		Summary:  packets.Summarize(pkt.Data(), s.linkType.LayerType()),
+	})
+}
+
+func (mod *RestAPI) streamPacketsHTTP(s *packetStream, w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming Not Supported", 500)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	pw := pcapgo.NewWriter(buf)
+	if s.format == packetsFormatPcap {
+		w.Header().Set("Content-Type", "application/vnd.tcpdump.pcap")
+		pw.WriteFileHeader(packets.CaptureSnapLen, s.linkType)
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.WriteHeader(200)
+
+	mod.subscribePackets(s, r)
+	defer mod.unsubscribePackets(s, r)
+
+	for {
+		if buf.Len() > 0 {
+			if _, err := w.Write(buf.Bytes()); err != nil {
+				return
+			}
+			flusher.Flush()
+			buf.Reset()
+		}
+
+		select {
+		case pkt := <-s.packets:
+			if err := s.encode(buf, pw, pkt); err != nil {
+				mod.Error("error while encoding packet: %v", err)
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-mod.streams:
+			return
+		}
+	}
+}
+
+func (mod *RestAPI) streamPacketsWS(s *packetStream, w http.ResponseWriter, r *http.Request) {
+	ws, err := mod.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		if _, ok := err.(websocket.HandshakeError); !ok {
+			mod.Error("Error while updating api.rest connection to websocket: %s", err)
+		}
+		return
+	}
+	defer ws.Close()
+
+	// the reader only handles the pongs and notices the client going away
+	closed := make(chan struct{})
+	go func() {
+		mod.streamReader(ws)
+		close(closed)
+	}()
+
+	// pcap records are sent one per binary message, after the file header
+	msgType := websocket.TextMessage
+	buf := &bytes.Buffer{}
+	pw := pcapgo.NewWriter(buf)
+	if s.format == packetsFormatPcap {
+		msgType = websocket.BinaryMessage
+		pw.WriteFileHeader(packets.CaptureSnapLen, s.linkType)
+	}
+
+	mod.subscribePackets(s, r)
+	defer mod.unsubscribePackets(s, r)
+
+	pingTicker := time.NewTicker(pingPeriod)
+	defer pingTicker.Stop()
+
+	for {
+		if buf.Len() > 0 {
+			ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := ws.WriteMessage(msgType, buf.Bytes()); err != nil {
+				mod.Debug("Error while writing websocket message: %s", err)
+				return
+			}
+			buf.Reset()
+		}
+
+		select {
+		case pkt := <-s.packets:
+			if err := s.encode(buf, pw, pkt); err != nil {
+				mod.Error("error while encoding packet: %v", err)
+				return
+			}
+		case <-pingTicker.C:
+			if err := mod.sendPing(ws); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-mod.streams:
+			return
+		}
+	}
+}
+
+func (mod *RestAPI) packetsStreamRoute(w http.ResponseWriter, r *http.Request) {
+	mod.setSecurityHeaders(w)
+
+	if !mod.checkAuth(r) {
+		mod.setAuthFailed(w, r)
+		return
+	} else if r.Method != "GET" {
+		http.Error(w, "Bad Request", 400)
+		return
+	} else if session.I.Queue == nil {
+		http.Error(w, "Packet Queue Not Available", 503)
+		return
+	}
+
+	s, err := mod.newPacketStream(r)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		mod.streamPacketsWS(s, w, r)
+	} else {
+		mod.streamPacketsHTTP(s, w, r)
+	}
+}
@@ -0,0 +1,172 @@
+package api_rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/session"
+)
+
+var ansiEscapes = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+
+type CommandResult struct {
+	Command  string `json:"cmd"`
+	Executed bool   `json:"executed"`
+	Success  bool   `json:"success"`
+	Output   string `json:"output"`
+	Error    string `json:"error"`
+	// what the show commands printed if output.format is json
+	Tables []json.RawMessage `json:"tables,omitempty"`
+	// the command that undid it after a later one failed
+	RolledBack string `json:"rolled_back,omitempty"`
+}
+
+type PipelineResponse struct {
+	Success bool            `json:"success"`
+	Results []CommandResult `json:"results"`
+}
+
+// captureOutput runs cb and returns the messages logged meanwhile, which
+// might include some logged by other goroutines. What the command prints
+// directly is not captured, except for the tables of the show commands if
+// output.format is json.
+func (mod *RestAPI) captureOutput(cb func() error) (string, []json.RawMessage, error) {
+	start := time.Now()
+	tables, err := mod.Session.CaptureTables(cb)
+
+	lines := make([]string, 0)
+	for _, e := range mod.Session.Events.Since(start) {
+		if e.Tag == "sys.log" {
+			lines = append(lines, e.Data.(session.LogMessage).Message)
+		}
+	}
+
+	return ansiEscapes.ReplaceAllString(strings.Join(lines, "\n"), ""), tables, err
+}
+
+// runPipeline checks that every command matches a handler before executing
+// any of them, then runs them in order stopping at the first error, the
+// {env.NAME} tokens are only expanded right before running each command.
+func (mod *RestAPI) runPipeline(w http.ResponseWriter, lines []string, who string) {
+	if mod.holdCommands(w, flattenCommands(lines), who) {
+		return
+	}
+
+	mod.pipeLock.Lock()
+	resp := mod.execPipeline(lines, who)
+	mod.pipeLock.Unlock()
+
+	if !resp.Success {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(400)
+	}
+	mod.toJSON(w, resp)
+}
+
+// flattenCommands splits the lines of a pipeline in single commands.
+func flattenCommands(lines []string) []string {
+	cmds := make([]string, 0)
+	for _, line := range lines {
+		cmds = append(cmds, session.ParseCommands(line)...)
+	}
+	return cmds
+}
+
+// undoCommand returns the command that reverts cmd given the current state
+// of the session, or an empty string: only starting or stopping a module
+// and setting a parameter can be undone.
+func (mod *RestAPI) undoCommand(cmd string) string {
+	fields := strings.Fields(cmd)
+	if len(fields) >= 3 && fields[0] == "set" {
+		if found, value := mod.Session.Env.Get(fields[1]); found {
+			if value == "" {
+				value = "\"\""
+			}
+			return "set " + fields[1] + " " + value
+		}
+	} else if len(fields) == 2 && (fields[1] == "on" || fields[1] == "off") {
+		if err, m := mod.Session.Module(fields[0]); err == nil {
+			if fields[1] == "on" && !m.Running() {
+				return fields[0] + " off"
+			} else if fields[1] == "off" && m.Running() {
+				return fields[0] + " on"
+			}
+		}
+	}
+	return ""
+}
+
+// execPipeline validates and runs the commands, pipeLock must be held. If a
+// command fails the modules started or stopped and the parameters set by the
+// previous ones are restored, in reverse order.
+func (mod *RestAPI) execPipeline(lines []string, who string) PipelineResponse {
+	resp := PipelineResponse{
+		Success: true,
+		Results: make([]CommandResult, 0),
+	}
+
+	for _, cmd := range flattenCommands(lines) {
+		res := CommandResult{Command: cmd, Success: true}
+		if err := mod.Session.Validate(cmd); err != nil {
+			res.Success = false
+			res.Error = ansiEscapes.ReplaceAllString(err.Error(), "")
+			resp.Success = false
+		}
+		resp.Results = append(resp.Results, res)
+	}
+
+	if !resp.Success {
+		return resp
+	}
+
+	undo := make([]string, len(resp.Results))
+	for i := range resp.Results {
+		res := &resp.Results[i]
+		if !resp.Success {
+			res.Success = false
+			continue
+		}
+
+		undo[i] = mod.undoCommand(res.Command)
+		output, tables, err := mod.captureOutput(func() error {
+			return mod.Session.RunAs(res.Command, who)
+		})
+
+		res.Executed = true
+		res.Output = output
+		if len(tables) > 0 {
+			res.Tables = tables
+		}
+		if err != nil {
+			res.Success = false
+			res.Error = ansiEscapes.ReplaceAllString(err.Error(), "")
+			resp.Success = false
+			for j := i - 1; j >= 0; j-- {
+				mod.rollback(&resp.Results[j], undo[j], who)
+			}
+		}
+	}
+
+	return resp
+}
+
+func (mod *RestAPI) rollback(res *CommandResult, undo string, who string) {
+	if undo == "" {
+		return
+	} else if err := mod.Session.RunAs(undo, who); err != nil {
+		mod.Warning("could not roll back '%s' with '%s': %v", res.Command, undo, err)
+		return
+	}
+	res.RolledBack = undo
+}
+
+// isPipeline returns true if the body of the request is a JSON array of
+// commands rather than a single CommandRequest object.
+func isPipeline(body []byte) bool {
+	body = bytes.TrimSpace(body)
+	return len(body) > 0 && body[0] == '['
+}
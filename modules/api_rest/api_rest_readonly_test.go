@@ -0,0 +1,82 @@
+package api_rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func hasRoute(mod *RestAPI, path string) bool {
+	for _, route := range mod.apiRoutes() {
+		if route.path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func TestReadOnlyRoutes(t *testing.T) {
+	mod := newTestAPI(t)
+	if !hasRoute(mod, "/api/file") {
+		t.Fatal("expected the file route")
+	}
+
+	mod.readOnly = true
+	if hasRoute(mod, "/api/file") {
+		t.Fatal("the file route must not be registered in read-only mode")
+	}
+}
+
+func TestReadOnlyRefused(t *testing.T) {
+	mod := newTestAPI(t)
+	mod.readOnly = true
+
+	tests := []struct {
+		method  string
+		path    string
+		handler http.HandlerFunc
+	}{
+		{"POST", "/api/session", mod.sessionRoute},
+		{"DELETE", "/api/events", mod.eventsRoute},
+		{"POST", "/api/pending/1", mod.confirmRoute},
+		{"DELETE", "/api/pending/1", mod.confirmRoute},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.handler(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != 403 {
+			t.Errorf("%s %s: expected 403, got %d", tt.method, tt.path, w.Code)
+		}
+	}
+}
+
+func TestReadOnlyCORS(t *testing.T) {
+	mod := newTestAPI(t)
+
+	w := httptest.NewRecorder()
+	mod.setSecurityHeaders(w)
+	if methods := w.Header().Get("Access-Control-Allow-Methods"); methods != "POST, GET, OPTIONS, PUT, DELETE" {
+		t.Fatalf("unexpected methods %s", methods)
+	}
+
+	mod.readOnly = true
+	w = httptest.NewRecorder()
+	mod.setSecurityHeaders(w)
+	if methods := w.Header().Get("Access-Control-Allow-Methods"); methods != "GET, OPTIONS" {
+		t.Fatalf("unexpected methods %s in read-only mode", methods)
+	}
+}
+
+func TestReadOnlyAuthFirst(t *testing.T) {
+	mod := newTestAPI(t)
+	mod.readOnly = true
+	mod.tokens, _ = parseTokens("dash:d4sh")
+
+	// unauthenticated clients don't learn about the read-only mode
+	w := httptest.NewRecorder()
+	mod.sessionRoute(w, httptest.NewRequest("POST", "/api/session", nil))
+	if w.Code != 401 {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
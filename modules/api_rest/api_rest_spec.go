@@ -0,0 +1,498 @@
+package api_rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/core"
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/bettercap/bettercap/modules/net_sniff"
+)
+
+var pathParamParser = regexp.MustCompile(`\{([^}]+)\}`)
+
+// specSchema values returned by the sample functions are used as they are
+// instead of being inferred.
+type specSchema map[string]interface{}
+
+func specOneOf(schemas ...map[string]interface{}) specSchema {
+	return specSchema{"oneOf": schemas}
+}
+
+type apiParam struct {
+	name        string
+	kind        string
+	description string
+}
+
+type apiOperation struct {
+	method      string
+	summary     string
+	query       []apiParam
+	request     func() interface{}
+	requestType string
+	response    func() interface{}
+	mimeType    string
+	mutates     bool
+}
+
+type apiRoute struct {
+	path       string
+	handler    http.HandlerFunc
+	operations []apiOperation
+}
+
+func getOperation(summary string, response func() interface{}) apiOperation {
+	return apiOperation{
+		method:   "GET",
+		summary:  summary,
+		response: response,
+		mimeType: "application/json",
+	}
+}
+
+func newSessionRoute(mod *RestAPI, path, summary string, response func() interface{}) apiRoute {
+	ops := []apiOperation{getOperation(summary, response)}
+	if path == "/api/session" {
+		ops = append(ops, apiOperation{
+			method:  "POST",
+			summary: "Run one or more commands, an array of commands is validated first and executed in order until the first error, then the modules started or stopped and the parameters set by the previous ones are restored.",
+			request: func() interface{} {
+				return specOneOf(specSchemaOf(CommandRequest{}), specSchemaOf([]string{"net.probe on"}))
+			},
+			requestType: "application/json",
+			response: func() interface{} {
+				return specOneOf(specSchemaOf(APIResponse{}), specSchemaOf(PipelineResponse{
+					Results: []CommandResult{{}},
+				}))
+			},
+			mimeType: "application/json",
+			mutates:  true,
+		})
+	}
+	return apiRoute{path: path, handler: mod.sessionRoute, operations: ops}
+}
+
+// the first element of a collection is used as a sample to describe
+// the schema of the /{id} routes, nil if the collection is empty.
+func firstOf(n int, get func() interface{}) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return get()
+}
+
+// apiRoutes returns every route exposed by the module, it's used both to
+// register the handlers and to generate the OpenAPI document.
+func (mod *RestAPI) apiRoutes() []apiRoute {
+	s := session.I
+	routes := []apiRoute{
+		{
+			path:    "/api/spec",
+			handler: mod.specRoute,
+			operations: []apiOperation{
+				getOperation("OpenAPI 3 document describing this API.", nil),
+			},
+		},
+		{
+			path:    "/api/events",
+			handler: mod.eventsRoute,
+			operations: []apiOperation{
+				{
+					method:   "GET",
+					summary:  "List the events of the session, or stream them if api.rest.websocket is true.",
+					query:    []apiParam{{"n", "integer", "Only return the last n events."}},
+					response: func() interface{} { return s.Events.Sorted() },
+					mimeType: "application/json",
+				},
+				{
+					method:  "DELETE",
+					summary: "Clear the events buffer, or only the events matching the tag and time filters.",
+					query: []apiParam{
+						{"tag", "string", "Comma separated list of tags, a tag also matches its children (net.sniff matches net.sniff.http.request)."},
+						{"from", "string", "Only clear events newer than this RFC3339 date or unix timestamp."},
+						{"to", "string", "Only clear events older than this RFC3339 date or unix timestamp."},
+					},
+					response: func() interface{} { return APIResponse{} },
+					mimeType: "application/json",
+					mutates:  true,
+				},
+			},
+		},
+		{
+			path:    "/api/confirm",
+			handler: mod.confirmRoute,
+			operations: []apiOperation{
+				getOperation("Commands held until confirmed because of api.rest.confirm.level.", func() interface{} { return mod.pendingList() }),
+			},
+		},
+		{
+			path:    "/api/confirm/{id}",
+			handler: mod.confirmRoute,
+			operations: []apiOperation{
+				{
+					method:   "POST",
+					summary:  "Confirm and run held commands, the api.rest.confirm.token must be sent in the X-Confirm-Token header.",
+					response: func() interface{} { return PipelineResponse{Results: []CommandResult{{}}} },
+					mimeType: "application/json",
+					mutates:  true,
+				},
+				{
+					method:   "DELETE",
+					summary:  "Discard held commands, the api.rest.confirm.token must be sent in the X-Confirm-Token header.",
+					response: func() interface{} { return APIResponse{} },
+					mimeType: "application/json",
+					mutates:  true,
+				},
+			},
+		},
+		{
+			path:    "/api/packets/stream",
+			handler: mod.packetsStreamRoute,
+			operations: []apiOperation{
+				{
+					method:  "GET",
+					summary: "Stream the captured packets as chunked HTTP, or as websocket messages if the connection is upgraded.",
+					query: []apiParam{
+						{"format", "string", "json (default) for a line of JSON summarizing each packet, pcap for a pcap file."},
+						{"filter", "string", "BPF filter the packets must match."},
+					},
+					response: func() interface{} { return PacketSummary{} },
+					mimeType: "application/x-ndjson",
+				},
+			},
+		},
+		newSessionRoute(mod, "/api/session", "The whole session object.", func() interface{} { return s }),
+		newSessionRoute(mod, "/api/session/ad", "Active Directory domains, domain controllers and computer accounts seen by net.sniff.", func() interface{} { return s.AD }),
+		newSessionRoute(mod, "/api/session/arp", "Entries of the system ARP table.", func() interface{} {
+			entries, _ := network.ArpEntries()
+			return entries
+		}),
+		newSessionRoute(mod, "/api/session/ble", "Bluetooth Low Energy devices.", func() interface{} { return s.BLE }),
+		newSessionRoute(mod, "/api/session/ble/{mac}", "A single Bluetooth Low Energy device.", func() interface{} {
+			devs := s.BLE.Devices()
+			return firstOf(len(devs), func() interface{} { return devs[0] })
+		}),
+		newSessionRoute(mod, "/api/session/bt", "Bluetooth Classic (BR/EDR) devices.", func() interface{} { return s.BT }),
+		newSessionRoute(mod, "/api/session/bt/{mac}", "A single Bluetooth Classic device.", func() interface{} {
+			devs := s.BT.Devices()
+			return firstOf(len(devs), func() interface{} { return devs[0] })
+		}),
+		newSessionRoute(mod, "/api/session/bus", "Records published on each topic of the modules bus and their subscribers.", func() interface{} { return s.Bus }),
+		newSessionRoute(mod, "/api/session/hid", "HID devices.", func() interface{} { return s.HID }),
+		newSessionRoute(mod, "/api/session/hid/{mac}", "A single HID device.", func() interface{} {
+			devs := s.HID.Devices()
+			return firstOf(len(devs), func() interface{} { return devs[0] })
+		}),
+		newSessionRoute(mod, "/api/session/zigbee", "802.15.4 / Zigbee devices.", func() interface{} { return s.Zigbee }),
+		newSessionRoute(mod, "/api/session/can", "CAN bus messages.", func() interface{} { return s.CAN }),
+		newSessionRoute(mod, "/api/session/sdr", "Sub-GHz devices decoded by the sdr module.", func() interface{} { return s.SDR }),
+		newSessionRoute(mod, "/api/session/dns", "DNS queries grouped by client.", func() interface{} { return s.DNS }),
+		newSessionRoute(mod, "/api/session/dns/{ip}", "DNS queries of a single client.", func() interface{} {
+			clients := s.DNS.Clients()
+			return firstOf(len(clients), func() interface{} { return clients[0] })
+		}),
+		newSessionRoute(mod, "/api/session/env", "Session variables.", func() interface{} { return s.Env }),
+		func() apiRoute {
+			route := newSessionRoute(mod, "/api/session/flows", "Connections tracked from the sniffed traffic.", func() interface{} { return s.Flows })
+			route.operations[0].query = []apiParam{{"filter", "string", "Comma separated list of IP addresses, ports, protocols and country codes flows must involve."}}
+			return route
+		}(),
+		newSessionRoute(mod, "/api/session/gateway", "The default gateway.", func() interface{} { return s.Gateway }),
+		newSessionRoute(mod, "/api/session/health", "Lifecycle, errors, recovered panics, restarts and state of every module.", func() interface{} { return s.ModulesHealth() }),
+		newSessionRoute(mod, "/api/session/interface", "The selected network interface.", func() interface{} { return s.Interface }),
+		newSessionRoute(mod, "/api/session/modules", "Modules with their parameters, handlers and state.", func() interface{} { return s.Modules }),
+		newSessionRoute(mod, "/api/session/lan", "Hosts of the local network.", func() interface{} { return s.Lan }),
+		newSessionRoute(mod, "/api/session/lan/{mac}", "A single host of the local network.", func() interface{} {
+			hosts := s.Lan.List()
+			return firstOf(len(hosts), func() interface{} { return hosts[0] })
+		}),
+		newSessionRoute(mod, "/api/session/options", "Command line options.", func() interface{} { return s.Options }),
+		newSessionRoute(mod, "/api/session/packets", "Packets and traffic statistics.", func() interface{} { return s.Queue }),
+		newSessionRoute(mod, "/api/session/routes", "Entries of the system routing table.", func() interface{} {
+			routes, _ := network.Routes()
+			return routes
+		}),
+		newSessionRoute(mod, "/api/session/sniffer", "Packets parsed, matches and errors of every net.sniff protocol parser.", func() interface{} { return net_sniff.Parsers.Stats() }),
+		newSessionRoute(mod, "/api/session/started-at", "Session start time.", func() interface{} { return s.StartedAt }),
+		newSessionRoute(mod, "/api/session/timeline", "Modules started, stopped and the actions they took in chronological order.", func() interface{} { return s.Timeline() }),
+		newSessionRoute(mod, "/api/session/topology", "The network topology graph.", func() interface{} { return s.Topology }),
+		newSessionRoute(mod, "/api/session/wifi", "WiFi access points and their clients.", func() interface{} { return s.WiFi }),
+		newSessionRoute(mod, "/api/session/wifi/{mac}", "A single access point or client station.", func() interface{} {
+			aps := s.WiFi.List()
+			return firstOf(len(aps), func() interface{} { return aps[0] })
+		}),
+	}
+
+	if !mod.readOnly {
+		name := []apiParam{{"name", "string", "Path of the file."}}
+		routes = append(routes, apiRoute{
+			path:    "/api/file",
+			handler: mod.fileRoute,
+			operations: []apiOperation{
+				{
+					method:   "GET",
+					summary:  "Download a file.",
+					query:    name,
+					mimeType: "application/octet-stream",
+				},
+				{
+					method:      "POST",
+					summary:     "Upload a file.",
+					query:       name,
+					requestType: "application/octet-stream",
+					response:    func() interface{} { return APIResponse{} },
+					mimeType:    "application/json",
+					mutates:     true,
+				},
+			},
+		})
+	}
+
+	return routes
+}
+
+func (mod *RestAPI) specRoute(w http.ResponseWriter, r *http.Request) {
+	mod.setSecurityHeaders(w)
+
+	if !mod.checkAuth(r) {
+		mod.setAuthFailed(w, r)
+		return
+	} else if r.Method != "GET" {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	session.I.Lock()
+	defer session.I.Unlock()
+
+	mod.toJSON(w, mod.buildSpec(r))
+}
+
+func (mod *RestAPI) buildSpec(r *http.Request) map[string]interface{} {
+	scheme := "http"
+	if mod.isTLS() {
+		scheme = "https"
+	}
+
+	paths := make(map[string]interface{})
+	for _, route := range mod.apiRoutes() {
+		pathParams := []interface{}{}
+		for _, m := range pathParamParser.FindAllStringSubmatch(route.path, -1) {
+			pathParams = append(pathParams, map[string]interface{}{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+
+		item := make(map[string]interface{})
+		for _, op := range route.operations {
+			if op.mutates && mod.readOnly {
+				continue
+			}
+			item[strings.ToLower(op.method)] = mod.specOperation(route, op, pathParams)
+		}
+		paths[route.path] = item
+	}
+
+	modules := make(map[string]interface{})
+	for _, m := range session.I.Modules {
+		modules["module."+m.Name()] = specSchemaOf(m.Extra())
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]interface{}{
+			"title":       core.Name + " REST API",
+			"description": "Schemas are inferred from the current state of the session.",
+			"version":     core.Version,
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"url": fmt.Sprintf("%s://%s", scheme, r.Host)},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"basicAuth": []string{}},
+			map[string]interface{}{"bearerAuth": []string{}},
+		},
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"basicAuth":  map[string]interface{}{"type": "http", "scheme": "basic"},
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "description": "One of the api.rest.tokens, GET routes need the read scope, /api/file the files scope and the others the commands scope."},
+			},
+			"schemas": modules,
+		},
+		"paths": paths,
+	}
+}
+
+func (mod *RestAPI) specOperation(route apiRoute, op apiOperation, pathParams []interface{}) map[string]interface{} {
+	params := append([]interface{}{}, pathParams...)
+	for _, q := range op.query {
+		params = append(params, map[string]interface{}{
+			"name":        q.name,
+			"in":          "query",
+			"description": q.description,
+			"required":    false,
+			"schema":      map[string]interface{}{"type": q.kind},
+		})
+	}
+
+	success := map[string]interface{}{"description": "Success."}
+	if op.mimeType == "application/json" {
+		schema := map[string]interface{}{"type": "object"}
+		if op.response != nil {
+			if sample := op.response(); sample != nil {
+				schema = specSchemaOf(sample)
+			}
+		}
+		success["content"] = map[string]interface{}{
+			op.mimeType: map[string]interface{}{"schema": schema},
+		}
+	} else if op.mimeType != "" {
+		success["content"] = map[string]interface{}{
+			op.mimeType: map[string]interface{}{
+				"schema": map[string]interface{}{"type": "string", "format": "binary"},
+			},
+		}
+	}
+
+	responses := map[string]interface{}{
+		"200": success,
+		"400": map[string]interface{}{"description": "Bad request."},
+		"401": map[string]interface{}{"description": "Authentication required."},
+	}
+	if op.method == "GET" && strings.HasPrefix(route.path, "/api/session") {
+		responses["304"] = map[string]interface{}{"description": "Not modified since the ETag sent with If-None-Match."}
+	}
+	if len(pathParams) > 0 || route.path == "/api/file" {
+		responses["404"] = map[string]interface{}{"description": "Not found."}
+	}
+
+	doc := map[string]interface{}{
+		"summary":    op.summary,
+		"parameters": params,
+		"responses":  responses,
+	}
+
+	if op.requestType == "application/json" {
+		doc["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				op.requestType: map[string]interface{}{"schema": specSchemaOf(op.request())},
+			},
+		}
+	} else if op.requestType != "" {
+		doc["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				op.requestType: map[string]interface{}{
+					"schema": map[string]interface{}{"type": "string", "format": "binary"},
+				},
+			},
+		}
+	}
+
+	return doc
+}
+
+// specSchemaOf serializes the object as the API would and infers a JSON
+// schema from the result, this way custom MarshalJSON methods and module
+// state objects are described exactly as they are returned.
+func specSchemaOf(o interface{}) map[string]interface{} {
+	if schema, ok := o.(specSchema); ok {
+		return schema
+	}
+
+	raw, err := json.Marshal(o)
+	if err != nil {
+		return map[string]interface{}{"type": "object"}
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err = dec.Decode(&doc); err != nil {
+		return map[string]interface{}{"type": "object"}
+	}
+
+	return specInfer(doc)
+}
+
+func specInfer(v interface{}) map[string]interface{} {
+	switch t := v.(type) {
+	case nil:
+		return map[string]interface{}{"nullable": true}
+	case bool:
+		return map[string]interface{}{"type": "boolean"}
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return map[string]interface{}{"type": "integer"}
+		}
+		return map[string]interface{}{"type": "number"}
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		}
+		return map[string]interface{}{"type": "string"}
+	case []interface{}:
+		items := map[string]interface{}{}
+		for _, elem := range t {
+			items = specMerge(items, specInfer(elem))
+		}
+		return map[string]interface{}{"type": "array", "items": items}
+	case map[string]interface{}:
+		props := make(map[string]interface{})
+		for k, elem := range t {
+			props[k] = specInfer(elem)
+		}
+		return map[string]interface{}{"type": "object", "properties": props}
+	}
+	return map[string]interface{}{}
+}
+
+// specMerge combines the schemas of two elements of the same array.
+func specMerge(a, b map[string]interface{}) map[string]interface{} {
+	if len(a) == 0 {
+		return b
+	} else if len(b) == 0 {
+		return a
+	}
+
+	ta, _ := a["type"].(string)
+	tb, _ := b["type"].(string)
+	switch {
+	case ta == "":
+		b["nullable"] = true
+		return b
+	case tb == "":
+		a["nullable"] = true
+		return a
+	case ta != tb:
+		if (ta == "integer" && tb == "number") || (ta == "number" && tb == "integer") {
+			return map[string]interface{}{"type": "number"}
+		}
+		// mixed types, anything goes
+		return map[string]interface{}{}
+	case ta == "array":
+		a["items"] = specMerge(a["items"].(map[string]interface{}), b["items"].(map[string]interface{}))
+	case ta == "object":
+		pa := a["properties"].(map[string]interface{})
+		for k, pb := range b["properties"].(map[string]interface{}) {
+			if prev, found := pa[k]; found {
+				pa[k] = specMerge(prev.(map[string]interface{}), pb.(map[string]interface{}))
+			} else {
+				pa[k] = pb
+			}
+		}
+	}
+	return a
+}
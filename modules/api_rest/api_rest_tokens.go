@@ -0,0 +1,145 @@
+package api_rest
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// what an API token can be used for
+const (
+	// the GET routes, except /api/file
+	scopeRead = "read"
+	// the routes executing commands or changing the session
+	scopeCommands = "commands"
+	// downloading and uploading files with /api/file
+	scopeFiles = "files"
+	// all of the above, like the api.rest.username and api.rest.password
+	scopeAll = "all"
+)
+
+var scopeNames = []string{scopeRead, scopeCommands, scopeFiles, scopeAll}
+
+// apiToken is one of the api.rest.tokens, sent by clients in the
+// Authorization: Bearer header or, for websockets, in the token parameter.
+type apiToken struct {
+	name   string
+	token  string
+	scopes map[string]bool
+}
+
+func (t apiToken) allows(scope string) bool {
+	return t.scopes[scopeAll] || t.scopes[scope]
+}
+
+// parseTokens parses the api.rest.tokens parameter, a comma separated list
+// of NAME:TOKEN:SCOPES with the scopes separated by +, read if omitted.
+func parseTokens(value string) ([]apiToken, error) {
+	tokens := make([]apiToken, 0)
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid token '%s', expected NAME:TOKEN:SCOPES", entry)
+		}
+
+		t := apiToken{
+			name:   parts[0],
+			token:  parts[1],
+			scopes: map[string]bool{scopeRead: true},
+		}
+
+		if len(parts) == 3 {
+			t.scopes = make(map[string]bool)
+			for _, scope := range strings.Split(parts[2], "+") {
+				valid := false
+				for _, name := range scopeNames {
+					if scope == name {
+						valid = true
+						break
+					}
+				}
+				if !valid {
+					return nil, fmt.Errorf("unknown scope '%s' for token %s, use %s", scope, t.name, strings.Join(scopeNames, ", "))
+				}
+				t.scopes[scope] = true
+			}
+		}
+
+		for _, other := range tokens {
+			if other.name == t.name {
+				return nil, fmt.Errorf("duplicated token name %s", t.name)
+			}
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+// requiredScope returns the scope needed to serve the request.
+func requiredScope(r *http.Request) string {
+	if r.URL.Path == "/api/file" {
+		return scopeFiles
+	} else if r.Method == "GET" || r.Method == "HEAD" {
+		return scopeRead
+	}
+	return scopeCommands
+}
+
+func (mod *RestAPI) authEnabled() bool {
+	return (mod.username != "" && mod.password != "") || len(mod.tokens) > 0
+}
+
+func (mod *RestAPI) checkCredentials(r *http.Request) bool {
+	if mod.username == "" || mod.password == "" {
+		return false
+	}
+	user, pass, _ := r.BasicAuth()
+	// timing attack my ass
+	if subtle.ConstantTimeCompare([]byte(user), []byte(mod.username)) != 1 {
+		return false
+	} else if subtle.ConstantTimeCompare([]byte(pass), []byte(mod.password)) != 1 {
+		return false
+	}
+	return true
+}
+
+// tokenOf returns the API token of the request, nil if it has none or it's
+// not one of api.rest.tokens.
+func (mod *RestAPI) tokenOf(r *http.Request) *apiToken {
+	value := ""
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		value = strings.TrimSpace(auth[len("Bearer "):])
+	} else if websocket.IsWebSocketUpgrade(r) {
+		// browsers can't set headers on websocket connections
+		value = r.URL.Query().Get("token")
+	}
+
+	if value == "" {
+		return nil
+	}
+
+	for i := range mod.tokens {
+		if subtle.ConstantTimeCompare([]byte(value), []byte(mod.tokens[i].token)) == 1 {
+			return &mod.tokens[i]
+		}
+	}
+	return nil
+}
+
+// authenticate returns whether the request has valid credentials and the
+// scopes they grant.
+func (mod *RestAPI) authenticate(r *http.Request) (bool, func(string) bool) {
+	if !mod.authEnabled() || mod.checkCredentials(r) {
+		return true, func(string) bool { return true }
+	} else if t := mod.tokenOf(r); t != nil {
+		return true, t.allows
+	}
+	return false, nil
+}
@@ -0,0 +1,289 @@
+package api_rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bettercap/bettercap/session"
+)
+
+func newTestAPI(t *testing.T) *RestAPI {
+	env, err := session.NewEnvironment("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session.I = &session.Session{
+		Env:    env,
+		Events: session.NewEventPool(false, true),
+	}
+	return NewRestAPI(session.I)
+}
+
+func TestParseTokens(t *testing.T) {
+	tests := []struct {
+		value  string
+		names  []string
+		scopes []string
+		err    bool
+	}{
+		{"", []string{}, nil, false},
+		{" , ", []string{}, nil, false},
+		{"dash:s3cr3t", []string{"dash"}, []string{"read"}, false},
+		{"bot:t0k3n:commands", []string{"bot"}, []string{"commands"}, false},
+		{"up:t0k3n:read+files", []string{"up"}, []string{"read", "files"}, false},
+		{"root:t0k3n:all", []string{"root"}, []string{"all"}, false},
+		{"a:one, b:two:files", []string{"a", "b"}, []string{"read"}, false},
+		{"nameonly", nil, nil, true},
+		{":t0k3n", nil, nil, true},
+		{"dash:", nil, nil, true},
+		{"dash:t0k3n:read:more", nil, nil, true},
+		{"dash:t0k3n:write", nil, nil, true},
+		{"dash:t0k3n:read+", nil, nil, true},
+		{"a:one,a:two", nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		tokens, err := parseTokens(tt.value)
+		if tt.err {
+			if err == nil {
+				t.Errorf("'%s': expected an error", tt.value)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("'%s': unexpected error %v", tt.value, err)
+			continue
+		} else if len(tokens) != len(tt.names) {
+			t.Errorf("'%s': expected %d tokens, got %d", tt.value, len(tt.names), len(tokens))
+			continue
+		}
+
+		for i, tok := range tokens {
+			if tok.name != tt.names[i] {
+				t.Errorf("'%s': expected token %s, got %s", tt.value, tt.names[i], tok.name)
+			}
+		}
+		if len(tokens) > 0 {
+			if len(tokens[0].scopes) != len(tt.scopes) {
+				t.Errorf("'%s': expected scopes %v, got %v", tt.value, tt.scopes, tokens[0].scopes)
+			}
+			for _, scope := range tt.scopes {
+				if !tokens[0].scopes[scope] {
+					t.Errorf("'%s': expected the %s scope", tt.value, scope)
+				}
+			}
+		}
+	}
+}
+
+func TestTokenAllows(t *testing.T) {
+	tokens, err := parseTokens("r:1:read,c:2:commands,f:3:files,rf:4:read+files,a:5:all")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string][]string{
+		"r":  {scopeRead},
+		"c":  {scopeCommands},
+		"f":  {scopeFiles},
+		"rf": {scopeRead, scopeFiles},
+		"a":  {scopeRead, scopeCommands, scopeFiles},
+	}
+
+	for _, tok := range tokens {
+		for _, scope := range []string{scopeRead, scopeCommands, scopeFiles} {
+			allowed := false
+			for _, s := range exp[tok.name] {
+				allowed = allowed || s == scope
+			}
+			if tok.allows(scope) != allowed {
+				t.Errorf("token %s: expected allows(%s) to be %v", tok.name, scope, allowed)
+			}
+		}
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		scope  string
+	}{
+		{"GET", "/api/session", scopeRead},
+		{"HEAD", "/api/session", scopeRead},
+		{"GET", "/api/events", scopeRead},
+		{"GET", "/api/session/wifi/aa:bb:cc:dd:ee:ff", scopeRead},
+		{"GET", "/api/spec", scopeRead},
+		{"POST", "/api/session", scopeCommands},
+		{"DELETE", "/api/events", scopeCommands},
+		{"POST", "/api/pending/1", scopeCommands},
+		{"DELETE", "/api/pending/1", scopeCommands},
+		// files is only for /api/file, whatever the method
+		{"GET", "/api/file", scopeFiles},
+		{"POST", "/api/file", scopeFiles},
+		{"GET", "/api/files", scopeRead},
+		{"GET", "/api/file/other", scopeRead},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := requiredScope(r); got != tt.scope {
+			t.Errorf("%s %s: expected the %s scope, got %s", tt.method, tt.path, tt.scope, got)
+		}
+	}
+}
+
+func authRequest(method, path, auth string) *http.Request {
+	r := httptest.NewRequest(method, path, nil)
+	if strings.HasPrefix(auth, "basic:") {
+		parts := strings.SplitN(auth[6:], ":", 2)
+		r.SetBasicAuth(parts[0], parts[1])
+	} else if auth != "" {
+		r.Header.Set("Authorization", "Bearer "+auth)
+	}
+	return r
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		username string
+		password string
+		tokens   string
+		auth     string
+		ok       bool
+		scopes   []string
+	}{
+		// no credentials configured, everything is allowed
+		{"", "", "", "", true, []string{scopeRead, scopeCommands, scopeFiles}},
+		{"", "", "", "whatever", true, []string{scopeRead, scopeCommands, scopeFiles}},
+		// half configured basic auth is disabled
+		{"user", "", "", "", true, []string{scopeRead, scopeCommands, scopeFiles}},
+		{"user", "pass", "", "", false, nil},
+		{"user", "pass", "", "basic:user:pass", true, []string{scopeRead, scopeCommands, scopeFiles}},
+		{"user", "pass", "", "basic:user:wrong", false, nil},
+		{"user", "pass", "", "basic:wrong:pass", false, nil},
+		{"user", "pass", "", "pass", false, nil},
+		// tokens alone enable the authentication
+		{"", "", "dash:d4sh", "", false, nil},
+		{"", "", "dash:d4sh", "d4sh", true, []string{scopeRead}},
+		{"", "", "dash:d4sh", "d4sh2", false, nil},
+		{"", "", "dash:d4sh", "basic:dash:d4sh", false, nil},
+		{"user", "pass", "bot:b0t:commands+files", "b0t", true, []string{scopeCommands, scopeFiles}},
+		{"user", "pass", "bot:b0t:commands+files", "basic:user:pass", true, []string{scopeRead, scopeCommands, scopeFiles}},
+		{"", "", "root:r00t:all", "r00t", true, []string{scopeRead, scopeCommands, scopeFiles}},
+	}
+
+	for i, tt := range tests {
+		mod := newTestAPI(t)
+		mod.username = tt.username
+		mod.password = tt.password
+
+		var err error
+		if mod.tokens, err = parseTokens(tt.tokens); err != nil {
+			t.Fatal(err)
+		}
+
+		ok, allows := mod.authenticate(authRequest("GET", "/api/session", tt.auth))
+		if ok != tt.ok {
+			t.Errorf("#%d: expected authenticated to be %v", i, tt.ok)
+			continue
+		} else if !ok {
+			continue
+		}
+
+		for _, scope := range []string{scopeRead, scopeCommands, scopeFiles} {
+			exp := false
+			for _, s := range tt.scopes {
+				exp = exp || s == scope
+			}
+			if allows(scope) != exp {
+				t.Errorf("#%d: expected the %s scope to be allowed: %v", i, scope, exp)
+			}
+		}
+	}
+}
+
+func TestWebsocketToken(t *testing.T) {
+	mod := newTestAPI(t)
+	mod.tokens, _ = parseTokens("dash:d4sh")
+
+	r := httptest.NewRequest("GET", "/api/events?ws=1&token=d4sh", nil)
+	if ok, _ := mod.authenticate(r); ok {
+		t.Fatal("the token parameter must only be accepted for websockets")
+	}
+
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	if ok, allows := mod.authenticate(r); !ok || !allows(scopeRead) {
+		t.Fatal("expected the token parameter to be accepted for websockets")
+	}
+}
+
+// routePath returns a request path for the route, with its {params} set.
+func routePath(route apiRoute) string {
+	return pathParamParser.ReplaceAllStringFunc(route.path, func(param string) string {
+		if param == "{id}" {
+			return "1"
+		}
+		return "aa:bb:cc:dd:ee:ff"
+	})
+}
+
+func TestRouteScopes(t *testing.T) {
+	mod := newTestAPI(t)
+	mod.username = "user"
+	mod.password = "pass"
+	mod.tokens, _ = parseTokens("r:r:read,c:c:commands,f:f:files,a:a:all")
+
+	allowed := map[string]map[string]bool{
+		"":                {},
+		"invalid":         {},
+		"basic:user:nope": {},
+		"r":               {scopeRead: true},
+		"c":               {scopeCommands: true},
+		"f":               {scopeFiles: true},
+		"a":               {scopeRead: true, scopeCommands: true, scopeFiles: true},
+		"basic:user:pass": {scopeRead: true, scopeCommands: true, scopeFiles: true},
+	}
+
+	for _, route := range mod.apiRoutes() {
+		path := routePath(route)
+		for _, op := range route.operations {
+			scope := requiredScope(httptest.NewRequest(op.method, path, nil))
+			if route.path == "/api/file" && scope != scopeFiles {
+				t.Errorf("%s %s: expected the files scope, got %s", op.method, route.path, scope)
+			} else if route.path != "/api/file" && op.mutates && scope != scopeCommands {
+				t.Errorf("%s %s: expected the commands scope, got %s", op.method, route.path, scope)
+			}
+
+			for auth, scopes := range allowed {
+				r := authRequest(op.method, path, auth)
+				if got := mod.checkAuth(r); got != scopes[scope] {
+					t.Errorf("%s %s with '%s': expected checkAuth to be %v", op.method, route.path, auth, scopes[scope])
+					continue
+				} else if got {
+					continue
+				}
+
+				// a valid token missing the scope is forbidden, otherwise
+				// the client has to authenticate
+				exp := 401
+				if auth == "r" || auth == "c" || auth == "f" {
+					exp = 403
+				}
+
+				w := httptest.NewRecorder()
+				mod.setAuthFailed(w, r)
+				if w.Code != exp {
+					t.Errorf("%s %s with '%s': expected %d, got %d", op.method, route.path, auth, exp, w.Code)
+				} else if exp == 401 && w.Header().Get("WWW-Authenticate") == "" {
+					t.Errorf("%s %s with '%s': expected the WWW-Authenticate header", op.method, route.path, auth)
+				} else if exp == 403 && w.Header().Get("WWW-Authenticate") != "" {
+					t.Errorf("%s %s with '%s': unexpected WWW-Authenticate header", op.method, route.path, auth)
+				}
+			}
+		}
+	}
+}
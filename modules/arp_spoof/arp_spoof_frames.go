@@ -0,0 +1,69 @@
+package arp_spoof
+
+import (
+	"net"
+	"sync"
+
+	"github.com/bettercap/bettercap/modules/utils"
+	"github.com/bettercap/bettercap/packets"
+)
+
+type cachedFrame struct {
+	raw  []byte
+	used bool
+}
+
+// frameCache keeps the serialized (and VLAN tagged) ARP replies across the
+// iterations of the spoofing loop, since they only change when targets do.
+type frameCache struct {
+	sync.Mutex
+	frames map[string]*cachedFrame
+}
+
+func newFrameCache() *frameCache {
+	return &frameCache{
+		frames: make(map[string]*cachedFrame),
+	}
+}
+
+func (c *frameCache) reply(vlan *utils.VLAN, from net.IP, fromHW net.HardwareAddr, to net.IP, toHW net.HardwareAddr) (error, []byte) {
+	key := string(from.To4()) + string(fromHW) + string(to.To4()) + string(toHW)
+
+	c.Lock()
+	defer c.Unlock()
+
+	if frame, found := c.frames[key]; found {
+		frame.used = true
+		return nil, frame.raw
+	}
+
+	err, raw := packets.NewARPReply(from, fromHW, to, toHW)
+	if err != nil {
+		return err, nil
+	} else if err, raw = vlan.Tag(raw); err != nil {
+		return err, nil
+	}
+
+	c.frames[key] = &cachedFrame{raw: raw, used: true}
+	return nil, raw
+}
+
+// sweep removes the frames that haven't been used since the last sweep.
+func (c *frameCache) sweep() {
+	c.Lock()
+	defer c.Unlock()
+
+	for key, frame := range c.frames {
+		if !frame.used {
+			delete(c.frames, key)
+		} else {
+			frame.used = false
+		}
+	}
+}
+
+func (c *frameCache) clear() {
+	c.Lock()
+	defer c.Unlock()
+	c.frames = make(map[string]*cachedFrame)
+}
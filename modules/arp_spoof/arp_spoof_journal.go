@@ -0,0 +1,117 @@
+package arp_spoof
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bettercap/bettercap/journal"
+	"github.com/bettercap/bettercap/packets"
+)
+
+const journalKind = "arp.spoof"
+
+// trackTargets saves the poisoned targets to the journal so that their ARP
+// cache can be restored even if we don't go through Stop.
+func (mod *ArpSpoofer) trackTargets() {
+	targets := make([]string, 0)
+	for ip, mac := range mod.getTargets(false) {
+		targets = append(targets, fmt.Sprintf("%s=%s", ip, mac))
+	}
+	sort.Strings(targets)
+
+	list := strings.Join(targets, ",")
+	routers := routersList(mod.currentGateways())
+	if list+"|"+routers == mod.tracked {
+		return
+	}
+
+	if err := journal.Track(journalKind, mod.Session.Interface.Name(), map[string]string{
+		"gateway":     mod.Session.Gateway.IpAddress,
+		"gateway.mac": mod.Session.Gateway.HwAddress,
+		"fullduplex":  strconv.FormatBool(mod.fullDuplex),
+		"internal":    strconv.FormatBool(mod.internal),
+		"vlan":        strconv.Itoa(int(mod.vlan.ID)),
+		"targets":     list,
+		"routers":     routers,
+	}); err != nil {
+		mod.Warning("could not update the journal: %s", err)
+	} else {
+		mod.tracked = list + "|" + routers
+	}
+}
+
+func (mod *ArpSpoofer) untrackTargets() {
+	if err := journal.Untrack(journalKind, mod.Session.Interface.Name()); err != nil {
+		mod.Warning("could not update the journal: %s", err)
+	}
+	mod.tracked = ""
+}
+
+// restoreJournal restores the ARP cache of the targets of a previous session.
+func (mod *ArpSpoofer) restoreJournal(args map[string]string) error {
+	gwIP := net.ParseIP(args["gateway"])
+	gwHW, err := net.ParseMAC(args["gateway.mac"])
+	if gwIP == nil || err != nil {
+		return fmt.Errorf("invalid gateway %s (%s)", args["gateway"], args["gateway.mac"])
+	}
+
+	vlan, _ := strconv.Atoi(args["vlan"])
+	mod.vlan.ID = uint16(vlan)
+
+	type target struct {
+		ip  net.IP
+		mac net.HardwareAddr
+	}
+
+	parse := func(list string) []target {
+		targets := make([]target, 0)
+		for _, entry := range strings.Split(list, ",") {
+			if parts := strings.SplitN(entry, "=", 2); len(parts) == 2 {
+				ip := net.ParseIP(parts[0])
+				mac, err := net.ParseMAC(parts[1])
+				if ip != nil && err == nil {
+					targets = append(targets, target{ip, mac})
+				}
+			}
+		}
+		return targets
+	}
+
+	targets := parse(args["targets"])
+	// the physical routers of the gateway VRRP or HSRP group
+	gateways := append([]target{{gwIP, gwHW}}, parse(args["routers"])...)
+
+	mod.Info("restoring ARP cache of %d targets.", len(targets))
+
+	send := func(srcIP net.IP, srcHW net.HardwareAddr, dstIP net.IP, dstHW net.HardwareAddr) {
+		if err, pkt := packets.NewARPReply(srcIP, srcHW, dstIP, dstHW); err != nil {
+			mod.Error("error while creating ARP packet for %s: %s", dstIP, err)
+		} else if err = mod.vlan.Send(pkt); err != nil {
+			mod.Error("error while sending packet: %v", err)
+		}
+	}
+
+	for _, gw := range gateways {
+		for _, t := range targets {
+			send(gw.ip, gw.mac, t.ip, t.mac)
+			if args["fullduplex"] == "true" {
+				send(t.ip, t.mac, gw.ip, gw.mac)
+			}
+		}
+	}
+
+	if args["internal"] == "true" {
+		for _, from := range targets {
+			for _, to := range targets {
+				if !from.ip.Equal(to.ip) {
+					send(from.ip, from.mac, to.ip, to.mac)
+				}
+			}
+		}
+	}
+
+	return journal.Untrack(journalKind, mod.Session.Interface.Name())
+}
@@ -0,0 +1,309 @@
+package arp_spoof
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const pairSeparator = "<>"
+
+// hostPair is a couple of hosts of the network whose traffic to each other
+// is spoofed, or not, in internal mode.
+type hostPair struct {
+	a net.IP
+	b net.IP
+}
+
+func newHostPair(a, b net.IP) hostPair {
+	// sorted so that A<>B and B<>A are the same pair
+	if bytes.Compare(a.To16(), b.To16()) > 0 {
+		a, b = b, a
+	}
+	return hostPair{a: a, b: b}
+}
+
+func (p hostPair) key() string {
+	return p.a.String() + pairSeparator + p.b.String()
+}
+
+// resolvePairSide returns the IPv4 addresses of one side of a pair, MAC
+// addresses and aliases must be known hosts of the network.
+func (mod *ArpSpoofer) resolvePairSide(side string) ([]net.IP, error) {
+	ips, macs, err := network.ParseTargets(side, mod.Session.Lan.Aliases())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hw := range macs {
+		e, found := mod.Session.Lan.Get(hw.String())
+		if !found || e.IP == nil {
+			return nil, fmt.Errorf("can't resolve the address of %s", hw)
+		}
+		ips = append(ips, e.IP)
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("empty side of pair")
+	}
+	return ips, nil
+}
+
+// parsePairs parses a comma separated list of A<>B pairs, every side is an IP
+// address, a MAC address, an alias or a nmap style range of addresses, in
+// which case every address of one side is paired with every one of the other.
+func (mod *ArpSpoofer) parsePairs(list string) (map[string]hostPair, error) {
+	pairs := make(map[string]hostPair)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		sides := strings.Split(item, pairSeparator)
+		if len(sides) != 2 {
+			return nil, fmt.Errorf("'%s' is not a pair of hosts like A%sB", item, pairSeparator)
+		}
+
+		left, err := mod.resolvePairSide(strings.TrimSpace(sides[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pair '%s': %v", item, err)
+		}
+		right, err := mod.resolvePairSide(strings.TrimSpace(sides[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pair '%s': %v", item, err)
+		}
+
+		for _, a := range left {
+			for _, b := range right {
+				if !a.Equal(b) {
+					pair := newHostPair(a, b)
+					pairs[pair.key()] = pair
+				}
+			}
+		}
+	}
+	return pairs, nil
+}
+
+func (mod *ArpSpoofer) configurePairs() (err error) {
+	var pairs, exclude string
+
+	if err, pairs = mod.StringParam("arp.spoof.internal.pairs"); err != nil {
+		return err
+	} else if err, exclude = mod.StringParam("arp.spoof.internal.exclude"); err != nil {
+		return err
+	} else if mod.pairs, err = mod.parsePairs(pairs); err != nil {
+		return fmt.Errorf("arp.spoof.internal.pairs: %v", err)
+	} else if mod.excluded, err = mod.parsePairs(exclude); err != nil {
+		return fmt.Errorf("arp.spoof.internal.exclude: %v", err)
+	}
+
+	for key := range mod.excluded {
+		delete(mod.pairs, key)
+	}
+	return nil
+}
+
+func (mod *ArpSpoofer) isExcluded(a, b net.IP) bool {
+	_, found := mod.excluded[newHostPair(a, b).key()]
+	return found
+}
+
+// interception returns whether the traffic from a to b and from b to a is
+// spoofed in internal mode, targets are only told where their neighbours are
+// so the traffic of a target to a host that is not is only spoofed one way.
+func (mod *ArpSpoofer) interception(a, b net.IP, targets map[string]net.HardwareAddr) (fromA bool, fromB bool) {
+	if mod.Session.Skip(a) || mod.Session.Skip(b) {
+		return false, false
+	} else if len(mod.pairs) > 0 {
+		_, found := mod.pairs[newHostPair(a, b).key()]
+		return found, found
+	} else if mod.isExcluded(a, b) {
+		return false, false
+	}
+	_, fromA = targets[a.String()]
+	_, fromB = targets[b.String()]
+	return
+}
+
+// internalFrames returns the frames spoofing, or restoring, the traffic among
+// the hosts of the network: the one of the pairs in both directions if any,
+// otherwise the one of every target to every neighbour but the excluded ones.
+func (mod *ArpSpoofer) internalFrames(neighbours []net.IP, targets map[string]net.HardwareAddr, restore bool) [][]byte {
+	myMAC := mod.Session.Interface.HW
+	frames := make([][]byte, 0)
+
+	if len(mod.pairs) > 0 {
+		for _, pair := range mod.pairs {
+			if mod.Session.Skip(pair.a) || mod.Session.Skip(pair.b) {
+				continue
+			}
+
+			hwA, err := mod.Session.FindMAC(pair.a, false)
+			if err != nil {
+				continue
+			}
+			hwB, err := mod.Session.FindMAC(pair.b, false)
+			if err != nil {
+				continue
+			}
+
+			macA, macB := myMAC, myMAC
+			if restore {
+				macA, macB = hwA, hwB
+			}
+			frames = append(frames, mod.spoofFrames(pair.b, macB, map[string]net.HardwareAddr{pair.a.String(): hwA}, !restore)...)
+			frames = append(frames, mod.spoofFrames(pair.a, macA, map[string]net.HardwareAddr{pair.b.String(): hwB}, !restore)...)
+		}
+		return frames
+	}
+
+	for _, address := range neighbours {
+		if mod.Session.Skip(address) {
+			continue
+		}
+
+		mac := myMAC
+		if restore {
+			realMAC, err := mod.Session.FindMAC(address, false)
+			if err != nil {
+				continue
+			}
+			mac = realMAC
+		}
+
+		spoofed := targets
+		if len(mod.excluded) > 0 {
+			spoofed = make(map[string]net.HardwareAddr, len(targets))
+			for ip, hw := range targets {
+				if !mod.isExcluded(address, net.ParseIP(ip)) {
+					spoofed[ip] = hw
+				}
+			}
+		}
+		frames = append(frames, mod.spoofFrames(address, mac, spoofed, !restore)...)
+	}
+	return frames
+}
+
+// pairTraffic is the traffic seen between two hosts of the network.
+type pairTraffic struct {
+	pair     hostPair
+	flows    int
+	packets  uint64
+	bytes    uint64
+	lastSeen time.Time
+}
+
+func (mod *ArpSpoofer) hostLabel(ip net.IP) string {
+	if e := mod.Session.Lan.GetByIp(ip.String()); e != nil && e.Alias != "" {
+		return fmt.Sprintf("%s %s", ip, tui.Dim(e.Alias))
+	}
+	return ip.String()
+}
+
+// showPairs shows the pairs of hosts whose traffic to each other is spoofed,
+// together with the traffic seen between any two hosts of the network.
+func (mod *ArpSpoofer) showPairs() error {
+	// show what would be spoofed with the current parameters
+	if !mod.Running() {
+		var err error
+		if err, mod.internal = mod.BoolParam("arp.spoof.internal"); err != nil {
+			return err
+		} else if err = mod.configurePairs(); err != nil {
+			return err
+		}
+	}
+
+	if !mod.internal {
+		return fmt.Errorf("arp.spoof.internal is false, only the traffic to and from the gateway is spoofed")
+	}
+
+	lan := mod.Session.Interface.Net
+	traffic := make(map[string]*pairTraffic)
+	for key, pair := range mod.pairs {
+		traffic[key] = &pairTraffic{pair: pair}
+	}
+
+	for _, f := range mod.Session.Flows.List() {
+		src, dst := net.ParseIP(f.SrcIP), net.ParseIP(f.DstIP)
+		if src == nil || dst == nil || lan == nil || !lan.Contains(src) || !lan.Contains(dst) {
+			continue
+		} else if mod.Session.Skip(src) || mod.Session.Skip(dst) {
+			// the traffic to and from the gateway is not internal
+			continue
+		}
+
+		pair := newHostPair(src, dst)
+		t, found := traffic[pair.key()]
+		if !found {
+			t = &pairTraffic{pair: pair}
+			traffic[pair.key()] = t
+		}
+		t.flows++
+		t.packets += f.Packets()
+		t.bytes += f.Bytes()
+		if f.LastSeen.After(t.lastSeen) {
+			t.lastSeen = f.LastSeen
+		}
+	}
+
+	if len(traffic) == 0 {
+		mod.Session.Println("no pairs of hosts defined and no traffic seen among the hosts of the network.")
+		return nil
+	}
+
+	list := make([]*pairTraffic, 0, len(traffic))
+	for _, t := range traffic {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].pair.key() < list[j].pair.key()
+	})
+
+	targets := mod.getTargets(false)
+	rows := make([][]string, 0, len(list))
+	for _, t := range list {
+		status := tui.Dim("not spoofed")
+		if fromA, fromB := mod.interception(t.pair.a, t.pair.b, targets); !mod.Running() && (fromA || fromB) {
+			status = tui.Dim("stopped")
+		} else if fromA && fromB {
+			status = tui.Green("intercepted")
+		} else if fromA {
+			status = tui.Yellow("from A only")
+		} else if fromB {
+			status = tui.Yellow("from B only")
+		} else if mod.isExcluded(t.pair.a, t.pair.b) {
+			status = tui.Red("excluded")
+		}
+
+		seen := ""
+		if !t.lastSeen.IsZero() {
+			seen = humanize.Time(t.lastSeen)
+		}
+
+		rows = append(rows, []string{
+			mod.hostLabel(t.pair.a),
+			mod.hostLabel(t.pair.b),
+			status,
+			fmt.Sprintf("%d", t.flows),
+			fmt.Sprintf("%d", t.packets),
+			humanize.Bytes(t.bytes),
+			seen,
+		})
+	}
+
+	mod.Session.Table([]string{"Host A", "Host B", "Status", "Flows", "Packets", "Bytes", "Seen"}, rows)
+	mod.Session.Println()
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package arp_spoof
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// gateway is an address the targets are told to be at our MAC address.
+type gateway struct {
+	IP net.IP
+	HW net.HardwareAddr
+}
+
+// updateGateways returns the configured gateway and, if it's the virtual
+// address of a VRRP or HSRP group and arp.spoof.redundancy is true, the
+// physical routers of the group we know the MAC address of.
+func (mod *ArpSpoofer) updateGateways() []gateway {
+	gwIP := mod.Session.Gateway.IP
+	gateways := []gateway{{gwIP, mod.Session.Gateway.HW}}
+
+	if group, found := mod.Session.Queue.Redundancy.ByVirtual(gwIP); found {
+		routers := make([]string, 0)
+		for _, r := range group.RoutersList() {
+			if r.IP.Equal(gwIP) {
+				continue
+			} else if !mod.redundancy {
+				routers = append(routers, r.IP.String())
+			} else if hw, err := mod.Session.FindMAC(r.IP, true); err != nil {
+				mod.Debug("could not resolve %s router %s: %v", group, r.IP, err)
+			} else {
+				gateways = append(gateways, gateway{r.IP, hw})
+				routers = append(routers, fmt.Sprintf("%s (%s)", r.IP, hw))
+			}
+		}
+
+		if announce := fmt.Sprintf("%s %s", group, strings.Join(routers, ", ")); announce != mod.announced {
+			mod.announced = announce
+			if mod.redundancy {
+				mod.Info("gateway %s is the virtual address of %s, also spoofing its routers: %s", gwIP, tui.Bold(group.String()), strings.Join(routers, ", "))
+			} else {
+				mod.Warning("gateway %s is the virtual address of %s (routers %s), set arp.spoof.redundancy to true to spoof them too.", gwIP, tui.Bold(group.String()), strings.Join(routers, ", "))
+			}
+		}
+	}
+
+	mod.gwLock.Lock()
+	defer mod.gwLock.Unlock()
+	mod.gateways = gateways
+	return gateways
+}
+
+// currentGateways returns the gateways of the last spoofing iteration.
+func (mod *ArpSpoofer) currentGateways() []gateway {
+	mod.gwLock.Lock()
+	defer mod.gwLock.Unlock()
+	if len(mod.gateways) == 0 {
+		return []gateway{{mod.Session.Gateway.IP, mod.Session.Gateway.HW}}
+	}
+	return mod.gateways
+}
+
+// gatewayHW returns the real MAC address of ip if it's one of the gateways.
+func (mod *ArpSpoofer) gatewayHW(ip net.IP) (net.HardwareAddr, bool) {
+	for _, gw := range mod.currentGateways() {
+		if gw.IP.Equal(ip) {
+			return gw.HW, true
+		}
+	}
+	return nil, false
+}
+
+// routersList returns the gateways other than the configured one for the
+// journal.
+func routersList(gateways []gateway) string {
+	routers := make([]string, 0)
+	for _, gw := range gateways[1:] {
+		routers = append(routers, fmt.Sprintf("%s=%s", gw.IP, gw.HW))
+	}
+	sort.Strings(routers)
+	return strings.Join(routers, ",")
+}
@@ -0,0 +1,105 @@
+// +build !windows
+// +build !darwin
+
+package ble
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/bettercap/gatt"
+)
+
+var reHCIDevice = regexp.MustCompile(`^(?:hci)?(\d+)$`)
+
+// parseHCIDevice parses hci1 or 1 as 1 and an empty string as -1, meaning
+// the first available HCI device.
+func parseHCIDevice(name string) (int, error) {
+	if name == "" {
+		return -1, nil
+	} else if m := reHCIDevice.FindStringSubmatch(name); m != nil {
+		return strconv.Atoi(m[1])
+	}
+	return -1, fmt.Errorf("invalid HCI device '%s', use hciN or N", name)
+}
+
+func hciName(devID int) string {
+	if devID < 0 {
+		return "default device"
+	}
+	return fmt.Sprintf("hci%d", devID)
+}
+
+// enumDeviceID returns the HCI device to use for ble.enum and ble.write and
+// whether it's another one than the recon device.
+func (mod *BLERecon) enumDeviceID() (int, bool, error) {
+	var err error
+	var recon, enum string
+
+	if err, recon = mod.StringParam("ble.device"); err != nil {
+		return -1, false, err
+	} else if err, enum = mod.StringParam("ble.enum.device"); err != nil {
+		return -1, false, err
+	}
+
+	reconID, err := parseHCIDevice(recon)
+	if err != nil {
+		return -1, false, err
+	}
+
+	enumID, err := parseHCIDevice(enum)
+	if err != nil {
+		return -1, false, err
+	} else if enumID == -1 {
+		enumID = reconID
+	}
+
+	return enumID, enumID != reconID, nil
+}
+
+// configureEnumDevice initializes the adapter used to connect to devices while
+// recon goes on with the other one.
+func (mod *BLERecon) configureEnumDevice(devID int) (err error) {
+	if mod.enumDevice != nil {
+		return nil
+	}
+
+	mod.Debug("initializing %s for enumeration ...", hciName(devID))
+
+	if mod.enumDevice, err = gatt.NewDevice(bleClientOptions(devID)...); err != nil {
+		mod.Debug("error while creating new gatt device: %v", err)
+		return err
+	}
+
+	mod.enumDevice.Handle(
+		gatt.PeripheralDiscovered(mod.onEnumDiscovered),
+		gatt.PeripheralConnected(mod.onPeriphConnected),
+		gatt.PeripheralDisconnected(mod.onPeriphDisconnected),
+	)
+
+	return mod.enumDevice.Init(mod.onEnumStateChanged)
+}
+
+func (mod *BLERecon) onEnumStateChanged(dev gatt.Device, s gatt.State) {
+	mod.Debug("enumeration device state changed to %v", s)
+
+	switch s {
+	case gatt.StatePoweredOn:
+		if mod.currDevice != nil {
+			dev.Scan([]gatt.UUID{}, true)
+		}
+	case gatt.StatePoweredOff:
+		mod.enumDevice = nil
+	}
+}
+
+// onEnumDiscovered connects to the device we're looking for as soon as the
+// enumeration adapter sees it, since it can only connect to known addresses.
+func (mod *BLERecon) onEnumDiscovered(p gatt.Peripheral, a *gatt.Advertisement, rssi int) {
+	if mod.currDevice != nil && !mod.connecting && p.ID() == mod.currDevice.Device.ID() {
+		mod.connecting = true
+		mod.enumDevice.StopScanning()
+		mod.enumDevice.Connect(p)
+	}
+}
@@ -10,6 +10,18 @@ var defaultBLEClientOptions = []gatt.Option{
 	gatt.LnxDeviceID(-1, true),
 }
 
+// bleClientOptions returns the options to use the HCI device with the given
+// index, -1 to use the first available one.
+func bleClientOptions(devID int) []gatt.Option {
+	if devID < 0 {
+		return defaultBLEClientOptions
+	}
+	return []gatt.Option{
+		gatt.LnxMaxConnections(255),
+		gatt.LnxDeviceID(devID, true),
+	}
+}
+
 /*
 
 var defaultBLEServerOptions = []gatt.Option{
@@ -0,0 +1,185 @@
+// +build !windows
+// +build !darwin
+
+package ble
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/bettercap/gatt"
+)
+
+// CharacteristicChange is the data of the ble.characteristic.changed event
+// sent when a polled characteristic has a new value.
+type CharacteristicChange struct {
+	MAC    string `json:"mac"`
+	Device string `json:"device"`
+	UUID   string `json:"uuid"`
+	Name   string `json:"name"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+}
+
+func parsePollUUIDs(list string) ([]gatt.UUID, error) {
+	uuids := make([]gatt.UUID, 0)
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		} else if uuid, err := gatt.ParseUUID(s); err != nil {
+			return nil, fmt.Errorf("Error parsing %s: %s", s, err)
+		} else {
+			uuids = append(uuids, uuid)
+		}
+	}
+
+	if len(uuids) == 0 {
+		return nil, fmt.Errorf("No characteristics to poll.")
+	}
+	return uuids, nil
+}
+
+func (mod *BLERecon) isPolling() bool {
+	return mod.isEnumerating() && len(mod.pollUUIDs) > 0
+}
+
+func (mod *BLERecon) startPolling(mac string, uuids []gatt.UUID) error {
+	mod.writeData = nil
+	mod.writeUUID = nil
+	mod.pollUUIDs = uuids
+	mod.pollQuit = make(chan bool, 1)
+
+	if err := mod.enumAllTheThings(mac); err != nil {
+		mod.pollUUIDs = nil
+		return err
+	}
+	return nil
+}
+
+func (mod *BLERecon) stopPolling() error {
+	if !mod.isPolling() {
+		return fmt.Errorf("No characteristics are being polled.")
+	}
+
+	select {
+	case mod.pollQuit <- true:
+	default:
+	}
+	return nil
+}
+
+// polledCharacteristics returns the readable characteristics to poll among
+// the ones of the services.
+func (mod *BLERecon) polledCharacteristics(services []*gatt.Service) []*gatt.Characteristic {
+	polled := make([]*gatt.Characteristic, 0)
+	for _, uuid := range mod.pollUUIDs {
+		found := false
+		for _, svc := range services {
+			for _, ch := range svc.Characteristics() {
+				if !uuid.Equal(ch.UUID()) {
+					continue
+				}
+
+				found = true
+				if _, isReadable, _, _ := parseProperties(ch); isReadable {
+					polled = append(polled, ch)
+				} else {
+					mod.Warning("characteristics %s is not readable.", uuid)
+				}
+			}
+		}
+
+		if !found {
+			mod.Warning("characteristics %s not found.", uuid)
+		}
+	}
+	return polled
+}
+
+// updateCharacteristic updates the data of the characteristic of the device
+// that is shown by ble.enum and returned by the API.
+func updateCharacteristic(dev *network.BLEDevice, ch *gatt.Characteristic, raw []byte) {
+	uuid := ch.UUID().String()
+	for i := range dev.Services {
+		for j := range dev.Services[i].Characteristics {
+			if char := &dev.Services[i].Characteristics[j]; char.UUID == uuid {
+				if data, multi := parseCharacteristicData(ch, raw); multi != nil {
+					char.Data = multi
+				} else {
+					char.Data = data
+				}
+			}
+		}
+	}
+}
+
+// pollCharacteristics reads the characteristics to poll every
+// ble.poll.interval seconds and sends an event for every value that changes,
+// until the polling is stopped or the device disconnects.
+func (mod *BLERecon) pollCharacteristics(p gatt.Peripheral, services []*gatt.Service) {
+	defer func() {
+		mod.pollUUIDs = nil
+	}()
+
+	err, secs := mod.IntParam("ble.poll.interval")
+	if err != nil {
+		mod.Error("%v", err)
+		return
+	}
+
+	polled := mod.polledCharacteristics(services)
+	if len(polled) == 0 {
+		mod.Error("no readable characteristics to poll for %s.", p.ID())
+		return
+	}
+
+	dev := mod.currDevice
+	interval := time.Duration(secs) * time.Second
+	values := make(map[*gatt.Characteristic][]byte)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	mod.Info("polling %d characteristics of %s every %s ...", len(polled), p.ID(), interval)
+
+	for {
+		for _, ch := range polled {
+			raw, err := p.ReadCharacteristic(ch)
+			if err != nil {
+				mod.Warning("error while reading %s, stopping: %s", ch.UUID(), err)
+				return
+			}
+
+			old, found := values[ch]
+			if found && bytes.Equal(old, raw) {
+				continue
+			} else if found {
+				mod.Session.Events.Add("ble.characteristic.changed", CharacteristicChange{
+					MAC:    p.ID(),
+					Device: dev.Name(),
+					UUID:   ch.UUID().String(),
+					Name:   ch.Name(),
+					Old:    hex.EncodeToString(old),
+					New:    hex.EncodeToString(raw),
+				})
+			}
+
+			values[ch] = raw
+			updateCharacteristic(dev, ch, raw)
+		}
+
+		select {
+		case <-mod.pollQuit:
+			mod.Info("stopped polling %s.", p.ID())
+			return
+		case <-ticker.C:
+			if mod.currDevice != dev {
+				return
+			}
+		}
+	}
+}
@@ -21,10 +21,15 @@ import (
 type BLERecon struct {
 	session.SessionModule
 	gattDevice  gatt.Device
+	enumDevice  gatt.Device
 	currDevice  *network.BLEDevice
 	writeUUID   *gatt.UUID
 	writeData   []byte
+	pollUUIDs   []gatt.UUID
+	pollQuit    chan bool
 	connected   bool
+	connecting  bool
+	separate    bool
 	connTimeout time.Duration
 	quit        chan bool
 	done        chan bool
@@ -44,6 +49,20 @@ func NewBLERecon(s *session.Session) *BLERecon {
 
 	mod.InitState("scanning")
 
+	mod.AddParam(session.NewStringParameter("ble.device",
+		"",
+		`^((hci)?\d+)?$`,
+		"HCI device to use, like hci0 or 0, leave empty to use the first available one."))
+
+	mod.AddParam(session.NewStringParameter("ble.enum.device",
+		"",
+		`^((hci)?\d+)?$`,
+		"HCI device to use for ble.enum and ble.write, if it's not the ble.device one the recon keeps running while connecting to devices, leave empty to use ble.device."))
+
+	mod.AddParam(session.NewIntParameter("ble.poll.interval",
+		"5",
+		"Seconds between the reads of the characteristics polled by ble.poll."))
+
 	mod.selector = utils.ViewSelectorFor(&mod.SessionModule,
 		"ble.show",
 		[]string{"rssi", "mac", "seen"}, "rssi asc")
@@ -76,12 +95,15 @@ func NewBLERecon(s *session.Session) *BLERecon {
 	enum := session.NewModuleHandler("ble.enum MAC", "ble.enum "+network.BLEMacValidator,
 		"Enumerate services and characteristics for the given BLE device.",
 		func(args []string) error {
-			if mod.isEnumerating() {
+			if mod.isPolling() {
+				return fmt.Errorf("The characteristics of %s are being polled, use ble.poll off first.", mod.currDevice.Device.ID())
+			} else if mod.isEnumerating() {
 				return fmt.Errorf("An enumeration for %s is already running, please wait.", mod.currDevice.Device.ID())
 			}
 
 			mod.writeData = nil
 			mod.writeUUID = nil
+			mod.pollUUIDs = nil
 
 			return mod.enumAllTheThings(network.NormalizeMac(args[0]))
 		})
@@ -110,6 +132,31 @@ func NewBLERecon(s *session.Session) *BLERecon {
 
 	mod.AddHandler(write)
 
+	poll := session.NewModuleHandler("ble.poll MAC UUIDS", "ble.poll "+network.BLEMacValidator+" ([a-fA-F0-9\\-,]+)",
+		"Connect to the BLE device with the specified MAC address and read the characteristics with the given comma separated UUIDs every ble.poll.interval seconds, sending an event when their value changes.",
+		func(args []string) error {
+			if mod.isEnumerating() {
+				return fmt.Errorf("An enumeration for %s is already running, please wait.", mod.currDevice.Device.ID())
+			}
+
+			uuids, err := parsePollUUIDs(args[1])
+			if err != nil {
+				return err
+			}
+
+			return mod.startPolling(network.NormalizeMac(args[0]), uuids)
+		})
+
+	poll.Complete("ble.poll", s.BLECompleter)
+
+	mod.AddHandler(poll)
+
+	mod.AddHandler(session.NewModuleHandler("ble.poll off", "",
+		"Stop polling the characteristics and disconnect from the device.",
+		func(args []string) error {
+			return mod.stopPolling()
+		}))
+
 	return mod
 }
 
@@ -139,14 +186,23 @@ func (w dummyWriter) Write(p []byte) (n int, err error) {
 }
 
 func (mod *BLERecon) Configure() (err error) {
+	var device string
+	var devID int
+
 	if mod.Running() {
 		return session.ErrAlreadyStarted
 	} else if mod.gattDevice == nil {
-		mod.Debug("initializing device ...")
+		if err, device = mod.StringParam("ble.device"); err != nil {
+			return err
+		} else if devID, err = parseHCIDevice(device); err != nil {
+			return err
+		}
+
+		mod.Debug("initializing %s ...", hciName(devID))
 
 		golog.SetFlags(0)
 		golog.SetOutput(dummyWriter{mod})
-		if mod.gattDevice, err = gatt.NewDevice(defaultBLEClientOptions...); err != nil {
+		if mod.gattDevice, err = gatt.NewDevice(bleClientOptions(devID)...); err != nil {
 			mod.Debug("error while creating new gatt device: %v", err)
 			return err
 		}
@@ -197,6 +253,10 @@ func (mod *BLERecon) Stop() error {
 		<-mod.done
 		mod.Debug("module stopped, cleaning state")
 		mod.gattDevice = nil
+		if mod.enumDevice != nil {
+			mod.enumDevice.Stop()
+			mod.enumDevice = nil
+		}
 		mod.setCurrentDevice(nil)
 		mod.ResetState()
 	})
@@ -218,26 +278,43 @@ func (mod *BLERecon) pruner() {
 
 func (mod *BLERecon) setCurrentDevice(dev *network.BLEDevice) {
 	mod.connected = false
+	mod.connecting = false
 	mod.currDevice = dev
 	mod.State.Store("scanning", dev)
 }
 
 func (mod *BLERecon) writeBuffer(mac string, uuid gatt.UUID, data []byte) error {
+	mod.pollUUIDs = nil
 	mod.writeUUID = &uuid
 	mod.writeData = data
 	return mod.enumAllTheThings(mac)
 }
 
 func (mod *BLERecon) enumAllTheThings(mac string) error {
+	enumID, separate, err := mod.enumDeviceID()
+	if err != nil {
+		return err
+	}
+
 	dev, found := mod.Session.BLE.Get(mac)
 	if !found || dev == nil {
 		return fmt.Errorf("BLE device with address %s not found.", mac)
-	} else if mod.Running() {
+	} else if mod.Running() && !separate {
 		mod.gattDevice.StopScanning()
 	}
 
+	mod.separate = separate
 	mod.setCurrentDevice(dev)
-	if err := mod.Configure(); err != nil && err != session.ErrAlreadyStarted {
+	if separate {
+		// a new adapter starts scanning for the device once powered on
+		ready := mod.enumDevice != nil
+		if err := mod.configureEnumDevice(enumID); err != nil {
+			mod.setCurrentDevice(nil)
+			return err
+		} else if ready {
+			mod.enumDevice.Scan([]gatt.UUID{}, true)
+		}
+	} else if err := mod.Configure(); err != nil && err != session.ErrAlreadyStarted {
 		return err
 	}
 
@@ -251,7 +328,11 @@ func (mod *BLERecon) enumAllTheThings(mac string) error {
 		}
 	}()
 
-	mod.gattDevice.Connect(dev.Device)
+	if separate {
+		mod.Info("looking for %s on %s ...", mac, hciName(enumID))
+	} else {
+		mod.gattDevice.Connect(dev.Device)
+	}
 
 	return nil
 }
@@ -36,7 +36,11 @@ func (mod *BLERecon) onPeriphDiscovered(p gatt.Peripheral, a *gatt.Advertisement
 func (mod *BLERecon) onPeriphDisconnected(p gatt.Peripheral, err error) {
 	mod.Session.Events.Add("ble.device.disconnected", mod.currDevice)
 	mod.setCurrentDevice(nil)
-	if mod.Running() {
+	if mod.separate {
+		if mod.enumDevice != nil {
+			mod.enumDevice.StopScanning()
+		}
+	} else if mod.Running() {
 		mod.Info("device disconnected, restoring discovery.")
 		mod.gattDevice.Scan([]gatt.UUID{}, true)
 	}
@@ -73,4 +77,8 @@ func (mod *BLERecon) onPeriphConnected(p gatt.Peripheral, err error) {
 	}
 
 	mod.showServices(p, services)
+
+	if len(mod.pollUUIDs) > 0 {
+		mod.pollCharacteristics(p, services)
+	}
 }
@@ -4,7 +4,6 @@
 package ble
 
 import (
-	"os"
 	"sort"
 	"time"
 
@@ -147,7 +146,7 @@ func (mod *BLERecon) Show() error {
 	}
 
 	if len(rows) > 0 {
-		tui.Table(os.Stdout, mod.colNames(hasName), rows)
+		mod.Session.Table(mod.colNames(hasName), rows)
 		mod.Session.Refresh()
 	}
 
@@ -6,7 +6,6 @@ package ble
 import (
 	"encoding/binary"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 
@@ -270,6 +269,24 @@ func parsePrivacyFlag(raw []byte) string {
 	return tui.Red("Privacy Enabled")
 }
 
+// parseCharacteristicData decodes the value of the characteristics whose
+// format is known, and shows the raw data of the others.
+func parseCharacteristicData(ch *gatt.Characteristic, raw []byte) (data string, multi []string) {
+	sz := len(raw)
+	if ch.Name() == "Appearance" && sz >= 2 {
+		data = parseAppearance(raw)
+	} else if ch.Name() == "PnP ID" && sz >= 7 {
+		multi = parsePNPID(raw)
+	} else if ch.Name() == "Peripheral Preferred Connection Parameters" && sz >= 8 {
+		multi = parseConnectionParams(raw)
+	} else if ch.Name() == "Peripheral Privacy Flag" && sz >= 1 {
+		data = parsePrivacyFlag(raw)
+	} else {
+		data = parseRawData(raw)
+	}
+	return
+}
+
 func (mod *BLERecon) showServices(p gatt.Peripheral, services []*gatt.Service) {
 	columns := []string{"Handles", "Service > Characteristics", "Properties", "Data"}
 	rows := make([][]string, 0)
@@ -337,34 +354,27 @@ func (mod *BLERecon) showServices(p gatt.Peripheral, services []*gatt.Service) {
 						mod.Warning("attempt to write %d bytes to non writable characteristics %s ...", len(mod.writeData), mod.writeUUID)
 					}
 
-					if err := p.WriteCharacteristic(ch, mod.writeData, !withResponse); err != nil {
-						mod.Error("error while writing: %s", err)
+					if mod.Session.MACInScope(p.ID(), "BLE write") {
+						if mod.Session.IsDryRun() {
+							mod.Session.DryRunAction("%d bytes to characteristics %s of %s", len(mod.writeData), mod.writeUUID, p.ID())
+						} else if err := p.WriteCharacteristic(ch, mod.writeData, !withResponse); err != nil {
+							mod.Error("error while writing: %s", err)
+						}
 					}
 				}
 
-				sz := 0
 				raw := ([]byte)(nil)
 				err := error(nil)
 				if isReadable {
-					if raw, err = p.ReadCharacteristic(ch); raw != nil {
-						sz = len(raw)
-					}
+					raw, err = p.ReadCharacteristic(ch)
 				}
 
 				data := ""
 				multi := ([]string)(nil)
 				if err != nil {
 					data = tui.Red(err.Error())
-				} else if ch.Name() == "Appearance" && sz >= 2 {
-					data = parseAppearance(raw)
-				} else if ch.Name() == "PnP ID" && sz >= 7 {
-					multi = parsePNPID(raw)
-				} else if ch.Name() == "Peripheral Preferred Connection Parameters" && sz >= 8 {
-					multi = parseConnectionParams(raw)
-				} else if ch.Name() == "Peripheral Privacy Flag" && sz >= 1 {
-					data = parsePrivacyFlag(raw)
 				} else {
-					data = parseRawData(raw)
+					data, multi = parseCharacteristicData(ch, raw)
 				}
 
 				if ch.Name() == "Device Name" && data != "" && mod.currDevice.DeviceName == "" {
@@ -407,7 +417,7 @@ func (mod *BLERecon) showServices(p gatt.Peripheral, services []*gatt.Service) {
 	if wantsToWrite && !foundToWrite {
 		mod.Error("writable characteristics %s not found.", mod.writeUUID)
 	} else {
-		tui.Table(os.Stdout, columns, rows)
+		mod.Session.Table(columns, rows)
 		mod.Session.Refresh()
 	}
 }
@@ -0,0 +1,152 @@
+package bt_recon
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/modules/utils"
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+)
+
+var reHCIDevice = regexp.MustCompile(`^(?:hci)?(\d+)$`)
+
+type BTRecon struct {
+	session.SessionModule
+	fd            int
+	devID         int
+	inquiryLength byte
+	resolveNames  bool
+	devTTL        time.Duration
+	// inquiry results of the devices to resolve the name of
+	names     []packets.HCIInquiryResult
+	resolved  map[string]bool
+	deadline  time.Time
+	waitGroup *sync.WaitGroup
+	selector  *utils.ViewSelector
+}
+
+func NewBTRecon(s *session.Session) *BTRecon {
+	mod := &BTRecon{
+		SessionModule: session.NewSessionModule("bt.recon", s),
+		fd:            -1,
+		waitGroup:     &sync.WaitGroup{},
+	}
+
+	mod.AddParam(session.NewStringParameter("bt.device",
+		"",
+		`^((hci)?\d+)?$`,
+		"HCI device to use, like hci0 or 0, leave empty to use hci0 (the device can't be used by ble.recon at the same time)."))
+
+	mod.AddParam(session.NewIntParameter("bt.recon.inquiry.length",
+		"8",
+		"Duration of each inquiry in units of 1.28 seconds, from 1 to 48."))
+
+	mod.AddParam(session.NewBoolParameter("bt.recon.names",
+		"true",
+		"Page the devices that don't send their name in the inquiry response to resolve it."))
+
+	mod.AddParam(session.NewIntParameter("bt.ttl",
+		"120",
+		"Seconds a device is kept after it last answered an inquiry."))
+
+	mod.AddHandler(session.NewModuleHandler("bt.recon on", "",
+		"Start Bluetooth Classic (BR/EDR) devices discovery.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("bt.recon off", "",
+		"Stop Bluetooth Classic (BR/EDR) devices discovery.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("bt.clear", "",
+		"Clear all devices collected by the Bluetooth Classic discovery module.",
+		func(args []string) error {
+			mod.Session.BT.Clear()
+			return nil
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("bt.show", "",
+		"Show discovered Bluetooth Classic devices.",
+		func(args []string) error {
+			return mod.Show()
+		}))
+
+	mod.selector = utils.ViewSelectorFor(&mod.SessionModule,
+		"bt.show",
+		[]string{"rssi", "mac", "seen"}, "rssi asc")
+
+	return mod
+}
+
+func (mod *BTRecon) Name() string {
+	return "bt.recon"
+}
+
+func (mod *BTRecon) Description() string {
+	return "Bluetooth Classic (BR/EDR) devices discovery with HCI inquiries."
+}
+
+func (mod *BTRecon) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func parseHCIDevice(name string) (int, error) {
+	if name == "" {
+		return 0, nil
+	} else if m := reHCIDevice.FindStringSubmatch(name); m != nil {
+		return strconv.Atoi(m[1])
+	}
+	return -1, fmt.Errorf("invalid HCI device '%s', use hciN or N", name)
+}
+
+func (mod *BTRecon) configure() (err error) {
+	var device string
+	var length, ttl int
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted
+	} else if err, device = mod.StringParam("bt.device"); err != nil {
+		return err
+	} else if err, length = mod.IntParam("bt.recon.inquiry.length"); err != nil {
+		return err
+	} else if err, mod.resolveNames = mod.BoolParam("bt.recon.names"); err != nil {
+		return err
+	} else if err, ttl = mod.IntParam("bt.ttl"); err != nil {
+		return err
+	} else if mod.devID, err = parseHCIDevice(device); err != nil {
+		return err
+	} else if length < 1 || length > 48 {
+		return fmt.Errorf("bt.recon.inquiry.length must be between 1 and 48")
+	}
+
+	mod.inquiryLength = byte(length)
+	mod.devTTL = time.Duration(ttl) * time.Second
+	mod.names = make([]packets.HCIInquiryResult, 0)
+	mod.resolved = make(map[string]bool)
+
+	return nil
+}
+
+func (mod *BTRecon) pruner() {
+	mod.waitGroup.Add(1)
+	defer mod.waitGroup.Done()
+
+	mod.Debug("started devices pruner ...")
+
+	for mod.Running() {
+		for _, dev := range mod.Session.BT.Devices() {
+			if sinceLastSeen := time.Since(dev.LastSeen); sinceLastSeen > mod.devTTL {
+				mod.Debug("device %s not seen in %s, removing.", dev.Address, sinceLastSeen)
+				mod.Session.BT.Remove(dev.Address)
+			}
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
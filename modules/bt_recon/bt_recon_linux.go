@@ -0,0 +1,174 @@
+package bt_recon
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/bettercap/gatt/linux/socket"
+)
+
+// events we need from the controller
+var btEvents = []byte{
+	packets.HCIEventInquiryComplete,
+	packets.HCIEventInquiryResult,
+	packets.HCIEventRemoteNameComplete,
+	packets.HCIEventCommandStatus,
+	packets.HCIEventInquiryResultRSSI,
+	packets.HCIEventExtendedInquiryResult,
+}
+
+func (mod *BTRecon) Configure() (err error) {
+	if err = mod.configure(); err != nil {
+		return err
+	}
+
+	// the raw channel shares the controller with bluetoothd instead of
+	// taking exclusive control of it like the user one used by ble.recon
+	if mod.fd, err = socket.Socket(socket.AF_BLUETOOTH, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, socket.BTPROTO_HCI); err != nil {
+		return fmt.Errorf("could not open HCI socket: %v", err)
+	}
+
+	filter := &socket.HCIFilter{TypeMask: 1 << packets.HCIEventPkt}
+	for _, code := range btEvents {
+		filter.EventMask[code>>5] |= 1 << (code & 31)
+	}
+
+	timeout := syscall.NsecToTimeval(time.Second.Nanoseconds())
+	if err = socket.Bind(mod.fd, &socket.SockaddrHCI{Dev: mod.devID, Channel: socket.HCI_CHANNEL_RAW}); err != nil {
+		err = fmt.Errorf("could not bind to hci%d: %v", mod.devID, err)
+	} else if err = socket.SetsockoptFilter(mod.fd, filter); err != nil {
+		err = fmt.Errorf("could not set the HCI filter: %v", err)
+	} else if err = syscall.SetsockoptTimeval(mod.fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		err = fmt.Errorf("could not set the HCI socket timeout: %v", err)
+	} else if err = mod.send(packets.HCIWriteInquiryMode(packets.HCIInquiryModeExtended)); err != nil {
+		err = fmt.Errorf("could not set the inquiry mode of hci%d (is it up?): %v", mod.devID, err)
+	}
+
+	if err != nil {
+		syscall.Close(mod.fd)
+		mod.fd = -1
+	}
+
+	return err
+}
+
+func (mod *BTRecon) send(pkt []byte) error {
+	_, err := syscall.Write(mod.fd, pkt)
+	return err
+}
+
+// next resolves the name of the next queued device or starts a new inquiry,
+// the controller can only do one at a time.
+func (mod *BTRecon) next() {
+	if len(mod.names) > 0 {
+		r := mod.names[0]
+		mod.names = mod.names[1:]
+		mod.Debug("resolving the name of %s ...", r.Address)
+		// the page timeout is about 5 seconds by default
+		mod.deadline = time.Now().Add(10 * time.Second)
+		if err := mod.send(packets.HCIRemoteNameRequest(r.Address, r.PageScanMode, r.ClockOffset)); err != nil {
+			mod.Warning("could not send the remote name request: %v", err)
+			mod.deadline = time.Now().Add(time.Second)
+		}
+		return
+	}
+
+	inquiryTime := time.Duration(mod.inquiryLength) * 1280 * time.Millisecond
+	mod.deadline = time.Now().Add(inquiryTime + 5*time.Second)
+	if err := mod.send(packets.HCIInquiry(mod.inquiryLength, 0)); err != nil {
+		mod.Warning("could not start the inquiry: %v", err)
+		mod.deadline = time.Now().Add(time.Second)
+	}
+}
+
+func (mod *BTRecon) onInquiryResult(r packets.HCIInquiryResult) {
+	mac := r.Address.String()
+	isNew, dev := mod.Session.BT.AddIfNew(mac, r.Class, int(r.RSSI), r.Name)
+	if isNew {
+		mod.Debug("new device %s (%s)", mac, dev.Type())
+	}
+
+	if mod.resolveNames && dev.Name == "" && !mod.resolved[mac] {
+		// only try once, devices not connectable won't answer anyway
+		mod.resolved[mac] = true
+		mod.names = append(mod.names, r)
+	}
+}
+
+func (mod *BTRecon) onEvent(code byte, params []byte) {
+	switch code {
+	case packets.HCIEventInquiryResult, packets.HCIEventInquiryResultRSSI, packets.HCIEventExtendedInquiryResult:
+		for _, r := range packets.HCIParseInquiryResults(code, params) {
+			mod.onInquiryResult(r)
+		}
+
+	case packets.HCIEventInquiryComplete:
+		mod.next()
+
+	case packets.HCIEventRemoteNameComplete:
+		if ok, addr, name := packets.HCIParseRemoteName(params); ok {
+			mod.Debug("%s is %s", addr, name)
+			mod.Session.BT.SetName(addr.String(), name)
+		}
+		mod.next()
+
+	case packets.HCIEventCommandStatus:
+		if ok, status, opcode := packets.HCIParseCommandStatus(params); ok && status != 0 {
+			if opcode == packets.HCIOpInquiry || opcode == packets.HCIOpRemoteNameRequest {
+				mod.Debug("command 0x%04x failed with status 0x%02x", opcode, status)
+				// retry or move on after a while, the controller might be busy
+				mod.deadline = time.Now().Add(time.Second)
+			}
+		}
+	}
+}
+
+func (mod *BTRecon) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.waitGroup.Add(1)
+		defer mod.waitGroup.Done()
+
+		go mod.pruner()
+
+		mod.Info("inquiring on hci%d ...", mod.devID)
+
+		buf := make([]byte, 260)
+		mod.next()
+		for mod.Running() {
+			n, err := syscall.Read(mod.fd, buf)
+			if err != nil && err != syscall.EAGAIN && err != syscall.EINTR {
+				mod.Error("error reading from hci%d: %v", mod.devID, err)
+				go mod.Stop()
+				return
+			} else if n > 0 {
+				if code, params, err := packets.HCIParseEvent(buf[:n]); err != nil {
+					mod.Debug("%v", err)
+				} else {
+					mod.onEvent(code, params)
+				}
+			}
+
+			if time.Now().After(mod.deadline) {
+				mod.next()
+			}
+		}
+	})
+}
+
+func (mod *BTRecon) Stop() error {
+	return mod.SetRunning(false, func() {
+		mod.waitGroup.Wait()
+		if mod.fd != -1 {
+			mod.send(packets.HCIInquiryCancel())
+			syscall.Close(mod.fd)
+			mod.fd = -1
+		}
+	})
+}
@@ -0,0 +1,19 @@
+// +build !linux
+
+package bt_recon
+
+import (
+	"github.com/bettercap/bettercap/session"
+)
+
+func (mod *BTRecon) Configure() error {
+	return session.ErrNotSupported
+}
+
+func (mod *BTRecon) Start() error {
+	return session.ErrNotSupported
+}
+
+func (mod *BTRecon) Stop() error {
+	return session.ErrNotSupported
+}
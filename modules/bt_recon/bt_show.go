@@ -0,0 +1,138 @@
+package bt_recon
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+var (
+	btAliveInterval   = time.Duration(30) * time.Second
+	btPresentInterval = time.Duration(60) * time.Second
+)
+
+func (mod *BTRecon) getRow(dev *network.BTDevice) []string {
+	rssi := tui.Dim("?")
+	if dev.RSSI != 0 {
+		rssi = network.ColorRSSI(dev.RSSI)
+	}
+
+	address := dev.Address
+	sinceSeen := time.Since(dev.LastSeen)
+	lastSeen := dev.LastSeen.Format("15:04:05")
+
+	if sinceSeen <= btAliveInterval {
+		lastSeen = tui.Bold(lastSeen)
+	} else if sinceSeen > btPresentInterval {
+		lastSeen = tui.Dim(lastSeen)
+		address = tui.Dim(address)
+	}
+
+	return []string{
+		rssi,
+		address,
+		tui.Yellow(dev.Name),
+		tui.Dim(dev.Vendor),
+		dev.Type(),
+		strings.Join(dev.Services, ", "),
+		lastSeen,
+	}
+}
+
+func (mod *BTRecon) doFilter(dev *network.BTDevice) bool {
+	if mod.selector.Expression == nil {
+		return true
+	}
+	return mod.selector.Expression.MatchString(dev.Address) ||
+		mod.selector.Expression.MatchString(dev.Name) ||
+		mod.selector.Expression.MatchString(dev.Vendor) ||
+		mod.selector.Expression.MatchString(dev.Type())
+}
+
+func (mod *BTRecon) doSelection() (err error, devices []*network.BTDevice) {
+	if err = mod.selector.Update(); err != nil {
+		return
+	}
+
+	filtered := []*network.BTDevice{}
+	for _, dev := range mod.Session.BT.Devices() {
+		if mod.doFilter(dev) {
+			filtered = append(filtered, dev)
+		}
+	}
+	devices = filtered
+
+	switch mod.selector.SortField {
+	case "mac":
+		sort.Slice(devices, func(i, j int) bool {
+			return devices[i].Address < devices[j].Address
+		})
+	case "seen":
+		sort.Slice(devices, func(i, j int) bool {
+			return devices[i].LastSeen.Before(devices[j].LastSeen)
+		})
+	default:
+		sort.Slice(devices, func(i, j int) bool {
+			if devices[i].RSSI == devices[j].RSSI {
+				return devices[i].Address < devices[j].Address
+			}
+			return devices[i].RSSI > devices[j].RSSI
+		})
+	}
+
+	// default is asc
+	if mod.selector.Sort == "desc" {
+		// from https://github.com/golang/go/wiki/SliceTricks
+		for i := len(devices)/2 - 1; i >= 0; i-- {
+			opp := len(devices) - 1 - i
+			devices[i], devices[opp] = devices[opp], devices[i]
+		}
+	}
+
+	if mod.selector.Limit > 0 {
+		limit := mod.selector.Limit
+		max := len(devices)
+		if limit > max {
+			limit = max
+		}
+		devices = devices[0:limit]
+	}
+
+	return
+}
+
+func (mod *BTRecon) colNames() []string {
+	colNames := []string{"RSSI", "MAC", "Name", "Vendor", "Class", "Services", "Seen"}
+	switch mod.selector.SortField {
+	case "rssi":
+		colNames[0] += " " + mod.selector.SortSymbol
+	case "mac":
+		colNames[1] += " " + mod.selector.SortSymbol
+	case "seen":
+		colNames[6] += " " + mod.selector.SortSymbol
+	}
+	return colNames
+}
+
+func (mod *BTRecon) Show() error {
+	err, devices := mod.doSelection()
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0)
+	for _, dev := range devices {
+		rows = append(rows, mod.getRow(dev))
+	}
+
+	if len(rows) > 0 {
+		mod.Session.Table(mod.colNames(), rows)
+		mod.Session.Refresh()
+	}
+
+	return nil
+}
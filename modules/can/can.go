@@ -0,0 +1,270 @@
+package can
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/modules/utils"
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/fs"
+)
+
+const readTimeout = 500 * time.Millisecond
+
+type CANModule struct {
+	session.SessionModule
+	deviceName string
+	dbcPath    string
+	dbc        *network.DBC
+	socket     *canSocket
+	writeLock  *sync.Mutex
+	waitGroup  *sync.WaitGroup
+	selector   *utils.ViewSelector
+}
+
+func NewCANModule(s *session.Session) *CANModule {
+	mod := &CANModule{
+		SessionModule: session.NewSessionModule("can", s),
+		deviceName:    "can0",
+		writeLock:     &sync.Mutex{},
+		waitGroup:     &sync.WaitGroup{},
+	}
+
+	mod.AddParam(session.NewStringParameter("can.device",
+		mod.deviceName,
+		"",
+		"SocketCAN interface to use."))
+
+	mod.AddParam(session.NewStringParameter("can.dbc.path",
+		"",
+		"",
+		"Optional path to a DBC file used to decode the signals of known messages."))
+
+	mod.AddHandler(session.NewModuleHandler("can.recon on", "",
+		"Start sniffing CAN frames and collecting statistics per arbitration id.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("can.recon off", "",
+		"Stop sniffing CAN frames.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("can.clear", "",
+		"Clear all the arbitration ids collected by the CAN module.",
+		func(args []string) error {
+			mod.Session.CAN.Clear()
+			return nil
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("can.show", "",
+		"Show the statistics of every arbitration id seen so far.",
+		func(args []string) error {
+			return mod.Show()
+		}))
+
+	showID := session.NewModuleHandler("can.show ID", `(?i)^can\.show ([a-f0-9]{3}|[a-f0-9]{8})$`,
+		"Show the last payload and, if a DBC file was loaded, the decoded signals of the arbitration ID.",
+		func(args []string) error {
+			return mod.showMessage(args[0])
+		})
+
+	showID.Complete("can.show", s.CANCompleter)
+
+	mod.AddHandler(showID)
+
+	mod.AddHandler(session.NewModuleHandler("can.inject FRAME", `(?i)^can\.inject ([a-f0-9]+#\S*)$`,
+		"Send a frame in the ID#DATA notation of cansend, e.g. 123#DEADBEEF or 7df#R for a remote request.",
+		func(args []string) error {
+			err, frame := packets.CANParse(args[0])
+			if err != nil {
+				return err
+			}
+			return mod.inject(frame)
+		}))
+
+	replay := session.NewModuleHandler("can.replay ID TIMES?", `(?i)^can\.replay ([a-f0-9]{3}|[a-f0-9]{8})\s*(\d*)$`,
+		"Resend the last payload seen for the arbitration ID, optionally TIMES times at the rate it was observed.",
+		func(args []string) error {
+			times := 1
+			if args[1] != "" {
+				times, _ = strconv.Atoi(args[1])
+			}
+			return mod.replay(args[0], times)
+		})
+
+	replay.Complete("can.replay", s.CANCompleter)
+
+	mod.AddHandler(replay)
+
+	mod.selector = utils.ViewSelectorFor(&mod.SessionModule, "can.show", []string{"id", "frames", "seen"}, "id asc")
+
+	return mod
+}
+
+func (mod *CANModule) Name() string {
+	return "can"
+}
+
+func (mod *CANModule) Description() string {
+	return "A SocketCAN module to sniff, decode, replay and inject CAN bus frames."
+}
+
+func (mod *CANModule) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *CANModule) Configure() (err error) {
+	if mod.Running() {
+		return session.ErrAlreadyStarted
+	} else if err, mod.deviceName = mod.StringParam("can.device"); err != nil {
+		return err
+	} else if err, mod.dbcPath = mod.StringParam("can.dbc.path"); err != nil {
+		return err
+	}
+
+	mod.dbc = nil
+	if mod.dbcPath != "" {
+		if mod.dbcPath, err = fs.Expand(mod.dbcPath); err != nil {
+			return err
+		} else if err, mod.dbc = network.LoadDBC(mod.dbcPath); err != nil {
+			return err
+		}
+		mod.Info("loaded %d message definitions from %s", len(mod.dbc.Messages), mod.dbcPath)
+	}
+
+	if err, mod.socket = openSocket(mod.deviceName, readTimeout); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (mod *CANModule) onFrame(frame *packets.CANFrame) {
+	name := ""
+	var def *network.DBCMessage
+	if mod.dbc != nil {
+		if msg, found := mod.dbc.Lookup(frame.ID); found {
+			def = msg
+			name = msg.Name
+		}
+	}
+
+	_, msg := mod.Session.CAN.AddIfNew(frame.ID, frame.IDString(), frame.Extended, name, frame.Data)
+	if def != nil {
+		msg.Lock()
+		for k, v := range def.Decode(frame.Data) {
+			msg.Signals[k] = v
+		}
+		msg.Unlock()
+	}
+}
+
+func (mod *CANModule) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.waitGroup.Add(1)
+		defer mod.waitGroup.Done()
+
+		mod.Info("sniffing frames on %s", mod.deviceName)
+
+		for mod.Running() {
+			err, frame := mod.socket.Read()
+			if err != nil {
+				mod.Warning("error reading from %s: %v", mod.deviceName, err)
+				continue
+			} else if frame == nil || frame.Error {
+				continue
+			}
+
+			mod.onFrame(frame)
+		}
+	})
+}
+
+func (mod *CANModule) Stop() error {
+	return mod.SetRunning(false, func() {
+		mod.waitGroup.Wait()
+		mod.writeLock.Lock()
+		defer mod.writeLock.Unlock()
+		if mod.socket != nil {
+			mod.socket.Close()
+			mod.socket = nil
+		}
+	})
+}
+
+func (mod *CANModule) send(frame *packets.CANFrame) (err error) {
+	mod.writeLock.Lock()
+	defer mod.writeLock.Unlock()
+
+	sock := mod.socket
+	if sock == nil {
+		// not sniffing, use a temporary socket
+		if err, mod.deviceName = mod.StringParam("can.device"); err != nil {
+			return err
+		} else if err, sock = openSocket(mod.deviceName, readTimeout); err != nil {
+			return err
+		}
+		defer sock.Close()
+	}
+
+	return sock.Write(frame)
+}
+
+func (mod *CANModule) inject(frame *packets.CANFrame) error {
+	if mod.Session.IsDryRun() {
+		mod.Session.DryRunAction("send CAN frame %s", frame)
+		return nil
+	} else if err := mod.send(frame); err != nil {
+		return fmt.Errorf("error sending %s: %v", frame, err)
+	}
+
+	mod.Info("sent %s", frame)
+	return nil
+}
+
+func (mod *CANModule) replay(id string, times int) error {
+	msg, found := mod.Session.CAN.Get(id)
+	if !found {
+		return fmt.Errorf("arbitration id %s not found", id)
+	} else if times < 1 {
+		return fmt.Errorf("invalid number of frames %d", times)
+	}
+
+	msg.Lock()
+	frame := &packets.CANFrame{
+		ID:       msg.ID,
+		Extended: msg.Extended,
+		Data:     append([]byte{}, msg.Data...),
+	}
+	interval := msg.Interval
+	msg.Unlock()
+
+	if mod.Session.IsDryRun() {
+		mod.Session.DryRunAction("replay CAN frame %s %d times", frame, times)
+		return nil
+	}
+
+	for i := 0; i < times; i++ {
+		if err := mod.send(frame); err != nil {
+			return fmt.Errorf("error replaying %s: %v", frame, err)
+		}
+		if i < times-1 && interval > 0 {
+			time.Sleep(interval)
+		}
+	}
+
+	mod.Info("replayed %s %d times", frame, times)
+	return nil
+}
@@ -0,0 +1,188 @@
+package can
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+var (
+	PresentTimeInterval    = time.Duration(1) * time.Minute
+	JustJoinedTimeInterval = time.Duration(10) * time.Second
+)
+
+// payloadString returns the hex payload with the bytes
+// that changed over time highlighted.
+func payloadString(msg *network.CANMessage) string {
+	changed := msg.Changed()
+	octets := make([]string, 0)
+	for i, b := range msg.Data {
+		octet := fmt.Sprintf("%02x", b)
+		if i < len(changed) && changed[i] != 0 {
+			octet = tui.Yellow(octet)
+		}
+		octets = append(octets, octet)
+	}
+	return strings.Join(octets, " ")
+}
+
+func (mod *CANModule) getRow(msg *network.CANMessage) []string {
+	payload := payloadString(msg)
+
+	msg.Lock()
+	defer msg.Unlock()
+
+	sinceLastSeen := time.Since(msg.LastSeen)
+	seen := msg.LastSeen.Format("15:04:05")
+	if sinceLastSeen <= JustJoinedTimeInterval {
+		seen = tui.Bold(seen)
+	} else if sinceLastSeen > PresentTimeInterval {
+		seen = tui.Dim(seen)
+	}
+
+	interval := ""
+	if msg.Interval > 0 {
+		interval = msg.Interval.Round(time.Millisecond).String()
+	}
+
+	return []string{
+		msg.Address,
+		msg.Name,
+		fmt.Sprintf("%d", len(msg.Data)),
+		payload,
+		fmt.Sprintf("%d", msg.Frames),
+		interval,
+		seen,
+	}
+}
+
+func (mod *CANModule) doFilter(msg *network.CANMessage) bool {
+	if mod.selector.Expression == nil {
+		return true
+	}
+	return mod.selector.Expression.MatchString(msg.Address) ||
+		mod.selector.Expression.MatchString(msg.Name)
+}
+
+func (mod *CANModule) doSelection() (err error, messages []*network.CANMessage) {
+	if err = mod.selector.Update(); err != nil {
+		return
+	}
+
+	filtered := []*network.CANMessage{}
+	for _, msg := range mod.Session.CAN.Messages() {
+		if mod.doFilter(msg) {
+			filtered = append(filtered, msg)
+		}
+	}
+	messages = filtered
+
+	switch mod.selector.SortField {
+	case "id":
+		sort.Slice(messages, func(i, j int) bool {
+			return messages[i].ID < messages[j].ID
+		})
+	case "frames":
+		sort.Slice(messages, func(i, j int) bool {
+			return messages[i].Frames < messages[j].Frames
+		})
+	case "seen":
+		sort.Slice(messages, func(i, j int) bool {
+			return messages[i].LastSeen.Before(messages[j].LastSeen)
+		})
+	}
+
+	// default is asc
+	if mod.selector.Sort == "desc" {
+		// from https://github.com/golang/go/wiki/SliceTricks
+		for i := len(messages)/2 - 1; i >= 0; i-- {
+			opp := len(messages) - 1 - i
+			messages[i], messages[opp] = messages[opp], messages[i]
+		}
+	}
+
+	if mod.selector.Limit > 0 {
+		limit := mod.selector.Limit
+		max := len(messages)
+		if limit > max {
+			limit = max
+		}
+		messages = messages[0:limit]
+	}
+
+	return
+}
+
+func (mod *CANModule) colNames() []string {
+	colNames := []string{"ID", "Name", "DLC", "Data", "Frames", "Interval", "Seen"}
+	switch mod.selector.SortField {
+	case "id":
+		colNames[0] += " " + mod.selector.SortSymbol
+	case "frames":
+		colNames[4] += " " + mod.selector.SortSymbol
+	case "seen":
+		colNames[6] += " " + mod.selector.SortSymbol
+	}
+	return colNames
+}
+
+func (mod *CANModule) Show() (err error) {
+	var messages []*network.CANMessage
+	if err, messages = mod.doSelection(); err != nil {
+		return
+	}
+
+	rows := make([][]string, 0)
+	for _, msg := range messages {
+		rows = append(rows, mod.getRow(msg))
+	}
+
+	mod.Session.Table(mod.colNames(), rows)
+
+	if len(rows) > 0 {
+		mod.Session.Refresh()
+	}
+
+	return nil
+}
+
+func (mod *CANModule) showMessage(id string) error {
+	msg, found := mod.Session.CAN.Get(strings.ToLower(id))
+	if !found {
+		return fmt.Errorf("arbitration id %s not found", id)
+	}
+
+	payload := payloadString(msg)
+
+	msg.Lock()
+	defer msg.Unlock()
+
+	name := msg.Name
+	if name == "" {
+		name = tui.Dim("unknown")
+	}
+
+	mod.Session.Printf("\n%s %s\n\n  data   : %s\n  frames : %d\n\n", tui.Bold(msg.Address), name, payload, msg.Frames)
+
+	if len(msg.Signals) > 0 {
+		names := make([]string, 0)
+		for name := range msg.Signals {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		rows := make([][]string, 0)
+		for _, name := range names {
+			rows = append(rows, []string{name, msg.Signals[name]})
+		}
+		mod.Session.Table([]string{"Signal", "Value"}, rows)
+		mod.Session.Println()
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package can
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"golang.org/x/sys/unix"
+)
+
+// canSocket is a raw SocketCAN socket bound to a single interface.
+type canSocket struct {
+	fd    int
+	iface string
+}
+
+func openSocket(iface string, timeout time.Duration) (error, *canSocket) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return fmt.Errorf("could not find CAN interface %s: %v", iface, err), nil
+	}
+
+	fd, err := unix.Socket(unix.AF_CAN, unix.SOCK_RAW, unix.CAN_RAW)
+	if err != nil {
+		return fmt.Errorf("could not create CAN socket: %v", err), nil
+	}
+
+	if err = unix.Bind(fd, &unix.SockaddrCAN{Ifindex: ifi.Index}); err != nil {
+		unix.Close(fd)
+		return fmt.Errorf("could not bind to %s: %v", iface, err), nil
+	}
+
+	// make reads return periodically so that the module can be stopped
+	tv := unix.NsecToTimeval(timeout.Nanoseconds())
+	if err = unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return err, nil
+	}
+
+	return nil, &canSocket{fd: fd, iface: iface}
+}
+
+// Read returns a nil frame and no error on timeouts.
+func (s *canSocket) Read() (error, *packets.CANFrame) {
+	raw := make([]byte, packets.CANFrameSize)
+	n, err := unix.Read(s.fd, raw)
+	if err == unix.EAGAIN || err == unix.EINTR {
+		return nil, nil
+	} else if err != nil {
+		return err, nil
+	}
+	return packets.CANUnmarshal(raw[:n])
+}
+
+func (s *canSocket) Write(frame *packets.CANFrame) error {
+	_, err := unix.Write(s.fd, frame.Marshal())
+	return err
+}
+
+func (s *canSocket) Close() {
+	unix.Close(s.fd)
+}
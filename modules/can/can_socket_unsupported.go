@@ -0,0 +1,26 @@
+// +build !linux
+
+package can
+
+import (
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+)
+
+type canSocket struct{}
+
+func openSocket(iface string, timeout time.Duration) (error, *canSocket) {
+	return session.ErrNotSupported, nil
+}
+
+func (s *canSocket) Read() (error, *packets.CANFrame) {
+	return session.ErrNotSupported, nil
+}
+
+func (s *canSocket) Write(frame *packets.CANFrame) error {
+	return session.ErrNotSupported
+}
+
+func (s *canSocket) Close() {}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/bettercap/bettercap/caplets"
 	"github.com/bettercap/bettercap/session"
@@ -12,6 +13,7 @@ import (
 	"github.com/dustin/go-humanize"
 
 	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/str"
 	"github.com/evilsocket/islazy/tui"
 	"github.com/evilsocket/islazy/zip"
 )
@@ -37,6 +39,12 @@ func NewCapletsModule(s *session.Session) *CapletsModule {
 			return mod.Paths()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("caplets.test NAME FIXTURE?", `^caplets\.test\s+([^\s]+)(\s+[^\s]+)?$`,
+		"Run a caplet against a simulated session, optionally with the network described by a JSON FIXTURE file, reporting the commands that don't parse, the undefined variables and the invalid parameters without executing any module command.",
+		func(args []string) error {
+			return mod.Test(args[0], str.Trim(args[1]))
+		}))
+
 	mod.AddHandler(session.NewModuleHandler("caplets.update", "",
 		"Install/updates the caplets.",
 		func(args []string) error {
@@ -91,7 +99,7 @@ func (mod *CapletsModule) Show() error {
 		})
 	}
 
-	tui.Table(os.Stdout, colNames, rows)
+	mod.Session.Table(colNames, rows)
 
 	return nil
 }
@@ -106,9 +114,57 @@ func (mod *CapletsModule) Paths() error {
 		rows = append(rows, []string{path})
 	}
 
-	tui.Table(os.Stdout, colNames, rows)
-	fmt.Printf("(paths can be customized by defining the %s environment variable)\n", tui.Bold(caplets.EnvVarName))
+	mod.Session.Table(colNames, rows)
+	mod.Session.Printf("(paths can be customized by defining the %s environment variable)\n", tui.Bold(caplets.EnvVarName))
+
+	return nil
+}
+
+func (mod *CapletsModule) Test(name string, fixtureFile string) error {
+	fixture := (*session.SimulationFixture)(nil)
+	if fixtureFile != "" {
+		fileName, err := fs.Expand(fixtureFile)
+		if err != nil {
+			return err
+		} else if fixture, err = session.LoadSimulationFixture(fileName); err != nil {
+			return err
+		}
+	}
 
+	sim, err := mod.Session.SimulateCaplet(name, fixture)
+	if err != nil {
+		return err
+	}
+
+	if !mod.Session.ShowJSON(sim) {
+		if len(sim.Issues) > 0 {
+			rows := [][]string{}
+			for _, issue := range sim.Issues {
+				level := tui.Yellow(issue.Level)
+				if issue.Level == session.CapletError {
+					level = tui.Red(issue.Level)
+				}
+				rows = append(rows, []string{
+					tui.Bold(issue.Caplet),
+					level,
+					issue.Command,
+					issue.Message,
+				})
+			}
+			mod.Session.Table([]string{"Caplet", "Level", "Command", "Issue"}, rows)
+		}
+
+		mod.Session.Printf("\n%d commands of %d caplets simulated, %d issues (%d errors)\n",
+			sim.Commands, len(sim.Caplets), len(sim.Issues), sim.Errors())
+		if len(sim.Modules) > 0 {
+			mod.Session.Printf("modules used: %s\n", strings.Join(sim.Modules, ", "))
+		}
+		mod.Session.Println()
+	}
+
+	if errors := sim.Errors(); errors > 0 {
+		return fmt.Errorf("caplet %s has %d errors", name, errors)
+	}
 	return nil
 }
 
@@ -0,0 +1,247 @@
+package cast
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/str"
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	KindChromecast = "chromecast"
+	KindDIAL       = "dial"
+	KindAirPlay    = "airplay"
+)
+
+// Device is a cast capable endpoint of the LAN.
+type Device struct {
+	Endpoint *network.Endpoint
+	Kinds    []string
+	Name     string
+	Model    string
+}
+
+func (d Device) Is(kind string) bool {
+	for _, k := range d.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+type CastModule struct {
+	session.SessionModule
+	sync.Mutex
+
+	client      *http.Client
+	airplayPort int
+	// DIAL application instances, by address and application name
+	instances map[string]string
+}
+
+func NewCastModule(s *session.Session) *CastModule {
+	mod := &CastModule{
+		SessionModule: session.NewSessionModule("cast", s),
+		client:        &http.Client{},
+		instances:     make(map[string]string),
+	}
+
+	mod.AddParam(session.NewIntParameter("cast.timeout",
+		"5",
+		"Timeout in seconds of the HTTP requests to the cast devices."))
+
+	mod.AddParam(session.NewIntParameter("cast.airplay.port",
+		"7000",
+		"Port of the AirPlay HTTP service."))
+
+	mod.AddHandler(session.NewModuleHandler("cast.show", "",
+		"Show the Chromecast, DIAL and AirPlay devices found by net.probe.",
+		func(args []string) error {
+			return mod.show()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("cast.apps ADDRESS APP", `cast\.apps\s+([^\s]+)\s+([^\s]+)`,
+		"Get the status of the DIAL application APP (YouTube, Netflix, ...) on the device at ADDRESS.",
+		func(args []string) error {
+			return mod.appStatus(args[0], args[1])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("cast.launch ADDRESS APP PAYLOAD?", `cast\.launch\s+([^\s]+)\s+([^\s]+)\s*(.*)`,
+		"Launch the DIAL application APP on the device at ADDRESS, optionally passing PAYLOAD to it (for instance v=VIDEO_ID for YouTube).",
+		func(args []string) error {
+			return mod.launch(args[0], args[1], str.Trim(args[2]))
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("cast.stop ADDRESS APP?", `cast\.stop\s+([^\s]+)\s*(.*)`,
+		"Stop the DIAL application APP on the device at ADDRESS or, without APP, the AirPlay playback.",
+		func(args []string) error {
+			return mod.stop(args[0], str.Trim(args[1]))
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("cast.play ADDRESS URL", `cast\.play\s+([^\s]+)\s+([^\s]+)`,
+		"Push the media at URL to the device at ADDRESS, with AirPlay or, for YouTube videos, with the DIAL YouTube application.",
+		func(args []string) error {
+			return mod.play(args[0], args[1])
+		}))
+
+	return mod
+}
+
+func (mod *CastModule) Name() string {
+	return "cast"
+}
+
+func (mod *CastModule) Description() string {
+	return "Interact with Chromecast, DIAL and AirPlay devices to launch and stop applications or push media to them."
+}
+
+func (mod *CastModule) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *CastModule) Configure() error {
+	var err error
+	var timeout int
+	if err, timeout = mod.IntParam("cast.timeout"); err != nil {
+		return err
+	} else if err, mod.airplayPort = mod.IntParam("cast.airplay.port"); err != nil {
+		return err
+	}
+	mod.client.Timeout = time.Duration(timeout) * time.Second
+	return nil
+}
+
+func (mod *CastModule) Start() error {
+	return nil
+}
+
+func (mod *CastModule) Stop() error {
+	return nil
+}
+
+func metaString(e *network.Endpoint, name string) string {
+	return fmt.Sprintf("%v", e.Meta.Get(name))
+}
+
+// detect checks the mDNS TXT records and SSDP headers of an endpoint.
+func detect(e *network.Endpoint) *Device {
+	dev := &Device{Endpoint: e}
+
+	if metaString(e, "mdns:md") != "" && (metaString(e, "mdns:ca") != "" || metaString(e, "mdns:ve") != "") {
+		dev.Kinds = append(dev.Kinds, KindChromecast, KindDIAL)
+		dev.Name = metaString(e, "mdns:fn")
+		dev.Model = metaString(e, "mdns:md")
+	} else if strings.Contains(metaString(e, "upnp:St"), "dial-multiscreen-org") {
+		dev.Kinds = append(dev.Kinds, KindDIAL)
+		dev.Model = metaString(e, "upnp:Server")
+	}
+
+	if metaString(e, "mdns:srcvers") != "" || (metaString(e, "mdns:features") != "" && metaString(e, "mdns:deviceid") != "") {
+		dev.Kinds = append(dev.Kinds, KindAirPlay)
+		if dev.Model == "" {
+			dev.Model = metaString(e, "mdns:model")
+		}
+	}
+
+	if len(dev.Kinds) == 0 {
+		return nil
+	} else if dev.Name == "" {
+		dev.Name = e.Hostname
+	}
+	return dev
+}
+
+func (mod *CastModule) devices() []*Device {
+	devices := make([]*Device, 0)
+	mod.Session.Lan.EachHost(func(mac string, e *network.Endpoint) {
+		if dev := detect(e); dev != nil {
+			devices = append(devices, dev)
+		}
+	})
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Endpoint.IpAddressUint32 < devices[j].Endpoint.IpAddressUint32
+	})
+	return devices
+}
+
+// device returns the cast device at address, if the address is a known endpoint
+// which has not been detected, it is assumed to support any protocol.
+func (mod *CastModule) device(address string) (*Device, error) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, fmt.Errorf("%s is not a valid IP address", address)
+	} else if !mod.Session.IPInScope(ip, "cast") {
+		return nil, fmt.Errorf("%s is out of scope", address)
+	} else if err := mod.Configure(); err != nil {
+		return nil, err
+	}
+
+	if e := mod.Session.Lan.GetByIp(address); e != nil {
+		if dev := detect(e); dev != nil {
+			return dev, nil
+		}
+	}
+
+	return &Device{
+		Endpoint: network.NewEndpointNoResolve(address, "", "", 0),
+		Kinds:    []string{KindDIAL, KindAirPlay},
+	}, nil
+}
+
+func (mod *CastModule) show() error {
+	rows := make([][]string, 0)
+	for _, dev := range mod.devices() {
+		rows = append(rows, []string{
+			tui.Bold(dev.Endpoint.IpAddress),
+			dev.Endpoint.HwAddress,
+			dev.Name,
+			tui.Dim(dev.Model),
+			tui.Green(strings.Join(dev.Kinds, ", ")),
+		})
+	}
+
+	if len(rows) == 0 {
+		return fmt.Errorf("no cast devices found yet, start net.recon and net.probe")
+	}
+
+	mod.Session.Println()
+	mod.Session.Table([]string{"IP", "MAC", "Name", "Model", "Protocols"}, rows)
+	mod.Session.Println()
+	return nil
+}
+
+func (mod *CastModule) stop(address, app string) error {
+	dev, err := mod.device(address)
+	if err != nil {
+		return err
+	} else if app != "" {
+		return mod.dialStop(dev, app)
+	} else if !dev.Is(KindAirPlay) {
+		return fmt.Errorf("%s doesn't support AirPlay, specify the DIAL application to stop", address)
+	}
+	return mod.airplayStop(dev)
+}
+
+func (mod *CastModule) play(address, media string) error {
+	dev, err := mod.device(address)
+	if err != nil {
+		return err
+	}
+
+	if id := youtubeVideoID(media); id != "" && dev.Is(KindDIAL) {
+		return mod.dialLaunch(dev, "YouTube", "v="+id)
+	} else if dev.Is(KindAirPlay) {
+		return mod.airplayPlay(dev, media)
+	}
+	return fmt.Errorf("only YouTube videos can be pushed to %s with DIAL", address)
+}
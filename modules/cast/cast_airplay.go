@@ -0,0 +1,59 @@
+package cast
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func (mod *CastModule) airplayRequest(dev *Device, path string, body string) error {
+	target := fmt.Sprintf("http://%s:%d%s", dev.Endpoint.IpAddress, mod.airplayPort, path)
+	req, err := http.NewRequest("POST", target, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "MediaControl/1.0")
+	if body != "" {
+		req.Header.Set("Content-Type", "text/parameters")
+	}
+
+	res, err := mod.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", target, res.Status)
+	}
+	return nil
+}
+
+func (mod *CastModule) airplayPlay(dev *Device, media string) error {
+	if mod.Session.IsDryRun() {
+		mod.Session.DryRunAction("AirPlay playback of %s on %s", media, dev.Endpoint.IpAddress)
+		return nil
+	}
+
+	body := fmt.Sprintf("Content-Location: %s\nStart-Position: 0\n", media)
+	if err := mod.airplayRequest(dev, "/play", body); err != nil {
+		return err
+	}
+
+	mod.Info("playing %s on %s", media, dev.Endpoint.IpAddress)
+	return nil
+}
+
+func (mod *CastModule) airplayStop(dev *Device) error {
+	if mod.Session.IsDryRun() {
+		mod.Session.DryRunAction("AirPlay stop on %s", dev.Endpoint.IpAddress)
+		return nil
+	}
+
+	if err := mod.airplayRequest(dev, "/stop", ""); err != nil {
+		return err
+	}
+
+	mod.Info("playback stopped on %s", dev.Endpoint.IpAddress)
+	return nil
+}
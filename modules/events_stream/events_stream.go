@@ -167,12 +167,21 @@ func NewEventsStream(s *session.Session) *EventsStream {
 			return nil
 		}))
 
-	mod.AddHandler(session.NewModuleHandler("events.clear", "",
-		"Clear events stream.",
+	clear := session.NewModuleHandler("events.clear TAG?", `events\.clear(\s+[^\s]+)?`,
+		"Clear events stream, if TAG is specified only the events with this tag or its children will be removed.",
 		func(args []string) error {
-			mod.Session.Events.Clear()
+			if tag := str.Trim(args[0]); tag != "" {
+				n := mod.Session.Events.ClearFiltered(session.EventFilter{Tags: []string{tag}})
+				mod.Info("%d %s events cleared", n, tag)
+			} else {
+				mod.Session.Events.Clear()
+			}
 			return nil
-		}))
+		})
+
+	clear.Complete("events.clear", s.EventsCompleter)
+
+	mod.AddHandler(clear)
 
 	mod.AddParam(session.NewStringParameter("events.stream.output",
 		"",
@@ -1,8 +1,6 @@
 package events_stream
 
 import (
-	"os"
-
 	"github.com/bettercap/bettercap/session"
 
 	"github.com/evilsocket/islazy/tui"
@@ -41,7 +39,7 @@ func (mod *EventsStream) showTriggers() error {
 	})
 
 	if len(rows) > 0 {
-		tui.Table(os.Stdout, colNames, rows)
+		mod.Session.Table(colNames, rows)
 		mod.Session.Refresh()
 	}
 
@@ -54,7 +52,7 @@ func (mod *EventsStream) dispatchTriggers(e session.Event) {
 	} else if found {
 		mod.Debug("running trigger %s (cmds:'%s') for event %v", id, cmds, e)
 		for _, cmd := range session.ParseCommands(cmds) {
-			if err := mod.Session.Run(cmd); err != nil {
+			if err := mod.Session.RunAs(cmd, "events.trigger "+id); err != nil {
 				mod.Error("%s", err.Error())
 			}
 		}
@@ -9,8 +9,15 @@ import (
 	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/session"
 
+	"github.com/bettercap/bettercap/modules/api_rest"
+	"github.com/bettercap/bettercap/modules/gps"
+	"github.com/bettercap/bettercap/modules/net_hygiene"
 	"github.com/bettercap/bettercap/modules/net_sniff"
+	"github.com/bettercap/bettercap/modules/phase"
+	"github.com/bettercap/bettercap/modules/rdp_proxy"
+	"github.com/bettercap/bettercap/modules/ssh_proxy"
 	"github.com/bettercap/bettercap/modules/syn_scan"
+	"github.com/bettercap/bettercap/modules/tcp_proxy"
 
 	"github.com/google/go-github/github"
 
@@ -26,8 +33,28 @@ func (mod *EventsStream) viewLogEvent(e session.Event) {
 		e.Data.(session.LogMessage).Message)
 }
 
+// aliased appends the alias of the host with the given IP address, if any.
+func (mod *EventsStream) aliased(address string) string {
+	if e := mod.Session.Lan.GetByIp(address); e != nil && e.Alias != "" {
+		return fmt.Sprintf("%s (%s)", address, e.Alias)
+	}
+	return address
+}
+
 func (mod *EventsStream) viewEndpointEvent(e session.Event) {
-	t := e.Data.(*network.Endpoint)
+	if e.Tag == "endpoint.lost" {
+		// shown by endpoint.expired, along with the reason
+		return
+	}
+
+	reason := ""
+	t, ok := e.Data.(*network.Endpoint)
+	if !ok {
+		expired := e.Data.(network.ExpiredEndpoint)
+		t = expired.Endpoint
+		reason = expired.Reason
+	}
+
 	vend := ""
 	name := ""
 
@@ -49,14 +76,28 @@ func (mod *EventsStream) viewEndpointEvent(e session.Event) {
 			tui.Dim(name),
 			tui.Green(t.HwAddress),
 			tui.Dim(vend))
-	} else if e.Tag == "endpoint.lost" {
-		fmt.Fprintf(mod.output, "[%s] [%s] endpoint %s%s %s%s lost.\n",
+	} else if e.Tag == "endpoint.expired" {
+		fmt.Fprintf(mod.output, "[%s] [%s] endpoint %s%s %s%s lost, %s.\n",
 			e.Time.Format(mod.timeFormat),
 			tui.Green(e.Tag),
 			tui.Red(t.IpAddress),
 			tui.Dim(name),
 			tui.Green(t.HwAddress),
-			tui.Dim(vend))
+			tui.Dim(vend),
+			reason)
+	} else if e.Tag == "endpoint.fingerprint" {
+		system := fmt.Sprintf("%v", t.Meta.GetOr("dhcp:os", "an unknown system"))
+		device := ""
+		if d := fmt.Sprintf("%v", t.Meta.Get("dhcp:device")); d != "" {
+			device = fmt.Sprintf(" (%s)", d)
+		}
+		fmt.Fprintf(mod.output, "[%s] [%s] endpoint %s%s fingerprinted as %s%s.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(t.IpAddress),
+			tui.Dim(name),
+			tui.Yellow(system),
+			tui.Dim(device))
 	} else {
 		fmt.Fprintf(mod.output, "[%s] [%s] %s\n",
 			e.Time.Format(mod.timeFormat),
@@ -66,7 +107,28 @@ func (mod *EventsStream) viewEndpointEvent(e session.Event) {
 }
 
 func (mod *EventsStream) viewModuleEvent(e session.Event) {
-	if *mod.Session.Options.Debug {
+	if e.Tag == "mod.error" {
+		merr := e.Data.(session.ModuleErrorEvent)
+		fmt.Fprintf(mod.output, "[%s] [%s] %s: %s\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Red(e.Tag),
+			tui.Bold(merr.Module),
+			merr.Error)
+	} else if e.Tag == "module.crashed" {
+		crash := e.Data.(session.ModuleCrashEvent)
+		fmt.Fprintf(mod.output, "[%s] [%s] %s crashed: %s\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Red(e.Tag),
+			tui.Bold(crash.Module),
+			crash.Error)
+	} else if e.Tag == "module.restarted" {
+		crash := e.Data.(session.ModuleCrashEvent)
+		fmt.Fprintf(mod.output, "[%s] [%s] %s restarted (%d restarts)\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Yellow(e.Tag),
+			tui.Bold(crash.Module),
+			crash.Restarts)
+	} else if *mod.Session.Options.Debug {
 		fmt.Fprintf(mod.output, "[%s] [%s] %s\n",
 			e.Time.Format(mod.timeFormat),
 			tui.Green(e.Tag),
@@ -94,6 +156,246 @@ func (mod *EventsStream) viewSynScanEvent(e session.Event) {
 		tui.Bold(se.Address))
 }
 
+func (mod *EventsStream) viewPhaseEvent(e session.Event) {
+	pe := e.Data.(phase.PhaseEvent)
+	fmt.Fprintf(mod.output, "[%s] [%s] entered phase %s (%s), loading %s\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Green(e.Tag),
+		tui.Bold(pe.Name),
+		pe.Reason,
+		tui.Yellow(pe.Caplet))
+}
+
+func (mod *EventsStream) viewGeofenceEvent(e session.Event) {
+	ge := e.Data.(gps.GeofenceEvent)
+	action := "entered"
+	if e.Tag == "gps.geofence.exit" {
+		action = "exited"
+	}
+	fmt.Fprintf(mod.output, "[%s] [%s] %s geofence %s at %f,%f\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Green(e.Tag),
+		action,
+		tui.Bold(ge.Name),
+		ge.Latitude,
+		ge.Longitude)
+}
+
+func (mod *EventsStream) viewMailEvent(e session.Event) {
+	me := e.Data.(tcp_proxy.MailEvent)
+	attachments := ""
+	if len(me.Attachments) > 0 {
+		attachments = fmt.Sprintf(" with %d attachments", len(me.Attachments))
+	}
+	fmt.Fprintf(mod.output, "[%s] [%s] %s message from %s to %s (%s) saved to %s%s\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Green(e.Tag),
+		strings.ToUpper(me.Protocol),
+		tui.Bold(me.From),
+		tui.Bold(me.To),
+		tui.Yellow(me.Subject),
+		me.File,
+		attachments)
+}
+
+func (mod *EventsStream) viewStarttlsEvent(e session.Event) {
+	se := e.Data.(tcp_proxy.StarttlsEvent)
+	if e.Tag == "tcp.proxy.starttls.credentials" {
+		fmt.Fprintf(mod.output, "[%s] [%s] %s credentials from %s to %s: %s %s\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			strings.ToUpper(se.Protocol),
+			tui.Bold(se.Client),
+			se.Server,
+			tui.Yellow(se.Username),
+			tui.Red(se.Password))
+	} else {
+		fmt.Fprintf(mod.output, "[%s] [%s] %s client %s proceeded in plaintext with %s\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			strings.ToUpper(se.Protocol),
+			tui.Bold(se.Client),
+			se.Server)
+	}
+}
+
+func (mod *EventsStream) viewSSHProxyEvent(e session.Event) {
+	if e.Tag == "ssh.proxy.credentials" {
+		creds := e.Data.(ssh_proxy.SSHCredentials)
+		status := ""
+		if creds.Relayed {
+			status = tui.Red(" (invalid)")
+			if creds.Valid {
+				status = tui.Green(" (valid)")
+			}
+		}
+		fmt.Fprintf(mod.output, "[%s] [%s] %s credentials from %s to %s: %s %s%s\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			creds.Method,
+			tui.Bold(creds.Client),
+			creds.Server,
+			tui.Yellow(creds.Username),
+			tui.Red(creds.Password),
+			status)
+	} else {
+		input := e.Data.(ssh_proxy.SSHInputEvent)
+		fmt.Fprintf(mod.output, "[%s] [%s] %s@%s > %s\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Yellow(input.Username),
+			tui.Bold(input.Client),
+			input.Data)
+	}
+}
+
+func (mod *EventsStream) viewRDPProxyEvent(e session.Event) {
+	if e.Tag == "rdp.proxy.credentials" {
+		creds := e.Data.(rdp_proxy.RDPCredentialsEvent)
+		fmt.Fprintf(mod.output, "[%s] [%s] credentials from %s to %s: %s %s\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(creds.Client),
+			creds.Server,
+			tui.Yellow(creds.Domain+"\\"+creds.Username),
+			tui.Red(creds.Password))
+	} else if e.Tag == "rdp.proxy.ntlm" {
+		h := e.Data.(rdp_proxy.RDPHashEvent)
+		fmt.Fprintf(mod.output, "[%s] [%s] %s hash from %s (%s) to %s: %s\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			h.Version,
+			tui.Bold(h.Client),
+			h.Workstation,
+			h.Server,
+			tui.Red(h.Hash))
+	} else {
+		se := e.Data.(rdp_proxy.RDPSessionEvent)
+		downgraded := ""
+		if se.Downgraded {
+			downgraded = tui.Red(" (downgraded)")
+		}
+		fmt.Fprintf(mod.output, "[%s] [%s] %s %s connected to %s requesting %s, using %s%s\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(se.Client),
+			tui.Yellow(se.Cookie),
+			se.Server,
+			se.Requested,
+			tui.Bold(se.Selected),
+			downgraded)
+	}
+}
+
+func (mod *EventsStream) viewTopologyEvent(e session.Event) {
+	if e.Tag == "topology.node.new" {
+		node := e.Data.(*network.TopologyNode)
+		fmt.Fprintf(mod.output, "[%s] [%s] new switch %s %s detected.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(node.Address),
+			tui.Dim(node.Vendor))
+	} else if e.Tag == "topology.root.changed" {
+		fmt.Fprintf(mod.output, "[%s] [%s] spanning tree root bridge is %s.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(e.Data.(string)))
+	}
+}
+
+func (mod *EventsStream) viewHygieneEvent(e session.Event) {
+	if e.Tag == "net.hygiene.issue" {
+		f := e.Data.(net_hygiene.Finding)
+		status := tui.Yellow(f.Status)
+		if f.Status == net_hygiene.StatusCritical {
+			status = tui.Red(f.Status)
+		}
+		fmt.Fprintf(mod.output, "[%s] [%s] %s %s: %s\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			status,
+			tui.Bold(f.Check),
+			f.Details)
+	} else if e.Tag == "net.hygiene.report" {
+		report := e.Data.(*net_hygiene.Report)
+		issues := 0
+		for _, f := range report.Findings {
+			if f.Penalty > 0 {
+				issues++
+			}
+		}
+		fmt.Fprintf(mod.output, "[%s] [%s] network hygiene score %s with %d issues.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(fmt.Sprintf("%d/100", report.Score)),
+			issues)
+	}
+}
+
+func (mod *EventsStream) viewADEvent(e session.Event) {
+	if e.Tag == "ad.domain.new" {
+		domain := e.Data.(network.ADDomain)
+		name := domain.ID()
+		if domain.Name != "" && domain.NetBIOS != "" {
+			name = fmt.Sprintf("%s (%s)", domain.Name, domain.NetBIOS)
+		}
+		fmt.Fprintf(mod.output, "[%s] [%s] new Active Directory domain %s detected.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Yellow(name))
+	} else if e.Tag == "ad.controller.new" {
+		dc := e.Data.(network.ADController)
+		name := dc.Address
+		if dc.Hostname != "" && dc.Address != "" {
+			name = fmt.Sprintf("%s (%s)", dc.Hostname, dc.Address)
+		} else if dc.Hostname != "" {
+			name = dc.Hostname
+		}
+		fmt.Fprintf(mod.output, "[%s] [%s] new domain controller %s of %s detected via %s.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(name),
+			tui.Yellow(dc.Domain),
+			strings.Join(dc.Sources, ", "))
+	}
+}
+
+func (mod *EventsStream) viewParamEvent(e session.Event) {
+	change := e.Data.(session.ParamChangedEvent)
+
+	old := tui.Dim("unset")
+	if change.Declared {
+		old = fmt.Sprintf("'%s'", change.Old)
+	}
+
+	fmt.Fprintf(mod.output, "[%s] [%s] %s changed %s from %s to '%s'\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Green(e.Tag),
+		tui.Bold(change.Who),
+		tui.Yellow(change.Name),
+		old,
+		change.New)
+}
+
+func (mod *EventsStream) viewConfirmEvent(e session.Event) {
+	ce := e.Data.(api_rest.ConfirmEvent)
+
+	by := ""
+	if ce.By != "" {
+		by = " by " + tui.Bold(ce.By)
+	}
+
+	fmt.Fprintf(mod.output, "[%s] [%s] commands #%d (%s risk) of %s %s%s: %s\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Green(e.Tag),
+		ce.ID,
+		ce.Risk,
+		tui.Bold(ce.Who),
+		strings.TrimPrefix(e.Tag, "api.rest.confirm."),
+		by,
+		tui.Yellow(strings.Join(ce.Commands, "; ")))
+}
+
 func (mod *EventsStream) viewUpdateEvent(e session.Event) {
 	update := e.Data.(*github.RepositoryRelease)
 
@@ -166,16 +468,56 @@ func (mod *EventsStream) View(e session.Event, refresh bool) {
 		mod.viewWiFiEvent(e)
 	} else if strings.HasPrefix(e.Tag, "ble.") {
 		mod.viewBLEEvent(e)
+	} else if strings.HasPrefix(e.Tag, "bt.") {
+		mod.viewBTEvent(e)
 	} else if strings.HasPrefix(e.Tag, "hid.") {
 		mod.viewHIDEvent(e)
-	} else if strings.HasPrefix(e.Tag, "mod.") {
+	} else if strings.HasPrefix(e.Tag, "zigbee.") {
+		mod.viewZigbeeEvent(e)
+	} else if strings.HasPrefix(e.Tag, "can.") {
+		mod.viewCANEvent(e)
+	} else if strings.HasPrefix(e.Tag, "sdr.") {
+		mod.viewSDREvent(e)
+	} else if strings.HasPrefix(e.Tag, "mod.") || strings.HasPrefix(e.Tag, "module.") {
 		mod.viewModuleEvent(e)
 	} else if strings.HasPrefix(e.Tag, "net.sniff.") {
 		mod.viewSnifferEvent(e)
+	} else if strings.HasPrefix(e.Tag, "topology.") {
+		mod.viewTopologyEvent(e)
+	} else if strings.HasPrefix(e.Tag, "net.hygiene.") {
+		mod.viewHygieneEvent(e)
+	} else if strings.HasPrefix(e.Tag, "ad.") {
+		mod.viewADEvent(e)
+	} else if e.Tag == "http.server.hit" {
+		mod.viewServerHitEvent(e)
+	} else if e.Tag == "http.proxy.collect" || e.Tag == "https.proxy.collect" {
+		mod.viewProxyCollectEvent(e)
+	} else if e.Tag == "http.proxy.hook.loaded" || e.Tag == "https.proxy.hook.loaded" {
+		mod.viewProxyHookEvent(e)
+	} else if e.Tag == "http.proxy.carved" || e.Tag == "https.proxy.carved" {
+		mod.viewProxyCarveEvent(e)
+	} else if e.Tag == "http.proxy.challenge.credentials" || e.Tag == "https.proxy.challenge.credentials" {
+		mod.viewProxyChallengeEvent(e)
+	} else if strings.HasPrefix(e.Tag, "tcp.proxy.starttls.") {
+		mod.viewStarttlsEvent(e)
+	} else if e.Tag == "tcp.proxy.mail" {
+		mod.viewMailEvent(e)
+	} else if strings.HasPrefix(e.Tag, "ssh.proxy.") {
+		mod.viewSSHProxyEvent(e)
+	} else if strings.HasPrefix(e.Tag, "rdp.proxy.") {
+		mod.viewRDPProxyEvent(e)
 	} else if e.Tag == "syn.scan" {
 		mod.viewSynScanEvent(e)
+	} else if strings.HasPrefix(e.Tag, "gps.geofence.") {
+		mod.viewGeofenceEvent(e)
+	} else if e.Tag == "phase.entered" {
+		mod.viewPhaseEvent(e)
 	} else if e.Tag == "update.available" {
 		mod.viewUpdateEvent(e)
+	} else if e.Tag == "param.changed" {
+		mod.viewParamEvent(e)
+	} else if strings.HasPrefix(e.Tag, "api.rest.confirm.") {
+		mod.viewConfirmEvent(e)
 	} else {
 		fmt.Fprintf(mod.output, "[%s] [%s] %v\n", e.Time.Format(mod.timeFormat), tui.Green(e.Tag), e)
 	}
@@ -6,6 +6,7 @@ package events_stream
 import (
 	"fmt"
 
+	"github.com/bettercap/bettercap/modules/ble"
 	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/session"
 
@@ -48,6 +49,24 @@ func (mod *EventsStream) viewBLEEvent(e session.Event) {
 			name,
 			dev.Device.ID(),
 			vend)
+	} else if e.Tag == "ble.characteristic.changed" {
+		change := e.Data.(ble.CharacteristicChange)
+		name := change.Name
+		if name == "" {
+			name = change.UUID
+		}
+		dev := change.MAC
+		if change.Device != "" {
+			dev = fmt.Sprintf("%s (%s)", tui.Bold(change.Device), change.MAC)
+		}
+
+		fmt.Fprintf(mod.output, "[%s] [%s] characteristics %s of %s changed from %s to %s.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Yellow(name),
+			dev,
+			tui.Dim(change.Old),
+			tui.Bold(change.New))
 	} /* else {
 		fmt.Fprintf(s.output,"[%s] [%s]\n",
 			e.Time.Format(mod.timeFormat),
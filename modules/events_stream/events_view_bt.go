@@ -0,0 +1,46 @@
+package events_stream
+
+import (
+	"fmt"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func (mod *EventsStream) viewBTEvent(e session.Event) {
+	dev := e.Data.(*network.BTDevice)
+	name := ""
+	if dev.Name != "" {
+		name = " " + tui.Bold(dev.Name)
+	}
+	vend := ""
+	if dev.Vendor != "" {
+		vend = fmt.Sprintf(" (%s)", tui.Yellow(dev.Vendor))
+	}
+
+	if e.Tag == "bt.device.new" {
+		rssi := ""
+		if dev.RSSI != 0 {
+			rssi = " " + tui.Dim(fmt.Sprintf("%d dBm", dev.RSSI))
+		}
+
+		fmt.Fprintf(mod.output, "[%s] [%s] new Bluetooth %s%s detected as %s%s%s.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			dev.Type(),
+			name,
+			dev.Address,
+			vend,
+			rssi)
+	} else if e.Tag == "bt.device.lost" {
+		fmt.Fprintf(mod.output, "[%s] [%s] Bluetooth %s%s %s%s lost.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			dev.Type(),
+			name,
+			dev.Address,
+			vend)
+	}
+}
@@ -0,0 +1,32 @@
+package events_stream
+
+import (
+	"fmt"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func (mod *EventsStream) viewCANEvent(e session.Event) {
+	msg := e.Data.(*network.CANMessage)
+	name := ""
+	if msg.Name != "" {
+		name = fmt.Sprintf(" (%s)", tui.Yellow(msg.Name))
+	}
+
+	if e.Tag == "can.message.new" {
+		fmt.Fprintf(mod.output, "[%s] [%s] new CAN arbitration id %s%s detected.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(msg.Address),
+			name)
+	} else if e.Tag == "can.message.lost" {
+		fmt.Fprintf(mod.output, "[%s] [%s] CAN arbitration id %s%s lost.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Red(msg.Address),
+			name)
+	}
+}
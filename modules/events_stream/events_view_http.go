@@ -6,13 +6,19 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"github.com/bettercap/bettercap/modules/net_sniff"
 	"net/url"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/bettercap/bettercap/modules/http_proxy"
+	"github.com/bettercap/bettercap/modules/http_server"
+	"github.com/bettercap/bettercap/modules/net_sniff"
 	"github.com/bettercap/bettercap/session"
 
+	"github.com/dustin/go-humanize"
+
 	"github.com/evilsocket/islazy/tui"
 )
 
@@ -196,3 +202,92 @@ func (mod *EventsStream) viewHttpEvent(e session.Event) {
 		mod.viewHttpResponse(e)
 	}
 }
+
+func (mod *EventsStream) viewProxyCollectEvent(e session.Event) {
+	ev := e.Data.(http_proxy.CollectEvent)
+
+	names := make([]string, 0, len(ev.Data))
+	for name := range ev.Data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	data := ""
+	for _, name := range names {
+		data += fmt.Sprintf("\n  %s : %s", tui.Green(name), tui.Bold(tui.Red(ev.Data[name])))
+	}
+
+	campaign := ""
+	if ev.Campaign != "" {
+		campaign = fmt.Sprintf(" (%s)", tui.Dim(ev.Campaign))
+	}
+
+	fmt.Fprintf(mod.output, "[%s] [%s] %s collected data from %s%s%s\n\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Green(e.Tag),
+		tui.Yellow(ev.Host),
+		tui.Bold(mod.aliased(ev.From)),
+		campaign,
+		data)
+}
+
+func (mod *EventsStream) viewServerHitEvent(e session.Event) {
+	ev := e.Data.(http_server.HitEvent)
+
+	label := ""
+	if ev.Label != "" {
+		label = fmt.Sprintf(" (%s)", tui.Yellow(ev.Label))
+	}
+
+	timing := fmt.Sprintf("%s after it was created", ev.Since.Round(time.Second))
+	if ev.Hit > 1 {
+		timing = fmt.Sprintf("hit #%d, %s after the previous one", ev.Hit, ev.SinceLast.Round(time.Second))
+	}
+
+	fmt.Fprintf(mod.output, "[%s] [%s] %s fetched %s with token %s%s, %s %s\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Green(e.Tag),
+		tui.Bold(mod.aliased(ev.Address)),
+		ev.Path,
+		tui.Bold(ev.Token),
+		label,
+		timing,
+		tui.Dim(ev.UserAgent))
+}
+
+func (mod *EventsStream) viewProxyCarveEvent(e session.Event) {
+	ev := e.Data.(http_proxy.CarveEvent)
+	fmt.Fprintf(mod.output, "[%s] [%s] %s downloaded %s (%s, %s) saved to %s\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Green(e.Tag),
+		tui.Bold(mod.aliased(ev.Client)),
+		tui.Yellow(ev.URL),
+		ev.ContentType,
+		humanize.Bytes(uint64(ev.Size)),
+		tui.Dim(ev.File))
+}
+
+func (mod *EventsStream) viewProxyHookEvent(e session.Event) {
+	victim := e.Data.(http_proxy.HookVictim)
+	fmt.Fprintf(mod.output, "[%s] [%s] %s loaded the hook after %d injections\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Green(e.Tag),
+		tui.Bold(mod.aliased(victim.Address)),
+		victim.Injections)
+}
+
+func (mod *EventsStream) viewProxyChallengeEvent(e session.Event) {
+	ev := e.Data.(http_proxy.ChallengeEvent)
+	creds := tui.Yellow(ev.Username) + " " + tui.Red(ev.Password)
+	if ev.Scheme == http_proxy.ChallengeNTLM {
+		creds = fmt.Sprintf("%s %s", tui.Yellow(ev.Domain+"\\"+ev.Username), tui.Red(ev.Hash))
+	}
+
+	fmt.Fprintf(mod.output, "[%s] [%s] %s %s credentials for %s: %s\n",
+		e.Time.Format(mod.timeFormat),
+		tui.Green(e.Tag),
+		tui.Bold(mod.aliased(ev.Client)),
+		ev.Scheme,
+		ev.URL,
+		creds)
+}
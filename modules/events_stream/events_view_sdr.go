@@ -0,0 +1,33 @@
+package events_stream
+
+import (
+	"fmt"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func (mod *EventsStream) viewSDREvent(e session.Event) {
+	dev := e.Data.(*network.SDRDevice)
+	if e.Tag == "sdr.device.new" {
+		fmt.Fprintf(mod.output, "[%s] [%s] new %s device %s detected.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Yellow(dev.Model),
+			tui.Bold(dev.Key))
+	} else if e.Tag == "sdr.device.lost" {
+		fmt.Fprintf(mod.output, "[%s] [%s] %s device %s lost.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			dev.Model,
+			tui.Red(dev.Key))
+	} else if e.Tag == "sdr.message" {
+		fmt.Fprintf(mod.output, "[%s] [%s] %s : %s\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(dev.Key),
+			dev.ReadingsString())
+	}
+}
@@ -3,6 +3,7 @@ package events_stream
 import (
 	"fmt"
 	"github.com/bettercap/bettercap/modules/wifi"
+	"github.com/bettercap/bettercap/modules/wifi_crack"
 	"strings"
 
 	"github.com/bettercap/bettercap/network"
@@ -44,6 +45,35 @@ func (mod *EventsStream) viewWiFiApEvent(e session.Event) {
 	}
 }
 
+func (mod *EventsStream) viewWiFiP2PEvent(e session.Event) {
+	dev := e.Data.(*network.P2PDevice)
+	name := dev.HwAddress
+	if dev.Name() != "" {
+		name = fmt.Sprintf("%s (%s)", dev.Name(), dev.HwAddress)
+	}
+	desc := ""
+	if dev.Type != "" {
+		desc = fmt.Sprintf(" (%s)", dev.Type)
+	} else if dev.Vendor != "" {
+		desc = fmt.Sprintf(" (%s)", dev.Vendor)
+	}
+
+	if e.Tag == "wifi.p2p.new" {
+		fmt.Fprintf(mod.output, "[%s] [%s] wifi direct %s %s%s detected.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			dev.Role,
+			tui.Bold(name),
+			tui.Dim(desc))
+	} else {
+		fmt.Fprintf(mod.output, "[%s] [%s] wifi direct %s %s lost.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			dev.Role,
+			tui.Red(name))
+	}
+}
+
 func (mod *EventsStream) viewWiFiClientProbeEvent(e session.Event) {
 	probe := e.Data.(wifi.ProbeEvent)
 	desc := ""
@@ -70,6 +100,9 @@ func (mod *EventsStream) viewWiFiHandshakeEvent(e session.Event) {
 	hand := e.Data.(wifi.HandshakeEvent)
 
 	from := hand.Station
+	if alias := mod.Session.Lan.GetAlias(hand.Station); alias != "" {
+		from = fmt.Sprintf("%s (%s)", from, tui.Green(alias))
+	}
 	to := hand.AP
 	what := "handshake"
 
@@ -80,6 +113,12 @@ func (mod *EventsStream) viewWiFiHandshakeEvent(e session.Event) {
 
 	if hand.PMKID != nil {
 		what = "RSN PMKID"
+	} else if e.Tag == "wifi.client.handshake.half" {
+		what = "half " + what
+	}
+
+	if hand.Messages != "" {
+		what = fmt.Sprintf("%s (%s)", what, hand.Messages)
 	}
 
 	fmt.Fprintf(mod.output, "[%s] [%s] captured %s -> %s %s to %s\n",
@@ -113,15 +152,87 @@ func (mod *EventsStream) viewWiFiClientEvent(e session.Event) {
 	}
 }
 
+func (mod *EventsStream) viewWiFiRogueEvent(e session.Event) {
+	ev := e.Data.(wifi.RogueStationEvent)
+	desc := ""
+	if alias := mod.Session.Lan.GetAlias(ev.Address); alias != "" {
+		desc = fmt.Sprintf(" (%s)", alias)
+	} else if ev.Vendor != "" {
+		desc = fmt.Sprintf(" (%s)", ev.Vendor)
+	}
+
+	if e.Tag == "wifi.rogue.station.connected" {
+		fmt.Fprintf(mod.output, "[%s] [%s] station %s%s connected to the access point\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(ev.Address),
+			tui.Dim(desc))
+	} else if e.Tag == "wifi.rogue.station.disconnected" {
+		fmt.Fprintf(mod.output, "[%s] [%s] station %s%s disconnected from the access point\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(ev.Address),
+			tui.Dim(desc))
+	} else {
+		fmt.Fprintf(mod.output, "[%s] [%s] station %s%s got address %s\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(ev.Address),
+			tui.Dim(desc),
+			tui.Yellow(ev.IP))
+	}
+}
+
+func (mod *EventsStream) viewWiFiCrackEvent(e session.Event) {
+	if e.Tag == "wifi.crack.found" {
+		f := e.Data.(wifi_crack.Found)
+		fmt.Fprintf(mod.output, "[%s] [%s] found passphrase %s for %s (%s)\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(tui.Red(f.Passphrase)),
+			tui.Bold(f.ESSID),
+			f.BSSID)
+	} else if e.Tag == "wifi.crack.progress" {
+		p := e.Data.(wifi_crack.CrackProgress)
+		fmt.Fprintf(mod.output, "[%s] [%s] [%.2f%%] tried %d/%d words from %s (%.1f/s), found %d/%d passphrases\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			100.0*float64(p.Tried)/float64(p.Total),
+			p.Tried,
+			p.Total,
+			p.Wordlist,
+			p.Rate,
+			p.Found,
+			p.Targets)
+	} else if e.Tag == "wifi.crack.submitted" {
+		j := e.Data.(wifi_crack.CrackJob)
+		fmt.Fprintf(mod.output, "[%s] [%s] sent %d hashes of %s (%s) to %s\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			j.Hashes,
+			tui.Bold(j.ESSID),
+			j.BSSID,
+			j.Server)
+	} else {
+		fmt.Fprintf(mod.output, "[%s] [%s] %v\n", e.Time.Format(mod.timeFormat), tui.Green(e.Tag), e)
+	}
+}
+
 func (mod *EventsStream) viewWiFiEvent(e session.Event) {
 	if strings.HasPrefix(e.Tag, "wifi.ap.") {
 		mod.viewWiFiApEvent(e)
 	} else if e.Tag == "wifi.client.probe" {
 		mod.viewWiFiClientProbeEvent(e)
-	} else if e.Tag == "wifi.client.handshake" {
+	} else if e.Tag == "wifi.client.handshake" || e.Tag == "wifi.client.handshake.half" {
 		mod.viewWiFiHandshakeEvent(e)
 	} else if e.Tag == "wifi.client.new" || e.Tag == "wifi.client.lost" {
 		mod.viewWiFiClientEvent(e)
+	} else if strings.HasPrefix(e.Tag, "wifi.rogue.") {
+		mod.viewWiFiRogueEvent(e)
+	} else if strings.HasPrefix(e.Tag, "wifi.crack.") {
+		mod.viewWiFiCrackEvent(e)
+	} else if e.Tag == "wifi.p2p.new" || e.Tag == "wifi.p2p.lost" {
+		mod.viewWiFiP2PEvent(e)
 	} else {
 		fmt.Fprintf(mod.output, "[%s] [%s] %v\n", e.Time.Format(mod.timeFormat), tui.Green(e.Tag), e)
 	}
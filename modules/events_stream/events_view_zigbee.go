@@ -0,0 +1,38 @@
+package events_stream
+
+import (
+	"fmt"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func (mod *EventsStream) viewZigbeeEvent(e session.Event) {
+	dev := e.Data.(*network.ZigbeeDevice)
+	if e.Tag == "zigbee.device.new" {
+		fmt.Fprintf(mod.output, "[%s] [%s] new Zigbee device %s detected on PAN %s (channel %d).\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(dev.Address),
+			dev.PANString(),
+			dev.Channel)
+	} else if e.Tag == "zigbee.device.lost" {
+		fmt.Fprintf(mod.output, "[%s] [%s] Zigbee device %s lost.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Red(dev.Address))
+	} else if e.Tag == "zigbee.permit.join" {
+		state := tui.Dim("closed")
+		if dev.PermitJoin {
+			state = tui.Red("open")
+		}
+		fmt.Fprintf(mod.output, "[%s] [%s] %s on PAN %s is %s for joining.\n",
+			e.Time.Format(mod.timeFormat),
+			tui.Green(e.Tag),
+			tui.Bold(dev.Address),
+			dev.PANString(),
+			state)
+	}
+}
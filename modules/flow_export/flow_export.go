@@ -0,0 +1,233 @@
+package flow_export
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// counters of a flow at the time of its last export
+type exported struct {
+	sentPackets uint64
+	sentBytes   uint64
+	recvPackets uint64
+	recvBytes   uint64
+	lastSeen    time.Time
+	seen        bool
+}
+
+type FlowExporter struct {
+	session.SessionModule
+
+	collector string
+	interval  time.Duration
+	encoder   *packets.NetFlowEncoder
+	conn      net.Conn
+	exported  map[string]*exported
+	sent      uint64
+	quit      chan bool
+	done      chan bool
+}
+
+func NewFlowExporter(s *session.Session) *FlowExporter {
+	mod := &FlowExporter{
+		SessionModule: session.NewSessionModule("flow.export", s),
+	}
+
+	mod.AddParam(session.NewStringParameter("flow.export.collector",
+		"127.0.0.1:2055",
+		"",
+		"Address and UDP port of the NetFlow or IPFIX collector."))
+
+	mod.AddParam(session.NewStringParameter("flow.export.protocol",
+		"ipfix",
+		"^(ipfix|netflow9)$",
+		"Export protocol, ipfix or netflow9."))
+
+	mod.AddParam(session.NewIntParameter("flow.export.interval",
+		"30",
+		"Seconds between exports, flows seen for longer are exported once per interval with the packets and bytes since the previous export."))
+
+	mod.AddParam(session.NewIntParameter("flow.export.source.id",
+		"0",
+		"Source id (NetFlow v9) or observation domain id (IPFIX) of the exported records."))
+
+	mod.AddHandler(session.NewModuleHandler("flow.export on", "",
+		"Start exporting the tracked connections to the collector.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("flow.export off", "",
+		"Stop exporting the tracked connections.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	return mod
+}
+
+func (mod *FlowExporter) Name() string {
+	return "flow.export"
+}
+
+func (mod *FlowExporter) Description() string {
+	return "Export the connection tracking table as NetFlow v9 or IPFIX records to a collector."
+}
+
+func (mod *FlowExporter) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *FlowExporter) Configure() (err error) {
+	var proto string
+	var interval, sourceID int
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted
+	} else if err, mod.collector = mod.StringParam("flow.export.collector"); err != nil {
+		return err
+	} else if err, proto = mod.StringParam("flow.export.protocol"); err != nil {
+		return err
+	} else if err, interval = mod.IntParam("flow.export.interval"); err != nil {
+		return err
+	} else if interval <= 0 {
+		return fmt.Errorf("flow.export.interval must be greater than 0")
+	} else if err, sourceID = mod.IntParam("flow.export.source.id"); err != nil {
+		return err
+	}
+
+	version := packets.IPFIX
+	if proto == "netflow9" {
+		version = packets.NetFlowV9
+	}
+
+	if mod.encoder, err = packets.NewNetFlowEncoder(version, uint32(sourceID)); err != nil {
+		return err
+	} else if mod.conn, err = net.Dial("udp", mod.collector); err != nil {
+		return fmt.Errorf("could not connect to collector %s: %v", mod.collector, err)
+	}
+
+	mod.interval = time.Duration(interval) * time.Second
+	mod.exported = make(map[string]*exported)
+	mod.sent = 0
+	mod.quit = make(chan bool)
+	mod.done = make(chan bool)
+	return nil
+}
+
+func flowID(f *network.Flow) string {
+	return fmt.Sprintf("%s|%s|%s|%d", f.Proto, f.Src(), f.Dst(), f.FirstSeen.UnixNano())
+}
+
+func record(f *network.Flow, fromSrc bool, pkts, bytes uint64, start time.Time) packets.FlowRecord {
+	r := packets.FlowRecord{
+		Proto:   packets.FlowProtocolNumber(f.Proto),
+		SrcIP:   net.ParseIP(f.SrcIP),
+		SrcPort: uint16(f.SrcPort),
+		DstIP:   net.ParseIP(f.DstIP),
+		DstPort: uint16(f.DstPort),
+		Packets: pkts,
+		Bytes:   bytes,
+		Start:   start,
+		End:     f.LastSeen,
+	}
+	if !fromSrc {
+		r.SrcIP, r.SrcPort, r.DstIP, r.DstPort = r.DstIP, r.DstPort, r.SrcIP, r.SrcPort
+	}
+	return r
+}
+
+// records returns the unidirectional records of the traffic seen since the
+// previous export, flows that didn't change are skipped.
+func (mod *FlowExporter) records() []packets.FlowRecord {
+	records := make([]packets.FlowRecord, 0)
+	for _, e := range mod.exported {
+		e.seen = false
+	}
+
+	for _, f := range mod.Session.Flows.List() {
+		id := flowID(f)
+		prev, found := mod.exported[id]
+		if !found {
+			prev = &exported{lastSeen: f.FirstSeen}
+			mod.exported[id] = prev
+		}
+		prev.seen = true
+
+		if p := f.SentPackets - prev.sentPackets; p > 0 {
+			records = append(records, record(f, true, p, f.SentBytes-prev.sentBytes, prev.lastSeen))
+		}
+		if p := f.RecvPackets - prev.recvPackets; p > 0 {
+			records = append(records, record(f, false, p, f.RecvBytes-prev.recvBytes, prev.lastSeen))
+		}
+
+		prev.sentPackets, prev.sentBytes = f.SentPackets, f.SentBytes
+		prev.recvPackets, prev.recvBytes = f.RecvPackets, f.RecvBytes
+		prev.lastSeen = f.LastSeen
+	}
+
+	// flows expired from the table
+	for id, e := range mod.exported {
+		if !e.seen {
+			delete(mod.exported, id)
+		}
+	}
+
+	return records
+}
+
+func (mod *FlowExporter) export() {
+	records := mod.records()
+	// templates are sent with every export, collectors forget them
+	for _, msg := range mod.encoder.Encode(records, true) {
+		if _, err := mod.conn.Write(msg); err != nil {
+			mod.Debug("could not send to %s: %v", mod.collector, err)
+			return
+		}
+	}
+
+	mod.sent += uint64(len(records))
+	mod.Debug("exported %d records to %s (%d so far)", len(records), mod.collector, mod.sent)
+}
+
+func (mod *FlowExporter) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		defer close(mod.done)
+
+		mod.Info("exporting flows to %s every %s", tui.Bold(mod.collector), mod.interval)
+
+		ticker := time.NewTicker(mod.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				mod.export()
+			case <-mod.quit:
+				// what's left since the last export
+				mod.export()
+				return
+			}
+		}
+	})
+}
+
+func (mod *FlowExporter) Stop() error {
+	return mod.SetRunning(false, func() {
+		close(mod.quit)
+		<-mod.done
+		mod.conn.Close()
+		mod.Info("exported %d records to %s", mod.sent, mod.collector)
+	})
+}
@@ -19,6 +19,7 @@ type GPS struct {
 	serialPort string
 	baudRate   int
 	serial     *serial.Port
+	geofences  geofences
 }
 
 func NewGPS(s *session.Session) *GPS {
@@ -26,6 +27,9 @@ func NewGPS(s *session.Session) *GPS {
 		SessionModule: session.NewSessionModule("gps", s),
 		serialPort:    "/dev/ttyUSB0",
 		baudRate:      19200,
+		geofences: geofences{
+			fences: make(map[string]*Geofence),
+		},
 	}
 
 	mod.AddParam(session.NewStringParameter("gps.device",
@@ -55,6 +59,24 @@ func NewGPS(s *session.Session) *GPS {
 			return mod.Show()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("gps.geofence.add NAME AREA", `gps\.geofence\.add ([^\s]+) (.+)`,
+		"Add or replace a geofence emitting gps.geofence.enter and gps.geofence.exit events, AREA is either a 'LATITUDE,LONGITUDE RADIUS' circle with the radius in meters or the 'LATITUDE,LONGITUDE' vertices of a polygon separated by spaces.",
+		func(args []string) error {
+			return mod.addGeofence(args[0], args[1])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("gps.geofence.del NAME", `gps\.geofence\.del ([^\s]+)`,
+		"Remove a geofence.",
+		func(args []string) error {
+			return mod.delGeofence(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("gps.geofences", "",
+		"Show the geofences and whether the last coordinates are inside them.",
+		func(args []string) error {
+			return mod.showGeofences()
+		}))
+
 	return mod
 }
 
@@ -106,6 +128,10 @@ func (mod *GPS) readLine() (line string, err error) {
 }
 
 func (mod *GPS) Show() error {
+	if mod.Session.ShowJSON(mod.Session.GPS) {
+		return nil
+	}
+
 	fmt.Printf("latitude:%f longitude:%f quality:%s satellites:%d altitude:%f\n",
 		mod.Session.GPS.Latitude,
 		mod.Session.GPS.Longitude,
@@ -138,6 +164,7 @@ func (mod *GPS) Start() error {
 						mod.Session.GPS.HDOP = m.HDOP
 						mod.Session.GPS.Altitude = m.Altitude
 						mod.Session.GPS.Separation = m.Separation
+						mod.checkGeofences()
 					} else if m, ok := s.(nmea.GPGGA); ok {
 						mod.Session.GPS.Latitude = m.Latitude
 						mod.Session.GPS.Longitude = m.Longitude
@@ -146,6 +173,7 @@ func (mod *GPS) Start() error {
 						mod.Session.GPS.HDOP = m.HDOP
 						mod.Session.GPS.Altitude = m.Altitude
 						mod.Session.GPS.Separation = m.Separation
+						mod.checkGeofences()
 					}
 				} else {
 					mod.Debug("error parsing line '%s': %s", line, err)
@@ -0,0 +1,225 @@
+package gps
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/adrianmo/go-nmea"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const earthRadius = 6371000.0
+
+type point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Geofence is either a circle, when Radius is set, or a polygon.
+type Geofence struct {
+	Name    string  `json:"name"`
+	Center  point   `json:"center"`
+	Radius  float64 `json:"radius"`
+	Polygon []point `json:"polygon"`
+
+	// nil until the first fix
+	inside *bool
+}
+
+// GeofenceEvent is emitted as gps.geofence.enter and gps.geofence.exit, the
+// first fix after a geofence is added emits one of them depending on where
+// the device is, so that triggers can also stop what must only run inside.
+type GeofenceEvent struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type geofences struct {
+	sync.Mutex
+	fences map[string]*Geofence
+}
+
+func parsePoint(s string) (p point, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return p, fmt.Errorf("'%s' is not a LATITUDE,LONGITUDE pair", s)
+	} else if p.Latitude, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil {
+		return p, fmt.Errorf("invalid latitude in '%s': %v", s, err)
+	} else if p.Longitude, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil {
+		return p, fmt.Errorf("invalid longitude in '%s': %v", s, err)
+	} else if math.Abs(p.Latitude) > 90 || math.Abs(p.Longitude) > 180 {
+		return p, fmt.Errorf("'%s' is out of range", s)
+	}
+	return p, nil
+}
+
+// parseGeofence parses a center and radius in meters, or the vertices of a
+// polygon, like '45.46,9.18 250' or '45.46,9.18 45.47,9.18 45.47,9.19'.
+func parseGeofence(name string, spec string) (*Geofence, error) {
+	fields := strings.Fields(spec)
+	fence := &Geofence{Name: name}
+
+	if len(fields) == 2 && !strings.Contains(fields[1], ",") {
+		center, err := parsePoint(fields[0])
+		if err != nil {
+			return nil, err
+		} else if fence.Radius, err = strconv.ParseFloat(fields[1], 64); err != nil || fence.Radius <= 0 {
+			return nil, fmt.Errorf("invalid radius '%s'", fields[1])
+		}
+		fence.Center = center
+		return fence, nil
+	} else if len(fields) < 3 {
+		return nil, fmt.Errorf("a geofence is either a center with a radius or a polygon of at least 3 points")
+	}
+
+	for _, field := range fields {
+		p, err := parsePoint(field)
+		if err != nil {
+			return nil, err
+		}
+		fence.Polygon = append(fence.Polygon, p)
+	}
+	return fence, nil
+}
+
+// distance returns the great-circle distance in meters between two points.
+func distance(a, b point) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+// Contains returns true if p is inside the geofence, polygons are treated as
+// planar which is accurate enough for the size of a site.
+func (f *Geofence) Contains(p point) bool {
+	if f.Radius > 0 {
+		return distance(f.Center, p) <= f.Radius
+	}
+
+	inside := false
+	for i, j := 0, len(f.Polygon)-1; i < len(f.Polygon); j, i = i, i+1 {
+		a, b := f.Polygon[i], f.Polygon[j]
+		if (a.Latitude > p.Latitude) != (b.Latitude > p.Latitude) &&
+			p.Longitude < (b.Longitude-a.Longitude)*(p.Latitude-a.Latitude)/(b.Latitude-a.Latitude)+a.Longitude {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func (f *Geofence) String() string {
+	if f.Radius > 0 {
+		return fmt.Sprintf("%fm around %f,%f", f.Radius, f.Center.Latitude, f.Center.Longitude)
+	}
+
+	vertices := make([]string, 0, len(f.Polygon))
+	for _, p := range f.Polygon {
+		vertices = append(vertices, fmt.Sprintf("%f,%f", p.Latitude, p.Longitude))
+	}
+	return "polygon " + strings.Join(vertices, " ")
+}
+
+func (mod *GPS) addGeofence(name string, spec string) error {
+	fence, err := parseGeofence(name, spec)
+	if err != nil {
+		return err
+	}
+
+	mod.geofences.Lock()
+	_, replaced := mod.geofences.fences[name]
+	mod.geofences.fences[name] = fence
+	mod.geofences.Unlock()
+
+	if replaced {
+		mod.Info("geofence %s replaced with %s", tui.Bold(name), fence)
+	} else {
+		mod.Info("geofence %s added: %s", tui.Bold(name), fence)
+	}
+	return nil
+}
+
+func (mod *GPS) delGeofence(name string) error {
+	mod.geofences.Lock()
+	defer mod.geofences.Unlock()
+
+	if _, found := mod.geofences.fences[name]; !found {
+		return fmt.Errorf("geofence %s not found", name)
+	}
+	delete(mod.geofences.fences, name)
+	return nil
+}
+
+// checkGeofences emits an event for every geofence the last fix entered or
+// exited, fixes with no position are ignored.
+func (mod *GPS) checkGeofences() {
+	if mod.Session.GPS.FixQuality == nmea.Invalid || mod.Session.GPS.FixQuality == "" {
+		return
+	}
+
+	pos := point{
+		Latitude:  mod.Session.GPS.Latitude,
+		Longitude: mod.Session.GPS.Longitude,
+	}
+
+	mod.geofences.Lock()
+	defer mod.geofences.Unlock()
+
+	for _, fence := range mod.geofences.fences {
+		inside := fence.Contains(pos)
+		if fence.inside != nil && *fence.inside == inside {
+			continue
+		}
+		fence.inside = &inside
+
+		tag := "gps.geofence.exit"
+		if inside {
+			tag = "gps.geofence.enter"
+		}
+		mod.Session.Events.Add(tag, GeofenceEvent{
+			Name:      fence.Name,
+			Latitude:  pos.Latitude,
+			Longitude: pos.Longitude,
+		})
+	}
+}
+
+func (mod *GPS) showGeofences() error {
+	mod.geofences.Lock()
+	defer mod.geofences.Unlock()
+
+	if len(mod.geofences.fences) == 0 {
+		mod.Session.Println("no geofences defined.")
+		return nil
+	}
+
+	names := make([]string, 0, len(mod.geofences.fences))
+	for name := range mod.geofences.fences {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		fence := mod.geofences.fences[name]
+		state := tui.Dim("unknown")
+		if fence.inside != nil && *fence.inside {
+			state = tui.Green("inside")
+		} else if fence.inside != nil {
+			state = tui.Red("outside")
+		}
+		rows = append(rows, []string{tui.Bold(name), fence.String(), state})
+	}
+
+	mod.Session.Table([]string{"Name", "Area", "State"}, rows)
+	return nil
+}
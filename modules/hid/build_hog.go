@@ -0,0 +1,33 @@
+package hid
+
+import (
+	"github.com/bettercap/bettercap/network"
+)
+
+const (
+	hogFrameDelay = 10
+)
+
+// HoGBuilder builds the keyboard input reports sent as notifications by the
+// HID-over-GATT keyboard hid.inject.ble emulates, the device is not used.
+type HoGBuilder struct {
+}
+
+func (b HoGBuilder) frameFor(cmd *Command) []byte {
+	return []byte{cmd.Mode, 0, cmd.HID, 0, 0, 0, 0, 0}
+}
+
+func (b HoGBuilder) BuildFrames(dev *network.HIDDevice, commands []*Command) error {
+	for _, cmd := range commands {
+		if cmd.IsHID() {
+			// every key press is followed by a release, or repeated keys
+			// would be seen as held down
+			cmd.AddFrame(b.frameFor(cmd), hogFrameDelay)
+			cmd.AddFrame(b.frameFor(&Command{}), hogFrameDelay)
+		} else if cmd.IsSleep() {
+			cmd.AddFrame(b.frameFor(&Command{}), cmd.Sleep)
+		}
+	}
+
+	return nil
+}
@@ -17,6 +17,10 @@ type HIDRecon struct {
 	dongle       *nrf24.Dongle
 	waitGroup    *sync.WaitGroup
 	channel      int
+	sweepChannel int
+	hopFocus     bool
+	focusTurn    bool
+	focusIdx     int
 	hopPeriod    time.Duration
 	pingPeriod   time.Duration
 	sniffPeriod  time.Duration
@@ -33,6 +37,7 @@ type HIDRecon struct {
 	sniffSilent  bool
 	inPromMode   bool
 	inInjectMode bool
+	bleInjecting bool
 	keyLayout    string
 	scriptPath   string
 	parser       DuckyParser
@@ -52,6 +57,7 @@ func NewHIDRecon(s *session.Session) *HIDRecon {
 		lastPing:      time.Now(),
 		useLNA:        true,
 		channel:       1,
+		sweepChannel:  1,
 		sniffAddrRaw:  nil,
 		sniffAddr:     "",
 		inSniffMode:   false,
@@ -102,6 +108,16 @@ func NewHIDRecon(s *session.Session) *HIDRecon {
 			return mod.Show()
 		}))
 
+	channels := session.NewModuleHandler("hid.show.channels ADDRESS", `(?i)^hid\.show\.channels ([a-f0-9]{1,2}:[a-f0-9]{1,2}:[a-f0-9]{1,2}:[a-f0-9]{1,2}:[a-f0-9]{1,2})$`,
+		"Show the channels the device with the given ADDRESS has been seen on, how many frames were received on each one and when the last one was.",
+		func(args []string) error {
+			return mod.showChannels(args[0])
+		})
+
+	channels.Complete("hid.show.channels", s.HIDCompleter)
+
+	mod.AddHandler(channels)
+
 	inject := session.NewModuleHandler("hid.inject ADDRESS LAYOUT FILENAME", `(?i)^hid\.inject ([a-f0-9]{2}:[a-f0-9]{2}:[a-f0-9]{2}:[a-f0-9]{2}:[a-f0-9]{2})\s+(.+)\s+(.+)$`,
 		"Parse the duckyscript FILENAME and inject it as HID frames spoofing the device ADDRESS, using the LAYOUT keyboard mapping.",
 		func(args []string) error {
@@ -117,6 +133,12 @@ func NewHIDRecon(s *session.Session) *HIDRecon {
 
 	mod.AddHandler(inject)
 
+	mod.AddHandler(session.NewModuleHandler("hid.inject.ble LAYOUT FILENAME", `(?i)^hid\.inject\.ble\s+(.+)\s+(.+)$`,
+		"Advertise a Bluetooth LE HID-over-GATT keyboard and inject the duckyscript FILENAME with the LAYOUT keyboard mapping into the first host connecting to it, hid.recon doesn't need to be running.",
+		func(args []string) error {
+			return mod.injectBLE(args[0], args[1])
+		}))
+
 	mod.AddParam(session.NewBoolParameter("hid.lna",
 		"true",
 		"If true, enable the LNA power amplifier for CrazyRadio devices."))
@@ -125,6 +147,10 @@ func NewHIDRecon(s *session.Session) *HIDRecon {
 		"100",
 		"Time in milliseconds to stay on each channel before hopping to the next one."))
 
+	mod.AddParam(session.NewBoolParameter("hid.hop.focus",
+		"false",
+		"If true, every other hop will be on one of the channels the detected devices are using instead of the next one."))
+
 	mod.AddParam(session.NewIntParameter("hid.ping.period",
 		"100",
 		"Time in milliseconds to attempt to ping a device on a given channel while in sniffer mode."))
@@ -140,6 +166,20 @@ func NewHIDRecon(s *session.Session) *HIDRecon {
 		fmt.Sprintf("(%s)", strings.Join(builders, "|")),
 		fmt.Sprintf("If the device is not visible or its type has not being detected, force the device type to this value. Accepted values: %s", strings.Join(builders, ", "))))
 
+	mod.AddParam(session.NewStringParameter("hid.ble.device",
+		"",
+		`^((hci)?\d+)?$`,
+		"HCI device to use for hid.inject.ble, like hci0 or 0, leave empty to use the first available one."))
+
+	mod.AddParam(session.NewStringParameter("hid.ble.name",
+		"Keyboard",
+		"",
+		"Name of the keyboard advertised by hid.inject.ble."))
+
+	mod.AddParam(session.NewIntParameter("hid.ble.timeout",
+		"60",
+		"Seconds hid.inject.ble waits for a host to connect and subscribe to the keyboard reports."))
+
 	mod.parser = DuckyParser{mod}
 	mod.selector = utils.ViewSelectorFor(&mod.SessionModule, "hid.show", []string{"mac", "seen"}, "mac desc")
 
@@ -176,6 +216,10 @@ func (mod *HIDRecon) Configure() error {
 		mod.hopPeriod = time.Duration(n) * time.Millisecond
 	}
 
+	if err, mod.hopFocus = mod.BoolParam("hid.hop.focus"); err != nil {
+		return err
+	}
+
 	if err, n = mod.IntParam("hid.ping.period"); err != nil {
 		return err
 	} else {
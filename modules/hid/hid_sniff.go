@@ -62,7 +62,8 @@ func (mod *HIDRecon) doPing() {
 	if time.Since(mod.lastPing) >= mod.pingPeriod {
 		// try on the current channel first
 		if err := mod.dongle.TransmitPayload(mod.pingPayload, 250, 1); err != nil {
-			for mod.channel = 1; mod.channel <= nrf24.TopChannel; mod.channel++ {
+			// then on the channels the device has been seen on
+			for _, mod.channel = range mod.pingChannels(mod.sniffAddr) {
 				if err := mod.dongle.SetChannel(mod.channel); err != nil {
 					mod.Error("error setting channel %d: %v", mod.channel, err)
 				} else if err = mod.dongle.TransmitPayload(mod.pingPayload, 250, 1); err == nil {
@@ -33,11 +33,109 @@ func NewHttpProxy(s *session.Session) *HttpProxy {
 		"",
 		"Path of a proxy JS script."))
 
+	mod.AddParam(session.NewStringParameter("http.proxy.rules",
+		"",
+		"",
+		"Path of a JSON file of rules matching on host, method, path, content type or body regex to replace, inject, redirect or reply with a status code without a proxy script, reloaded when it changes."))
+
 	mod.AddParam(session.NewStringParameter("http.proxy.injectjs",
 		"",
 		"",
 		"URL, path or javascript code to inject into every HTML page."))
 
+	mod.AddParam(session.NewBoolParameter("http.proxy.injectjs.template",
+		"false",
+		"If true, the injected javascript is a template that can use the {{.ClientIP}}, {{.ClientMAC}}, {{.Hostname}}, {{.Alias}}, {{.Vendor}}, {{.Host}}, {{.Path}}, {{.Campaign}} and {{.Collect}} variables."))
+
+	mod.AddParam(session.NewStringParameter("http.proxy.injectjs.campaign",
+		"",
+		"",
+		"Campaign identifier available to the injected javascript template as {{.Campaign}}."))
+
+	mod.AddParam(session.NewStringParameter("http.proxy.collect",
+		"/__bcap/collect",
+		"",
+		"Path of the collection endpoint that injected payloads can send data to (available as {{.Collect}}), every hit will generate a http.proxy.collect event, leave empty to disable."))
+
+	mod.AddParam(session.NewStringParameter("http.proxy.hook",
+		"",
+		"",
+		"URL of a BeEF (or any other C2) hook to inject into every proxied HTML page, for instance http://192.168.1.10:3000/hook.js ."))
+
+	mod.AddParam(session.NewStringParameter("http.proxy.hook.targets",
+		"",
+		"",
+		"Comma separated list of IP or MAC addresses to inject the hook for, leave empty to hook every victim."))
+
+	mod.AddParam(session.NewStringParameter("http.proxy.hook.exclude",
+		"",
+		"",
+		"Comma separated list of domains (subdomains included) the hook must not be injected into."))
+
+	mod.AddParam(session.NewBoolParameter("http.proxy.carve",
+		"false",
+		"If true, files transferred to the victims through the proxy are saved to http.proxy.carve.path, in a folder per victim with an index of their URL, content type and SHA256."))
+
+	mod.AddParam(session.NewStringParameter("http.proxy.carve.path",
+		"~/bettercap-carved",
+		"",
+		"Folder to save the carved files to."))
+
+	mod.AddParam(session.NewListParameter("http.proxy.carve.types",
+		"",
+		"Comma separated list of content types to carve, values ending with / match every subtype (like image/), if empty every type but text, javascript, json and xml is carved."))
+
+	mod.AddParam(session.NewIntParameter("http.proxy.carve.min.size",
+		"1024",
+		"Minimum size in bytes of the carved files."))
+
+	mod.AddParam(session.NewIntParameter("http.proxy.carve.max.size",
+		"52428800",
+		"Maximum size in bytes of the carved files."))
+
+	mod.AddParam(session.NewBoolParameter("http.proxy.raw",
+		"false",
+		"If true, connections are relayed upstream exactly as the clients sent them, without normalizing the requests, to test HTTP request smuggling and desync behaviors, the onRawRequest and onRawResponse script callbacks get the bytes relayed in each direction and ambiguous request framings are reported, but nothing is injected, stripped or carved."))
+
+	mod.AddParam(session.NewBoolParameter("http.proxy.cache",
+		"false",
+		"If true, the responses to GET requests are saved to http.proxy.cache.path and served from there when the upstream server can't be reached."))
+
+	mod.AddParam(session.NewBoolParameter("http.proxy.cache.offline",
+		"false",
+		"If true together with http.proxy.cache, every response is served from the cache without contacting the upstream servers, the requests not cached get a 404."))
+
+	mod.AddParam(session.NewStringParameter("http.proxy.cache.path",
+		"~/bettercap-http-cache",
+		"",
+		"Folder to save the cached responses to, it's kept across sessions."))
+
+	mod.AddParam(session.NewIntParameter("http.proxy.cache.max.size",
+		"10485760",
+		"Maximum size in bytes of the cached responses."))
+
+	mod.AddParam(session.NewListParameter("http.proxy.challenge",
+		"",
+		"Comma separated list of regular expressions matching the host and path of the requests (like intranet.corp.com/admin) to answer with an authentication challenge until the victim sends credentials, leave empty to disable."))
+
+	mod.AddParam(session.NewStringParameter("http.proxy.challenge.scheme",
+		"basic",
+		"^(basic|ntlm)$",
+		"Authentication scheme of the challenge, basic captures clear text credentials, ntlm the NTLMv1/v2 hashes."))
+
+	mod.AddParam(session.NewBoolParameter("http.proxy.challenge.proxy",
+		"false",
+		"If true, the challenge is a 407 for proxy authentication, which browsers only answer when configured to use the proxy (for instance with WPAD), otherwise a 401."))
+
+	mod.AddParam(session.NewStringParameter("http.proxy.challenge.realm",
+		"",
+		"",
+		"Realm of the basic challenge and domain of the NTLM one, if empty the host of the request and WORKGROUP are used."))
+
+	mod.AddParam(session.NewListParameter("http.proxy.challenge.targets",
+		"",
+		"Comma separated list of IP or MAC addresses of the victims to challenge, leave empty to challenge every victim in scope."))
+
 	mod.AddParam(session.NewBoolParameter("http.proxy.sslstrip",
 		"false",
 		"Enable or disable SSL stripping."))
@@ -48,6 +146,12 @@ func NewHttpProxy(s *session.Session) *HttpProxy {
 			return mod.Start()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("http.proxy.hook.show", "",
+		"Show which victims have been injected with the hook and which ones loaded it.",
+		func(args []string) error {
+			return mod.proxy.ShowHooks()
+		}))
+
 	mod.AddHandler(session.NewModuleHandler("http.proxy off", "",
 		"Stop HTTP proxy.",
 		func(args []string) error {
@@ -92,11 +196,102 @@ func (mod *HttpProxy) Configure() error {
 		return err
 	} else if err, jsToInject = mod.StringParam("http.proxy.injectjs"); err != nil {
 		return err
+	} else if err, mod.proxy.Campaign = mod.StringParam("http.proxy.injectjs.campaign"); err != nil {
+		return err
+	} else if err, mod.proxy.Template = mod.BoolParam("http.proxy.injectjs.template"); err != nil {
+		return err
+	} else if err, mod.proxy.CollectPath = mod.StringParam("http.proxy.collect"); err != nil {
+		return err
+	} else if err, mod.proxy.HookURL = mod.StringParam("http.proxy.hook"); err != nil {
+		return err
+	} else if err, mod.proxy.HookTargets = mod.ListParam("http.proxy.hook.targets"); err != nil {
+		return err
+	} else if err, mod.proxy.HookExclude = mod.ListParam("http.proxy.hook.exclude"); err != nil {
+		return err
+	} else if err, mod.proxy.RulesFile = mod.StringParam("http.proxy.rules"); err != nil {
+		return err
+	} else if err, mod.proxy.Raw = mod.BoolParam("http.proxy.raw"); err != nil {
+		return err
+	}
+
+	if err = mod.configureCarver(); err != nil {
+		return err
+	} else if err = mod.configureCache(); err != nil {
+		return err
+	} else if err = mod.configureChallenge(); err != nil {
+		return err
 	}
 
 	return mod.proxy.Configure(address, proxyPort, httpPort, scriptPath, jsToInject, stripSSL)
 }
 
+// configureCarver copies the carving parameters to the proxy, an empty path
+// disables it.
+func (mod *HttpProxy) configureCarver() error {
+	var err error
+	var enabled bool
+	var minSize, maxSize int
+
+	mod.proxy.CarvePath = ""
+	if err, enabled = mod.BoolParam("http.proxy.carve"); err != nil || !enabled {
+		return err
+	} else if err, mod.proxy.CarvePath = mod.StringParam("http.proxy.carve.path"); err != nil {
+		return err
+	} else if err, mod.proxy.CarveTypes = mod.ListParam("http.proxy.carve.types"); err != nil {
+		return err
+	} else if err, minSize = mod.IntParam("http.proxy.carve.min.size"); err != nil {
+		return err
+	} else if err, maxSize = mod.IntParam("http.proxy.carve.max.size"); err != nil {
+		return err
+	}
+
+	mod.proxy.CarveMinSize = int64(minSize)
+	mod.proxy.CarveMaxSize = int64(maxSize)
+	return nil
+}
+
+// configureCache copies the caching parameters to the proxy, an empty path
+// disables it.
+func (mod *HttpProxy) configureCache() error {
+	var err error
+	var enabled bool
+	var maxSize int
+
+	mod.proxy.CachePath = ""
+	if err, enabled = mod.BoolParam("http.proxy.cache"); err != nil || !enabled {
+		return err
+	} else if err, mod.proxy.CachePath = mod.StringParam("http.proxy.cache.path"); err != nil {
+		return err
+	} else if err, mod.proxy.CacheOffline = mod.BoolParam("http.proxy.cache.offline"); err != nil {
+		return err
+	} else if err, maxSize = mod.IntParam("http.proxy.cache.max.size"); err != nil {
+		return err
+	}
+
+	mod.proxy.CacheMaxSize = int64(maxSize)
+	return nil
+}
+
+// configureChallenge copies the authentication challenge parameters to the
+// proxy, an empty list of patterns disables it.
+func (mod *HttpProxy) configureChallenge() error {
+	var err error
+
+	if err, mod.proxy.ChallengeURLs = mod.ListParam("http.proxy.challenge"); err != nil || len(mod.proxy.ChallengeURLs) == 0 {
+		return err
+	} else if err, mod.proxy.ChallengeScheme = mod.StringParam("http.proxy.challenge.scheme"); err != nil {
+		return err
+	} else if err, mod.proxy.ChallengeProxy = mod.BoolParam("http.proxy.challenge.proxy"); err != nil {
+		return err
+	} else if err, mod.proxy.ChallengeRealm = mod.StringParam("http.proxy.challenge.realm"); err != nil {
+		return err
+	} else if err, mod.proxy.ChallengeTargets = mod.ListParam("http.proxy.challenge.targets"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (mod *HttpProxy) Start() error {
 	if err := mod.Configure(); err != nil {
 		return err
@@ -7,6 +7,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"html/template"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -41,12 +42,46 @@ type HTTPProxy struct {
 	Script      *HttpProxyScript
 	CertFile    string
 	KeyFile     string
-
+	Campaign    string
+	Template    bool
+	CollectPath string
+	HookURL     string
+	HookTargets []string
+	HookExclude []string
+	// if set, only the TLS connections matching them are intercepted
+	Targets []string
+	// if set, connections are relayed upstream without parsing the requests
+	Raw       bool
+	RulesFile string
+	// if set, files transferred to the victims are saved here
+	CarvePath    string
+	CarveTypes   []string
+	CarveMinSize int64
+	CarveMaxSize int64
+	// if set, responses are cached here and served when upstream is down
+	CachePath    string
+	CacheOffline bool
+	CacheMaxSize int64
+	// if set, the requests matching them are challenged for credentials
+	ChallengeURLs    []string
+	ChallengeTargets []string
+	ChallengeScheme  string
+	ChallengeProxy   bool
+	ChallengeRealm   string
+
+	rules       *proxyRules
+	carver      *fileCarver
+	cache       *responseCache
+	challenger  *authChallenger
 	jsHook      string
+	jsTemplate  *template.Template
+	hookHost    string
+	hooks       *hookTracker
 	isTLS       bool
 	isRunning   bool
 	stripper    *SSLStripper
 	sniListener net.Listener
+	rawListener net.Listener
 	sess        *session.Session
 	tag         string
 }
@@ -65,6 +100,7 @@ func NewHTTPProxy(s *session.Session) *HTTPProxy {
 		Proxy:    goproxy.NewProxyHttpServer(),
 		sess:     s,
 		stripper: NewSSLStripper(s, false),
+		hooks:    newHookTracker(),
 		isTLS:    false,
 		Server:   nil,
 		tag:      session.AsTag("http.proxy"),
@@ -155,6 +191,29 @@ func (p *HTTPProxy) Configure(address string, proxyPort int, httpPort int, scrip
 		p.jsHook = fmt.Sprintf("%s</head>", jsToInject)
 	}
 
+	if err := p.compilePayload(); err != nil {
+		return fmt.Errorf("error parsing the javascript payload template: %s", err)
+	} else if err := p.configureHook(); err != nil {
+		return err
+	} else if err := p.configureCarver(); err != nil {
+		return err
+	} else if err := p.configureCache(); err != nil {
+		return err
+	} else if err := p.configureChallenge(); err != nil {
+		return err
+	}
+
+	p.rules = nil
+	if p.RulesFile != "" {
+		if p.RulesFile, err = fs.Expand(p.RulesFile); err != nil {
+			return err
+		} else if p.rules, err = loadProxyRules(p.RulesFile); err != nil {
+			return err
+		} else {
+			p.Info("loaded %d rules from %s", len(p.rules.rules), p.RulesFile)
+		}
+	}
+
 	if scriptPath != "" {
 		if err, p.Script = LoadHttpProxyScript(scriptPath, p.sess); err != nil {
 			return err
@@ -325,6 +384,11 @@ func (p *HTTPProxy) httpsWorker() error {
 				return
 			}
 
+			if !p.isMITMTarget(stripPort(c.RemoteAddr().String()), hostname) {
+				p.passThrough(tlsConn, hostname)
+				return
+			}
+
 			p.Debug("proxying connection from %s to %s", tui.Bold(stripPort(c.RemoteAddr().String())), tui.Yellow(hostname))
 
 			req := &http.Request{
@@ -356,6 +420,8 @@ func (p *HTTPProxy) Start() {
 
 		if p.isTLS {
 			err = p.httpsWorker()
+		} else if p.Raw {
+			err = p.rawWorker()
 		} else {
 			err = p.httpWorker()
 		}
@@ -377,10 +443,18 @@ func (p *HTTPProxy) Stop() error {
 
 	p.sess.UnkCmdCallback = nil
 
+	if p.Script != nil {
+		p.Script.Unload()
+	}
+
 	if p.isTLS {
 		p.isRunning = false
 		p.sniListener.Close()
 		return nil
+	} else if p.Raw {
+		p.isRunning = false
+		p.rawListener.Close()
+		return nil
 	} else {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -0,0 +1,204 @@
+package http_proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/elazarl/goproxy"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+// cachedResponse is a response saved by the cache, one json file per URL.
+type cachedResponse struct {
+	Time   time.Time   `json:"time"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// responseCache saves the responses to the GET requests going through the
+// proxy and serves them back when the upstream server can't be reached, or
+// always in offline mode, without contacting the upstream servers at all.
+type responseCache struct {
+	sync.Mutex
+	path      string
+	offline   bool
+	maxSize   int64
+	transport http.RoundTripper
+}
+
+func newResponseCache(basePath string, offline bool, maxSize int64, transport http.RoundTripper) (*responseCache, error) {
+	basePath, err := fs.Expand(basePath)
+	if err != nil {
+		return nil, err
+	} else if maxSize <= 0 {
+		return nil, fmt.Errorf("invalid cache max size %d", maxSize)
+	}
+
+	c := &responseCache{
+		path:      basePath,
+		offline:   offline,
+		maxSize:   maxSize,
+		transport: transport,
+	}
+	return c, os.MkdirAll(basePath, os.ModePerm)
+}
+
+func cacheURL(req *http.Request) string {
+	if req.URL.Host == "" {
+		return "http://" + req.Host + req.URL.RequestURI()
+	}
+	return req.URL.String()
+}
+
+func (c *responseCache) fileFor(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return filepath.Join(c.path, hex.EncodeToString(hash[:])+".json")
+}
+
+// get returns the cached response to the request, or nil if there's none.
+func (c *responseCache) get(req *http.Request) *http.Response {
+	if req.Method != "GET" {
+		return nil
+	}
+
+	c.Lock()
+	raw, err := ioutil.ReadFile(c.fileFor(cacheURL(req)))
+	c.Unlock()
+	if err != nil {
+		return nil
+	}
+
+	var cached cachedResponse
+	if err = json.Unmarshal(raw, &cached); err != nil {
+		return nil
+	}
+
+	res := &http.Response{
+		Status:        fmt.Sprintf("%d %s", cached.Status, http.StatusText(cached.Status)),
+		StatusCode:    cached.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cached.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       req,
+	}
+	res.Header.Set("Content-Length", strconv.Itoa(len(cached.Body)))
+	return res
+}
+
+// wants returns true if the response could be cached before reading its body,
+// server errors and partial contents are not.
+func (c *responseCache) wants(req *http.Request, res *http.Response) bool {
+	return req.Method == "GET" &&
+		res.StatusCode < 500 &&
+		res.StatusCode != http.StatusPartialContent &&
+		res.ContentLength <= c.maxSize
+}
+
+// put saves the response if its body is not bigger than maxSize, leaving it
+// intact for the client.
+func (c *responseCache) put(req *http.Request, res *http.Response) error {
+	var raw []byte
+	var err error
+
+	if res.Body != nil {
+		raw, err = ioutil.ReadAll(io.LimitReader(res.Body, c.maxSize+1))
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(raw), res.Body), res.Body}
+
+		if err != nil {
+			return err
+		} else if int64(len(raw)) > c.maxSize {
+			return nil
+		}
+	}
+
+	data, err := json.Marshal(cachedResponse{
+		Time:   time.Now(),
+		URL:    cacheURL(req),
+		Status: res.StatusCode,
+		Header: res.Header,
+		Body:   raw,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	return ioutil.WriteFile(c.fileFor(cacheURL(req)), data, 0644)
+}
+
+// roundTrip sends the request upstream and caches the response, unless in
+// offline mode, upErr is set if the cached response is served because of it.
+func (c *responseCache) roundTrip(req *http.Request) (res *http.Response, cached bool, upErr error) {
+	if c.offline {
+		if res = c.get(req); res != nil {
+			return res, true, nil
+		}
+		return goproxy.NewResponse(req, goproxy.ContentTypeText, http.StatusNotFound, "Not Found"), false, nil
+	}
+
+	if res, upErr = c.transport.RoundTrip(req); upErr != nil {
+		if res = c.get(req); res != nil {
+			return res, true, upErr
+		}
+		return nil, false, upErr
+	}
+
+	if c.wants(req, res) {
+		if err := c.put(req, res); err != nil {
+			return res, false, fmt.Errorf("error caching %s: %s", cacheURL(req), err)
+		}
+	}
+	return res, false, nil
+}
+
+// cachedRoundTrip is used by the proxy instead of its transport for the
+// requests that are not answered by the filters.
+func (p *HTTPProxy) cachedRoundTrip(req *http.Request, ctx *goproxy.ProxyCtx) (*http.Response, error) {
+	res, cached, err := p.cache.roundTrip(req)
+	if cached && err != nil {
+		p.Info("%s unreachable (%s), serving %s from the cache", req.Host, err, tui.Yellow(cacheURL(req)))
+	} else if cached {
+		p.Debug("serving %s from the cache", cacheURL(req))
+	} else if res != nil && err != nil {
+		p.Warning("%s", err)
+	} else if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *HTTPProxy) configureCache() (err error) {
+	p.cache = nil
+	if p.CachePath != "" {
+		if p.cache, err = newResponseCache(p.CachePath, p.CacheOffline, p.CacheMaxSize, p.Proxy.Tr); err != nil {
+			return fmt.Errorf("error configuring the response cache: %s", err)
+		} else if p.CacheOffline {
+			p.Info("serving every response from the cache in %s", p.cache.path)
+		} else {
+			p.Info("caching responses to %s", p.cache.path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package http_proxy
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+)
+
+type cacheTestTransport struct {
+	body string
+	err  error
+	hits int
+}
+
+func (t *cacheTestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.hits++
+	if t.err != nil {
+		return nil, t.err
+	}
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": []string{"text/html"}},
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(t.body))),
+		ContentLength: int64(len(t.body)),
+		Request:       req,
+	}, nil
+}
+
+func cacheRequest(method, rawURL string) *http.Request {
+	u, _ := url.Parse(rawURL)
+	return &http.Request{Method: method, URL: u, Host: u.Host, Header: make(http.Header)}
+}
+
+func readCached(t *testing.T, res *http.Response) string {
+	raw, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(raw)
+}
+
+func TestResponseCacheFallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tr := &cacheTestTransport{body: "<html>online</html>"}
+	c, err := newResponseCache(dir, false, 1024, tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, cached, err := c.roundTrip(cacheRequest("GET", "http://example.com/index.html"))
+	if err != nil {
+		t.Fatal(err)
+	} else if cached {
+		t.Fatal("expected the first response to come from upstream")
+	} else if body := readCached(t, res); body != tr.body {
+		t.Fatalf("expected the client to get the original body, got '%s'", body)
+	}
+
+	tr.err = errors.New("connection refused")
+	if res, cached, err = c.roundTrip(cacheRequest("GET", "http://example.com/index.html")); !cached || err != tr.err {
+		t.Fatalf("expected the cached response because of the upstream error, got %v", err)
+	} else if body := readCached(t, res); body != "<html>online</html>" {
+		t.Fatalf("unexpected cached body '%s'", body)
+	} else if res.ContentLength != int64(len(body)) {
+		t.Fatalf("unexpected content length %d", res.ContentLength)
+	}
+
+	if res, _, _ = c.roundTrip(cacheRequest("GET", "http://example.com/other.html")); res != nil {
+		t.Fatal("expected no response for a request not cached")
+	} else if res, _, _ = c.roundTrip(cacheRequest("POST", "http://example.com/index.html")); res != nil {
+		t.Fatal("expected POST requests not to be served from the cache")
+	}
+}
+
+func TestResponseCacheOffline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tr := &cacheTestTransport{body: "big enough to not be cached"}
+	online, err := newResponseCache(dir, false, 8, tr)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, _, err = online.roundTrip(cacheRequest("GET", "http://example.com/big")); err != nil {
+		t.Fatal(err)
+	}
+
+	tr.body = "small"
+	if _, _, err = online.roundTrip(cacheRequest("GET", "http://example.com/small")); err != nil {
+		t.Fatal(err)
+	}
+
+	offline, err := newResponseCache(dir, true, 8, tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hits := tr.hits
+	if res, cached, _ := offline.roundTrip(cacheRequest("GET", "http://example.com/small")); !cached {
+		t.Fatal("expected the response to be served from the cache")
+	} else if body := readCached(t, res); body != "small" {
+		t.Fatalf("unexpected cached body '%s'", body)
+	} else if res, cached, _ = offline.roundTrip(cacheRequest("GET", "http://example.com/big")); cached {
+		t.Fatal("expected responses bigger than the max size not to be cached")
+	} else if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a 404 for a response not cached, got %d", res.StatusCode)
+	} else if tr.hits != hits {
+		t.Fatal("expected the offline cache not to contact the upstream servers")
+	}
+}
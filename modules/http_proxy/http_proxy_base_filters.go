@@ -21,6 +21,20 @@ func (p *HTTPProxy) fixRequestHeaders(req *http.Request) {
 func (p *HTTPProxy) onRequestFilter(req *http.Request, ctx *goproxy.ProxyCtx) (*http.Request, *http.Response) {
 	p.Debug("< %s %s %s%s", req.RemoteAddr, req.Method, req.Host, req.URL.Path)
 
+	if p.isHookCallback(req) {
+		if res := p.onHookCallback(req); res != nil {
+			return req, res
+		}
+	} else if p.isCollectRequest(req) {
+		return req, p.onCollect(req)
+	} else if res := p.challenge(req); res != nil {
+		return req, res
+	}
+
+	if p.cache != nil {
+		ctx.RoundTripper = goproxy.RoundTripperFunc(p.cachedRoundTrip)
+	}
+
 	p.fixRequestHeaders(req)
 
 	redir := p.stripper.Preprocess(req, ctx)
@@ -30,6 +44,10 @@ func (p *HTTPProxy) onRequestFilter(req *http.Request, ctx *goproxy.ProxyCtx) (*
 		return req, redir
 	}
 
+	if res := p.applyRequestRules(req); res != nil {
+		return req, res
+	}
+
 	// do we have a proxy script?
 	if p.Script == nil {
 		return req, nil
@@ -82,7 +100,7 @@ func (p *HTTPProxy) getHeader(res *http.Response, header string) string {
 }
 
 func (p *HTTPProxy) isScriptInjectable(res *http.Response) (bool, string) {
-	if p.jsHook == "" {
+	if p.jsHook == "" && p.hookHost == "" {
 		return false, ""
 	} else if contentType := p.getHeader(res, "Content-Type"); strings.Contains(contentType, "text/html") {
 		return true, contentType
@@ -91,6 +109,19 @@ func (p *HTTPProxy) isScriptInjectable(res *http.Response) (bool, string) {
 }
 
 func (p *HTTPProxy) doScriptInjection(res *http.Response, cType string) (error, *http.Response) {
+	err, payload := p.renderPayload(res.Request)
+	if err != nil {
+		return err, nil
+	} else if payload == "" {
+		payload = "</head>"
+	}
+
+	hook := p.hookFor(res.Request)
+	if hook == "" && payload == "</head>" {
+		return nil, nil
+	}
+	payload = hook + payload
+
 	defer res.Body.Close()
 
 	raw, err := ioutil.ReadAll(res.Body)
@@ -98,12 +129,17 @@ func (p *HTTPProxy) doScriptInjection(res *http.Response, cType string) (error,
 		return err, nil
 	} else if html := string(raw); strings.Contains(html, "</head>") {
 		p.Info("> injecting javascript (%d bytes) into %s (%d bytes) for %s",
-			len(p.jsHook),
+			len(payload),
 			tui.Yellow(res.Request.Host+res.Request.URL.Path),
 			len(raw),
 			tui.Bold(strings.Split(res.Request.RemoteAddr, ":")[0]))
+		p.sess.Meter(p.Name, "injected", "%s for %s", res.Request.Host, strings.Split(res.Request.RemoteAddr, ":")[0])
 
-		html = strings.Replace(html, "</head>", p.jsHook, -1)
+		if hook != "" {
+			p.hooks.injected(stripPort(res.Request.RemoteAddr))
+		}
+
+		html = strings.Replace(html, "</head>", payload, -1)
 		newResp := goproxy.NewResponse(res.Request, cType, res.StatusCode, html)
 		for k, vv := range res.Header {
 			for _, v := range vv {
@@ -129,6 +165,12 @@ func (p *HTTPProxy) onResponseFilter(res *http.Response, ctx *goproxy.ProxyCtx)
 
 	p.stripper.Process(res, ctx)
 
+	p.carve(res)
+
+	if ruled := p.applyResponseRules(res); ruled != nil {
+		res = ruled
+	}
+
 	// do we have a proxy script?
 	if p.Script != nil {
 		_, jsres := p.Script.OnResponse(res)
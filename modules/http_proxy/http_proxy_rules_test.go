@@ -0,0 +1,122 @@
+package http_proxy
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestParseProxyRules(t *testing.T) {
+	var units = []struct {
+		raw   string
+		valid bool
+	}{
+		{`[]`, true},
+		{`[{"action": "status", "status": 404}]`, true},
+		{`[{"action": "redirect", "location": "http://evil.com/"}]`, true},
+		{`[{"action": "inject", "content": "<script></script>"}]`, true},
+		{`[{"action": "replace", "regex": "logo\\.png", "with": "evil.png"}]`, true},
+		{`[{"action": "replace", "with": "evil.png"}]`, false},
+		{`[{"action": "inject"}]`, false},
+		{`[{"action": "redirect"}]`, false},
+		{`[{"action": "status", "status": 42}]`, false},
+		{`[{"action": "drop"}]`, false},
+		{`[{"action": "status", "status": 404, "path": "("}]`, false},
+		{`[{"action": "replace", "regex": "(", "with": ""}]`, false},
+		{`{"action": "status", "status": 404}`, false},
+		{`not json`, false},
+	}
+
+	for _, u := range units {
+		if _, err := ParseProxyRules([]byte(u.raw)); (err == nil) != u.valid {
+			t.Fatalf("expected %s to be valid=%v, got %v", u.raw, u.valid, err)
+		}
+	}
+}
+
+func TestProxyRuleDefaults(t *testing.T) {
+	rules, err := ParseProxyRules([]byte(`[
+		{"action": "redirect", "location": "http://evil.com/", "host": "*.Example.COM"},
+		{"action": "inject", "content": "<script></script>", "content_type": "Text/HTML"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rules[0].Status != http.StatusFound {
+		t.Fatalf("expected status %d, got %d", http.StatusFound, rules[0].Status)
+	} else if rules[0].Host != "example.com" {
+		t.Fatalf("expected host example.com, got %s", rules[0].Host)
+	} else if !rules[0].onRequest() {
+		t.Fatal("expected redirect rule to be applied on the request")
+	} else if rules[1].Before != "</head>" {
+		t.Fatalf("expected inject rule before </head>, got %s", rules[1].Before)
+	} else if rules[1].ContentType != "text/html" {
+		t.Fatalf("expected content type text/html, got %s", rules[1].ContentType)
+	} else if rules[1].onRequest() {
+		t.Fatal("expected inject rule to be applied on the response")
+	}
+}
+
+func buildRuleResponse(method, host, path, contentType string) *http.Response {
+	return &http.Response{
+		Request: &http.Request{
+			Method: method,
+			Host:   host,
+			URL:    &url.URL{Path: path},
+		},
+		Header: http.Header{"Content-Type": []string{contentType}},
+	}
+}
+
+func TestProxyRuleMatchesResponse(t *testing.T) {
+	var units = []struct {
+		rule    string
+		res     *http.Response
+		body    string
+		matches bool
+	}{
+		{`{"action": "status", "status": 404}`, buildRuleResponse("GET", "example.com", "/", "text/html"), "", true},
+		{`{"action": "status", "status": 404, "host": "example.com"}`, buildRuleResponse("GET", "www.example.com:8080", "/", ""), "", true},
+		{`{"action": "status", "status": 404, "host": "*.example.com"}`, buildRuleResponse("GET", "example.com", "/", ""), "", true},
+		{`{"action": "status", "status": 404, "host": "example.com"}`, buildRuleResponse("GET", "notexample.com", "/", ""), "", false},
+		{`{"action": "status", "status": 404, "method": "post"}`, buildRuleResponse("POST", "example.com", "/", ""), "", true},
+		{`{"action": "status", "status": 404, "method": "post"}`, buildRuleResponse("GET", "example.com", "/", ""), "", false},
+		{`{"action": "status", "status": 404, "path": "^/login"}`, buildRuleResponse("GET", "example.com", "/login.php", ""), "", true},
+		{`{"action": "status", "status": 404, "path": "^/login"}`, buildRuleResponse("GET", "example.com", "/index.php", ""), "", false},
+		{`{"action": "status", "status": 404, "content_type": "text/html"}`, buildRuleResponse("GET", "example.com", "/", "text/html; charset=utf-8"), "", true},
+		{`{"action": "status", "status": 404, "content_type": "text/html"}`, buildRuleResponse("GET", "example.com", "/", "application/json"), "", false},
+		{`{"action": "replace", "regex": "logo\\.png", "with": "evil.png"}`, buildRuleResponse("GET", "example.com", "/", "text/html"), "<img src='logo.png'>", true},
+		{`{"action": "replace", "regex": "logo\\.png", "with": "evil.png"}`, buildRuleResponse("GET", "example.com", "/", "text/html"), "<img src='logo.jpg'>", false},
+	}
+
+	for _, u := range units {
+		rules, err := ParseProxyRules([]byte("[" + u.rule + "]"))
+		if err != nil {
+			t.Fatal(err)
+		} else if got := rules[0].matchesResponse(u.res, []byte(u.body)); got != u.matches {
+			t.Fatalf("expected %s to match %s%s=%v, got %v", u.rule, u.res.Request.Host, u.res.Request.URL.Path, u.matches, got)
+		}
+	}
+}
+
+func TestProxyRuleApply(t *testing.T) {
+	var units = []struct {
+		rule string
+		body string
+		exp  string
+	}{
+		{`{"action": "replace", "regex": "(?i)logo\\.png", "with": "evil.png"}`, "<img src='LOGO.png'><img src='logo.png'>", "<img src='evil.png'><img src='evil.png'>"},
+		{`{"action": "inject", "content": "<script></script>"}`, "<head></head>", "<head><script></script></head>"},
+		{`{"action": "inject", "content": "<b>", "before": "</body>"}`, "<body></body></body>", "<body><b></body></body>"},
+	}
+
+	for _, u := range units {
+		rules, err := ParseProxyRules([]byte("[" + u.rule + "]"))
+		if err != nil {
+			t.Fatal(err)
+		} else if got := string(rules[0].apply([]byte(u.body))); got != u.exp {
+			t.Fatalf("expected '%s', got '%s'", u.exp, got)
+		}
+	}
+}
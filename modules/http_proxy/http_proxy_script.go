@@ -14,9 +14,13 @@ import (
 type HttpProxyScript struct {
 	*plugin.Plugin
 
-	doOnRequest  bool
-	doOnResponse bool
-	doOnCommand  bool
+	doOnRequest     bool
+	doOnResponse    bool
+	doOnRawRequest  bool
+	doOnRawResponse bool
+	doOnCommand     bool
+	unhook          func()
+	unsubscribe     func()
 }
 
 func LoadHttpProxyScript(path string, sess *session.Session) (err error, s *HttpProxyScript) {
@@ -42,14 +46,62 @@ func LoadHttpProxyScript(path string, sess *session.Session) (err error, s *Http
 	}
 
 	s = &HttpProxyScript{
-		Plugin:       plug,
-		doOnRequest:  plug.HasFunc("onRequest"),
-		doOnResponse: plug.HasFunc("onResponse"),
-		doOnCommand:  plug.HasFunc("onCommand"),
+		Plugin:          plug,
+		doOnRequest:     plug.HasFunc("onRequest"),
+		doOnResponse:    plug.HasFunc("onResponse"),
+		doOnRawRequest:  plug.HasFunc("onRawRequest"),
+		doOnRawResponse: plug.HasFunc("onRawResponse"),
+		doOnCommand:     plug.HasFunc("onCommand"),
+	}
+
+	if plug.HasFunc("onModuleEvent") {
+		s.unhook = sess.OnModuleEvent(func(event string, module string, err error) {
+			s.OnModuleEvent(event, module, err)
+		})
+	}
+
+	if plug.HasFunc("onRecord") {
+		s.unsubscribe = sess.Bus.Subscribe(path, session.TopicAll, s.OnRecord)
 	}
 	return
 }
 
+func (s *HttpProxyScript) OnModuleEvent(event string, module string, err error) {
+	errDesc := ""
+	if err != nil {
+		errDesc = err.Error()
+	}
+
+	if _, err := s.Call("onModuleEvent", event, module, errDesc); err != nil {
+		log.Error("Error while executing onModuleEvent callback: %+v", err)
+	}
+}
+
+// OnRecord passes the records published on the session bus to the script.
+func (s *HttpProxyScript) OnRecord(r session.Record) {
+	data, err := r.Object()
+	if err != nil {
+		log.Error("error while encoding the %s record: %v", r.Topic, err)
+		return
+	}
+
+	if _, err := s.Call("onRecord", r.Topic, r.Source, data); err != nil {
+		log.Error("Error while executing onRecord callback: %+v", err)
+	}
+}
+
+// Unload stops the delivery of the session events and records to the script.
+func (s *HttpProxyScript) Unload() {
+	if s.unhook != nil {
+		s.unhook()
+		s.unhook = nil
+	}
+	if s.unsubscribe != nil {
+		s.unsubscribe()
+		s.unsubscribe = nil
+	}
+}
+
 func (s *HttpProxyScript) OnRequest(original *http.Request) (jsreq *JSRequest, jsres *JSResponse) {
 	if s.doOnRequest {
 		jsreq := NewJSRequest(original)
@@ -87,6 +139,31 @@ func (s *HttpProxyScript) OnResponse(res *http.Response) (jsreq *JSRequest, jsre
 	return nil, nil
 }
 
+// onRaw passes a chunk of bytes relayed in raw mode to the callback, which can
+// return a string to replace them with, an empty one drops them.
+func (s *HttpProxyScript) onRaw(callback string, data []byte, client, host string) []byte {
+	if ret, err := s.Call(callback, string(data), client, host); err != nil {
+		log.Error("Error while executing %s callback: %+v", callback, err)
+	} else if replaced, ok := ret.(string); ok {
+		return []byte(replaced)
+	}
+	return data
+}
+
+func (s *HttpProxyScript) OnRawRequest(data []byte, client, host string) []byte {
+	if s.doOnRawRequest {
+		return s.onRaw("onRawRequest", data, client, host)
+	}
+	return data
+}
+
+func (s *HttpProxyScript) OnRawResponse(data []byte, client, host string) []byte {
+	if s.doOnRawResponse {
+		return s.onRaw("onRawResponse", data, client, host)
+	}
+	return data
+}
+
 func (s *HttpProxyScript) OnCommand(cmd string) bool {
 	if s.doOnCommand {
 		if ret, err := s.Call("onCommand", cmd); err != nil {
@@ -14,13 +14,16 @@ import (
 
 type HttpServer struct {
 	session.SessionModule
-	server *http.Server
+	server     *http.Server
+	tokens     *tokenStore
+	tokenParam string
 }
 
 func NewHttpServer(s *session.Session) *HttpServer {
 	mod := &HttpServer{
 		SessionModule: session.NewSessionModule("http.server", s),
 		server:        &http.Server{},
+		tokens:        newTokenStore(),
 	}
 
 	mod.AddParam(session.NewStringParameter("http.server.path",
@@ -37,6 +40,16 @@ func NewHttpServer(s *session.Session) *HttpServer {
 		"80",
 		"Port to bind the http server to."))
 
+	mod.AddParam(session.NewStringParameter("http.server.tokens.param",
+		"t",
+		`^[a-zA-Z0-9_\-]+$`,
+		"Name of the query parameter carrying the tracking tokens."))
+
+	mod.AddParam(session.NewStringParameter("http.server.tokens.file",
+		"",
+		"",
+		"If set, the tracking tokens and their hits are saved to and loaded from this JSON file."))
+
 	mod.AddHandler(session.NewModuleHandler("http.server on", "",
 		"Start httpd server.",
 		func(args []string) error {
@@ -49,6 +62,24 @@ func NewHttpServer(s *session.Session) *HttpServer {
 			return mod.Stop()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("http.server.token PATH LABEL?", `^http\.server\.token\s+([^\s]+)(\s+.+)?$`,
+		"Create a tracking token for the file served at PATH, optionally labeled with the target it's given to, and print its link: every request of the link sends an http.server.hit event.",
+		func(args []string) error {
+			return mod.addToken(args[0], strings.TrimSpace(args[1]))
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("http.server.token.del TOKEN", `^http\.server\.token\.del\s+([a-fA-F0-9]+)$`,
+		"Delete a tracking token.",
+		func(args []string) error {
+			return mod.delToken(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("http.server.tokens", "",
+		"Show the tracking tokens, their links and how many times they have been fetched.",
+		func(args []string) error {
+			return mod.showTokens()
+		}))
+
 	return mod
 }
 
@@ -76,6 +107,10 @@ func (mod *HttpServer) Configure() error {
 
 	if err, path = mod.StringParam("http.server.path"); err != nil {
 		return err
+	} else if err, mod.tokenParam = mod.StringParam("http.server.tokens.param"); err != nil {
+		return err
+	} else if err = mod.updateTokens(); err != nil {
+		return err
 	}
 
 	router := http.NewServeMux()
@@ -83,6 +118,9 @@ func (mod *HttpServer) Configure() error {
 
 	router.HandleFunc("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		mod.Info("%s %s %s%s", tui.Bold(strings.Split(r.RemoteAddr, ":")[0]), r.Method, r.Host, r.URL.Path)
+		if id := r.URL.Query().Get(mod.tokenParam); id != "" {
+			mod.onTokenHit(id, r)
+		}
 		fileServer.ServeHTTP(w, r)
 	}))
 
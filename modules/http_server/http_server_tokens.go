@@ -0,0 +1,295 @@
+package http_server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+const tokenSize = 8
+
+// Token tracks the requests of a link to a served file, given to a single
+// target in order to know if, when and from where it has been fetched.
+type Token struct {
+	ID       string    `json:"id"`
+	Path     string    `json:"path"`
+	Label    string    `json:"label"`
+	Created  time.Time `json:"created"`
+	Hits     int       `json:"hits"`
+	FirstHit time.Time `json:"first_hit"`
+	LastHit  time.Time `json:"last_hit"`
+}
+
+// HitEvent is the http.server.hit event, sent every time a token is fetched.
+type HitEvent struct {
+	Token     string        `json:"token"`
+	Label     string        `json:"label"`
+	Path      string        `json:"path"`
+	Address   string        `json:"address"`
+	UserAgent string        `json:"user_agent"`
+	Referer   string        `json:"referer"`
+	Hit       int           `json:"hit"`
+	Since     time.Duration `json:"since"`
+	SinceLast time.Duration `json:"since_last"`
+}
+
+// tokenStore keeps the tokens in memory and, if a file name is set, saves
+// them in order to keep tracking a campaign across sessions.
+type tokenStore struct {
+	sync.Mutex
+	fileName string
+	tokens   map[string]*Token
+}
+
+func newTokenStore() *tokenStore {
+	return &tokenStore{
+		tokens: make(map[string]*Token),
+	}
+}
+
+// Load replaces the tokens with the ones of the file, if it's not the one
+// already loaded, an empty name keeps the tokens in memory.
+func (s *tokenStore) Load(fileName string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if fileName != "" {
+		var err error
+		if fileName, err = fs.Expand(fileName); err != nil {
+			return err
+		}
+	}
+
+	if fileName == s.fileName {
+		return nil
+	}
+
+	tokens := make(map[string]*Token)
+	if fileName != "" {
+		if raw, err := ioutil.ReadFile(fileName); err == nil {
+			list := make([]*Token, 0)
+			if err = json.Unmarshal(raw, &list); err != nil {
+				return fmt.Errorf("error parsing %s: %v", fileName, err)
+			}
+			for _, t := range list {
+				tokens[t.ID] = t
+			}
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	s.fileName = fileName
+	s.tokens = tokens
+	return nil
+}
+
+// save must be called with the lock held.
+func (s *tokenStore) save() error {
+	if s.fileName == "" {
+		return nil
+	}
+
+	raw, err := json.MarshalIndent(s.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.fileName, raw, 0600)
+}
+
+// sorted must be called with the lock held.
+func (s *tokenStore) sorted() []*Token {
+	list := make([]*Token, 0, len(s.tokens))
+	for _, t := range s.tokens {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Created.Before(list[j].Created)
+	})
+	return list
+}
+
+func (s *tokenStore) Add(path string, label string) (*Token, error) {
+	raw := make([]byte, tokenSize)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	t := &Token{
+		ID:      hex.EncodeToString(raw),
+		Path:    path,
+		Label:   label,
+		Created: time.Now(),
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.tokens[t.ID] = t
+	return t, s.save()
+}
+
+func (s *tokenStore) Del(id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, found := s.tokens[id]; !found {
+		return fmt.Errorf("token %s not found", id)
+	}
+	delete(s.tokens, id)
+	return s.save()
+}
+
+// Hit counts a request of the token and returns its event, nil if the
+// token is unknown.
+func (s *tokenStore) Hit(id string, r *http.Request) (*HitEvent, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	t, found := s.tokens[id]
+	if !found {
+		return nil, nil
+	}
+
+	now := time.Now()
+	ev := &HitEvent{
+		Token:     t.ID,
+		Label:     t.Label,
+		Path:      r.URL.Path,
+		Address:   strings.Split(r.RemoteAddr, ":")[0],
+		UserAgent: r.UserAgent(),
+		Referer:   r.Referer(),
+		Hit:       t.Hits + 1,
+		Since:     now.Sub(t.Created),
+	}
+
+	if t.Hits == 0 {
+		t.FirstHit = now
+	} else {
+		ev.SinceLast = now.Sub(t.LastHit)
+	}
+	t.Hits++
+	t.LastHit = now
+
+	return ev, s.save()
+}
+
+func (s *tokenStore) List() []Token {
+	s.Lock()
+	defer s.Unlock()
+
+	list := make([]Token, 0, len(s.tokens))
+	for _, t := range s.sorted() {
+		list = append(list, *t)
+	}
+	return list
+}
+
+// tokenURL returns the link to give to the target of the token.
+func (mod *HttpServer) tokenURL(t Token) string {
+	host := "<address>"
+	if err, address := mod.StringParam("http.server.address"); err == nil {
+		host = address
+	}
+	if err, port := mod.IntParam("http.server.port"); err == nil && port != 80 {
+		host = fmt.Sprintf("%s:%d", host, port)
+	}
+
+	_, param := mod.StringParam("http.server.tokens.param")
+	return fmt.Sprintf("http://%s%s?%s=%s", host, t.Path, param, t.ID)
+}
+
+func (mod *HttpServer) updateTokens() error {
+	if err, fileName := mod.StringParam("http.server.tokens.file"); err != nil {
+		return err
+	} else {
+		return mod.tokens.Load(fileName)
+	}
+}
+
+func (mod *HttpServer) addToken(path string, label string) error {
+	if err := mod.updateTokens(); err != nil {
+		return err
+	}
+
+	t, err := mod.tokens.Add(path, label)
+	if err != nil {
+		return err
+	}
+
+	mod.Info("token %s created for %s: %s", tui.Bold(t.ID), t.Path, tui.Yellow(mod.tokenURL(*t)))
+	return nil
+}
+
+func (mod *HttpServer) delToken(id string) error {
+	if err := mod.updateTokens(); err != nil {
+		return err
+	}
+	return mod.tokens.Del(id)
+}
+
+// onTokenHit is called for every request with the token parameter.
+func (mod *HttpServer) onTokenHit(id string, r *http.Request) {
+	if ev, err := mod.tokens.Hit(id, r); err != nil {
+		mod.Error("error saving the tokens: %v", err)
+	} else if ev == nil {
+		mod.Debug("%s requested unknown token %s", r.RemoteAddr, id)
+	} else {
+		mod.Session.Events.Add("http.server.hit", *ev)
+	}
+}
+
+func (mod *HttpServer) showTokens() error {
+	if err := mod.updateTokens(); err != nil {
+		return err
+	}
+
+	tokens := mod.tokens.List()
+	if mod.Session.ShowJSON(tokens) {
+		return nil
+	} else if len(tokens) == 0 {
+		return fmt.Errorf("no tokens, create one with http.server.token PATH LABEL")
+	}
+
+	rows := make([][]string, 0, len(tokens))
+	for _, t := range tokens {
+		hits := tui.Dim("0")
+		first, last := "", ""
+		if t.Hits > 0 {
+			hits = tui.Red(fmt.Sprintf("%d", t.Hits))
+			first = t.FirstHit.Format("2006-01-02 15:04:05")
+			last = t.LastHit.Format("2006-01-02 15:04:05")
+		}
+
+		rows = append(rows, []string{
+			tui.Bold(t.ID),
+			t.Label,
+			t.Path,
+			t.Created.Format("2006-01-02 15:04:05"),
+			hits,
+			first,
+			last,
+			tui.Dim(mod.tokenURL(t)),
+		})
+	}
+
+	mod.Session.Table([]string{"Token", "Label", "Path", "Created", "Hits", "First Hit", "Last Hit", "URL"}, rows)
+	mod.Session.Println()
+
+	return nil
+}
@@ -32,22 +32,120 @@ func NewHttpsProxy(s *session.Session) *HttpsProxy {
 		"8083",
 		"Port to bind the HTTPS proxy to."))
 
+	mod.AddParam(session.NewBoolParameter("https.proxy.carve",
+		"false",
+		"If true, files transferred to the victims through the proxy are saved to https.proxy.carve.path, in a folder per victim with an index of their URL, content type and SHA256."))
+
+	mod.AddParam(session.NewStringParameter("https.proxy.carve.path",
+		"~/bettercap-carved",
+		"",
+		"Folder to save the carved files to."))
+
+	mod.AddParam(session.NewListParameter("https.proxy.carve.types",
+		"",
+		"Comma separated list of content types to carve, values ending with / match every subtype (like image/), if empty every type but text, javascript, json and xml is carved."))
+
+	mod.AddParam(session.NewIntParameter("https.proxy.carve.min.size",
+		"1024",
+		"Minimum size in bytes of the carved files."))
+
+	mod.AddParam(session.NewIntParameter("https.proxy.carve.max.size",
+		"52428800",
+		"Maximum size in bytes of the carved files."))
+
+	mod.AddParam(session.NewBoolParameter("https.proxy.cache",
+		"false",
+		"If true, the responses to GET requests are saved to https.proxy.cache.path and served from there when the upstream server can't be reached."))
+
+	mod.AddParam(session.NewBoolParameter("https.proxy.cache.offline",
+		"false",
+		"If true together with https.proxy.cache, every response is served from the cache without contacting the upstream servers, the requests not cached get a 404."))
+
+	mod.AddParam(session.NewStringParameter("https.proxy.cache.path",
+		"~/bettercap-http-cache",
+		"",
+		"Folder to save the cached responses to, it's kept across sessions."))
+
+	mod.AddParam(session.NewIntParameter("https.proxy.cache.max.size",
+		"10485760",
+		"Maximum size in bytes of the cached responses."))
+
+	mod.AddParam(session.NewListParameter("https.proxy.challenge",
+		"",
+		"Comma separated list of regular expressions matching the host and path of the requests (like intranet.corp.com/admin) to answer with an authentication challenge until the victim sends credentials, leave empty to disable."))
+
+	mod.AddParam(session.NewStringParameter("https.proxy.challenge.scheme",
+		"basic",
+		"^(basic|ntlm)$",
+		"Authentication scheme of the challenge, basic captures clear text credentials, ntlm the NTLMv1/v2 hashes."))
+
+	mod.AddParam(session.NewBoolParameter("https.proxy.challenge.proxy",
+		"false",
+		"If true, the challenge is a 407 for proxy authentication, which browsers only answer when configured to use the proxy (for instance with WPAD), otherwise a 401."))
+
+	mod.AddParam(session.NewStringParameter("https.proxy.challenge.realm",
+		"",
+		"",
+		"Realm of the basic challenge and domain of the NTLM one, if empty the host of the request and WORKGROUP are used."))
+
+	mod.AddParam(session.NewListParameter("https.proxy.challenge.targets",
+		"",
+		"Comma separated list of IP or MAC addresses of the victims to challenge, leave empty to challenge every victim in scope."))
+
 	mod.AddParam(session.NewBoolParameter("https.proxy.sslstrip",
 		"false",
 		"Enable or disable SSL stripping."))
 
+	mod.AddParam(session.NewStringParameter("https.proxy.rules",
+		"",
+		"",
+		"Path of a JSON file of rules matching on host, method, path, content type or body regex to replace, inject, redirect or reply with a status code without a proxy script, reloaded when it changes."))
+
 	mod.AddParam(session.NewStringParameter("https.proxy.injectjs",
 		"",
 		"",
 		"URL, path or javascript code to inject into every HTML page."))
 
+	mod.AddParam(session.NewBoolParameter("https.proxy.injectjs.template",
+		"false",
+		"If true, the injected javascript is a template that can use the {{.ClientIP}}, {{.ClientMAC}}, {{.Hostname}}, {{.Alias}}, {{.Vendor}}, {{.Host}}, {{.Path}}, {{.Campaign}} and {{.Collect}} variables."))
+
+	mod.AddParam(session.NewStringParameter("https.proxy.injectjs.campaign",
+		"",
+		"",
+		"Campaign identifier available to the injected javascript template as {{.Campaign}}."))
+
+	mod.AddParam(session.NewStringParameter("https.proxy.collect",
+		"/__bcap/collect",
+		"",
+		"Path of the collection endpoint that injected payloads can send data to (available as {{.Collect}}), every hit will generate a https.proxy.collect event, leave empty to disable."))
+
+	mod.AddParam(session.NewStringParameter("https.proxy.hook",
+		"",
+		"",
+		"URL of a BeEF (or any other C2) hook to inject into every proxied HTML page, for instance http://192.168.1.10:3000/hook.js ."))
+
+	mod.AddParam(session.NewListParameter("https.proxy.targets",
+		"",
+		"Comma separated list of hostnames (subdomains included, matched against the SNI) and victim IP, CIDR or MAC addresses whose TLS connections are intercepted, a connection must match one of each kind given, the others are passed through to their servers untouched, if empty every connection is intercepted."))
+
+	mod.AddParam(session.NewStringParameter("https.proxy.hook.targets",
+		"",
+		"",
+		"Comma separated list of IP or MAC addresses to inject the hook for, leave empty to hook every victim."))
+
+	mod.AddParam(session.NewStringParameter("https.proxy.hook.exclude",
+		"",
+		"",
+		"Comma separated list of domains (subdomains included) the hook must not be injected into."))
+
 	mod.AddParam(session.NewStringParameter("https.proxy.certificate",
-		"~/.bettercap-ca.cert.pem",
+		tls.DefaultCACertFile,
 		"",
 		"HTTPS proxy certification authority TLS certificate file."))
 
 	mod.AddParam(session.NewStringParameter("https.proxy.key",
-		"~/.bettercap-ca.key.pem",
+		tls.DefaultCAKeyFile,
 		"",
 		"HTTPS proxy certification authority TLS key file."))
 
@@ -64,6 +162,12 @@ func NewHttpsProxy(s *session.Session) *HttpsProxy {
 			return mod.Start()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("https.proxy.hook.show", "",
+		"Show which victims have been injected with the hook and which ones loaded it.",
+		func(args []string) error {
+			return mod.proxy.ShowHooks()
+		}))
+
 	mod.AddHandler(session.NewModuleHandler("https.proxy off", "",
 		"Stop HTTPS proxy.",
 		func(args []string) error {
@@ -118,6 +222,30 @@ func (mod *HttpsProxy) Configure() error {
 		return err
 	} else if err, jsToInject = mod.StringParam("https.proxy.injectjs"); err != nil {
 		return err
+	} else if err, mod.proxy.Campaign = mod.StringParam("https.proxy.injectjs.campaign"); err != nil {
+		return err
+	} else if err, mod.proxy.Template = mod.BoolParam("https.proxy.injectjs.template"); err != nil {
+		return err
+	} else if err, mod.proxy.CollectPath = mod.StringParam("https.proxy.collect"); err != nil {
+		return err
+	} else if err, mod.proxy.HookURL = mod.StringParam("https.proxy.hook"); err != nil {
+		return err
+	} else if err, mod.proxy.HookTargets = mod.ListParam("https.proxy.hook.targets"); err != nil {
+		return err
+	} else if err, mod.proxy.HookExclude = mod.ListParam("https.proxy.hook.exclude"); err != nil {
+		return err
+	} else if err, mod.proxy.Targets = mod.ListParam("https.proxy.targets"); err != nil {
+		return err
+	} else if err, mod.proxy.RulesFile = mod.StringParam("https.proxy.rules"); err != nil {
+		return err
+	}
+
+	if err = mod.configureCarver(); err != nil {
+		return err
+	} else if err = mod.configureCache(); err != nil {
+		return err
+	} else if err = mod.configureChallenge(); err != nil {
+		return err
 	}
 
 	if !fs.Exists(certFile) || !fs.Exists(keyFile) {
@@ -140,6 +268,73 @@ func (mod *HttpsProxy) Configure() error {
 	return mod.proxy.ConfigureTLS(address, proxyPort, httpPort, scriptPath, certFile, keyFile, jsToInject, stripSSL)
 }
 
+// configureCarver copies the carving parameters to the proxy, an empty path
+// disables it.
+func (mod *HttpsProxy) configureCarver() error {
+	var err error
+	var enabled bool
+	var minSize, maxSize int
+
+	mod.proxy.CarvePath = ""
+	if err, enabled = mod.BoolParam("https.proxy.carve"); err != nil || !enabled {
+		return err
+	} else if err, mod.proxy.CarvePath = mod.StringParam("https.proxy.carve.path"); err != nil {
+		return err
+	} else if err, mod.proxy.CarveTypes = mod.ListParam("https.proxy.carve.types"); err != nil {
+		return err
+	} else if err, minSize = mod.IntParam("https.proxy.carve.min.size"); err != nil {
+		return err
+	} else if err, maxSize = mod.IntParam("https.proxy.carve.max.size"); err != nil {
+		return err
+	}
+
+	mod.proxy.CarveMinSize = int64(minSize)
+	mod.proxy.CarveMaxSize = int64(maxSize)
+	return nil
+}
+
+// configureCache copies the caching parameters to the proxy, an empty path
+// disables it.
+func (mod *HttpsProxy) configureCache() error {
+	var err error
+	var enabled bool
+	var maxSize int
+
+	mod.proxy.CachePath = ""
+	if err, enabled = mod.BoolParam("https.proxy.cache"); err != nil || !enabled {
+		return err
+	} else if err, mod.proxy.CachePath = mod.StringParam("https.proxy.cache.path"); err != nil {
+		return err
+	} else if err, mod.proxy.CacheOffline = mod.BoolParam("https.proxy.cache.offline"); err != nil {
+		return err
+	} else if err, maxSize = mod.IntParam("https.proxy.cache.max.size"); err != nil {
+		return err
+	}
+
+	mod.proxy.CacheMaxSize = int64(maxSize)
+	return nil
+}
+
+// configureChallenge copies the authentication challenge parameters to the
+// proxy, an empty list of patterns disables it.
+func (mod *HttpsProxy) configureChallenge() error {
+	var err error
+
+	if err, mod.proxy.ChallengeURLs = mod.ListParam("https.proxy.challenge"); err != nil || len(mod.proxy.ChallengeURLs) == 0 {
+		return err
+	} else if err, mod.proxy.ChallengeScheme = mod.StringParam("https.proxy.challenge.scheme"); err != nil {
+		return err
+	} else if err, mod.proxy.ChallengeProxy = mod.BoolParam("https.proxy.challenge.proxy"); err != nil {
+		return err
+	} else if err, mod.proxy.ChallengeRealm = mod.StringParam("https.proxy.challenge.realm"); err != nil {
+		return err
+	} else if err, mod.proxy.ChallengeTargets = mod.ListParam("https.proxy.challenge.targets"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (mod *HttpsProxy) Start() error {
 	if err := mod.Configure(); err != nil {
 		return err
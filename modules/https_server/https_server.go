@@ -52,6 +52,7 @@ func NewHttpsServer(s *session.Session) *HttpsServer {
 		"TLS key file (will be auto generated if filled but not existing)."))
 
 	tls.CertConfigToModule("https.server", &mod.SessionModule, tls.DefaultLegitConfig)
+	tls.SignedCertConfigToModule("https.server", &mod.SessionModule)
 
 	mod.AddHandler(session.NewModuleHandler("https.server on", "",
 		"Start https server.",
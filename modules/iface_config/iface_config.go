@@ -0,0 +1,316 @@
+package iface_config
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bettercap/bettercap/journal"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket/pcap"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const journalKind = "iface.config"
+
+type IfaceConfig struct {
+	session.SessionModule
+
+	sync.Mutex
+	// promiscuous mode lasts as long as the capture handle is open
+	promisc map[string]*pcap.Handle
+}
+
+func NewIfaceConfig(s *session.Session) *IfaceConfig {
+	mod := &IfaceConfig{
+		SessionModule: session.NewSessionModule("iface.config", s),
+		promisc:       make(map[string]*pcap.Handle),
+	}
+
+	mod.AddHandler(session.NewModuleHandler("iface.config.show", "",
+		"Show the addresses, MTU and flags of the network interfaces.",
+		func(args []string) error {
+			return mod.Show()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("iface.config.up IFACE", `^iface\.config\.up\s+([^\s]+)$`,
+		"Bring the interface up.",
+		func(args []string) error {
+			return mod.setState(args[0], true)
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("iface.config.down IFACE", `^iface\.config\.down\s+([^\s]+)$`,
+		"Bring the interface down.",
+		func(args []string) error {
+			return mod.setState(args[0], false)
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("iface.config.mtu IFACE MTU", `^iface\.config\.mtu\s+([^\s]+)\s+(\d+)$`,
+		"Set the MTU of the interface.",
+		func(args []string) error {
+			mtu, _ := strconv.Atoi(args[1])
+			return mod.setMTU(args[0], mtu)
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("iface.config.address IFACE ADDRESS/BITS", `^iface\.config\.address\s+([^\s]+)\s+([^\s]+/\d+)$`,
+		"Add an IPv4 or IPv6 address to the interface.",
+		func(args []string) error {
+			return mod.setAddress(args[0], args[1], true)
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("iface.config.address.del IFACE ADDRESS/BITS", `^iface\.config\.address\.del\s+([^\s]+)\s+([^\s]+/\d+)$`,
+		"Remove an IPv4 or IPv6 address from the interface.",
+		func(args []string) error {
+			return mod.setAddress(args[0], args[1], false)
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("iface.config.promisc IFACE true|false", `^iface\.config\.promisc\s+([^\s]+)\s+(true|false)$`,
+		"Enable or disable promiscuous mode on the interface until the session ends.",
+		func(args []string) error {
+			return mod.setPromisc(args[0], args[1] == "true")
+		}))
+
+	journal.Register(journalKind, mod.restoreJournal)
+
+	return mod
+}
+
+func (mod *IfaceConfig) Name() string {
+	return "iface.config"
+}
+
+func (mod *IfaceConfig) Description() string {
+	return "Configure the network interfaces of the system, every change is reverted when the session ends."
+}
+
+func (mod *IfaceConfig) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *IfaceConfig) Configure() error {
+	return nil
+}
+
+func (mod *IfaceConfig) Start() error {
+	return nil
+}
+
+func (mod *IfaceConfig) Stop() error {
+	mod.Lock()
+	defer mod.Unlock()
+
+	for name, handle := range mod.promisc {
+		handle.Close()
+		delete(mod.promisc, name)
+	}
+	return nil
+}
+
+func (mod *IfaceConfig) findIface(name string) (*net.Interface, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s not found", name)
+	}
+	return iface, nil
+}
+
+// track saves the original value of a setting the first time it's
+// successfully changed, so that it's restored even if we don't exit cleanly.
+func (mod *IfaceConfig) track(name, setting string, args map[string]string) {
+	id := name + "." + setting
+	if _, found := journal.Get(journalKind, id); found {
+		return
+	}
+
+	args["iface"] = name
+	args["setting"] = setting
+	if err := journal.Track(journalKind, id, args); err != nil {
+		mod.Warning("could not update the journal: %s", err)
+	}
+}
+
+func (mod *IfaceConfig) setState(name string, up bool) error {
+	iface, err := mod.findIface(name)
+	if err != nil {
+		return err
+	}
+
+	if err := setState(name, up); err != nil {
+		return err
+	}
+
+	mod.track(name, "state", map[string]string{
+		"up": strconv.FormatBool(iface.Flags&net.FlagUp != 0),
+	})
+
+	mod.Info("interface %s is now %s", tui.Bold(name), map[bool]string{true: "up", false: "down"}[up])
+	return nil
+}
+
+func (mod *IfaceConfig) setMTU(name string, mtu int) error {
+	iface, err := mod.findIface(name)
+	if err != nil {
+		return err
+	} else if mtu < 68 || mtu > 65535 {
+		return fmt.Errorf("invalid MTU %d", mtu)
+	}
+
+	if err := setMTU(name, mtu); err != nil {
+		return err
+	}
+
+	mod.track(name, "mtu", map[string]string{
+		"mtu": strconv.Itoa(iface.MTU),
+	})
+
+	mod.Info("interface %s MTU set to %d", tui.Bold(name), mtu)
+	return nil
+}
+
+func (mod *IfaceConfig) setAddress(name, address string, add bool) error {
+	if _, err := mod.findIface(name); err != nil {
+		return err
+	}
+
+	ip, ipNet, err := net.ParseCIDR(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %s: %v", address, err)
+	}
+	ipNet.IP = ip
+
+	if err := setAddress(name, ipNet, add); err != nil {
+		return err
+	}
+
+	// adding and then removing the same address cancel each other out
+	id := name + ".address." + ipNet.String()
+	if _, found := journal.Get(journalKind, id); found {
+		if err := journal.Untrack(journalKind, id); err != nil {
+			mod.Warning("could not update the journal: %s", err)
+		}
+	} else {
+		mod.track(name, "address."+ipNet.String(), map[string]string{
+			"address": ipNet.String(),
+			"added":   strconv.FormatBool(add),
+		})
+	}
+
+	if add {
+		mod.Info("address %s added to %s", tui.Bold(ipNet.String()), tui.Bold(name))
+	} else {
+		mod.Info("address %s removed from %s", tui.Bold(ipNet.String()), tui.Bold(name))
+	}
+	return nil
+}
+
+func (mod *IfaceConfig) setPromisc(name string, enabled bool) error {
+	if _, err := mod.findIface(name); err != nil {
+		return err
+	}
+
+	mod.Lock()
+	defer mod.Unlock()
+
+	handle, found := mod.promisc[name]
+	if enabled && !found {
+		if handle, err := pcap.OpenLive(name, 64, true, pcap.BlockForever); err != nil {
+			return fmt.Errorf("could not enable promiscuous mode on %s: %v", name, err)
+		} else {
+			mod.promisc[name] = handle
+		}
+		mod.Info("interface %s is now in promiscuous mode", tui.Bold(name))
+	} else if !enabled && found {
+		handle.Close()
+		delete(mod.promisc, name)
+		mod.Info("interface %s is not in promiscuous mode anymore", tui.Bold(name))
+	}
+	return nil
+}
+
+// restoreJournal reverts a setting changed by a previous session.
+func (mod *IfaceConfig) restoreJournal(args map[string]string) error {
+	name := args["iface"]
+	setting := args["setting"]
+
+	var err error
+	switch {
+	case setting == "state":
+		err = setState(name, args["up"] == "true")
+	case setting == "mtu":
+		var mtu int
+		if mtu, err = strconv.Atoi(args["mtu"]); err == nil {
+			err = setMTU(name, mtu)
+		}
+	case strings.HasPrefix(setting, "address."):
+		var ip net.IP
+		var ipNet *net.IPNet
+		if ip, ipNet, err = net.ParseCIDR(args["address"]); err == nil {
+			ipNet.IP = ip
+			err = setAddress(name, ipNet, args["added"] != "true")
+		}
+	default:
+		err = fmt.Errorf("unknown setting %s", setting)
+	}
+
+	if err != nil {
+		return err
+	}
+	mod.Info("interface %s %s restored", name, setting)
+	return journal.Untrack(journalKind, name+"."+setting)
+}
+
+func (mod *IfaceConfig) Show() error {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(ifaces, func(i, j int) bool {
+		return ifaces[i].Name < ifaces[j].Name
+	})
+
+	mod.Lock()
+	defer mod.Unlock()
+
+	rows := make([][]string, 0)
+	for _, iface := range ifaces {
+		name := iface.Name
+		if name == mod.Session.Interface.Name() {
+			name = tui.Bold(name)
+		}
+
+		flags := strings.Replace(iface.Flags.String(), "|", ", ", -1)
+		if _, found := mod.promisc[iface.Name]; found {
+			flags += ", " + tui.Yellow("promisc")
+		}
+		if iface.Flags&net.FlagUp == 0 {
+			flags = tui.Dim(flags)
+		}
+
+		addresses := make([]string, 0)
+		if addrs, err := iface.Addrs(); err == nil {
+			for _, addr := range addrs {
+				addresses = append(addresses, addr.String())
+			}
+		}
+
+		rows = append(rows, []string{
+			name,
+			iface.HardwareAddr.String(),
+			strconv.Itoa(iface.MTU),
+			flags,
+			strings.Join(addresses, ", "),
+		})
+	}
+
+	mod.Session.Println()
+	mod.Session.Table([]string{"Name", "MAC", "MTU", "Flags", "Addresses"}, rows)
+	mod.Session.Println()
+
+	return nil
+}
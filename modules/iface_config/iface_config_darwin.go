@@ -0,0 +1,36 @@
+package iface_config
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/bettercap/bettercap/core"
+)
+
+func setState(name string, up bool) error {
+	state := "down"
+	if up {
+		state = "up"
+	}
+	_, err := core.Exec("ifconfig", []string{name, state})
+	return err
+}
+
+func setMTU(name string, mtu int) error {
+	_, err := core.Exec("ifconfig", []string{name, "mtu", strconv.Itoa(mtu)})
+	return err
+}
+
+func setAddress(name string, address *net.IPNet, add bool) error {
+	family := "inet"
+	if address.IP.To4() == nil {
+		family = "inet6"
+	}
+
+	action := "-alias"
+	if add {
+		action = "alias"
+	}
+	_, err := core.Exec("ifconfig", []string{name, family, address.String(), action})
+	return err
+}
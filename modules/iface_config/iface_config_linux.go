@@ -0,0 +1,31 @@
+package iface_config
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/bettercap/bettercap/core"
+)
+
+func setState(name string, up bool) error {
+	state := "down"
+	if up {
+		state = "up"
+	}
+	_, err := core.Exec("ip", []string{"link", "set", "dev", name, state})
+	return err
+}
+
+func setMTU(name string, mtu int) error {
+	_, err := core.Exec("ip", []string{"link", "set", "dev", name, "mtu", strconv.Itoa(mtu)})
+	return err
+}
+
+func setAddress(name string, address *net.IPNet, add bool) error {
+	action := "del"
+	if add {
+		action = "add"
+	}
+	_, err := core.Exec("ip", []string{"addr", action, address.String(), "dev", name})
+	return err
+}
@@ -0,0 +1,50 @@
+package iface_config
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/bettercap/bettercap/core"
+)
+
+func setState(name string, up bool) error {
+	state := "disabled"
+	if up {
+		state = "enabled"
+	}
+	_, err := core.Exec("netsh", []string{"interface", "set", "interface", fmt.Sprintf("name=%s", name), fmt.Sprintf("admin=%s", state)})
+	return err
+}
+
+func setMTU(name string, mtu int) error {
+	for _, family := range []string{"ipv4", "ipv6"} {
+		if _, err := core.Exec("netsh", []string{"interface", family, "set", "subinterface", name, fmt.Sprintf("mtu=%d", mtu), "store=active"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setAddress(name string, address *net.IPNet, add bool) error {
+	action := "delete"
+	if add {
+		action = "add"
+	}
+
+	if address.IP.To4() == nil {
+		args := []string{"interface", "ipv6", action, "address", name, address.IP.String()}
+		if add {
+			args[len(args)-1] = address.String()
+			args = append(args, "store=active")
+		}
+		_, err := core.Exec("netsh", args)
+		return err
+	}
+
+	args := []string{"interface", "ipv4", action, "address", name, address.IP.String()}
+	if add {
+		args = append(args, net.IP(address.Mask).String(), "store=active")
+	}
+	_, err := core.Exec("netsh", args)
+	return err
+}
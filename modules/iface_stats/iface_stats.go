@@ -0,0 +1,338 @@
+package iface_stats
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// Counters are the totals of the interface since it was brought up.
+type Counters struct {
+	RxBytes   uint64 `json:"rx_bytes"`
+	TxBytes   uint64 `json:"tx_bytes"`
+	RxPackets uint64 `json:"rx_packets"`
+	TxPackets uint64 `json:"tx_packets"`
+	RxErrors  uint64 `json:"rx_errors"`
+	TxErrors  uint64 `json:"tx_errors"`
+	RxDropped uint64 `json:"rx_dropped"`
+	TxDropped uint64 `json:"tx_dropped"`
+}
+
+// Wireless is the link quality reported by the driver of a wireless interface.
+type Wireless struct {
+	Link  float64 `json:"link"`
+	Level float64 `json:"level"`
+	Noise float64 `json:"noise"`
+}
+
+// Capture are the packets received and dropped by libpcap, the kernel drops
+// them when bettercap can't keep up while the interface drops them before.
+type Capture struct {
+	Received  int `json:"received"`
+	Dropped   int `json:"dropped"`
+	IfDropped int `json:"if_dropped"`
+}
+
+type Sample struct {
+	Time time.Time `json:"time"`
+	Counters
+	// bytes per second since the previous sample
+	RxRate   float64   `json:"rx_rate"`
+	TxRate   float64   `json:"tx_rate"`
+	Wireless *Wireless `json:"wireless"`
+	Capture  *Capture  `json:"capture"`
+}
+
+type IfaceStats struct {
+	session.SessionModule
+	sync.Mutex
+
+	period  time.Duration
+	size    int
+	iface   string
+	history []*Sample
+}
+
+func NewIfaceStats(s *session.Session) *IfaceStats {
+	mod := &IfaceStats{
+		SessionModule: session.NewSessionModule("iface.stats", s),
+		history:       make([]*Sample, 0),
+	}
+
+	mod.AddParam(session.NewIntParameter("iface.stats.period",
+		"1",
+		"Seconds between two samples of the interface counters."))
+
+	mod.AddParam(session.NewIntParameter("iface.stats.history",
+		"60",
+		"Number of samples to keep for the sparklines and the /api/session/modules state."))
+
+	mod.AddHandler(session.NewModuleHandler("iface.stats on", "",
+		"Start sampling the counters of the selected interface.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("iface.stats off", "",
+		"Stop sampling the interface counters.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("iface.stats.show", "",
+		"Show the traffic, errors and drops of the selected interface, of its wireless link and of the capture.",
+		func(args []string) error {
+			return mod.Show()
+		}))
+
+	mod.InitState("current", "history")
+
+	return mod
+}
+
+func (mod *IfaceStats) Name() string {
+	return "iface.stats"
+}
+
+func (mod *IfaceStats) Description() string {
+	return "Sample the traffic and error counters of the interface, its wireless link quality and the capture drops, to tell whether packets are lost by the link or by bettercap."
+}
+
+func (mod *IfaceStats) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *IfaceStats) Configure() error {
+	var err error
+	var period int
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted
+	} else if err, period = mod.IntParam("iface.stats.period"); err != nil {
+		return err
+	} else if err, mod.size = mod.IntParam("iface.stats.history"); err != nil {
+		return err
+	} else if period <= 0 {
+		return fmt.Errorf("iface.stats.period must be greater than 0")
+	} else if mod.size <= 1 {
+		return fmt.Errorf("iface.stats.history must be greater than 1")
+	}
+
+	mod.period = time.Duration(period) * time.Second
+	mod.iface = mod.Session.Interface.Name()
+	if _, err = readCounters(mod.iface); err != nil {
+		return fmt.Errorf("can't read the counters of %s: %v", mod.iface, err)
+	}
+
+	mod.Lock()
+	mod.history = make([]*Sample, 0, mod.size)
+	mod.Unlock()
+
+	return nil
+}
+
+func (mod *IfaceStats) sample() (*Sample, error) {
+	counters, err := readCounters(mod.iface)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sample{
+		Time:     time.Now(),
+		Counters: *counters,
+		Wireless: readWireless(mod.iface),
+	}
+
+	if mod.Session.Queue != nil {
+		if stats, err := mod.Session.Queue.CaptureStats(); err == nil && stats != nil {
+			s.Capture = &Capture{
+				Received:  stats.PacketsReceived,
+				Dropped:   stats.PacketsDropped,
+				IfDropped: stats.PacketsIfDropped,
+			}
+		}
+	}
+
+	return s, nil
+}
+
+func (mod *IfaceStats) add(s *Sample) {
+	mod.Lock()
+	defer mod.Unlock()
+
+	if n := len(mod.history); n > 0 {
+		prev := mod.history[n-1]
+		// counters are reset when the interface goes down and up again
+		if secs := s.Time.Sub(prev.Time).Seconds(); secs > 0 && s.RxBytes >= prev.RxBytes && s.TxBytes >= prev.TxBytes {
+			s.RxRate = float64(s.RxBytes-prev.RxBytes) / secs
+			s.TxRate = float64(s.TxBytes-prev.TxBytes) / secs
+		}
+	}
+
+	mod.history = append(mod.history, s)
+	if len(mod.history) > mod.size {
+		mod.history = mod.history[len(mod.history)-mod.size:]
+	}
+
+	history := make([]*Sample, len(mod.history))
+	copy(history, mod.history)
+	mod.State.Store("current", s)
+	mod.State.Store("history", history)
+}
+
+func (mod *IfaceStats) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.Info("sampling %s every %s", mod.iface, mod.period)
+
+		for mod.Running() {
+			if s, err := mod.sample(); err != nil {
+				mod.Warning("error reading the counters of %s: %v", mod.iface, err)
+			} else {
+				mod.add(s)
+			}
+			time.Sleep(mod.period)
+		}
+	})
+}
+
+func (mod *IfaceStats) Stop() error {
+	return mod.SetRunning(false, func() {
+		mod.ResetState()
+	})
+}
+
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline renders the values scaled to their maximum.
+func sparkline(values []float64) string {
+	max := 0.0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+
+	line := make([]rune, 0, len(values))
+	for _, v := range values {
+		tick := 0
+		if max > 0 {
+			tick = int(v / max * float64(len(sparkTicks)-1))
+		}
+		line = append(line, sparkTicks[tick])
+	}
+	return string(line)
+}
+
+func (mod *IfaceStats) Show() error {
+	mod.Lock()
+	history := make([]*Sample, len(mod.history))
+	copy(history, mod.history)
+	mod.Unlock()
+
+	if len(history) == 0 {
+		return fmt.Errorf("no samples yet, start the module with iface.stats on")
+	}
+
+	first, last := history[0], history[len(history)-1]
+	window := last.Time.Sub(first.Time).Round(time.Second)
+	hints := diagnose(first, last)
+
+	if mod.Session.ShowJSON(map[string]interface{}{
+		"interface": mod.iface,
+		"current":   last,
+		"history":   history,
+		"hints":     hints,
+	}) {
+		return nil
+	}
+
+	rxRates := make([]float64, 0, len(history))
+	txRates := make([]float64, 0, len(history))
+	for _, s := range history[1:] {
+		rxRates = append(rxRates, s.RxRate)
+		txRates = append(txRates, s.TxRate)
+	}
+
+	delta := func(get func(s *Sample) uint64) string {
+		total, prev := get(last), get(first)
+		if total > prev {
+			return tui.Red(fmt.Sprintf("%d (+%d)", total, total-prev))
+		}
+		return fmt.Sprintf("%d", total)
+	}
+
+	rows := [][]string{
+		{"RX", humanize.Bytes(last.RxBytes), humanize.Bytes(uint64(last.RxRate)) + "/s", sparkline(rxRates)},
+		{"TX", humanize.Bytes(last.TxBytes), humanize.Bytes(uint64(last.TxRate)) + "/s", sparkline(txRates)},
+		{"RX Errors", delta(func(s *Sample) uint64 { return s.RxErrors }), "", ""},
+		{"TX Errors", delta(func(s *Sample) uint64 { return s.TxErrors }), "", ""},
+		{"RX Dropped", delta(func(s *Sample) uint64 { return s.RxDropped }), "", ""},
+		{"TX Dropped", delta(func(s *Sample) uint64 { return s.TxDropped }), "", ""},
+	}
+
+	if last.Wireless != nil {
+		levels := make([]float64, 0, len(history))
+		for _, s := range history {
+			if s.Wireless != nil {
+				// dBm are negative, shift them to draw the sparkline
+				levels = append(levels, s.Wireless.Level+100)
+			}
+		}
+		noise := "n/a"
+		if last.Wireless.Noise > -256 {
+			noise = fmt.Sprintf("%.0f dBm", last.Wireless.Noise)
+		}
+		rows = append(rows,
+			[]string{"Link Quality", fmt.Sprintf("%.0f", last.Wireless.Link), "", ""},
+			[]string{"Signal", fmt.Sprintf("%.0f dBm", last.Wireless.Level), "", sparkline(levels)},
+			[]string{"Noise", noise, "", ""})
+	}
+
+	if last.Capture != nil {
+		dropped := fmt.Sprintf("%d", last.Capture.Dropped)
+		ifDropped := fmt.Sprintf("%d", last.Capture.IfDropped)
+		if first.Capture != nil && last.Capture.Dropped > first.Capture.Dropped {
+			dropped = tui.Red(fmt.Sprintf("%d (+%d)", last.Capture.Dropped, last.Capture.Dropped-first.Capture.Dropped))
+		}
+		if first.Capture != nil && last.Capture.IfDropped > first.Capture.IfDropped {
+			ifDropped = tui.Red(fmt.Sprintf("%d (+%d)", last.Capture.IfDropped, last.Capture.IfDropped-first.Capture.IfDropped))
+		}
+		rows = append(rows,
+			[]string{"Captured", fmt.Sprintf("%d", last.Capture.Received), "", ""},
+			[]string{"Capture Dropped", dropped, "", ""},
+			[]string{"Interface Dropped", ifDropped, "", ""})
+	}
+
+	mod.Session.Printf("\n%s (last %s)\n\n", tui.Bold(mod.iface), window)
+	mod.Session.Table([]string{"Counter", "Total", "Rate", "History"}, rows)
+
+	if len(hints) > 0 {
+		mod.Session.Printf("\n%s\n\n", tui.Yellow(strings.Join(hints, "\n")))
+	}
+
+	return nil
+}
+
+// diagnose tells where the packets lost during the window were dropped.
+func diagnose(first, last *Sample) []string {
+	hints := make([]string, 0)
+	if last.Capture != nil && first.Capture != nil && last.Capture.Dropped > first.Capture.Dropped {
+		hints = append(hints, "packets are being dropped by the kernel because bettercap can't read them fast enough.")
+	}
+	if (last.Capture != nil && first.Capture != nil && last.Capture.IfDropped > first.Capture.IfDropped) ||
+		last.RxDropped > first.RxDropped || last.RxErrors > first.RxErrors {
+		hints = append(hints, "packets are being dropped or corrupted by the interface, the link or the driver.")
+	}
+	return hints
+}
@@ -0,0 +1,48 @@
+package iface_stats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bettercap/bettercap/core"
+)
+
+// readCounters parses the link line of 'netstat -ibI NAME', the address
+// column is missing for some interfaces so fields are counted from the end:
+// Name Mtu Network Address Ipkts Ierrs Ibytes Opkts Oerrs Obytes Coll
+func readCounters(name string) (*Counters, error) {
+	out, err := core.ExecSilent("netstat", []string{"-ibI", name})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 10 || fields[0] != name || !strings.HasPrefix(fields[2], "<Link") {
+			continue
+		}
+
+		values := make([]uint64, 6)
+		for i, field := range fields[len(fields)-7 : len(fields)-1] {
+			if values[i], err = strconv.ParseUint(field, 10, 64); err != nil {
+				return nil, fmt.Errorf("unexpected netstat output '%s'", line)
+			}
+		}
+
+		return &Counters{
+			RxPackets: values[0],
+			RxErrors:  values[1],
+			RxBytes:   values[2],
+			TxPackets: values[3],
+			TxErrors:  values[4],
+			TxBytes:   values[5],
+		}, nil
+	}
+
+	return nil, fmt.Errorf("interface %s not found in the netstat output", name)
+}
+
+func readWireless(name string) *Wireless {
+	return nil
+}
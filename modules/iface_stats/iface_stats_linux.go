@@ -0,0 +1,71 @@
+package iface_stats
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func readCounter(name, counter string) (uint64, error) {
+	raw, err := ioutil.ReadFile(filepath.Join("/sys/class/net", name, "statistics", counter))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(raw)), 10, 64)
+}
+
+func readCounters(name string) (*Counters, error) {
+	var err error
+
+	c := &Counters{}
+	for counter, value := range map[string]*uint64{
+		"rx_bytes":   &c.RxBytes,
+		"tx_bytes":   &c.TxBytes,
+		"rx_packets": &c.RxPackets,
+		"tx_packets": &c.TxPackets,
+		"rx_errors":  &c.RxErrors,
+		"tx_errors":  &c.TxErrors,
+		"rx_dropped": &c.RxDropped,
+		"tx_dropped": &c.TxDropped,
+	} {
+		if *value, err = readCounter(name, counter); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// readWireless parses the line of the interface in /proc/net/wireless, like
+// 'wlan0: 0000   70.  -40.  -256        0 ...', nil if it's not wireless.
+func readWireless(name string) *Wireless {
+	fp, err := os.Open("/proc/net/wireless")
+	if err != nil {
+		return nil
+	}
+	defer fp.Close()
+
+	scanner := bufio.NewScanner(fp)
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scanner.Text()), ":", 2)
+		if len(parts) != 2 || parts[0] != name {
+			continue
+		}
+
+		fields := strings.Fields(parts[1])
+		if len(fields) < 4 {
+			return nil
+		}
+
+		values := make([]float64, 3)
+		for i := range values {
+			if values[i], err = strconv.ParseFloat(strings.TrimRight(fields[i+1], "."), 64); err != nil {
+				return nil
+			}
+		}
+		return &Wireless{Link: values[0], Level: values[1], Noise: values[2]}
+	}
+	return nil
+}
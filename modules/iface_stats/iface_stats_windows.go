@@ -0,0 +1,13 @@
+package iface_stats
+
+import (
+	"errors"
+)
+
+func readCounters(name string) (*Counters, error) {
+	return nil, errors.New("iface.stats is not supported on this OS")
+}
+
+func readWireless(name string) *Wireless {
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/bettercap/bettercap/core"
+	"github.com/bettercap/bettercap/journal"
 	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/session"
 
@@ -47,6 +48,21 @@ func NewMacChanger(s *session.Session) *MacChanger {
 			return mod.Stop()
 		}))
 
+	journal.Register("mac.changer", func(args map[string]string) error {
+		mac, err := net.ParseMAC(args["mac"])
+		if err != nil {
+			return err
+		}
+
+		mod.iface = args["iface"]
+		if err := mod.setMac(mac); err != nil {
+			return err
+		}
+
+		mod.Info("interface %s mac address restored to %s", mod.iface, tui.Bold(mac.String()))
+		return journal.Untrack("mac.changer", mod.iface)
+	})
+
 	return mod
 }
 
@@ -108,6 +124,11 @@ func (mod *MacChanger) Start() error {
 		return err
 	} else if err := mod.setMac(mod.fakeMac); err != nil {
 		return err
+	} else if err := journal.Track("mac.changer", mod.iface, map[string]string{
+		"iface": mod.iface,
+		"mac":   mod.originalMac.String(),
+	}); err != nil {
+		mod.Warning("could not update the journal: %s", err)
 	}
 
 	return mod.SetRunning(true, func() {
@@ -119,6 +140,7 @@ func (mod *MacChanger) Stop() error {
 	return mod.SetRunning(false, func() {
 		if err := mod.setMac(mod.originalMac); err == nil {
 			mod.Info("interface mac address restored to %s", tui.Bold(mod.originalMac.String()))
+			journal.Untrack("mac.changer", mod.iface)
 		} else {
 			mod.Error("error while restoring mac address: %s", err)
 		}
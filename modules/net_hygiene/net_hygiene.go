@@ -0,0 +1,253 @@
+package net_hygiene
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/session"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusUnknown  = "unknown"
+	StatusWarning  = "warning"
+	StatusCritical = "critical"
+)
+
+// penalties are the points subtracted from the score for every finding
+var penalties = map[string]int{
+	StatusWarning:  15,
+	StatusCritical: 40,
+}
+
+// Finding is the result of one of the checks.
+type Finding struct {
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+	Details string `json:"details"`
+	Penalty int    `json:"penalty"`
+}
+
+func newFinding(check string, status string, format string, args ...interface{}) Finding {
+	return Finding{
+		Check:   check,
+		Status:  status,
+		Details: fmt.Sprintf(format, args...),
+		Penalty: penalties[status],
+	}
+}
+
+// Report is the result of a run of the checks, scored from 0 to 100.
+type Report struct {
+	Time     time.Time `json:"time"`
+	Score    int       `json:"score"`
+	Findings []Finding `json:"findings"`
+}
+
+func newReport(findings []Finding) *Report {
+	score := 100
+	for _, f := range findings {
+		score -= f.Penalty
+	}
+	if score < 0 {
+		score = 0
+	}
+
+	return &Report{
+		Time:     time.Now(),
+		Score:    score,
+		Findings: findings,
+	}
+}
+
+// has returns whether the report, if any, has the same finding.
+func (r *Report) has(f Finding) bool {
+	if r != nil {
+		for _, other := range r.Findings {
+			if other == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type NetHygiene struct {
+	session.SessionModule
+	period      time.Duration
+	timeout     time.Duration
+	trustedDHCP []net.IP
+	canaries    []string
+	report      *Report
+	reportLock  *sync.Mutex
+	checkLock   *sync.Mutex
+	quit        chan bool
+}
+
+func NewNetHygiene(s *session.Session) *NetHygiene {
+	mod := &NetHygiene{
+		SessionModule: session.NewSessionModule("net.hygiene", s),
+		reportLock:    &sync.Mutex{},
+		checkLock:     &sync.Mutex{},
+		quit:          make(chan bool),
+	}
+
+	mod.AddParam(session.NewIntParameter("net.hygiene.period",
+		"300",
+		"Period in seconds between the runs of the checks while the module is running."))
+
+	mod.AddParam(session.NewIntParameter("net.hygiene.timeout",
+		"5",
+		"Seconds to wait for the answers to the DHCP, ARP and router solicitation probes and for the canary requests."))
+
+	mod.AddParam(session.NewStringParameter("net.hygiene.dhcp.servers",
+		"",
+		"",
+		"Comma separated list of the addresses of the legit DHCP servers, if empty any server is rogue when more than one answers."))
+
+	mod.AddParam(session.NewStringParameter("net.hygiene.canaries",
+		"http://github.com/,http://www.wikipedia.org/,http://www.paypal.com/",
+		"",
+		"Comma separated list of plain HTTP URLs of websites always redirecting to HTTPS, requested to detect SSL stripping and TLS interception upstream."))
+
+	mod.AddHandler(session.NewModuleHandler("net.hygiene on", "",
+		"Start running the network hygiene checks periodically, sending an event for every new issue.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.hygiene off", "",
+		"Stop running the network hygiene checks.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.hygiene.check", "",
+		"Run the network hygiene checks once and show the report.",
+		func(args []string) error {
+			if !mod.Running() {
+				if err := mod.Configure(); err != nil {
+					return err
+				}
+			}
+			mod.runChecks()
+			return mod.Show()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.hygiene.show", "",
+		"Show the report of the last run of the network hygiene checks.",
+		func(args []string) error {
+			return mod.Show()
+		}))
+
+	return mod
+}
+
+func (mod NetHygiene) Name() string {
+	return "net.hygiene"
+}
+
+func (mod NetHygiene) Description() string {
+	return "Detects rogue DHCP servers, multiple gateways and routers answering and SSL stripping upstream, scoring the hygiene of the network."
+}
+
+func (mod NetHygiene) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *NetHygiene) Configure() (err error) {
+	var period, timeout int
+	var servers []string
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted
+	} else if err, period = mod.IntParam("net.hygiene.period"); err != nil {
+		return err
+	} else if period <= 0 {
+		return fmt.Errorf("net.hygiene.period must be greater than 0")
+	} else if err, timeout = mod.IntParam("net.hygiene.timeout"); err != nil {
+		return err
+	} else if timeout <= 0 {
+		return fmt.Errorf("net.hygiene.timeout must be greater than 0")
+	} else if err, servers = mod.ListParam("net.hygiene.dhcp.servers"); err != nil {
+		return err
+	} else if err, mod.canaries = mod.ListParam("net.hygiene.canaries"); err != nil {
+		return err
+	}
+
+	mod.trustedDHCP = make([]net.IP, 0, len(servers))
+	for _, server := range servers {
+		ip := net.ParseIP(server)
+		if ip == nil || ip.To4() == nil {
+			return fmt.Errorf("net.hygiene.dhcp.servers: '%s' is not an IPv4 address", server)
+		}
+		mod.trustedDHCP = append(mod.trustedDHCP, ip)
+	}
+
+	for _, canary := range mod.canaries {
+		if u, err := url.Parse(canary); err != nil || u.Scheme != "http" || u.Host == "" {
+			return fmt.Errorf("net.hygiene.canaries: '%s' is not a plain HTTP URL", canary)
+		}
+	}
+
+	mod.period = time.Duration(period) * time.Second
+	mod.timeout = time.Duration(timeout) * time.Second
+
+	return nil
+}
+
+// runChecks runs all the checks and stores their report, sending an event
+// for every issue that wasn't found by the previous run.
+func (mod *NetHygiene) runChecks() *Report {
+	mod.checkLock.Lock()
+	defer mod.checkLock.Unlock()
+
+	mod.Info("running network hygiene checks ...")
+
+	findings := mod.checkLink()
+	findings = append(findings, mod.checkCanaries())
+	report := newReport(findings)
+
+	mod.reportLock.Lock()
+	prev := mod.report
+	mod.report = report
+	mod.reportLock.Unlock()
+
+	for _, f := range report.Findings {
+		if f.Penalty > 0 && !prev.has(f) {
+			mod.Session.Events.Add("net.hygiene.issue", f)
+		}
+	}
+	mod.Session.Events.Add("net.hygiene.report", report)
+
+	return report
+}
+
+func (mod *NetHygiene) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.Info("running checks every %s", mod.period)
+
+		for {
+			mod.runChecks()
+
+			select {
+			case <-mod.quit:
+				return
+			case <-time.After(mod.period):
+			}
+		}
+	})
+}
+
+func (mod *NetHygiene) Stop() error {
+	return mod.SetRunning(false, func() {
+		mod.quit <- true
+	})
+}
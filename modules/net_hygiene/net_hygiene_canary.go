@@ -0,0 +1,88 @@
+package net_hygiene
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// canaryResult is the outcome of the request of a canary URL.
+type canaryResult struct {
+	status  string
+	details string
+}
+
+func isCertificateError(err error) bool {
+	if uerr, ok := err.(*url.Error); ok {
+		err = uerr.Err
+	}
+
+	switch err.(type) {
+	case x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError:
+		return true
+	}
+	return false
+}
+
+func (mod *NetHygiene) canaryClient() *http.Client {
+	return &http.Client{
+		Timeout: mod.timeout,
+		// never go through a proxy, the network itself is being checked
+		Transport: &http.Transport{Proxy: nil},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkCanary requests the plain HTTP URL of a website that always redirects
+// to HTTPS, if it doesn't the response has been stripped, if the certificate
+// of the HTTPS website is not trusted it's being intercepted.
+func (mod *NetHygiene) checkCanary(client *http.Client, canary string) canaryResult {
+	res, err := client.Get(canary)
+	if err != nil {
+		return canaryResult{StatusUnknown, fmt.Sprintf("%s: %v", canary, err)}
+	}
+	res.Body.Close()
+
+	location := res.Header.Get("Location")
+	if res.StatusCode < 300 || res.StatusCode >= 400 || !strings.HasPrefix(strings.ToLower(location), "https://") {
+		return canaryResult{StatusCritical, fmt.Sprintf("%s answered %d without redirecting to HTTPS", canary, res.StatusCode)}
+	}
+
+	if res, err = client.Get(location); err != nil {
+		if isCertificateError(err) {
+			return canaryResult{StatusCritical, fmt.Sprintf("%s has an untrusted certificate: %v", location, err)}
+		}
+		return canaryResult{StatusUnknown, fmt.Sprintf("%s: %v", location, err)}
+	}
+	res.Body.Close()
+
+	return canaryResult{StatusOK, canary}
+}
+
+// checkCanaries returns the worst result of the canary requests.
+func (mod *NetHygiene) checkCanaries() Finding {
+	const check = "SSL stripping"
+
+	if len(mod.canaries) == 0 {
+		return newFinding(check, StatusUnknown, "no canaries")
+	}
+
+	client := mod.canaryClient()
+	results := make(map[string][]string)
+	for _, canary := range mod.canaries {
+		r := mod.checkCanary(client, canary)
+		mod.Debug("canary %s: %s", r.status, r.details)
+		results[r.status] = append(results[r.status], r.details)
+	}
+
+	if issues, found := results[StatusCritical]; found {
+		return newFinding(check, StatusCritical, "%s", strings.Join(issues, ", "))
+	} else if ok, found := results[StatusOK]; found {
+		return newFinding(check, StatusOK, "%d of %d canaries redirected to HTTPS", len(ok), len(mod.canaries))
+	}
+	return newFinding(check, StatusUnknown, "%s", strings.Join(results[StatusUnknown], ", "))
+}
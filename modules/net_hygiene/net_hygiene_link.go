@@ -0,0 +1,287 @@
+package net_hygiene
+
+import (
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+// dhcpOffer is an offer received for the DISCOVER of the checks.
+type dhcpOffer struct {
+	server net.IP
+	hw     net.HardwareAddr
+	router net.IP
+}
+
+// linkAnswers are the answers to the probes seen on the link.
+type linkAnswers struct {
+	xid         uint32
+	gateway     net.IP
+	offers      map[string]dhcpOffer
+	gatewayMACs map[string]bool
+	routers     map[string]string
+}
+
+func newLinkAnswers(xid uint32, gateway net.IP) *linkAnswers {
+	return &linkAnswers{
+		xid:         xid,
+		gateway:     gateway,
+		offers:      make(map[string]dhcpOffer),
+		gatewayMACs: make(map[string]bool),
+		routers:     make(map[string]string),
+	}
+}
+
+func (a *linkAnswers) onPacket(pkt gopacket.Packet) {
+	leth := pkt.Layer(layers.LayerTypeEthernet)
+	if leth == nil {
+		return
+	}
+	eth := leth.(*layers.Ethernet)
+
+	if ldhcp := pkt.Layer(layers.LayerTypeDHCPv4); ldhcp != nil {
+		offer := ldhcp.(*layers.DHCPv4)
+		if offer.Operation != layers.DHCPOpReply || offer.Xid != a.xid ||
+			packets.DHCP4MessageType(offer) != layers.DHCPMsgTypeOffer {
+			return
+		}
+
+		server := net.IP(packets.DHCP4Option(offer, layers.DHCPOptServerID))
+		if len(server) != net.IPv4len {
+			if lip := pkt.Layer(layers.LayerTypeIPv4); lip != nil {
+				server = lip.(*layers.IPv4).SrcIP
+			}
+		}
+
+		router := net.IP(nil)
+		if data := packets.DHCP4Option(offer, layers.DHCPOptRouter); len(data) >= net.IPv4len {
+			router = net.IP(data[:net.IPv4len])
+		}
+
+		a.offers[server.String()] = dhcpOffer{
+			server: server,
+			hw:     eth.SrcMAC,
+			router: router,
+		}
+	} else if larp := pkt.Layer(layers.LayerTypeARP); larp != nil {
+		arp := larp.(*layers.ARP)
+		if arp.Operation == layers.ARPReply && a.gateway != nil && a.gateway.Equal(net.IP(arp.SourceProtAddress)) {
+			a.gatewayMACs[net.HardwareAddr(arp.SourceHwAddress).String()] = true
+		}
+	} else if lra := pkt.Layer(layers.LayerTypeICMPv6RouterAdvertisement); lra != nil {
+		// only the routers advertising themselves as default gateways
+		if lra.(*layers.ICMPv6RouterAdvertisement).RouterLifetime > 0 {
+			if lip := pkt.Layer(layers.LayerTypeIPv6); lip != nil {
+				a.routers[lip.(*layers.IPv6).SrcIP.String()] = eth.SrcMAC.String()
+			}
+		}
+	}
+}
+
+func (mod *NetHygiene) sendProbes(xid uint32, gateway net.IP) {
+	iface := mod.Session.Interface
+	probes := make([][]byte, 0)
+
+	if err, raw := packets.NewDHCP4Discover(iface.HW, xid); err != nil {
+		mod.Error("error creating DHCP discover: %s", err)
+	} else {
+		probes = append(probes, raw)
+	}
+
+	if gateway != nil {
+		eth, arp := packets.NewARPTo(iface.IP, iface.HW, gateway, layers.EthernetBroadcast, layers.ARPRequest)
+		if err, raw := packets.Serialize(&eth, &arp); err != nil {
+			mod.Error("error creating ARP request: %s", err)
+		} else {
+			probes = append(probes, raw)
+		}
+	}
+
+	if iface.IPv6 != nil {
+		if err, raw := packets.NewRouterSolicitation(iface.IPv6, iface.HW); err != nil {
+			mod.Error("error creating router solicitation: %s", err)
+		} else {
+			probes = append(probes, raw)
+		}
+	}
+
+	for _, raw := range probes {
+		if err := mod.Session.Queue.Send(raw); err != nil {
+			mod.Error("error sending probe: %s", err)
+		}
+	}
+}
+
+// probeLink sends a DHCP discover, an ARP request for the gateway and a
+// router solicitation, and collects the answers until the timeout.
+func (mod *NetHygiene) probeLink(gateway net.IP) (*linkAnswers, error) {
+	handle, err := pcap.OpenLive(mod.Session.Interface.Name(), 65536, true, 500*time.Millisecond)
+	if err != nil {
+		return nil, err
+	} else if err = handle.SetBPFFilter("arp or (udp and dst port 68) or icmp6"); err != nil {
+		handle.Close()
+		return nil, err
+	}
+
+	answers := newLinkAnswers(rand.Uint32(), gateway)
+	src := gopacket.NewPacketSource(handle, handle.LinkType())
+	pktSourceChan := src.Packets()
+
+	mod.sendProbes(answers.xid, gateway)
+
+	deadline := time.After(mod.timeout)
+	for done := false; !done; {
+		select {
+		case pkt, ok := <-pktSourceChan:
+			if !ok {
+				done = true
+			} else {
+				answers.onPacket(pkt)
+			}
+		case <-deadline:
+			done = true
+		}
+	}
+
+	handle.Close()
+	// let the packet source exit
+	go func() {
+		for range pktSourceChan {
+		}
+	}()
+
+	return answers, nil
+}
+
+func (mod *NetHygiene) isTrustedDHCP(ip net.IP) bool {
+	for _, trusted := range mod.trustedDHCP {
+		if trusted.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *linkAnswers) describeOffers() []string {
+	servers := make([]string, 0, len(a.offers))
+	for _, offer := range a.offers {
+		servers = append(servers, offer.server.String()+" ("+offer.hw.String()+")")
+	}
+	sort.Strings(servers)
+	return servers
+}
+
+func (a *linkAnswers) offeredRouters() []string {
+	routers := make(map[string]bool)
+	for _, offer := range a.offers {
+		if offer.router != nil {
+			routers[offer.router.String()] = true
+		}
+	}
+	return sortedKeys(routers)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (mod *NetHygiene) checkDHCP(answers *linkAnswers) Finding {
+	const check = "DHCP servers"
+
+	if len(answers.offers) == 0 {
+		return newFinding(check, StatusUnknown, "no DHCP server answered")
+	}
+
+	if len(mod.trustedDHCP) > 0 {
+		rogue := make([]string, 0)
+		for _, offer := range answers.offers {
+			if !mod.isTrustedDHCP(offer.server) {
+				rogue = append(rogue, offer.server.String()+" ("+offer.hw.String()+")")
+			}
+		}
+		if len(rogue) > 0 {
+			sort.Strings(rogue)
+			return newFinding(check, StatusCritical, "rogue DHCP servers answering: %s", strings.Join(rogue, ", "))
+		}
+	} else if len(answers.offers) > 1 {
+		return newFinding(check, StatusCritical, "%d DHCP servers answering: %s", len(answers.offers), strings.Join(answers.describeOffers(), ", "))
+	}
+
+	return newFinding(check, StatusOK, "%s", strings.Join(answers.describeOffers(), ", "))
+}
+
+func (mod *NetHygiene) checkGateway(gateway net.IP, answers *linkAnswers) Finding {
+	const check = "Gateway"
+
+	if gateway == nil {
+		return newFinding(check, StatusUnknown, "no gateway")
+	}
+
+	macs := sortedKeys(answers.gatewayMACs)
+	if len(macs) > 1 {
+		return newFinding(check, StatusCritical, "%s answered by %d hosts: %s", gateway, len(macs), strings.Join(macs, ", "))
+	} else if routers := answers.offeredRouters(); len(routers) > 1 {
+		return newFinding(check, StatusWarning, "the DHCP servers offer different gateways: %s", strings.Join(routers, ", "))
+	} else if len(routers) == 1 && routers[0] != gateway.String() {
+		return newFinding(check, StatusWarning, "the DHCP server offers %s instead of %s", routers[0], gateway)
+	} else if len(macs) == 0 {
+		return newFinding(check, StatusUnknown, "%s didn't answer", gateway)
+	}
+
+	return newFinding(check, StatusOK, "%s is at %s", gateway, macs[0])
+}
+
+func (mod *NetHygiene) checkRouters(answers *linkAnswers) Finding {
+	const check = "IPv6 routers"
+
+	routers := make([]string, 0, len(answers.routers))
+	for ip, hw := range answers.routers {
+		routers = append(routers, ip+" ("+hw+")")
+	}
+	sort.Strings(routers)
+
+	if len(routers) > 1 {
+		return newFinding(check, StatusWarning, "%d routers advertising: %s", len(routers), strings.Join(routers, ", "))
+	} else if len(routers) == 0 {
+		return newFinding(check, StatusOK, "no router advertisements")
+	}
+	return newFinding(check, StatusOK, "%s", routers[0])
+}
+
+// checkLink returns the findings about the DHCP servers, the gateway and the
+// IPv6 routers of the network.
+func (mod *NetHygiene) checkLink() []Finding {
+	gateway := net.IP(nil)
+	if mod.Session.Gateway != mod.Session.Interface {
+		gateway = mod.Session.Gateway.IP
+	}
+
+	answers, err := mod.probeLink(gateway)
+	if err != nil {
+		mod.Error("can't probe the link: %v", err)
+		return []Finding{
+			newFinding("DHCP servers", StatusUnknown, "%v", err),
+			newFinding("Gateway", StatusUnknown, "%v", err),
+			newFinding("IPv6 routers", StatusUnknown, "%v", err),
+		}
+	}
+
+	return []Finding{
+		mod.checkDHCP(answers),
+		mod.checkGateway(gateway, answers),
+		mod.checkRouters(answers),
+	}
+}
@@ -0,0 +1,62 @@
+package net_hygiene
+
+import (
+	"fmt"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func colorStatus(status string) string {
+	switch status {
+	case StatusOK:
+		return tui.Green(status)
+	case StatusWarning:
+		return tui.Yellow(status)
+	case StatusCritical:
+		return tui.Red(status)
+	}
+	return tui.Dim(status)
+}
+
+func colorScore(score int) string {
+	s := fmt.Sprintf("%d/100", score)
+	if score >= 80 {
+		return tui.Green(s)
+	} else if score >= 50 {
+		return tui.Yellow(s)
+	}
+	return tui.Red(s)
+}
+
+func (mod *NetHygiene) Show() error {
+	mod.reportLock.Lock()
+	report := mod.report
+	mod.reportLock.Unlock()
+
+	if report == nil {
+		return fmt.Errorf("no checks run yet, use net.hygiene.check or net.hygiene on")
+	} else if mod.Session.ShowJSON(report) {
+		return nil
+	}
+
+	rows := make([][]string, 0, len(report.Findings))
+	for _, f := range report.Findings {
+		penalty := ""
+		if f.Penalty > 0 {
+			penalty = fmt.Sprintf("-%d", f.Penalty)
+		}
+		rows = append(rows, []string{
+			f.Check,
+			colorStatus(f.Status),
+			penalty,
+			f.Details,
+		})
+	}
+
+	mod.Session.Table([]string{"Check", "Status", "Penalty", "Details"}, rows)
+	mod.Session.Printf("\nnetwork hygiene score %s %s\n\n",
+		tui.Bold(colorScore(report.Score)),
+		tui.Dim("("+report.Time.Format("15:04:05")+")"))
+
+	return nil
+}
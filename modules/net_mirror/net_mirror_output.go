@@ -0,0 +1,132 @@
+package net_mirror
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket/pcap"
+)
+
+const (
+	ModeInterface = "interface"
+	ModeVXLAN     = "vxlan"
+	ModeERSPAN    = "erspan"
+)
+
+// output is where the mirrored frames are copied to.
+type output interface {
+	Write(frame []byte) error
+	Close()
+	String() string
+}
+
+// ifaceOutput writes the frames as they are to a local interface.
+type ifaceOutput struct {
+	name     string
+	injector packets.Injector
+}
+
+func newIfaceOutput(name string) (*ifaceOutput, error) {
+	out := &ifaceOutput{name: name}
+	if injector, err := packets.NewNativeInjector(name); err == nil {
+		out.injector = injector
+	} else if handle, err := pcap.OpenLive(name, 65536, false, pcap.BlockForever); err != nil {
+		return nil, err
+	} else {
+		out.injector = handle
+	}
+	return out, nil
+}
+
+func (out *ifaceOutput) Write(frame []byte) error {
+	return out.injector.WritePacketData(frame)
+}
+
+func (out *ifaceOutput) Close() {
+	out.injector.Close()
+}
+
+func (out *ifaceOutput) String() string {
+	return out.name
+}
+
+// vxlanOutput encapsulates the frames in VXLAN over UDP.
+type vxlanOutput struct {
+	conn *net.UDPConn
+	vni  uint32
+}
+
+func newVXLANOutput(destination string, vni uint32) (*vxlanOutput, error) {
+	if _, _, err := net.SplitHostPort(destination); err != nil {
+		destination = net.JoinHostPort(destination, strconv.Itoa(packets.VXLANPort))
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", destination)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &vxlanOutput{conn: conn, vni: vni}, nil
+}
+
+func (out *vxlanOutput) Write(frame []byte) error {
+	_, err := out.conn.Write(packets.NewVXLANFrame(out.vni, frame))
+	return err
+}
+
+func (out *vxlanOutput) Close() {
+	out.conn.Close()
+}
+
+func (out *vxlanOutput) String() string {
+	return fmt.Sprintf("vxlan://%s (vni %d)", out.conn.RemoteAddr(), out.vni)
+}
+
+// erspanOutput encapsulates the frames in GRE and ERSPAN type II over a raw
+// socket, it requires root privileges.
+type erspanOutput struct {
+	conn    *net.IPConn
+	session uint16
+	index   uint32
+	seq     uint32
+}
+
+func newERSPANOutput(destination string, session uint16, index uint32) (*erspanOutput, error) {
+	addr, err := net.ResolveIPAddr("ip", destination)
+	if err != nil {
+		return nil, err
+	}
+
+	network := "ip4:gre"
+	if addr.IP.To4() == nil {
+		network = "ip6:gre"
+	}
+
+	conn, err := net.DialIP(network, nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &erspanOutput{conn: conn, session: session, index: index}, nil
+}
+
+func (out *erspanOutput) Write(frame []byte) error {
+	seq := atomic.AddUint32(&out.seq, 1)
+	_, err := out.conn.Write(packets.NewERSPANFrame(out.session, seq, out.index, frame))
+	return err
+}
+
+func (out *erspanOutput) Close() {
+	out.conn.Close()
+}
+
+func (out *erspanOutput) String() string {
+	return fmt.Sprintf("erspan://%s (session %d)", out.conn.RemoteAddr(), out.session)
+}
@@ -1,6 +1,7 @@
 package net_probe
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -11,19 +12,37 @@ import (
 )
 
 type Probes struct {
-	NBNS bool
-	MDNS bool
-	UPNP bool
-	WSD  bool
+	NBNS   bool
+	MDNS   bool
+	UPNP   bool
+	WSD    bool
+	Custom []CustomProbe
+}
+
+// probeSchedule keeps track of when a prober has to be triggered again.
+type probeSchedule struct {
+	interval time.Duration
+	next     time.Time
+}
+
+func (s *probeSchedule) due(now time.Time) bool {
+	if now.Before(s.next) {
+		return false
+	}
+	s.next = now.Add(s.interval)
+	return true
 }
 
 type Prober struct {
 	session.SessionModule
 	throttle  int
 	probes    Probes
+	schedules map[string]*probeSchedule
 	waitGroup *sync.WaitGroup
 }
 
+var probers = []string{"nbns", "mdns", "upnp", "wsd", "custom"}
+
 func NewProber(s *session.Session) *Prober {
 	mod := &Prober{
 		SessionModule: session.NewSessionModule("net.probe", s),
@@ -46,6 +65,17 @@ func NewProber(s *session.Session) *Prober {
 		"true",
 		"Enable WSD discovery probes."))
 
+	for _, name := range probers {
+		mod.AddParam(session.NewIntParameter("net.probe."+name+".interval",
+			"5",
+			"Number of seconds between "+name+" probing rounds."))
+	}
+
+	mod.AddParam(session.NewStringParameter("net.probe.custom",
+		"",
+		"",
+		"Comma separated list of custom UDP probes sent to every address of the subnet, each one in the PORT:HEX_PAYLOAD form (example: 10001:01000000)."))
+
 	mod.AddParam(session.NewIntParameter("net.probe.throttle",
 		"10",
 		"If greater than 0, probe packets will be throttled by this value in milliseconds."))
@@ -89,9 +119,27 @@ func (mod *Prober) Configure() error {
 		return err
 	} else if err, mod.probes.WSD = mod.BoolParam("net.probe.wsd"); err != nil {
 		return err
-	} else {
-		mod.Debug("Throttling packets of %d ms.", mod.throttle)
 	}
+
+	err, custom := mod.StringParam("net.probe.custom")
+	if err != nil {
+		return err
+	} else if err, mod.probes.Custom = parseCustomProbes(custom); err != nil {
+		return err
+	}
+
+	mod.schedules = make(map[string]*probeSchedule)
+	for _, name := range probers {
+		err, interval := mod.IntParam("net.probe." + name + ".interval")
+		if err != nil {
+			return err
+		} else if interval <= 0 {
+			return fmt.Errorf("net.probe.%s.interval must be greater than 0", name)
+		}
+		mod.schedules[name] = &probeSchedule{interval: time.Duration(interval) * time.Second}
+	}
+
+	mod.Debug("Throttling packets of %d ms.", mod.throttle)
 	return nil
 }
 
@@ -120,31 +168,44 @@ func (mod *Prober) Start() error {
 		throttle := time.Duration(mod.throttle) * time.Millisecond
 
 		for mod.Running() {
-			if mod.probes.MDNS {
+			now := time.Now()
+
+			if mod.probes.MDNS && mod.schedules["mdns"].due(now) {
 				mod.sendProbeMDNS(fromIP, fromHW)
 			}
 
-			if mod.probes.UPNP {
+			if mod.probes.UPNP && mod.schedules["upnp"].due(now) {
 				mod.sendProbeUPNP(fromIP, fromHW)
 			}
 
-			if mod.probes.WSD {
+			if mod.probes.WSD && mod.schedules["wsd"].due(now) {
 				mod.sendProbeWSD(fromIP, fromHW)
 			}
 
-			for _, ip := range addresses {
-				if !mod.Running() {
-					return
-				} else if mod.Session.Skip(ip) {
-					mod.Debug("skipping address %s from probing.", ip)
-					continue
-				} else if mod.probes.NBNS {
-					mod.sendProbeNBNS(fromIP, fromHW, ip)
+			nbns := mod.probes.NBNS && mod.schedules["nbns"].due(now)
+			custom := len(mod.probes.Custom) > 0 && mod.schedules["custom"].due(now)
+			if nbns || custom {
+				for _, ip := range addresses {
+					if !mod.Running() {
+						return
+					} else if mod.Session.Skip(ip) {
+						mod.Debug("skipping address %s from probing.", ip)
+						continue
+					}
+
+					if nbns {
+						mod.sendProbeNBNS(fromIP, fromHW, ip)
+					}
+					if custom {
+						for _, probe := range mod.probes.Custom {
+							mod.sendProbeCustom(ip, probe)
+						}
+					}
+					time.Sleep(throttle)
 				}
-				time.Sleep(throttle)
 			}
 
-			time.Sleep(5 * time.Second)
+			time.Sleep(time.Second)
 		}
 	})
 }
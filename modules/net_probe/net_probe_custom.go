@@ -0,0 +1,63 @@
+package net_probe
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/evilsocket/islazy/str"
+)
+
+// CustomProbe is a user defined UDP payload sent to every address of the subnet,
+// useful to discover devices answering to proprietary discovery protocols.
+type CustomProbe struct {
+	Port    int
+	Payload []byte
+}
+
+// parseCustomProbes parses a comma separated list of PORT:HEX definitions.
+func parseCustomProbes(value string) (error, []CustomProbe) {
+	probes := make([]CustomProbe, 0)
+	for _, def := range str.Comma(value) {
+		parts := strings.SplitN(def, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid custom probe '%s', expected PORT:HEX", def), nil
+		}
+
+		port, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil || port <= 0 || port > 65535 {
+			return fmt.Errorf("invalid port in custom probe '%s'", def), nil
+		}
+
+		payload, err := hex.DecodeString(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return fmt.Errorf("invalid payload in custom probe '%s': %s", def, err), nil
+		} else if len(payload) == 0 {
+			return fmt.Errorf("empty payload in custom probe '%s'", def), nil
+		}
+
+		probes = append(probes, CustomProbe{
+			Port:    port,
+			Payload: payload,
+		})
+	}
+	return nil, probes
+}
+
+func (mod *Prober) sendProbeCustom(ip net.IP, probe CustomProbe) {
+	name := fmt.Sprintf("%s:%d", ip, probe.Port)
+	if addr, err := net.ResolveUDPAddr("udp", name); err != nil {
+		mod.Debug("could not resolve %s.", name)
+	} else if con, err := net.DialUDP("udp", nil, addr); err != nil {
+		mod.Debug("could not dial %s.", name)
+	} else {
+		defer con.Close()
+		if wrote, _ := con.Write(probe.Payload); wrote > 0 {
+			mod.Session.Queue.TrackSent(uint64(wrote))
+		} else {
+			mod.Session.Queue.TrackError()
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package net_recon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/fs"
+)
+
+func (mod *Discovery) exportHosts(filename string) error {
+	filename, err := fs.Expand(filename)
+	if err != nil {
+		return err
+	}
+
+	hosts := []*network.Endpoint{mod.Session.Gateway}
+	hosts = append(hosts, mod.Session.Lan.List()...)
+
+	fp, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	format := "csv"
+	if strings.ToLower(filepath.Ext(filename)) == ".xml" {
+		format = "nmap"
+		err = network.ExportNmapXML(fp, hosts, mod.Session.StartedAt)
+	} else {
+		err = network.ExportServicesCSV(fp, hosts)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	mod.Info("exported %d hosts to %s (%s)", len(hosts), filename, format)
+	return nil
+}
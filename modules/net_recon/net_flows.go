@@ -0,0 +1,191 @@
+package net_recon
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func (mod *Discovery) flowEndpoint(ip string, port int, geo *network.GeoInfo) string {
+	addr := ip
+	if e := mod.Session.Lan.GetByIp(ip); e != nil && e.Alias != "" {
+		addr = fmt.Sprintf("%s (%s)", ip, tui.Green(e.Alias))
+	}
+	if port > 0 {
+		addr = fmt.Sprintf("%s:%d", addr, port)
+	}
+	if geo != nil {
+		addr = fmt.Sprintf("%s %s", addr, tui.Dim("["+geo.String()+"]"))
+	}
+	return addr
+}
+
+func (mod *Discovery) getFlowRow(flow *network.Flow) []string {
+	state := flow.State
+	switch state {
+	case network.FlowStateEstablished, network.FlowStateReplied:
+		state = tui.Green(state)
+	case network.FlowStateReset:
+		state = tui.Red(state)
+	case network.FlowStateClosed:
+		state = tui.Dim(state)
+	}
+
+	seen := flow.LastSeen.Format("15:04:05")
+	if sinceLastSeen := time.Since(flow.LastSeen); sinceLastSeen <= JustJoinedTimeInterval {
+		seen = tui.Bold(seen)
+	} else if sinceLastSeen > PresentTimeInterval {
+		seen = tui.Dim(seen)
+	}
+
+	return []string{
+		flow.Proto,
+		mod.flowEndpoint(flow.SrcIP, flow.SrcPort, flow.SrcGeo),
+		mod.flowEndpoint(flow.DstIP, flow.DstPort, flow.DstGeo),
+		state,
+		fmt.Sprintf("%d", flow.Packets()),
+		humanize.Bytes(flow.SentBytes),
+		humanize.Bytes(flow.RecvBytes),
+		flow.Duration().Round(time.Second).String(),
+		seen,
+	}
+}
+
+func (mod *Discovery) doFlowFilter(flow *network.Flow) bool {
+	if mod.flowsSelector.Expression == nil {
+		return true
+	}
+	for _, geo := range []*network.GeoInfo{flow.SrcGeo, flow.DstGeo} {
+		if geo != nil && mod.flowsSelector.Expression.MatchString(geo.String()) {
+			return true
+		}
+	}
+	return mod.flowsSelector.Expression.MatchString(flow.Src()) ||
+		mod.flowsSelector.Expression.MatchString(flow.Dst()) ||
+		mod.flowsSelector.Expression.MatchString(flow.State)
+}
+
+func (mod *Discovery) doFlowsSelection(filter network.FlowFilter) (err error, flows []*network.Flow) {
+	if err = mod.flowsSelector.Update(); err != nil {
+		return
+	}
+
+	flows = make([]*network.Flow, 0)
+	for _, flow := range mod.Session.Flows.List() {
+		if filter.Matches(flow) && mod.doFlowFilter(flow) {
+			flows = append(flows, flow)
+		}
+	}
+
+	switch mod.flowsSelector.SortField {
+	case "proto":
+		sort.Slice(flows, func(i, j int) bool {
+			return flows[i].Proto < flows[j].Proto
+		})
+	case "src":
+		sort.Slice(flows, func(i, j int) bool {
+			return flows[i].Src() < flows[j].Src()
+		})
+	case "dst":
+		sort.Slice(flows, func(i, j int) bool {
+			return flows[i].Dst() < flows[j].Dst()
+		})
+	case "state":
+		sort.Slice(flows, func(i, j int) bool {
+			return flows[i].State < flows[j].State
+		})
+	case "packets":
+		sort.Slice(flows, func(i, j int) bool {
+			return flows[i].Packets() < flows[j].Packets()
+		})
+	case "bytes":
+		sort.Slice(flows, func(i, j int) bool {
+			return flows[i].Bytes() < flows[j].Bytes()
+		})
+	case "duration":
+		sort.Slice(flows, func(i, j int) bool {
+			return flows[i].Duration() < flows[j].Duration()
+		})
+	case "seen":
+		sort.Slice(flows, func(i, j int) bool {
+			return flows[i].LastSeen.Before(flows[j].LastSeen)
+		})
+	}
+
+	// default is asc
+	if mod.flowsSelector.Sort == "desc" {
+		// from https://github.com/golang/go/wiki/SliceTricks
+		for i := len(flows)/2 - 1; i >= 0; i-- {
+			opp := len(flows) - 1 - i
+			flows[i], flows[opp] = flows[opp], flows[i]
+		}
+	}
+
+	if mod.flowsSelector.Limit > 0 {
+		limit := mod.flowsSelector.Limit
+		max := len(flows)
+		if limit > max {
+			limit = max
+		}
+		flows = flows[0:limit]
+	}
+
+	return
+}
+
+func (mod *Discovery) flowsColNames() []string {
+	colNames := []string{"Proto", "Source", "Destination", "State", "Packets", "Sent", "Recvd", "Duration", "Seen"}
+	switch mod.flowsSelector.SortField {
+	case "proto":
+		colNames[0] += " " + mod.flowsSelector.SortSymbol
+	case "src":
+		colNames[1] += " " + mod.flowsSelector.SortSymbol
+	case "dst":
+		colNames[2] += " " + mod.flowsSelector.SortSymbol
+	case "state":
+		colNames[3] += " " + mod.flowsSelector.SortSymbol
+	case "packets":
+		colNames[4] += " " + mod.flowsSelector.SortSymbol
+	case "bytes":
+		colNames[5] += " " + mod.flowsSelector.SortSymbol
+		colNames[6] += " " + mod.flowsSelector.SortSymbol
+	case "duration":
+		colNames[7] += " " + mod.flowsSelector.SortSymbol
+	case "seen":
+		colNames[8] += " " + mod.flowsSelector.SortSymbol
+	}
+	return colNames
+}
+
+func (mod *Discovery) showFlows(expr string) (err error) {
+	filter, err := network.ParseFlowFilter(expr)
+	if err != nil {
+		return err
+	}
+
+	var flows []*network.Flow
+	if err, flows = mod.doFlowsSelection(filter); err != nil {
+		return
+	}
+
+	rows := make([][]string, 0)
+	for _, flow := range flows {
+		rows = append(rows, mod.getFlowRow(flow))
+	}
+
+	mod.Session.Println()
+	mod.Session.Table(mod.flowsColNames(), rows)
+	mod.Session.Printf("\n%d of %d flows\n\n", len(rows), mod.Session.Flows.Len())
+
+	if len(rows) > 0 {
+		mod.Session.Refresh()
+	}
+
+	return nil
+}
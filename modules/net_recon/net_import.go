@@ -0,0 +1,85 @@
+package net_recon
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/fs"
+)
+
+func (mod *Discovery) importHosts(filename string) error {
+	filename, err := fs.Expand(filename)
+	if err != nil {
+		return err
+	}
+
+	fp, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	// detect nmap XML output from the extension or the first character
+	reader := bufio.NewReader(fp)
+	format := "csv"
+	if strings.ToLower(filepath.Ext(filename)) == ".xml" {
+		format = "nmap"
+	} else if head, err := reader.Peek(1); err == nil && head[0] == '<' {
+		format = "nmap"
+	}
+
+	var hosts []*network.ImportedHost
+	if format == "nmap" {
+		err, hosts = network.ParseNmapXML(reader)
+	} else {
+		err, hosts = network.ParseHostsCSV(reader)
+	}
+	if err != nil {
+		return err
+	}
+
+	source := filepath.Base(filename)
+	added, updated, skipped := 0, 0, 0
+	for _, host := range hosts {
+		if ip := net.ParseIP(host.IP); ip == nil {
+			mod.Warning("skipping invalid address %s", host.IP)
+			skipped++
+			continue
+		} else if !mod.Session.IPInScope(ip, "net.import") {
+			skipped++
+			continue
+		}
+
+		if host.MAC == "" {
+			// not in the file, the host might have been reached already
+			if mac, err := network.ArpLookup(mod.Session.Interface.Name(), host.IP, false); err == nil && mac != "" {
+				host.MAC = mac
+			}
+		}
+
+		isNew, _, err := mod.Session.Lan.Import(host, source)
+		if err != nil {
+			mod.Debug("skipping %s: %v", host.IP, err)
+			skipped++
+		} else if isNew {
+			added++
+		} else {
+			updated++
+		}
+	}
+
+	mod.Info("imported %d hosts from %s (%s): %d new, %d updated, %d skipped (not in the LAN, out of scope or without a MAC address)",
+		len(hosts), source, format, added, updated, skipped)
+
+	if added+updated == 0 && len(hosts) > 0 {
+		return fmt.Errorf("none of the hosts in %s could be imported", source)
+	}
+
+	return nil
+}
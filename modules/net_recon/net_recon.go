@@ -1,16 +1,19 @@
 package net_recon
 
 import (
-	"github.com/bettercap/bettercap/modules/utils"
+	"fmt"
 	"time"
 
+	"github.com/bettercap/bettercap/modules/utils"
+
 	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/session"
 )
 
 type Discovery struct {
 	session.SessionModule
-	selector *utils.ViewSelector
+	selector      *utils.ViewSelector
+	flowsSelector *utils.ViewSelector
 }
 
 func NewDiscovery(s *session.Session) *Discovery {
@@ -37,6 +40,22 @@ func NewDiscovery(s *session.Session) *Discovery {
 			return nil
 		}))
 
+	mod.AddParam(session.NewIntParameter("net.recon.ttl.arp",
+		"10",
+		"Seconds a host found in the ARP table is kept after it disappeared from it, 0 to never expire it."))
+
+	mod.AddParam(session.NewIntParameter("net.recon.ttl.sniffed",
+		"10",
+		"Seconds a host learned from its traffic is kept after it was last seen, 0 to never expire it."))
+
+	mod.AddParam(session.NewIntParameter("net.recon.ttl.scan",
+		"10",
+		"Seconds a host found by net.scan is kept after it was last seen, 0 to never expire it."))
+
+	mod.AddParam(session.NewIntParameter("net.recon.ttl.imported",
+		"0",
+		"Seconds a host imported with net.import is kept after it was last seen, 0 to never expire it."))
+
 	mod.AddParam(session.NewBoolParameter("net.show.meta",
 		"false",
 		"If true, the net.show command will show all metadata collected about each endpoint."))
@@ -59,9 +78,54 @@ func NewDiscovery(s *session.Session) *Discovery {
 			return mod.showMeta(args[0])
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("net.import FILENAME", `net\.import (.+)`,
+		"Import hosts, open ports and services from a nmap XML (-oX) or CSV (ip,mac,hostname,port,proto,service) file into the hosts list.",
+		func(args []string) error {
+			return mod.importHosts(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.export FILENAME", `net\.export (.+)`,
+		"Export hosts and the open ports found by syn.scan as a nmap XML file (if FILENAME ends with .xml, for Metasploit db_import and similar tools) or as a services CSV.",
+		func(args []string) error {
+			return mod.exportHosts(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.arp.show", "",
+		"Show the ARP cache of the operating system for every interface.",
+		func(args []string) error {
+			return mod.showArp()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.routes.show", "",
+		"Show the routing table of the operating system.",
+		func(args []string) error {
+			return mod.showRoutes()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.flows.show", "",
+		"Show the connections tracked from the sniffed traffic (default sorting by last seen).",
+		func(args []string) error {
+			return mod.showFlows("")
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.flows.show FILTER", `net\.flows\.show (.+)`,
+		"Show the tracked connections involving any of a comma separated list of IP addresses, ports, protocols (tcp, udp, icmp, icmp6, sctp) and country codes (if geoip.db is set).",
+		func(args []string) error {
+			return mod.showFlows(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.flows.clear", "",
+		"Clear the connections tracking table.",
+		func(args []string) error {
+			mod.Session.Flows.Clear()
+			return nil
+		}))
 	mod.selector = utils.ViewSelectorFor(&mod.SessionModule, "net.show", []string{"ip", "mac", "seen", "sent", "rcvd"},
 		"ip asc")
 
+	mod.flowsSelector = utils.ViewSelectorFor(&mod.SessionModule, "net.flows.show",
+		[]string{"proto", "src", "dst", "state", "packets", "bytes", "duration", "seen"}, "seen desc")
+
 	return mod
 }
 
@@ -97,7 +161,24 @@ func (mod *Discovery) runDiff(cache network.ArpTable) {
 	}
 }
 
+// ttlParams are the parameters setting the expiry of the hosts of each source
+var ttlParams = map[string]string{
+	network.SourceARP:      "net.recon.ttl.arp",
+	network.SourceSniffed:  "net.recon.ttl.sniffed",
+	network.SourceScan:     "net.recon.ttl.scan",
+	network.SourceImported: "net.recon.ttl.imported",
+}
+
 func (mod *Discovery) Configure() error {
+	for source, name := range ttlParams {
+		if err, ttl := mod.IntParam(name); err != nil {
+			return err
+		} else if ttl < 0 {
+			return fmt.Errorf("%s can't be negative", name)
+		} else {
+			mod.Session.Expiry.Set(source, time.Duration(ttl)*time.Second)
+		}
+	}
 	return nil
 }
 
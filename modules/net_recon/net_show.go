@@ -2,7 +2,6 @@ package net_recon
 
 import (
 	"fmt"
-	"os"
 	"sort"
 	"strings"
 	"time"
@@ -59,13 +58,17 @@ func (mod *Discovery) getRow(e *network.Endpoint, withMeta bool) [][]string {
 		name = tui.Yellow(e.Hostname)
 	}
 
+	if e.Meta.Get("imported") != "" {
+		name = strings.TrimSpace(name + " " + tui.Dim("(imported)"))
+	}
+
 	var traffic *packets.Traffic
 	var found bool
 	var v interface{}
 	if v, found = mod.Session.Queue.Traffic.Load(e.IpAddress); !found {
 		traffic = &packets.Traffic{}
 	} else {
-		traffic = v.(*packets.Traffic)
+		traffic = v.(*packets.Traffic).Load()
 	}
 
 	seen := e.LastSeen.Format("15:04:05")
@@ -206,17 +209,18 @@ func (mod *Discovery) colNames(hasMeta bool) []string {
 }
 
 func (mod *Discovery) showStatusBar() {
+	stats := mod.Session.Queue.Stats()
 	parts := []string{
-		fmt.Sprintf("%s %s", tui.Red("↑"), humanize.Bytes(mod.Session.Queue.Stats.Sent)),
-		fmt.Sprintf("%s %s", tui.Green("↓"), humanize.Bytes(mod.Session.Queue.Stats.Received)),
-		fmt.Sprintf("%d pkts", mod.Session.Queue.Stats.PktReceived),
+		fmt.Sprintf("%s %s", tui.Red("↑"), humanize.Bytes(stats.Sent)),
+		fmt.Sprintf("%s %s", tui.Green("↓"), humanize.Bytes(stats.Received)),
+		fmt.Sprintf("%d pkts", stats.PktReceived),
 	}
 
-	if nErrors := mod.Session.Queue.Stats.Errors; nErrors > 0 {
+	if nErrors := stats.Errors; nErrors > 0 {
 		parts = append(parts, fmt.Sprintf("%d errs", nErrors))
 	}
 
-	fmt.Printf("\n%s\n\n", strings.Join(parts, " / "))
+	mod.Session.Printf("\n%s\n\n", strings.Join(parts, " / "))
 }
 
 func (mod *Discovery) Show(arg string) (err error) {
@@ -256,7 +260,7 @@ func (mod *Discovery) Show(arg string) (err error) {
 		}
 	}
 
-	tui.Table(os.Stdout, colNames, rows)
+	mod.Session.Table(colNames, rows)
 
 	mod.showStatusBar()
 
@@ -298,7 +302,7 @@ func (mod *Discovery) showMeta(arg string) (err error) {
 			}
 
 			any = true
-			tui.Table(os.Stdout, colNames, rows)
+			mod.Session.Table(colNames, rows)
 		}
 	}
 
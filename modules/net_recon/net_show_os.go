@@ -0,0 +1,71 @@
+package net_recon
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func (mod *Discovery) showArp() error {
+	entries, err := network.ArpEntries()
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0)
+	for _, entry := range entries {
+		mac := entry.MAC
+		if e, found := mod.Session.Lan.Get(entry.MAC); found && e.Alias != "" {
+			mac = fmt.Sprintf("%s (%s)", mac, tui.Green(e.Alias))
+		}
+
+		ip := entry.IP
+		if entry.IP == mod.Session.Gateway.IpAddress {
+			ip = tui.Bold(ip)
+		}
+
+		rows = append(rows, []string{ip, mac, entry.Interface})
+	}
+
+	mod.Session.Println()
+	mod.Session.Table([]string{"IP", "MAC", "Interface"}, rows)
+	mod.Session.Println()
+
+	return nil
+}
+
+func (mod *Discovery) showRoutes() error {
+	routes, err := network.Routes()
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0)
+	for _, route := range routes {
+		dst := route.Destination
+		if route.IsDefault() {
+			dst = tui.Bold(dst)
+		}
+
+		gw := route.Gateway
+		if gw == "" {
+			gw = tui.Dim("-")
+		}
+
+		metric := ""
+		if route.Metric > 0 {
+			metric = strconv.Itoa(route.Metric)
+		}
+
+		rows = append(rows, []string{dst, gw, route.Interface, route.Flags, metric})
+	}
+
+	mod.Session.Println()
+	mod.Session.Table([]string{"Destination", "Gateway", "Interface", "Flags", "Metric"}, rows)
+	mod.Session.Println()
+
+	return nil
+}
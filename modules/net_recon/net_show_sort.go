@@ -45,7 +45,7 @@ func trafficOf(ip string) *packets.Traffic {
 	if v, found := session.I.Queue.Traffic.Load(ip); !found {
 		return &packets.Traffic{}
 	} else {
-		return v.(*packets.Traffic)
+		return v.(*packets.Traffic).Load()
 	}
 }
 
@@ -0,0 +1,278 @@
+package net_scan
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/malfunkt/iprange"
+
+	"github.com/evilsocket/islazy/str"
+	"github.com/evilsocket/islazy/tui"
+)
+
+type NetScanner struct {
+	session.SessionModule
+	workers int
+	retries int
+	wait    time.Duration
+
+	sync.Mutex
+	pending map[string]bool
+	replies map[string]string
+}
+
+func NewNetScanner(s *session.Session) *NetScanner {
+	mod := &NetScanner{
+		SessionModule: session.NewSessionModule("net.scan", s),
+		pending:       make(map[string]bool),
+		replies:       make(map[string]string),
+	}
+
+	mod.AddParam(session.NewIntParameter("net.scan.arp.workers",
+		"32",
+		"Number of ARP requests sent in parallel."))
+
+	mod.AddParam(session.NewIntParameter("net.scan.arp.retries",
+		"2",
+		"Number of times an ARP request is sent again to the addresses that didn't reply."))
+
+	mod.AddParam(session.NewIntParameter("net.scan.arp.wait",
+		"1000",
+		"Milliseconds to wait for the replies after each ARP sweep."))
+
+	mod.AddHandler(session.NewModuleHandler("net.scan.arp stop", `^net\.scan\.arp (stop|off)$`,
+		"Stop the current ARP sweep.",
+		func(args []string) error {
+			if !mod.Running() {
+				return fmt.Errorf("no ARP sweep is running")
+			}
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.scan.arp ADDRESSES?", `^net\.scan\.arp(\s+.+)?$`,
+		"Send an ARP request to every address of the subnet (or the comma separated IP ranges in ADDRESSES), add the hosts that replied to the LAN table and show them.",
+		func(args []string) error {
+			return mod.arpScan(str.Trim(args[0]))
+		}))
+
+	return mod
+}
+
+func (mod *NetScanner) Name() string {
+	return "net.scan"
+}
+
+func (mod *NetScanner) Description() string {
+	return "On demand active host discovery with ARP sweeps."
+}
+
+func (mod *NetScanner) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *NetScanner) Configure() (err error) {
+	var wait int
+	if err, mod.workers = mod.IntParam("net.scan.arp.workers"); err != nil {
+		return err
+	} else if err, mod.retries = mod.IntParam("net.scan.arp.retries"); err != nil {
+		return err
+	} else if err, wait = mod.IntParam("net.scan.arp.wait"); err != nil {
+		return err
+	} else if mod.workers <= 0 {
+		return fmt.Errorf("net.scan.arp.workers must be greater than 0")
+	} else if mod.retries < 0 {
+		return fmt.Errorf("net.scan.arp.retries can't be negative")
+	}
+	mod.wait = time.Duration(wait) * time.Millisecond
+	return nil
+}
+
+func (mod *NetScanner) Start() error {
+	return mod.arpScan("")
+}
+
+func (mod *NetScanner) Stop() error {
+	return mod.SetRunning(false, nil)
+}
+
+func (mod *NetScanner) parseTargets(arg string) ([]net.IP, error) {
+	if arg == "" {
+		arg = mod.Session.Interface.CIDR()
+	}
+
+	addresses := make([]net.IP, 0)
+	for _, part := range str.Comma(arg) {
+		list, err := iprange.Parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("error while parsing IP range '%s': %s", part, err)
+		}
+
+		for _, ip := range list.Expand() {
+			if !mod.Session.Interface.Net.Contains(ip) {
+				return nil, fmt.Errorf("%s is not on the %s subnet", ip, mod.Session.Interface.CIDR())
+			} else if !mod.Session.Skip(ip) {
+				addresses = append(addresses, ip)
+			}
+		}
+	}
+
+	return addresses, nil
+}
+
+func (mod *NetScanner) onPacket(pkt gopacket.Packet) {
+	layer := pkt.Layer(layers.LayerTypeARP)
+	if layer == nil {
+		return
+	}
+
+	arp := layer.(*layers.ARP)
+	if arp.Operation != layers.ARPReply {
+		return
+	}
+
+	ip := net.IP(arp.SourceProtAddress).String()
+	mac := net.HardwareAddr(arp.SourceHwAddress).String()
+
+	mod.Lock()
+	defer mod.Unlock()
+
+	if mod.pending[ip] {
+		delete(mod.pending, ip)
+		mod.replies[ip] = mac
+	}
+}
+
+func (mod *NetScanner) sweep(addresses []net.IP) {
+	queue := make(chan net.IP)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < mod.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ip := range queue {
+				if err, raw := packets.NewARPRequest(mod.Session.Interface.IP, mod.Session.Interface.HW, ip); err != nil {
+					mod.Error("error creating ARP request for %s: %s", ip, err)
+				} else if err = mod.Session.Queue.Send(raw); err != nil {
+					mod.Error("error sending ARP request to %s: %s", ip, err)
+				}
+			}
+		}()
+	}
+
+	for _, ip := range addresses {
+		if !mod.Running() {
+			break
+		}
+		queue <- ip
+	}
+	close(queue)
+	wg.Wait()
+
+	time.Sleep(mod.wait)
+}
+
+func (mod *NetScanner) unanswered() []net.IP {
+	mod.Lock()
+	defer mod.Unlock()
+
+	addresses := make([]net.IP, 0, len(mod.pending))
+	for ip := range mod.pending {
+		addresses = append(addresses, net.ParseIP(ip).To4())
+	}
+	return addresses
+}
+
+func (mod *NetScanner) arpScan(targets string) error {
+	if mod.Running() {
+		return fmt.Errorf("an ARP sweep is already running, wait for it to end before starting a new one")
+	} else if mod.Session.Interface.IpAddress == network.MonitorModeAddress {
+		return fmt.Errorf("interface is in monitor mode, can't send ARP requests")
+	} else if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	addresses, err := mod.parseTargets(targets)
+	if err != nil {
+		return err
+	} else if len(addresses) == 0 {
+		return fmt.Errorf("no addresses to scan")
+	}
+
+	mod.Lock()
+	mod.pending = make(map[string]bool)
+	mod.replies = make(map[string]string)
+	for _, ip := range addresses {
+		mod.pending[ip.String()] = true
+	}
+	mod.Unlock()
+
+	if err := mod.SetRunning(true, nil); err != nil {
+		return err
+	}
+	defer mod.SetRunning(false, nil)
+
+	mod.Session.Queue.OnPacket(mod.onPacket)
+	defer mod.Session.Queue.OnPacket(nil)
+
+	started := time.Now()
+	mod.Info("sending ARP requests to %d addresses with %d workers ...", len(addresses), mod.workers)
+
+	for round := 0; round <= mod.retries && mod.Running(); round++ {
+		if round > 0 {
+			if addresses = mod.unanswered(); len(addresses) == 0 {
+				break
+			}
+			mod.Debug("retry %d/%d for %d addresses", round, mod.retries, len(addresses))
+		}
+		mod.sweep(addresses)
+	}
+
+	return mod.showResults(time.Since(started))
+}
+
+func (mod *NetScanner) showResults(took time.Duration) error {
+	mod.Lock()
+	defer mod.Unlock()
+
+	ips := make([]string, 0, len(mod.replies))
+	for ip := range mod.replies {
+		ips = append(ips, ip)
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		return bytes.Compare(net.ParseIP(ips[i]).To4(), net.ParseIP(ips[j]).To4()) < 0
+	})
+
+	rows := make([][]string, 0)
+	found := 0
+	for _, ip := range ips {
+		mac := mod.replies[ip]
+		status := ""
+		if _, known := mod.Session.Lan.Get(mac); !known {
+			found++
+			status = tui.Green("new")
+		}
+		mod.Session.Lan.AddIfNewFrom(ip, mac, network.SourceScan)
+
+		rows = append(rows, []string{ip, mac, network.ManufLookup(mac), status})
+	}
+
+	mod.Info("%d hosts replied (%d new) in %s.", len(ips), found, took.Round(time.Millisecond))
+	if len(rows) > 0 {
+		mod.Session.Table([]string{"IP", "MAC", "Vendor", ""}, rows)
+	}
+
+	mod.Session.Refresh()
+	return nil
+}
@@ -2,12 +2,17 @@ package net_sniff
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/bettercap/bettercap/packets"
 	"github.com/bettercap/bettercap/session"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/str"
 )
 
 type Sniffer struct {
@@ -15,6 +20,9 @@ type Sniffer struct {
 	Stats         *SnifferStats
 	Ctx           *SnifferContext
 	pktSourceChan chan gopacket.Packet
+	buffer        *packetBuffer
+	paceStart     time.Time
+	paceFirst     time.Time
 
 	fuzzActive bool
 	fuzzSilent bool
@@ -27,6 +35,7 @@ func NewSniffer(s *session.Session) *Sniffer {
 	mod := &Sniffer{
 		SessionModule: session.NewSessionModule("net.sniff", s),
 		Stats:         nil,
+		buffer:        newPacketBuffer(),
 	}
 
 	mod.AddParam(session.NewBoolParameter("net.sniff.verbose",
@@ -42,6 +51,14 @@ func NewSniffer(s *session.Session) *Sniffer {
 		"",
 		"BPF filter for the sniffer."))
 
+	mod.AddParam(session.NewIntParameter("net.sniff.filter.preview",
+		"50",
+		"Number of the last captured packets kept to show which of them would match the filters tested with net.sniff.filter.test, 0 to disable."))
+
+	mod.AddParam(session.NewIntParameter("net.sniff.vlan",
+		"0",
+		"If greater than 0, only 802.1Q frames tagged with this VLAN identifier will be considered (use a 'vlan' BPF filter to capture tagged traffic)."))
+
 	mod.AddParam(session.NewStringParameter("net.sniff.regexp",
 		"",
 		"",
@@ -55,7 +72,25 @@ func NewSniffer(s *session.Session) *Sniffer {
 	mod.AddParam(session.NewStringParameter("net.sniff.source",
 		"",
 		"",
-		"If set, the sniffer will read from this pcap file instead of the current interface."))
+		"If set, the sniffer will read from this pcap file (optionally prefixed by file://) instead of the current interface, feeding its packets to the credentials parsers and to the endpoints discovery and fingerprinting."))
+
+	mod.AddParam(session.NewDecimalParameter("net.sniff.source.speed",
+		"0",
+		"When reading from net.sniff.source, replay the packets at this multiple of the speed they've been captured at, 0 to process them as fast as possible."))
+
+	mod.AddParam(session.NewBoolParameter("net.sniff.dlp",
+		"false",
+		"If true, the payloads of the sniffed packets will be scanned for sensitive data and every match will generate a net.sniff.dlp event with the redacted data."))
+
+	mod.AddParam(session.NewStringParameter("net.sniff.dlp.patterns",
+		"",
+		"",
+		"Comma separated list of built-in patterns to look for when net.sniff.dlp is true, leave empty for all of them ("+strings.Join(DLPBuiltins(), ", ")+")."))
+
+	mod.AddParam(session.NewStringParameter("net.sniff.dlp.custom",
+		"",
+		"",
+		"If set, a custom regular expression to look for when net.sniff.dlp is true."))
 
 	mod.AddHandler(session.NewModuleHandler("net.sniff stats", "",
 		"Print sniffer session configuration and statistics.",
@@ -69,6 +104,30 @@ func NewSniffer(s *session.Session) *Sniffer {
 			return mod.Stats.Print()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("net.sniff.stats", "",
+		"Show the packets parsed, the matches and the errors of every protocol parser.",
+		func(args []string) error {
+			return mod.showParserStats()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.filter.test EXPR", `^net\.sniff\.filter\.test\s+(.+)$`,
+		"Compile the BPF filter expression, showing where the error is if it's not valid, and which of the last captured packets it would match.",
+		func(args []string) error {
+			return mod.testFilter(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.parsers.enable PARSERS", `^net\.sniff\.parsers\.enable\s+(.+)$`,
+		"Enable a comma separated list of protocol parsers (or all of them) while the sniffer is running ("+strings.Join(Parsers.Names(), ", ")+").",
+		func(args []string) error {
+			return Parsers.SetEnabled(args[0], true)
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.parsers.disable PARSERS", `^net\.sniff\.parsers\.disable\s+(.+)$`,
+		"Disable a comma separated list of protocol parsers (or all of them) while the sniffer is running.",
+		func(args []string) error {
+			return Parsers.SetEnabled(args[0], false)
+		}))
+
 	mod.AddHandler(session.NewModuleHandler("net.sniff on", "",
 		"Start network sniffer in background.",
 		func(args []string) error {
@@ -81,6 +140,29 @@ func NewSniffer(s *session.Session) *Sniffer {
 			return mod.Stop()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("net.sniff.cookies VICTIM?", `^net\.sniff\.cookies(\s+[^\s]+)?$`,
+		"Show the HTTP cookies captured so far, for every victim or only for the given IP address (session cookies are highlighted).",
+		func(args []string) error {
+			return mod.showCookies(str.Trim(args[0]))
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.cookies.save FILE VICTIM?", `^net\.sniff\.cookies\.save\s+([^\s]+)(\s+[^\s]+)?$`,
+		"Save the HTTP cookies captured so far as JSON that can be imported by the EditThisCookie and Cookie-Editor browser extensions, optionally only the ones of the given victim.",
+		func(args []string) error {
+			fileName, err := fs.Expand(args[0])
+			if err != nil {
+				return err
+			}
+			return mod.saveCookies(fileName, str.Trim(args[1]))
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.sniff.cookies.clear", "",
+		"Clear the captured HTTP cookies.",
+		func(args []string) error {
+			Cookies.Clear()
+			return nil
+		}))
+
 	mod.AddHandler(session.NewModuleHandler("net.fuzz on", "",
 		"Enable fuzzing for every sniffed packet containing the specified layers.",
 		func(args []string) error {
@@ -140,6 +222,9 @@ func (mod *Sniffer) onPacketMatched(pkt gopacket.Packet) {
 	if mainParser(pkt, mod.Ctx.Verbose) {
 		mod.Stats.NumDumped++
 	}
+	if mod.Ctx.DLP != nil {
+		mod.Ctx.DLP.Scan(pkt)
+	}
 }
 
 func (mod *Sniffer) Configure() error {
@@ -165,26 +250,44 @@ func (mod *Sniffer) Start() error {
 
 	return mod.SetRunning(true, func() {
 		mod.Stats = NewSnifferStats()
+		Parsers.Reset()
+		mod.paceStart = time.Time{}
+		mod.buffer.Reset(mod.Ctx.Preview, mod.Ctx.Handle.LinkType())
 
 		src := gopacket.NewPacketSource(mod.Ctx.Handle, mod.Ctx.Handle.LinkType())
 		mod.pktSourceChan = src.Packets()
+		processed := 0
 		for packet := range mod.pktSourceChan {
 			if !mod.Running() {
 				mod.Debug("end pkt loop (pkt=%v filter='%s')", packet, mod.Ctx.Filter)
 				break
 			}
 
+			if mod.Ctx.Offline {
+				if !mod.pace(packet) {
+					break
+				}
+				mod.Session.Queue.Feed(packet)
+				processed++
+			}
+
 			now := time.Now()
 			if mod.Stats.FirstPacket.IsZero() {
 				mod.Stats.FirstPacket = now
 			}
 			mod.Stats.LastPacket = now
 
+			mod.buffer.Add(packet)
+
 			isLocal := mod.isLocalPacket(packet)
 			if isLocal {
 				mod.Stats.NumLocal++
 			}
 
+			if mod.Ctx.VLAN > 0 && packets.Dot1QGetVLAN(packet) != mod.Ctx.VLAN {
+				continue
+			}
+
 			if mod.fuzzActive {
 				mod.doFuzzing(packet)
 			}
@@ -204,6 +307,10 @@ func (mod *Sniffer) Start() error {
 			}
 		}
 
+		if mod.Ctx.Offline && mod.Running() {
+			mod.Info("done processing %s (%d packets, %d matched)", mod.Ctx.Source, processed, mod.Stats.NumMatched)
+		}
+
 		mod.pktSourceChan = nil
 	})
 }
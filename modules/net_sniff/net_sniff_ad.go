@@ -0,0 +1,227 @@
+package net_sniff
+
+import (
+	"encoding/asn1"
+	"encoding/binary"
+	"regexp"
+	"strings"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+var (
+	// DC locator records, _ldap._tcp[.site._sites][.dc|pdc|gc._msdcs].domain
+	adSRVRecord = regexp.MustCompile(`^_(ldap|kerberos|kpasswd|gc)\._(tcp|udp)\.(?:([^.]+)\._sites\.)?(?:(dc|pdc|gc)\._msdcs\.)?([^_].+)$`)
+
+	krb5TgsReqParam = "application,explicit,tag:12"
+
+	ldapParserStats = newParser("ldap")
+	nbnsParserStats = newParser("nbns")
+)
+
+func adSighting(s network.ADSighting) {
+	if session.I.AD != nil {
+		session.I.AD.Add(s)
+	}
+}
+
+// adKerberos records the realm of a KDC request, the server is a domain
+// controller and clients authenticating as name$ are computer accounts.
+func adKerberos(ip *layers.IPv4, payload []byte) bool {
+	var req packets.Krb5Request
+	if _, err := asn1.UnmarshalWithParams(payload, &req, packets.Krb5AsReqParam); err != nil {
+		if _, err = asn1.UnmarshalWithParams(payload, &req, krb5TgsReqParam); err != nil {
+			return false
+		}
+	}
+
+	s := network.ADSighting{
+		Source:     "krb5",
+		Domain:     req.ReqBody.Realm,
+		Controller: ip.DstIP.String(),
+	}
+	if names := req.ReqBody.Cname.NameString; len(names) == 1 && strings.HasSuffix(names[0], "$") {
+		s.Machine = names[0]
+		s.MachineAddress = ip.SrcIP.String()
+	}
+
+	adSighting(s)
+	return true
+}
+
+// adDNS records the domains and the domain controllers of the DC locator
+// queries and of their SRV answers.
+func adDNS(dns *layers.DNS) {
+	for _, q := range dns.Questions {
+		if m := adSRVRecord.FindStringSubmatch(strings.ToLower(string(q.Name))); m != nil && !strings.Contains(m[5], "_msdcs") {
+			adSighting(network.ADSighting{Source: "dns", Domain: m[5]})
+		}
+	}
+
+	if !dns.QR {
+		return
+	}
+
+	addresses := make(map[string]string)
+	records := append(append(dns.Answers, dns.Authorities...), dns.Additionals...)
+	for _, rr := range records {
+		if rr.Type == layers.DNSTypeA && rr.IP != nil {
+			addresses[strings.ToLower(string(rr.Name))] = rr.IP.String()
+		}
+	}
+
+	for _, rr := range records {
+		if rr.Type != layers.DNSTypeSRV {
+			continue
+		} else if m := adSRVRecord.FindStringSubmatch(strings.ToLower(string(rr.Name))); m != nil && !strings.Contains(m[5], "_msdcs") {
+			target := strings.ToLower(string(rr.SRV.Name))
+			adSighting(network.ADSighting{
+				Source:         "dns",
+				Domain:         m[5],
+				Controller:     addresses[target],
+				ControllerName: target,
+				Site:           m[3],
+				PDC:            m[4] == "pdc",
+			})
+		}
+	}
+}
+
+func firstOf(values []string) string {
+	if len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// adLDAP records the domain controller locator pings (CLDAP) and the rootDSE
+// of the domain controllers.
+func adLDAP(ip *layers.IPv4, payload []byte, fromServer bool) bool {
+	attrs := packets.LDAPAttributes(payload)
+	if len(attrs) == 0 {
+		return false
+	}
+
+	found := false
+	if domain := firstOf(attrs["dnsdomain"]); domain != "" && !fromServer {
+		// the client asks the DCs of its domain, Host is its computer name
+		found = true
+		adSighting(network.ADSighting{
+			Source:         "ldap",
+			Domain:         domain,
+			Machine:        firstOf(attrs["host"]),
+			MachineAddress: ip.SrcIP.String(),
+		})
+	}
+
+	if raw := firstOf(attrs["netlogon"]); raw != "" && fromServer {
+		if resp, err := packets.ParseNetlogonResponse([]byte(raw)); err == nil {
+			found = true
+			adSighting(network.ADSighting{
+				Source:         "ldap",
+				Domain:         resp.Domain,
+				NetBIOS:        resp.NetBIOSDomain,
+				Forest:         resp.Forest,
+				Controller:     ip.SrcIP.String(),
+				ControllerName: resp.Host,
+				Site:           resp.Site,
+				PDC:            resp.Flags&packets.NetlogonFlagPDC != 0,
+			})
+		}
+	}
+
+	if context := firstOf(attrs["defaultnamingcontext"]); context != "" && fromServer {
+		if host := firstOf(attrs["dnshostname"]); host != "" {
+			found = true
+			adSighting(network.ADSighting{
+				Source:         "ldap",
+				Domain:         namingContextDomain(context),
+				Forest:         namingContextDomain(firstOf(attrs["rootdomainnamingcontext"])),
+				Controller:     ip.SrcIP.String(),
+				ControllerName: host,
+			})
+		}
+	}
+
+	return found
+}
+
+// namingContextDomain converts DC=corp,DC=local to corp.local
+func namingContextDomain(context string) string {
+	labels := make([]string, 0)
+	for _, rdn := range strings.Split(context, ",") {
+		if parts := strings.SplitN(strings.TrimSpace(rdn), "=", 2); len(parts) == 2 && strings.EqualFold(parts[0], "dc") {
+			labels = append(labels, parts[1])
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// adNBNS records the domain controllers (the DOMAIN<1C> group) and the
+// primary domain controller (DOMAIN<1B>) of registrations and name query
+// responses.
+func adNBNS(payload []byte) bool {
+	p, err := packets.NBNSParse(payload)
+	if err != nil {
+		return false
+	}
+
+	registration := !p.Response && (p.Opcode == packets.NBNSOpcodeRegistration ||
+		p.Opcode == packets.NBNSOpcodeRefresh ||
+		p.Opcode == packets.NBNSOpcodeMultiHomed)
+	answer := p.Response && p.Opcode == packets.NBNSOpcodeQuery
+	if !registration && !answer {
+		return false
+	}
+
+	found := false
+	for _, r := range p.Records {
+		if r.IP == nil || r.IP.IsUnspecified() {
+			continue
+		} else if r.Suffix == packets.NBNSDomainController && r.Group {
+			found = true
+			adSighting(network.ADSighting{Source: "nbns", NetBIOS: r.Name, Controller: r.IP.String()})
+		} else if r.Suffix == packets.NBNSDomainMaster && !r.Group {
+			found = true
+			adSighting(network.ADSighting{Source: "nbns", NetBIOS: r.Name, Controller: r.IP.String(), PDC: true})
+		}
+	}
+	return found
+}
+
+func ldapUDPParser(ip *layers.IPv4, pkt gopacket.Packet, udp *layers.UDP) bool {
+	if udp.DstPort != packets.LDAPPort && udp.SrcPort != packets.LDAPPort {
+		return false
+	}
+	return adLDAP(ip, udp.Payload, udp.SrcPort == packets.LDAPPort)
+}
+
+func ldapTCPParser(ip *layers.IPv4, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if (tcp.DstPort != packets.LDAPPort && tcp.SrcPort != packets.LDAPPort) || len(tcp.Payload) == 0 {
+		return false
+	}
+	return adLDAP(ip, tcp.Payload, tcp.SrcPort == packets.LDAPPort)
+}
+
+func nbnsParser(ip *layers.IPv4, pkt gopacket.Packet, udp *layers.UDP) bool {
+	if udp.DstPort != packets.NBNSPort && udp.SrcPort != packets.NBNSPort {
+		return false
+	}
+	return adNBNS(udp.Payload)
+}
+
+// krb5TCPParser handles the KDC requests sent over TCP, which are prefixed
+// by their length.
+func krb5TCPParser(ip *layers.IPv4, pkt gopacket.Packet, tcp *layers.TCP) bool {
+	if tcp.DstPort != 88 || len(tcp.Payload) < 4 {
+		return false
+	} else if size := binary.BigEndian.Uint32(tcp.Payload); int(size) != len(tcp.Payload)-4 {
+		return false
+	}
+	return adKerberos(ip, tcp.Payload[4:])
+}
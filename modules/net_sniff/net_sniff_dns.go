@@ -1,9 +1,12 @@
 package net_sniff
 
 import (
+	"strings"
+
+	"github.com/bettercap/bettercap/session"
+
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
-	"strings"
 
 	"github.com/evilsocket/islazy/tui"
 )
@@ -18,7 +21,22 @@ func dnsParser(ip *layers.IPv4, pkt gopacket.Packet, udp *layers.UDP) bool {
 		return false
 	}
 
+	adDNS(dns)
+
+	if !dns.QR {
+		for _, q := range dns.Questions {
+			session.I.DNS.Add(ip.SrcIP.String(), string(q.Name), dns.ID, false)
+			session.I.Bus.Publish(session.TopicDNSQuery, "net.sniff", session.DNSQuery{
+				Client:   ip.SrcIP.String(),
+				Server:   ip.DstIP.String(),
+				Hostname: string(q.Name),
+			})
+		}
+	}
+
 	m := make(map[string][]string)
+	// the same without colors and geolocation for the bus
+	raw := make(map[string][]string)
 	answers := [][]layers.DNSResourceRecord{
 		dns.Answers,
 		dns.Authorities,
@@ -36,7 +54,8 @@ func dnsParser(ip *layers.IPv4, pkt gopacket.Packet, udp *layers.UDP) bool {
 				m[hostname] = make([]string, 0)
 			}
 
-			m[hostname] = append(m[hostname], vIP(a.IP))
+			m[hostname] = append(m[hostname], vIP(a.IP)+vGeo(a.IP))
+			raw[hostname] = append(raw[hostname], a.IP.String())
 		}
 	}
 
@@ -47,6 +66,17 @@ func dnsParser(ip *layers.IPv4, pkt gopacket.Packet, udp *layers.UDP) bool {
 	}
 
 	for hostname, ips := range m {
+		record := session.DNSQuery{
+			Client:   ip.DstIP.String(),
+			Server:   ip.SrcIP.String(),
+			Hostname: hostname,
+			Answers:  raw[hostname],
+		}
+		if len(record.Answers) == 0 {
+			record.Rcode = dns.ResponseCode.String()
+		}
+		session.I.Bus.Publish(session.TopicDNSQuery, "net.sniff", record)
+
 		NewSnifferEvent(
 			pkt.Metadata().Timestamp,
 			"dns",
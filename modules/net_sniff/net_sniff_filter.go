@@ -0,0 +1,165 @@
+package net_sniff
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const filterSnapLen = 65536
+
+var (
+	// libpcap quotes the names it can't resolve, like unknown host 'foo'
+	bpfQuotedRe = regexp.MustCompile(`'([^']+)'`)
+	bpfTokenRe  = regexp.MustCompile(`\S+`)
+)
+
+// packetBuffer keeps the last captured packets in order to preview which of
+// them a BPF filter would match.
+type packetBuffer struct {
+	sync.Mutex
+	linkType layers.LinkType
+	packets  []gopacket.Packet
+	next     int
+	full     bool
+}
+
+func newPacketBuffer() *packetBuffer {
+	return &packetBuffer{
+		linkType: layers.LinkTypeEthernet,
+		packets:  make([]gopacket.Packet, 0),
+	}
+}
+
+// Reset empties the buffer and makes it keep up to size packets of the given
+// link type.
+func (b *packetBuffer) Reset(size int, linkType layers.LinkType) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.linkType = linkType
+	b.packets = make([]gopacket.Packet, size)
+	b.next = 0
+	b.full = false
+}
+
+func (b *packetBuffer) Add(pkt gopacket.Packet) {
+	b.Lock()
+	defer b.Unlock()
+
+	if len(b.packets) == 0 {
+		return
+	}
+
+	b.packets[b.next] = pkt
+	if b.next = (b.next + 1) % len(b.packets); b.next == 0 {
+		b.full = true
+	}
+}
+
+// List returns the buffered packets, the oldest first, and their link type.
+func (b *packetBuffer) List() ([]gopacket.Packet, layers.LinkType) {
+	b.Lock()
+	defer b.Unlock()
+
+	if !b.full {
+		return append([]gopacket.Packet{}, b.packets[:b.next]...), b.linkType
+	}
+	return append(append([]gopacket.Packet{}, b.packets[b.next:]...), b.packets[:b.next]...), b.linkType
+}
+
+// bpfErrorPosition returns the offset of expr the compilation error is about:
+// the name libpcap quotes if any, otherwise the token following the longest
+// prefix of the expression that compiles.
+func bpfErrorPosition(linkType layers.LinkType, expr string, err error) int {
+	if m := bpfQuotedRe.FindStringSubmatch(err.Error()); m != nil {
+		if pos := strings.Index(expr, m[1]); pos >= 0 {
+			return pos
+		}
+	}
+
+	tokens := bpfTokenRe.FindAllStringIndex(expr, -1)
+	valid := -1
+	for i, tok := range tokens {
+		if _, err := pcap.CompileBPFFilter(linkType, filterSnapLen, expr[:tok[1]]); err == nil {
+			valid = i
+		}
+	}
+
+	if valid+1 < len(tokens) {
+		return tokens[valid+1][0]
+	}
+	return len(expr)
+}
+
+func unquoteFilter(expr string) string {
+	expr = strings.TrimSpace(expr)
+	if len(expr) >= 2 && (expr[0] == '"' || expr[0] == '\'') && expr[len(expr)-1] == expr[0] {
+		expr = expr[1 : len(expr)-1]
+	}
+	return expr
+}
+
+// testFilter compiles a BPF expression, showing where the error is if it
+// doesn't, and which of the buffered packets it would match if it does.
+func (mod *Sniffer) testFilter(expr string) error {
+	if expr = unquoteFilter(expr); expr == "" {
+		return fmt.Errorf("empty filter")
+	}
+
+	buffered, linkType := mod.buffer.List()
+
+	insns, err := pcap.CompileBPFFilter(linkType, filterSnapLen, expr)
+	if err != nil {
+		pos := bpfErrorPosition(linkType, expr, err)
+		mod.Session.Printf("\n  %s\n  %s%s\n\n", expr, strings.Repeat(" ", pos), tui.Red("^"))
+		return fmt.Errorf("invalid filter at offset %d: %v", pos, err)
+	}
+
+	mod.Session.Printf("\n'%s' compiles to %d instructions.\n\n", tui.Yellow(expr), len(insns))
+
+	if len(buffered) == 0 {
+		mod.Session.Printf("%s\n\n", tui.Dim("no packets buffered to preview the filter with, start net.sniff with net.sniff.filter.preview greater than 0."))
+		return nil
+	}
+
+	bpf, err := pcap.NewBPF(linkType, filterSnapLen, expr)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(buffered))
+	matched := 0
+	for i, pkt := range buffered {
+		match := no
+		if bpf.Matches(pkt.Metadata().CaptureInfo, pkt.Data()) {
+			match = yes
+			matched++
+		}
+
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", i+1),
+			pkt.Metadata().Timestamp.Format("15:04:05"),
+			match,
+			packets.Summarize(pkt.Data(), linkType.LayerType()),
+		})
+	}
+
+	mod.Session.Table([]string{"#", "Time", "Match", "Packet"}, rows)
+	mod.Session.Printf("\n%d of the last %d packets match", matched, len(buffered))
+	if mod.Ctx != nil && mod.Ctx.Filter != "" {
+		mod.Session.Printf(" %s", tui.Dim(fmt.Sprintf("(captured with the '%s' filter)", mod.Ctx.Filter)))
+	}
+	mod.Session.Printf(".\n\n")
+
+	return nil
+}
@@ -3,6 +3,8 @@ package net_sniff
 import (
 	"regexp"
 
+	"github.com/bettercap/bettercap/session"
+
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 
@@ -20,6 +22,18 @@ func ftpParser(ip *layers.IPv4, pkt gopacket.Packet, tcp *layers.TCP) bool {
 	if matches := ftpRe.FindAllStringSubmatch(data, -1); matches != nil {
 		what := str.Trim(matches[0][1])
 		cred := str.Trim(matches[0][2])
+		record := session.Credential{
+			Protocol: "ftp",
+			Client:   ip.SrcIP.String(),
+			Server:   ip.DstIP.String(),
+		}
+		if what == "USER" {
+			record.Username = cred
+		} else {
+			record.Password = cred
+		}
+		session.I.Bus.Publish(session.TopicCredential, "net.sniff", record)
+
 		NewSnifferEvent(
 			pkt.Metadata().Timestamp,
 			"ftp",
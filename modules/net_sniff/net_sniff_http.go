@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/bettercap/bettercap/session"
+
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 
@@ -45,6 +47,15 @@ func (r HTTPResponse) IsType(ctype string) bool {
 	return strings.Contains(r.ContentType, ctype)
 }
 
+// HTTPRecord is published on the http.request and http.response topics of
+// the session bus with either the request or the response.
+type HTTPRecord struct {
+	Client   string        `json:"client"`
+	Server   string        `json:"server"`
+	Request  *HTTPRequest  `json:"request,omitempty"`
+	Response *HTTPResponse `json:"response,omitempty"`
+}
+
 func toSerializableRequest(req *http.Request) HTTPRequest {
 	body := []byte(nil)
 	ctype := "?"
@@ -119,12 +130,19 @@ func toSerializableResponse(res *http.Response) HTTPResponse {
 func httpParser(ip *layers.IPv4, pkt gopacket.Packet, tcp *layers.TCP) bool {
 	data := tcp.Payload
 	if req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(data))); err == nil {
+		trackRequestCookies(ip, pkt, req)
+		sreq := toSerializableRequest(req)
+		session.I.Bus.Publish(session.TopicHTTPRequest, "net.sniff", HTTPRecord{
+			Client:  ip.SrcIP.String(),
+			Server:  ip.DstIP.String(),
+			Request: &sreq,
+		})
 		NewSnifferEvent(
 			pkt.Metadata().Timestamp,
 			"http.request",
 			ip.SrcIP.String(),
 			req.Host,
-			toSerializableRequest(req),
+			sreq,
 			"%s %s %s %s%s",
 			tui.Wrap(tui.BACKRED+tui.FOREBLACK, "http"),
 			vIP(ip.SrcIP),
@@ -135,7 +153,13 @@ func httpParser(ip *layers.IPv4, pkt gopacket.Packet, tcp *layers.TCP) bool {
 
 		return true
 	} else if res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil); err == nil {
+		trackResponseCookies(ip, pkt, res)
 		sres := toSerializableResponse(res)
+		session.I.Bus.Publish(session.TopicHTTPResponse, "net.sniff", HTTPRecord{
+			Client:   ip.DstIP.String(),
+			Server:   ip.SrcIP.String(),
+			Response: &sres,
+		})
 		NewSnifferEvent(
 			pkt.Metadata().Timestamp,
 			"http.response",
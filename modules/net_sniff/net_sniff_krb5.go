@@ -4,6 +4,7 @@ import (
 	"encoding/asn1"
 
 	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
@@ -11,18 +12,28 @@ import (
 	"github.com/evilsocket/islazy/tui"
 )
 
+var krb5ParserStats = newParser("krb5")
+
 func krb5Parser(ip *layers.IPv4, pkt gopacket.Packet, udp *layers.UDP) bool {
 	if udp.DstPort != 88 {
 		return false
 	}
 
+	found := adKerberos(ip, udp.Payload)
+
 	var req packets.Krb5Request
 	_, err := asn1.UnmarshalWithParams(udp.Payload, &req, packets.Krb5AsReqParam)
 	if err != nil {
-		return false
+		return found
 	}
 
 	if s, err := req.String(); err == nil {
+		session.I.Bus.Publish(session.TopicCredential, "net.sniff", session.Credential{
+			Protocol: "krb5",
+			Client:   ip.SrcIP.String(),
+			Server:   ip.DstIP.String(),
+			Hash:     s,
+		})
 		NewSnifferEvent(
 			pkt.Metadata().Timestamp,
 			"krb5",
@@ -39,5 +50,5 @@ func krb5Parser(ip *layers.IPv4, pkt gopacket.Packet, udp *layers.UDP) bool {
 		return true
 	}
 
-	return false
+	return found
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
@@ -47,6 +48,14 @@ func ntlmParser(ip *layers.IPv4, pkt gopacket.Packet, tcp *layers.TCP) bool {
 			} else if isResponse(line) {
 				ok = true
 				ntlm.AddClientResponse(tcp.Seq, tokens[2], func(data packets.NTLMChallengeResponseParsed) {
+					session.I.Bus.Publish(session.TopicCredential, "net.sniff", session.Credential{
+						Protocol: "ntlm",
+						Client:   ip.SrcIP.String(),
+						Server:   ip.DstIP.String(),
+						Domain:   data.Domain,
+						Username: data.User,
+						Hash:     data.LcString(),
+					})
 					NewSnifferEvent(
 						pkt.Metadata().Timestamp,
 						"ntlm.response",
@@ -0,0 +1,39 @@
+package net_sniff
+
+import (
+	"time"
+
+	"github.com/google/gopacket"
+)
+
+// the longest sleep between two checks of the module state while pacing
+const paceStep = 250 * time.Millisecond
+
+// pace waits until it's time to process the packet of an offline capture
+// according to net.sniff.source.speed, it returns false if the sniffer has
+// been stopped meanwhile.
+func (mod *Sniffer) pace(packet gopacket.Packet) bool {
+	if mod.Ctx.Speed <= 0 {
+		return true
+	}
+
+	captured := packet.Metadata().Timestamp
+	if mod.paceStart.IsZero() {
+		mod.paceStart = time.Now()
+		mod.paceFirst = captured
+		return true
+	}
+
+	elapsed := float64(captured.Sub(mod.paceFirst)) / mod.Ctx.Speed
+	at := mod.paceStart.Add(time.Duration(elapsed))
+	for mod.Running() {
+		wait := time.Until(at)
+		if wait <= 0 {
+			return true
+		} else if wait > paceStep {
+			wait = paceStep
+		}
+		time.Sleep(wait)
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package net_sniff
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/bettercap/bettercap/log"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// SnifferParser holds the counters of a protocol parser and whether it's
+// enabled, counters are updated atomically from the sniffer loop.
+type SnifferParser struct {
+	name     string
+	disabled int32
+	parsed   uint64
+	matches  uint64
+	errors   uint64
+}
+
+// ParserStats is a snapshot of the counters of a parser, errors are the
+// packets that made the parser panic.
+type ParserStats struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Parsed  uint64 `json:"parsed"`
+	Matches uint64 `json:"matches"`
+	Errors  uint64 `json:"errors"`
+}
+
+func (p *SnifferParser) Enabled() bool {
+	return atomic.LoadInt32(&p.disabled) == 0
+}
+
+func (p *SnifferParser) SetEnabled(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&p.disabled, 0)
+	} else {
+		atomic.StoreInt32(&p.disabled, 1)
+	}
+}
+
+func (p *SnifferParser) Stats() ParserStats {
+	return ParserStats{
+		Name:    p.name,
+		Enabled: p.Enabled(),
+		Parsed:  atomic.LoadUint64(&p.parsed),
+		Matches: atomic.LoadUint64(&p.matches),
+		Errors:  atomic.LoadUint64(&p.errors),
+	}
+}
+
+func (p *SnifferParser) reset() {
+	atomic.StoreUint64(&p.parsed, 0)
+	atomic.StoreUint64(&p.matches, 0)
+	atomic.StoreUint64(&p.errors, 0)
+}
+
+// run calls the parser if it's enabled, a malformed packet making it panic is
+// counted as an error instead of stopping the sniffer.
+func (p *SnifferParser) run(parse func() bool) (matched bool) {
+	if !p.Enabled() {
+		return false
+	}
+
+	atomic.AddUint64(&p.parsed, 1)
+	defer func() {
+		if err := recover(); err != nil {
+			atomic.AddUint64(&p.errors, 1)
+			log.Debug("%s parser error: %v", p.name, err)
+			matched = false
+		}
+	}()
+
+	if matched = parse(); matched {
+		atomic.AddUint64(&p.matches, 1)
+	}
+	return
+}
+
+// SnifferParsers is the list of the protocol parsers, in the order they are
+// tried.
+type SnifferParsers []*SnifferParser
+
+var Parsers = SnifferParsers{}
+
+func newParser(name string) *SnifferParser {
+	p := &SnifferParser{name: name}
+	Parsers = append(Parsers, p)
+	return p
+}
+
+func (ps SnifferParsers) Get(name string) (*SnifferParser, bool) {
+	for _, p := range ps {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+func (ps SnifferParsers) Names() []string {
+	names := make([]string, len(ps))
+	for i, p := range ps {
+		names[i] = p.name
+	}
+	return names
+}
+
+func (ps SnifferParsers) Stats() []ParserStats {
+	stats := make([]ParserStats, len(ps))
+	for i, p := range ps {
+		stats[i] = p.Stats()
+	}
+	return stats
+}
+
+func (ps SnifferParsers) Reset() {
+	for _, p := range ps {
+		p.reset()
+	}
+}
+
+// SetEnabled toggles a comma separated list of parsers, or all of them.
+func (ps SnifferParsers) SetEnabled(names string, enabled bool) error {
+	selected := make([]*SnifferParser, 0)
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name == "" {
+			continue
+		} else if name == "all" {
+			selected = append(selected, ps...)
+		} else if p, found := ps.Get(name); !found {
+			return fmt.Errorf("unknown parser '%s', valid parsers are %s", name, strings.Join(ps.Names(), ", "))
+		} else {
+			selected = append(selected, p)
+		}
+	}
+
+	for _, p := range selected {
+		p.SetEnabled(enabled)
+	}
+	return nil
+}
+
+func (mod *Sniffer) showParserStats() error {
+	rows := make([][]string, 0)
+	for _, s := range Parsers.Stats() {
+		name := s.Name
+		status := tui.Green("on")
+		if !s.Enabled {
+			name = tui.Dim(name)
+			status = tui.Dim("off")
+		}
+
+		errors := fmt.Sprintf("%d", s.Errors)
+		if s.Errors > 0 {
+			errors = tui.Red(errors)
+		}
+
+		rows = append(rows, []string{
+			name,
+			status,
+			fmt.Sprintf("%d", s.Parsed),
+			fmt.Sprintf("%d", s.Matches),
+			errors,
+		})
+	}
+
+	mod.Session.Println()
+	mod.Session.Table([]string{"Parser", "Status", "Parsed", "Matches", "Errors"}, rows)
+	mod.Session.Println()
+
+	return nil
+}
@@ -12,6 +12,11 @@ import (
 	"github.com/evilsocket/islazy/tui"
 )
 
+var (
+	pppoeParserStats = newParser("pppoe")
+	dot11ParserStats = newParser("802.11")
+)
+
 func onUNK(ip *layers.IPv4, pkt gopacket.Packet, verbose bool) {
 	if verbose {
 		NewSnifferEvent(
@@ -32,6 +37,11 @@ func onUNK(ip *layers.IPv4, pkt gopacket.Packet, verbose bool) {
 }
 
 func mainParser(pkt gopacket.Packet, verbose bool) bool {
+	// PPPoE discovery or PPP authentication?
+	if pppoeParserStats.run(func() bool { return pppoeParser(pkt, verbose) }) {
+		return true
+	}
+
 	// simple networking sniffing mode?
 	nlayer := pkt.NetworkLayer()
 	if nlayer != nil {
@@ -60,8 +70,10 @@ func mainParser(pkt gopacket.Packet, verbose bool) bool {
 		return true
 	} else if ok, radiotap, dot11 := packets.Dot11Parse(pkt); ok {
 		// are we sniffing in monitor mode?
-		onDOT11(radiotap, dot11, pkt, verbose)
-		return true
+		return dot11ParserStats.run(func() bool {
+			onDOT11(radiotap, dot11, pkt, verbose)
+			return true
+		})
 	}
 	return false
 }
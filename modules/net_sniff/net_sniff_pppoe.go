@@ -0,0 +1,165 @@
+package net_sniff
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// challenges whose response is never seen are dropped once there are this many
+const chapMaxChallenges = 1024
+
+var (
+	chapLock       = sync.Mutex{}
+	chapChallenges = make(map[string][]byte)
+)
+
+func chapKey(sessionId uint16, id uint8) string {
+	return fmt.Sprintf("%d:%d", sessionId, id)
+}
+
+func onPPPoEDiscovery(eth *layers.Ethernet, pppoe *layers.PPPoE, pkt gopacket.Packet, verbose bool) {
+	code, found := packets.PPPoECodeNames[pppoe.Code]
+	if !found {
+		code = fmt.Sprintf("0x%02x", uint8(pppoe.Code))
+	}
+
+	tags := packets.PPPoEParseTags(pppoe.Payload)
+	service := string(tags[packets.PPPoETagServiceName])
+	acName := string(tags[packets.PPPoETagACName])
+
+	// only offers and confirmations are interesting unless verbose
+	if !verbose && pppoe.Code != layers.PPPoECodePADO && pppoe.Code != layers.PPPoECodePADS {
+		return
+	}
+
+	extra := ""
+	if acName != "" {
+		extra += fmt.Sprintf(" ac=%s", tui.Yellow(acName))
+	}
+	if service != "" {
+		extra += fmt.Sprintf(" service=%s", tui.Yellow(service))
+	}
+	if pppoe.SessionId != 0 {
+		extra += fmt.Sprintf(" session=%s", tui.Bold(fmt.Sprintf("0x%04x", pppoe.SessionId)))
+	}
+
+	NewSnifferEvent(
+		pkt.Metadata().Timestamp,
+		"pppoe",
+		eth.SrcMAC.String(),
+		eth.DstMAC.String(),
+		SniffData{
+			"code":       code,
+			"session_id": pppoe.SessionId,
+			"ac_name":    acName,
+			"service":    service,
+		},
+		"%s %s > %s %s%s",
+		tui.Wrap(tui.BACKDARKGRAY+tui.FOREWHITE, "pppoe"),
+		eth.SrcMAC,
+		eth.DstMAC,
+		tui.Bold(code),
+		extra,
+	).Push()
+}
+
+func onPPPAuth(eth *layers.Ethernet, pppoe *layers.PPPoE, auth *packets.PPPAuth, pkt gopacket.Packet) {
+	proto := auth.Protocol()
+	key := chapKey(pppoe.SessionId, auth.ID)
+	what := ""
+	data := SniffData{
+		"session_id": pppoe.SessionId,
+		"id":         auth.ID,
+	}
+
+	switch {
+	case auth.Type == packets.PPPTypePAP && auth.Code == packets.PPPAuthRequest:
+		data["username"] = auth.Name
+		data["password"] = auth.Password
+		what = fmt.Sprintf("%s %s", tui.Bold(auth.Name), tui.Yellow(auth.Password))
+
+	case auth.Type == packets.PPPTypeCHAP && auth.Code == packets.PPPAuthRequest:
+		chapLock.Lock()
+		if _, found := chapChallenges[key]; !found && len(chapChallenges) >= chapMaxChallenges {
+			chapChallenges = make(map[string][]byte)
+		}
+		chapChallenges[key] = auth.Value
+		chapLock.Unlock()
+		// challenges are just stored until we see the response
+		return
+
+	case auth.Type == packets.PPPTypeCHAP && auth.Code == packets.PPPAuthResponse:
+		chapLock.Lock()
+		challenge, found := chapChallenges[key]
+		delete(chapChallenges, key)
+		chapLock.Unlock()
+
+		data["username"] = auth.Name
+		if !found {
+			what = fmt.Sprintf("%s (challenge not captured)", tui.Bold(auth.Name))
+		} else {
+			hash := packets.CHAPHash(auth.ID, challenge, auth.Value)
+			data["hash"] = hash
+			what = fmt.Sprintf("%s %s", tui.Bold(auth.Name), tui.Yellow(hash))
+		}
+
+	case auth.Code == packets.PPPAuthSuccess:
+		what = tui.Green("authentication succeeded")
+
+	case auth.Code == packets.PPPAuthFailure:
+		what = tui.Red("authentication failed")
+
+	default:
+		return
+	}
+
+	NewSnifferEvent(
+		pkt.Metadata().Timestamp,
+		proto,
+		eth.SrcMAC.String(),
+		eth.DstMAC.String(),
+		data,
+		"%s %s > %s session=0x%04x %s",
+		tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, proto),
+		eth.SrcMAC,
+		eth.DstMAC,
+		pppoe.SessionId,
+		what,
+	).Push()
+}
+
+func pppoeParser(pkt gopacket.Packet, verbose bool) bool {
+	leth := pkt.Layer(layers.LayerTypeEthernet)
+	lpppoe := pkt.Layer(layers.LayerTypePPPoE)
+	if leth == nil || lpppoe == nil {
+		return false
+	}
+
+	eth := leth.(*layers.Ethernet)
+	pppoe := lpppoe.(*layers.PPPoE)
+
+	if pppoe.Code != layers.PPPoECodeSession {
+		onPPPoEDiscovery(eth, pppoe, pkt, verbose)
+		return true
+	}
+
+	lppp := pkt.Layer(layers.LayerTypePPP)
+	if lppp == nil {
+		return false
+	}
+
+	ppp := lppp.(*layers.PPP)
+	if err, auth := packets.PPPParseAuth(ppp.PPPType, ppp.Payload); err == nil {
+		onPPPAuth(eth, pppoe, auth, pkt)
+		return true
+	}
+
+	return false
+}
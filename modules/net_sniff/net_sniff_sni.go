@@ -38,10 +38,11 @@ func sniParser(ip *layers.IPv4, pkt gopacket.Packet, tcp *layers.TCP) bool {
 		ip.SrcIP.String(),
 		domain,
 		nil,
-		"%s %s > %s",
+		"%s %s > %s%s",
 		tui.Wrap(tui.BACKYELLOW+tui.FOREWHITE, "sni"),
 		vIP(ip.SrcIP),
 		tui.Yellow("https://"+domain),
+		vGeo(ip.DstIP),
 	).Push()
 
 	return true
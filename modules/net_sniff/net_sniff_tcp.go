@@ -9,18 +9,23 @@ import (
 	"github.com/evilsocket/islazy/tui"
 )
 
-var tcpParsers = []func(*layers.IPv4, gopacket.Packet, *layers.TCP) bool{
-	sniParser,
-	ntlmParser,
-	httpParser,
-	ftpParser,
-	teamViewerParser,
+var tcpParsers = []struct {
+	*SnifferParser
+	parse func(*layers.IPv4, gopacket.Packet, *layers.TCP) bool
+}{
+	{newParser("sni"), sniParser},
+	{newParser("ntlm"), ntlmParser},
+	{newParser("http"), httpParser},
+	{newParser("ftp"), ftpParser},
+	{newParser("teamviewer"), teamViewerParser},
+	{krb5ParserStats, krb5TCPParser},
+	{ldapParserStats, ldapTCPParser},
 }
 
 func onTCP(ip *layers.IPv4, pkt gopacket.Packet, verbose bool) {
 	tcp := pkt.Layer(layers.LayerTypeTCP).(*layers.TCP)
 	for _, parser := range tcpParsers {
-		if parser(ip, pkt, tcp) {
+		if parser.run(func() bool { return parser.parse(ip, pkt, tcp) }) {
 			return
 		}
 	}
@@ -34,12 +39,14 @@ func onTCP(ip *layers.IPv4, pkt gopacket.Packet, verbose bool) {
 			SniffData{
 				"Size": len(ip.Payload),
 			},
-			"%s %s:%s > %s:%s %s",
+			"%s %s:%s%s > %s:%s%s %s",
 			tui.Wrap(tui.BACKLIGHTBLUE+tui.FOREBLACK, "tcp"),
 			vIP(ip.SrcIP),
 			vPort(tcp.SrcPort),
+			vGeo(ip.SrcIP),
 			vIP(ip.DstIP),
 			vPort(tcp.DstPort),
+			vGeo(ip.DstIP),
 			tui.Dim(fmt.Sprintf("%d bytes", len(ip.Payload))),
 		).Push()
 	}
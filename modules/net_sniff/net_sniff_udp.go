@@ -9,17 +9,22 @@ import (
 	"github.com/evilsocket/islazy/tui"
 )
 
-var udpParsers = []func(*layers.IPv4, gopacket.Packet, *layers.UDP) bool{
-	dnsParser,
-	mdnsParser,
-	krb5Parser,
-	upnpParser,
+var udpParsers = []struct {
+	*SnifferParser
+	parse func(*layers.IPv4, gopacket.Packet, *layers.UDP) bool
+}{
+	{newParser("dns"), dnsParser},
+	{newParser("mdns"), mdnsParser},
+	{krb5ParserStats, krb5Parser},
+	{ldapParserStats, ldapUDPParser},
+	{nbnsParserStats, nbnsParser},
+	{newParser("upnp"), upnpParser},
 }
 
 func onUDP(ip *layers.IPv4, pkt gopacket.Packet, verbose bool) {
 	udp := pkt.Layer(layers.LayerTypeUDP).(*layers.UDP)
 	for _, parser := range udpParsers {
-		if parser(ip, pkt, udp) {
+		if parser.run(func() bool { return parser.parse(ip, pkt, udp) }) {
 			return
 		}
 	}
@@ -33,12 +38,14 @@ func onUDP(ip *layers.IPv4, pkt gopacket.Packet, verbose bool) {
 			SniffData{
 				"Size": len(ip.Payload),
 			},
-			"%s %s:%s > %s:%s %s",
+			"%s %s:%s%s > %s:%s%s %s",
 			tui.Wrap(tui.BACKDARKGRAY+tui.FOREWHITE, "udp"),
 			vIP(ip.SrcIP),
 			vPort(udp.SrcPort),
+			vGeo(ip.SrcIP),
 			vIP(ip.DstIP),
 			vPort(udp.DstPort),
+			vGeo(ip.DstIP),
 			tui.Dim(fmt.Sprintf("%d bytes", len(ip.Payload))),
 		).Push()
 	}
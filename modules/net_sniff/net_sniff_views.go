@@ -20,7 +20,9 @@ func vIP(ip net.IP) string {
 	address := ip.String()
 	host := session.I.Lan.GetByIp(address)
 	if host != nil {
-		if host.Hostname != "" {
+		if host.Alias != "" {
+			return host.Alias
+		} else if host.Hostname != "" {
 			return host.Hostname
 		}
 	}
@@ -28,6 +30,15 @@ func vIP(ip net.IP) string {
 	return address
 }
 
+// vGeo returns the country and autonomous system of external addresses if
+// a geoip database has been loaded.
+func vGeo(ip net.IP) string {
+	if info := session.I.GeoIP.Lookup(ip); info != nil {
+		return " " + tui.Dim("["+info.String()+"]")
+	}
+	return ""
+}
+
 func vPort(p interface{}) string {
 	sp := fmt.Sprintf("%d", p)
 	if tcp, ok := p.(layers.TCPPort); ok {
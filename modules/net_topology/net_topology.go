@@ -0,0 +1,219 @@
+package net_topology
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+const topologyFilter = "ether dst 01:80:c2:00:00:00 or ether dst 01:00:0c:cc:cc:cc or ether dst 01:80:c2:00:00:0e or ether proto 0x88cc"
+
+type TopologyDiscovery struct {
+	session.SessionModule
+	handle        *pcap.Handle
+	root          *packets.STPBridgeID
+	waitGroup     *sync.WaitGroup
+	pktSourceChan chan gopacket.Packet
+}
+
+func NewTopologyDiscovery(s *session.Session) *TopologyDiscovery {
+	mod := &TopologyDiscovery{
+		SessionModule: session.NewSessionModule("net.topology", s),
+		waitGroup:     &sync.WaitGroup{},
+	}
+
+	mod.AddHandler(session.NewModuleHandler("net.topology on", "",
+		"Start passive STP, CDP and LLDP topology discovery.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.topology off", "",
+		"Stop passive topology discovery.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.topology.show", "",
+		"Show the switches, ports and VLANs discovered so far.",
+		func(args []string) error {
+			return mod.Show()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("net.topology.clear", "",
+		"Clear the topology model.",
+		func(args []string) error {
+			mod.root = nil
+			mod.Session.Topology.Clear()
+			return nil
+		}))
+
+	return mod
+}
+
+func (mod TopologyDiscovery) Name() string {
+	return "net.topology"
+}
+
+func (mod TopologyDiscovery) Description() string {
+	return "Passively parse spanning-tree BPDUs, CDP and LLDP frames to map switches, ports and VLANs."
+}
+
+func (mod TopologyDiscovery) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *TopologyDiscovery) Configure() error {
+	var err error
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted
+	} else if mod.handle, err = pcap.OpenLive(mod.Session.Interface.Name(), 65536, true, pcap.BlockForever); err != nil {
+		return err
+	} else if err = mod.handle.SetBPFFilter(topologyFilter); err != nil {
+		mod.handle.Close()
+		return err
+	}
+
+	return nil
+}
+
+func (mod *TopologyDiscovery) onSTP(eth *layers.Ethernet, bpdu *packets.STPBPDU) {
+	_, node := mod.Session.Topology.AddIfNew(eth.SrcMAC.String())
+
+	node.Lock()
+	node.AddProtocol("stp")
+	if bpdu.Type != packets.STPTypeTCN {
+		node.BridgeID = bpdu.BridgeID.String()
+		node.RootID = bpdu.RootID.String()
+		node.RootCost = bpdu.RootPathCost
+		if node.Port == "" {
+			node.Port = fmt.Sprintf("0x%04x", bpdu.PortID)
+		}
+	}
+	node.Unlock()
+
+	if bpdu.Type == packets.STPTypeTCN {
+		mod.Info("topology change notification from %s", eth.SrcMAC)
+		return
+	}
+
+	if mod.root == nil || bpdu.RootID.Less(*mod.root) {
+		root := bpdu.RootID
+		mod.root = &root
+		if mod.Session.Topology.SetRoot(root.String(), root.Address.String()) {
+			mod.Session.Events.Add("topology.root.changed", root.String())
+		}
+	}
+}
+
+func (mod *TopologyDiscovery) onCDP(eth *layers.Ethernet, cdp *layers.CiscoDiscoveryInfo) {
+	_, node := mod.Session.Topology.AddIfNew(eth.SrcMAC.String())
+
+	node.Lock()
+	defer node.Unlock()
+
+	node.AddProtocol("cdp")
+	node.Name = cdp.DeviceID
+	node.Platform = cdp.Platform
+	node.Port = cdp.PortID
+	node.NativeVLAN = cdp.NativeVLAN
+	node.AddVLAN(cdp.NativeVLAN)
+	for _, addr := range append(cdp.Addresses, cdp.MgmtAddresses...) {
+		node.AddAddress(addr.String())
+	}
+}
+
+func (mod *TopologyDiscovery) onLLDP(eth *layers.Ethernet, lldp *layers.LinkLayerDiscovery, info *layers.LinkLayerDiscoveryInfo) {
+	_, node := mod.Session.Topology.AddIfNew(eth.SrcMAC.String())
+
+	node.Lock()
+	defer node.Unlock()
+
+	node.AddProtocol("lldp")
+	node.Port = string(lldp.PortID.ID)
+	if lldp.PortID.Subtype == layers.LLDPPortIDSubtypeMACAddr && len(lldp.PortID.ID) == 6 {
+		node.Port = net.HardwareAddr(lldp.PortID.ID).String()
+	}
+
+	if info != nil {
+		if info.SysName != "" {
+			node.Name = info.SysName
+		}
+		if info.SysDescription != "" {
+			node.Platform = strings.Split(info.SysDescription, "\n")[0]
+		}
+		if info.PortDescription != "" {
+			node.Port = info.PortDescription
+		}
+		if len(info.MgmtAddress.Address) == 4 || len(info.MgmtAddress.Address) == 16 {
+			node.AddAddress(net.IP(info.MgmtAddress.Address).String())
+		}
+		if dot1, err := info.Decode8021(); err == nil {
+			node.NativeVLAN = dot1.PVID
+			node.AddVLAN(dot1.PVID)
+			for _, vlan := range dot1.VLANNames {
+				node.AddVLAN(vlan.ID)
+			}
+		}
+	}
+}
+
+func (mod *TopologyDiscovery) onPacket(pkt gopacket.Packet) {
+	leth := pkt.Layer(layers.LayerTypeEthernet)
+	if leth == nil {
+		return
+	}
+	eth := leth.(*layers.Ethernet)
+
+	if bpdu := packets.STPGetBPDU(pkt); bpdu != nil {
+		mod.onSTP(eth, bpdu)
+	} else if lcdp := pkt.Layer(layers.LayerTypeCiscoDiscoveryInfo); lcdp != nil {
+		mod.onCDP(eth, lcdp.(*layers.CiscoDiscoveryInfo))
+	} else if llldp := pkt.Layer(layers.LayerTypeLinkLayerDiscovery); llldp != nil {
+		var info *layers.LinkLayerDiscoveryInfo
+		if linfo := pkt.Layer(layers.LayerTypeLinkLayerDiscoveryInfo); linfo != nil {
+			info = linfo.(*layers.LinkLayerDiscoveryInfo)
+		}
+		mod.onLLDP(eth, llldp.(*layers.LinkLayerDiscovery), info)
+	}
+}
+
+func (mod *TopologyDiscovery) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.waitGroup.Add(1)
+		defer mod.waitGroup.Done()
+
+		mod.Info("listening for STP, CDP and LLDP frames on %s", mod.Session.Interface.Name())
+
+		src := gopacket.NewPacketSource(mod.handle, mod.handle.LinkType())
+		mod.pktSourceChan = src.Packets()
+		for packet := range mod.pktSourceChan {
+			if !mod.Running() {
+				break
+			}
+
+			mod.onPacket(packet)
+		}
+	})
+}
+
+func (mod *TopologyDiscovery) Stop() error {
+	return mod.SetRunning(false, func() {
+		mod.pktSourceChan <- nil
+		mod.handle.Close()
+		mod.waitGroup.Wait()
+	})
+}
@@ -0,0 +1,71 @@
+package net_topology
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func vlansString(vlans []uint16) string {
+	parts := make([]string, 0)
+	for _, vlan := range vlans {
+		parts = append(parts, fmt.Sprintf("%d", vlan))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (mod *TopologyDiscovery) getRow(node *network.TopologyNode, rootAddr string) []string {
+	node.Lock()
+	defer node.Unlock()
+
+	address := node.Address
+	if node.Address == rootAddr {
+		address = tui.Bold(tui.Green(address))
+	}
+
+	name := node.Name
+	if name == "" {
+		name = tui.Dim(node.Vendor)
+	}
+
+	return []string{
+		address,
+		name,
+		tui.Dim(node.Platform),
+		node.Port,
+		vlansString(node.VLANs),
+		strings.Join(node.Protocols, ","),
+		node.LastSeen.Format("15:04:05"),
+	}
+}
+
+func (mod *TopologyDiscovery) Show() error {
+	nodes := mod.Session.Topology.Nodes()
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Address < nodes[j].Address
+	})
+
+	rootID, rootAddr := mod.Session.Topology.Root()
+
+	rows := make([][]string, 0)
+	for _, node := range nodes {
+		rows = append(rows, mod.getRow(node, rootAddr))
+	}
+
+	if len(rows) > 0 {
+		colNames := []string{"MAC", "Name", "Platform", "Port", "VLANs", "Protocols", "Seen"}
+		mod.Session.Table(colNames, rows)
+	}
+
+	if rootID != "" {
+		mod.Session.Printf("\nroot bridge: %s\n\n", tui.Bold(rootID))
+	} else {
+		mod.Session.Printf("\nroot bridge: %s\n\n", tui.Dim("unknown"))
+	}
+
+	return nil
+}
@@ -0,0 +1,285 @@
+package phase
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// how often the entry condition of the next phase is checked
+const checkPeriod = time.Second
+
+// Phase is a step of the engagement, its caplet is loaded when the entry
+// condition is met, phases without a condition are only entered with
+// phase.next (or on start if it's the first one).
+type Phase struct {
+	Name      string
+	Caplet    string
+	Condition *Condition
+	Entered   time.Time
+}
+
+type PhaseEvent struct {
+	Name   string
+	Caplet string
+	Reason string
+}
+
+type PhaseManager struct {
+	session.SessionModule
+
+	sync.Mutex
+	phases  []*Phase
+	current int
+	started time.Time
+	seen    map[string]time.Time
+	quit    chan bool
+}
+
+func NewPhaseManager(s *session.Session) *PhaseManager {
+	mod := &PhaseManager{
+		SessionModule: session.NewSessionModule("phase", s),
+		phases:        make([]*Phase, 0),
+		current:       -1,
+		seen:          make(map[string]time.Time),
+	}
+
+	mod.InitState("phase")
+
+	mod.AddHandler(session.NewModuleHandler("phase on", "",
+		"Enter the first phase and start checking the entry condition of the next ones.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("phase off", "",
+		"Stop switching phases, the current one is kept.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("phase.add NAME CAPLET when CONDITION", `phase\.add\s+([^\s]+)\s+([^\s]+)(?:\s+when\s+(.+))?$`,
+		"Add a phase loading CAPLET, the optional CONDITION is one of hosts N, aps N, clients N, handshakes N, time DURATION (spent in the previous phase) or event TAG.",
+		func(args []string) error {
+			return mod.add(args[0], args[1], args[2])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("phase.show", "",
+		"Show the phases and their entry conditions.",
+		func(args []string) error {
+			return mod.Show()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("phase.next", "",
+		"Enter the next phase without waiting for its condition.",
+		func(args []string) error {
+			return mod.next()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("phase.clear", "",
+		"Remove every phase.",
+		func(args []string) error {
+			if mod.Running() {
+				return fmt.Errorf("stop the phase module first")
+			}
+			mod.Lock()
+			defer mod.Unlock()
+			mod.phases = make([]*Phase, 0)
+			mod.current = -1
+			mod.State.Store("phase", nil)
+			return nil
+		}))
+
+	return mod
+}
+
+func (mod *PhaseManager) Name() string {
+	return "phase"
+}
+
+func (mod *PhaseManager) Description() string {
+	return "Switch between engagement phases loading a different caplet when the entry condition of the next phase is met."
+}
+
+func (mod *PhaseManager) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *PhaseManager) add(name, caplet, when string) error {
+	var cond *Condition
+	if when != "" {
+		var err error
+		if cond, err = ParseCondition(when); err != nil {
+			return err
+		}
+	}
+
+	mod.Lock()
+	defer mod.Unlock()
+
+	for _, p := range mod.phases {
+		if p.Name == name {
+			return fmt.Errorf("phase %s already defined", name)
+		}
+	}
+
+	mod.phases = append(mod.phases, &Phase{
+		Name:      name,
+		Caplet:    caplet,
+		Condition: cond,
+	})
+	return nil
+}
+
+// since returns when the current phase was entered, or when the module was
+// started if no phase was entered yet, must be called with the lock held.
+func (mod *PhaseManager) since() time.Time {
+	if mod.current >= 0 {
+		return mod.phases[mod.current].Entered
+	}
+	return mod.started
+}
+
+// pending returns the next phase if its entry condition is met.
+func (mod *PhaseManager) pending() *Phase {
+	mod.Lock()
+	defer mod.Unlock()
+
+	next := mod.current + 1
+	if next >= len(mod.phases) {
+		return nil
+	}
+
+	p := mod.phases[next]
+	if p.Condition == nil {
+		// the first phase has nothing to wait for
+		if mod.current < 0 {
+			return p
+		}
+		return nil
+	}
+
+	if p.Condition.Met(mod.Session, mod.since(), mod.seen) {
+		return p
+	}
+	return nil
+}
+
+func (mod *PhaseManager) enter(p *Phase, reason string) error {
+	mod.Lock()
+	for i, o := range mod.phases {
+		if o == p {
+			mod.current = i
+		}
+	}
+	p.Entered = time.Now()
+	mod.Unlock()
+
+	mod.State.Store("phase", p.Name)
+	mod.Info("entering phase %s (%s)", tui.Bold(p.Name), reason)
+	mod.Session.Events.Add("phase.entered", PhaseEvent{
+		Name:   p.Name,
+		Caplet: p.Caplet,
+		Reason: reason,
+	})
+
+	// the caplet runs outside the lock as it might use the phase commands
+	if err := mod.Session.RunAs("include "+p.Caplet, mod.Name()); err != nil {
+		return fmt.Errorf("error loading %s for phase %s: %v", p.Caplet, p.Name, err)
+	}
+	return nil
+}
+
+func (mod *PhaseManager) next() error {
+	mod.Lock()
+	next := mod.current + 1
+	if next >= len(mod.phases) {
+		mod.Unlock()
+		return fmt.Errorf("no more phases")
+	}
+	p := mod.phases[next]
+	mod.Unlock()
+
+	return mod.enter(p, "manual")
+}
+
+func (mod *PhaseManager) check() {
+	if p := mod.pending(); p != nil {
+		reason := "start"
+		if p.Condition != nil {
+			reason = p.Condition.String()
+		}
+		if err := mod.enter(p, reason); err != nil {
+			mod.Error("%v", err)
+		}
+	}
+}
+
+func (mod *PhaseManager) Configure() error {
+	mod.Lock()
+	defer mod.Unlock()
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted
+	} else if len(mod.phases) == 0 {
+		return fmt.Errorf("no phases defined, use phase.add first")
+	}
+
+	mod.started = time.Now()
+	mod.seen = make(map[string]time.Time)
+	mod.quit = make(chan bool)
+	return nil
+}
+
+func (mod *PhaseManager) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		// events are only recorded here, Events.Add blocks until every
+		// listener received the event and entering a phase generates some
+		wake := make(chan bool, 1)
+		listener := mod.Session.Events.Listen()
+		go func() {
+			for e := range listener {
+				// the backlog is replayed from the most recent event
+				mod.Lock()
+				if e.Time.After(mod.seen[e.Tag]) {
+					mod.seen[e.Tag] = e.Time
+				}
+				mod.Unlock()
+				select {
+				case wake <- true:
+				default:
+				}
+			}
+		}()
+		defer mod.Session.Events.Unlisten(listener)
+
+		tick := time.NewTicker(checkPeriod)
+		defer tick.Stop()
+
+		mod.check()
+		for {
+			select {
+			case <-wake:
+				mod.check()
+			case <-tick.C:
+				mod.check()
+			case <-mod.quit:
+				return
+			}
+		}
+	})
+}
+
+func (mod *PhaseManager) Stop() error {
+	return mod.SetRunning(false, func() {
+		close(mod.quit)
+	})
+}
@@ -0,0 +1,104 @@
+package phase
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/session"
+)
+
+var conditionParser = regexp.MustCompile(`^(hosts|aps|clients|handshakes|time|event)\s+([^\s]+)$`)
+
+// Condition is the entry condition of a phase, either a threshold on the
+// session objects, the time spent in the previous phase or an event.
+type Condition struct {
+	Kind     string
+	Count    int
+	Duration time.Duration
+	Tag      string
+}
+
+// ParseCondition parses conditions such as "hosts 50", "handshakes 1",
+// "time 10m" or "event wifi.client.handshake".
+func ParseCondition(line string) (*Condition, error) {
+	line = strings.TrimSpace(line)
+	m := conditionParser.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("invalid condition '%s', valid conditions are hosts N, aps N, clients N, handshakes N, time DURATION and event TAG", line)
+	}
+
+	c := &Condition{Kind: m[1]}
+	switch c.Kind {
+	case "time":
+		if d, err := time.ParseDuration(m[2]); err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid duration '%s'", m[2])
+		} else {
+			c.Duration = d
+		}
+	case "event":
+		c.Tag = m[2]
+	default:
+		if n, err := strconv.Atoi(m[2]); err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid count '%s'", m[2])
+		} else {
+			c.Count = n
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Condition) String() string {
+	switch c.Kind {
+	case "time":
+		return fmt.Sprintf("time %s", c.Duration)
+	case "event":
+		return fmt.Sprintf("event %s", c.Tag)
+	}
+	return fmt.Sprintf("%s %d", c.Kind, c.Count)
+}
+
+// count returns the current value of the threshold conditions.
+func (c *Condition) count(s *session.Session) int {
+	switch c.Kind {
+	case "hosts":
+		return len(s.Lan.List())
+	case "aps":
+		return len(s.WiFi.List())
+	case "clients":
+		return len(s.WiFi.Stations())
+	case "handshakes":
+		return s.WiFi.NumHandshakes()
+	}
+	return 0
+}
+
+// Progress returns a short description of how far the condition is from
+// being met given when the current phase was entered.
+func (c *Condition) Progress(s *session.Session, since time.Time) string {
+	switch c.Kind {
+	case "time":
+		return fmt.Sprintf("%s/%s", time.Since(since).Truncate(time.Second), c.Duration)
+	case "event":
+		return "waiting"
+	}
+	return fmt.Sprintf("%d/%d", c.count(s), c.Count)
+}
+
+// Met returns true if the condition is satisfied, seen holds the last time
+// each event tag was received.
+func (c *Condition) Met(s *session.Session, since time.Time, seen map[string]time.Time) bool {
+	switch c.Kind {
+	case "time":
+		return time.Since(since) >= c.Duration
+	case "event":
+		// the events backlog is replayed to new listeners, only the events
+		// that happened during the current phase count
+		at, found := seen[c.Tag]
+		return found && !at.Before(since)
+	}
+	return c.count(s) >= c.Count
+}
@@ -0,0 +1,59 @@
+package phase
+
+import (
+	"fmt"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func (mod *PhaseManager) Show() error {
+	mod.Lock()
+	defer mod.Unlock()
+
+	if len(mod.phases) == 0 {
+		return fmt.Errorf("no phases defined, use phase.add first")
+	}
+
+	rows := make([][]string, 0)
+	for i, p := range mod.phases {
+		name := p.Name
+		cond := tui.Dim("manual")
+		status := ""
+		entered := ""
+
+		if p.Condition != nil {
+			cond = p.Condition.String()
+		} else if i == 0 {
+			cond = tui.Dim("start")
+		}
+
+		if !p.Entered.IsZero() {
+			entered = p.Entered.Format("15:04:05")
+		}
+
+		if i == mod.current {
+			name = tui.Bold(tui.Green(name))
+			status = tui.Green("current")
+		} else if i < mod.current {
+			name = tui.Dim(name)
+			status = tui.Dim("done")
+		} else if i == mod.current+1 && p.Condition != nil && mod.Running() {
+			status = tui.Yellow(p.Condition.Progress(mod.Session, mod.since()))
+		}
+
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", i+1),
+			name,
+			p.Caplet,
+			cond,
+			status,
+			entered,
+		})
+	}
+
+	mod.Session.Println()
+	mod.Session.Table([]string{"#", "Phase", "Caplet", "When", "Status", "Entered"}, rows)
+	mod.Session.Println()
+
+	return nil
+}
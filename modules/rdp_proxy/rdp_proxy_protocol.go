@@ -0,0 +1,213 @@
+package rdp_proxy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// security protocols of the RDP negotiation, see MS-RDPBCGR 2.2.1.1.1
+const (
+	protocolRDP      = 0x00000000
+	protocolSSL      = 0x00000001
+	protocolHybrid   = 0x00000002
+	protocolRDSTLS   = 0x00000004
+	protocolHybridEx = 0x00000008
+
+	negTypeRequest  = 0x01
+	negTypeResponse = 0x02
+	negTypeFailure  = 0x03
+
+	x224ConnectionRequest = 0xe0
+	x224ConnectionConfirm = 0xd0
+
+	secInfoPacket = 0x0040
+	infoUnicode   = 0x00000010
+
+	mcsSendDataRequest = 25
+)
+
+var protocolNames = []struct {
+	flag uint32
+	name string
+}{
+	{protocolSSL, "TLS"},
+	{protocolHybrid, "CredSSP"},
+	{protocolRDSTLS, "RDSTLS"},
+	{protocolHybridEx, "CredSSP-EX"},
+}
+
+var negFailures = map[uint32]string{
+	1: "SSL_REQUIRED_BY_SERVER",
+	2: "SSL_NOT_ALLOWED_BY_SERVER",
+	3: "SSL_CERT_NOT_ON_SERVER",
+	4: "INCONSISTENT_FLAGS",
+	5: "HYBRID_REQUIRED_BY_SERVER",
+	6: "SSL_WITH_USER_AUTH_REQUIRED_BY_SERVER",
+}
+
+func protocolsString(protocols uint32) string {
+	names := []string{}
+	for _, p := range protocolNames {
+		if protocols&p.flag != 0 {
+			names = append(names, p.name)
+		}
+	}
+	if len(names) == 0 {
+		return "RDP"
+	}
+	return strings.Join(names, ",")
+}
+
+// readTPKT reads a whole TPKT (RFC 1006) packet.
+func readTPKT(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	} else if header[0] != 0x03 {
+		return nil, fmt.Errorf("not a TPKT packet (version %d)", header[0])
+	}
+
+	size := int(binary.BigEndian.Uint16(header[2:]))
+	if size < 4 {
+		return nil, fmt.Errorf("invalid TPKT size %d", size)
+	}
+
+	packet := make([]byte, size)
+	copy(packet, header)
+	if _, err := io.ReadFull(r, packet[4:]); err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+// x224Negotiation is an X.224 connection request or confirm with its optional
+// RDP negotiation structure, which is always at the end of the packet.
+type x224Negotiation struct {
+	raw     []byte
+	cookie  string
+	negType byte
+	// requested or selected protocols, or failure code
+	value uint32
+}
+
+func parseX224(packet []byte, code byte) (error, *x224Negotiation) {
+	if len(packet) < 11 {
+		return fmt.Errorf("X.224 packet too short (%d bytes)", len(packet)), nil
+	} else if packet[5]&0xf0 != code {
+		return fmt.Errorf("unexpected X.224 code 0x%x", packet[5]), nil
+	} else if end := 5 + int(packet[4]); end != len(packet) {
+		return fmt.Errorf("invalid X.224 length indicator %d", packet[4]), nil
+	}
+
+	x := &x224Negotiation{raw: packet}
+	variable := packet[11:]
+	if n := len(variable); n >= 8 && variable[n-8] >= negTypeRequest && variable[n-8] <= negTypeFailure &&
+		binary.LittleEndian.Uint16(variable[n-6:]) == 8 {
+		x.negType = variable[n-8]
+		x.value = binary.LittleEndian.Uint32(variable[n-4:])
+		variable = variable[:n-8]
+	}
+
+	if idx := strings.Index(string(variable), "\r\n"); idx > 0 {
+		x.cookie = string(variable[:idx])
+	}
+
+	return nil, x
+}
+
+// user returns the user name from the mstshash cookie, if any.
+func (x *x224Negotiation) user() string {
+	if strings.HasPrefix(x.cookie, "Cookie: mstshash=") {
+		return strings.TrimPrefix(x.cookie, "Cookie: mstshash=")
+	}
+	return ""
+}
+
+// withProtocols returns a copy of the connection request asking for different protocols.
+func (x *x224Negotiation) withProtocols(protocols uint32) []byte {
+	packet := make([]byte, len(x.raw))
+	copy(packet, x.raw)
+	binary.LittleEndian.PutUint32(packet[len(packet)-4:], protocols)
+	return packet
+}
+
+func decodeUTF16(b []byte) string {
+	chars := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		chars = append(chars, binary.LittleEndian.Uint16(b[i:]))
+	}
+	return strings.TrimRight(string(utf16.Decode(chars)), "\x00")
+}
+
+// clientInfo is the relevant part of a Client Info PDU, see MS-RDPBCGR 2.2.1.11.1.1
+type clientInfo struct {
+	Domain   string
+	Username string
+	Password string
+}
+
+func perLength(data []byte) (int, int) {
+	if len(data) < 1 {
+		return -1, 0
+	} else if data[0]&0x80 == 0 {
+		return int(data[0]), 1
+	} else if len(data) < 2 {
+		return -1, 0
+	}
+	return int(data[0]&0x7f)<<8 | int(data[1]), 2
+}
+
+// parseClientInfo checks if a TPKT packet sent by the client is a Client Info
+// PDU and returns its credentials.
+func parseClientInfo(packet []byte) *clientInfo {
+	// TPKT header, X.224 data header, MCS send data request with initiator, channel and flags
+	if len(packet) < 15 || packet[4] != 0x02 || packet[5] != 0xf0 || packet[7]>>2 != mcsSendDataRequest {
+		return nil
+	}
+
+	size, n := perLength(packet[13:])
+	if size < 0 {
+		return nil
+	}
+
+	data := packet[13+n:]
+	if len(data) < size || size < 4+18 {
+		return nil
+	} else if binary.LittleEndian.Uint16(data)&secInfoPacket == 0 {
+		return nil
+	}
+
+	info := data[4:size]
+	flags := binary.LittleEndian.Uint32(info[4:])
+	cbDomain := int(binary.LittleEndian.Uint16(info[8:]))
+	cbUser := int(binary.LittleEndian.Uint16(info[10:]))
+	cbPassword := int(binary.LittleEndian.Uint16(info[12:]))
+
+	// every field is followed by a mandatory null terminator
+	null := 1
+	decode := func(b []byte) string { return strings.TrimRight(string(b), "\x00") }
+	if flags&infoUnicode != 0 {
+		null = 2
+		decode = decodeUTF16
+	}
+
+	fields := info[18:]
+	if len(fields) < cbDomain+cbUser+cbPassword+3*null {
+		return nil
+	}
+
+	domain := fields[:cbDomain]
+	fields = fields[cbDomain+null:]
+	user := fields[:cbUser]
+	fields = fields[cbUser+null:]
+	password := fields[:cbPassword]
+
+	return &clientInfo{
+		Domain:   decode(domain),
+		Username: decode(user),
+		Password: decode(password),
+	}
+}
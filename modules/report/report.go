@@ -0,0 +1,159 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bettercap/bettercap/loot"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+type ReportModule struct {
+	session.SessionModule
+}
+
+func NewReportModule(s *session.Session) *ReportModule {
+	mod := &ReportModule{
+		SessionModule: session.NewSessionModule("report", s),
+	}
+
+	mod.AddParam(session.NewStringParameter("report.title",
+		"bettercap engagement report",
+		"",
+		"Title of the report."))
+
+	mod.AddParam(session.NewIntParameter("report.timeline.max",
+		"500",
+		"Maximum number of events in the timeline of the report, 0 for no limit."))
+
+	mod.AddHandler(session.NewModuleHandler("report.generate TEMPLATE OUTPUT", `report\.generate\s+([^\s]+)\s+([^\s]+)`,
+		"Render a report of the session to OUTPUT using the markdown or html built-in templates or a custom template file.",
+		func(args []string) error {
+			return mod.generate(args[0], args[1])
+		}))
+
+	return mod
+}
+
+func (mod *ReportModule) Name() string {
+	return "report"
+}
+
+func (mod *ReportModule) Description() string {
+	return "A module to generate Markdown and HTML reports of the hosts, networks, credentials and events of the session."
+}
+
+func (mod *ReportModule) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *ReportModule) Configure() error {
+	return nil
+}
+
+func (mod *ReportModule) Start() error {
+	return nil
+}
+
+func (mod *ReportModule) Stop() error {
+	return nil
+}
+
+// loadTemplate returns the source of the template and whether it must be escaped as HTML.
+func loadTemplate(name string) (error, string, bool) {
+	switch strings.ToLower(name) {
+	case "markdown", "md":
+		return nil, markdownTemplate, false
+	case "html":
+		return nil, htmlTemplate, true
+	}
+
+	fileName, err := fs.Expand(name)
+	if err != nil {
+		return err, "", false
+	}
+
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("could not load template %s: %v", fileName, err), "", false
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	return nil, string(raw), ext == ".html" || ext == ".htm"
+}
+
+func render(source string, isHTML bool, data *reportData) (error, []byte) {
+	buf := bytes.Buffer{}
+	funcs := map[string]interface{}{
+		"date": func(t time.Time) string {
+			return t.Format("2006-01-02 15:04:05")
+		},
+		"md": escapeMarkdown,
+	}
+
+	if isHTML {
+		tpl, err := htmltemplate.New("report").Funcs(funcs).Parse(source)
+		if err != nil {
+			return err, nil
+		} else if err = tpl.Execute(&buf, data); err != nil {
+			return err, nil
+		}
+	} else {
+		tpl, err := template.New("report").Funcs(funcs).Parse(source)
+		if err != nil {
+			return err, nil
+		} else if err = tpl.Execute(&buf, data); err != nil {
+			return err, nil
+		}
+	}
+
+	return nil, buf.Bytes()
+}
+
+func (mod *ReportModule) generate(templateName, output string) error {
+	err, source, isHTML := loadTemplate(templateName)
+	if err != nil {
+		return err
+	}
+
+	err, title := mod.StringParam("report.title")
+	if err != nil {
+		return err
+	}
+
+	err, maxEvents := mod.IntParam("report.timeline.max")
+	if err != nil {
+		return err
+	}
+
+	data := collect(mod.Session, title, maxEvents)
+	err, raw := render(source, isHTML, data)
+	if err != nil {
+		return fmt.Errorf("could not render template %s: %v", templateName, err)
+	}
+
+	fileName, err := fs.Expand(output)
+	if err != nil {
+		return err
+	} else if fileName, err = loot.WriteFile(fileName, raw, 0644); err != nil {
+		return err
+	}
+
+	mod.Info("report with %d hosts, %d wifi networks, %d credentials and %d events saved to %s",
+		len(data.Hosts),
+		len(data.WiFi),
+		len(data.Credentials),
+		len(data.Timeline),
+		tui.Bold(fileName))
+
+	return nil
+}
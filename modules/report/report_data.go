@@ -0,0 +1,205 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/log"
+)
+
+var (
+	reANSI = regexp.MustCompile("\033\\[(?:[0-9]{1,3}(?:;[0-9]{1,3})*)?[m|K]")
+
+	// events with captured credentials or hashes
+	credentialTags = map[string]bool{
+		"net.sniff.ftp":           true,
+		"net.sniff.cookie":        true,
+		"net.sniff.ntlm.response": true,
+		"net.sniff.krb5":          true,
+		"rdp.proxy.ntlm":          true,
+	}
+)
+
+type reportHost struct {
+	IP       string
+	IPv6     string
+	MAC      string
+	Hostname string
+	Alias    string
+	Vendor   string
+	Ports    string
+	First    time.Time
+	Last     time.Time
+}
+
+type reportAP struct {
+	ESSID      string
+	BSSID      string
+	Channel    int
+	Encryption string
+	Vendor     string
+	Clients    int
+	Handshakes int
+	PMKID      bool
+}
+
+type reportEvent struct {
+	Time        time.Time
+	Tag         string
+	Description string
+}
+
+type reportData struct {
+	Title       string
+	Generated   time.Time
+	Started     time.Time
+	Interface   *network.Endpoint
+	Gateway     *network.Endpoint
+	Hosts       []reportHost
+	WiFi        []reportAP
+	Credentials []reportEvent
+	Handshakes  []reportEvent
+	Timeline    []reportEvent
+}
+
+func stripANSI(s string) string {
+	return reANSI.ReplaceAllString(s, "")
+}
+
+func escapeMarkdown(s string) string {
+	s = strings.Replace(s, "|", "\\|", -1)
+	return strings.Replace(s, "\n", " ", -1)
+}
+
+// describe turns the payload of any event into a single line of plain text.
+func describe(e session.Event) string {
+	switch data := e.Data.(type) {
+	case nil:
+		return ""
+	case string:
+		return stripANSI(data)
+	case session.LogMessage:
+		return fmt.Sprintf("[%s] %s", log.LevelName(data.Level), stripANSI(data.Message))
+	case fmt.Stringer:
+		return stripANSI(data.String())
+	}
+
+	// net.sniff events carry an already formatted message
+	if raw, err := json.Marshal(e.Data); err == nil {
+		obj := make(map[string]interface{})
+		if json.Unmarshal(raw, &obj) == nil {
+			if msg, ok := obj["message"].(string); ok && msg != "" {
+				return stripANSI(msg)
+			}
+		}
+		return string(raw)
+	}
+
+	return fmt.Sprintf("%v", e.Data)
+}
+
+func hostPorts(e *network.Endpoint) string {
+	// syn.scan stores the open ports as a comma separated list
+	if ports, ok := e.Meta.Get("tcp-ports").(string); ok {
+		return strings.Replace(ports, ",", ", ", -1)
+	}
+	return ""
+}
+
+func collectHosts(s *session.Session) []reportHost {
+	endpoints := s.Lan.List()
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].IpAddressUint32 < endpoints[j].IpAddressUint32
+	})
+
+	hosts := make([]reportHost, 0)
+	for _, e := range endpoints {
+		hosts = append(hosts, reportHost{
+			IP:       e.IpAddress,
+			IPv6:     e.Ip6Address,
+			MAC:      e.HwAddress,
+			Hostname: e.Hostname,
+			Alias:    e.Alias,
+			Vendor:   e.Vendor,
+			Ports:    hostPorts(e),
+			First:    e.FirstSeen,
+			Last:     e.LastSeen,
+		})
+	}
+
+	return hosts
+}
+
+func collectWiFi(s *session.Session) []reportAP {
+	aps := make([]reportAP, 0)
+	for _, ap := range s.WiFi.List() {
+		aps = append(aps, reportAP{
+			ESSID:      ap.ESSID(),
+			BSSID:      ap.BSSID(),
+			Channel:    ap.Channel,
+			Encryption: strings.TrimSpace(fmt.Sprintf("%s %s %s", ap.Encryption, ap.Cipher, ap.Authentication)),
+			Vendor:     ap.Vendor,
+			Clients:    ap.NumClients(),
+			Handshakes: ap.NumHandshakes(),
+			PMKID:      ap.HasPMKID(),
+		})
+	}
+
+	sort.Slice(aps, func(i, j int) bool {
+		return aps[i].ESSID < aps[j].ESSID
+	})
+
+	return aps
+}
+
+func collect(s *session.Session, title string, maxEvents int) *reportData {
+	data := &reportData{
+		Title:       title,
+		Generated:   time.Now(),
+		Started:     s.StartedAt,
+		Interface:   s.Interface,
+		Gateway:     s.Gateway,
+		Hosts:       collectHosts(s),
+		WiFi:        collectWiFi(s),
+		Credentials: make([]reportEvent, 0),
+		Handshakes:  make([]reportEvent, 0),
+		Timeline:    make([]reportEvent, 0),
+	}
+
+	events := append([]session.Event{}, s.Events.Sorted()...)
+	for _, e := range events {
+		ev := reportEvent{
+			Time:        e.Time,
+			Tag:         e.Tag,
+			Description: describe(e),
+		}
+
+		if credentialTags[e.Tag] || strings.HasSuffix(e.Tag, ".credentials") {
+			data.Credentials = append(data.Credentials, ev)
+		} else if strings.HasPrefix(e.Tag, "wifi.client.handshake") {
+			data.Handshakes = append(data.Handshakes, ev)
+		}
+
+		if e.Tag == "sys.log" {
+			// only warnings and errors make it to the timeline
+			if msg, ok := e.Data.(session.LogMessage); !ok || msg.Level < log.WARNING {
+				continue
+			}
+		}
+
+		data.Timeline = append(data.Timeline, ev)
+	}
+
+	if maxEvents > 0 && len(data.Timeline) > maxEvents {
+		data.Timeline = data.Timeline[len(data.Timeline)-maxEvents:]
+	}
+
+	return data
+}
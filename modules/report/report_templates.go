@@ -0,0 +1,105 @@
+package report
+
+const markdownTemplate = `# {{.Title}}
+
+Generated on {{date .Generated}}, session started on {{date .Started}}.
+
+| Interface | IP | MAC | Gateway |
+|-----------|----|-----|---------|
+| {{.Interface.Name}} | {{.Interface.IpAddress}} | {{.Interface.HwAddress}} | {{if .Gateway}}{{.Gateway.IpAddress}} ({{.Gateway.HwAddress}}){{end}} |
+
+## Hosts ({{len .Hosts}})
+{{if .Hosts}}
+| IP | MAC | Name | Vendor | Open Ports | First Seen | Last Seen |
+|----|-----|------|--------|------------|------------|-----------|
+{{range .Hosts}}| {{.IP}} | {{.MAC}} | {{md .Hostname}}{{if .Alias}} ({{md .Alias}}){{end}} | {{md .Vendor}} | {{.Ports}} | {{date .First}} | {{date .Last}} |
+{{end}}{{else}}
+No hosts discovered.
+{{end}}
+## WiFi Networks ({{len .WiFi}})
+{{if .WiFi}}
+| SSID | BSSID | Channel | Encryption | Vendor | Clients | Handshakes | PMKID |
+|------|-------|---------|------------|--------|---------|------------|-------|
+{{range .WiFi}}| {{md .ESSID}} | {{.BSSID}} | {{.Channel}} | {{.Encryption}} | {{md .Vendor}} | {{.Clients}} | {{.Handshakes}} | {{if .PMKID}}yes{{else}}no{{end}} |
+{{end}}{{else}}
+No wifi networks discovered.
+{{end}}
+## Credentials ({{len .Credentials}})
+{{if .Credentials}}
+| Time | Source | Details |
+|------|--------|---------|
+{{range .Credentials}}| {{date .Time}} | {{.Tag}} | {{md .Description}} |
+{{end}}{{else}}
+No credentials captured.
+{{end}}
+## Handshakes ({{len .Handshakes}})
+{{if .Handshakes}}
+| Time | Event | Details |
+|------|-------|---------|
+{{range .Handshakes}}| {{date .Time}} | {{.Tag}} | {{md .Description}} |
+{{end}}{{else}}
+No handshakes captured.
+{{end}}
+## Timeline ({{len .Timeline}})
+{{if .Timeline}}
+| Time | Event | Details |
+|------|-------|---------|
+{{range .Timeline}}| {{date .Time}} | {{.Tag}} | {{md .Description}} |
+{{end}}{{else}}
+No events.
+{{end}}`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; font-size: 0.9em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #eee; }
+td.details { font-family: monospace; word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated on {{date .Generated}}, session started on {{date .Started}}.</p>
+
+<table>
+<tr><th>Interface</th><th>IP</th><th>MAC</th><th>Gateway</th></tr>
+<tr><td>{{.Interface.Name}}</td><td>{{.Interface.IpAddress}}</td><td>{{.Interface.HwAddress}}</td><td>{{if .Gateway}}{{.Gateway.IpAddress}} ({{.Gateway.HwAddress}}){{end}}</td></tr>
+</table>
+
+<h2>Hosts ({{len .Hosts}})</h2>
+{{if .Hosts}}<table>
+<tr><th>IP</th><th>MAC</th><th>Name</th><th>Vendor</th><th>Open Ports</th><th>First Seen</th><th>Last Seen</th></tr>
+{{range .Hosts}}<tr><td>{{.IP}}</td><td>{{.MAC}}</td><td>{{.Hostname}}{{if .Alias}} ({{.Alias}}){{end}}</td><td>{{.Vendor}}</td><td>{{.Ports}}</td><td>{{date .First}}</td><td>{{date .Last}}</td></tr>
+{{end}}</table>{{else}}<p>No hosts discovered.</p>{{end}}
+
+<h2>WiFi Networks ({{len .WiFi}})</h2>
+{{if .WiFi}}<table>
+<tr><th>SSID</th><th>BSSID</th><th>Channel</th><th>Encryption</th><th>Vendor</th><th>Clients</th><th>Handshakes</th><th>PMKID</th></tr>
+{{range .WiFi}}<tr><td>{{.ESSID}}</td><td>{{.BSSID}}</td><td>{{.Channel}}</td><td>{{.Encryption}}</td><td>{{.Vendor}}</td><td>{{.Clients}}</td><td>{{.Handshakes}}</td><td>{{if .PMKID}}yes{{else}}no{{end}}</td></tr>
+{{end}}</table>{{else}}<p>No wifi networks discovered.</p>{{end}}
+
+<h2>Credentials ({{len .Credentials}})</h2>
+{{if .Credentials}}<table>
+<tr><th>Time</th><th>Source</th><th>Details</th></tr>
+{{range .Credentials}}<tr><td>{{date .Time}}</td><td>{{.Tag}}</td><td class="details">{{.Description}}</td></tr>
+{{end}}</table>{{else}}<p>No credentials captured.</p>{{end}}
+
+<h2>Handshakes ({{len .Handshakes}})</h2>
+{{if .Handshakes}}<table>
+<tr><th>Time</th><th>Event</th><th>Details</th></tr>
+{{range .Handshakes}}<tr><td>{{date .Time}}</td><td>{{.Tag}}</td><td class="details">{{.Description}}</td></tr>
+{{end}}</table>{{else}}<p>No handshakes captured.</p>{{end}}
+
+<h2>Timeline ({{len .Timeline}})</h2>
+{{if .Timeline}}<table>
+<tr><th>Time</th><th>Event</th><th>Details</th></tr>
+{{range .Timeline}}<tr><td>{{date .Time}}</td><td>{{.Tag}}</td><td class="details">{{.Description}}</td></tr>
+{{end}}</table>{{else}}<p>No events.</p>{{end}}
+</body>
+</html>
+`
@@ -0,0 +1,331 @@
+package sdr
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/modules/utils"
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/str"
+)
+
+type SDRRecon struct {
+	session.SessionModule
+	source     string
+	binary     string
+	args       []string
+	udpAddress string
+	devTTL     time.Duration
+	cmd        *exec.Cmd
+	conn       *net.UDPConn
+	waitGroup  *sync.WaitGroup
+	selector   *utils.ViewSelector
+}
+
+func NewSDRRecon(s *session.Session) *SDRRecon {
+	mod := &SDRRecon{
+		SessionModule: session.NewSessionModule("sdr", s),
+		waitGroup:     &sync.WaitGroup{},
+	}
+
+	mod.AddParam(session.NewStringParameter("sdr.source",
+		"exec",
+		"^(exec|udp)$",
+		"Use exec to run rtl_433 as a subprocess, or udp to receive the messages of an already running instance started with -F syslog:HOST:PORT."))
+
+	mod.AddParam(session.NewStringParameter("sdr.rtl433.path",
+		"rtl_433",
+		"",
+		"Path of the rtl_433 executable."))
+
+	mod.AddParam(session.NewStringParameter("sdr.device",
+		"0",
+		"",
+		"rtl_433 device selector (index, :serial or a SoapySDR string)."))
+
+	mod.AddParam(session.NewStringParameter("sdr.frequency",
+		"433.92M",
+		"",
+		"Comma separated list of frequencies to listen on, rtl_433 will hop between them if more than one is given."))
+
+	mod.AddParam(session.NewIntParameter("sdr.hop.period",
+		"600",
+		"Seconds to stay on each frequency when more than one is set."))
+
+	mod.AddParam(session.NewStringParameter("sdr.gain",
+		"",
+		"",
+		"Tuner gain in dB, empty for automatic gain."))
+
+	mod.AddParam(session.NewStringParameter("sdr.protocols",
+		"",
+		"",
+		"Comma separated list of rtl_433 protocol numbers to enable, empty for the default ones."))
+
+	mod.AddParam(session.NewStringParameter("sdr.args",
+		"",
+		"",
+		"Additional command line arguments for rtl_433."))
+
+	mod.AddParam(session.NewStringParameter("sdr.udp.address",
+		"127.0.0.1:1433",
+		"",
+		"Address to listen on for rtl_433 syslog messages when sdr.source is udp."))
+
+	mod.AddParam(session.NewIntParameter("sdr.ttl",
+		"3600",
+		"Seconds of silence for a device to be pruned."))
+
+	mod.AddHandler(session.NewModuleHandler("sdr.recon on", "",
+		"Start receiving and decoding sub-GHz ISM transmissions.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("sdr.recon off", "",
+		"Stop receiving sub-GHz ISM transmissions.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("sdr.show", "",
+		"Show the sub-GHz devices detected so far and their last readings.",
+		func(args []string) error {
+			return mod.Show()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("sdr.clear", "",
+		"Clear all devices collected by the SDR module.",
+		func(args []string) error {
+			mod.Session.SDR.Clear()
+			return nil
+		}))
+
+	mod.selector = utils.ViewSelectorFor(&mod.SessionModule, "sdr.show", []string{"model", "messages", "seen"}, "seen desc")
+
+	return mod
+}
+
+func (mod *SDRRecon) Name() string {
+	return "sdr"
+}
+
+func (mod *SDRRecon) Description() string {
+	return "Receive and decode sub-GHz ISM traffic (weather stations, remotes, tire pressure sensors, ...) using rtl_433 compatible hardware."
+}
+
+func (mod *SDRRecon) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *SDRRecon) buildArgs() (err error) {
+	var device, freqs, gain, protocols, extra string
+	var hop int
+
+	if err, device = mod.StringParam("sdr.device"); err != nil {
+		return err
+	} else if err, freqs = mod.StringParam("sdr.frequency"); err != nil {
+		return err
+	} else if err, hop = mod.IntParam("sdr.hop.period"); err != nil {
+		return err
+	} else if err, gain = mod.StringParam("sdr.gain"); err != nil {
+		return err
+	} else if err, protocols = mod.StringParam("sdr.protocols"); err != nil {
+		return err
+	} else if err, extra = mod.StringParam("sdr.args"); err != nil {
+		return err
+	}
+
+	mod.args = []string{"-F", "json", "-M", "level"}
+	if device != "" {
+		mod.args = append(mod.args, "-d", device)
+	}
+
+	frequencies := str.Comma(freqs)
+	for _, freq := range frequencies {
+		mod.args = append(mod.args, "-f", freq)
+	}
+	if len(frequencies) > 1 {
+		mod.args = append(mod.args, "-H", fmt.Sprintf("%d", hop))
+	}
+
+	if gain != "" {
+		mod.args = append(mod.args, "-g", gain)
+	}
+
+	for _, proto := range str.Comma(protocols) {
+		mod.args = append(mod.args, "-R", proto)
+	}
+
+	if extra != "" {
+		mod.args = append(mod.args, strings.Fields(extra)...)
+	}
+
+	return nil
+}
+
+func (mod *SDRRecon) Configure() (err error) {
+	var ttl int
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted
+	} else if err, mod.source = mod.StringParam("sdr.source"); err != nil {
+		return err
+	} else if err, ttl = mod.IntParam("sdr.ttl"); err != nil {
+		return err
+	}
+
+	mod.devTTL = time.Duration(ttl) * time.Second
+
+	if mod.source == "udp" {
+		var addr *net.UDPAddr
+		if err, mod.udpAddress = mod.StringParam("sdr.udp.address"); err != nil {
+			return err
+		} else if addr, err = net.ResolveUDPAddr("udp", mod.udpAddress); err != nil {
+			return err
+		} else if mod.conn, err = net.ListenUDP("udp", addr); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	if err, mod.binary = mod.StringParam("sdr.rtl433.path"); err != nil {
+		return err
+	} else if mod.binary, err = exec.LookPath(mod.binary); err != nil {
+		return fmt.Errorf("rtl_433 not found, install it or set sdr.rtl433.path: %v", err)
+	}
+
+	return mod.buildArgs()
+}
+
+func (mod *SDRRecon) onLine(line string) {
+	if line = strings.TrimSpace(line); line == "" {
+		return
+	}
+
+	err, fields := network.ParseRTL433(line)
+	if err != nil {
+		mod.Debug("%s", line)
+		return
+	}
+
+	_, dev := mod.Session.SDR.AddIfNew(fields)
+	mod.Session.Events.Add("sdr.message", dev)
+}
+
+func (mod *SDRRecon) runProcess() error {
+	mod.cmd = exec.Command(mod.binary, mod.args...)
+
+	stdout, err := mod.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := mod.cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	mod.Debug("running %s %s", mod.binary, strings.Join(mod.args, " "))
+
+	if err = mod.cmd.Start(); err != nil {
+		return err
+	}
+
+	go mod.readLines(stderr, func(line string) {
+		mod.Debug("%s", line)
+	})
+	mod.readLines(stdout, mod.onLine)
+
+	if err = mod.cmd.Wait(); err != nil && mod.Running() {
+		return fmt.Errorf("rtl_433 exited: %v (use debug mode for details)", err)
+	}
+	return nil
+}
+
+func (mod *SDRRecon) readLines(r io.Reader, cb func(line string)) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		cb(scanner.Text())
+	}
+}
+
+func (mod *SDRRecon) runListener() {
+	buf := make([]byte, 4096)
+	for mod.Running() {
+		mod.conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := mod.conn.ReadFromUDP(buf)
+		if err != nil {
+			if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+				if mod.Running() {
+					mod.Warning("error reading from %s: %v", mod.udpAddress, err)
+				}
+			}
+			continue
+		}
+		mod.onLine(string(buf[:n]))
+	}
+}
+
+func (mod *SDRRecon) devPruner() {
+	mod.waitGroup.Add(1)
+	defer mod.waitGroup.Done()
+
+	for mod.Running() {
+		for _, dev := range mod.Session.SDR.Devices() {
+			dev.Lock()
+			sinceLastSeen := time.Since(dev.LastSeen)
+			dev.Unlock()
+			if sinceLastSeen > mod.devTTL {
+				mod.Debug("device %s not seen in %s, removing.", dev.Key, sinceLastSeen)
+				mod.Session.SDR.Remove(dev.Key)
+			}
+		}
+		time.Sleep(30 * time.Second)
+	}
+}
+
+func (mod *SDRRecon) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.waitGroup.Add(1)
+		defer mod.waitGroup.Done()
+
+		go mod.devPruner()
+
+		if mod.source == "udp" {
+			mod.Info("waiting for rtl_433 messages on %s", mod.udpAddress)
+			mod.runListener()
+		} else {
+			mod.Info("started rtl_433")
+			if err := mod.runProcess(); err != nil {
+				mod.Error("%v", err)
+				go mod.Stop()
+			}
+		}
+	})
+}
+
+func (mod *SDRRecon) Stop() error {
+	return mod.SetRunning(false, func() {
+		if mod.cmd != nil && mod.cmd.Process != nil {
+			mod.cmd.Process.Kill()
+		}
+		if mod.conn != nil {
+			mod.conn.Close()
+		}
+		mod.waitGroup.Wait()
+		mod.cmd = nil
+		mod.conn = nil
+	})
+}
@@ -0,0 +1,141 @@
+package sdr
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+var (
+	PresentTimeInterval    = time.Duration(5) * time.Minute
+	JustJoinedTimeInterval = time.Duration(10) * time.Second
+)
+
+func (mod *SDRRecon) getRow(dev *network.SDRDevice) []string {
+	readings := dev.ReadingsString()
+
+	dev.Lock()
+	defer dev.Unlock()
+
+	sinceLastSeen := time.Since(dev.LastSeen)
+	seen := dev.LastSeen.Format("15:04:05")
+	if sinceLastSeen <= JustJoinedTimeInterval {
+		seen = tui.Bold(seen)
+	} else if sinceLastSeen > PresentTimeInterval {
+		seen = tui.Dim(seen)
+	}
+
+	freq := ""
+	if dev.Frequency != 0 {
+		freq = fmt.Sprintf("%.3f MHz", dev.Frequency)
+	}
+
+	rssi := ""
+	if dev.RSSI != 0 {
+		rssi = fmt.Sprintf("%.1f dB", dev.RSSI)
+	}
+
+	return []string{
+		tui.Yellow(dev.Model),
+		dev.ID,
+		dev.Channel,
+		freq,
+		rssi,
+		readings,
+		fmt.Sprintf("%d", dev.Messages),
+		seen,
+	}
+}
+
+func (mod *SDRRecon) doFilter(dev *network.SDRDevice) bool {
+	if mod.selector.Expression == nil {
+		return true
+	}
+	return mod.selector.Expression.MatchString(dev.Key)
+}
+
+func (mod *SDRRecon) doSelection() (err error, devices []*network.SDRDevice) {
+	if err = mod.selector.Update(); err != nil {
+		return
+	}
+
+	filtered := []*network.SDRDevice{}
+	for _, dev := range mod.Session.SDR.Devices() {
+		if mod.doFilter(dev) {
+			filtered = append(filtered, dev)
+		}
+	}
+	devices = filtered
+
+	switch mod.selector.SortField {
+	case "model":
+		sort.Slice(devices, func(i, j int) bool {
+			return devices[i].Key < devices[j].Key
+		})
+	case "messages":
+		sort.Slice(devices, func(i, j int) bool {
+			return devices[i].Messages < devices[j].Messages
+		})
+	case "seen":
+		sort.Slice(devices, func(i, j int) bool {
+			return devices[i].LastSeen.Before(devices[j].LastSeen)
+		})
+	}
+
+	// default is asc
+	if mod.selector.Sort == "desc" {
+		// from https://github.com/golang/go/wiki/SliceTricks
+		for i := len(devices)/2 - 1; i >= 0; i-- {
+			opp := len(devices) - 1 - i
+			devices[i], devices[opp] = devices[opp], devices[i]
+		}
+	}
+
+	if mod.selector.Limit > 0 {
+		limit := mod.selector.Limit
+		max := len(devices)
+		if limit > max {
+			limit = max
+		}
+		devices = devices[0:limit]
+	}
+
+	return
+}
+
+func (mod *SDRRecon) colNames() []string {
+	colNames := []string{"Model", "ID", "Channel", "Frequency", "RSSI", "Readings", "Messages", "Seen"}
+	switch mod.selector.SortField {
+	case "model":
+		colNames[0] += " " + mod.selector.SortSymbol
+	case "messages":
+		colNames[6] += " " + mod.selector.SortSymbol
+	case "seen":
+		colNames[7] += " " + mod.selector.SortSymbol
+	}
+	return colNames
+}
+
+func (mod *SDRRecon) Show() (err error) {
+	var devices []*network.SDRDevice
+	if err, devices = mod.doSelection(); err != nil {
+		return
+	}
+
+	rows := make([][]string, 0)
+	for _, dev := range devices {
+		rows = append(rows, mod.getRow(dev))
+	}
+
+	mod.Session.Table(mod.colNames(), rows)
+
+	if len(rows) > 0 {
+		mod.Session.Refresh()
+	}
+
+	return nil
+}
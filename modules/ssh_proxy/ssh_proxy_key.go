@@ -0,0 +1,57 @@
+package ssh_proxy
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/evilsocket/islazy/fs"
+)
+
+// loadHostKey loads the ed25519 host key from a PEM file, generating it if missing.
+func loadHostKey(fileName string) (error, ed25519.PrivateKey, bool) {
+	if !fs.Exists(fileName) {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err, nil, false
+		}
+
+		raw, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			return err, nil, false
+		}
+
+		data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: raw})
+		if err := ioutil.WriteFile(fileName, data, os.FileMode(0600)); err != nil {
+			return err, nil, false
+		}
+
+		return nil, priv, true
+	}
+
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err, nil, false
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("%s is not a PEM file", fileName), nil, false
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return err, nil, false
+	}
+
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return fmt.Errorf("%s is not an ed25519 private key", fileName), nil, false
+	}
+
+	return nil, priv, false
+}
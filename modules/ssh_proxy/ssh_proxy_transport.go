@@ -0,0 +1,539 @@
+package ssh_proxy
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+const maxPacketSize = 256 * 1024
+
+var (
+	kexAlgos        = []string{"curve25519-sha256", "curve25519-sha256@libssh.org"}
+	serverHostAlgos = []string{"ssh-ed25519"}
+	// we don't verify the host key of the real server, anything goes
+	clientHostAlgos = []string{"ssh-ed25519", "ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384", "ecdsa-sha2-nistp521", "rsa-sha2-512", "rsa-sha2-256", "ssh-rsa"}
+	cipherAlgos     = []string{"aes128-ctr", "aes192-ctr", "aes256-ctr"}
+	macAlgos        = []string{"hmac-sha2-256", "hmac-sha2-512", "hmac-sha1"}
+	compAlgos       = []string{"none"}
+)
+
+var cipherKeySizes = map[string]int{
+	"aes128-ctr": 16,
+	"aes192-ctr": 24,
+	"aes256-ctr": 32,
+}
+
+var macs = map[string]struct {
+	size int
+	new  func() hash.Hash
+}{
+	"hmac-sha2-256": {32, sha256.New},
+	"hmac-sha2-512": {64, sha512.New},
+	"hmac-sha1":     {20, sha1.New},
+}
+
+type direction struct {
+	stream    cipher.Stream
+	mac       hash.Hash
+	macSize   int
+	blockSize int
+	seq       uint32
+}
+
+type algorithms struct {
+	kex     string
+	host    string
+	cipherC string
+	cipherS string
+	macC    string
+	macS    string
+}
+
+// transport implements the SSH transport layer (RFC 4253) for both the
+// server side (the victim connecting to us) and the client side (us
+// connecting to the real server).
+type transport struct {
+	sync.Mutex
+	conn          net.Conn
+	reader        *bufio.Reader
+	isServer      bool
+	hostKey       ed25519.PrivateKey
+	localVersion  string
+	remoteVersion string
+	sessionID     []byte
+	in            direction
+	out           direction
+	// KEXINIT we sent when the other side started a key exchange we didn't initiate
+	pendingKexInit []byte
+}
+
+func newTransport(conn net.Conn, isServer bool, version string, hostKey ed25519.PrivateKey) *transport {
+	return &transport{
+		conn:         conn,
+		reader:       bufio.NewReader(conn),
+		isServer:     isServer,
+		hostKey:      hostKey,
+		localVersion: version,
+		in:           direction{blockSize: 8},
+		out:          direction{blockSize: 8},
+	}
+}
+
+func (t *transport) exchangeVersions() error {
+	if _, err := fmt.Fprintf(t.conn, "%s\r\n", t.localVersion); err != nil {
+		return err
+	}
+
+	// the server can send other lines before its version string
+	for i := 0; i < 32; i++ {
+		line, err := t.reader.ReadString('\n')
+		if err != nil {
+			return err
+		} else if len(line) > 255 {
+			return fmt.Errorf("version line too long")
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "SSH-") {
+			if !strings.HasPrefix(line, "SSH-2.0-") && !strings.HasPrefix(line, "SSH-1.99-") {
+				return fmt.Errorf("unsupported protocol version %s", line)
+			}
+			t.remoteVersion = line
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no SSH version string received")
+}
+
+func (t *transport) readPacket() ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(t.reader, header); err != nil {
+		return nil, err
+	}
+	if t.in.stream != nil {
+		t.in.stream.XORKeyStream(header, header)
+	}
+
+	size := binary.BigEndian.Uint32(header)
+	if size < 5 || size > maxPacketSize || (size+4)%uint32(t.in.blockSize) != 0 {
+		return nil, fmt.Errorf("invalid packet size %d", size)
+	}
+
+	packet := make([]byte, 4+size+uint32(t.in.macSize))
+	copy(packet, header)
+	if _, err := io.ReadFull(t.reader, packet[4:]); err != nil {
+		return nil, err
+	}
+
+	body := packet[4 : 4+size]
+	if t.in.stream != nil {
+		t.in.stream.XORKeyStream(body, body)
+	}
+
+	if t.in.mac != nil {
+		t.in.mac.Reset()
+		binary.Write(t.in.mac, binary.BigEndian, t.in.seq)
+		t.in.mac.Write(packet[:4+size])
+		if !hmac.Equal(t.in.mac.Sum(nil)[:t.in.macSize], packet[4+size:]) {
+			return nil, fmt.Errorf("MAC verification failed")
+		}
+	}
+	t.in.seq++
+
+	padding := uint32(body[0])
+	if padding+1 > size {
+		return nil, fmt.Errorf("invalid padding size %d", padding)
+	}
+
+	return body[1 : size-padding], nil
+}
+
+func (t *transport) writePacketLocked(payload []byte) error {
+	padding := t.out.blockSize - (5+len(payload))%t.out.blockSize
+	if padding < 4 {
+		padding += t.out.blockSize
+	}
+
+	size := 1 + len(payload) + padding
+	packet := make([]byte, 4+size, 4+size+t.out.macSize)
+	binary.BigEndian.PutUint32(packet, uint32(size))
+	packet[4] = byte(padding)
+	copy(packet[5:], payload)
+	if _, err := rand.Read(packet[5+len(payload):]); err != nil {
+		return err
+	}
+
+	if t.out.mac != nil {
+		t.out.mac.Reset()
+		binary.Write(t.out.mac, binary.BigEndian, t.out.seq)
+		t.out.mac.Write(packet)
+	}
+	t.out.seq++
+
+	if t.out.stream != nil {
+		t.out.stream.XORKeyStream(packet, packet)
+	}
+	if t.out.mac != nil {
+		packet = append(packet, t.out.mac.Sum(nil)[:t.out.macSize]...)
+	}
+
+	_, err := t.conn.Write(packet)
+	return err
+}
+
+func (t *transport) writePacket(payload []byte) error {
+	t.Lock()
+	defer t.Unlock()
+	return t.writePacketLocked(payload)
+}
+
+// readMessage returns the next message which is not part of the transport layer,
+// handling key re-exchanges started by the other side.
+func (t *transport) readMessage() ([]byte, error) {
+	for {
+		payload, err := t.readPacket()
+		if err != nil {
+			return nil, err
+		}
+
+		switch payload[0] {
+		case msgIgnore, msgDebug, msgUnimplemented:
+			continue
+		case msgDisconnect:
+			r := newReader(payload[1:])
+			r.uint32()
+			return nil, fmt.Errorf("disconnected: %s", r.string())
+		case msgKexInit:
+			t.Lock()
+			err = t.keyExchange(payload)
+			t.Unlock()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		return payload, nil
+	}
+}
+
+func (t *transport) readExpected(msgType byte) ([]byte, error) {
+	payload, err := t.readMessage()
+	if err != nil {
+		return nil, err
+	} else if payload[0] != msgType {
+		return nil, fmt.Errorf("unexpected message %d (expected %d)", payload[0], msgType)
+	}
+	return payload, nil
+}
+
+func (t *transport) disconnect(reason uint32, message string) {
+	t.writePacket(newMessage(msgDisconnect).uint32(reason).string(message).string("").Bytes())
+	t.conn.Close()
+}
+
+func (t *transport) kexInit() []byte {
+	hostAlgos := clientHostAlgos
+	if t.isServer {
+		hostAlgos = serverHostAlgos
+	}
+
+	cookie := make([]byte, 16)
+	rand.Read(cookie)
+
+	msg := newMessage(msgKexInit)
+	msg.Write(cookie)
+	msg.nameList(kexAlgos).
+		nameList(hostAlgos).
+		nameList(cipherAlgos).
+		nameList(cipherAlgos).
+		nameList(macAlgos).
+		nameList(macAlgos).
+		nameList(compAlgos).
+		nameList(compAlgos).
+		nameList(nil).
+		nameList(nil).
+		bool(false).
+		uint32(0)
+	return msg.Bytes()
+}
+
+func negotiate(what string, client, server []string) (string, error) {
+	for _, c := range client {
+		for _, s := range server {
+			if c == s {
+				return c, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no common %s algorithm (client: %s, server: %s)", what, strings.Join(client, ","), strings.Join(server, ","))
+}
+
+func parseKexInit(payload []byte) ([][]string, error) {
+	r := newReader(payload[17:])
+	lists := make([][]string, 10)
+	for i := range lists {
+		lists[i] = r.nameList()
+	}
+	return lists, r.err
+}
+
+func (t *transport) negotiate(clientInit, serverInit []byte) (*algorithms, error) {
+	if len(clientInit) < 17 || len(serverInit) < 17 {
+		return nil, fmt.Errorf("KEXINIT too short")
+	}
+
+	c, err := parseKexInit(clientInit)
+	if err != nil {
+		return nil, err
+	}
+	s, err := parseKexInit(serverInit)
+	if err != nil {
+		return nil, err
+	}
+
+	algos := &algorithms{}
+	if algos.kex, err = negotiate("kex", c[0], s[0]); err != nil {
+		return nil, err
+	} else if algos.host, err = negotiate("host key", c[1], s[1]); err != nil {
+		return nil, err
+	} else if algos.cipherC, err = negotiate("cipher", c[2], s[2]); err != nil {
+		return nil, err
+	} else if algos.cipherS, err = negotiate("cipher", c[3], s[3]); err != nil {
+		return nil, err
+	} else if algos.macC, err = negotiate("mac", c[4], s[4]); err != nil {
+		return nil, err
+	} else if algos.macS, err = negotiate("mac", c[5], s[5]); err != nil {
+		return nil, err
+	} else if _, err = negotiate("compression", c[6], s[6]); err != nil {
+		return nil, err
+	} else if _, err = negotiate("compression", c[7], s[7]); err != nil {
+		return nil, err
+	}
+	return algos, nil
+}
+
+func hostKeyBlob(key ed25519.PublicKey) []byte {
+	w := &sshWriter{}
+	w.string("ssh-ed25519").bytes(key)
+	return w.Bytes()
+}
+
+func exchangeHash(vc, vs string, ic, is, ks, qc, qs, k []byte) []byte {
+	w := &sshWriter{}
+	w.string(vc).string(vs).bytes(ic).bytes(is).bytes(ks).bytes(qc).bytes(qs).mpint(k)
+	h := sha256.Sum256(w.Bytes())
+	return h[:]
+}
+
+func deriveKey(k, h []byte, letter byte, sessionID []byte, size int) []byte {
+	kw := &sshWriter{}
+	kw.mpint(k)
+
+	digest := sha256.New()
+	digest.Write(kw.Bytes())
+	digest.Write(h)
+	digest.Write([]byte{letter})
+	digest.Write(sessionID)
+	key := digest.Sum(nil)
+
+	for len(key) < size {
+		digest.Reset()
+		digest.Write(kw.Bytes())
+		digest.Write(h)
+		digest.Write(key)
+		key = digest.Sum(key)
+	}
+	return key[:size]
+}
+
+func newDirection(k, h, sessionID []byte, ivLetter byte, cipherName, macName string) (direction, error) {
+	keySize := cipherKeySizes[cipherName]
+	mac := macs[macName]
+
+	iv := deriveKey(k, h, ivLetter, sessionID, aes.BlockSize)
+	key := deriveKey(k, h, ivLetter+2, sessionID, keySize)
+	macKey := deriveKey(k, h, ivLetter+4, sessionID, mac.size)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return direction{}, err
+	}
+
+	return direction{
+		stream:    cipher.NewCTR(block, iv),
+		mac:       hmac.New(mac.new, macKey),
+		macSize:   mac.size,
+		blockSize: aes.BlockSize,
+	}, nil
+}
+
+// keyExchange performs a curve25519 key exchange, if remoteInit is not nil the
+// other side started it and we already received its KEXINIT. Must be called
+// with the transport lock held so that no other message is sent meanwhile.
+func (t *transport) keyExchange(remoteInit []byte) error {
+	localInit := t.kexInit()
+	if err := t.writePacketLocked(localInit); err != nil {
+		return err
+	}
+
+	if remoteInit == nil {
+		payload, err := t.readPacket()
+		if err != nil {
+			return err
+		} else if payload[0] != msgKexInit {
+			return fmt.Errorf("expected KEXINIT, got message %d", payload[0])
+		}
+		remoteInit = payload
+	}
+
+	clientInit, serverInit := localInit, remoteInit
+	clientVersion, serverVersion := t.localVersion, t.remoteVersion
+	if t.isServer {
+		clientInit, serverInit = remoteInit, localInit
+		clientVersion, serverVersion = t.remoteVersion, t.localVersion
+	}
+
+	algos, err := t.negotiate(clientInit, serverInit)
+	if err != nil {
+		return err
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	var h, k []byte
+	if t.isServer {
+		payload, err := t.readPacket()
+		if err != nil {
+			return err
+		} else if payload[0] != msgKexECDHInit {
+			return fmt.Errorf("expected KEX_ECDH_INIT, got message %d", payload[0])
+		}
+
+		r := newReader(payload[1:])
+		qc := r.bytes()
+		if r.err != nil {
+			return r.err
+		}
+
+		peer, err := ecdh.X25519().NewPublicKey(qc)
+		if err != nil {
+			return err
+		} else if k, err = ephemeral.ECDH(peer); err != nil {
+			return err
+		}
+
+		ks := hostKeyBlob(t.hostKey.Public().(ed25519.PublicKey))
+		qs := ephemeral.PublicKey().Bytes()
+		h = exchangeHash(clientVersion, serverVersion, clientInit, serverInit, ks, qc, qs, k)
+
+		sig := &sshWriter{}
+		sig.string("ssh-ed25519").bytes(ed25519.Sign(t.hostKey, h))
+
+		reply := newMessage(msgKexECDHReply).bytes(ks).bytes(qs).bytes(sig.Bytes())
+		if err := t.writePacketLocked(reply.Bytes()); err != nil {
+			return err
+		}
+	} else {
+		qc := ephemeral.PublicKey().Bytes()
+		if err := t.writePacketLocked(newMessage(msgKexECDHInit).bytes(qc).Bytes()); err != nil {
+			return err
+		}
+
+		payload, err := t.readPacket()
+		if err != nil {
+			return err
+		} else if payload[0] != msgKexECDHReply {
+			return fmt.Errorf("expected KEX_ECDH_REPLY, got message %d", payload[0])
+		}
+
+		r := newReader(payload[1:])
+		ks := r.bytes()
+		qs := r.bytes()
+		r.bytes()
+		if r.err != nil {
+			return r.err
+		}
+
+		peer, err := ecdh.X25519().NewPublicKey(qs)
+		if err != nil {
+			return err
+		} else if k, err = ephemeral.ECDH(peer); err != nil {
+			return err
+		}
+
+		h = exchangeHash(clientVersion, serverVersion, clientInit, serverInit, ks, qc, qs, k)
+	}
+
+	if t.sessionID == nil {
+		t.sessionID = h
+	}
+
+	// client to server keys use the A, C and E letters, server to client ones B, D and F
+	c2s, err := newDirection(k, h, t.sessionID, 'A', algos.cipherC, algos.macC)
+	if err != nil {
+		return err
+	}
+	s2c, err := newDirection(k, h, t.sessionID, 'B', algos.cipherS, algos.macS)
+	if err != nil {
+		return err
+	}
+
+	if err := t.writePacketLocked([]byte{msgNewKeys}); err != nil {
+		return err
+	}
+
+	outSeq, inSeq := t.out.seq, t.in.seq
+	if t.isServer {
+		t.out = s2c
+	} else {
+		t.out = c2s
+	}
+	t.out.seq = outSeq
+
+	if payload, err := t.readPacket(); err != nil {
+		return err
+	} else if payload[0] != msgNewKeys {
+		return fmt.Errorf("expected NEWKEYS, got message %d", payload[0])
+	}
+
+	inSeq = t.in.seq
+	if t.isServer {
+		t.in = c2s
+	} else {
+		t.in = s2c
+	}
+	t.in.seq = inSeq
+
+	return nil
+}
+
+// handshake exchanges the versions and performs the initial key exchange.
+func (t *transport) handshake() error {
+	if err := t.exchangeVersions(); err != nil {
+		return err
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	return t.keyExchange(nil)
+}
@@ -0,0 +1,352 @@
+package ssh_proxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+// recordingConn keeps a copy of everything read from the connection.
+type recordingConn struct {
+	net.Conn
+	read bytes.Buffer
+}
+
+func (c *recordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.read.Write(b[:n])
+	return n, err
+}
+
+func newTestHostKey(t *testing.T) ed25519.PrivateKey {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+// tcpPipe returns the two ends of a loopback connection, unlike net.Pipe
+// they are buffered so both sides can send their KEXINIT before reading.
+func tcpPipe(t *testing.T) (net.Conn, net.Conn) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	b, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, b
+}
+
+// handshakePair connects a server and a client transport and completes
+// their handshake, the client connection records what it reads.
+func handshakePair(t *testing.T) (*transport, *transport, *recordingConn) {
+	a, b := tcpPipe(t)
+	rec := &recordingConn{Conn: b}
+
+	server := newTransport(a, true, "SSH-2.0-OpenSSH_8.9", newTestHostKey(t))
+	client := newTransport(rec, false, "SSH-2.0-OpenSSH_9.0", nil)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.handshake()
+	}()
+
+	if err := client.handshake(); err != nil {
+		t.Fatalf("client handshake: %v", err)
+	} else if err := <-errs; err != nil {
+		t.Fatalf("server handshake: %v", err)
+	}
+
+	return server, client, rec
+}
+
+func TestTransportFraming(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	writer := newTransport(a, false, "", nil)
+	reader := newTransport(b, true, "", nil)
+
+	for _, size := range []int{0, 1, 2, 3, 4, 7, 8, 15, 16, 100, 4096} {
+		payload := make([]byte, size)
+		rand.Read(payload)
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- writer.writePacket(payload)
+		}()
+
+		got, err := reader.readPacket()
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		} else if err := <-errs; err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		} else if !bytes.Equal(got, payload) {
+			t.Fatalf("size %d: payload mismatch", size)
+		}
+	}
+
+	if writer.out.seq != 11 || reader.in.seq != 11 {
+		t.Fatalf("unexpected sequence numbers %d and %d", writer.out.seq, reader.in.seq)
+	}
+}
+
+func TestTransportFramingLayout(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	writer := newTransport(a, false, "", nil)
+	go writer.writePacket([]byte("hello"))
+
+	raw := make([]byte, 4)
+	r := bufio.NewReader(b)
+	if _, err := r.Read(raw); err != nil {
+		t.Fatal(err)
+	}
+
+	// RFC 4253 section 6: the whole packet is a multiple of the block size
+	// and has at least 4 bytes of padding
+	size := binary.BigEndian.Uint32(raw)
+	if (size+4)%8 != 0 {
+		t.Fatalf("packet size %d is not aligned to the block size", size+4)
+	}
+
+	body := make([]byte, size)
+	if _, err := r.Read(body); err != nil {
+		t.Fatal(err)
+	}
+	if padding := int(body[0]); padding < 4 || 1+len("hello")+padding != int(size) {
+		t.Fatalf("invalid padding %d for a packet of %d bytes", padding, size)
+	} else if string(body[1:6]) != "hello" {
+		t.Fatalf("unexpected payload %q", body[1:6])
+	}
+}
+
+func TestTransportInvalidSize(t *testing.T) {
+	for _, size := range []uint32{0, 4, maxPacketSize + 4, 13} {
+		raw := make([]byte, 4, 64)
+		binary.BigEndian.PutUint32(raw, size)
+		raw = append(raw, make([]byte, 60)...)
+
+		tr := &transport{
+			reader: bufio.NewReader(bytes.NewReader(raw)),
+			in:     direction{blockSize: 8},
+		}
+		if _, err := tr.readPacket(); err == nil {
+			t.Fatalf("expected size %d to be rejected", size)
+		}
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	k := []byte{0x80, 1, 2, 3}
+	h := bytes.Repeat([]byte{0x42}, 32)
+	sessionID := bytes.Repeat([]byte{0x24}, 32)
+
+	// RFC 4253 section 7.2, K is encoded as an mpint: 0x80 needs a leading zero
+	mpint := []byte{0, 0, 0, 5, 0, 0x80, 1, 2, 3}
+
+	digest := sha256.New()
+	digest.Write(mpint)
+	digest.Write(h)
+	digest.Write([]byte{'C'})
+	digest.Write(sessionID)
+	k1 := digest.Sum(nil)
+
+	digest.Reset()
+	digest.Write(mpint)
+	digest.Write(h)
+	digest.Write(k1)
+	k2 := digest.Sum(nil)
+
+	if got := deriveKey(k, h, 'C', sessionID, 16); !bytes.Equal(got, k1[:16]) {
+		t.Fatalf("unexpected short key %x", got)
+	} else if got := deriveKey(k, h, 'C', sessionID, 64); !bytes.Equal(got, append(k1, k2...)) {
+		t.Fatalf("unexpected extended key %x", got)
+	} else if bytes.Equal(deriveKey(k, h, 'D', sessionID, 32), k1) {
+		t.Fatal("keys of different letters must differ")
+	}
+}
+
+func TestMpint(t *testing.T) {
+	tests := []struct {
+		in  []byte
+		exp []byte
+	}{
+		{[]byte{}, []byte{0, 0, 0, 0}},
+		{[]byte{0, 0}, []byte{0, 0, 0, 0}},
+		{[]byte{0x7f}, []byte{0, 0, 0, 1, 0x7f}},
+		{[]byte{0, 0x80}, []byte{0, 0, 0, 2, 0, 0x80}},
+		{[]byte{0x12, 0x34}, []byte{0, 0, 0, 2, 0x12, 0x34}},
+	}
+
+	for _, tt := range tests {
+		w := &sshWriter{}
+		if got := w.mpint(tt.in).Bytes(); !bytes.Equal(got, tt.exp) {
+			t.Errorf("mpint(%x): expected %x, got %x", tt.in, tt.exp, got)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	if algo, err := negotiate("cipher", []string{"aes256-ctr", "aes128-ctr"}, []string{"aes128-ctr", "aes256-ctr"}); err != nil {
+		t.Fatal(err)
+	} else if algo != "aes256-ctr" {
+		t.Fatalf("the client preference must win, got %s", algo)
+	} else if _, err := negotiate("cipher", []string{"chacha20-poly1305@openssh.com"}, cipherAlgos); err == nil {
+		t.Fatal("expected no common algorithm")
+	}
+}
+
+func TestKeyExchange(t *testing.T) {
+	server, client, rec := handshakePair(t)
+	defer server.conn.Close()
+	defer client.conn.Close()
+
+	if !bytes.Equal(server.sessionID, client.sessionID) || len(client.sessionID) != sha256.Size {
+		t.Fatalf("session identifiers mismatch:\n%x\n%x", server.sessionID, client.sessionID)
+	}
+
+	// the exchange hash must be signed with the host key, parse what the
+	// client received in clear text: the version, KEXINIT and KEX_ECDH_REPLY
+	plain := newTransport(nil, false, "", nil)
+	plain.reader = bufio.NewReader(bytes.NewReader(rec.read.Bytes()))
+	if _, err := plain.reader.ReadString('\n'); err != nil {
+		t.Fatal(err)
+	} else if payload, err := plain.readPacket(); err != nil || payload[0] != msgKexInit {
+		t.Fatalf("expected KEXINIT: %v", err)
+	}
+
+	payload, err := plain.readPacket()
+	if err != nil || payload[0] != msgKexECDHReply {
+		t.Fatalf("expected KEX_ECDH_REPLY: %v", err)
+	}
+
+	r := newReader(payload[1:])
+	ks := newReader(r.bytes())
+	r.bytes()
+	sig := newReader(r.bytes())
+	if r.err != nil {
+		t.Fatal(r.err)
+	} else if ks.string() != "ssh-ed25519" || sig.string() != "ssh-ed25519" {
+		t.Fatal("unexpected host key algorithm")
+	}
+
+	pub := ks.bytes()
+	if !bytes.Equal(pub, server.hostKey.Public().(ed25519.PublicKey)) {
+		t.Fatal("unexpected host key")
+	} else if !ed25519.Verify(pub, client.sessionID, sig.bytes()) {
+		t.Fatal("invalid signature of the exchange hash")
+	}
+}
+
+func TestEncryptedMessages(t *testing.T) {
+	server, client, _ := handshakePair(t)
+	defer server.conn.Close()
+	defer client.conn.Close()
+
+	if server.in.stream == nil || server.in.macSize == 0 || client.in.stream == nil || client.in.macSize == 0 {
+		t.Fatal("expected the keys to be in use after the handshake")
+	}
+
+	exchange := func(from, to *transport, msg []byte) {
+		errs := make(chan error, 1)
+		go func() {
+			errs <- from.writePacket(msg)
+		}()
+
+		if got, err := to.readMessage(); err != nil {
+			t.Fatal(err)
+		} else if err := <-errs; err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(got, msg) {
+			t.Fatalf("expected %x, got %x", msg, got)
+		}
+	}
+
+	// the ignored messages are skipped
+	if err := client.writePacket([]byte{msgIgnore, 1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	exchange(client, server, newMessage(msgServiceRequest).string("ssh-userauth").Bytes())
+	exchange(server, client, newMessage(msgServiceAccept).string("ssh-userauth").Bytes())
+}
+
+func TestReKeyExchange(t *testing.T) {
+	server, client, _ := handshakePair(t)
+	defer server.conn.Close()
+	defer client.conn.Close()
+
+	sessionID := append([]byte{}, client.sessionID...)
+	oldKey := client.out.mac
+
+	// the client starts a new key exchange, the server handles it while
+	// waiting for the next message
+	msg := newMessage(msgServiceRequest).string("ssh-userauth").Bytes()
+	errs := make(chan error, 1)
+	go func() {
+		client.Lock()
+		err := client.keyExchange(nil)
+		client.Unlock()
+		if err == nil {
+			err = client.writePacket(msg)
+		}
+		errs <- err
+	}()
+
+	if got, err := server.readMessage(); err != nil {
+		t.Fatal(err)
+	} else if err := <-errs; err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(got, msg) {
+		t.Fatalf("expected %x, got %x", msg, got)
+	} else if !bytes.Equal(client.sessionID, sessionID) || !bytes.Equal(server.sessionID, sessionID) {
+		t.Fatal("the session identifier must not change when re-keying")
+	} else if client.out.mac == oldKey {
+		t.Fatal("expected new keys after re-keying")
+	}
+}
+
+func TestTamperedPacket(t *testing.T) {
+	server, client, _ := handshakePair(t)
+	defer server.conn.Close()
+	defer client.conn.Close()
+
+	// flip a byte of the MAC of an encrypted packet
+	client.Lock()
+	realConn := client.conn
+	pr, pw := net.Pipe()
+	client.conn = pw
+	client.Unlock()
+
+	go func() {
+		client.writePacket([]byte{msgServiceRequest, 0, 0, 0, 0})
+		pw.Close()
+	}()
+	go func() {
+		buf := make([]byte, 1024)
+		n, _ := pr.Read(buf)
+		buf[n-1] ^= 0xff
+		realConn.Write(buf[:n])
+	}()
+
+	if _, err := server.readPacket(); err == nil {
+		t.Fatal("expected the MAC verification to fail")
+	}
+}
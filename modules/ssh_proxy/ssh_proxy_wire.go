@@ -0,0 +1,146 @@
+package ssh_proxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// SSH message numbers, see RFC 4250.
+const (
+	msgDisconnect            = 1
+	msgIgnore                = 2
+	msgUnimplemented         = 3
+	msgDebug                 = 4
+	msgServiceRequest        = 5
+	msgServiceAccept         = 6
+	msgKexInit               = 20
+	msgNewKeys               = 21
+	msgKexECDHInit           = 30
+	msgKexECDHReply          = 31
+	msgUserAuthRequest       = 50
+	msgUserAuthFailure       = 51
+	msgUserAuthSuccess       = 52
+	msgUserAuthBanner        = 53
+	msgUserAuthInfoRequest   = 60
+	msgUserAuthInfoResponse  = 61
+	msgGlobalRequest         = 80
+	msgChannelOpen           = 90
+	msgChannelData           = 94
+	msgChannelExtendedData   = 95
+	msgChannelClose          = 97
+	msgChannelRequest        = 98
+	disconnectByApplication  = 11
+	disconnectNoMoreAuthMeth = 14
+)
+
+type sshWriter struct {
+	bytes.Buffer
+}
+
+func newMessage(msgType byte) *sshWriter {
+	w := &sshWriter{}
+	w.WriteByte(msgType)
+	return w
+}
+
+func (w *sshWriter) uint32(v uint32) *sshWriter {
+	binary.Write(&w.Buffer, binary.BigEndian, v)
+	return w
+}
+
+func (w *sshWriter) bool(v bool) *sshWriter {
+	if v {
+		w.WriteByte(1)
+	} else {
+		w.WriteByte(0)
+	}
+	return w
+}
+
+func (w *sshWriter) bytes(v []byte) *sshWriter {
+	w.uint32(uint32(len(v)))
+	w.Write(v)
+	return w
+}
+
+func (w *sshWriter) string(v string) *sshWriter {
+	return w.bytes([]byte(v))
+}
+
+func (w *sshWriter) nameList(v []string) *sshWriter {
+	return w.string(strings.Join(v, ","))
+}
+
+// mpint encodes an unsigned big endian integer as an SSH mpint.
+func (w *sshWriter) mpint(v []byte) *sshWriter {
+	for len(v) > 0 && v[0] == 0 {
+		v = v[1:]
+	}
+	if len(v) > 0 && v[0]&0x80 != 0 {
+		v = append([]byte{0}, v...)
+	}
+	return w.bytes(v)
+}
+
+type sshReader struct {
+	data []byte
+	err  error
+}
+
+func newReader(payload []byte) *sshReader {
+	return &sshReader{data: payload}
+}
+
+func (r *sshReader) fail() {
+	if r.err == nil {
+		r.err = fmt.Errorf("malformed SSH message")
+	}
+}
+
+func (r *sshReader) byte() byte {
+	if len(r.data) < 1 {
+		r.fail()
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *sshReader) uint32() uint32 {
+	if len(r.data) < 4 {
+		r.fail()
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return v
+}
+
+func (r *sshReader) bool() bool {
+	return r.byte() != 0
+}
+
+func (r *sshReader) bytes() []byte {
+	size := r.uint32()
+	if r.err != nil || uint32(len(r.data)) < size {
+		r.fail()
+		return nil
+	}
+	v := r.data[:size]
+	r.data = r.data[size:]
+	return v
+}
+
+func (r *sshReader) string() string {
+	return string(r.bytes())
+}
+
+func (r *sshReader) nameList() []string {
+	if s := r.string(); s != "" {
+		return strings.Split(s, ",")
+	}
+	return nil
+}
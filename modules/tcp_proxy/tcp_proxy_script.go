@@ -14,7 +14,9 @@ import (
 
 type TcpProxyScript struct {
 	*plugin.Plugin
-	doOnData bool
+	doOnData    bool
+	unhook      func()
+	unsubscribe func()
 }
 
 func LoadTcpProxyScript(path string, sess *session.Session) (err error, s *TcpProxyScript) {
@@ -43,9 +45,55 @@ func LoadTcpProxyScript(path string, sess *session.Session) (err error, s *TcpPr
 		Plugin:   plug,
 		doOnData: plug.HasFunc("onData"),
 	}
+
+	if plug.HasFunc("onModuleEvent") {
+		s.unhook = sess.OnModuleEvent(func(event string, module string, err error) {
+			s.OnModuleEvent(event, module, err)
+		})
+	}
+
+	if plug.HasFunc("onRecord") {
+		s.unsubscribe = sess.Bus.Subscribe(path, session.TopicAll, s.OnRecord)
+	}
 	return
 }
 
+func (s *TcpProxyScript) OnModuleEvent(event string, module string, err error) {
+	errDesc := ""
+	if err != nil {
+		errDesc = err.Error()
+	}
+
+	if _, err := s.Call("onModuleEvent", event, module, errDesc); err != nil {
+		log.Error("error while executing onModuleEvent callback: %s", err)
+	}
+}
+
+// OnRecord passes the records published on the session bus to the script.
+func (s *TcpProxyScript) OnRecord(r session.Record) {
+	data, err := r.Object()
+	if err != nil {
+		log.Error("error while encoding the %s record: %v", r.Topic, err)
+		return
+	}
+
+	if _, err := s.Call("onRecord", r.Topic, r.Source, data); err != nil {
+		log.Error("error while executing onRecord callback: %s", err)
+	}
+}
+
+// Unload stops the delivery of the session events and records to the script.
+func (s *TcpProxyScript) Unload() {
+	if s.unhook != nil {
+		s.unhook()
+		s.unhook = nil
+	}
+	if s.unsubscribe != nil {
+		s.unsubscribe()
+		s.unsubscribe = nil
+	}
+}
+
 func (s *TcpProxyScript) OnData(from, to net.Addr, data []byte) []byte {
 	if s.doOnData {
 		addrFrom := strings.Split(from.String(), ":")[0]
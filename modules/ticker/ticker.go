@@ -86,7 +86,7 @@ func (mod *Ticker) Start() error {
 			}
 
 			for _, cmd := range mod.Commands {
-				if err := mod.Session.Run(cmd); err != nil {
+				if err := mod.Session.RunAs(cmd, mod.Name()); err != nil {
 					mod.Error("%s", err)
 				}
 			}
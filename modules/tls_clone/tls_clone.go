@@ -0,0 +1,136 @@
+package tls_clone
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/bettercap/bettercap/session"
+	btls "github.com/bettercap/bettercap/tls"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+type TLSCloner struct {
+	session.SessionModule
+}
+
+func NewTLSCloner(s *session.Session) *TLSCloner {
+	mod := &TLSCloner{
+		SessionModule: session.NewSessionModule("tls.clone", s),
+	}
+
+	mod.AddParam(session.NewStringParameter("tls.clone.ca.certificate",
+		btls.DefaultCACertFile,
+		"",
+		"Certification authority TLS certificate file used to sign the cloned certificates (will be auto generated if not existing)."))
+
+	mod.AddParam(session.NewStringParameter("tls.clone.ca.key",
+		btls.DefaultCAKeyFile,
+		"",
+		"Certification authority TLS key file used to sign the cloned certificates (will be auto generated if not existing)."))
+
+	mod.AddHandler(session.NewModuleHandler("tls.clone HOST:PORT CERT KEY", `tls\.clone\s+([^\s]+)\s+([^\s]+)\s+([^\s]+)`,
+		"Fetch the certificate of a live TLS server and save a lookalike signed by the certification authority to the CERT and KEY files.",
+		func(args []string) error {
+			return mod.clone(args[0], args[1], args[2])
+		}))
+
+	return mod
+}
+
+func (mod *TLSCloner) Name() string {
+	return "tls.clone"
+}
+
+func (mod *TLSCloner) Description() string {
+	return "A module to clone the certificates of live TLS servers, with the same subject, alternative names and validity."
+}
+
+func (mod *TLSCloner) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *TLSCloner) Configure() error {
+	return nil
+}
+
+func (mod *TLSCloner) Start() error {
+	return nil
+}
+
+func (mod *TLSCloner) Stop() error {
+	return nil
+}
+
+func parseAddress(address string) (string, int, error) {
+	if !strings.Contains(address, ":") {
+		return address, 443, nil
+	}
+
+	host, sport, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, err
+	}
+
+	port, err := strconv.Atoi(sport)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port %s", sport)
+	}
+
+	return host, port, nil
+}
+
+func (mod *TLSCloner) clone(address, certFile, keyFile string) error {
+	var err error
+	var caCert string
+	var caKey string
+
+	host, port, err := parseAddress(address)
+	if err != nil {
+		return err
+	} else if err, caCert = mod.StringParam("tls.clone.ca.certificate"); err != nil {
+		return err
+	} else if caCert, err = fs.Expand(caCert); err != nil {
+		return err
+	} else if err, caKey = mod.StringParam("tls.clone.ca.key"); err != nil {
+		return err
+	} else if caKey, err = fs.Expand(caKey); err != nil {
+		return err
+	} else if certFile, err = fs.Expand(certFile); err != nil {
+		return err
+	} else if keyFile, err = fs.Expand(keyFile); err != nil {
+		return err
+	}
+
+	if !fs.Exists(caCert) || !fs.Exists(caKey) {
+		mod.Info("generating certification authority TLS key to %s", caKey)
+		mod.Info("generating certification authority TLS certificate to %s", caCert)
+		if err := btls.Generate(btls.DefaultSpoofConfig, caCert, caKey); err != nil {
+			return err
+		}
+	}
+
+	err, ca := btls.LoadCA(caCert, caKey)
+	if err != nil {
+		return err
+	}
+
+	err, orig := btls.CloneToFiles(ca, host, port, certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("could not clone the certificate of %s:%d: %v", host, port, err)
+	}
+
+	mod.Info("cloned certificate of %s:%d (%s, valid until %s, %d alternative names) saved to %s and %s",
+		host,
+		port,
+		tui.Bold(orig.Subject.CommonName),
+		orig.NotAfter.Format("2006-01-02"),
+		len(orig.DNSNames)+len(orig.IPAddresses),
+		tui.Bold(certFile),
+		tui.Bold(keyFile))
+
+	return nil
+}
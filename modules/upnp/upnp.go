@@ -0,0 +1,235 @@
+package upnp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/str"
+)
+
+type UPNPModule struct {
+	session.SessionModule
+	sync.Mutex
+
+	client  *http.Client
+	devices map[string]*network.UPNPRoot
+}
+
+func NewUPNPModule(s *session.Session) *UPNPModule {
+	mod := &UPNPModule{
+		SessionModule: session.NewSessionModule("upnp", s),
+		client:        &http.Client{},
+		devices:       make(map[string]*network.UPNPRoot),
+	}
+
+	mod.AddParam(session.NewIntParameter("upnp.timeout",
+		"5",
+		"Timeout in seconds of the HTTP requests to the UPnP devices."))
+
+	mod.AddHandler(session.NewModuleHandler("upnp.enum ADDRESS?", `upnp\.enum\s*(.*)`,
+		"Fetch the description of the UPnP devices found by net.probe, or of the device at ADDRESS (IP or description URL), with their services and actions.",
+		func(args []string) error {
+			return mod.enum(str.Trim(args[0]))
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("upnp.show", "",
+		"Show the enumerated UPnP devices and their services.",
+		func(args []string) error {
+			return mod.show("")
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("upnp.show ADDRESS", `upnp\.show\s+([^\s]+)`,
+		"Show the services and actions of the UPnP device at ADDRESS.",
+		func(args []string) error {
+			return mod.show(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("upnp.soap ADDRESS SERVICE ACTION ARGS?", `upnp\.soap\s+([^\s]+)\s+([^\s]+)\s+([^\s]+)\s*(.*)`,
+		"Invoke the ACTION of a SERVICE (WANIPConnection, urn:upnp-org:serviceId:WANIPConn1, ...) of the UPnP device at ADDRESS, ARGS are in the NAME=VALUE form separated by spaces.",
+		func(args []string) error {
+			return mod.soapCommand(args[0], args[1], args[2], str.Trim(args[3]))
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("upnp.portmaps ADDRESS", `upnp\.portmaps\s+([^\s]+)`,
+		"List the NAT port mappings of the Internet Gateway Device at ADDRESS.",
+		func(args []string) error {
+			return mod.showPortMappings(args[0])
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("upnp.portmap.add ADDRESS PROTOCOL EXTERNAL-PORT INTERNAL-IP INTERNAL-PORT DESCRIPTION?",
+		`upnp\.portmap\.add\s+([^\s]+)\s+(tcp|udp|TCP|UDP)\s+(\d+)\s+([^\s]+)\s+(\d+)\s*(.*)`,
+		"Add a NAT port mapping on the Internet Gateway Device at ADDRESS.",
+		func(args []string) error {
+			return mod.addPortMapping(args[0], strings.ToUpper(args[1]), args[2], args[3], args[4], str.Trim(args[5]))
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("upnp.portmap.del ADDRESS PROTOCOL EXTERNAL-PORT", `upnp\.portmap\.del\s+([^\s]+)\s+(tcp|udp|TCP|UDP)\s+(\d+)`,
+		"Remove a NAT port mapping from the Internet Gateway Device at ADDRESS.",
+		func(args []string) error {
+			return mod.delPortMapping(args[0], strings.ToUpper(args[1]), args[2])
+		}))
+
+	return mod
+}
+
+func (mod *UPNPModule) Name() string {
+	return "upnp"
+}
+
+func (mod *UPNPModule) Description() string {
+	return "Enumerate the services of UPnP devices and invoke their actions, like managing the port mappings of a router."
+}
+
+func (mod *UPNPModule) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *UPNPModule) Configure() error {
+	err, timeout := mod.IntParam("upnp.timeout")
+	if err != nil {
+		return err
+	}
+	mod.client.Timeout = time.Duration(timeout) * time.Second
+	return nil
+}
+
+func (mod *UPNPModule) Start() error {
+	return nil
+}
+
+func (mod *UPNPModule) Stop() error {
+	return nil
+}
+
+func (mod *UPNPModule) fetch(location string) ([]byte, error) {
+	res, err := mod.client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", location, res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// locations returns the description URLs of the devices at address, or of every device
+// announced via SSDP if address is empty.
+func (mod *UPNPModule) locations(address string) ([]string, error) {
+	if strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://") {
+		return []string{address}, nil
+	} else if address != "" && net.ParseIP(address) == nil {
+		return nil, fmt.Errorf("'%s' is neither an IP address nor a description URL", address)
+	}
+
+	unique := make(map[string]bool)
+	mod.Session.Lan.EachHost(func(mac string, e *network.Endpoint) {
+		if address == "" || e.IpAddress == address {
+			if location := e.Meta.GetOr("upnp:Location", "").(string); location != "" {
+				unique[location] = true
+			}
+		}
+	})
+
+	locations := make([]string, 0, len(unique))
+	for location := range unique {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+
+	if len(locations) == 0 {
+		if address == "" {
+			return nil, fmt.Errorf("no UPnP devices found yet, start net.recon and net.probe or specify a description URL")
+		}
+		return nil, fmt.Errorf("no UPnP description URL known for %s, use its full URL instead", address)
+	}
+	return locations, nil
+}
+
+func (mod *UPNPModule) describe(location string) (*network.UPNPRoot, error) {
+	raw, err := mod.fetch(location)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := network.ParseUPNPDescription(location, raw)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", location, err)
+	}
+
+	root.Device.Each(func(d *network.UPNPDevice, s *network.UPNPService) {
+		if s.SCPDURL == "" {
+			return
+		} else if raw, err := mod.fetch(s.SCPDURL); err != nil {
+			mod.Warning("error fetching %s: %v", s.SCPDURL, err)
+		} else if s.Actions, err = network.ParseUPNPSCPD(raw); err != nil {
+			mod.Warning("error parsing %s: %v", s.SCPDURL, err)
+		}
+	})
+
+	mod.Lock()
+	mod.devices[location] = root
+	mod.Unlock()
+
+	return root, nil
+}
+
+func (mod *UPNPModule) enum(address string) error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	locations, err := mod.locations(address)
+	if err != nil {
+		return err
+	}
+
+	for _, location := range locations {
+		mod.Info("fetching %s ...", location)
+		if _, err := mod.describe(location); err != nil {
+			mod.Error("%v", err)
+		}
+	}
+
+	return mod.show(address)
+}
+
+func locationHost(location string) string {
+	if u, err := url.Parse(location); err == nil {
+		return u.Hostname()
+	}
+	return ""
+}
+
+// device returns the description of the device at address, fetching it if needed.
+func (mod *UPNPModule) device(address string) (*network.UPNPRoot, error) {
+	mod.Lock()
+	for location, root := range mod.devices {
+		if location == address || locationHost(location) == address {
+			mod.Unlock()
+			return root, nil
+		}
+	}
+	mod.Unlock()
+
+	if err := mod.Configure(); err != nil {
+		return nil, err
+	}
+
+	locations, err := mod.locations(address)
+	if err != nil {
+		return nil, err
+	}
+	return mod.describe(locations[0])
+}
@@ -0,0 +1,78 @@
+package upnp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// shortType turns urn:schemas-upnp-org:service:WANIPConnection:1 into WANIPConnection:1
+func shortType(t string) string {
+	if parts := strings.Split(t, ":"); len(parts) >= 2 {
+		return strings.Join(parts[len(parts)-2:], ":")
+	}
+	return t
+}
+
+func (mod *UPNPModule) show(address string) error {
+	mod.Lock()
+	defer mod.Unlock()
+
+	locations := make([]string, 0)
+	for location := range mod.devices {
+		if address == "" || location == address || locationHost(location) == address {
+			locations = append(locations, location)
+		}
+	}
+	sort.Strings(locations)
+
+	if len(locations) == 0 {
+		return fmt.Errorf("no UPnP devices enumerated yet, use upnp.enum")
+	}
+
+	for _, location := range locations {
+		root := mod.devices[location]
+		rows := make([][]string, 0)
+		root.Device.Each(func(d *network.UPNPDevice, s *network.UPNPService) {
+			actions := make([]string, 0, len(s.Actions))
+			for _, a := range s.Actions {
+				if address == "" {
+					actions = append(actions, a.Name)
+				} else {
+					args := make([]string, 0)
+					for _, arg := range a.Arguments {
+						if arg.Direction == "in" {
+							args = append(args, arg.Name)
+						}
+					}
+					actions = append(actions, fmt.Sprintf("%s(%s)", a.Name, strings.Join(args, ", ")))
+				}
+			}
+
+			rows = append(rows, []string{
+				d.FriendlyName,
+				tui.Bold(shortType(s.Type)),
+				tui.Dim(s.ControlURL),
+				strings.Join(actions, ", "),
+			})
+		})
+
+		mod.Session.Println()
+		mod.Session.Printf("%s %s %s (%s)\n\n",
+			tui.Bold(root.Device.FriendlyName),
+			tui.Dim(strings.Trim(root.Device.Manufacturer+" "+root.Device.ModelName, " ")),
+			tui.Yellow(shortType(root.Device.Type)),
+			location)
+
+		if len(rows) > 0 {
+			mod.Session.Table([]string{"Device", "Service", "Control URL", "Actions"}, rows)
+		}
+	}
+	mod.Session.Println()
+
+	return nil
+}
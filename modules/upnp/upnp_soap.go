@@ -0,0 +1,237 @@
+package upnp
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// port mappings can be managed by either one of these services
+var igdServices = []string{"WANIPConnection", "WANPPPConnection"}
+
+func (mod *UPNPModule) soap(service *network.UPNPService, action string, args []network.UPNPArg) ([]network.UPNPArg, error) {
+	if service.ControlURL == "" {
+		return nil, fmt.Errorf("service %s has no control URL", service.Type)
+	}
+
+	if ip := net.ParseIP(locationHost(service.ControlURL)); ip != nil && !mod.Session.IPInScope(ip, "UPnP "+action) {
+		return nil, fmt.Errorf("%s is out of scope", ip)
+	}
+
+	body := network.NewUPNPSOAPRequest(service.Type, action, args)
+	req, err := http.NewRequest("POST", service.ControlURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, service.Type, action))
+
+	mod.Debug("POST %s SOAPAction %s#%s", service.ControlURL, service.Type, action)
+
+	res, err := mod.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	raw, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := network.ParseUPNPSOAPResponse(raw)
+	if err != nil {
+		return nil, err
+	} else if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", service.ControlURL, res.Status)
+	}
+	return out, nil
+}
+
+func parseSOAPArgs(line string) ([]network.UPNPArg, error) {
+	args := make([]network.UPNPArg, 0)
+	for _, tok := range strings.Fields(line) {
+		parts := strings.SplitN(tok, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid argument '%s', expected NAME=VALUE", tok)
+		}
+		args = append(args, network.UPNPArg{Name: parts[0], Value: parts[1]})
+	}
+	return args, nil
+}
+
+func (mod *UPNPModule) soapCommand(address, serviceName, action, argsLine string) error {
+	args, err := parseSOAPArgs(argsLine)
+	if err != nil {
+		return err
+	}
+
+	root, err := mod.device(address)
+	if err != nil {
+		return err
+	}
+
+	service := root.Device.Find(serviceName)
+	if service == nil {
+		return fmt.Errorf("service %s not found on %s", serviceName, root.Location)
+	}
+
+	if mod.Session.IsDryRun() {
+		mod.Session.DryRunAction("UPnP %s#%s on %s", service.Type, action, service.ControlURL)
+		return nil
+	}
+
+	out, err := mod.soap(service, action, args)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0)
+	for _, arg := range out {
+		rows = append(rows, []string{tui.Bold(arg.Name), arg.Value})
+	}
+
+	if len(rows) > 0 {
+		mod.Session.Println()
+		mod.Session.Table([]string{"Name", "Value"}, rows)
+		mod.Session.Println()
+	} else {
+		mod.Info("%s completed.", action)
+	}
+	return nil
+}
+
+func (mod *UPNPModule) igd(address string) (*network.UPNPRoot, *network.UPNPService, error) {
+	root, err := mod.device(address)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, name := range igdServices {
+		if service := root.Device.Find(name); service != nil {
+			return root, service, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%s is not an Internet Gateway Device", root.Location)
+}
+
+func argValue(args []network.UPNPArg, name string) string {
+	for _, arg := range args {
+		if arg.Name == name {
+			return arg.Value
+		}
+	}
+	return ""
+}
+
+func (mod *UPNPModule) showPortMappings(address string) error {
+	root, service, err := mod.igd(address)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0)
+	// there's no way to know the number of entries, iterate until the device fails
+	for idx := 0; idx < 1024; idx++ {
+		out, err := mod.soap(service, "GetGenericPortMappingEntry", []network.UPNPArg{
+			{Name: "NewPortMappingIndex", Value: strconv.Itoa(idx)},
+		})
+		if err != nil {
+			if idx == 0 {
+				mod.Debug("GetGenericPortMappingEntry: %v", err)
+			}
+			break
+		}
+
+		enabled := tui.Green("yes")
+		if argValue(out, "NewEnabled") == "0" {
+			enabled = tui.Dim("no")
+		}
+
+		remote := argValue(out, "NewRemoteHost")
+		if remote == "" {
+			remote = tui.Dim("*")
+		}
+
+		rows = append(rows, []string{
+			argValue(out, "NewProtocol"),
+			remote,
+			tui.Bold(argValue(out, "NewExternalPort")),
+			fmt.Sprintf("%s:%s", argValue(out, "NewInternalClient"), argValue(out, "NewInternalPort")),
+			enabled,
+			argValue(out, "NewPortMappingDescription"),
+			argValue(out, "NewLeaseDuration"),
+		})
+	}
+
+	if len(rows) == 0 {
+		mod.Info("no port mappings found on %s", root.Location)
+		return nil
+	}
+
+	mod.Session.Println()
+	mod.Session.Table([]string{"Proto", "Remote", "External", "Internal", "Enabled", "Description", "Lease"}, rows)
+	mod.Session.Println()
+	return nil
+}
+
+func (mod *UPNPModule) addPortMapping(address, proto, extPort, intIP, intPort, desc string) error {
+	if net.ParseIP(intIP) == nil {
+		return fmt.Errorf("%s is not a valid IP address", intIP)
+	} else if desc == "" {
+		desc = "bettercap"
+	}
+
+	_, service, err := mod.igd(address)
+	if err != nil {
+		return err
+	} else if mod.Session.IsDryRun() {
+		mod.Session.DryRunAction("UPnP port mapping %s %s -> %s:%s on %s", proto, extPort, intIP, intPort, service.ControlURL)
+		return nil
+	}
+
+	if _, err = mod.soap(service, "AddPortMapping", []network.UPNPArg{
+		{Name: "NewRemoteHost", Value: ""},
+		{Name: "NewExternalPort", Value: extPort},
+		{Name: "NewProtocol", Value: proto},
+		{Name: "NewInternalPort", Value: intPort},
+		{Name: "NewInternalClient", Value: intIP},
+		{Name: "NewEnabled", Value: "1"},
+		{Name: "NewPortMappingDescription", Value: desc},
+		{Name: "NewLeaseDuration", Value: "0"},
+	}); err != nil {
+		return err
+	}
+
+	mod.Info("%s port %s of %s mapped to %s:%s", proto, extPort, address, intIP, intPort)
+	return nil
+}
+
+func (mod *UPNPModule) delPortMapping(address, proto, extPort string) error {
+	_, service, err := mod.igd(address)
+	if err != nil {
+		return err
+	} else if mod.Session.IsDryRun() {
+		mod.Session.DryRunAction("UPnP port mapping %s %s removal on %s", proto, extPort, service.ControlURL)
+		return nil
+	}
+
+	if _, err = mod.soap(service, "DeletePortMapping", []network.UPNPArg{
+		{Name: "NewRemoteHost", Value: ""},
+		{Name: "NewExternalPort", Value: extPort},
+		{Name: "NewProtocol", Value: proto},
+	}); err != nil {
+		return err
+	}
+
+	mod.Info("%s port %s of %s unmapped", proto, extPort, address)
+	return nil
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bettercap/readline"
+)
+
+// every how many frames the whole screen is redrawn, in order to recover
+// from anything else printed on it meanwhile
+const liveFullRedraw = 30
+
+// LiveView draws a screen of lines in place like top does, rewriting only
+// the lines that changed since the previous frame.
+type LiveView struct {
+	out    io.Writer
+	prev   []string
+	frames int
+	width  int
+	height int
+}
+
+func NewLiveView(out io.Writer) *LiveView {
+	return &LiveView{
+		out: out,
+	}
+}
+
+// Size returns the size of the terminal, zero if unknown.
+func (v *LiveView) Size() (width, height int) {
+	if w, h, err := readline.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 && h > 0 {
+		return w, h
+	}
+	return 0, 0
+}
+
+// Draw renders the frame, the lines after the height of the terminal are
+// cut and the cursor is left after the last line.
+func (v *LiveView) Draw(frame string) {
+	lines := strings.Split(strings.TrimRight(frame, "\n"), "\n")
+
+	width, height := v.Size()
+	if height > 0 && len(lines) >= height {
+		lines = lines[:height-1]
+	}
+
+	full := v.prev == nil || width != v.width || height != v.height || v.frames%liveFullRedraw == 0
+	v.width, v.height = width, height
+	v.frames++
+
+	buf := bytes.Buffer{}
+	if full {
+		// cursor home and clear the screen
+		buf.WriteString("\033[H\033[2J")
+	}
+
+	for i, line := range lines {
+		if !full && i < len(v.prev) && v.prev[i] == line {
+			continue
+		}
+		// move to the line, write it and clear what's left of the old one
+		fmt.Fprintf(&buf, "\033[%d;1H%s\033[K", i+1, line)
+	}
+
+	if !full && len(lines) < len(v.prev) {
+		// clear the lines left from a taller frame
+		fmt.Fprintf(&buf, "\033[%d;1H\033[J", len(lines)+1)
+	}
+	fmt.Fprintf(&buf, "\033[%d;1H", len(lines)+1)
+
+	v.out.Write(buf.Bytes())
+	v.prev = lines
+}
+
+// Reset makes the next frame redraw the whole screen.
+func (v *LiveView) Reset() {
+	v.prev = nil
+	v.frames = 0
+}
@@ -238,11 +238,31 @@ func init() {
 			// set
 			varName := call.Argument(0).String()
 			varValue := call.Argument(1).String()
-			session.I.Env.Set(varName, varValue)
+			if err := session.I.SetParam(varName, varValue, "script"); err != nil {
+				return errOtto("env: %s", err)
+			}
 		} else {
 			return errOtto("env: expected 1 or 2 arguments, %d given instead.", argc)
 		}
 
 		return nullOtto
 	}
+
+	// run one or more session commands separated by ; and return the error message, if any
+	plugin.Defines["run"] = func(call otto.FunctionCall) otto.Value {
+		argv := call.ArgumentList
+		argc := len(argv)
+		if argc != 1 {
+			return errOtto("run: expected 1 argument, %d given instead.", argc)
+		}
+
+		for _, cmd := range session.ParseCommands(argv[0].String()) {
+			if err := session.I.RunAs(cmd, "script"); err != nil {
+				v, _ := otto.ToValue(err.Error())
+				return v
+			}
+		}
+
+		return nullOtto
+	}
 }
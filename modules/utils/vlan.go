@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+)
+
+// VLAN is used by modules to tag the frames they inject
+// with a configurable 802.1Q VLAN identifier.
+type VLAN struct {
+	owner *session.SessionModule
+	name  string
+	ID    uint16
+}
+
+func VLANFor(m *session.SessionModule, prefix string) *VLAN {
+	v := &VLAN{
+		owner: m,
+		name:  prefix + ".vlan",
+	}
+
+	m.AddParam(session.NewIntParameter(v.name, "0",
+		"If greater than 0, frames sent by "+prefix+" will be tagged with this 802.1Q VLAN identifier."))
+
+	return v
+}
+
+func (v *VLAN) Update() error {
+	err, id := v.owner.IntParam(v.name)
+	if err != nil {
+		return err
+	} else if id < 0 || id > packets.Dot1QMaxVLAN {
+		return fmt.Errorf("%s must be in the [0,%d] interval", v.name, packets.Dot1QMaxVLAN)
+	}
+	v.ID = uint16(id)
+	return nil
+}
+
+// Tag returns the frame tagged with the configured VLAN identifier, or the
+// frame itself if no identifier is set.
+func (v *VLAN) Tag(raw []byte) (error, []byte) {
+	if v.ID > 0 {
+		return packets.Dot1QEncapsulate(raw, v.ID)
+	}
+	return nil, raw
+}
+
+func (v *VLAN) Send(raw []byte) error {
+	err, raw := v.Tag(raw)
+	if err != nil {
+		return err
+	}
+	return v.owner.Session.Queue.Send(raw)
+}
@@ -2,12 +2,14 @@ package wifi
 
 import (
 	"bytes"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/bettercap/bettercap/journal"
 	"github.com/bettercap/bettercap/modules/utils"
 	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/packets"
@@ -47,14 +49,26 @@ type WiFiModule struct {
 	assocSkip           []net.HardwareAddr
 	assocSilent         bool
 	assocOpen           bool
+	assoc               *assocTracker
+	showAssoc           bool
 	shakesFile          string
 	apRunning           bool
+	apMode              string
+	rogue               *rogueAP
+	injTest             *injectionTest
+	injLock             *sync.Mutex
+	gtk                 *groupKeys
+	monitorIface        string
+	supplicant          *network.WPAControl
 	showManuf           bool
 	apConfig            packets.Dot11ApConfig
 	writes              *sync.WaitGroup
 	reads               *sync.WaitGroup
 	chanLock            *sync.Mutex
+	timing              *injectTiming
 	selector            *utils.ViewSelector
+	liveSelector        *utils.ViewSelector
+	live                *liveShow
 }
 
 func NewWiFiModule(s *session.Session) *WiFiModule {
@@ -75,13 +89,18 @@ func NewWiFiModule(s *session.Session) *WiFiModule {
 		assocSkip:     []net.HardwareAddr{},
 		assocSilent:   false,
 		assocOpen:     false,
+		live:          newLiveShow(),
+		assoc:         newAssocTracker(),
 		showManuf:     false,
 		writes:        &sync.WaitGroup{},
 		reads:         &sync.WaitGroup{},
 		chanLock:      &sync.Mutex{},
+		injLock:       &sync.Mutex{},
+		gtk:           newGroupKeys(),
+		timing:        newInjectTiming(),
 	}
 
-	mod.InitState("channels")
+	mod.InitState("channels", "channel")
 
 	mod.AddParam(session.NewStringParameter("wifi.interface",
 		"",
@@ -136,6 +155,14 @@ func NewWiFiModule(s *session.Session) *WiFiModule {
 		"-200",
 		"Minimum WiFi signal strength in dBm."))
 
+	mod.AddParam(session.NewIntParameter("wifi.ttl.ap",
+		"300",
+		"Seconds an access point is kept after it was last seen, 0 to never expire it."))
+
+	mod.AddParam(session.NewIntParameter("wifi.ttl.client",
+		"300",
+		"Seconds a client station is kept after it was last seen, 0 to never expire it."))
+
 	deauth := session.NewModuleHandler("wifi.deauth BSSID", `wifi\.deauth ((?:[a-fA-F0-9:]{11,})|all|\*)`,
 		"Start a 802.11 deauth attack, if an access point BSSID is provided, every client will be deauthenticated, otherwise only the selected client. Use 'all', '*' or a broadcast BSSID (ff:ff:ff:ff:ff:ff) to iterate every access point with at least one client and start a deauth attack for each one.",
 		func(args []string) error {
@@ -205,16 +232,39 @@ func NewWiFiModule(s *session.Session) *WiFiModule {
 		"false",
 		"Send association requests to open networks."))
 
+	mod.AddParam(session.NewIntParameter("wifi.assoc.max",
+		"5",
+		"Stop sending association requests to an access point after this number of attempts without a PMKID, 0 for no limit (ignored when a single BSSID is given)."))
+
+	mod.AddParam(session.NewIntParameter("wifi.assoc.backoff",
+		"30",
+		"Seconds to wait before sending a new association request to an access point which didn't answer with a PMKID, doubled after every failure."))
+
+	mod.AddHandler(session.NewModuleHandler("wifi.assoc.clear", "",
+		"Clear the association attempts and results shown by wifi.show.",
+		func(args []string) error {
+			mod.assoc.clear()
+			return nil
+		}))
+
 	mod.AddHandler(session.NewModuleHandler("wifi.ap", "",
-		"Inject fake management beacons in order to create a rogue access point.",
+		"Create a rogue access point, either injecting fake management beacons or running a real access point if wifi.ap.mode is hostapd.",
 		func(args []string) error {
 			if err := mod.parseApConfig(); err != nil {
 				return err
+			} else if mod.apMode == "hostapd" {
+				return mod.startHostapd()
 			} else {
 				return mod.startAp()
 			}
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("wifi.ap off", "",
+		"Stop the rogue access point.",
+		func(args []string) error {
+			return mod.stopAp()
+		}))
+
 	mod.AddParam(session.NewStringParameter("wifi.handshakes.file",
 		"~/bettercap-wifi-handshakes.pcap",
 		"",
@@ -238,6 +288,79 @@ func NewWiFiModule(s *session.Session) *WiFiModule {
 		"true",
 		"If true, the fake access point will use WPA2, otherwise it'll result as an open AP."))
 
+	mod.AddParam(session.NewStringParameter("wifi.ap.mode",
+		"beacon",
+		"^(beacon|hostapd)$",
+		"Rogue access point mode, 'beacon' only injects fake beacons while 'hostapd' runs a real access point (requires hostapd and a driver supporting AP mode)."))
+
+	mod.AddParam(session.NewStringParameter("wifi.ap.passphrase",
+		"",
+		"",
+		"WPA2 passphrase of the access point in hostapd mode."))
+
+	mod.AddParam(session.NewStringParameter("wifi.ap.sharing",
+		"nat",
+		"^(nat|bridge|none)$",
+		"How to share connectivity with the access point clients in hostapd mode: 'nat', 'bridge' to the upstream interface or 'none'."))
+
+	mod.AddParam(session.NewStringParameter("wifi.ap.upstream",
+		"",
+		"",
+		"Upstream interface to share connectivity from in hostapd mode."))
+
+	mod.AddParam(session.NewStringParameter("wifi.ap.address",
+		"10.0.0.1/24",
+		"",
+		"Address and subnet of the access point in hostapd mode, DHCP leases are allocated from this subnet (not used when bridging)."))
+
+	mod.AddParam(session.NewStringParameter("wifi.ap.mitm",
+		"dns.spoof, http.proxy",
+		"",
+		"Comma separated list of modules to start once the access point is up in hostapd mode, their address parameter will be set to the access point address."))
+
+	mod.AddHandler(session.NewModuleHandler("wifi.test", "",
+		"Report the capabilities of the wireless interface and, if wifi.recon is running, test packet injection against the nearest access points.",
+		func(args []string) error {
+			return mod.selfTest()
+		}))
+
+	mod.AddParam(session.NewIntParameter("wifi.test.probes",
+		"10",
+		"Number of directed probe requests to send to each access point during the injection test."))
+
+	mod.AddHandler(session.NewModuleHandler("wifi.gtk BSSID", `wifi\.gtk ((?:[a-fA-F0-9]{2}:){5}[a-fA-F0-9]{2})`,
+		"Derive the group temporal key of an access point from a captured handshake and the passphrase in wifi.gtk.psk (experimental).",
+		func(args []string) error {
+			bssid, err := net.ParseMAC(args[0])
+			if err != nil {
+				return err
+			}
+			return mod.getGTK(bssid)
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("wifi.gtk.inject BSSID FRAME", `wifi\.gtk\.inject ((?:[a-fA-F0-9]{2}:){5}[a-fA-F0-9]{2})\s+([a-fA-F0-9]+)`,
+		"Inject an ethernet frame (hex encoded) as a group addressed frame sent by the access point and encrypted with its GTK, in order to reach isolated clients (experimental).",
+		func(args []string) error {
+			bssid, err := net.ParseMAC(args[0])
+			if err != nil {
+				return err
+			}
+			frame, err := hex.DecodeString(args[1])
+			if err != nil {
+				return err
+			}
+			return mod.injectGroupFrame(bssid, frame)
+		}))
+
+	mod.AddParam(session.NewStringParameter("wifi.gtk.psk",
+		"",
+		"",
+		"WPA2 passphrase of the access point used by wifi.gtk to derive its group temporal key."))
+
+	mod.AddParam(session.NewBoolParameter("wifi.gtk.unsafe",
+		"false",
+		"Must be set to true in order for wifi.gtk.inject to send forged group frames, this can disrupt the network."))
+
 	mod.AddHandler(session.NewModuleHandler("wifi.show.wps BSSID",
 		`wifi\.show\.wps ((?:[a-fA-F0-9:]{11,})|all|\*)`,
 		"Show WPS information about a given station (use 'all', '*' or a broadcast BSSID for all).",
@@ -254,9 +377,35 @@ func NewWiFiModule(s *session.Session) *WiFiModule {
 			return mod.Show()
 		}))
 
+	mod.AddHandler(session.NewModuleHandler("wifi.show live", "",
+		"Keep showing the wireless stations list, refreshing in place only what changed, until wifi.show live off.",
+		func(args []string) error {
+			return mod.startLive()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("wifi.show live off", "",
+		"Stop showing the live wireless stations list.",
+		func(args []string) error {
+			return mod.stopLive()
+		}))
+
+	mod.AddParam(session.NewIntParameter("wifi.show.live.interval",
+		"1",
+		"Seconds between the refreshes of wifi.show live."))
+
+	mod.AddHandler(session.NewModuleHandler("wifi.show.p2p", "",
+		"Show the Wi-Fi Direct devices with their names, types and roles.",
+		func(args []string) error {
+			return mod.ShowP2P()
+		}))
+
 	mod.selector = utils.ViewSelectorFor(&mod.SessionModule, "wifi.show",
 		[]string{"rssi", "bssid", "essid", "channel", "encryption", "clients", "seen", "sent", "rcvd"}, "rssi asc")
 
+	// if no limit is set the live view shows as many rows as fit in the terminal
+	mod.liveSelector = utils.ViewSelectorFor(&mod.SessionModule, "wifi.show.live",
+		[]string{"rssi", "bssid", "essid", "channel", "encryption", "clients", "seen", "sent", "rcvd"}, "rssi asc")
+
 	mod.AddParam(session.NewBoolParameter("wifi.show.manufacturer",
 		"false",
 		"If true, wifi.show will also show the devices manufacturers."))
@@ -298,19 +447,50 @@ func NewWiFiModule(s *session.Session) *WiFiModule {
 			return nil
 		}))
 
+	mod.AddParam(session.NewStringParameter("wifi.supplicant.socket",
+		defaultSupplicantSocket,
+		"",
+		"If set, wifi.recon will periodically read the scan results of wpa_supplicant from this control socket instead of using monitor mode (recon only, no injection), use 'auto' to look for it in the default locations."))
+
 	mod.AddParam(session.NewStringParameter("wifi.source.file",
 		"",
 		"",
 		"If set, the wifi module will read from this pcap file instead of the hardware interface."))
 
+	mod.AddHandler(session.NewModuleHandler("wifi.replay FILENAME", `wifi\.replay (.+)`,
+		"Feed the 802.11 frames of a pcap file through the wifi parsing pipeline, populating access points, clients and handshakes without any hardware.",
+		func(args []string) error {
+			return mod.replay(args[0])
+		}))
+
 	mod.AddParam(session.NewIntParameter("wifi.hop.period",
 		"250",
 		"If channel hopping is enabled (empty wifi.recon.channel), this is the time in milliseconds the algorithm will hop on every channel (it'll be doubled if both 2.4 and 5.0 bands are available)."))
 
+	mod.AddParam(session.NewStringParameter("wifi.inject.seq",
+		"default",
+		"^(default|random|follow)$",
+		"How to pick the sequence numbers of injected deauth, association and beacon frames: default counts from the attack's own counter, random starts from a random number for each transmitter, follow continues from the last sequence number observed from the spoofed transmitter."))
+
+	mod.AddParam(session.NewBoolParameter("wifi.inject.tsf",
+		"false",
+		"If true, beacons sent by wifi.ap will carry an increasing timestamp as a real access point would, instead of zero."))
+
+	mod.AddParam(session.NewIntParameter("wifi.inject.delay",
+		"10",
+		"Time in milliseconds to wait after each injected frame."))
+
+	mod.AddParam(session.NewIntParameter("wifi.inject.jitter",
+		"0",
+		"If greater than zero, a random delay up to this many milliseconds will be added to wifi.inject.delay."))
+
 	mod.AddParam(session.NewBoolParameter("wifi.skip-broken",
 		"true",
 		"If true, dot11 packets with an invalid checksum will be skipped."))
 
+	journal.Register(monitorJournalKind, mod.restoreMonitor)
+	journal.Register(hostapdJournalKind, mod.restoreHostapd)
+
 	return mod
 }
 
@@ -345,7 +525,9 @@ func (mod *WiFiModule) setFrequencies(freqs []int) {
 
 func (mod *WiFiModule) Configure() error {
 	var ifName string
+	var supplicant string
 	var hopPeriod int
+	var apTTL, clientTTL int
 	var err error
 
 	if err, mod.region = mod.StringParam("wifi.region"); err != nil {
@@ -356,8 +538,19 @@ func (mod *WiFiModule) Configure() error {
 		return err
 	} else if err, mod.minRSSI = mod.IntParam("wifi.rssi.min"); err != nil {
 		return err
+	} else if err, supplicant = mod.StringParam("wifi.supplicant.socket"); err != nil {
+		return err
+	} else if err, apTTL = mod.IntParam("wifi.ttl.ap"); err != nil {
+		return err
+	} else if err, clientTTL = mod.IntParam("wifi.ttl.client"); err != nil {
+		return err
+	} else if apTTL < 0 || clientTTL < 0 {
+		return fmt.Errorf("wifi.ttl.ap and wifi.ttl.client can't be negative")
 	}
 
+	mod.Session.Expiry.Set(network.SourceWiFiAP, time.Duration(apTTL)*time.Second)
+	mod.Session.Expiry.Set(network.SourceWiFiClient, time.Duration(clientTTL)*time.Second)
+
 	if err, mod.shakesFile = mod.StringParam("wifi.handshakes.file"); err != nil {
 		return err
 	} else if mod.shakesFile != "" {
@@ -381,6 +574,10 @@ func (mod *WiFiModule) Configure() error {
 		if mod.handle, err = pcap.OpenOffline(mod.source); err != nil {
 			return fmt.Errorf("error while opening file %s: %s", mod.source, err)
 		}
+	} else if supplicant != "" {
+		if err = mod.openSupplicant(ifName, supplicant); err != nil {
+			return fmt.Errorf("could not connect to wpa_supplicant: %s", err)
+		}
 	} else {
 		if mod.region != "" {
 			if err := network.SetWiFiRegion(mod.region); err != nil {
@@ -428,6 +625,11 @@ func (mod *WiFiModule) Configure() error {
 				return fmt.Errorf("error while activating handle: %s", err)
 			}
 
+			// interfaces without an address are assumed to be already in monitor mode
+			if !mod.iface.IsMonitor() {
+				mod.trackMonitor(ifName)
+			}
+
 			break
 		}
 	}
@@ -440,7 +642,7 @@ func (mod *WiFiModule) Configure() error {
 
 	mod.hopPeriod = time.Duration(hopPeriod) * time.Millisecond
 
-	if mod.source == "" {
+	if mod.source == "" && mod.supplicant == nil {
 		// No channels setted, retrieve frequencies supported by the card
 		if len(mod.frequencies) == 0 {
 			if freqs, err := network.GetSupportedFrequencies(ifName); err != nil {
@@ -506,20 +708,50 @@ func (mod *WiFiModule) updateStats(dot11 *layers.Dot11, packet gopacket.Packet)
 	}
 }
 
+// onPacket feeds a captured frame through the parsing pipeline.
+func (mod *WiFiModule) onPacket(packet gopacket.Packet) {
+	// perform initial dot11 parsing and layers validation
+	if ok, radiotap, dot11 := packets.Dot11Parse(packet); ok {
+		// check FCS checksum
+		if mod.skipBroken && !dot11.ChecksumValid() {
+			mod.Debug("skipping dot11 packet with invalid checksum.")
+			return
+		}
+
+		mod.discoverProbes(radiotap, dot11, packet)
+		mod.discoverP2P(radiotap, dot11, packet)
+		mod.discoverAccessPoints(radiotap, dot11, packet)
+		mod.discoverClients(radiotap, dot11, packet)
+		mod.discoverHandshakes(radiotap, dot11, packet)
+		mod.trackInjectionTest(dot11)
+		mod.trackGroupPN(dot11)
+		mod.trackSequence(dot11)
+		mod.updateInfo(dot11, packet)
+		mod.updateStats(dot11, packet)
+	}
+}
+
 func (mod *WiFiModule) Start() error {
-	if err := mod.Configure(); err != nil {
+	if mod.rogue != nil {
+		return fmt.Errorf("the hostapd access point must be stopped with 'wifi.ap off' first")
+	} else if err := mod.Configure(); err != nil {
 		return err
 	}
 
 	mod.SetRunning(true, func() {
+		// start the pruner
+		go mod.stationPruner()
+
+		if mod.supplicant != nil {
+			mod.supplicantScanner()
+			return
+		}
+
 		// start channel hopper if needed
 		if mod.channel == 0 && mod.source == "" {
 			go mod.channelHopper()
 		}
 
-		// start the pruner
-		go mod.stationPruner()
-
 		mod.reads.Add(1)
 		defer mod.reads.Done()
 
@@ -536,21 +768,7 @@ func (mod *WiFiModule) Start() error {
 				mod.Session.Queue.TrackPacket(uint64(len(packet.Data())))
 			}
 
-			// perform initial dot11 parsing and layers validation
-			if ok, radiotap, dot11 := packets.Dot11Parse(packet); ok {
-				// check FCS checksum
-				if mod.skipBroken && !dot11.ChecksumValid() {
-					mod.Debug("skipping dot11 packet with invalid checksum.")
-					continue
-				}
-
-				mod.discoverProbes(radiotap, dot11, packet)
-				mod.discoverAccessPoints(radiotap, dot11, packet)
-				mod.discoverClients(radiotap, dot11, packet)
-				mod.discoverHandshakes(radiotap, dot11, packet)
-				mod.updateInfo(dot11, packet)
-				mod.updateStats(dot11, packet)
-			}
+			mod.onPacket(packet)
 		}
 
 		mod.pktSourceChanClosed = true
@@ -563,6 +781,12 @@ func (mod *WiFiModule) Stop() error {
 	return mod.SetRunning(false, func() {
 		// wait any pending write operation
 		mod.writes.Wait()
+		if mod.supplicant != nil {
+			mod.reads.Wait()
+			mod.supplicant.Close()
+			mod.supplicant = nil
+			return
+		}
 		// signal the main for loop we want to exit
 		if !mod.pktSourceChanClosed {
 			mod.pktSourceChan <- nil
@@ -570,5 +794,7 @@ func (mod *WiFiModule) Stop() error {
 		mod.reads.Wait()
 		// close the pcap handle to make the main for exit
 		mod.handle.Close()
+		mod.untrackMonitor()
+		mod.State.Store("channel", nil)
 	})
 }
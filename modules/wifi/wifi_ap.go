@@ -26,6 +26,8 @@ func (mod *WiFiModule) parseApConfig() (err error) {
 		return
 	} else if err, mod.apConfig.Encryption = mod.BoolParam("wifi.ap.encryption"); err != nil {
 		return
+	} else if err, mod.apMode = mod.StringParam("wifi.ap.mode"); err != nil {
+		return
 	}
 	return
 }
@@ -34,7 +36,9 @@ func (mod *WiFiModule) startAp() error {
 	// we need channel hopping and packet injection for this
 	if !mod.Running() {
 		return errNoRecon
-	} else if mod.apRunning {
+	} else if err := mod.canInject(); err != nil {
+		return err
+	} else if mod.apRunning || mod.rogue != nil {
 		return session.ErrAlreadyStarted
 	}
 
@@ -54,11 +58,11 @@ func (mod *WiFiModule) startAp() error {
 			mod.apConfig.Channel,
 			enc)
 
-		for seqn := uint16(0); mod.Running(); seqn++ {
+		for seqn := uint16(0); mod.Running() && mod.apRunning; seqn++ {
 			mod.writes.Add(1)
 			defer mod.writes.Done()
 
-			if err, pkt := packets.NewDot11Beacon(mod.apConfig, seqn); err != nil {
+			if err, pkt := packets.NewDot11BeaconAt(mod.apConfig, mod.injectSeq(mod.apConfig.BSSID, seqn), mod.beaconTimestamp()); err != nil {
 				mod.Error("could not create beacon packet: %s", err)
 			} else {
 				mod.injectPacket(pkt)
@@ -70,3 +74,16 @@ func (mod *WiFiModule) startAp() error {
 
 	return nil
 }
+
+func (mod *WiFiModule) stopAp() error {
+	if mod.rogue != nil {
+		return mod.stopHostapd()
+	} else if !mod.apRunning {
+		return session.ErrAlreadyStopped
+	}
+
+	mod.apRunning = false
+	mod.Info("stopped sending beacons.")
+
+	return nil
+}
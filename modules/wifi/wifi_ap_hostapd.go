@@ -0,0 +1,545 @@
+package wifi
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/core"
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+
+	"github.com/evilsocket/islazy/str"
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	hostapdBridge    = "bcap-br0"
+	hostapdLeaseTime = 12 * time.Hour
+	hostapdTimeout   = 10 * time.Second
+)
+
+type rogueAP struct {
+	sync.Mutex
+
+	iface      *network.Endpoint
+	passphrase string
+	sharing    string
+	upstream   string
+	gateway    net.IP
+	subnet     *net.IPNet
+	mitm       []string
+	confFile   string
+	cmd        *exec.Cmd
+	handle     *pcap.Handle
+	leases     map[string]net.IP
+	addressed  bool
+	bridged    bool
+	rules      [][]string
+	started    []string
+	done       chan bool
+	// called after every change to the system state, see trackHostapd
+	track func()
+}
+
+func (mod *WiFiModule) parseHostapdConfig() (err error, ap *rogueAP) {
+	var ifName, address, mitm string
+
+	ap = &rogueAP{
+		leases:  make(map[string]net.IP),
+		rules:   make([][]string, 0),
+		started: make([]string, 0),
+		done:    make(chan bool),
+	}
+
+	if err, ifName = mod.StringParam("wifi.interface"); err != nil {
+		return
+	} else if ifName == "" {
+		ap.iface = mod.Session.Interface
+	} else if ap.iface, err = network.FindInterface(ifName); err != nil {
+		return fmt.Errorf("could not find interface %s: %v", ifName, err), nil
+	}
+
+	if err, ap.passphrase = mod.StringParam("wifi.ap.passphrase"); err != nil {
+		return
+	} else if err, ap.sharing = mod.StringParam("wifi.ap.sharing"); err != nil {
+		return
+	} else if err, ap.upstream = mod.StringParam("wifi.ap.upstream"); err != nil {
+		return
+	} else if err, address = mod.StringParam("wifi.ap.address"); err != nil {
+		return
+	} else if err, mitm = mod.StringParam("wifi.ap.mitm"); err != nil {
+		return
+	}
+
+	if mod.apConfig.Encryption && (len(ap.passphrase) < 8 || len(ap.passphrase) > 63) {
+		return fmt.Errorf("wifi.ap.passphrase must be between 8 and 63 characters for WPA2 access points"), nil
+	} else if ap.sharing != "none" && ap.upstream == "" {
+		return fmt.Errorf("wifi.ap.upstream is required when wifi.ap.sharing is %s", ap.sharing), nil
+	} else if ap.upstream == ap.iface.Name() {
+		return fmt.Errorf("wifi.ap.upstream can't be the access point interface"), nil
+	}
+
+	if ap.gateway, ap.subnet, err = net.ParseCIDR(address); err != nil {
+		return fmt.Errorf("could not parse wifi.ap.address: %s", err), nil
+	} else if ap.gateway = ap.gateway.To4(); ap.gateway == nil {
+		return fmt.Errorf("wifi.ap.address must be an IPv4 address"), nil
+	} else if ones, _ := ap.subnet.Mask.Size(); ones > 30 {
+		return fmt.Errorf("wifi.ap.address subnet is too small"), nil
+	}
+
+	ap.mitm = str.Comma(mitm)
+
+	return nil, ap
+}
+
+func (ap *rogueAP) writeConfig(conf packets.Dot11ApConfig) error {
+	hwMode := "g"
+	if conf.Channel > 14 {
+		hwMode = "a"
+	}
+
+	lines := []string{
+		fmt.Sprintf("interface=%s", ap.iface.Name()),
+		"driver=nl80211",
+		fmt.Sprintf("ssid=%s", conf.SSID),
+		fmt.Sprintf("bssid=%s", conf.BSSID),
+		fmt.Sprintf("channel=%d", conf.Channel),
+		fmt.Sprintf("hw_mode=%s", hwMode),
+		"ieee80211n=1",
+		"wmm_enabled=1",
+	}
+
+	if ap.sharing == "bridge" {
+		lines = append(lines, fmt.Sprintf("bridge=%s", hostapdBridge))
+	}
+
+	if conf.Encryption {
+		lines = append(lines,
+			"wpa=2",
+			"wpa_key_mgmt=WPA-PSK",
+			"rsn_pairwise=CCMP",
+			fmt.Sprintf("wpa_passphrase=%s", ap.passphrase))
+	}
+
+	fp, err := ioutil.TempFile("", "bettercap-hostapd-")
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	ap.confFile = fp.Name()
+	_, err = fp.WriteString(strings.Join(lines, "\n") + "\n")
+	return err
+}
+
+func (ap *rogueAP) exec(args ...string) error {
+	if out, err := core.Exec(args[0], args[1:]); err != nil {
+		return fmt.Errorf("error while running '%s': %s %s", strings.Join(args, " "), err, out)
+	}
+	return nil
+}
+
+// rule adds an iptables rule that will be removed when the access point is stopped.
+func (ap *rogueAP) rule(args ...string) error {
+	if err := ap.exec(append([]string{"iptables"}, args...)...); err != nil {
+		return err
+	}
+	ap.rules = append(ap.rules, args)
+	ap.changed()
+	return nil
+}
+
+func (ap *rogueAP) changed() {
+	if ap.track != nil {
+		ap.track()
+	}
+}
+
+// undoRule returns the arguments to delete an iptables rule.
+func undoRule(rule []string) []string {
+	args := make([]string, len(rule))
+	copy(args, rule)
+	for j, arg := range args {
+		if arg == "-A" {
+			args[j] = "-D"
+		}
+	}
+	return args
+}
+
+func (mod *WiFiModule) setupSharing(ap *rogueAP) error {
+	switch ap.sharing {
+	case "bridge":
+		if err := ap.exec("ip", "link", "add", "name", hostapdBridge, "type", "bridge"); err != nil {
+			return err
+		}
+		ap.bridged = true
+		ap.changed()
+
+		if err := ap.exec("ip", "link", "set", ap.upstream, "master", hostapdBridge); err != nil {
+			return err
+		} else if err := ap.exec("ip", "link", "set", hostapdBridge, "up"); err != nil {
+			return err
+		}
+		mod.Info("bridging access point to %s", tui.Bold(ap.upstream))
+
+	case "nat":
+		if !mod.Session.Firewall.IsForwardingEnabled() {
+			mod.Info("enabling forwarding")
+			mod.Session.Firewall.EnableForwarding(true)
+		}
+
+		if err := ap.rule("-t", "nat", "-A", "POSTROUTING", "-s", ap.subnet.String(), "-o", ap.upstream, "-j", "MASQUERADE"); err != nil {
+			return err
+		} else if err := ap.rule("-A", "FORWARD", "-i", ap.iface.Name(), "-o", ap.upstream, "-j", "ACCEPT"); err != nil {
+			return err
+		} else if err := ap.rule("-A", "FORWARD", "-i", ap.upstream, "-o", ap.iface.Name(), "-m", "state", "--state", "RELATED,ESTABLISHED", "-j", "ACCEPT"); err != nil {
+			return err
+		}
+		mod.Info("sharing %s connectivity via NAT", tui.Bold(ap.upstream))
+	}
+
+	return nil
+}
+
+func (mod *WiFiModule) teardownSharing(ap *rogueAP) {
+	for i := len(ap.rules) - 1; i >= 0; i-- {
+		if err := ap.exec(append([]string{"iptables"}, undoRule(ap.rules[i])...)...); err != nil {
+			mod.Warning("%s", err)
+		}
+	}
+	ap.rules = nil
+
+	if ap.bridged {
+		if err := ap.exec("ip", "link", "delete", hostapdBridge, "type", "bridge"); err != nil {
+			mod.Warning("%s", err)
+		}
+		ap.bridged = false
+	} else if ap.addressed {
+		if err := ap.exec("ip", "addr", "del", fmt.Sprintf("%s/%d", ap.gateway, maskBits(ap.subnet)), "dev", ap.iface.Name()); err != nil {
+			mod.Warning("%s", err)
+		}
+		ap.addressed = false
+	}
+}
+
+func maskBits(subnet *net.IPNet) int {
+	ones, _ := subnet.Mask.Size()
+	return ones
+}
+
+// watchHostapd parses the output of hostapd, signaling when the access point
+// is enabled and pushing an event for every station connecting to it.
+func (mod *WiFiModule) watchHostapd(ap *rogueAP, scanner *bufio.Scanner, enabled chan bool) {
+	for scanner.Scan() {
+		line := str.Trim(scanner.Text())
+		mod.Debug("hostapd: %s", line)
+
+		if strings.Contains(line, "AP-ENABLED") {
+			select {
+			case enabled <- true:
+			default:
+			}
+		} else if fields := strings.Fields(line); len(fields) >= 2 {
+			tag := ""
+			switch fields[len(fields)-2] {
+			case "AP-STA-CONNECTED":
+				tag = "wifi.rogue.station.connected"
+			case "AP-STA-DISCONNECTED":
+				tag = "wifi.rogue.station.disconnected"
+			}
+
+			if tag != "" {
+				mac := network.NormalizeMac(fields[len(fields)-1])
+				mod.Session.Events.Add(tag, RogueStationEvent{
+					Address: mac,
+					Vendor:  network.ManufLookup(mac),
+				})
+			}
+		}
+	}
+
+	close(ap.done)
+}
+
+func (ap *rogueAP) leaseFor(mac string, requested net.IP) net.IP {
+	ap.Lock()
+	defer ap.Unlock()
+
+	if ip, found := ap.leases[mac]; found {
+		return ip
+	}
+
+	used := make(map[string]bool)
+	for _, ip := range ap.leases {
+		used[ip.String()] = true
+	}
+
+	base := binary.BigEndian.Uint32(ap.subnet.IP.To4())
+	size := uint32(1) << uint(32-maskBits(ap.subnet))
+	free := func(ip net.IP) bool {
+		// skip the network and broadcast addresses
+		if offset := binary.BigEndian.Uint32(ip) - base; offset == 0 || offset >= size-1 {
+			return false
+		}
+		return !ip.Equal(ap.gateway) && !used[ip.String()]
+	}
+
+	if requested = requested.To4(); requested != nil && ap.subnet.Contains(requested) && free(requested) {
+		ap.leases[mac] = requested
+		return requested
+	}
+
+	for i := uint32(1); i < size-1; i++ {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, base+i)
+		if free(ip) {
+			ap.leases[mac] = ip
+			return ip
+		}
+	}
+
+	return nil
+}
+
+func (mod *WiFiModule) onDHCPRequest(ap *rogueAP, req *layers.DHCPv4) {
+	reply := layers.DHCPMsgTypeUnspecified
+	mac := req.ClientHWAddr.String()
+	requested := packets.DHCP4RequestedIP(req)
+	lease := packets.DHCP4Lease{
+		ServerIP: ap.gateway,
+		ServerHW: ap.iface.HW,
+		Mask:     ap.subnet.Mask,
+		Router:   ap.gateway,
+		DNS:      ap.gateway,
+		Duration: hostapdLeaseTime,
+	}
+
+	switch packets.DHCP4MessageType(req) {
+	case layers.DHCPMsgTypeDiscover:
+		reply = layers.DHCPMsgTypeOffer
+		lease.ClientIP = ap.leaseFor(mac, requested)
+
+	case layers.DHCPMsgTypeRequest:
+		lease.ClientIP = ap.leaseFor(mac, requested)
+		if lease.ClientIP == nil || (requested != nil && !requested.Equal(lease.ClientIP)) {
+			reply = layers.DHCPMsgTypeNak
+		} else {
+			reply = layers.DHCPMsgTypeAck
+		}
+
+	default:
+		return
+	}
+
+	// without a lease there's nothing to offer, but the requests are still
+	// refused with a NAK so that the client doesn't wait for the timeout
+	if lease.ClientIP == nil {
+		mod.Warning("no more addresses available in %s for %s", ap.subnet, mac)
+		if reply != layers.DHCPMsgTypeNak {
+			return
+		}
+	}
+
+	if err, raw := packets.NewDHCP4Reply(req, reply, lease); err != nil {
+		mod.Error("could not create DHCP %s: %s", reply, err)
+	} else if err = ap.handle.WritePacketData(raw); err != nil {
+		mod.Error("could not send DHCP %s: %s", reply, err)
+	} else if reply == layers.DHCPMsgTypeAck {
+		mod.Session.Events.Add("wifi.rogue.lease", RogueStationEvent{
+			Address: mac,
+			IP:      lease.ClientIP.String(),
+			Vendor:  network.ManufLookup(mac),
+		})
+	}
+}
+
+func (mod *WiFiModule) dhcpServer(ap *rogueAP) {
+	mod.Info("serving DHCP leases for %s", ap.subnet)
+
+	src := gopacket.NewPacketSource(ap.handle, ap.handle.LinkType())
+	for packet := range src.Packets() {
+		if layer := packet.Layer(layers.LayerTypeDHCPv4); layer != nil {
+			if req := layer.(*layers.DHCPv4); req.Operation == layers.DHCPOpRequest {
+				mod.onDHCPRequest(ap, req)
+			}
+		}
+	}
+}
+
+func (mod *WiFiModule) startMitm(ap *rogueAP) {
+	gateway := ap.gateway.String()
+	for _, name := range ap.mitm {
+		if err, m := mod.Session.Module(name); err != nil {
+			mod.Warning("%s", err)
+			continue
+		} else if m.Running() {
+			mod.Warning("%s is already running, its configuration won't be updated", name)
+			continue
+		}
+
+		if param := name + ".address"; mod.Session.Env.Has(param) {
+			if err := mod.Session.RunAs(fmt.Sprintf("set %s %s", param, gateway), mod.Name()); err != nil {
+				mod.Warning("could not set %s: %s", param, err)
+			}
+		}
+
+		if err := mod.Session.Run(name + " on"); err != nil {
+			mod.Error("could not start %s: %s", name, err)
+		} else {
+			ap.started = append(ap.started, name)
+		}
+	}
+}
+
+func (mod *WiFiModule) stopMitm(ap *rogueAP) {
+	for _, name := range ap.started {
+		if err := mod.Session.Run(name + " off"); err != nil {
+			mod.Warning("could not stop %s: %s", name, err)
+		}
+	}
+	ap.started = nil
+}
+
+func (mod *WiFiModule) startHostapd() error {
+	if mod.Running() {
+		return fmt.Errorf("wifi.recon must be stopped in hostapd mode since the interface can't be in monitor mode")
+	} else if mod.rogue != nil {
+		return session.ErrAlreadyStarted
+	} else if !core.HasBinary("hostapd") {
+		return fmt.Errorf("hostapd mode requires the hostapd binary to be installed")
+	}
+
+	err, ap := mod.parseHostapdConfig()
+	if err != nil {
+		return err
+	} else if !mod.Session.SSIDInScope(mod.apConfig.SSID, "hostapd access point") {
+		return fmt.Errorf("%s is not in %s", mod.apConfig.SSID, session.ScopeSSIDsParam)
+	} else if mod.Session.IsDryRun() {
+		mod.Session.DryRunAction("hostapd access point %s on channel %d from %s", mod.apConfig.SSID, mod.apConfig.Channel, ap.iface.Name())
+		return nil
+	} else if err = ap.writeConfig(mod.apConfig); err != nil {
+		return err
+	}
+
+	ap.track = func() {
+		mod.trackHostapd(ap)
+	}
+	ap.changed()
+
+	fail := func(err error) error {
+		mod.cleanupHostapd(ap)
+		return err
+	}
+
+	if err = mod.setupSharing(ap); err != nil {
+		return fail(err)
+	}
+
+	ap.cmd = exec.Command("hostapd", ap.confFile)
+	stdout, err := ap.cmd.StdoutPipe()
+	if err != nil {
+		return fail(err)
+	} else if err = ap.cmd.Start(); err != nil {
+		return fail(err)
+	}
+	ap.changed()
+
+	enabled := make(chan bool, 1)
+	go mod.watchHostapd(ap, bufio.NewScanner(stdout), enabled)
+
+	select {
+	case <-enabled:
+	case <-ap.done:
+		return fail(fmt.Errorf("hostapd exited, make sure the interface supports AP mode (use debug mode for details)"))
+	case <-time.After(hostapdTimeout):
+		return fail(fmt.Errorf("timeout while waiting for hostapd to enable the access point"))
+	}
+
+	if ap.sharing != "bridge" {
+		if err = ap.exec("ip", "addr", "add", fmt.Sprintf("%s/%d", ap.gateway, maskBits(ap.subnet)), "dev", ap.iface.Name()); err != nil {
+			return fail(err)
+		}
+
+		ap.addressed = true
+		ap.changed()
+
+		if ap.handle, err = pcap.OpenLive(ap.iface.Name(), 65536, true, pcap.BlockForever); err != nil {
+			return fail(err)
+		} else if err = ap.handle.SetBPFFilter("udp and dst port 67"); err != nil {
+			return fail(err)
+		}
+
+		go mod.dhcpServer(ap)
+
+		if ap.iface.Name() != mod.Session.Interface.Name() {
+			mod.Warning("%s is not the session interface, modules might not intercept access point traffic", ap.iface.Name())
+		}
+		mod.startMitm(ap)
+	}
+
+	enc := tui.Yellow("WPA2")
+	if !mod.apConfig.Encryption {
+		enc = tui.Green("Open")
+	}
+	mod.Info("access point %s (%s) is up on channel %d (%s).",
+		tui.Bold(mod.apConfig.SSID),
+		mod.apConfig.BSSID.String(),
+		mod.apConfig.Channel,
+		enc)
+
+	mod.rogue = ap
+
+	return nil
+}
+
+func (mod *WiFiModule) cleanupHostapd(ap *rogueAP) {
+	mod.stopMitm(ap)
+
+	if ap.cmd != nil && ap.cmd.Process != nil {
+		ap.cmd.Process.Signal(os.Interrupt)
+		select {
+		case <-ap.done:
+		case <-time.After(hostapdTimeout):
+			ap.cmd.Process.Kill()
+		}
+		ap.cmd.Wait()
+	}
+
+	if ap.handle != nil {
+		ap.handle.Close()
+	}
+
+	mod.teardownSharing(ap)
+
+	if ap.confFile != "" {
+		os.Remove(ap.confFile)
+	}
+
+	mod.untrackHostapd(ap)
+}
+
+func (mod *WiFiModule) stopHostapd() error {
+	if mod.rogue == nil {
+		return session.ErrAlreadyStopped
+	}
+
+	mod.cleanupHostapd(mod.rogue)
+	mod.rogue = nil
+	mod.Info("access point stopped")
+
+	return nil
+}
@@ -5,19 +5,20 @@ import (
 	"fmt"
 	"net"
 	"sort"
+	"time"
 
 	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/packets"
 )
 
 func (mod *WiFiModule) sendAssocPacket(ap *network.AccessPoint) {
-	if err, pkt := packets.NewDot11Auth(mod.iface.HW, ap.HW, 1); err != nil {
+	if err, pkt := packets.NewDot11Auth(mod.iface.HW, ap.HW, mod.injectSeq(mod.iface.HW, 1)); err != nil {
 		mod.Error("cloud not create auth packet: %s", err)
 	} else {
 		mod.injectPacket(pkt)
 	}
 
-	if err, pkt := packets.NewDot11AssociationRequest(mod.iface.HW, ap.HW, ap.ESSID(), 1); err != nil {
+	if err, pkt := packets.NewDot11AssociationRequest(mod.iface.HW, ap.HW, ap.ESSID(), mod.injectSeq(mod.iface.HW, 1)); err != nil {
 		mod.Error("cloud not create association request packet: %s", err)
 	} else {
 		mod.injectPacket(pkt)
@@ -51,7 +52,31 @@ func (mod *WiFiModule) doAssocOpen() bool {
 	return mod.assocOpen
 }
 
+// assocBudget returns the maximum number of failed attempts for each access
+// point and the initial backoff between them.
+func (mod *WiFiModule) assocBudget() (int, time.Duration) {
+	max := 5
+	if err, v := mod.IntParam("wifi.assoc.max"); err != nil {
+		mod.Warning("%v", err)
+	} else {
+		max = v
+	}
+
+	backoff := 30
+	if err, v := mod.IntParam("wifi.assoc.backoff"); err != nil {
+		mod.Warning("%v", err)
+	} else {
+		backoff = v
+	}
+
+	return max, time.Duration(backoff) * time.Second
+}
+
 func (mod *WiFiModule) startAssoc(to net.HardwareAddr) error {
+	if err := mod.canInject(); err != nil {
+		return err
+	}
+
 	// parse skip list
 	if err, assocSkip := mod.StringParam("wifi.assoc.skip"); err != nil {
 		return err
@@ -89,6 +114,8 @@ func (mod *WiFiModule) startAssoc(to net.HardwareAddr) error {
 		return fmt.Errorf("%s is an unknown BSSID or it is in the association skip list.", to.String())
 	}
 
+	max, backoff := mod.assocBudget()
+
 	go func() {
 		mod.writes.Add(1)
 		defer mod.writes.Done()
@@ -110,8 +137,11 @@ func (mod *WiFiModule) startAssoc(to net.HardwareAddr) error {
 
 				if ap.IsOpen() && !mod.doAssocOpen() {
 					mod.Debug("skipping association for open network %s (wifi.assoc.open is false)", ap.ESSID())
+				} else if ok, why := mod.assoc.attempt(ap.BSSID(), max, backoff, !isBcast); !ok {
+					mod.Debug("skipping association for %s: %s", ap.ESSID(), why)
 				} else {
 					logger("sending association request to AP %s (channel:%d encryption:%s)", ap.ESSID(), ap.Channel, ap.Encryption)
+					mod.Meter("associate", "%s", ap.ESSID())
 
 					mod.onChannel(ap.Channel, func() {
 						mod.sendAssocPacket(ap)
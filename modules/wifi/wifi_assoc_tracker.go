@@ -0,0 +1,148 @@
+package wifi
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// how long we wait for the first EAPOL frame after an association request
+	assocResponseWindow = 5 * time.Second
+	// the maximum time between two attempts to the same access point
+	assocMaxBackoff = 30 * time.Minute
+)
+
+type assocResult struct {
+	Attempts    int
+	Failures    int
+	PMKID       bool
+	LastAttempt time.Time
+	NextAttempt time.Time
+
+	pending bool
+}
+
+// assocTracker keeps the outcome of the association requests sent to each
+// access point, in order to back off from those that don't answer with a
+// PMKID and to give up on them once their retry budget is exhausted.
+type assocTracker struct {
+	sync.Mutex
+	results map[string]*assocResult
+}
+
+func newAssocTracker() *assocTracker {
+	return &assocTracker{
+		results: make(map[string]*assocResult),
+	}
+}
+
+// settle must be called with the lock held, it counts as failed the pending
+// attempt if its response window expired without a PMKID.
+func (t *assocTracker) settle(r *assocResult, now time.Time, backoff time.Duration) {
+	if !r.pending || now.Sub(r.LastAttempt) < assocResponseWindow {
+		return
+	}
+
+	r.pending = false
+	r.Failures++
+
+	// exponential backoff, doubling after every failure
+	wait := backoff
+	for i := 1; i < r.Failures && wait < assocMaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > assocMaxBackoff {
+		wait = assocMaxBackoff
+	}
+	r.NextAttempt = r.LastAttempt.Add(wait)
+}
+
+// attempt returns true and records a new attempt if an association request
+// can be sent to the BSSID, otherwise the reason why it should be skipped, if
+// force is true budget and backoff are ignored.
+func (t *assocTracker) attempt(bssid string, max int, backoff time.Duration, force bool) (bool, string) {
+	t.Lock()
+	defer t.Unlock()
+
+	now := time.Now()
+	r, found := t.results[bssid]
+	if !found {
+		r = &assocResult{}
+		t.results[bssid] = r
+	}
+	t.settle(r, now, backoff)
+
+	if !force {
+		if r.PMKID {
+			return false, "PMKID already captured"
+		} else if max > 0 && r.Failures >= max {
+			return false, fmt.Sprintf("no PMKID after %d attempts", r.Failures)
+		} else if r.pending {
+			return false, "still waiting for a response"
+		} else if now.Before(r.NextAttempt) {
+			return false, fmt.Sprintf("backing off for %s", r.NextAttempt.Sub(now).Round(time.Second))
+		}
+	}
+
+	r.Attempts++
+	r.LastAttempt = now
+	r.pending = true
+	return true, ""
+}
+
+// onPMKID marks the association to the BSSID as successful.
+func (t *assocTracker) onPMKID(bssid string) {
+	t.Lock()
+	defer t.Unlock()
+
+	if r, found := t.results[bssid]; found {
+		r.PMKID = true
+		r.pending = false
+	}
+}
+
+func (t *assocTracker) Len() int {
+	t.Lock()
+	defer t.Unlock()
+	return len(t.results)
+}
+
+func (t *assocTracker) clear() {
+	t.Lock()
+	defer t.Unlock()
+	t.results = make(map[string]*assocResult)
+}
+
+// status returns a short description of the associations to the BSSID for
+// wifi.show, or an empty string if we never tried.
+func (t *assocTracker) status(bssid string, max int, backoff time.Duration) string {
+	t.Lock()
+	defer t.Unlock()
+
+	r, found := t.results[bssid]
+	if !found {
+		return ""
+	}
+
+	now := time.Now()
+	t.settle(r, now, backoff)
+
+	budget := fmt.Sprintf("%d", r.Attempts)
+	if max > 0 {
+		budget = fmt.Sprintf("%d/%d", r.Failures, max)
+	}
+
+	if r.PMKID {
+		return tui.Green(fmt.Sprintf("pmkid (%d)", r.Attempts))
+	} else if max > 0 && r.Failures >= max {
+		return tui.Red("gave up")
+	} else if r.pending {
+		return tui.Yellow("waiting")
+	} else if now.Before(r.NextAttempt) {
+		return tui.Dim(fmt.Sprintf("retry in %s (%s)", r.NextAttempt.Sub(now).Round(time.Second), budget))
+	}
+	return tui.Dim(fmt.Sprintf("failed (%s)", budget))
+}
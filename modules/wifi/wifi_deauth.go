@@ -5,13 +5,21 @@ import (
 	"fmt"
 	"net"
 	"sort"
-	"time"
 
 	"github.com/bettercap/bettercap/network"
 	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket/layers"
 )
 
 func (mod *WiFiModule) injectPacket(data []byte) {
+	if !mod.Session.PacketInScope(data, layers.LayerTypeRadioTap) {
+		return
+	} else if mod.Session.IsDryRun() {
+		mod.Session.DryRunPacket(data, layers.LayerTypeRadioTap)
+		return
+	}
+
 	if err := mod.handle.WritePacketData(data); err != nil {
 		mod.Error("could not inject WiFi packet: %s", err)
 		mod.Session.Queue.TrackError()
@@ -19,19 +27,19 @@ func (mod *WiFiModule) injectPacket(data []byte) {
 		mod.Session.Queue.TrackSent(uint64(len(data)))
 	}
 	// let the network card breath a little
-	time.Sleep(10 * time.Millisecond)
+	mod.injectWait()
 }
 
 func (mod *WiFiModule) sendDeauthPacket(ap net.HardwareAddr, client net.HardwareAddr) {
 	for seq := uint16(0); seq < 64 && mod.Running(); seq++ {
-		if err, pkt := packets.NewDot11Deauth(ap, client, ap, seq); err != nil {
+		if err, pkt := packets.NewDot11Deauth(ap, client, ap, mod.injectSeq(client, seq)); err != nil {
 			mod.Error("could not create deauth packet: %s", err)
 			continue
 		} else {
 			mod.injectPacket(pkt)
 		}
 
-		if err, pkt := packets.NewDot11Deauth(client, ap, ap, seq); err != nil {
+		if err, pkt := packets.NewDot11Deauth(client, ap, ap, mod.injectSeq(ap, seq)); err != nil {
 			mod.Error("could not create deauth packet: %s", err)
 			continue
 		} else {
@@ -68,6 +76,10 @@ func (mod *WiFiModule) doDeauthOpen() bool {
 }
 
 func (mod *WiFiModule) startDeauth(to net.HardwareAddr) error {
+	if err := mod.canInject(); err != nil {
+		return err
+	}
+
 	// parse skip list
 	if err, deauthSkip := mod.StringParam("wifi.deauth.skip"); err != nil {
 		return err
@@ -138,6 +150,7 @@ func (mod *WiFiModule) startDeauth(to net.HardwareAddr) error {
 					mod.Debug("skipping deauth for open network %s (wifi.deauth.open is false)", ap.ESSID())
 				} else {
 					logger("deauthing client %s from AP %s (channel:%d encryption:%s)", client.String(), ap.ESSID(), ap.Channel, ap.Encryption)
+					mod.Meter("deauth", "%s from %s", client.String(), ap.ESSID())
 
 					mod.onChannel(ap.Channel, func() {
 						mod.sendDeauthPacket(ap.HW, client.HW)
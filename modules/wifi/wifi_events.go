@@ -23,4 +23,11 @@ type HandshakeEvent struct {
 	AP         string `json:"ap"`
 	Station    string `json:"station"`
 	PMKID      []byte `json:"pmkid"`
+	Messages   string `json:"messages"`
+}
+
+type RogueStationEvent struct {
+	Address string `json:"mac"`
+	IP      string `json:"address"`
+	Vendor  string `json:"vendor"`
 }
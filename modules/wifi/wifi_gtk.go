@@ -0,0 +1,199 @@
+package wifi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+type groupKey struct {
+	Station string
+	GTK     []byte
+	Index   int
+}
+
+// groupKeys keeps track of the GTKs we derived and of the highest group
+// packet number seen for each access point, injected frames need a greater
+// one or receivers will drop them as replays.
+type groupKeys struct {
+	sync.Mutex
+	keys map[string]*groupKey
+	pn   map[string]uint64
+	seq  uint16
+}
+
+func newGroupKeys() *groupKeys {
+	return &groupKeys{
+		keys: make(map[string]*groupKey),
+		pn:   make(map[string]uint64),
+	}
+}
+
+// deriveGTK looks for a client of the access point with both M2 and M3 captured, verifies
+// the PSK against the MIC of M2 and decrypts the GTK from the key data of M3.
+func deriveGTK(ap *network.AccessPoint, psk string) (error, *groupKey) {
+	pmk := packets.Dot11PMK(psk, ap.ESSID())
+	lastErr := fmt.Errorf("no client of %s with messages M2 and M3 of the handshake", ap.BSSID())
+
+	for _, client := range ap.Clients() {
+		hs := client.Handshake
+		hs.Lock()
+		responses := append([]gopacket.Packet{}, hs.Responses...)
+		confirmations := append([]gopacket.Packet{}, hs.Confirmations...)
+		hs.Unlock()
+
+		for _, m3 := range confirmations {
+			m3Key, _ := packets.Dot11EAPOLRaw(m3)
+			if m3Key == nil {
+				continue
+			}
+
+			for _, m2 := range responses {
+				m2Key, m2Raw := packets.Dot11EAPOLRaw(m2)
+				if m2Key == nil {
+					continue
+				}
+
+				keys := packets.Dot11DeriveKeys(pmk, ap.HW, client.HW, m3Key.Nonce, m2Key.Nonce)
+				if err, valid := packets.Dot11VerifyMIC(keys.KCK, m2Raw); err != nil {
+					lastErr = err
+					continue
+				} else if !valid {
+					lastErr = fmt.Errorf("MIC verification failed, wrong PSK?")
+					continue
+				}
+
+				err, keyData := packets.Dot11AESUnwrap(keys.KEK, m3Key.EncryptedKeyData)
+				if err != nil {
+					return err, nil
+				}
+
+				err, gtk, idx := packets.Dot11ParseGTK(keyData)
+				if err != nil {
+					return err, nil
+				}
+
+				return nil, &groupKey{
+					Station: client.BSSID(),
+					GTK:     gtk,
+					Index:   idx,
+				}
+			}
+		}
+	}
+
+	return lastErr, nil
+}
+
+// trackGroupPN keeps track of the packet numbers of protected group frames sent by the access points.
+func (mod *WiFiModule) trackGroupPN(dot11 *layers.Dot11) {
+	if dot11.Type.MainType() != layers.Dot11TypeData || !dot11.Flags.FromDS() || dot11.Flags.ToDS() || !dot11.Flags.WEP() {
+		return
+	} else if dot11.Address1[0]&0x01 == 0 {
+		return
+	}
+
+	if ok, pn := packets.Dot11CCMPPacketNumber(dot11.Payload); ok {
+		bssid := dot11.Address2.String()
+		mod.gtk.Lock()
+		if pn > mod.gtk.pn[bssid] {
+			mod.gtk.pn[bssid] = pn
+		}
+		mod.gtk.Unlock()
+	}
+}
+
+func (mod *WiFiModule) getGTK(bssid net.HardwareAddr) error {
+	err, psk := mod.StringParam("wifi.gtk.psk")
+	if err != nil {
+		return err
+	} else if psk == "" {
+		return fmt.Errorf("wifi.gtk.psk is empty")
+	}
+
+	ap, found := mod.Session.WiFi.Get(bssid.String())
+	if !found {
+		return fmt.Errorf("could not find access point %s", bssid)
+	}
+
+	err, key := deriveGTK(ap, psk)
+	if err != nil {
+		return err
+	}
+
+	mod.gtk.Lock()
+	mod.gtk.keys[ap.BSSID()] = key
+	mod.gtk.Unlock()
+
+	mod.Info("GTK of %s (%s) from the handshake of %s: %s (key index %d)",
+		tui.Bold(ap.ESSID()),
+		ap.BSSID(),
+		key.Station,
+		tui.Red(hex.EncodeToString(key.GTK)),
+		key.Index)
+
+	return nil
+}
+
+func (mod *WiFiModule) injectGroupFrame(bssid net.HardwareAddr, frame []byte) error {
+	if err, unsafe := mod.BoolParam("wifi.gtk.unsafe"); err != nil {
+		return err
+	} else if !unsafe {
+		return fmt.Errorf("injecting forged group frames is experimental and can disrupt the network, set wifi.gtk.unsafe to true to enable it")
+	} else if !mod.Running() {
+		return errNoRecon
+	} else if err := mod.canInject(); err != nil {
+		return err
+	} else if len(frame) < 14 {
+		return fmt.Errorf("ethernet frame too short (%d bytes)", len(frame))
+	}
+
+	ap, found := mod.Session.WiFi.Get(bssid.String())
+	if !found {
+		return fmt.Errorf("could not find access point %s", bssid)
+	}
+
+	mod.gtk.Lock()
+	key, found := mod.gtk.keys[ap.BSSID()]
+	pn := mod.gtk.pn[ap.BSSID()] + 1
+	mod.gtk.pn[ap.BSSID()] = pn
+	mod.gtk.seq++
+	seq := mod.gtk.seq
+	mod.gtk.Unlock()
+
+	if !found {
+		return fmt.Errorf("GTK of %s not known, use wifi.gtk %s first", ap.BSSID(), ap.BSSID())
+	} else if pn == 1 {
+		mod.Warning("no group traffic from %s observed yet, clients might drop the frame as a replay", ap.BSSID())
+	}
+
+	dst := net.HardwareAddr(frame[0:6])
+	src := net.HardwareAddr(frame[6:12])
+	etherType := layers.EthernetType(uint16(frame[12])<<8 | uint16(frame[13]))
+	if dst[0]&0x01 == 0 {
+		mod.Warning("%s is not a group address, the frame will be sent to broadcast", dst)
+		dst = network.BroadcastHw
+	}
+
+	err, raw := packets.NewDot11GroupData(ap.HW, src, dst, etherType, frame[14:], key.GTK, key.Index, pn, seq)
+	if err != nil {
+		return err
+	}
+
+	mod.onChannel(ap.Channel, func() {
+		mod.injectPacket(raw)
+	})
+
+	mod.Info("injected %d bytes group frame from %s to %s via %s (pn %d)", len(raw), src, dst, ap.BSSID(), pn)
+
+	return nil
+}
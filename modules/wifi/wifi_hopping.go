@@ -17,6 +17,7 @@ func (mod *WiFiModule) onChannel(channel int, cb func()) {
 		mod.Warning("error while hopping to channel %d: %s", channel, err)
 	} else {
 		mod.Debug("hopped on channel %d", channel)
+		mod.State.Store("channel", channel)
 	}
 
 	cb()
@@ -55,6 +56,8 @@ func (mod *WiFiModule) channelHopper() {
 			mod.chanLock.Lock()
 			if err := network.SetInterfaceChannel(mod.iface.Name(), channel); err != nil {
 				mod.Warning("error while hopping to channel %d: %s", channel, err)
+			} else {
+				mod.State.Store("channel", channel)
 			}
 			mod.chanLock.Unlock()
 
@@ -0,0 +1,114 @@
+package wifi
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket/layers"
+)
+
+const dot11SeqMask = 0x0fff
+
+// injectTiming keeps the state needed to make injected frames look
+// like they belong to the transmitters they are spoofing.
+type injectTiming struct {
+	sync.Mutex
+	seqs map[string]uint16
+	// fake boot time of the access points we pretend to be
+	booted time.Time
+}
+
+func newInjectTiming() *injectTiming {
+	// pretend the access point has been up for up to a week
+	uptime := time.Duration(rand.Int63n(int64(7 * 24 * time.Hour)))
+	return &injectTiming{
+		seqs:   make(map[string]uint16),
+		booted: time.Now().Add(-uptime),
+	}
+}
+
+func (mod *WiFiModule) injectSeqMode() string {
+	if err, mode := mod.StringParam("wifi.inject.seq"); err != nil {
+		mod.Warning("%v", err)
+		return "default"
+	} else {
+		return mode
+	}
+}
+
+// injectSeq returns the sequence number to use for the next frame spoofing
+// the transmitter tx, seq is the one the attack would normally use.
+func (mod *WiFiModule) injectSeq(tx net.HardwareAddr, seq uint16) uint16 {
+	mode := mod.injectSeqMode()
+	if mode == "default" {
+		return seq & dot11SeqMask
+	}
+
+	mod.timing.Lock()
+	defer mod.timing.Unlock()
+
+	key := tx.String()
+	last, found := mod.timing.seqs[key]
+	if !found {
+		// nothing observed from tx yet (or random mode), pick a random starting point
+		last = uint16(rand.Intn(dot11SeqMask + 1))
+	}
+
+	next := (last + 1) & dot11SeqMask
+	mod.timing.seqs[key] = next
+	return next
+}
+
+// trackSequence records the last sequence number used by each transmitter
+// so that wifi.inject.seq follow can continue from it.
+func (mod *WiFiModule) trackSequence(dot11 *layers.Dot11) {
+	if dot11.Address2 == nil || mod.injectSeqMode() != "follow" {
+		return
+	}
+
+	mod.timing.Lock()
+	defer mod.timing.Unlock()
+	mod.timing.seqs[dot11.Address2.String()] = dot11.SequenceNumber & dot11SeqMask
+}
+
+// beaconTimestamp returns the tsf value to advertise in beacons, zero
+// unless wifi.inject.tsf is enabled.
+func (mod *WiFiModule) beaconTimestamp() uint64 {
+	if err, enabled := mod.BoolParam("wifi.inject.tsf"); err != nil {
+		mod.Warning("%v", err)
+		return 0
+	} else if !enabled {
+		return 0
+	}
+	return uint64(time.Since(mod.timing.booted) / time.Microsecond)
+}
+
+// injectWait sleeps between injected frames for wifi.inject.delay plus a
+// random amount of time up to wifi.inject.jitter.
+func (mod *WiFiModule) injectWait() {
+	delay := 10
+	jitter := 0
+
+	if err, n := mod.IntParam("wifi.inject.delay"); err != nil {
+		mod.Warning("%v", err)
+	} else {
+		delay = n
+	}
+
+	if err, n := mod.IntParam("wifi.inject.jitter"); err != nil {
+		mod.Warning("%v", err)
+	} else {
+		jitter = n
+	}
+
+	wait := time.Duration(delay) * time.Millisecond
+	if jitter > 0 {
+		wait += time.Duration(rand.Int63n(int64(jitter) * int64(time.Millisecond)))
+	}
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
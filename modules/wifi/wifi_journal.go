@@ -0,0 +1,125 @@
+package wifi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bettercap/bettercap/core"
+	"github.com/bettercap/bettercap/journal"
+	"github.com/bettercap/bettercap/network"
+)
+
+const monitorJournalKind = "wifi.monitor"
+
+// trackMonitor saves to the journal that we put the interface in monitor mode.
+func (mod *WiFiModule) trackMonitor(ifName string) {
+	if err := journal.Track(monitorJournalKind, ifName, map[string]string{"iface": ifName}); err != nil {
+		mod.Warning("could not update the journal: %s", err)
+	} else {
+		mod.monitorIface = ifName
+	}
+}
+
+// untrackMonitor is called when the pcap handle is closed, which releases the monitor mode.
+func (mod *WiFiModule) untrackMonitor() {
+	if mod.monitorIface != "" {
+		if err := journal.Untrack(monitorJournalKind, mod.monitorIface); err != nil {
+			mod.Warning("could not update the journal: %s", err)
+		}
+		mod.monitorIface = ""
+	}
+}
+
+// restoreMonitor puts back in managed mode an interface left in monitor mode by a previous session.
+func (mod *WiFiModule) restoreMonitor(args map[string]string) error {
+	ifName := args["iface"]
+	if err := network.SetInterfaceManaged(ifName); err != nil {
+		return err
+	}
+
+	mod.Info("interface %s set back to managed mode", ifName)
+	return journal.Untrack(monitorJournalKind, ifName)
+}
+
+const hostapdJournalKind = "wifi.hostapd"
+
+// trackHostapd saves to the journal the processes, routes and firewall rules
+// set up for the rogue access point so far.
+func (mod *WiFiModule) trackHostapd(ap *rogueAP) {
+	rules := make([]string, 0, len(ap.rules))
+	for _, rule := range ap.rules {
+		rules = append(rules, strings.Join(rule, " "))
+	}
+
+	args := map[string]string{
+		"iface":   ap.iface.Name(),
+		"conf":    ap.confFile,
+		"bridge":  strconv.FormatBool(ap.bridged),
+		"rules":   strings.Join(rules, ";"),
+		"address": "",
+		"pid":     "",
+	}
+	if ap.addressed {
+		args["address"] = fmt.Sprintf("%s/%d", ap.gateway, maskBits(ap.subnet))
+	}
+	if ap.cmd != nil && ap.cmd.Process != nil {
+		args["pid"] = strconv.Itoa(ap.cmd.Process.Pid)
+	}
+
+	if err := journal.Track(hostapdJournalKind, ap.iface.Name(), args); err != nil {
+		mod.Warning("could not update the journal: %s", err)
+	}
+}
+
+func (mod *WiFiModule) untrackHostapd(ap *rogueAP) {
+	if err := journal.Untrack(hostapdJournalKind, ap.iface.Name()); err != nil {
+		mod.Warning("could not update the journal: %s", err)
+	}
+}
+
+// restoreHostapd stops the rogue access point if it's still running or, if it
+// was left by a previous session, kills its hostapd and reverts its changes.
+func (mod *WiFiModule) restoreHostapd(args map[string]string) error {
+	ifName := args["iface"]
+	if mod.rogue != nil && mod.rogue.iface.Name() == ifName {
+		return mod.stopHostapd()
+	}
+
+	run := func(args ...string) {
+		if out, err := core.Exec(args[0], args[1:]); err != nil {
+			mod.Warning("error while running '%s': %s %s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	// make sure the pid has not been reused by something else
+	if pid, err := strconv.Atoi(args["pid"]); err == nil {
+		if cmdline, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid)); err == nil && strings.Contains(string(cmdline), "hostapd") {
+			if proc, err := os.FindProcess(pid); err == nil {
+				proc.Kill()
+			}
+		}
+	}
+
+	if args["rules"] != "" {
+		rules := strings.Split(args["rules"], ";")
+		for i := len(rules) - 1; i >= 0; i-- {
+			run(append([]string{"iptables"}, undoRule(strings.Fields(rules[i]))...)...)
+		}
+	}
+
+	if args["bridge"] == "true" {
+		run("ip", "link", "delete", hostapdBridge, "type", "bridge")
+	} else if args["address"] != "" {
+		run("ip", "addr", "del", args["address"], "dev", ifName)
+	}
+
+	if args["conf"] != "" {
+		os.Remove(args["conf"])
+	}
+
+	mod.Info("access point left on %s by a previous session stopped", ifName)
+	return journal.Untrack(hostapdJournalKind, ifName)
+}
@@ -0,0 +1,134 @@
+package wifi
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func (mod *WiFiModule) updateP2P(dev packets.Dot11P2PDevice, role, group string, frequency int, rssi int8) {
+	if network.IsZeroMac(dev.Address) || network.IsBroadcastMac(dev.Address) {
+		return
+	}
+
+	p2p, isNew := mod.Session.WiFi.AddP2PIfNew(dev.Address.String(), dev.Name, frequency, rssi)
+	if dev.Type != "" {
+		p2p.Type = dev.Type
+	}
+	// probes don't tell if the device is in a group, keep the last role seen
+	if role != network.P2PRoleDevice {
+		p2p.Role = role
+	}
+	if group != "" {
+		p2p.Group = group
+	}
+
+	if isNew {
+		mod.Session.Events.Add("wifi.p2p.new", p2p)
+	}
+}
+
+// discoverP2P adds the Wi-Fi Direct devices advertising themselves in probes,
+// the group owners beaconing and the clients associating to them.
+func (mod *WiFiModule) discoverP2P(radiotap *layers.RadioTap, dot11 *layers.Dot11, packet gopacket.Packet) {
+	switch dot11.Type {
+	case layers.Dot11TypeMgmtBeacon, layers.Dot11TypeMgmtProbeReq, layers.Dot11TypeMgmtProbeResp, layers.Dot11TypeMgmtAssociationReq:
+	default:
+		return
+	}
+
+	ok, p2p := packets.Dot11ParseP2P(packet)
+	if !ok || bytes.Equal(dot11.Address2, mod.iface.HW) {
+		return
+	} else if int(radiotap.DBMAntennaSignal) < mod.minRSSI {
+		return
+	}
+
+	dev := p2p.Device
+	if dev.Address == nil {
+		dev.Address = dot11.Address2
+	}
+
+	frequency := int(radiotap.ChannelFrequency)
+	role, group := network.P2PRoleDevice, ""
+	if dot11.Type == layers.Dot11TypeMgmtAssociationReq {
+		role, group = network.P2PRoleClient, dot11.Address1.String()
+		if ap, found := mod.Session.WiFi.Get(group); found {
+			group = ap.ESSID()
+		}
+	} else if dev.GroupOwner && dot11.Type != layers.Dot11TypeMgmtProbeReq {
+		role, group = network.P2PRoleGroupOwner, dot11.Address3.String()
+		if found, ssid := packets.Dot11ParseIDSSID(packet); found && ssid != "" {
+			group = ssid
+		}
+	}
+
+	mod.updateP2P(dev, role, group, frequency, radiotap.DBMAntennaSignal)
+	for _, client := range p2p.Clients {
+		// not heard directly, keep the rssi unknown
+		mod.updateP2P(client, network.P2PRoleClient, group, frequency, 0)
+	}
+}
+
+func (mod *WiFiModule) pruneP2P() {
+	for _, dev := range mod.Session.WiFi.P2PDevices() {
+		if sinceLastSeen := time.Since(dev.LastSeen); sinceLastSeen > maxStationTTL {
+			mod.Debug("wifi direct device %s not seen in %s, removing.", dev.HwAddress, sinceLastSeen)
+			mod.Session.WiFi.RemoveP2P(dev.HwAddress)
+			mod.Session.Events.Add("wifi.p2p.lost", dev)
+		}
+	}
+}
+
+func (mod *WiFiModule) ShowP2P() error {
+	devices := mod.Session.WiFi.P2PDevices()
+	if len(devices) == 0 {
+		return fmt.Errorf("no Wi-Fi Direct devices seen yet")
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].RSSI > devices[j].RSSI
+	})
+
+	rows := make([][]string, 0)
+	for _, dev := range devices {
+		role := tui.Dim(dev.Role)
+		if dev.Role == network.P2PRoleGroupOwner {
+			role = tui.Yellow(dev.Role)
+		} else if dev.Role == network.P2PRoleClient {
+			role = tui.Green(dev.Role)
+		}
+
+		rssi := tui.Dim("?")
+		if dev.RSSI != 0 {
+			rssi = network.ColorRSSI(int(dev.RSSI))
+		}
+
+		rows = append(rows, []string{
+			rssi,
+			dev.HwAddress,
+			tui.Bold(dev.Name()),
+			tui.Dim(dev.Vendor),
+			dev.Type,
+			role,
+			dev.Group,
+			fmt.Sprintf("%d", dev.Channel),
+			dev.LastSeen.Format("15:04:05"),
+		})
+	}
+
+	mod.Session.Println()
+	mod.Session.Table([]string{"RSSI", "Device", "Name", "Vendor", "Type", "Role", "Group", "Ch", "Seen"}, rows)
+	mod.Session.Println()
+
+	return nil
+}
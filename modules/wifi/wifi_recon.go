@@ -21,17 +21,15 @@ func (mod *WiFiModule) stationPruner() {
 	for mod.Running() {
 		// loop every AP
 		for _, ap := range mod.Session.WiFi.List() {
-			sinceLastSeen := time.Since(ap.LastSeen)
-			if sinceLastSeen > maxStationTTL {
-				mod.Debug("station %s not seen in %s, removing.", ap.BSSID(), sinceLastSeen)
+			if expired, reason := mod.Session.Expiry.Check(network.SourceWiFiAP, ap.LastSeen); expired {
+				mod.Debug("station %s %s, removing.", ap.BSSID(), reason)
 				mod.Session.WiFi.Remove(ap.BSSID())
 				continue
 			}
 			// loop every AP client
 			for _, c := range ap.Clients() {
-				sinceLastSeen := time.Since(c.LastSeen)
-				if sinceLastSeen > maxStationTTL {
-					mod.Debug("client %s of station %s not seen in %s, removing.", c.String(), ap.BSSID(), sinceLastSeen)
+				if expired, reason := mod.Session.Expiry.Check(network.SourceWiFiClient, c.LastSeen); expired {
+					mod.Debug("client %s of station %s %s, removing.", c.String(), ap.BSSID(), reason)
 					ap.RemoveClient(c.BSSID())
 
 					mod.Session.Events.Add("wifi.client.lost", ClientEvent{
@@ -41,6 +39,7 @@ func (mod *WiFiModule) stationPruner() {
 				}
 			}
 		}
+		mod.pruneP2P()
 		time.Sleep(1 * time.Second)
 	}
 }
@@ -124,7 +123,7 @@ func (mod *WiFiModule) discoverClients(radiotap *layers.RadioTap, dot11 *layers.
 			freq := int(radiotap.ChannelFrequency)
 			rssi := radiotap.DBMAntennaSignal
 
-			if station, isNew := ap.AddClientIfNew(bssid, freq, rssi, mod.Session.Lan.Aliases()); isNew {
+			if station, isNew := ap.AddClientIfNew(bssid, freq, rssi, mod.Session.Lan); isNew {
 				mod.Session.Events.Add("wifi.client.new", ClientEvent{
 					AP:     ap,
 					Client: station,
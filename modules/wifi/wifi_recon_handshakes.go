@@ -3,6 +3,7 @@ package wifi
 import (
 	"bytes"
 
+	"github.com/bettercap/bettercap/loot"
 	"github.com/bettercap/bettercap/packets"
 
 	"github.com/google/gopacket"
@@ -36,9 +37,10 @@ func (mod *WiFiModule) discoverHandshakes(radiotap *layers.RadioTap, dot11 *laye
 		staIsUs := bytes.Equal(staMac, mod.iface.HW)
 		station, found := ap.Get(staMac.String())
 		if !found {
-			station, _ = ap.AddClientIfNew(staMac.String(), ap.Frequency, ap.RSSI, mod.Session.Lan.Aliases())
+			station, _ = ap.AddClientIfNew(staMac.String(), ap.Frequency, ap.RSSI, mod.Session.Lan)
 		}
 
+		prevState := station.Handshake.State()
 		rawPMKID := []byte(nil)
 		if !key.Install && key.KeyACK && !key.KeyMIC {
 			// [1] (ACK) AP is sending ANonce to the client
@@ -46,6 +48,7 @@ func (mod *WiFiModule) discoverHandshakes(radiotap *layers.RadioTap, dot11 *laye
 			PMKID := "without PMKID"
 			if rawPMKID != nil {
 				PMKID = "with PMKID"
+				mod.assoc.onPMKID(ap.BSSID())
 			}
 
 			mod.Debug("got frame 1/4 of the %s <-> %s handshake (%s) (anonce:%x)",
@@ -70,6 +73,14 @@ func (mod *WiFiModule) discoverHandshakes(radiotap *layers.RadioTap, dot11 *laye
 				apMac,
 				staMac,
 				key.MIC)
+		} else if !key.Install && !key.KeyACK && key.KeyMIC && allZeros(key.Nonce) {
+			// [4]: (MIC) client confirms the PTK has been installed
+			station.Handshake.AddFrame(3, packet)
+
+			mod.Debug("got frame 4/4 of the %s <-> %s handshake (mic:%x)",
+				apMac,
+				staMac,
+				key.MIC)
 		}
 
 		// if we have unsaved packets as part of the handshake, save them.
@@ -84,17 +95,28 @@ func (mod *WiFiModule) discoverHandshakes(radiotap *layers.RadioTap, dot11 *laye
 
 		// if we had unsaved packets and either the handshake is complete
 		// or it contains the PMKID, generate a new event.
+		state := station.Handshake.State()
 		if doSave && (rawPMKID != nil || station.Handshake.Complete()) {
 			mod.Session.Events.Add("wifi.client.handshake", HandshakeEvent{
-				File:       mod.shakesFile,
+				File:       loot.FileName(mod.shakesFile),
 				NewPackets: numUnsaved,
 				AP:         apMac.String(),
 				Station:    staMac.String(),
 				PMKID:      rawPMKID,
+				Messages:   state,
 			})
 			// make sure the info that we have key material for this AP
 			// is persisted even after stations are pruned due to inactivity
 			ap.WithKeyMaterial(true)
+		} else if doSave && state != prevState && station.Handshake.Half() && !staIsUs {
+			// not crackable yet, but still useful for half-handshake attacks
+			mod.Session.Events.Add("wifi.client.handshake.half", HandshakeEvent{
+				File:       mod.shakesFile,
+				NewPackets: numUnsaved,
+				AP:         apMac.String(),
+				Station:    staMac.String(),
+				Messages:   state,
+			})
 		}
 
 		// if we added ourselves as a client station but we didn't get any
@@ -0,0 +1,68 @@
+package wifi
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcap"
+
+	"github.com/evilsocket/islazy/fs"
+)
+
+// replay reads the frames of a capture taken elsewhere and processes them as
+// if they were captured live, so that access points, clients, handshakes and
+// the related events can be analyzed offline.
+func (mod *WiFiModule) replay(fileName string) (err error) {
+	if mod.Running() {
+		return fmt.Errorf("wifi.replay can't be used while wifi.recon is running, stop it first")
+	} else if fileName, err = fs.Expand(fileName); err != nil {
+		return err
+	} else if !fs.Exists(fileName) {
+		return fmt.Errorf("%s does not exist", fileName)
+	}
+
+	if err, mod.skipBroken = mod.BoolParam("wifi.skip-broken"); err != nil {
+		return err
+	} else if err, mod.shakesFile = mod.StringParam("wifi.handshakes.file"); err != nil {
+		return err
+	} else if mod.shakesFile != "" {
+		if mod.shakesFile, err = fs.Expand(mod.shakesFile); err != nil {
+			return err
+		}
+	}
+
+	handle, err := pcap.OpenOffline(fileName)
+	if err != nil {
+		return fmt.Errorf("error while opening file %s: %s", fileName, err)
+	}
+	defer handle.Close()
+
+	// the pipeline needs an interface to tell our own frames apart and a
+	// handle for the link type of the saved handshakes
+	if mod.iface == nil {
+		mod.iface = mod.Session.Interface
+	}
+	mod.handle = handle
+
+	mod.Info("replaying %s ...", fileName)
+
+	start := time.Now()
+	before := len(mod.Session.WiFi.List())
+	frames := 0
+	src := gopacket.NewPacketSource(handle, handle.LinkType())
+	for packet := range src.Packets() {
+		mod.onPacket(packet)
+		frames++
+	}
+
+	after := len(mod.Session.WiFi.List())
+	mod.Info("replayed %d frames in %s, %d new access points (%d total, %d handshakes)",
+		frames,
+		time.Since(start).Round(time.Millisecond),
+		after-before,
+		after,
+		mod.Session.WiFi.NumHandshakes())
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package wifi
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/ops"
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	injectionTestAPs  = 5
+	injectionTestWait = 500 * time.Millisecond
+)
+
+// drivers with known monitor mode or injection issues
+var driverQuirks = map[string]string{
+	"iwlwifi":      "Intel chipsets can't reliably inject frames, deauth and association attacks will likely not work.",
+	"brcmfmac":     "Broadcom FullMAC chipsets require the nexmon firmware patches for monitor mode and injection.",
+	"ath10k_pci":   "ath10k firmwares do not support frame injection.",
+	"mwifiex_pcie": "Marvell chipsets do not support monitor mode.",
+	"mwifiex_sdio": "Marvell chipsets do not support monitor mode.",
+	"r8188eu":      "the staging r8188eu driver has a broken monitor mode, use the out of tree 8188eu driver instead.",
+	"rtl8xxxu":     "this driver has incomplete monitor mode support and drops most injected frames.",
+	"rtl8192cu":    "this driver is known to silently drop injected frames.",
+}
+
+type injectionTest struct {
+	sync.Mutex
+	responses map[string]int
+}
+
+func (t *injectionTest) track(bssid string) {
+	t.Lock()
+	defer t.Unlock()
+	t.responses[bssid]++
+}
+
+func (t *injectionTest) count(bssid string) int {
+	t.Lock()
+	defer t.Unlock()
+	return t.responses[bssid]
+}
+
+// injectionTest returns the running injection test, read by the packet
+// processing goroutine, or nil.
+func (mod *WiFiModule) injectionTest() *injectionTest {
+	mod.injLock.Lock()
+	defer mod.injLock.Unlock()
+	return mod.injTest
+}
+
+// startInjectionTest returns a new injection test, or nil if one is already running.
+func (mod *WiFiModule) startInjectionTest() *injectionTest {
+	mod.injLock.Lock()
+	defer mod.injLock.Unlock()
+	if mod.injTest != nil {
+		return nil
+	}
+	mod.injTest = &injectionTest{responses: make(map[string]int)}
+	return mod.injTest
+}
+
+func (mod *WiFiModule) stopInjectionTest() {
+	mod.injLock.Lock()
+	defer mod.injLock.Unlock()
+	mod.injTest = nil
+}
+
+// trackInjectionTest counts the probe responses addressed to us while wifi.test is running.
+func (mod *WiFiModule) trackInjectionTest(dot11 *layers.Dot11) {
+	if dot11.Type != layers.Dot11TypeMgmtProbeResp || !bytes.Equal(dot11.Address1, mod.iface.HW) {
+		return
+	} else if test := mod.injectionTest(); test != nil {
+		test.track(dot11.Address2.String())
+	}
+}
+
+func (mod *WiFiModule) showCapabilities() error {
+	caps, err := network.GetWiFiCapabilities(mod.iface.Name())
+	if err != nil {
+		return err
+	}
+
+	driver := ops.Ternary(caps.Driver == "", "unknown", caps.Driver).(string)
+	mod.Session.Printf("\n%s (%s, driver %s)\n\n", tui.Bold(mod.iface.Name()), caps.Phy, tui.Bold(driver))
+	mod.Session.Printf("  modes : %s\n\n", strings.Join(caps.Modes, ", "))
+
+	rows := make([][]string, 0)
+	for _, band := range caps.Bands {
+		channels := make([]string, 0)
+		for _, ch := range band.Channels {
+			channels = append(channels, fmt.Sprintf("%d", ch))
+		}
+		rows = append(rows, []string{
+			band.Name,
+			ops.Ternary(band.HT, tui.Green("yes"), tui.Dim("no")).(string),
+			ops.Ternary(band.VHT, tui.Green("yes"), tui.Dim("no")).(string),
+			strings.Join(channels, ","),
+		})
+	}
+
+	mod.Session.Table([]string{"Band", "HT", "VHT", "Channels"}, rows)
+	mod.Session.Println()
+
+	if !caps.HasMode("monitor") {
+		mod.Warning("%s does not support monitor mode.", mod.iface.Name())
+	}
+	if quirk, found := driverQuirks[caps.Driver]; found {
+		mod.Warning("%s: %s", caps.Driver, quirk)
+	}
+
+	return nil
+}
+
+func (mod *WiFiModule) testInjection() error {
+	if !mod.Running() {
+		return errNoRecon
+	} else if err := mod.canInject(); err != nil {
+		return err
+	} else if mod.source != "" {
+		return fmt.Errorf("can't test packet injection while reading from %s", mod.source)
+	} else if mod.injectionTest() != nil {
+		return fmt.Errorf("injection test already running")
+	}
+
+	err, probes := mod.IntParam("wifi.test.probes")
+	if err != nil {
+		return err
+	} else if probes <= 0 {
+		return fmt.Errorf("wifi.test.probes must be greater than 0")
+	}
+
+	aps := make([]*network.AccessPoint, 0)
+	for _, ap := range mod.Session.WiFi.List() {
+		if ap.Channel > 0 {
+			aps = append(aps, ap)
+		}
+	}
+
+	if len(aps) == 0 {
+		return fmt.Errorf("no access points found yet, keep wifi.recon running for a while")
+	}
+
+	sort.Slice(aps, func(i, j int) bool {
+		return aps[i].RSSI > aps[j].RSSI
+	})
+	if len(aps) > injectionTestAPs {
+		aps = aps[:injectionTestAPs]
+	}
+
+	test := mod.startInjectionTest()
+	if test == nil {
+		return fmt.Errorf("injection test already running")
+	}
+	defer mod.stopInjectionTest()
+
+	mod.writes.Add(1)
+	defer mod.writes.Done()
+
+	mod.Info("testing injection with %d directed probes to %d access points ...", probes, len(aps))
+
+	rows := make([][]string, 0)
+	working := 0
+	for _, ap := range aps {
+		if !mod.Running() {
+			break
+		}
+
+		mod.onChannel(ap.Channel, func() {
+			for seq := 0; seq < probes; seq++ {
+				if err, pkt := packets.NewDot11ProbeRequest(mod.iface.HW, ap.HW, ap.ESSID(), uint16(seq)); err != nil {
+					mod.Error("could not create probe request packet: %s", err)
+				} else {
+					mod.injectPacket(pkt)
+				}
+			}
+			time.Sleep(injectionTestWait)
+		})
+
+		got := test.count(ap.BSSID())
+		if got > probes {
+			got = probes
+		}
+		if got > 0 {
+			working++
+		}
+
+		rate := got * 100 / probes
+		color := tui.Red
+		if rate >= 50 {
+			color = tui.Green
+		}
+		rows = append(rows, []string{
+			ap.BSSID(),
+			ap.ESSID(),
+			fmt.Sprintf("%d", ap.Channel),
+			network.ColorRSSI(int(ap.RSSI)),
+			fmt.Sprintf("%d/%d", got, probes),
+			color(fmt.Sprintf("%d%%", rate)),
+		})
+	}
+
+	mod.Session.Println()
+	mod.Session.Table([]string{"BSSID", "SSID", "Ch", "RSSI", "Responses", "Rate"}, rows)
+	mod.Session.Println()
+
+	if working > 0 {
+		mod.Info("injection is working (%d/%d access points responded).", working, len(rows))
+	} else {
+		mod.Warning("no access point responded to the injected probes, the driver is probably not injecting frames.")
+	}
+
+	return nil
+}
+
+func (mod *WiFiModule) selfTest() error {
+	if err := mod.showCapabilities(); err != nil {
+		mod.Warning("could not read capabilities of %s: %s", mod.iface.Name(), err)
+	}
+
+	if !mod.Running() {
+		mod.Warning("wifi.recon is not running, skipping the injection test.")
+		return nil
+	} else if mod.supplicant != nil {
+		mod.Warning("%s", errNoInjection)
+		return nil
+	}
+
+	return mod.testInjection()
+}
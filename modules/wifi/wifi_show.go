@@ -2,13 +2,13 @@ package wifi
 
 import (
 	"fmt"
-	"os"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bettercap/bettercap/modules/net_recon"
+	"github.com/bettercap/bettercap/modules/utils"
 	"github.com/bettercap/bettercap/network"
 
 	"github.com/dustin/go-humanize"
@@ -81,12 +81,24 @@ func (mod *WiFiModule) getRow(station *network.Station) ([]string, bool) {
 	}
 
 	if mod.isApSelected() {
+		if station.Alias != "" {
+			bssid = fmt.Sprintf("%s (%s)", bssid, tui.Green(station.Alias))
+		}
+
+		handshake := ""
+		if station.Handshake.Complete() {
+			handshake = tui.Red(station.Handshake.State())
+		} else if station.Handshake.Half() {
+			handshake = tui.Yellow(station.Handshake.State())
+		}
+
 		if mod.showManuf {
 			return []string{
 				rssi,
 				bssid,
 				tui.Dim(station.Vendor),
 				strconv.Itoa(station.Channel),
+				handshake,
 				sent,
 				recvd,
 				seen,
@@ -96,6 +108,7 @@ func (mod *WiFiModule) getRow(station *network.Station) ([]string, bool) {
 				rssi,
 				bssid,
 				strconv.Itoa(station.Channel),
+				handshake,
 				sent,
 				recvd,
 				seen,
@@ -129,50 +142,47 @@ func (mod *WiFiModule) getRow(station *network.Station) ([]string, bool) {
 			wps = tui.Dim(tui.Yellow(wps))
 		}
 
+		row := []string{
+			rssi,
+			bssid,
+			ssid,
+			encryption,
+			wps,
+			strconv.Itoa(station.Channel),
+			clients,
+			sent,
+			recvd,
+		}
 		if mod.showManuf {
-			return []string{
-				rssi,
-				bssid,
-				tui.Dim(station.Vendor),
-				ssid,
-				encryption,
-				wps,
-				strconv.Itoa(station.Channel),
-				clients,
-				sent,
-				recvd,
-				seen,
-			}, include
-		} else {
-			return []string{
-				rssi,
-				bssid,
-				ssid,
-				encryption,
-				wps,
-				strconv.Itoa(station.Channel),
-				clients,
-				sent,
-				recvd,
-				seen,
-			}, include
+			row = append(row[:2], append([]string{tui.Dim(station.Vendor)}, row[2:]...)...)
 		}
+		if mod.showAssoc {
+			max, backoff := mod.assocBudget()
+			row = append(row, mod.assoc.status(station.BSSID(), max, backoff))
+		}
+		return append(row, seen), include
 	}
 }
 
-func (mod *WiFiModule) doFilter(station *network.Station) bool {
-	if mod.selector.Expression == nil {
+func (mod *WiFiModule) doFilter(selector *utils.ViewSelector, station *network.Station) bool {
+	if selector.Expression == nil {
 		return true
 	}
-	return mod.selector.Expression.MatchString(station.BSSID()) ||
-		mod.selector.Expression.MatchString(station.ESSID()) ||
-		mod.selector.Expression.MatchString(station.Alias) ||
-		mod.selector.Expression.MatchString(station.Vendor) ||
-		mod.selector.Expression.MatchString(station.Encryption)
+	return selector.Expression.MatchString(station.BSSID()) ||
+		selector.Expression.MatchString(station.ESSID()) ||
+		selector.Expression.MatchString(station.Alias) ||
+		selector.Expression.MatchString(station.Vendor) ||
+		selector.Expression.MatchString(station.Encryption)
 }
 
 func (mod *WiFiModule) doSelection() (err error, stations []*network.Station) {
-	if err = mod.selector.Update(); err != nil {
+	return mod.doSelectionWith(mod.selector)
+}
+
+// doSelectionWith returns the stations filtered, sorted and limited by the
+// parameters of the selector.
+func (mod *WiFiModule) doSelectionWith(selector *utils.ViewSelector) (err error, stations []*network.Station) {
+	if err = selector.Update(); err != nil {
 		return
 	}
 
@@ -190,13 +200,13 @@ func (mod *WiFiModule) doSelection() (err error, stations []*network.Station) {
 
 	filtered := []*network.Station{}
 	for _, station := range stations {
-		if mod.doFilter(station) {
+		if mod.doFilter(selector, station) {
 			filtered = append(filtered, station)
 		}
 	}
 	stations = filtered
 
-	switch mod.selector.SortField {
+	switch selector.SortField {
 	case "seen":
 		sort.Sort(ByWiFiSeenSorter(stations))
 	case "essid":
@@ -220,7 +230,7 @@ func (mod *WiFiModule) doSelection() (err error, stations []*network.Station) {
 	}
 
 	// default is asc
-	if mod.selector.Sort == "desc" {
+	if selector.Sort == "desc" {
 		// from https://github.com/golang/go/wiki/SliceTricks
 		for i := len(stations)/2 - 1; i >= 0; i-- {
 			opp := len(stations) - 1 - i
@@ -228,8 +238,8 @@ func (mod *WiFiModule) doSelection() (err error, stations []*network.Station) {
 		}
 	}
 
-	if mod.selector.Limit > 0 {
-		limit := mod.selector.Limit
+	if selector.Limit > 0 {
+		limit := selector.Limit
 		max := len(stations)
 		if limit > max {
 			limit = max
@@ -250,45 +260,59 @@ func (mod *WiFiModule) colDecorate(colNames []string, name string, dir string) {
 }
 
 func (mod *WiFiModule) colNames(nrows int) []string {
+	if mod.isApSelected() {
+		if nrows > 0 {
+			mod.Session.Printf("\n%s clients:\n", mod.ap.HwAddress)
+		} else {
+			mod.Session.Printf("\nNo authenticated clients detected for %s.\n", mod.ap.HwAddress)
+		}
+	}
+	return mod.columnsOf(mod.selector, nrows)
+}
+
+// columnsOf returns the columns of the table, the sorting one decorated as
+// by the selector, nil if no client of the selected access point is shown.
+func (mod *WiFiModule) columnsOf(selector *utils.ViewSelector, nrows int) []string {
 	columns := []string(nil)
 
 	if !mod.isApSelected() {
 		if mod.showManuf {
-			columns = []string{"RSSI", "BSSID", "Manufacturer", "SSID", "Encryption", "WPS", "Ch", "Clients", "Sent", "Recvd", "Seen"}
+			columns = []string{"RSSI", "BSSID", "Manufacturer", "SSID", "Encryption", "WPS", "Ch", "Clients", "Sent", "Recvd"}
 		} else {
-			columns = []string{"RSSI", "BSSID", "SSID", "Encryption", "WPS", "Ch", "Clients", "Sent", "Recvd", "Seen"}
+			columns = []string{"RSSI", "BSSID", "SSID", "Encryption", "WPS", "Ch", "Clients", "Sent", "Recvd"}
 		}
+		if mod.showAssoc {
+			columns = append(columns, "Assoc")
+		}
+		columns = append(columns, "Seen")
 	} else if nrows > 0 {
 		if mod.showManuf {
-			columns = []string{"RSSI", "BSSID", "Manufacturer", "Ch", "Sent", "Recvd", "Seen"}
+			columns = []string{"RSSI", "BSSID", "Manufacturer", "Ch", "Handshake", "Sent", "Recvd", "Seen"}
 		} else {
-			columns = []string{"RSSI", "BSSID", "Ch", "Sent", "Recvd", "Seen"}
+			columns = []string{"RSSI", "BSSID", "Ch", "Handshake", "Sent", "Recvd", "Seen"}
 		}
-		fmt.Printf("\n%s clients:\n", mod.ap.HwAddress)
-	} else {
-		fmt.Printf("\nNo authenticated clients detected for %s.\n", mod.ap.HwAddress)
 	}
 
 	if columns != nil {
-		switch mod.selector.SortField {
+		switch selector.SortField {
 		case "seen":
-			mod.colDecorate(columns, "Seen", mod.selector.SortSymbol)
+			mod.colDecorate(columns, "Seen", selector.SortSymbol)
 		case "essid":
-			mod.colDecorate(columns, "SSID", mod.selector.SortSymbol)
+			mod.colDecorate(columns, "SSID", selector.SortSymbol)
 		case "bssid":
-			mod.colDecorate(columns, "BSSID", mod.selector.SortSymbol)
+			mod.colDecorate(columns, "BSSID", selector.SortSymbol)
 		case "channel":
-			mod.colDecorate(columns, "Ch", mod.selector.SortSymbol)
+			mod.colDecorate(columns, "Ch", selector.SortSymbol)
 		case "clients":
-			mod.colDecorate(columns, "Clients", mod.selector.SortSymbol)
+			mod.colDecorate(columns, "Clients", selector.SortSymbol)
 		case "encryption":
-			mod.colDecorate(columns, "Encryption", mod.selector.SortSymbol)
+			mod.colDecorate(columns, "Encryption", selector.SortSymbol)
 		case "sent":
-			mod.colDecorate(columns, "Sent", mod.selector.SortSymbol)
+			mod.colDecorate(columns, "Sent", selector.SortSymbol)
 		case "rcvd":
-			mod.colDecorate(columns, "Recvd", mod.selector.SortSymbol)
+			mod.colDecorate(columns, "Recvd", selector.SortSymbol)
 		case "rssi":
-			mod.colDecorate(columns, "RSSI", mod.selector.SortSymbol)
+			mod.colDecorate(columns, "RSSI", selector.SortSymbol)
 		}
 	}
 
@@ -296,14 +320,19 @@ func (mod *WiFiModule) colNames(nrows int) []string {
 }
 
 func (mod *WiFiModule) showStatusBar() {
+	mod.Session.Printf("\n%s\n\n", mod.statusBar())
+}
+
+func (mod *WiFiModule) statusBar() string {
+	stats := mod.Session.Queue.Stats()
 	parts := []string{
 		fmt.Sprintf("%s (ch. %d)", mod.iface.Name(), network.GetInterfaceChannel(mod.iface.Name())),
-		fmt.Sprintf("%s %s", tui.Red("↑"), humanize.Bytes(mod.Session.Queue.Stats.Sent)),
-		fmt.Sprintf("%s %s", tui.Green("↓"), humanize.Bytes(mod.Session.Queue.Stats.Received)),
-		fmt.Sprintf("%d pkts", mod.Session.Queue.Stats.PktReceived),
+		fmt.Sprintf("%s %s", tui.Red("↑"), humanize.Bytes(stats.Sent)),
+		fmt.Sprintf("%s %s", tui.Green("↓"), humanize.Bytes(stats.Received)),
+		fmt.Sprintf("%d pkts", stats.PktReceived),
 	}
 
-	if nErrors := mod.Session.Queue.Stats.Errors; nErrors > 0 {
+	if nErrors := stats.Errors; nErrors > 0 {
 		parts = append(parts, fmt.Sprintf("%d errs", nErrors))
 	}
 
@@ -311,7 +340,7 @@ func (mod *WiFiModule) showStatusBar() {
 		parts = append(parts, fmt.Sprintf("%d handshakes", nHandshakes))
 	}
 
-	fmt.Printf("\n%s\n\n", strings.Join(parts, " / "))
+	return strings.Join(parts, " / ")
 }
 
 func (mod *WiFiModule) Show() (err error) {
@@ -323,6 +352,7 @@ func (mod *WiFiModule) Show() (err error) {
 	if err, mod.showManuf = mod.BoolParam("wifi.show.manufacturer"); err != nil {
 		return err
 	}
+	mod.showAssoc = mod.assoc.Len() > 0
 
 	rows := make([][]string, 0)
 	for _, s := range stations {
@@ -332,7 +362,7 @@ func (mod *WiFiModule) Show() (err error) {
 	}
 	nrows := len(rows)
 	if nrows > 0 {
-		tui.Table(os.Stdout, mod.colNames(nrows), rows)
+		mod.Session.Table(mod.colNames(nrows), rows)
 	}
 
 	mod.showStatusBar()
@@ -388,7 +418,7 @@ func (mod *WiFiModule) ShowWPS(bssid string) (err error) {
 			})
 		}
 
-		tui.Table(os.Stdout, colNames, rows)
+		mod.Session.Table(colNames, rows)
 	}
 
 	return nil
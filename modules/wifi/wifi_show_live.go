@@ -0,0 +1,152 @@
+package wifi
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/modules/utils"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+// the lines of the live view that are not rows: the title, the borders and
+// the header of the table, the status bar and the prompt
+const liveChromeLines = 9
+
+type liveShow struct {
+	sync.Mutex
+	view    *utils.LiveView
+	every   time.Duration
+	quit    chan bool
+	done    chan bool
+	running bool
+}
+
+func newLiveShow() *liveShow {
+	return &liveShow{
+		view: utils.NewLiveView(os.Stdout),
+	}
+}
+
+// liveFrame renders the stations of the live view, as many as fit in the
+// terminal unless wifi.show.live.limit is set.
+func (mod *WiFiModule) liveFrame(height int) (string, error) {
+	err, stations := mod.doSelectionWith(mod.liveSelector)
+	if err != nil {
+		return "", err
+	} else if err, mod.showManuf = mod.BoolParam("wifi.show.manufacturer"); err != nil {
+		return "", err
+	}
+	mod.showAssoc = mod.assoc.Len() > 0
+
+	rows := make([][]string, 0, len(stations))
+	for _, s := range stations {
+		if row, include := mod.getRow(s); include {
+			rows = append(rows, row)
+		}
+	}
+
+	total := len(rows)
+	if mod.liveSelector.Limit <= 0 && height > 0 {
+		if max := height - liveChromeLines; max >= 0 && total > max {
+			rows = rows[:max]
+		}
+	}
+
+	what := "stations"
+	if mod.isApSelected() {
+		what = fmt.Sprintf("clients of %s", mod.ap.HwAddress)
+	}
+	shown := fmt.Sprintf("%d %s", total, what)
+	if len(rows) < total {
+		shown = fmt.Sprintf("%d of %d %s", len(rows), total, what)
+	}
+
+	frame := bytes.Buffer{}
+	fmt.Fprintf(&frame, "%s %s %s\n",
+		tui.Bold("wifi.show live"),
+		shown,
+		tui.Dim(fmt.Sprintf("(%s, every %s, wifi.show live off to stop)", time.Now().Format("15:04:05"), mod.live.every)))
+
+	if columns := mod.columnsOf(mod.liveSelector, len(rows)); columns != nil && len(rows) > 0 {
+		tui.Table(&frame, columns, rows)
+	} else {
+		frame.WriteString("\n")
+	}
+	fmt.Fprintf(&frame, "\n%s\n", mod.statusBar())
+
+	return frame.String(), nil
+}
+
+func (mod *WiFiModule) startLive() error {
+	var err error
+	var every int
+
+	if mod.Session.IsJSONOutput() {
+		return fmt.Errorf("wifi.show live can't be used with the json output format")
+	} else if err, every = mod.IntParam("wifi.show.live.interval"); err != nil {
+		return err
+	} else if every <= 0 {
+		return fmt.Errorf("wifi.show.live.interval must be greater than 0")
+	} else if err = mod.liveSelector.Update(); err != nil {
+		return err
+	}
+
+	mod.live.Lock()
+	defer mod.live.Unlock()
+
+	if mod.live.running {
+		return fmt.Errorf("wifi.show live is already running")
+	}
+
+	mod.live.every = time.Duration(every) * time.Second
+	mod.live.quit = make(chan bool)
+	mod.live.done = make(chan bool)
+	mod.live.running = true
+	mod.live.view.Reset()
+
+	go mod.liveLoop(mod.live.quit, mod.live.done)
+
+	return nil
+}
+
+func (mod *WiFiModule) liveLoop(quit chan bool, done chan bool) {
+	defer close(done)
+
+	tick := time.NewTicker(mod.live.every)
+	defer tick.Stop()
+
+	for {
+		_, height := mod.live.view.Size()
+		if frame, err := mod.liveFrame(height); err != nil {
+			mod.Error("%s", err)
+		} else {
+			mod.live.view.Draw(frame)
+			mod.Session.Refresh()
+		}
+
+		select {
+		case <-quit:
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+func (mod *WiFiModule) stopLive() error {
+	mod.live.Lock()
+	defer mod.live.Unlock()
+
+	if !mod.live.running {
+		return fmt.Errorf("wifi.show live is not running")
+	}
+
+	close(mod.live.quit)
+	<-mod.live.done
+	mod.live.running = false
+
+	return nil
+}
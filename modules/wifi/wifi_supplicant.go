@@ -0,0 +1,82 @@
+package wifi
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+)
+
+const (
+	supplicantScanPeriod = 10 * time.Second
+	supplicantScanWait   = 3 * time.Second
+)
+
+var errNoInjection = errors.New("packet injection is not available while wifi.recon is reading the scan results of wpa_supplicant.")
+
+func (mod *WiFiModule) openSupplicant(ifName string, path string) (err error) {
+	if path == "auto" {
+		if path, err = network.WPAControlSocket(ifName); err != nil {
+			return err
+		}
+	}
+
+	if mod.supplicant, err = network.NewWPAControl(path); err != nil {
+		return err
+	}
+
+	mod.Info("reading scan results from wpa_supplicant via %s, monitor mode and injection are disabled.", path)
+	return nil
+}
+
+// canInject returns an error if the interface can't be (or will not be) opened for injection.
+func (mod *WiFiModule) canInject() error {
+	if mod.supplicant != nil {
+		return errNoInjection
+	} else if err, path := mod.StringParam("wifi.supplicant.socket"); err != nil {
+		return err
+	} else if path != "" {
+		return errNoInjection
+	}
+	return nil
+}
+
+func (mod *WiFiModule) updateFromScanResults(results []network.WPAScanResult) {
+	for _, result := range results {
+		if int(result.RSSI) < mod.minRSSI {
+			mod.Debug("skipping %s with %d dBm", result.BSSID, result.RSSI)
+			continue
+		}
+
+		ap, _ := mod.Session.WiFi.AddIfNew(result.ESSID, result.BSSID, result.Frequency, result.RSSI)
+		ap.Encryption, ap.Cipher, ap.Authentication = result.Encryption()
+		if result.WPSEnabled() {
+			ap.WPS["Enabled"] = "yes"
+		}
+	}
+}
+
+// supplicantScanner periodically asks wpa_supplicant to scan and collects the
+// results, this only needs the interface to be managed by wpa_supplicant.
+func (mod *WiFiModule) supplicantScanner() {
+	mod.reads.Add(1)
+	defer mod.reads.Done()
+
+	for mod.Running() {
+		if err := mod.supplicant.Scan(); err != nil {
+			mod.Warning("%s", err)
+		}
+
+		time.Sleep(supplicantScanWait)
+
+		if results, err := mod.supplicant.ScanResults(); err != nil {
+			mod.Error("could not read scan results: %s", err)
+		} else {
+			mod.updateFromScanResults(results)
+		}
+
+		for waited := supplicantScanWait; waited < supplicantScanPeriod && mod.Running(); waited += time.Second {
+			time.Sleep(time.Second)
+		}
+	}
+}
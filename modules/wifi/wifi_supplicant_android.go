@@ -0,0 +1,4 @@
+package wifi
+
+// Android devices don't support monitor mode unless patched, but wpa_supplicant is always there.
+const defaultSupplicantSocket = "auto"
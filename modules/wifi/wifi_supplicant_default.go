@@ -0,0 +1,5 @@
+// +build !android
+
+package wifi
+
+const defaultSupplicantSocket = ""
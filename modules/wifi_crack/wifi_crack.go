@@ -0,0 +1,268 @@
+package wifi_crack
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+type CrackProgress struct {
+	Wordlist string  `json:"wordlist"`
+	Tried    uint64  `json:"tried"`
+	Total    uint64  `json:"total"`
+	Rate     float64 `json:"rate"`
+	Targets  int     `json:"targets"`
+	Found    int     `json:"found"`
+}
+
+type CrackJob struct {
+	Server  string `json:"server"`
+	BSSID   string `json:"bssid"`
+	ESSID   string `json:"essid"`
+	Station string `json:"station"`
+	Kind    string `json:"kind"`
+	Hashes  int    `json:"hashes"`
+}
+
+type WiFiCracker struct {
+	session.SessionModule
+
+	wordlist string
+	workers  int
+	filter   []string
+	server   string
+	token    string
+	store    *crackSession
+	client   *http.Client
+	quit     chan bool
+	done     chan bool
+}
+
+func NewWiFiCracker(s *session.Session) *WiFiCracker {
+	mod := &WiFiCracker{
+		SessionModule: session.NewSessionModule("wifi.crack", s),
+		client:        &http.Client{Timeout: 30 * time.Second},
+	}
+
+	mod.InitState("progress")
+
+	mod.AddParam(session.NewStringParameter("wifi.crack.wordlist",
+		"",
+		"",
+		"Wordlist of the dictionary attack, one passphrase per line."))
+
+	mod.AddParam(session.NewIntParameter("wifi.crack.workers",
+		fmt.Sprintf("%d", runtime.NumCPU()),
+		"Number of passphrases tried in parallel."))
+
+	mod.AddParam(session.NewListParameter("wifi.crack.targets",
+		"",
+		"Comma separated list of BSSIDs to attack, if empty every access point with a captured handshake or PMKID is attacked."))
+
+	mod.AddParam(session.NewStringParameter("wifi.crack.session",
+		"~/bettercap-wifi-crack.json",
+		"",
+		"File used to resume the attacks and to keep the found passphrases, if empty nothing is saved."))
+
+	mod.AddParam(session.NewStringParameter("wifi.crack.hashcat.url",
+		"",
+		"",
+		"If set, the handshakes and PMKIDs are sent to this hashcat server in the 22000 format as they are captured instead of being cracked locally."))
+
+	mod.AddParam(session.NewStringParameter("wifi.crack.hashcat.token",
+		"",
+		"",
+		"Optional bearer token to authenticate to the hashcat server."))
+
+	mod.AddHandler(session.NewModuleHandler("wifi.crack on", "",
+		"Start the dictionary attack against the captured handshakes and PMKIDs, or send them to the hashcat server.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("wifi.crack off", "",
+		"Stop the attack, it will be resumed from the last word tried.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("wifi.crack.show", "",
+		"Show the targets and the passphrases found so far.",
+		func(args []string) error {
+			return mod.Show()
+		}))
+
+	return mod
+}
+
+func (mod *WiFiCracker) Name() string {
+	return "wifi.crack"
+}
+
+func (mod *WiFiCracker) Description() string {
+	return "A WPA-PSK dictionary attack against the handshakes and PMKIDs captured by wifi.recon."
+}
+
+func (mod *WiFiCracker) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *WiFiCracker) loadStore() error {
+	err, fileName := mod.StringParam("wifi.crack.session")
+	if err != nil {
+		return err
+	} else if fileName != "" {
+		if fileName, err = fs.Expand(fileName); err != nil {
+			return err
+		}
+	}
+
+	if mod.store == nil || mod.store.fileName != fileName {
+		if err, mod.store = loadSession(fileName); err != nil {
+			return fmt.Errorf("could not load %s: %v", fileName, err)
+		}
+	}
+	return nil
+}
+
+func (mod *WiFiCracker) Configure() (err error) {
+	if mod.Running() {
+		return session.ErrAlreadyStarted
+	} else if err, mod.wordlist = mod.StringParam("wifi.crack.wordlist"); err != nil {
+		return err
+	} else if err, mod.workers = mod.IntParam("wifi.crack.workers"); err != nil {
+		return err
+	} else if err, mod.filter = mod.ListParam("wifi.crack.targets"); err != nil {
+		return err
+	} else if err, mod.server = mod.StringParam("wifi.crack.hashcat.url"); err != nil {
+		return err
+	} else if err, mod.token = mod.StringParam("wifi.crack.hashcat.token"); err != nil {
+		return err
+	} else if err = mod.loadStore(); err != nil {
+		return err
+	}
+
+	if mod.server == "" {
+		if mod.wordlist == "" {
+			return fmt.Errorf("wifi.crack.wordlist or wifi.crack.hashcat.url must be set")
+		} else if mod.wordlist, err = fs.Expand(mod.wordlist); err != nil {
+			return err
+		} else if !fs.Exists(mod.wordlist) {
+			return fmt.Errorf("wordlist %s does not exist", mod.wordlist)
+		}
+	}
+
+	if mod.workers <= 0 {
+		mod.workers = 1
+	}
+
+	mod.quit = make(chan bool)
+	mod.done = make(chan bool)
+	return nil
+}
+
+// pending returns the targets we don't know the passphrase of.
+func (mod *WiFiCracker) pending() []*Target {
+	targets := make([]*Target, 0)
+	for _, t := range mod.collectTargets() {
+		if _, found := mod.store.found(t.ID()); !found {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+func (mod *WiFiCracker) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	var targets []*Target
+	if mod.server == "" {
+		if targets = mod.pending(); len(targets) == 0 {
+			return fmt.Errorf("no handshakes or PMKIDs to crack, capture some with wifi.recon first")
+		}
+	}
+
+	return mod.SetRunning(true, func() {
+		defer mod.SetRunning(false, nil)
+		defer close(mod.done)
+
+		if mod.server != "" {
+			mod.streamJobs()
+		} else {
+			mod.dictionaryAttack(targets)
+		}
+	})
+}
+
+func (mod *WiFiCracker) Stop() error {
+	return mod.SetRunning(false, func() {
+		close(mod.quit)
+		<-mod.done
+	})
+}
+
+// onFound saves the passphrase of the target, returning false if it was
+// already found.
+func (mod *WiFiCracker) onFound(t *Target, passphrase string) bool {
+	f := &Found{
+		BSSID:      t.BSSID,
+		ESSID:      t.ESSID,
+		Station:    t.Station,
+		Passphrase: passphrase,
+		Time:       time.Now(),
+	}
+
+	if !mod.store.addFound(t.ID(), f) {
+		return false
+	} else if err := mod.store.save(); err != nil {
+		mod.Warning("could not save %s: %v", mod.store.fileName, err)
+	}
+
+	mod.Session.Events.Add("wifi.crack.found", *f)
+	return true
+}
+
+func (mod *WiFiCracker) Show() error {
+	if mod.Running() {
+		// the attack is using it
+	} else if err := mod.loadStore(); err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0)
+	seen := make(map[string]bool)
+	for _, t := range mod.collectTargets() {
+		seen[t.ID()] = true
+		passphrase := tui.Dim("not found")
+		if f, found := mod.store.found(t.ID()); found {
+			passphrase = tui.Green(f.Passphrase)
+		}
+		rows = append(rows, []string{t.ESSID, t.BSSID, t.Station, t.Kind(), passphrase})
+	}
+
+	// found in previous sessions
+	for id, f := range mod.store.foundList() {
+		if !seen[id] {
+			rows = append(rows, []string{f.ESSID, f.BSSID, f.Station, tui.Dim("saved"), tui.Green(f.Passphrase)})
+		}
+	}
+
+	if len(rows) == 0 {
+		mod.Info("no handshakes or PMKIDs captured yet")
+		return nil
+	}
+
+	mod.Session.Println()
+	mod.Session.Table([]string{"ESSID", "BSSID", "Station", "Captured", "Passphrase"}, rows)
+	mod.Session.Println()
+
+	return nil
+}
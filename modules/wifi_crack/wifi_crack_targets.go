@@ -0,0 +1,222 @@
+package wifi_crack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// message pairs of the hashcat 22000 format, telling which messages the
+// ANonce and the EAPOL frame with the MIC come from
+const (
+	pairM1M2 = 0x00
+	pairM2M3 = 0x02
+)
+
+// eapolPair is an M2 with the ANonce of an M1 or M3 of the same handshake.
+type eapolPair struct {
+	ANonce  []byte
+	SNonce  []byte
+	MIC     []byte
+	Raw     []byte
+	Message byte
+}
+
+// Target is a client of an access point we have either the PMKID or a
+// crackable handshake for.
+type Target struct {
+	BSSID   string
+	ESSID   string
+	Station string
+	AP      net.HardwareAddr
+	STA     net.HardwareAddr
+	PMKID   []byte
+	Pairs   []eapolPair
+}
+
+func (t *Target) ID() string {
+	return t.BSSID + "/" + t.Station
+}
+
+func (t *Target) Kind() string {
+	kinds := make([]string, 0)
+	if t.PMKID != nil {
+		kinds = append(kinds, "PMKID")
+	}
+	if len(t.Pairs) > 0 {
+		kinds = append(kinds, "handshake")
+	}
+	return strings.Join(kinds, ", ")
+}
+
+// Check returns true if pmk is the pairwise master key of the target.
+func (t *Target) Check(pmk []byte) bool {
+	if t.PMKID != nil && bytes.Equal(packets.Dot11PMKID(pmk, t.AP, t.STA), t.PMKID) {
+		return true
+	}
+
+	for _, pair := range t.Pairs {
+		keys := packets.Dot11DeriveKeys(pmk, t.AP, t.STA, pair.ANonce, pair.SNonce)
+		if err, valid := packets.Dot11VerifyMIC(keys.KCK, pair.Raw); err == nil && valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Hashcat returns the target in the hashcat 22000 format, one line for the
+// PMKID and one for each handshake message pair.
+func (t *Target) Hashcat() []string {
+	lines := make([]string, 0)
+	essid := hex.EncodeToString([]byte(t.ESSID))
+	ap := hex.EncodeToString(t.AP)
+	sta := hex.EncodeToString(t.STA)
+
+	if t.PMKID != nil {
+		lines = append(lines, fmt.Sprintf("WPA*01*%x*%s*%s*%s***", t.PMKID, ap, sta, essid))
+	}
+
+	for _, pair := range t.Pairs {
+		// hashcat wants the frame with the MIC zeroed
+		raw := append([]byte{}, pair.Raw...)
+		copy(raw[81:97], make([]byte, 16))
+		lines = append(lines, fmt.Sprintf("WPA*02*%x*%s*%s*%s*%x*%x*%02x", pair.MIC, ap, sta, essid, pair.ANonce, raw, pair.Message))
+	}
+
+	return lines
+}
+
+// pmkidOf returns the PMKID carried by the key data of an M1, if any.
+func pmkidOf(pkt gopacket.Packet) []byte {
+	prevWasKey := false
+	for _, layer := range pkt.Layers() {
+		if layer.LayerType() == layers.LayerTypeEAPOLKey {
+			prevWasKey = true
+			continue
+		}
+
+		if prevWasKey && layer.LayerType() == layers.LayerTypeDot11InformationElement {
+			info := layer.(*layers.Dot11InformationElement)
+			if info.ID == layers.Dot11InformationElementIDVendor && info.Length == 20 && !allZeros(info.Info) {
+				return info.Info
+			}
+		}
+
+		prevWasKey = false
+	}
+	return nil
+}
+
+func allZeros(s []byte) bool {
+	for _, v := range s {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// newTarget builds the target from the handshake of the client, returning
+// nil if there's nothing to crack.
+func newTarget(ap *network.AccessPoint, client *network.Station) *Target {
+	hs := client.Handshake
+	hs.Lock()
+	challenges := append([]gopacket.Packet{}, hs.Challenges...)
+	responses := append([]gopacket.Packet{}, hs.Responses...)
+	confirmations := append([]gopacket.Packet{}, hs.Confirmations...)
+	hs.Unlock()
+
+	t := &Target{
+		BSSID:   ap.BSSID(),
+		ESSID:   ap.ESSID(),
+		Station: client.BSSID(),
+		AP:      ap.HW,
+		STA:     client.HW,
+		Pairs:   make([]eapolPair, 0),
+	}
+
+	for _, m1 := range challenges {
+		if t.PMKID = pmkidOf(m1); t.PMKID != nil {
+			break
+		}
+	}
+
+	// only the nonces of the most recent messages are paired
+	anonces := make(map[string]byte)
+	for _, m := range [][]gopacket.Packet{challenges, confirmations} {
+		for i := len(m) - 1; i >= 0; i-- {
+			if key, _ := packets.Dot11EAPOLRaw(m[i]); key != nil && !allZeros(key.Nonce) {
+				if _, found := anonces[string(key.Nonce)]; !found {
+					anonces[string(key.Nonce)] = map[bool]byte{true: pairM2M3, false: pairM1M2}[key.Install]
+				}
+				break
+			}
+		}
+	}
+
+	for i := len(responses) - 1; i >= 0; i-- {
+		key, raw := packets.Dot11EAPOLRaw(responses[i])
+		if key == nil || len(raw) < 97 {
+			continue
+		}
+
+		for anonce, message := range anonces {
+			t.Pairs = append(t.Pairs, eapolPair{
+				ANonce:  []byte(anonce),
+				SNonce:  key.Nonce,
+				MIC:     key.MIC,
+				Raw:     raw,
+				Message: message,
+			})
+		}
+		break
+	}
+
+	if t.ESSID == "" || (t.PMKID == nil && len(t.Pairs) == 0) {
+		return nil
+	}
+
+	sort.Slice(t.Pairs, func(i, j int) bool {
+		return t.Pairs[i].Message < t.Pairs[j].Message
+	})
+	return t
+}
+
+// collectTargets returns the targets for the access points in the filter, or
+// for every access point if the filter is empty.
+func (mod *WiFiCracker) collectTargets() []*Target {
+	filter := make(map[string]bool)
+	for _, bssid := range mod.filter {
+		if hw, err := net.ParseMAC(bssid); err == nil {
+			filter[hw.String()] = true
+		}
+	}
+
+	targets := make([]*Target, 0)
+	for _, ap := range mod.Session.WiFi.List() {
+		if len(filter) > 0 && !filter[ap.BSSID()] {
+			continue
+		}
+
+		for _, client := range ap.Clients() {
+			if t := newTarget(ap, client); t != nil {
+				targets = append(targets, t)
+			}
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].ID() < targets[j].ID()
+	})
+	return targets
+}
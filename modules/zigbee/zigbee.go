@@ -0,0 +1,236 @@
+package zigbee
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/modules/utils"
+	"github.com/bettercap/bettercap/packets"
+	"github.com/bettercap/bettercap/session"
+
+	"github.com/google/gopacket/pcapgo"
+)
+
+const (
+	firstChannel = 11
+	lastChannel  = 26
+)
+
+type ZigbeeRecon struct {
+	session.SessionModule
+	source     frameSource
+	sourceName string
+	zepAddress string
+	waitGroup  *sync.WaitGroup
+	channel    int
+	hopping    bool
+	hopPeriod  time.Duration
+	lastHop    time.Time
+	devTTL     time.Duration
+	output     string
+	outputFile *os.File
+	writer     *pcapgo.Writer
+	selector   *utils.ViewSelector
+}
+
+func NewZigbeeRecon(s *session.Session) *ZigbeeRecon {
+	mod := &ZigbeeRecon{
+		SessionModule: session.NewSessionModule("zigbee", s),
+		waitGroup:     &sync.WaitGroup{},
+		channel:       firstChannel,
+		hopPeriod:     500 * time.Millisecond,
+		lastHop:       time.Now(),
+		devTTL:        20 * time.Minute,
+	}
+
+	mod.State.Store("channel", &mod.channel)
+
+	mod.AddHandler(session.NewModuleHandler("zigbee.recon on", "",
+		"Start sniffing IEEE 802.15.4 frames to discover Zigbee networks and devices.",
+		func(args []string) error {
+			return mod.Start()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("zigbee.recon off", "",
+		"Stop sniffing IEEE 802.15.4 frames.",
+		func(args []string) error {
+			return mod.Stop()
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("zigbee.clear", "",
+		"Clear all devices collected by the Zigbee discovery module.",
+		func(args []string) error {
+			mod.Session.Zigbee.Clear()
+			return nil
+		}))
+
+	mod.AddHandler(session.NewModuleHandler("zigbee.show", "",
+		"Show the discovered Zigbee PANs and devices.",
+		func(args []string) error {
+			return mod.Show()
+		}))
+
+	mod.AddParam(session.NewStringParameter("zigbee.source",
+		"cc2531",
+		"^(cc2531|zep)$",
+		"Where to read frames from: cc2531 for a TI CC2531 USB dongle running the stock sniffer firmware, zep for ZEP datagrams sent by other sniffers (ConBee, whsniff, Wireshark extcap bridges, ...)."))
+
+	mod.AddParam(session.NewIntParameter("zigbee.channel",
+		"0",
+		"802.15.4 channel to sniff on (11-26), 0 to hop on all of them."))
+
+	mod.AddParam(session.NewIntParameter("zigbee.hop.period",
+		"500",
+		"Time in milliseconds to stay on each channel before hopping to the next one."))
+
+	mod.AddParam(session.NewStringParameter("zigbee.zep.address",
+		fmt.Sprintf("0.0.0.0:%d", packets.ZEPPort),
+		"",
+		"UDP address to listen on for ZEP datagrams when zigbee.source is zep."))
+
+	mod.AddParam(session.NewStringParameter("zigbee.output",
+		"",
+		"",
+		"If set, captured frames will be saved to this pcap file."))
+
+	mod.AddParam(session.NewIntParameter("zigbee.ttl",
+		"1200",
+		"Seconds of inactivity for a Zigbee device to be pruned."))
+
+	mod.selector = utils.ViewSelectorFor(&mod.SessionModule, "zigbee.show", []string{"address", "pan", "seen"}, "seen desc")
+
+	return mod
+}
+
+func (mod ZigbeeRecon) Name() string {
+	return "zigbee"
+}
+
+func (mod ZigbeeRecon) Description() string {
+	return "A passive scanner for IEEE 802.15.4 and Zigbee networks, using TI CC2531 sniffer dongles or any ZEP capable sniffer."
+}
+
+func (mod ZigbeeRecon) Author() string {
+	return "Simone Margaritelli <evilsocket@gmail.com>"
+}
+
+func (mod *ZigbeeRecon) Configure() (err error) {
+	var n int
+
+	if mod.Running() {
+		return session.ErrAlreadyStarted
+	}
+
+	if err, mod.sourceName = mod.StringParam("zigbee.source"); err != nil {
+		return err
+	} else if err, mod.zepAddress = mod.StringParam("zigbee.zep.address"); err != nil {
+		return err
+	}
+
+	if err, n = mod.IntParam("zigbee.channel"); err != nil {
+		return err
+	} else if n == 0 {
+		mod.hopping = true
+		mod.channel = firstChannel
+	} else if n < firstChannel || n > lastChannel {
+		return fmt.Errorf("invalid 802.15.4 channel %d, valid channels are %d-%d", n, firstChannel, lastChannel)
+	} else {
+		mod.hopping = false
+		mod.channel = n
+	}
+
+	if err, n = mod.IntParam("zigbee.hop.period"); err != nil {
+		return err
+	} else {
+		mod.hopPeriod = time.Duration(n) * time.Millisecond
+	}
+
+	if err, n = mod.IntParam("zigbee.ttl"); err != nil {
+		return err
+	} else {
+		mod.devTTL = time.Duration(n) * time.Second
+	}
+
+	if err, mod.source = openSource(mod.sourceName, mod.zepAddress); err != nil {
+		return err
+	}
+
+	mod.Debug("using %s", mod.source)
+
+	if err = mod.source.SetChannel(mod.channel); err != nil {
+		mod.source.Close()
+		return err
+	}
+
+	if err, mod.output = mod.StringParam("zigbee.output"); err != nil {
+		mod.source.Close()
+		return err
+	} else if mod.output != "" {
+		if mod.outputFile, err = os.Create(mod.output); err != nil {
+			mod.source.Close()
+			return err
+		}
+		mod.writer = pcapgo.NewWriter(mod.outputFile)
+		if err = mod.writer.WriteFileHeader(65536, linkTypeIEEE802154); err != nil {
+			mod.source.Close()
+			mod.outputFile.Close()
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (mod *ZigbeeRecon) Start() error {
+	if err := mod.Configure(); err != nil {
+		return err
+	}
+
+	return mod.SetRunning(true, func() {
+		mod.waitGroup.Add(1)
+		defer mod.waitGroup.Done()
+
+		go mod.devPruner()
+
+		if mod.hopping && mod.source.CanHop() {
+			mod.Info("hopping on channels %d-%d every %s", firstChannel, lastChannel, mod.hopPeriod)
+		} else {
+			mod.Info("sniffing on channel %d", mod.channel)
+		}
+
+		for mod.Running() {
+			if mod.hopping && mod.source.CanHop() {
+				mod.doHopping()
+			}
+
+			channel, frame, err := mod.source.Read()
+			if err != nil {
+				mod.Warning("error reading from %s: %v", mod.source, err)
+				continue
+			} else if frame == nil {
+				continue
+			}
+
+			mod.onFrame(channel, frame)
+		}
+
+		mod.Debug("stopped")
+	})
+}
+
+func (mod *ZigbeeRecon) Stop() error {
+	return mod.SetRunning(false, func() {
+		mod.waitGroup.Wait()
+		if mod.source != nil {
+			mod.source.Close()
+			mod.Debug("source closed")
+		}
+		if mod.outputFile != nil {
+			mod.outputFile.Close()
+			mod.outputFile = nil
+			mod.writer = nil
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package zigbee
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LINKTYPE_IEEE802_15_4_NOFCS, frames are logged without their FCS
+const linkTypeIEEE802154 = layers.LinkType(230)
+
+func (mod *ZigbeeRecon) doHopping() {
+	if time.Since(mod.lastHop) < mod.hopPeriod {
+		return
+	}
+
+	channel := mod.channel + 1
+	if channel > lastChannel {
+		channel = firstChannel
+	}
+
+	if err := mod.source.SetChannel(channel); err != nil {
+		mod.Warning("error hopping on channel %d: %v", channel, err)
+	} else {
+		mod.channel = channel
+	}
+	mod.lastHop = time.Now()
+}
+
+func (mod *ZigbeeRecon) logFrame(frame []byte) {
+	if mod.writer == nil {
+		return
+	}
+
+	info := gopacket.CaptureInfo{
+		Timestamp:     time.Now(),
+		CaptureLength: len(frame),
+		Length:        len(frame),
+	}
+
+	if err := mod.writer.WritePacket(info, frame); err != nil {
+		mod.Error("error while writing to %s: %v", mod.output, err)
+	}
+}
+
+func (mod *ZigbeeRecon) onFrame(channel int, raw []byte) {
+	mod.logFrame(raw)
+
+	err, frame := packets.Dot15d4Parse(raw)
+	if err != nil {
+		mod.Debug("channel %d: %v", channel, err)
+		return
+	}
+
+	if frame.Type == packets.Dot15d4TypeCommand && frame.Command == packets.Dot15d4CmdBeaconRequest {
+		mod.Debug("beacon request on channel %d", channel)
+	}
+
+	if frame.SrcAddr != "" {
+		_, dev := mod.Session.Zigbee.AddIfNew(frame.SrcAddr, frame.SrcPAN, channel)
+		mod.updateDevice(dev, frame)
+	}
+
+	if frame.DstAddr != "" && frame.DstAddr != "0xffff" && frame.DstPAN != packets.Dot15d4Broadcast {
+		mod.Session.Zigbee.AddIfNew(frame.DstAddr, frame.DstPAN, channel)
+	}
+}
+
+func (mod *ZigbeeRecon) updateDevice(dev *network.ZigbeeDevice, frame *packets.Dot15d4Frame) {
+	dev.Lock()
+
+	if frame.Security {
+		dev.Encrypted = true
+	}
+
+	if frame.Type == packets.Dot15d4TypeCommand && frame.Command == packets.Dot15d4CmdAssociationRequest {
+		mod.Info("%s is asking to join PAN %s", dev.Address, network.ZigbeePAN(frame.DstPAN))
+	}
+
+	permitChanged := false
+	if b := frame.Beacon; b != nil {
+		dev.Coordinator = b.PANCoordinator
+		if b.Zigbee {
+			dev.ExtPAN = b.ExtendedPANID
+		}
+		permitChanged = b.AssociationPermit != dev.PermitJoin
+		dev.PermitJoin = b.AssociationPermit
+	}
+
+	dev.Unlock()
+
+	if permitChanged {
+		mod.Session.Events.Add("zigbee.permit.join", dev)
+	}
+}
+
+func (mod *ZigbeeRecon) devPruner() {
+	mod.waitGroup.Add(1)
+	defer mod.waitGroup.Done()
+
+	mod.Debug("devices pruner started.")
+	for mod.Running() {
+		for _, dev := range mod.Session.Zigbee.Devices() {
+			sinceLastSeen := time.Since(dev.LastSeen)
+			if sinceLastSeen > mod.devTTL {
+				mod.Debug("device %s not seen in %s, removing.", dev.Address, sinceLastSeen)
+				mod.Session.Zigbee.Remove(dev.Address)
+			}
+		}
+		time.Sleep(30 * time.Second)
+	}
+}
+
+func channelName(channel int) string {
+	// 2405 MHz + 5 MHz * (k - 11)
+	return fmt.Sprintf("%d (%d MHz)", channel, 2405+5*(channel-firstChannel))
+}
@@ -0,0 +1,150 @@
+package zigbee
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+var (
+	PresentTimeInterval    = time.Duration(1) * time.Minute
+	JustJoinedTimeInterval = time.Duration(10) * time.Second
+)
+
+func (mod *ZigbeeRecon) getRow(dev *network.ZigbeeDevice) []string {
+	dev.Lock()
+	defer dev.Unlock()
+
+	sinceLastSeen := time.Since(dev.LastSeen)
+	seen := dev.LastSeen.Format("15:04:05")
+
+	if sinceLastSeen <= JustJoinedTimeInterval {
+		seen = tui.Bold(seen)
+	} else if sinceLastSeen > PresentTimeInterval {
+		seen = tui.Dim(seen)
+	}
+
+	role := ""
+	if dev.Coordinator {
+		role = tui.Bold("coordinator")
+	}
+
+	join := ""
+	if dev.PermitJoin {
+		join = tui.Red("open")
+	}
+
+	encrypted := tui.Red("no")
+	if dev.Encrypted {
+		encrypted = tui.Green("yes")
+	}
+
+	return []string{
+		dev.Address,
+		network.ZigbeePAN(dev.PAN),
+		dev.ExtPAN,
+		role,
+		join,
+		encrypted,
+		fmt.Sprintf("%d", dev.Channel),
+		fmt.Sprintf("%d", dev.Frames),
+		seen,
+	}
+}
+
+func (mod *ZigbeeRecon) doFilter(dev *network.ZigbeeDevice) bool {
+	if mod.selector.Expression == nil {
+		return true
+	}
+	return mod.selector.Expression.MatchString(dev.Address) ||
+		mod.selector.Expression.MatchString(dev.PANString()) ||
+		mod.selector.Expression.MatchString(dev.ExtPAN)
+}
+
+func (mod *ZigbeeRecon) doSelection() (err error, devices []*network.ZigbeeDevice) {
+	if err = mod.selector.Update(); err != nil {
+		return
+	}
+
+	filtered := []*network.ZigbeeDevice{}
+	for _, dev := range mod.Session.Zigbee.Devices() {
+		if mod.doFilter(dev) {
+			filtered = append(filtered, dev)
+		}
+	}
+	devices = filtered
+
+	switch mod.selector.SortField {
+	case "address":
+		sort.Sort(ByZigbeeAddressSorter(devices))
+	case "pan":
+		sort.Sort(ByZigbeePANSorter(devices))
+	case "seen":
+		sort.Sort(ByZigbeeSeenSorter(devices))
+	}
+
+	// default is asc
+	if mod.selector.Sort == "desc" {
+		// from https://github.com/golang/go/wiki/SliceTricks
+		for i := len(devices)/2 - 1; i >= 0; i-- {
+			opp := len(devices) - 1 - i
+			devices[i], devices[opp] = devices[opp], devices[i]
+		}
+	}
+
+	if mod.selector.Limit > 0 {
+		limit := mod.selector.Limit
+		max := len(devices)
+		if limit > max {
+			limit = max
+		}
+		devices = devices[0:limit]
+	}
+
+	return
+}
+
+func (mod *ZigbeeRecon) colNames() []string {
+	colNames := []string{"Address", "PAN", "Extended PAN", "Role", "Join", "Encrypted", "Channel", "Frames", "Seen"}
+	switch mod.selector.SortField {
+	case "address":
+		colNames[0] += " " + mod.selector.SortSymbol
+	case "pan":
+		colNames[1] += " " + mod.selector.SortSymbol
+	case "seen":
+		colNames[8] += " " + mod.selector.SortSymbol
+	}
+	return colNames
+}
+
+func (mod *ZigbeeRecon) Show() (err error) {
+	var devices []*network.ZigbeeDevice
+	if err, devices = mod.doSelection(); err != nil {
+		return
+	}
+
+	rows := make([][]string, 0)
+	pans := make(map[uint16]bool)
+	for _, dev := range devices {
+		rows = append(rows, mod.getRow(dev))
+		pans[dev.PAN] = true
+	}
+
+	mod.Session.Table(mod.colNames(), rows)
+
+	if mod.Running() {
+		mod.Session.Printf("\n%d devices on %d PANs, channel:%s\n\n", len(devices), len(pans), channelName(mod.channel))
+	} else {
+		mod.Session.Printf("\n%d devices on %d PANs\n\n", len(devices), len(pans))
+	}
+
+	if len(rows) > 0 {
+		mod.Session.Refresh()
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package zigbee
+
+import (
+	"github.com/bettercap/bettercap/network"
+)
+
+type ByZigbeeAddressSorter []*network.ZigbeeDevice
+
+func (a ByZigbeeAddressSorter) Len() int           { return len(a) }
+func (a ByZigbeeAddressSorter) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ByZigbeeAddressSorter) Less(i, j int) bool { return a[i].Address < a[j].Address }
+
+type ByZigbeePANSorter []*network.ZigbeeDevice
+
+func (a ByZigbeePANSorter) Len() int      { return len(a) }
+func (a ByZigbeePANSorter) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByZigbeePANSorter) Less(i, j int) bool {
+	if a[i].PAN == a[j].PAN {
+		return a[i].Address < a[j].Address
+	}
+	return a[i].PAN < a[j].PAN
+}
+
+type ByZigbeeSeenSorter []*network.ZigbeeDevice
+
+func (a ByZigbeeSeenSorter) Len() int           { return len(a) }
+func (a ByZigbeeSeenSorter) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ByZigbeeSeenSorter) Less(i, j int) bool { return a[i].LastSeen.Before(a[j].LastSeen) }
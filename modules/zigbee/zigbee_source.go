@@ -0,0 +1,237 @@
+package zigbee
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gousb"
+)
+
+// frameSource is anything 802.15.4 frames can be read from, frames
+// are returned without the trailing FCS bytes.
+type frameSource interface {
+	fmt.Stringer
+	CanHop() bool
+	SetChannel(channel int) error
+	// Read returns a nil frame without error on timeouts so that the
+	// caller can periodically check its state and hop.
+	Read() (int, []byte, error)
+	Close()
+}
+
+const readTimeout = 500 * time.Millisecond
+
+func openSource(name, zepAddress string) (error, frameSource) {
+	switch name {
+	case "cc2531":
+		return openCC2531()
+	case "zep":
+		return openZEP(zepAddress)
+	}
+	return fmt.Errorf("unknown source %s", name), nil
+}
+
+// TI CC2531 USB dongle running the stock packet sniffer firmware.
+const (
+	cc2531VendorID  gousb.ID = 0x0451
+	cc2531ProductID gousb.ID = 0x16ae
+
+	cc2531ReqOut = 0x40
+	cc2531ReqIn  = 0xc0
+
+	cc2531SetPower = 0xc5
+	cc2531GetPower = 0xc6
+	cc2531SetChan  = 0xd2
+	cc2531Start    = 0xd0
+	cc2531Stop     = 0xd1
+
+	cc2531PowerOn    = 4
+	cc2531DataEP     = 0x83
+	cc2531HeaderSize = 8
+	cc2531BufferSize = 256
+)
+
+type cc2531 struct {
+	ctx       *gousb.Context
+	dev       *gousb.Device
+	iface     *gousb.Interface
+	ifaceDone func()
+	reader    *gousb.InEndpoint
+	channel   int
+	started   bool
+}
+
+func openCC2531() (error, frameSource) {
+	var err error
+
+	s := &cc2531{ctx: gousb.NewContext()}
+	if s.dev, err = s.ctx.OpenDeviceWithVIDPID(cc2531VendorID, cc2531ProductID); s.dev == nil && err == nil {
+		err = fmt.Errorf("usb device %s:%s not found", cc2531VendorID, cc2531ProductID)
+	}
+	if err != nil {
+		s.Close()
+		return fmt.Errorf("make sure that a CC2531 dongle running the sniffer firmware is connected: %v", err), nil
+	}
+
+	if s.iface, s.ifaceDone, err = s.dev.DefaultInterface(); err != nil {
+		s.Close()
+		return err, nil
+	} else if s.reader, err = s.iface.InEndpoint(cc2531DataEP); err != nil {
+		s.Close()
+		return err, nil
+	}
+
+	if _, err = s.dev.Control(cc2531ReqOut, cc2531SetPower, cc2531PowerOn, 0, nil); err != nil {
+		s.Close()
+		return err, nil
+	}
+
+	// wait for the radio to be powered up
+	power := []byte{0}
+	for attempt := 0; power[0] != cc2531PowerOn; attempt++ {
+		if attempt == 10 {
+			s.Close()
+			return fmt.Errorf("timeout while powering up the CC2531 radio"), nil
+		} else if _, err = s.dev.Control(cc2531ReqIn, cc2531GetPower, 0, 0, power); err != nil {
+			s.Close()
+			return err, nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return nil, s
+}
+
+func (s *cc2531) String() string {
+	return fmt.Sprintf("CC2531 sniffer %s", s.dev)
+}
+
+func (s *cc2531) CanHop() bool {
+	return true
+}
+
+func (s *cc2531) SetChannel(channel int) (err error) {
+	if s.started {
+		if _, err = s.dev.Control(cc2531ReqOut, cc2531Stop, 0, 0, nil); err != nil {
+			return
+		}
+	}
+
+	if _, err = s.dev.Control(cc2531ReqOut, cc2531SetChan, 0, 0, []byte{byte(channel)}); err != nil {
+		return
+	} else if _, err = s.dev.Control(cc2531ReqOut, cc2531SetChan, 0, 1, []byte{0}); err != nil {
+		return
+	} else if _, err = s.dev.Control(cc2531ReqOut, cc2531Start, 0, 0, nil); err != nil {
+		return
+	}
+
+	s.started = true
+	s.channel = channel
+	return nil
+}
+
+func (s *cc2531) Read() (int, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), readTimeout)
+	defer cancel()
+
+	buf := make([]byte, cc2531BufferSize)
+	n, err := s.reader.ReadContext(ctx, buf)
+	if err != nil {
+		if ctx.Err() != nil {
+			return s.channel, nil, nil
+		}
+		return s.channel, nil, err
+	}
+
+	// type (1) | length (2) | timestamp (4) | frame length (1) | frame | rssi (1) | fcs ok + lqi (1)
+	if n < cc2531HeaderSize || buf[0] != 0 {
+		return s.channel, nil, nil
+	}
+
+	size := int(binary.LittleEndian.Uint16(buf[1:3]))
+	frameSize := int(buf[7])
+	if frameSize < 2 || cc2531HeaderSize+frameSize > n || size < frameSize {
+		return s.channel, nil, nil
+	} else if buf[cc2531HeaderSize+frameSize-1]&0x80 == 0 {
+		// bad FCS
+		return s.channel, nil, nil
+	}
+
+	return s.channel, buf[cc2531HeaderSize : cc2531HeaderSize+frameSize-2], nil
+}
+
+func (s *cc2531) Close() {
+	if s.dev != nil && s.started {
+		s.dev.Control(cc2531ReqOut, cc2531Stop, 0, 0, nil)
+	}
+	if s.ifaceDone != nil {
+		s.ifaceDone()
+	}
+	if s.dev != nil {
+		s.dev.Close()
+	}
+	if s.ctx != nil {
+		s.ctx.Close()
+	}
+}
+
+// ZEP datagrams forwarded by any other sniffer, the channel is
+// decided by the sender and reported in each datagram.
+type zep struct {
+	conn *net.UDPConn
+}
+
+func openZEP(address string) (error, frameSource) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return err, nil
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err, nil
+	}
+
+	return nil, &zep{conn: conn}
+}
+
+func (s *zep) String() string {
+	return fmt.Sprintf("ZEP listener on %s", s.conn.LocalAddr())
+}
+
+func (s *zep) CanHop() bool {
+	return false
+}
+
+func (s *zep) SetChannel(channel int) error {
+	return nil
+}
+
+func (s *zep) Read() (int, []byte, error) {
+	buf := make([]byte, 2048)
+
+	s.conn.SetReadDeadline(time.Now().Add(readTimeout))
+	n, _, err := s.conn.ReadFromUDP(buf)
+	if err != nil {
+		if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+			return 0, nil, nil
+		}
+		return 0, nil, err
+	}
+
+	err, z := packets.ZEPParse(buf[:n])
+	if err != nil || len(z.Frame) < 2 {
+		return 0, nil, nil
+	}
+
+	return z.Channel, z.Frame[:len(z.Frame)-2], nil
+}
+
+func (s *zep) Close() {
+	s.conn.Close()
+}
@@ -0,0 +1,383 @@
+package network
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ADDomainNewCallback func(domain ADDomain)
+type ADControllerNewCallback func(dc ADController)
+
+type ADController struct {
+	Domain    string    `json:"domain"`
+	Address   string    `json:"address"`
+	Hostname  string    `json:"hostname"`
+	Site      string    `json:"site"`
+	PDC       bool      `json:"pdc"`
+	Sources   []string  `json:"sources"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// ADMachine is a computer account, Name is the account name (WS01$).
+type ADMachine struct {
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// ADDomain is an Active Directory domain, either of Name (the DNS name) and
+// NetBIOS can be empty until a packet mentioning both is seen.
+type ADDomain struct {
+	Name        string          `json:"name"`
+	NetBIOS     string          `json:"netbios"`
+	Forest      string          `json:"forest"`
+	Controllers []*ADController `json:"controllers"`
+	Machines    []*ADMachine    `json:"machines"`
+	FirstSeen   time.Time       `json:"first_seen"`
+	LastSeen    time.Time       `json:"last_seen"`
+}
+
+// ADSighting is what a parser learned from a single packet.
+type ADSighting struct {
+	Source string
+	// DNS and NetBIOS names of the domain, at least one is required
+	Domain  string
+	NetBIOS string
+	Forest  string
+	// address and hostname of a domain controller
+	Controller     string
+	ControllerName string
+	Site           string
+	PDC            bool
+	// computer account and address of a domain member
+	Machine        string
+	MachineAddress string
+}
+
+func (d *ADDomain) copy() ADDomain {
+	cp := *d
+	cp.Controllers = make([]*ADController, len(d.Controllers))
+	for i, dc := range d.Controllers {
+		c := *dc
+		c.Sources = append([]string{}, dc.Sources...)
+		cp.Controllers[i] = &c
+	}
+	cp.Machines = make([]*ADMachine, len(d.Machines))
+	for i, m := range d.Machines {
+		c := *m
+		cp.Machines[i] = &c
+	}
+	return cp
+}
+
+// ID is the name the domain is shown with.
+func (d *ADDomain) ID() string {
+	if d.Name != "" {
+		return d.Name
+	}
+	return d.NetBIOS
+}
+
+// controller returns the controller with the given address or, if it's
+// unknown, the one with the same hostname.
+func (d *ADDomain) controller(address, hostname string) *ADController {
+	for _, dc := range d.Controllers {
+		if address != "" && dc.Address == address {
+			return dc
+		}
+	}
+	for _, dc := range d.Controllers {
+		if hostname != "" && dc.Hostname == hostname && (address == "" || dc.Address == "") {
+			return dc
+		}
+	}
+	return nil
+}
+
+// dedup merges into dc the entries seen with its hostname only, returning
+// true if there were any.
+func (d *ADDomain) dedup(dc *ADController) bool {
+	merged := false
+	kept := d.Controllers[:0]
+	for _, e := range d.Controllers {
+		if e != dc && e.Address == "" && e.Hostname != "" && e.Hostname == dc.Hostname {
+			dc.absorb(e)
+			merged = true
+			continue
+		}
+		kept = append(kept, e)
+	}
+	d.Controllers = kept
+	return merged
+}
+
+func (dc *ADController) absorb(other *ADController) {
+	if dc.Address == "" {
+		dc.Address = other.Address
+	}
+	if dc.Hostname == "" {
+		dc.Hostname = other.Hostname
+	}
+	if dc.Site == "" {
+		dc.Site = other.Site
+	}
+	dc.PDC = dc.PDC || other.PDC
+	if other.FirstSeen.Before(dc.FirstSeen) {
+		dc.FirstSeen = other.FirstSeen
+	}
+	if other.LastSeen.After(dc.LastSeen) {
+		dc.LastSeen = other.LastSeen
+	}
+	for _, src := range other.Sources {
+		dc.addSource(src)
+	}
+}
+
+func (dc *ADController) addSource(source string) {
+	if source != "" && !stringsContain(dc.Sources, source) {
+		dc.Sources = append(dc.Sources, source)
+		sort.Strings(dc.Sources)
+	}
+}
+
+func (d *ADDomain) sortControllers() {
+	sort.Slice(d.Controllers, func(i, j int) bool {
+		if d.Controllers[i].Address == d.Controllers[j].Address {
+			return d.Controllers[i].Hostname < d.Controllers[j].Hostname
+		}
+		return d.Controllers[i].Address < d.Controllers[j].Address
+	})
+}
+
+func (d *ADDomain) machine(name string) *ADMachine {
+	for _, m := range d.Machines {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func (d *ADDomain) merge(other *ADDomain) {
+	if d.Name == "" {
+		d.Name = other.Name
+	}
+	if d.NetBIOS == "" {
+		d.NetBIOS = other.NetBIOS
+	}
+	if d.Forest == "" {
+		d.Forest = other.Forest
+	}
+	if other.FirstSeen.Before(d.FirstSeen) {
+		d.FirstSeen = other.FirstSeen
+	}
+	for _, dc := range other.Controllers {
+		if known := d.controller(dc.Address, dc.Hostname); known != nil {
+			known.absorb(dc)
+		} else {
+			d.Controllers = append(d.Controllers, dc)
+		}
+	}
+	for _, m := range other.Machines {
+		if d.machine(m.Name) == nil {
+			d.Machines = append(d.Machines, m)
+		}
+	}
+	d.sortControllers()
+}
+
+// AD is the Active Directory inventory built from the sniffed Kerberos, LDAP,
+// DNS and NetBIOS traffic.
+type AD struct {
+	sync.RWMutex
+	domains      []*ADDomain
+	domainCb     ADDomainNewCallback
+	controllerCb ADControllerNewCallback
+}
+
+func NewAD(domainCb ADDomainNewCallback, controllerCb ADControllerNewCallback) *AD {
+	return &AD{
+		domains:      make([]*ADDomain, 0),
+		domainCb:     domainCb,
+		controllerCb: controllerCb,
+	}
+}
+
+func (ad *AD) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ad.Domains())
+}
+
+// Domains returns a copy of the domains, sorted by name.
+func (ad *AD) Domains() []ADDomain {
+	ad.RLock()
+	defer ad.RUnlock()
+
+	domains := make([]ADDomain, len(ad.domains))
+	for i, d := range ad.domains {
+		domains[i] = d.copy()
+	}
+
+	sort.Slice(domains, func(i, j int) bool {
+		return domains[i].ID() < domains[j].ID()
+	})
+
+	return domains
+}
+
+// Get returns a copy of the domain with the given DNS or NetBIOS name.
+func (ad *AD) Get(name string) (ADDomain, bool) {
+	ad.RLock()
+	defer ad.RUnlock()
+
+	for _, d := range ad.domains {
+		if strings.EqualFold(d.Name, name) || strings.EqualFold(d.NetBIOS, name) {
+			return d.copy(), true
+		}
+	}
+	return ADDomain{}, false
+}
+
+func (ad *AD) Clear() {
+	ad.Lock()
+	defer ad.Unlock()
+	ad.domains = make([]*ADDomain, 0)
+}
+
+func normalizeADName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+// domain returns the domain matching the DNS or the NetBIOS name, merging the
+// entries seen with only one of them, or creates it.
+func (ad *AD) domain(name, netbios string, now time.Time) (*ADDomain, bool) {
+	var match *ADDomain
+	for i := 0; i < len(ad.domains); i++ {
+		d := ad.domains[i]
+		if (name == "" || d.Name == "" || d.Name == name) && (netbios == "" || d.NetBIOS == "" || d.NetBIOS == netbios) &&
+			((name != "" && d.Name == name) || (netbios != "" && d.NetBIOS == netbios)) {
+			if match == nil {
+				match = d
+			} else {
+				match.merge(d)
+				ad.domains = append(ad.domains[:i], ad.domains[i+1:]...)
+				i--
+			}
+		}
+	}
+
+	if match != nil {
+		return match, false
+	}
+
+	d := &ADDomain{
+		Controllers: make([]*ADController, 0),
+		Machines:    make([]*ADMachine, 0),
+		FirstSeen:   now,
+	}
+	ad.domains = append(ad.domains, d)
+	return d, true
+}
+
+// Add updates the inventory with what was seen in a packet.
+func (ad *AD) Add(s ADSighting) {
+	name, netbios := normalizeADName(s.Domain), strings.ToUpper(strings.TrimSpace(s.NetBIOS))
+	if name == "" && netbios == "" {
+		return
+	}
+
+	ad.Lock()
+	defer ad.Unlock()
+
+	now := time.Now()
+	d, isNew := ad.domain(name, netbios, now)
+	if d.Name == "" {
+		d.Name = name
+	}
+	if d.NetBIOS == "" {
+		d.NetBIOS = netbios
+	}
+	if forest := normalizeADName(s.Forest); forest != "" {
+		d.Forest = forest
+	}
+	d.LastSeen = now
+	// the domain may have just been named
+	for _, dc := range d.Controllers {
+		dc.Domain = d.ID()
+	}
+
+	if isNew && ad.domainCb != nil {
+		ad.domainCb(d.copy())
+	}
+
+	if hostname := normalizeADName(s.ControllerName); s.Controller != "" || hostname != "" {
+		dc := d.controller(s.Controller, hostname)
+		newDC := dc == nil
+		if newDC {
+			dc = &ADController{
+				Sources:   make([]string, 0),
+				FirstSeen: now,
+			}
+			d.Controllers = append(d.Controllers, dc)
+		}
+
+		dc.Domain = d.ID()
+		if s.Controller != "" {
+			dc.Address = s.Controller
+		}
+		if hostname != "" {
+			dc.Hostname = hostname
+		}
+		if s.Site != "" {
+			dc.Site = s.Site
+		}
+		dc.PDC = dc.PDC || s.PDC
+		dc.LastSeen = now
+		dc.addSource(s.Source)
+		if d.dedup(dc) {
+			newDC = false
+		}
+		d.sortControllers()
+
+		if newDC && ad.controllerCb != nil {
+			cp := *dc
+			cp.Sources = append([]string{}, dc.Sources...)
+			ad.controllerCb(cp)
+		}
+	}
+
+	if machine := strings.ToUpper(strings.TrimSpace(s.Machine)); machine != "" {
+		if !strings.HasSuffix(machine, "$") {
+			machine += "$"
+		}
+
+		m := d.machine(machine)
+		if m == nil {
+			m = &ADMachine{
+				Name:      machine,
+				FirstSeen: now,
+			}
+			d.Machines = append(d.Machines, m)
+			sort.Slice(d.Machines, func(i, j int) bool {
+				return d.Machines[i].Name < d.Machines[j].Name
+			})
+		}
+		if s.MachineAddress != "" {
+			m.Address = s.MachineAddress
+		}
+		m.LastSeen = now
+	}
+}
+
+func stringsContain(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
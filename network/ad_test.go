@@ -0,0 +1,64 @@
+package network
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestADAdd(t *testing.T) {
+	domains, controllers := 0, 0
+	ad := NewAD(func(d ADDomain) {
+		domains++
+	}, func(dc ADController) {
+		controllers++
+	})
+
+	// the DNS name from a kerberos realm, the NetBIOS one from a registration
+	ad.Add(ADSighting{Source: "krb5", Domain: "CORP.LOCAL", Controller: "10.0.0.1", Machine: "ws01$", MachineAddress: "10.0.0.50"})
+	ad.Add(ADSighting{Source: "nbns", NetBIOS: "corp", Controller: "10.0.0.1"})
+	ad.Add(ADSighting{Source: "dns", Domain: "corp.local.", ControllerName: "DC02.corp.local", Site: "HQ"})
+
+	if n := len(ad.Domains()); n != 2 {
+		t.Fatalf("expected 2 domains before the netlogon response, got %d", n)
+	}
+
+	// a netlogon response names both
+	ad.Add(ADSighting{Source: "ldap", Domain: "corp.local", NetBIOS: "CORP", Controller: "10.0.0.2", ControllerName: "dc02.corp.local", PDC: true})
+
+	all := ad.Domains()
+	if len(all) != 1 {
+		t.Fatalf("expected the domains to be merged, got %d", len(all))
+	}
+
+	d := all[0]
+	if d.Name != "corp.local" || d.NetBIOS != "CORP" {
+		t.Fatalf("unexpected domain %s/%s", d.Name, d.NetBIOS)
+	} else if len(d.Controllers) != 2 {
+		t.Fatalf("expected 2 domain controllers, got %+v", d.Controllers)
+	} else if len(d.Machines) != 1 || d.Machines[0].Name != "WS01$" || d.Machines[0].Address != "10.0.0.50" {
+		t.Fatalf("unexpected machines %+v", d.Machines)
+	}
+
+	dc1, dc2 := d.Controllers[0], d.Controllers[1]
+	if dc1.Address != "10.0.0.1" || dc1.Domain != "corp.local" || len(dc1.Sources) != 2 {
+		t.Fatalf("unexpected first controller %+v", dc1)
+	} else if dc2.Hostname != "dc02.corp.local" || dc2.Address != "10.0.0.2" || !dc2.PDC || dc2.Site != "HQ" {
+		t.Fatalf("unexpected second controller %+v", dc2)
+	}
+
+	// 10.0.0.1 was seen by both entries, dc02 hostname first then its address
+	if domains != 2 || controllers != 3 {
+		t.Fatalf("unexpected callbacks, %d domains and %d controllers", domains, controllers)
+	}
+
+	if _, found := ad.Get("corp"); !found {
+		t.Fatal("domain not found by NetBIOS name")
+	} else if _, err := json.Marshal(ad); err != nil {
+		t.Fatal(err)
+	}
+
+	ad.Clear()
+	if len(ad.Domains()) != 0 {
+		t.Fatal("expected no domains after Clear")
+	}
+}
@@ -1,7 +1,11 @@
 package network
 
 import (
+	"bytes"
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -10,6 +14,13 @@ import (
 
 type ArpTable map[string]string
 
+// ArpEntry is an entry of the ARP cache of the operating system.
+type ArpEntry struct {
+	IP        string `json:"ip"`
+	MAC       string `json:"mac"`
+	Interface string `json:"interface"`
+}
+
 var (
 	arpWasParsed = false
 	arpLock      = &sync.RWMutex{}
@@ -30,30 +41,79 @@ func ArpUpdate(iface string) (ArpTable, error) {
 	}
 
 	newTable := make(ArpTable)
+	for _, entry := range parseArpTable(output, iface) {
+		if entry.Interface == iface {
+			newTable[entry.IP] = entry.MAC
+		}
+	}
+
+	arpTable = newTable
+
+	return arpTable, nil
+}
+
+// parseArpTable parses the output of the ARP command, iface is used for the
+// entries of platforms that don't report the interface.
+func parseArpTable(output string, iface string) []ArpEntry {
+	entries := make([]ArpEntry, 0)
+	section := iface
 	for _, line := range strings.Split(output, "\n") {
+		// on Windows the entries are grouped by interface index
+		if ArpTableSectionParser != nil {
+			if m := ArpTableSectionParser.FindStringSubmatch(line); len(m) == 2 {
+				section = iface
+				if index, err := strconv.ParseInt(m[1], 16, 32); err == nil {
+					if ifi, err := net.InterfaceByIndex(int(index)); err == nil {
+						section = getInterfaceName(*ifi)
+					}
+				}
+				continue
+			}
+		}
+
 		m := ArpTableParser.FindStringSubmatch(line)
 		if len(m) == ArpTableTokens {
 			ipIndex := ArpTableTokenIndex[0]
 			hwIndex := ArpTableTokenIndex[1]
 			ifIndex := ArpTableTokenIndex[2]
 
-			address := m[ipIndex]
-			mac := m[hwIndex]
-			ifname := iface
+			entry := ArpEntry{
+				IP:        m[ipIndex],
+				MAC:       NormalizeMac(m[hwIndex]),
+				Interface: section,
+			}
 
 			if ifIndex != -1 {
-				ifname = m[ifIndex]
+				entry.Interface = m[ifIndex]
 			}
 
-			if ifname == iface {
-				newTable[address] = mac
+			// skip the static broadcast and multicast entries
+			if hw, err := net.ParseMAC(entry.MAC); err != nil || hw[0]&0x01 != 0 {
+				continue
 			}
+
+			entries = append(entries, entry)
 		}
 	}
+	return entries
+}
 
-	arpTable = newTable
+// ArpEntries returns the whole ARP cache of the operating system, for every interface.
+func ArpEntries() ([]ArpEntry, error) {
+	output, err := core.Exec(ArpCmd, ArpCmdOpts)
+	if err != nil {
+		return nil, err
+	}
 
-	return arpTable, nil
+	entries := parseArpTable(output, "")
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Interface != entries[j].Interface {
+			return entries[i].Interface < entries[j].Interface
+		}
+		return bytes.Compare(net.ParseIP(entries[i].IP), net.ParseIP(entries[j].IP)) < 0
+	})
+
+	return entries, nil
 }
 
 func ArpLookup(iface string, address string, refresh bool) (string, error) {
@@ -3,6 +3,7 @@ package network
 import "regexp"
 
 var ArpTableParser = regexp.MustCompile(`^[^\d\.]+([\d\.]+).+\s+([a-f0-9\-]{11,17})\s+.+$`)
+var ArpTableSectionParser = regexp.MustCompile(`^Interface:\s+[\d\.]+\s+---\s+0x([a-f0-9]+)`)
 var ArpTableTokens = 3
 var ArpTableTokenIndex = []int{1, 2, -1}
 var ArpCmd = "arp"
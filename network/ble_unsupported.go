@@ -42,6 +42,10 @@ func (b *BLE) Get(id string) (dev *BLEDevice, found bool) {
 	return
 }
 
+func (b *BLE) Devices() (devices []*BLEDevice) {
+	return
+}
+
 func (b *BLE) MarshalJSON() ([]byte, error) {
 	doc := bleJSON{
 		Devices: make([]*BLEDevice, 0),
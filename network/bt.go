@@ -0,0 +1,282 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type BTDevNewCallback func(dev *BTDevice)
+type BTDevLostCallback func(dev *BTDevice)
+
+var btMajorClasses = map[uint32]string{
+	0:  "Miscellaneous",
+	1:  "Computer",
+	2:  "Phone",
+	3:  "Network Access Point",
+	4:  "Audio/Video",
+	5:  "Peripheral",
+	6:  "Imaging",
+	7:  "Wearable",
+	8:  "Toy",
+	9:  "Health",
+	31: "Uncategorized",
+}
+
+var btMinorClasses = map[uint32]map[uint32]string{
+	1: {
+		1: "Desktop",
+		2: "Server",
+		3: "Laptop",
+		4: "Handheld",
+		5: "Palm",
+		6: "Wearable",
+		7: "Tablet",
+	},
+	2: {
+		1: "Cellular",
+		2: "Cordless",
+		3: "Smartphone",
+		4: "Modem",
+		5: "ISDN",
+	},
+	4: {
+		1:  "Headset",
+		2:  "Hands-free",
+		4:  "Microphone",
+		5:  "Loudspeaker",
+		6:  "Headphones",
+		7:  "Portable Audio",
+		8:  "Car Audio",
+		9:  "Set-top Box",
+		10: "HiFi Audio",
+		11: "VCR",
+		12: "Video Camera",
+		13: "Camcorder",
+		14: "Video Monitor",
+		15: "Video Display",
+		16: "Video Conferencing",
+		18: "Gaming Toy",
+	},
+	7: {
+		1: "Wristwatch",
+		2: "Pager",
+		3: "Jacket",
+		4: "Helmet",
+		5: "Glasses",
+	},
+}
+
+// service class bits of the class of device
+var btServiceClasses = []struct {
+	bit  uint
+	name string
+}{
+	{13, "Limited Discoverable"},
+	{16, "Positioning"},
+	{17, "Networking"},
+	{18, "Rendering"},
+	{19, "Capturing"},
+	{20, "Object Transfer"},
+	{21, "Audio"},
+	{22, "Telephony"},
+	{23, "Information"},
+}
+
+// BTDeviceClass decodes the major and minor device classes and the service
+// classes of a Bluetooth class of device.
+func BTDeviceClass(class uint32) (major, minor string, services []string) {
+	majorID := (class >> 8) & 0x1f
+	minorID := (class >> 2) & 0x3f
+
+	if major = btMajorClasses[majorID]; major == "" {
+		major = fmt.Sprintf("Reserved (%d)", majorID)
+	}
+
+	if majorID == 5 {
+		// peripherals have a keyboard and pointing device bit field
+		switch minorID >> 4 {
+		case 1:
+			minor = "Keyboard"
+		case 2:
+			minor = "Pointing Device"
+		case 3:
+			minor = "Combo Keyboard/Pointing Device"
+		}
+	} else if names, found := btMinorClasses[majorID]; found {
+		minor = names[minorID]
+	}
+
+	services = make([]string, 0)
+	for _, svc := range btServiceClasses {
+		if class&(1<<svc.bit) != 0 {
+			services = append(services, svc.name)
+		}
+	}
+
+	return
+}
+
+// BTDevice is a Bluetooth Classic (BR/EDR) device answering inquiries.
+type BTDevice struct {
+	Address   string    `json:"mac"`
+	Name      string    `json:"name"`
+	Vendor    string    `json:"vendor"`
+	Class     uint32    `json:"class"`
+	Major     string    `json:"major"`
+	Minor     string    `json:"minor"`
+	Services  []string  `json:"services"`
+	RSSI      int       `json:"rssi"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+func NewBTDevice(mac string, class uint32, rssi int) *BTDevice {
+	now := time.Now()
+	dev := &BTDevice{
+		Address:   mac,
+		Vendor:    ManufLookup(mac),
+		RSSI:      rssi,
+		FirstSeen: now,
+		LastSeen:  now,
+	}
+	dev.setClass(class)
+	return dev
+}
+
+func (dev *BTDevice) setClass(class uint32) {
+	dev.Class = class
+	dev.Major, dev.Minor, dev.Services = BTDeviceClass(class)
+}
+
+// Type returns the minor device class if known, the major one otherwise.
+func (dev *BTDevice) Type() string {
+	if dev.Minor != "" {
+		return dev.Minor
+	}
+	return dev.Major
+}
+
+type BT struct {
+	sync.RWMutex
+	devices map[string]*BTDevice
+	newCb   BTDevNewCallback
+	lostCb  BTDevLostCallback
+}
+
+type btJSON struct {
+	Devices []*BTDevice `json:"devices"`
+}
+
+func NewBT(newcb BTDevNewCallback, lostcb BTDevLostCallback) *BT {
+	return &BT{
+		devices: make(map[string]*BTDevice),
+		newCb:   newcb,
+		lostCb:  lostcb,
+	}
+}
+
+func (b *BT) MarshalJSON() ([]byte, error) {
+	b.RLock()
+	defer b.RUnlock()
+
+	doc := btJSON{
+		Devices: make([]*BTDevice, 0),
+	}
+	for _, dev := range b.devices {
+		doc.Devices = append(doc.Devices, dev)
+	}
+	return json.Marshal(doc)
+}
+
+func (b *BT) Get(mac string) (dev *BTDevice, found bool) {
+	b.RLock()
+	defer b.RUnlock()
+
+	dev, found = b.devices[NormalizeMac(mac)]
+	return
+}
+
+// AddIfNew adds or updates a device from an inquiry result, an empty name or
+// a zero rssi don't replace the known ones.
+func (b *BT) AddIfNew(mac string, class uint32, rssi int, name string) (bool, *BTDevice) {
+	b.Lock()
+	defer b.Unlock()
+
+	mac = NormalizeMac(mac)
+	if dev, found := b.devices[mac]; found {
+		dev.LastSeen = time.Now()
+		if rssi != 0 {
+			dev.RSSI = rssi
+		}
+		if name != "" {
+			dev.Name = name
+		}
+		if class != dev.Class {
+			dev.setClass(class)
+		}
+		return false, dev
+	}
+
+	newDev := NewBTDevice(mac, class, rssi)
+	newDev.Name = name
+	b.devices[mac] = newDev
+
+	if b.newCb != nil {
+		b.newCb(newDev)
+	}
+
+	return true, newDev
+}
+
+// SetName sets the name resolved with a remote name request.
+func (b *BT) SetName(mac, name string) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	if dev, found := b.devices[NormalizeMac(mac)]; found && name != "" {
+		dev.Name = name
+		return true
+	}
+	return false
+}
+
+func (b *BT) Remove(mac string) {
+	b.Lock()
+	defer b.Unlock()
+
+	mac = NormalizeMac(mac)
+	if dev, found := b.devices[mac]; found {
+		delete(b.devices, mac)
+		if b.lostCb != nil {
+			b.lostCb(dev)
+		}
+	}
+}
+
+func (b *BT) Devices() (devices []*BTDevice) {
+	b.RLock()
+	defer b.RUnlock()
+
+	devices = make([]*BTDevice, 0)
+	for _, dev := range b.devices {
+		devices = append(devices, dev)
+	}
+	return
+}
+
+func (b *BT) EachDevice(cb func(mac string, d *BTDevice)) {
+	b.RLock()
+	defer b.RUnlock()
+
+	for m, dev := range b.devices {
+		cb(m, dev)
+	}
+}
+
+func (b *BT) Clear() {
+	b.Lock()
+	defer b.Unlock()
+	b.devices = make(map[string]*BTDevice)
+}
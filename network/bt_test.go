@@ -0,0 +1,48 @@
+package network
+
+import (
+	"testing"
+)
+
+func TestBTDeviceClass(t *testing.T) {
+	// car kit: audio and telephony services, audio/video major, car audio minor
+	major, minor, services := BTDeviceClass(0x600420)
+	if major != "Audio/Video" || minor != "Car Audio" {
+		t.Fatalf("unexpected class %s/%s", major, minor)
+	} else if len(services) != 2 || services[0] != "Audio" || services[1] != "Telephony" {
+		t.Fatalf("unexpected services %v", services)
+	}
+
+	if major, minor, _ = BTDeviceClass(0x002540); major != "Peripheral" || minor != "Keyboard" {
+		t.Fatalf("unexpected class %s/%s", major, minor)
+	}
+}
+
+func TestBTAddIfNew(t *testing.T) {
+	added, lost := 0, 0
+	bt := NewBT(func(dev *BTDevice) {
+		added++
+	}, func(dev *BTDevice) {
+		lost++
+	})
+
+	if isNew, dev := bt.AddIfNew("11:22:33:44:55:66", 0x5a020c, -70, ""); !isNew || dev.Type() != "Smartphone" {
+		t.Fatalf("unexpected device %+v", dev)
+	}
+
+	// a plain inquiry result has no rssi nor name
+	if isNew, dev := bt.AddIfNew("11:22:33:44:55:66", 0x5a020c, 0, ""); isNew || dev.RSSI != -70 {
+		t.Fatalf("unexpected update %+v", dev)
+	}
+
+	if !bt.SetName("11:22:33:44:55:66", "Phone") {
+		t.Fatal("expected the name to be set")
+	} else if dev, found := bt.Get("11:22:33:44:55:66"); !found || dev.Name != "Phone" {
+		t.Fatalf("unexpected device %+v", dev)
+	}
+
+	bt.Remove("11:22:33:44:55:66")
+	if added != 1 || lost != 1 || len(bt.Devices()) != 0 {
+		t.Fatalf("unexpected callbacks, %d new and %d lost", added, lost)
+	}
+}
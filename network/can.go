@@ -0,0 +1,195 @@
+package network
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+type CANMessageNewCallback func(msg *CANMessage)
+type CANMessageLostCallback func(msg *CANMessage)
+
+// CANMessage holds the statistics of a single arbitration ID.
+type CANMessage struct {
+	sync.Mutex
+	ID        uint32
+	Address   string
+	Extended  bool
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Frames    uint64
+	Interval  time.Duration
+	Data      []byte
+	Name      string
+	Signals   map[string]string
+	// mask of the payload bits that changed at least once
+	changed []byte
+}
+
+type canMessageJSON struct {
+	ID        uint32            `json:"id"`
+	Address   string            `json:"address"`
+	Extended  bool              `json:"extended"`
+	FirstSeen time.Time         `json:"first_seen"`
+	LastSeen  time.Time         `json:"last_seen"`
+	Frames    uint64            `json:"frames"`
+	Interval  string            `json:"interval"`
+	Data      string            `json:"data"`
+	Name      string            `json:"name"`
+	Signals   map[string]string `json:"signals"`
+}
+
+func NewCANMessage(id uint32, address string, extended bool, name string, data []byte) *CANMessage {
+	now := time.Now()
+	return &CANMessage{
+		ID:        id,
+		Address:   address,
+		Extended:  extended,
+		FirstSeen: now,
+		LastSeen:  now,
+		Frames:    1,
+		Data:      append([]byte{}, data...),
+		Name:      name,
+		Signals:   make(map[string]string),
+		changed:   make([]byte, len(data)),
+	}
+}
+
+func (m *CANMessage) update(data []byte) {
+	m.Lock()
+	defer m.Unlock()
+
+	now := time.Now()
+	m.Interval = now.Sub(m.LastSeen)
+	m.LastSeen = now
+	m.Frames++
+
+	for len(m.changed) < len(data) {
+		m.changed = append(m.changed, 0)
+	}
+	for i := range data {
+		if i < len(m.Data) {
+			m.changed[i] |= m.Data[i] ^ data[i]
+		}
+	}
+
+	m.Data = append([]byte{}, data...)
+}
+
+// Changed returns the bitmask of the payload bits that changed over time.
+func (m *CANMessage) Changed() []byte {
+	m.Lock()
+	defer m.Unlock()
+	return append([]byte{}, m.changed...)
+}
+
+func (m *CANMessage) MarshalJSON() ([]byte, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	doc := canMessageJSON{
+		ID:        m.ID,
+		Address:   m.Address,
+		Extended:  m.Extended,
+		FirstSeen: m.FirstSeen,
+		LastSeen:  m.LastSeen,
+		Frames:    m.Frames,
+		Interval:  m.Interval.String(),
+		Data:      hex.EncodeToString(m.Data),
+		Name:      m.Name,
+		Signals:   m.Signals,
+	}
+
+	return json.Marshal(doc)
+}
+
+type CAN struct {
+	sync.RWMutex
+	messages map[string]*CANMessage
+	newCb    CANMessageNewCallback
+	lostCb   CANMessageLostCallback
+}
+
+type canJSON struct {
+	Messages []*CANMessage `json:"messages"`
+}
+
+func NewCAN(newcb CANMessageNewCallback, lostcb CANMessageLostCallback) *CAN {
+	return &CAN{
+		messages: make(map[string]*CANMessage),
+		newCb:    newcb,
+		lostCb:   lostcb,
+	}
+}
+
+func (c *CAN) MarshalJSON() ([]byte, error) {
+	doc := canJSON{
+		Messages: c.Messages(),
+	}
+	return json.Marshal(doc)
+}
+
+func (c *CAN) Get(address string) (msg *CANMessage, found bool) {
+	c.RLock()
+	defer c.RUnlock()
+	msg, found = c.messages[address]
+	return
+}
+
+func (c *CAN) AddIfNew(id uint32, address string, extended bool, name string, data []byte) (bool, *CANMessage) {
+	c.Lock()
+	defer c.Unlock()
+
+	if msg, found := c.messages[address]; found {
+		msg.update(data)
+		return false, msg
+	}
+
+	msg := NewCANMessage(id, address, extended, name, data)
+	c.messages[address] = msg
+
+	if c.newCb != nil {
+		c.newCb(msg)
+	}
+
+	return true, msg
+}
+
+func (c *CAN) Remove(address string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if msg, found := c.messages[address]; found {
+		delete(c.messages, address)
+		if c.lostCb != nil {
+			c.lostCb(msg)
+		}
+	}
+}
+
+func (c *CAN) Messages() (messages []*CANMessage) {
+	c.RLock()
+	defer c.RUnlock()
+
+	messages = make([]*CANMessage, 0)
+	for _, msg := range c.messages {
+		messages = append(messages, msg)
+	}
+	return
+}
+
+func (c *CAN) EachMessage(cb func(address string, m *CANMessage)) {
+	c.RLock()
+	defer c.RUnlock()
+
+	for a, msg := range c.messages {
+		cb(a, msg)
+	}
+}
+
+func (c *CAN) Clear() {
+	c.Lock()
+	defer c.Unlock()
+	c.messages = make(map[string]*CANMessage)
+}
@@ -0,0 +1,172 @@
+package network
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	dbcMessageParser = regexp.MustCompile(`^BO_\s+(\d+)\s+(\w+)\s*:\s*(\d+)\s+(\w+)`)
+	dbcSignalParser  = regexp.MustCompile(`^SG_\s+(\w+)\s*(\w*)\s*:\s*(\d+)\|(\d+)@([01])([+-])\s*\(([^,]+),([^)]+)\)\s*\[([^|]*)\|([^\]]*)\]\s*"([^"]*)"`)
+)
+
+// DBC message identifiers have bit 31 set for extended frames.
+const dbcExtendedFlag = 0x80000000
+
+type DBCSignal struct {
+	Name      string  `json:"name"`
+	StartBit  int     `json:"start_bit"`
+	Length    int     `json:"length"`
+	BigEndian bool    `json:"big_endian"`
+	Signed    bool    `json:"signed"`
+	Factor    float64 `json:"factor"`
+	Offset    float64 `json:"offset"`
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max"`
+	Unit      string  `json:"unit"`
+}
+
+type DBCMessage struct {
+	ID      uint32       `json:"id"`
+	Name    string       `json:"name"`
+	Length  int          `json:"length"`
+	Sender  string       `json:"sender"`
+	Signals []*DBCSignal `json:"signals"`
+}
+
+// DBC holds the message definitions of a CAN database file, indexed by arbitration ID.
+type DBC struct {
+	Path     string
+	Messages map[uint32]*DBCMessage
+}
+
+func LoadDBC(path string) (error, *DBC) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return err, nil
+	}
+	defer fp.Close()
+
+	dbc := &DBC{
+		Path:     path,
+		Messages: make(map[uint32]*DBCMessage),
+	}
+
+	var current *DBCMessage
+	scanner := bufio.NewScanner(fp)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if m := dbcMessageParser.FindStringSubmatch(line); m != nil {
+			id, _ := strconv.ParseUint(m[1], 10, 32)
+			size, _ := strconv.Atoi(m[3])
+			current = &DBCMessage{
+				ID:      uint32(id) &^ dbcExtendedFlag,
+				Name:    m[2],
+				Length:  size,
+				Sender:  m[4],
+				Signals: make([]*DBCSignal, 0),
+			}
+			dbc.Messages[current.ID] = current
+		} else if strings.HasPrefix(line, "SG_ ") {
+			if current == nil {
+				return fmt.Errorf("%s:%d: signal outside of a message definition", path, lineNo), nil
+			}
+
+			m := dbcSignalParser.FindStringSubmatch(line)
+			if m == nil {
+				return fmt.Errorf("%s:%d: can't parse signal '%s'", path, lineNo, line), nil
+			}
+
+			sig := &DBCSignal{
+				Name:      m[1],
+				BigEndian: m[5] == "0",
+				Signed:    m[6] == "-",
+				Unit:      m[11],
+			}
+			sig.StartBit, _ = strconv.Atoi(m[3])
+			sig.Length, _ = strconv.Atoi(m[4])
+			sig.Factor, _ = strconv.ParseFloat(strings.TrimSpace(m[7]), 64)
+			sig.Offset, _ = strconv.ParseFloat(strings.TrimSpace(m[8]), 64)
+			sig.Min, _ = strconv.ParseFloat(strings.TrimSpace(m[9]), 64)
+			sig.Max, _ = strconv.ParseFloat(strings.TrimSpace(m[10]), 64)
+
+			if sig.Length < 1 || sig.Length > 64 {
+				return fmt.Errorf("%s:%d: invalid length for signal %s", path, lineNo, sig.Name), nil
+			}
+
+			current.Signals = append(current.Signals, sig)
+		} else if line == "" {
+			current = nil
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return err, nil
+	}
+
+	return nil, dbc
+}
+
+func (d *DBC) Lookup(id uint32) (msg *DBCMessage, found bool) {
+	msg, found = d.Messages[id]
+	return
+}
+
+func dbcBit(data []byte, bit int) uint64 {
+	if bit < 0 || bit/8 >= len(data) {
+		return 0
+	}
+	return uint64((data[bit/8] >> uint(bit%8)) & 1)
+}
+
+// Raw extracts the unscaled value of the signal from the frame payload.
+func (s *DBCSignal) Raw(data []byte) uint64 {
+	value := uint64(0)
+	if s.BigEndian {
+		// motorola byte order, StartBit is the most significant bit
+		// and bits are numbered as in the DBC "sawtooth" layout
+		bit := s.StartBit
+		for i := 0; i < s.Length; i++ {
+			value = (value << 1) | dbcBit(data, bit)
+			if bit%8 == 0 {
+				bit += 15
+			} else {
+				bit--
+			}
+		}
+	} else {
+		for i := 0; i < s.Length; i++ {
+			value |= dbcBit(data, s.StartBit+i) << uint(i)
+		}
+	}
+	return value
+}
+
+// Decode returns the physical value of the signal.
+func (s *DBCSignal) Decode(data []byte) float64 {
+	raw := s.Raw(data)
+	if s.Signed && s.Length < 64 && raw&(1<<uint(s.Length-1)) != 0 {
+		return float64(int64(raw)-int64(1)<<uint(s.Length))*s.Factor + s.Offset
+	} else if s.Signed {
+		return float64(int64(raw))*s.Factor + s.Offset
+	}
+	return float64(raw)*s.Factor + s.Offset
+}
+
+// Decode returns the physical value of every signal of the message.
+func (m *DBCMessage) Decode(data []byte) map[string]string {
+	values := make(map[string]string)
+	for _, sig := range m.Signals {
+		value := strconv.FormatFloat(sig.Decode(data), 'f', -1, 64)
+		if sig.Unit != "" {
+			value += " " + sig.Unit
+		}
+		values[sig.Name] = value
+	}
+	return values
+}
@@ -0,0 +1,60 @@
+package network
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+const testDBC = `VERSION ""
+
+BO_ 256 EngineData: 8 ECU
+ SG_ RPM : 0|16@1+ (0.25,0) [0|16383.75] "rpm" Dash
+ SG_ Temperature : 16|8@1- (1,-40) [-40|215] "C" Dash
+ SG_ Gear : 31|4@0+ (1,0) [0|15] "" Dash
+
+BO_ 2566844926 Extended: 2 ECU
+ SG_ Flag : 0|1@1+ (1,0) [0|1] "" Dash
+`
+
+func TestLoadDBC(t *testing.T) {
+	fp, err := ioutil.TempFile("", "bettercap-dbc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fp.Name())
+	fp.WriteString(testDBC)
+	fp.Close()
+
+	err, dbc := LoadDBC(fp.Name())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(dbc.Messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(dbc.Messages))
+	}
+
+	msg, found := dbc.Lookup(0x100)
+	if !found {
+		t.Fatal("expected message 0x100")
+	} else if msg.Name != "EngineData" || len(msg.Signals) != 3 {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	if _, found = dbc.Lookup(0x18fef1fe); !found {
+		t.Fatal("expected extended message 0x18fef1fe")
+	}
+
+	// rpm = 0x1f40 * 0.25, temperature = -2 - 40, gear (motorola) = 0x3
+	data := []byte{0x40, 0x1f, 0xfe, 0x30, 0, 0, 0, 0}
+	values := msg.Decode(data)
+	expected := map[string]string{
+		"RPM":         "2000 rpm",
+		"Temperature": "-42 C",
+		"Gear":        "3",
+	}
+	for name, value := range expected {
+		if values[name] != value {
+			t.Fatalf("expected %s=%s, got %s", name, value, values[name])
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package network
+
+import (
+	"strings"
+)
+
+// DHCPFingerprint is the device type and operating system associated to
+// the parameter request list (option 55) or vendor class (option 60) of a
+// DHCP client.
+type DHCPFingerprint struct {
+	Device string `json:"device"`
+	OS     string `json:"os"`
+}
+
+var (
+	// DHCPFingerprints maps the comma separated list of options a client
+	// requests to the system that sent it.
+	DHCPFingerprints = map[string]DHCPFingerprint{
+		"1,3,6,15,31,33,43,44,46,47,119,121,249,252": {"Desktop", "Windows 10"},
+		"1,3,6,15,31,33,43,44,46,47,121,249,252":     {"Desktop", "Windows 8"},
+		"1,15,3,6,44,46,47,31,33,121,249,43,252":     {"Desktop", "Windows 8"},
+		"1,15,3,6,44,46,47,31,33,121,249,43":         {"Desktop", "Windows 7"},
+		"1,15,3,6,44,46,47,31,33,249,43":             {"Desktop", "Windows XP"},
+		"1,15,3,6,44,46,47,31,33,249,43,252":         {"Desktop", "Windows XP"},
+		"1,121,3,6,15,119,252,95,44,46":              {"Desktop", "macOS"},
+		"1,121,3,6,15,114,119,252,95,44,46":          {"Desktop", "macOS"},
+		"1,3,6,15,119,95,252,44,46,101":              {"Desktop", "macOS"},
+		"1,121,3,6,15,119,252":                       {"Phone", "iOS"},
+		"1,121,3,6,15,114,119,252":                   {"Phone", "iOS"},
+		"1,3,6,15,119,252":                           {"Phone", "iOS"},
+		"1,3,6,15,26,28,51,58,59,43":                 {"Phone", "Android"},
+		"1,3,6,15,26,28,51,58,59,43,114":             {"Phone", "Android"},
+		"1,33,3,6,15,28,51,58,59":                    {"Phone", "Android"},
+		"1,33,3,6,28,51,58,59":                       {"Phone", "Android"},
+		"1,28,2,3,15,6,119,12,44,47,26,121,42":       {"Desktop", "Linux"},
+		"1,28,2,3,15,6,12,40,41,42,26,119,121":       {"Desktop", "Linux"},
+		"1,28,2,121,15,6,12,40,41,42,26,119,3,249":   {"Desktop", "Linux"},
+		"1,3,6,12,15,28,42,43,119,121":               {"Desktop", "Linux"},
+		"1,3,6,12,15,28,42":                          {"Embedded", "Linux (BusyBox)"},
+		"1,3,6,12,15,28,42,33,121":                   {"Embedded", "Linux (BusyBox)"},
+		"1,3,6,15,66,67,13,44":                       {"Phone", "Cisco IP Phone"},
+		"1,3,43,60":                                  {"Boot", "PXE client"},
+	}
+
+	// DHCPVendorFingerprints maps the prefix of a vendor class identifier
+	// to the system that sent it, used when the parameter list is unknown.
+	DHCPVendorFingerprints = map[string]DHCPFingerprint{
+		"MSFT 5.0":                     {"Desktop", "Windows"},
+		"MSFT 98":                      {"Desktop", "Windows 98"},
+		"android-dhcp-":                {"Phone", "Android"},
+		"HUAWEI:android":               {"Phone", "Android"},
+		"dhcpcd-":                      {"Desktop", "Linux"},
+		"udhcp":                        {"Embedded", "Linux (BusyBox)"},
+		"Cisco Systems, Inc. IP Phone": {"Phone", "Cisco IP Phone"},
+		"Cisco AP":                     {"Access Point", "Cisco"},
+		"ubnt":                         {"Access Point", "Ubiquiti"},
+		"PXEClient":                    {"Boot", "PXE client"},
+		"Linux":                        {"Device", "Linux"},
+	}
+)
+
+// DHCPFingerprintLookup returns the device associated to the parameters list
+// and vendor class of a DHCP request, or nil if they are unknown.
+func DHCPFingerprintLookup(params string, vendor string) *DHCPFingerprint {
+	if fp, found := DHCPFingerprints[params]; found {
+		return &fp
+	}
+
+	// prefer the longest prefix, 'android-dhcp-' over 'Linux' and so on
+	var match *DHCPFingerprint
+	longest := 0
+	for prefix, fp := range DHCPVendorFingerprints {
+		if len(prefix) > longest && strings.HasPrefix(vendor, prefix) {
+			fp := fp
+			match = &fp
+			longest = len(prefix)
+		}
+	}
+	return match
+}
@@ -0,0 +1,33 @@
+package network
+
+import (
+	"testing"
+)
+
+func TestDHCPFingerprintLookup(t *testing.T) {
+	var tests = []struct {
+		Params   string
+		Vendor   string
+		Expected string
+	}{
+		{"1,3,6,15,31,33,43,44,46,47,119,121,249,252", "MSFT 5.0", "Windows 10"},
+		{"1,2,3", "MSFT 5.0", "Windows"},
+		{"1,2,3", "android-dhcp-9", "Android"},
+		{"1,3,6,15,26,28,51,58,59,43", "", "Android"},
+		{"1,2,3", "udhcp 1.30.1", "Linux (BusyBox)"},
+		{"1,2,3", "", ""},
+	}
+
+	for _, test := range tests {
+		fp := DHCPFingerprintLookup(test.Params, test.Vendor)
+		if test.Expected == "" {
+			if fp != nil {
+				t.Fatalf("unexpected fingerprint %v for '%s' '%s'", fp, test.Params, test.Vendor)
+			}
+		} else if fp == nil {
+			t.Fatalf("expected %s for '%s' '%s'", test.Expected, test.Params, test.Vendor)
+		} else if fp.OS != test.Expected {
+			t.Fatalf("expected %s for '%s' '%s', got %s", test.Expected, test.Params, test.Vendor, fp.OS)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package network
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// the same query can be seen by more than one module (dns.spoof and net.sniff)
+const dnsQueryDupWindow = 2 * time.Second
+
+type DNSQuery struct {
+	Domain    string    `json:"domain"`
+	Hits      uint64    `json:"hits"`
+	Spoofed   uint64    `json:"spoofed"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+
+	lastID uint16
+}
+
+type DNSClient struct {
+	sync.Mutex
+	Address   string
+	Hits      uint64
+	FirstSeen time.Time
+	LastSeen  time.Time
+
+	queries map[string]*DNSQuery
+}
+
+type dnsClientJSON struct {
+	Address   string      `json:"address"`
+	Hits      uint64      `json:"hits"`
+	FirstSeen time.Time   `json:"first_seen"`
+	LastSeen  time.Time   `json:"last_seen"`
+	Queries   []*DNSQuery `json:"queries"`
+}
+
+func NewDNSClient(address string) *DNSClient {
+	now := time.Now()
+	return &DNSClient{
+		Address:   address,
+		FirstSeen: now,
+		LastSeen:  now,
+		queries:   make(map[string]*DNSQuery),
+	}
+}
+
+// Queries returns a copy of the domains queried by the client, most queried first.
+func (c *DNSClient) Queries() []DNSQuery {
+	c.Lock()
+	defer c.Unlock()
+
+	queries := make([]DNSQuery, 0, len(c.queries))
+	for _, q := range c.queries {
+		queries = append(queries, *q)
+	}
+
+	sort.Slice(queries, func(i, j int) bool {
+		if queries[i].Hits == queries[j].Hits {
+			return queries[i].Domain < queries[j].Domain
+		}
+		return queries[i].Hits > queries[j].Hits
+	})
+
+	return queries
+}
+
+func (c *DNSClient) NumQueries() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.queries)
+}
+
+func (c *DNSClient) MarshalJSON() ([]byte, error) {
+	doc := dnsClientJSON{
+		Queries: make([]*DNSQuery, 0),
+	}
+
+	for _, q := range c.Queries() {
+		query := q
+		doc.Queries = append(doc.Queries, &query)
+	}
+
+	c.Lock()
+	doc.Address = c.Address
+	doc.Hits = c.Hits
+	doc.FirstSeen = c.FirstSeen
+	doc.LastSeen = c.LastSeen
+	c.Unlock()
+
+	return json.Marshal(doc)
+}
+
+func (c *DNSClient) add(domain string, id uint16, spoofed bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	q, found := c.queries[domain]
+	if !found {
+		q = &DNSQuery{
+			Domain:    domain,
+			FirstSeen: now,
+		}
+		c.queries[domain] = q
+	} else if q.lastID == id && now.Sub(q.LastSeen) < dnsQueryDupWindow {
+		if spoofed {
+			q.Spoofed++
+		}
+		return
+	}
+
+	q.Hits++
+	q.LastSeen = now
+	q.lastID = id
+	if spoofed {
+		q.Spoofed++
+	}
+
+	c.Hits++
+	c.LastSeen = now
+}
+
+// DNSLog aggregates the DNS queries observed on the network by client address.
+type DNSLog struct {
+	sync.RWMutex
+	clients map[string]*DNSClient
+}
+
+type dnsLogJSON struct {
+	Clients []*DNSClient `json:"clients"`
+}
+
+func NewDNSLog() *DNSLog {
+	return &DNSLog{
+		clients: make(map[string]*DNSClient),
+	}
+}
+
+func (l *DNSLog) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dnsLogJSON{
+		Clients: l.Clients(),
+	})
+}
+
+// Add tracks a query for domain with the given DNS transaction id made by the client.
+func (l *DNSLog) Add(client, domain string, id uint16, spoofed bool) {
+	l.Lock()
+	c, found := l.clients[client]
+	if !found {
+		c = NewDNSClient(client)
+		l.clients[client] = c
+	}
+	l.Unlock()
+
+	c.add(domain, id, spoofed)
+}
+
+func (l *DNSLog) Get(client string) (c *DNSClient, found bool) {
+	l.RLock()
+	defer l.RUnlock()
+	c, found = l.clients[client]
+	return
+}
+
+func (l *DNSLog) Clients() (clients []*DNSClient) {
+	l.RLock()
+	defer l.RUnlock()
+
+	clients = make([]*DNSClient, 0)
+	for _, c := range l.clients {
+		clients = append(clients, c)
+	}
+	return
+}
+
+func (l *DNSLog) Clear() {
+	l.Lock()
+	defer l.Unlock()
+	l.clients = make(map[string]*DNSClient)
+}
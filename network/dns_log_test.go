@@ -0,0 +1,46 @@
+package network
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDNSLogAdd(t *testing.T) {
+	log := NewDNSLog()
+
+	log.Add("192.168.1.10", "example.com", 1, false)
+	// same transaction seen twice, once by the spoofer
+	log.Add("192.168.1.10", "example.com", 1, true)
+	log.Add("192.168.1.10", "example.com", 2, false)
+	log.Add("192.168.1.10", "foo.com", 3, false)
+	log.Add("192.168.1.20", "foo.com", 1, false)
+
+	if n := len(log.Clients()); n != 2 {
+		t.Fatalf("expected 2 clients, got %d", n)
+	}
+
+	c, found := log.Get("192.168.1.10")
+	if !found {
+		t.Fatal("client not found")
+	} else if c.Hits != 3 {
+		t.Fatalf("expected 3 hits, got %d", c.Hits)
+	} else if n := c.NumQueries(); n != 2 {
+		t.Fatalf("expected 2 domains, got %d", n)
+	}
+
+	queries := c.Queries()
+	if queries[0].Domain != "example.com" || queries[0].Hits != 2 || queries[0].Spoofed != 1 {
+		t.Fatalf("unexpected first query %+v", queries[0])
+	} else if queries[1].Domain != "foo.com" || queries[1].Hits != 1 {
+		t.Fatalf("unexpected second query %+v", queries[1])
+	}
+
+	if _, err := json.Marshal(log); err != nil {
+		t.Fatal(err)
+	}
+
+	log.Clear()
+	if _, found := log.Get("192.168.1.10"); found {
+		t.Fatal("expected empty log after Clear")
+	}
+}
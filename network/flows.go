@@ -0,0 +1,392 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TCP flags relevant to the state of a flow.
+const (
+	FlowFIN = 1 << iota
+	FlowSYN
+	FlowRST
+	FlowACK
+)
+
+const (
+	FlowStateNew         = "new"
+	FlowStateReplied     = "replied"
+	FlowStateSynSent     = "syn_sent"
+	FlowStateSynReceived = "syn_recv"
+	FlowStateEstablished = "established"
+	FlowStateFinWait     = "fin_wait"
+	FlowStateClosed      = "closed"
+	FlowStateReset       = "reset"
+)
+
+var (
+	// how long idle flows are kept
+	FlowTimeout = time.Duration(2) * time.Minute
+	// how long closed or reset TCP flows are kept
+	FlowClosedTimeout = time.Duration(10) * time.Second
+	// the oldest flows are dropped when the table is full
+	FlowsMax = 65536
+)
+
+// Flow is a conntrack-style entry, Src is the endpoint which started the
+// connection (or sent the first packet we've seen).
+type Flow struct {
+	Proto       string    `json:"proto"`
+	SrcIP       string    `json:"src_ip"`
+	SrcPort     int       `json:"src_port"`
+	DstIP       string    `json:"dst_ip"`
+	DstPort     int       `json:"dst_port"`
+	State       string    `json:"state"`
+	SentPackets uint64    `json:"sent_packets"`
+	SentBytes   uint64    `json:"sent_bytes"`
+	RecvPackets uint64    `json:"recv_packets"`
+	RecvBytes   uint64    `json:"recv_bytes"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	SrcGeo      *GeoInfo  `json:"src_geo,omitempty"`
+	DstGeo      *GeoInfo  `json:"dst_geo,omitempty"`
+
+	srcFin bool
+	dstFin bool
+}
+
+func hostPort(ip string, port int) string {
+	return net.JoinHostPort(ip, strconv.Itoa(port))
+}
+
+func (f *Flow) Src() string {
+	return hostPort(f.SrcIP, f.SrcPort)
+}
+
+func (f *Flow) Dst() string {
+	return hostPort(f.DstIP, f.DstPort)
+}
+
+func (f *Flow) Packets() uint64 {
+	return f.SentPackets + f.RecvPackets
+}
+
+func (f *Flow) Bytes() uint64 {
+	return f.SentBytes + f.RecvBytes
+}
+
+func (f *Flow) Duration() time.Duration {
+	return f.LastSeen.Sub(f.FirstSeen)
+}
+
+func (f *Flow) String() string {
+	return fmt.Sprintf("%s %s -> %s (%s)", f.Proto, f.Src(), f.Dst(), f.State)
+}
+
+// Involves returns true if ip is one of the two endpoints of the flow.
+func (f *Flow) Involves(ip string) bool {
+	return f.SrcIP == ip || f.DstIP == ip
+}
+
+// InCountry returns true if any of the endpoints of the flow is located in
+// the country with the given ISO code.
+func (f *Flow) InCountry(code string) bool {
+	return (f.SrcGeo != nil && f.SrcGeo.Country == code) || (f.DstGeo != nil && f.DstGeo.Country == code)
+}
+
+// HasPort returns true if port is one of the two ports of the flow.
+func (f *Flow) HasPort(port int) bool {
+	return f.SrcPort == port || f.DstPort == port
+}
+
+func (f *Flow) closed() bool {
+	return f.State == FlowStateClosed || f.State == FlowStateReset
+}
+
+func (f *Flow) update(fromSrc bool, size int, flags int) {
+	f.LastSeen = time.Now()
+	if fromSrc {
+		f.SentPackets++
+		f.SentBytes += uint64(size)
+	} else {
+		f.RecvPackets++
+		f.RecvBytes += uint64(size)
+	}
+
+	if f.Proto != "tcp" {
+		if !fromSrc {
+			f.State = FlowStateReplied
+		}
+		return
+	}
+
+	syn := flags&FlowSYN != 0
+	ack := flags&FlowACK != 0
+
+	switch {
+	case flags&FlowRST != 0:
+		f.State = FlowStateReset
+	case flags&FlowFIN != 0:
+		if fromSrc {
+			f.srcFin = true
+		} else {
+			f.dstFin = true
+		}
+		if f.srcFin && f.dstFin {
+			f.State = FlowStateClosed
+		} else {
+			f.State = FlowStateFinWait
+		}
+	case syn && !ack:
+		f.State = FlowStateSynSent
+	case syn && ack && !fromSrc:
+		f.State = FlowStateSynReceived
+	case f.State == FlowStateSynReceived && fromSrc:
+		f.State = FlowStateEstablished
+	case f.State == FlowStateNew:
+		// joined in the middle of the connection
+		f.State = FlowStateEstablished
+	}
+}
+
+type Flows struct {
+	sync.RWMutex
+	flows     map[string]*Flow
+	lastPrune time.Time
+	geo       *GeoIP
+}
+
+type flowsJSON struct {
+	Flows []*Flow `json:"flows"`
+}
+
+func NewFlows() *Flows {
+	return &Flows{
+		flows:     make(map[string]*Flow),
+		lastPrune: time.Now(),
+	}
+}
+
+func flowKey(proto, a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return proto + "|" + a + "|" + b
+}
+
+// SetGeoIP makes new flows annotated with the country and autonomous system
+// of their external endpoints.
+func (t *Flows) SetGeoIP(geo *GeoIP) {
+	t.Lock()
+	defer t.Unlock()
+	t.geo = geo
+}
+
+func (t *Flows) MarshalJSON() ([]byte, error) {
+	return json.Marshal(flowsJSON{
+		Flows: t.List(),
+	})
+}
+
+// Track updates the flow a packet belongs to, creating it if needed.
+func (t *Flows) Track(proto string, srcIP net.IP, srcPort int, dstIP net.IP, dstPort int, size int, flags int) {
+	src := hostPort(srcIP.String(), srcPort)
+	dst := hostPort(dstIP.String(), dstPort)
+	key := flowKey(proto, src, dst)
+
+	t.Lock()
+	defer t.Unlock()
+
+	if time.Since(t.lastPrune) > FlowClosedTimeout {
+		t.prune()
+	}
+
+	flow, found := t.flows[key]
+	if found && flow.closed() && flags&FlowSYN != 0 && flags&FlowACK == 0 {
+		// new connection reusing the same ports
+		found = false
+	}
+
+	if !found {
+		if len(t.flows) >= FlowsMax {
+			t.evictOldest()
+		}
+
+		now := time.Now()
+		flow = &Flow{
+			Proto:     proto,
+			SrcIP:     srcIP.String(),
+			SrcPort:   srcPort,
+			DstIP:     dstIP.String(),
+			DstPort:   dstPort,
+			State:     FlowStateNew,
+			FirstSeen: now,
+			LastSeen:  now,
+		}
+		if t.geo != nil {
+			flow.SrcGeo = t.geo.Lookup(srcIP)
+			flow.DstGeo = t.geo.Lookup(dstIP)
+		}
+		// for TCP the initiator is who sent the SYN
+		if proto == "tcp" && flags&FlowSYN != 0 && flags&FlowACK != 0 {
+			flow.SrcIP, flow.SrcPort, flow.DstIP, flow.DstPort = flow.DstIP, flow.DstPort, flow.SrcIP, flow.SrcPort
+			flow.SrcGeo, flow.DstGeo = flow.DstGeo, flow.SrcGeo
+		}
+		t.flows[key] = flow
+	}
+
+	flow.update(flow.Src() == src, size, flags)
+}
+
+func (t *Flows) prune() {
+	now := time.Now()
+	for key, flow := range t.flows {
+		idle := now.Sub(flow.LastSeen)
+		if idle > FlowTimeout || (flow.closed() && idle > FlowClosedTimeout) {
+			delete(t.flows, key)
+		}
+	}
+	t.lastPrune = now
+}
+
+func (t *Flows) evictOldest() {
+	oldestKey := ""
+	var oldest time.Time
+	for key, flow := range t.flows {
+		if oldestKey == "" || flow.LastSeen.Before(oldest) {
+			oldestKey = key
+			oldest = flow.LastSeen
+		}
+	}
+	delete(t.flows, oldestKey)
+}
+
+// List returns a copy of every flow.
+func (t *Flows) List() []*Flow {
+	t.RLock()
+	defer t.RUnlock()
+
+	list := make([]*Flow, 0, len(t.flows))
+	for _, flow := range t.flows {
+		copied := *flow
+		list = append(list, &copied)
+	}
+	return list
+}
+
+func (t *Flows) Len() int {
+	t.RLock()
+	defer t.RUnlock()
+	return len(t.flows)
+}
+
+func (t *Flows) Clear() {
+	t.Lock()
+	defer t.Unlock()
+	t.flows = make(map[string]*Flow)
+}
+
+// FlowFilter selects flows by host, port, protocol and country, an empty
+// FlowFilter matches every flow.
+type FlowFilter struct {
+	Hosts     []string
+	Ports     []int
+	Protos    []string
+	Countries []string
+}
+
+// ParseFlowFilter parses a comma separated list of IP addresses, port numbers,
+// protocol names (tcp, udp, icmp ...) and upper case country ISO codes.
+func ParseFlowFilter(expr string) (FlowFilter, error) {
+	filter := FlowFilter{}
+	for _, token := range strings.Split(expr, ",") {
+		if token = strings.TrimSpace(token); token == "" {
+			continue
+		} else if ip := net.ParseIP(token); ip != nil {
+			filter.Hosts = append(filter.Hosts, ip.String())
+		} else if port, err := strconv.Atoi(token); err == nil {
+			if port < 0 || port > 65535 {
+				return filter, fmt.Errorf("invalid port %d", port)
+			}
+			filter.Ports = append(filter.Ports, port)
+		} else if isFlowProto(token) {
+			filter.Protos = append(filter.Protos, strings.ToLower(token))
+		} else if isCountryCode(token) {
+			filter.Countries = append(filter.Countries, token)
+		} else {
+			return filter, fmt.Errorf("'%s' is not an IP address, a port, a protocol or a country code", token)
+		}
+	}
+	return filter, nil
+}
+
+func isFlowProto(token string) bool {
+	switch strings.ToLower(token) {
+	case "tcp", "udp", "icmp", "icmp6", "sctp":
+		return true
+	}
+	return false
+}
+
+func isCountryCode(token string) bool {
+	return len(token) == 2 && token[0] >= 'A' && token[0] <= 'Z' && token[1] >= 'A' && token[1] <= 'Z'
+}
+
+// Matches returns true if the flow involves any of the hosts, any of the ports,
+// any of the protocols and any of the countries of the filter.
+func (f FlowFilter) Matches(flow *Flow) bool {
+	if len(f.Hosts) > 0 {
+		found := false
+		for _, host := range f.Hosts {
+			if flow.Involves(host) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Ports) > 0 {
+		found := false
+		for _, port := range f.Ports {
+			if flow.HasPort(port) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Countries) > 0 {
+		found := false
+		for _, code := range f.Countries {
+			if flow.InCountry(code) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Protos) > 0 {
+		for _, proto := range f.Protos {
+			if flow.Proto == proto {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,153 @@
+package network
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+)
+
+var (
+	flowClient = net.ParseIP("192.168.1.10")
+	flowServer = net.ParseIP("10.0.0.1")
+)
+
+func TestFlowsTCPHandshake(t *testing.T) {
+	flows := NewFlows()
+
+	// the SYN/ACK is the first packet we see, the client is still the source
+	flows.Track("tcp", flowServer, 443, flowClient, 50000, 60, FlowSYN|FlowACK)
+	flows.Track("tcp", flowClient, 50000, flowServer, 443, 40, FlowACK)
+	flows.Track("tcp", flowClient, 50000, flowServer, 443, 500, FlowACK)
+	flows.Track("tcp", flowServer, 443, flowClient, 50000, 1500, FlowACK)
+
+	list := flows.List()
+	if len(list) != 1 {
+		t.Fatalf("expected 1 flow, got %d", len(list))
+	}
+
+	flow := list[0]
+	if flow.Src() != "192.168.1.10:50000" || flow.Dst() != "10.0.0.1:443" {
+		t.Fatalf("unexpected endpoints %s -> %s", flow.Src(), flow.Dst())
+	} else if flow.State != FlowStateEstablished {
+		t.Fatalf("expected %s, got %s", FlowStateEstablished, flow.State)
+	} else if flow.SentPackets != 2 || flow.SentBytes != 540 {
+		t.Fatalf("unexpected sent counters %d/%d", flow.SentPackets, flow.SentBytes)
+	} else if flow.RecvPackets != 2 || flow.RecvBytes != 1560 {
+		t.Fatalf("unexpected received counters %d/%d", flow.RecvPackets, flow.RecvBytes)
+	}
+
+	flows.Track("tcp", flowClient, 50000, flowServer, 443, 40, FlowFIN|FlowACK)
+	if state := flows.List()[0].State; state != FlowStateFinWait {
+		t.Fatalf("expected %s, got %s", FlowStateFinWait, state)
+	}
+	flows.Track("tcp", flowServer, 443, flowClient, 50000, 40, FlowFIN|FlowACK)
+	if state := flows.List()[0].State; state != FlowStateClosed {
+		t.Fatalf("expected %s, got %s", FlowStateClosed, state)
+	}
+
+	// a new connection reusing the same ports
+	flows.Track("tcp", flowClient, 50000, flowServer, 443, 60, FlowSYN)
+	if flow := flows.List()[0]; flow.State != FlowStateSynSent || flow.Packets() != 1 {
+		t.Fatalf("expected a new flow, got %s with %d packets", flow.State, flow.Packets())
+	}
+}
+
+func TestFlowsUDPAndReset(t *testing.T) {
+	flows := NewFlows()
+
+	flows.Track("udp", flowClient, 5353, flowServer, 53, 70, 0)
+	if state := flows.List()[0].State; state != FlowStateNew {
+		t.Fatalf("expected %s, got %s", FlowStateNew, state)
+	}
+	flows.Track("udp", flowServer, 53, flowClient, 5353, 120, 0)
+	if state := flows.List()[0].State; state != FlowStateReplied {
+		t.Fatalf("expected %s, got %s", FlowStateReplied, state)
+	}
+
+	flows.Track("tcp", flowClient, 40000, flowServer, 22, 60, FlowSYN)
+	flows.Track("tcp", flowServer, 22, flowClient, 40000, 40, FlowRST|FlowACK)
+	if flows.Len() != 2 {
+		t.Fatalf("expected 2 flows, got %d", flows.Len())
+	}
+
+	filter, err := ParseFlowFilter("tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, flow := range flows.List() {
+		if filter.Matches(flow) && flow.State != FlowStateReset {
+			t.Fatalf("expected %s, got %s", FlowStateReset, flow.State)
+		}
+	}
+
+	flows.Clear()
+	if flows.Len() != 0 {
+		t.Fatalf("expected no flows, got %d", flows.Len())
+	}
+}
+
+func TestFlowsMax(t *testing.T) {
+	prev := FlowsMax
+	defer func() { FlowsMax = prev }()
+	FlowsMax = 2
+
+	flows := NewFlows()
+	flows.Track("udp", flowClient, 1, flowServer, 53, 10, 0)
+	flows.Track("udp", flowClient, 2, flowServer, 53, 10, 0)
+	flows.Track("udp", flowClient, 3, flowServer, 53, 10, 0)
+
+	if flows.Len() != 2 {
+		t.Fatalf("expected 2 flows, got %d", flows.Len())
+	}
+	for _, flow := range flows.List() {
+		if flow.SrcPort == 1 {
+			t.Fatal("the oldest flow should have been evicted")
+		}
+	}
+}
+
+func TestParseFlowFilter(t *testing.T) {
+	filter, err := ParseFlowFilter("192.168.1.10, 443, TCP")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(filter.Hosts) != 1 || len(filter.Ports) != 1 || len(filter.Protos) != 1 {
+		t.Fatalf("unexpected filter %+v", filter)
+	}
+
+	https := &Flow{Proto: "tcp", SrcIP: "192.168.1.10", SrcPort: 50000, DstIP: "10.0.0.1", DstPort: 443}
+	dns := &Flow{Proto: "udp", SrcIP: "192.168.1.10", SrcPort: 5353, DstIP: "10.0.0.1", DstPort: 53}
+	if !filter.Matches(https) {
+		t.Fatal("expected https flow to match")
+	} else if filter.Matches(dns) {
+		t.Fatal("expected dns flow not to match")
+	}
+
+	if empty, _ := ParseFlowFilter(""); !empty.Matches(dns) {
+		t.Fatal("expected empty filter to match everything")
+	}
+
+	for _, expr := range []string{"foo", "70000", "1.2.3.4,bar"} {
+		if _, err := ParseFlowFilter(expr); err == nil {
+			t.Fatalf("expected error for '%s'", expr)
+		}
+	}
+}
+
+func TestFlowsMarshalJSON(t *testing.T) {
+	flows := NewFlows()
+	flows.Track("tcp", flowClient, 50000, flowServer, 443, 60, FlowSYN)
+
+	raw, err := json.Marshal(flows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var obj struct {
+		Flows []Flow `json:"flows"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		t.Fatal(err)
+	} else if len(obj.Flows) != 1 || obj.Flows[0].DstPort != 443 || obj.Flows[0].State != FlowStateSynSent {
+		t.Fatalf("unexpected json %s", raw)
+	}
+}
@@ -0,0 +1,203 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// the lookups cache is reset when it grows over this size
+const geoCacheMax = 8192
+
+var geoReservedNets = func() []*net.IPNet {
+	nets := make([]*net.IPNet, 0)
+	for _, cidr := range []string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+		"::1/128",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// IsExternalIP returns false for private, loopback, link local, multicast
+// and otherwise reserved addresses.
+func IsExternalIP(ip net.IP) bool {
+	if ip == nil || ip.IsUnspecified() {
+		return false
+	}
+	for _, n := range geoReservedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// GeoInfo is what the loaded MaxMind databases know about an address.
+type GeoInfo struct {
+	Country     string `json:"country,omitempty"`
+	CountryName string `json:"country_name,omitempty"`
+	City        string `json:"city,omitempty"`
+	ASN         uint64 `json:"asn,omitempty"`
+	Org         string `json:"org,omitempty"`
+}
+
+func (info *GeoInfo) String() string {
+	parts := make([]string, 0)
+	if info.Country != "" {
+		parts = append(parts, info.Country)
+	}
+	if info.ASN > 0 {
+		parts = append(parts, fmt.Sprintf("AS%d", info.ASN))
+	}
+	if info.Org != "" {
+		parts = append(parts, info.Org)
+	}
+	return strings.Join(parts, " ")
+}
+
+// GeoIP annotates external addresses with the country and autonomous system
+// they belong to using one or more MaxMind databases (GeoLite2 Country, City
+// and ASN or the commercial equivalents).
+type GeoIP struct {
+	sync.RWMutex
+	dbs   []*mmdb
+	cache map[string]*GeoInfo
+}
+
+func NewGeoIP() *GeoIP {
+	return &GeoIP{
+		dbs:   make([]*mmdb, 0),
+		cache: make(map[string]*GeoInfo),
+	}
+}
+
+// Load replaces the databases in use, no paths disables the lookups.
+func (g *GeoIP) Load(paths ...string) error {
+	dbs := make([]*mmdb, 0)
+	for _, path := range paths {
+		if db, err := openMMDB(path); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		} else {
+			dbs = append(dbs, db)
+		}
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	g.dbs = dbs
+	g.cache = make(map[string]*GeoInfo)
+	return nil
+}
+
+func (g *GeoIP) Enabled() bool {
+	g.RLock()
+	defer g.RUnlock()
+	return len(g.dbs) > 0
+}
+
+// Databases returns the types of the loaded databases.
+func (g *GeoIP) Databases() []string {
+	g.RLock()
+	defer g.RUnlock()
+
+	types := make([]string, 0, len(g.dbs))
+	for _, db := range g.dbs {
+		types = append(types, db.Type)
+	}
+	return types
+}
+
+func geoString(record map[string]interface{}, path ...string) string {
+	var value interface{} = record
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = m[key]
+	}
+	s, _ := value.(string)
+	return s
+}
+
+func (info *GeoInfo) merge(record map[string]interface{}) {
+	if info.Country == "" {
+		if info.Country = geoString(record, "country", "iso_code"); info.Country == "" {
+			info.Country = geoString(record, "registered_country", "iso_code")
+		}
+	}
+	if info.CountryName == "" {
+		info.CountryName = geoString(record, "country", "names", "en")
+	}
+	if info.City == "" {
+		info.City = geoString(record, "city", "names", "en")
+	}
+	if info.ASN == 0 {
+		info.ASN, _ = record["autonomous_system_number"].(uint64)
+	}
+	if info.Org == "" {
+		info.Org = geoString(record, "autonomous_system_organization")
+	}
+}
+
+// Lookup returns what is known about an external address or nil if the
+// address is internal, not found or no database has been loaded.
+func (g *GeoIP) Lookup(ip net.IP) *GeoInfo {
+	if !IsExternalIP(ip) {
+		return nil
+	}
+
+	key := ip.String()
+
+	g.RLock()
+	if len(g.dbs) == 0 {
+		g.RUnlock()
+		return nil
+	} else if info, found := g.cache[key]; found {
+		g.RUnlock()
+		return info
+	}
+	dbs := g.dbs
+	g.RUnlock()
+
+	info := &GeoInfo{}
+	for _, db := range dbs {
+		if value, found, err := db.Lookup(ip); err == nil && found {
+			if record, ok := value.(map[string]interface{}); ok {
+				info.merge(record)
+			}
+		}
+	}
+
+	if *info == (GeoInfo{}) {
+		info = nil
+	}
+
+	g.Lock()
+	defer g.Unlock()
+	if len(g.dbs) == 0 || g.dbs[0] != dbs[0] {
+		// the databases have been reloaded meanwhile
+		return info
+	} else if len(g.cache) >= geoCacheMax {
+		g.cache = make(map[string]*GeoInfo)
+	}
+	g.cache[key] = info
+
+	return info
+}
@@ -0,0 +1,300 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"net"
+)
+
+// a minimal reader of the MaxMind DB format, see
+// https://maxmind.github.io/MaxMind-DB/
+
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbArray
+	mmdbContainer
+	mmdbEndMarker
+	mmdbBool
+	mmdbFloat
+)
+
+// the maximum nesting of maps and arrays, protects from malicious files
+const mmdbMaxDepth = 32
+
+type mmdbDecoder struct {
+	buf []byte
+}
+
+type mmdb struct {
+	Type       string
+	IPVersion  uint
+	NodeCount  uint
+	RecordSize uint
+
+	tree      []byte
+	data      mmdbDecoder
+	ipv4Start uint
+}
+
+func openMMDB(path string) (*mmdb, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseMMDB(raw)
+}
+
+func mmdbUint(meta map[string]interface{}, key string) (uint, error) {
+	switch v := meta[key].(type) {
+	case uint64:
+		return uint(v), nil
+	}
+	return 0, fmt.Errorf("invalid or missing %s in database metadata", key)
+}
+
+func parseMMDB(raw []byte) (db *mmdb, err error) {
+	idx := bytes.LastIndex(raw, mmdbMetadataMarker)
+	if idx == -1 {
+		return nil, fmt.Errorf("not a MaxMind database")
+	}
+
+	metaDecoder := mmdbDecoder{buf: raw[idx+len(mmdbMetadataMarker):]}
+	value, _, err := metaDecoder.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding database metadata: %v", err)
+	}
+	meta, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected database metadata")
+	}
+
+	db = &mmdb{}
+	if db.NodeCount, err = mmdbUint(meta, "node_count"); err != nil {
+		return nil, err
+	} else if db.RecordSize, err = mmdbUint(meta, "record_size"); err != nil {
+		return nil, err
+	} else if db.IPVersion, err = mmdbUint(meta, "ip_version"); err != nil {
+		return nil, err
+	} else if db.RecordSize != 24 && db.RecordSize != 28 && db.RecordSize != 32 {
+		return nil, fmt.Errorf("unsupported record size %d", db.RecordSize)
+	} else if db.IPVersion != 4 && db.IPVersion != 6 {
+		return nil, fmt.Errorf("unsupported ip version %d", db.IPVersion)
+	}
+	db.Type, _ = meta["database_type"].(string)
+
+	treeSize := db.NodeCount * db.RecordSize / 4
+	if treeSize+16 > uint(idx) {
+		return nil, fmt.Errorf("the search tree is larger than the database")
+	}
+	db.tree = raw[:treeSize]
+	db.data = mmdbDecoder{buf: raw[treeSize+16 : idx]}
+
+	// IPv4 addresses live under ::/96 in IPv6 databases
+	if db.IPVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < db.NodeCount; i++ {
+			if node, err = db.record(node, 0); err != nil {
+				return nil, err
+			}
+		}
+		db.ipv4Start = node
+	}
+
+	return db, nil
+}
+
+func (db *mmdb) record(node uint, bit uint) (uint, error) {
+	size := db.RecordSize / 4
+	off := node * size
+	if off+size > uint(len(db.tree)) {
+		return 0, fmt.Errorf("node %d out of the search tree", node)
+	}
+	b := db.tree[off : off+size]
+
+	switch db.RecordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6]), nil
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:])), nil
+	}
+}
+
+// Lookup returns the data record of the network containing ip, found is
+// false if the address is not in the database.
+func (db *mmdb) Lookup(ip net.IP) (value interface{}, found bool, err error) {
+	node := uint(0)
+	addr := ip.To4()
+	if addr != nil {
+		node = db.ipv4Start
+	} else if db.IPVersion == 4 {
+		return nil, false, nil
+	} else if addr = ip.To16(); addr == nil {
+		return nil, false, fmt.Errorf("invalid address %v", ip)
+	}
+
+	for i := uint(0); i < uint(len(addr))*8 && node < db.NodeCount; i++ {
+		bit := uint(addr[i>>3]>>(7-(i&7))) & 1
+		if node, err = db.record(node, bit); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if node == db.NodeCount {
+		return nil, false, nil
+	} else if node < db.NodeCount+16 {
+		return nil, false, fmt.Errorf("invalid search tree")
+	}
+
+	offset := node - db.NodeCount - 16
+	if value, _, err = db.data.decode(offset, 0); err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (d mmdbDecoder) bytes(offset, size uint) ([]byte, error) {
+	if offset+size > uint(len(d.buf)) || offset+size < offset {
+		return nil, fmt.Errorf("unexpected end of data at offset %d", offset)
+	}
+	return d.buf[offset : offset+size], nil
+}
+
+func (d mmdbDecoder) uint(offset, size uint) (uint64, error) {
+	if size > 8 {
+		return 0, fmt.Errorf("integer of %d bytes", size)
+	}
+	b, err := d.bytes(offset, size)
+	if err != nil {
+		return 0, err
+	}
+	v := uint64(0)
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// decode returns the value at offset and the offset of the next one.
+func (d mmdbDecoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > mmdbMaxDepth {
+		return nil, 0, fmt.Errorf("maximum data nesting exceeded")
+	}
+
+	ctrl, err := d.bytes(offset, 1)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset++
+
+	kind := uint(ctrl[0] >> 5)
+	if kind == mmdbPointer {
+		ss := uint(ctrl[0]>>3) & 3
+		p, err := d.uint(offset, ss+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch ss {
+		case 0:
+			p |= uint64(ctrl[0]&7) << 8
+		case 1:
+			p = p | uint64(ctrl[0]&7)<<16 + 2048
+		case 2:
+			p = p | uint64(ctrl[0]&7)<<24 + 526336
+		}
+		// pointers to pointers are not allowed by the format
+		value, _, err := d.decode(uint(p), depth+1)
+		return value, offset + ss + 1, err
+	} else if kind == mmdbExtended {
+		ext, err := d.bytes(offset, 1)
+		if err != nil {
+			return nil, 0, err
+		}
+		offset++
+		kind = 7 + uint(ext[0])
+	}
+
+	size := uint(ctrl[0] & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		v, err := d.uint(offset, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		offset += n
+		size = [...]uint{29, 285, 65821}[n-1] + uint(v)
+	}
+
+	switch kind {
+	case mmdbString:
+		b, err := d.bytes(offset, size)
+		return string(b), offset + size, err
+	case mmdbBytes:
+		b, err := d.bytes(offset, size)
+		return b, offset + size, err
+	case mmdbDouble:
+		v, err := d.uint(offset, 8)
+		return math.Float64frombits(v), offset + 8, err
+	case mmdbFloat:
+		v, err := d.uint(offset, 4)
+		return float64(math.Float32frombits(uint32(v))), offset + 4, err
+	case mmdbUint16, mmdbUint32, mmdbUint64:
+		v, err := d.uint(offset, size)
+		return v, offset + size, err
+	case mmdbInt32:
+		v, err := d.uint(offset, size)
+		return int64(int32(v)), offset + size, err
+	case mmdbUint128:
+		b, err := d.bytes(offset, size)
+		return new(big.Int).SetBytes(b), offset + size, err
+	case mmdbBool:
+		return size != 0, offset, nil
+	case mmdbMap:
+		m := make(map[string]interface{})
+		for i := uint(0); i < size; i++ {
+			var key, value interface{}
+			if key, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			} else if _, ok := key.(string); !ok {
+				return nil, 0, fmt.Errorf("map key is not a string")
+			} else if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			m[key.(string)] = value
+		}
+		return m, offset, nil
+	case mmdbArray:
+		a := make([]interface{}, 0)
+		for i := uint(0); i < size; i++ {
+			var value interface{}
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+		}
+		return a, offset, nil
+	}
+
+	return nil, 0, fmt.Errorf("unsupported data type %d at offset %d", kind, offset)
+}
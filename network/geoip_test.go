@@ -0,0 +1,209 @@
+package network
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// mmdbValue encodes strings shorter than 285 bytes, uint32 and maps (with
+// sorted keys) as the MaxMind DB data section does.
+func mmdbValue(v interface{}) []byte {
+	ctrl := func(kind, size int) []byte {
+		extra := []byte{}
+		if size >= 29 {
+			extra = []byte{byte(size - 29)}
+			size = 29
+		}
+		if kind > 7 {
+			return append([]byte{byte(size), byte(kind - 7)}, extra...)
+		}
+		return append([]byte{byte(kind<<5 | size)}, extra...)
+	}
+
+	switch v := v.(type) {
+	case string:
+		return append(ctrl(mmdbString, len(v)), v...)
+	case uint32:
+		return append(ctrl(mmdbUint32, 4), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	case map[string]interface{}:
+		keys := make([]string, 0)
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := ctrl(mmdbMap, len(v))
+		for _, k := range keys {
+			out = append(out, mmdbValue(k)...)
+			out = append(out, mmdbValue(v[k])...)
+		}
+		return out
+	}
+	panic("unsupported type")
+}
+
+// buildMMDB returns an IPv4 database with 24 bits records mapping cidr to record.
+func buildMMDB(cidr string, record map[string]interface{}) []byte {
+	_, network, _ := net.ParseCIDR(cidr)
+	bits, _ := network.Mask.Size()
+	ip := network.IP.To4()
+	nodeCount := bits
+
+	tree := make([]byte, 0)
+	for i := 0; i < bits; i++ {
+		next := i + 1
+		if next == bits {
+			// pointer to the first record of the data section
+			next = nodeCount + 16
+		}
+
+		left, right := nodeCount, nodeCount
+		if (ip[i>>3]>>(7-uint(i&7)))&1 == 1 {
+			right = next
+		} else {
+			left = next
+		}
+		tree = append(tree, byte(left>>16), byte(left>>8), byte(left), byte(right>>16), byte(right>>8), byte(right))
+	}
+
+	raw := append(tree, make([]byte, 16)...)
+	raw = append(raw, mmdbValue(record)...)
+	raw = append(raw, mmdbMetadataMarker...)
+	raw = append(raw, mmdbValue(map[string]interface{}{
+		"node_count":    uint32(nodeCount),
+		"record_size":   uint32(24),
+		"ip_version":    uint32(4),
+		"database_type": "Test",
+	})...)
+	return raw
+}
+
+func TestMMDBLookup(t *testing.T) {
+	db, err := parseMMDB(buildMMDB("8.8.8.0/24", map[string]interface{}{
+		"country": map[string]interface{}{
+			"iso_code": "US",
+			"names": map[string]interface{}{
+				"en": "United States",
+			},
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	} else if db.Type != "Test" || db.NodeCount != 24 {
+		t.Fatalf("unexpected metadata %+v", db)
+	}
+
+	value, found, err := db.Lookup(net.ParseIP("8.8.8.8"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !found {
+		t.Fatal("expected 8.8.8.8 to be found")
+	} else if code := geoString(value.(map[string]interface{}), "country", "iso_code"); code != "US" {
+		t.Fatalf("expected US, got '%s'", code)
+	}
+
+	for _, addr := range []string{"8.8.9.8", "1.1.1.1", "2001:4860:4860::8888"} {
+		if _, found, err := db.Lookup(net.ParseIP(addr)); err != nil {
+			t.Fatal(err)
+		} else if found {
+			t.Fatalf("%s should not be found", addr)
+		}
+	}
+
+	if _, err := parseMMDB([]byte("not a database")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestGeoIP(t *testing.T) {
+	folder, err := ioutil.TempDir("", "geoip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+
+	country := filepath.Join(folder, "country.mmdb")
+	asn := filepath.Join(folder, "asn.mmdb")
+	ioutil.WriteFile(country, buildMMDB("8.8.0.0/16", map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "US"},
+	}), 0644)
+	ioutil.WriteFile(asn, buildMMDB("8.8.8.0/24", map[string]interface{}{
+		"autonomous_system_number":       uint32(15169),
+		"autonomous_system_organization": "Google LLC",
+	}), 0644)
+
+	geo := NewGeoIP()
+	if geo.Lookup(net.ParseIP("8.8.8.8")) != nil {
+		t.Fatal("expected no info without databases")
+	}
+
+	if err := geo.Load(country, asn); err != nil {
+		t.Fatal(err)
+	} else if !geo.Enabled() {
+		t.Fatal("expected geoip to be enabled")
+	}
+
+	info := geo.Lookup(net.ParseIP("8.8.8.8"))
+	if info == nil {
+		t.Fatal("expected info for 8.8.8.8")
+	} else if s := info.String(); s != "US AS15169 Google LLC" {
+		t.Fatalf("unexpected info '%s'", s)
+	}
+
+	if info = geo.Lookup(net.ParseIP("8.8.4.4")); info == nil || info.Country != "US" || info.ASN != 0 {
+		t.Fatalf("unexpected info %+v", info)
+	} else if geo.Lookup(net.ParseIP("192.168.1.1")) != nil {
+		t.Fatal("expected no info for internal addresses")
+	} else if geo.Lookup(net.ParseIP("1.1.1.1")) != nil {
+		t.Fatal("expected no info for unknown addresses")
+	}
+
+	if err := geo.Load(filepath.Join(folder, "missing.mmdb")); err == nil {
+		t.Fatal("expected error")
+	} else if !geo.Enabled() {
+		t.Fatal("a failed load should keep the previous databases")
+	}
+
+	flows := NewFlows()
+	flows.SetGeoIP(geo)
+	flows.Track("tcp", net.ParseIP("192.168.1.10"), 50000, net.ParseIP("8.8.8.8"), 443, 60, FlowSYN)
+	flow := flows.List()[0]
+	if flow.SrcGeo != nil || flow.DstGeo == nil || flow.DstGeo.Org != "Google LLC" {
+		t.Fatalf("unexpected flow geo %+v %+v", flow.SrcGeo, flow.DstGeo)
+	}
+
+	if filter, err := ParseFlowFilter("US"); err != nil {
+		t.Fatal(err)
+	} else if !filter.Matches(flow) {
+		t.Fatal("expected flow to match US")
+	} else if filter, _ = ParseFlowFilter("IT"); filter.Matches(flow) {
+		t.Fatal("expected flow not to match IT")
+	}
+
+	if err := geo.Load(); err != nil {
+		t.Fatal(err)
+	} else if geo.Enabled() {
+		t.Fatal("expected geoip to be disabled")
+	}
+}
+
+func TestIsExternalIP(t *testing.T) {
+	for addr, expected := range map[string]bool{
+		"8.8.8.8":     true,
+		"10.0.0.1":    false,
+		"172.20.1.1":  false,
+		"127.0.0.1":   false,
+		"224.0.0.251": false,
+		"0.0.0.0":     false,
+		"2a00:1450::": true,
+		"fe80::1":     false,
+		"fd00::1":     false,
+	} {
+		if got := IsExternalIP(net.ParseIP(addr)); got != expected {
+			t.Fatalf("expected %v for %s, got %v", expected, addr, got)
+		}
+	}
+}
@@ -38,24 +38,32 @@ func (t HIDType) String() string {
 
 type HIDPayload []byte
 
+// HIDChannelStats tells how many frames of a device were received on a
+// channel and when the last one was.
+type HIDChannelStats struct {
+	Hits     uint64    `json:"hits"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
 type HIDDevice struct {
 	sync.Mutex
 	LastSeen   time.Time
 	Type       HIDType
 	Address    string
 	RawAddress []byte
-	channels   map[int]bool
+	channels   map[int]*HIDChannelStats
 	payloads   []HIDPayload
 	payloadsSz uint64
 }
 
 type hidDeviceJSON struct {
-	LastSeen     time.Time `json:"last_seen"`
-	Type         string    `json:"type"`
-	Address      string    `json:"address"`
-	Channels     []string  `json:"channels"`
-	Payloads     []string  `json:"payloads"`
-	PayloadsSize uint64    `json:"payloads_size"`
+	LastSeen     time.Time               `json:"last_seen"`
+	Type         string                  `json:"type"`
+	Address      string                  `json:"address"`
+	Channels     []string                `json:"channels"`
+	ChannelStats map[int]HIDChannelStats `json:"channel_stats"`
+	Payloads     []string                `json:"payloads"`
+	PayloadsSize uint64                  `json:"payloads_size"`
 }
 
 func NormalizeHIDAddress(address string) string {
@@ -83,7 +91,7 @@ func NewHIDDevice(address []byte, channel int, payload []byte) *HIDDevice {
 		Type:       HIDTypeUnknown,
 		RawAddress: address,
 		Address:    HIDAddress(address),
-		channels:   make(map[int]bool),
+		channels:   make(map[int]*HIDChannelStats),
 		payloads:   make([]HIDPayload, 0),
 		payloadsSz: 0,
 	}
@@ -103,6 +111,7 @@ func (dev *HIDDevice) MarshalJSON() ([]byte, error) {
 		Type:         dev.Type.String(),
 		Address:      dev.Address,
 		Channels:     dev.channelsListUnlocked(),
+		ChannelStats: dev.channelStatsUnlocked(),
 		Payloads:     make([]string, 0),
 		PayloadsSize: dev.payloadsSz,
 	}
@@ -123,16 +132,66 @@ func (dev *HIDDevice) AddChannel(ch int) {
 	dev.Lock()
 	defer dev.Unlock()
 
-	dev.channels[ch] = true
+	stats, found := dev.channels[ch]
+	if !found {
+		stats = &HIDChannelStats{}
+		dev.channels[ch] = stats
+	}
+	stats.Hits++
+	stats.LastSeen = time.Now()
 }
 
 func (dev *HIDDevice) channelsListUnlocked() []string {
-	chans := []string{}
+	nums := make([]int, 0, len(dev.channels))
 	for ch := range dev.channels {
+		nums = append(nums, ch)
+	}
+
+	sort.Ints(nums)
+
+	chans := []string{}
+	for _, ch := range nums {
 		chans = append(chans, fmt.Sprintf("%d", ch))
 	}
 
-	sort.Strings(chans)
+	return chans
+}
+
+func (dev *HIDDevice) channelStatsUnlocked() map[int]HIDChannelStats {
+	stats := make(map[int]HIDChannelStats, len(dev.channels))
+	for ch, s := range dev.channels {
+		stats[ch] = *s
+	}
+	return stats
+}
+
+// ChannelStats returns a copy of the per channel statistics of the device.
+func (dev *HIDDevice) ChannelStats() map[int]HIDChannelStats {
+	dev.Lock()
+	defer dev.Unlock()
+	return dev.channelStatsUnlocked()
+}
+
+// ActiveChannels returns the channels the device has been seen on in the
+// given period of time, the most used first.
+func (dev *HIDDevice) ActiveChannels(period time.Duration) []int {
+	dev.Lock()
+	defer dev.Unlock()
+
+	chans := make([]int, 0)
+	for ch, s := range dev.channels {
+		if time.Since(s.LastSeen) <= period {
+			chans = append(chans, ch)
+		}
+	}
+
+	sort.Slice(chans, func(i, j int) bool {
+		a, b := dev.channels[chans[i]], dev.channels[chans[j]]
+		if a.Hits == b.Hits {
+			return chans[i] < chans[j]
+		}
+		return a.Hits > b.Hits
+	})
 
 	return chans
 }
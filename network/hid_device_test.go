@@ -0,0 +1,27 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHIDDeviceChannels(t *testing.T) {
+	dev := NewHIDDevice([]byte{1, 2, 3, 4, 5}, 12, nil)
+	for _, ch := range []int{5, 5, 5, 12, 70, 70} {
+		dev.AddChannel(ch)
+	}
+
+	if got, exp := dev.Channels(), "5,12,70"; got != exp {
+		t.Fatalf("expected channels %s, got %s", exp, got)
+	} else if got, exp := dev.ActiveChannels(time.Minute), []int{5, 12, 70}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expected active channels %v, got %v", exp, got)
+	} else if stats := dev.ChannelStats(); stats[5].Hits != 3 || stats[12].Hits != 2 || stats[70].Hits != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	dev.channels[5].LastSeen = time.Now().Add(-time.Hour)
+	if got, exp := dev.ActiveChannels(time.Minute), []int{12, 70}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expected active channels %v, got %v", exp, got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/evilsocket/islazy/data"
 	"github.com/evilsocket/islazy/fs"
@@ -14,6 +15,9 @@ import (
 const LANDefaultttl = 10
 const LANAliasesFile = "~/bettercap.aliases"
 
+// a host not refreshed for this long is shown as missed by net.show
+const lanMissedAfter = 2 * time.Second
+
 type EndpointNewCallback func(e *Endpoint)
 type EndpointLostCallback func(e *Endpoint)
 
@@ -24,10 +28,17 @@ type LAN struct {
 	hosts   map[string]*Endpoint
 	iface   *Endpoint
 	gateway *Endpoint
-	ttl     map[string]uint
+	seen    map[string]time.Time
+	sources map[string]string
+	expiry  *ExpiryPolicy
 	aliases *data.UnsortedKV
 	newCb   EndpointNewCallback
 	lostCb  EndpointLostCallback
+	expCb   EndpointExpiredCallback
+
+	// names learned from the traffic of each host by source
+	learned      map[string]map[string]string
+	aliasSources []string
 }
 
 type lanJSON struct {
@@ -44,10 +55,15 @@ func NewLAN(iface, gateway *Endpoint, newcb EndpointNewCallback, lostcb Endpoint
 		iface:   iface,
 		gateway: gateway,
 		hosts:   make(map[string]*Endpoint),
-		ttl:     make(map[string]uint),
+		seen:    make(map[string]time.Time),
+		sources: make(map[string]string),
+		expiry:  NewExpiryPolicy(),
 		aliases: aliases,
 		newCb:   newcb,
 		lostCb:  lostcb,
+
+		learned:      make(map[string]map[string]string),
+		aliasSources: AliasSources,
 	}
 }
 
@@ -68,9 +84,14 @@ func (lan *LAN) SetAliasFor(mac, alias string) bool {
 	defer lan.Unlock()
 
 	mac = NormalizeMac(mac)
-	lan.aliases.Set(mac, alias)
+	if alias == "" {
+		lan.aliases.Del(mac)
+	} else {
+		lan.aliases.Set(mac, alias)
+	}
 	if e, found := lan.hosts[mac]; found {
-		e.Alias = alias
+		// an empty alias falls back to the learned one
+		e.Alias = lan.aliasFor(mac)
 		return true
 	}
 	return false
@@ -128,6 +149,28 @@ func (lan *LAN) Aliases() *data.UnsortedKV {
 	return lan.aliases
 }
 
+// SetExpiry sets the policy deciding when the hosts expire.
+func (lan *LAN) SetExpiry(policy *ExpiryPolicy) {
+	lan.Lock()
+	defer lan.Unlock()
+	lan.expiry = policy
+}
+
+// OnExpired sets the callback called, after the lost one, with the reason a
+// host expired.
+func (lan *LAN) OnExpired(cb EndpointExpiredCallback) {
+	lan.Lock()
+	defer lan.Unlock()
+	lan.expCb = cb
+}
+
+// Source returns where the host has been learned from.
+func (lan *LAN) Source(mac string) string {
+	lan.Lock()
+	defer lan.Unlock()
+	return lan.sources[NormalizeMac(mac)]
+}
+
 func (lan *LAN) WasMissed(mac string) bool {
 	if mac == lan.iface.HwAddress || mac == lan.gateway.HwAddress {
 		return false
@@ -136,24 +179,33 @@ func (lan *LAN) WasMissed(mac string) bool {
 	lan.Lock()
 	defer lan.Unlock()
 
-	if ttl, found := lan.ttl[mac]; found {
-		return ttl < LANDefaultttl
+	if seen, found := lan.seen[mac]; found {
+		return time.Since(seen) > lanMissedAfter
 	}
 	return true
 }
 
+// Remove is called when the host is missing from the ARP table, it's removed
+// if it hasn't been seen for longer than the expiry of its source.
 func (lan *LAN) Remove(ip, mac string) {
 	lan.Lock()
 	defer lan.Unlock()
 
 	if e, found := lan.hosts[mac]; found {
-		lan.ttl[mac]--
-		if lan.ttl[mac] == 0 {
+		source := lan.sources[mac]
+		if expired, reason := lan.expiry.Check(source, lan.seen[mac]); expired {
 			delete(lan.hosts, mac)
-			delete(lan.ttl, mac)
+			delete(lan.seen, mac)
+			delete(lan.sources, mac)
 			lan.lostCb(e)
+			if lan.expCb != nil {
+				lan.expCb(ExpiredEndpoint{
+					Endpoint: e,
+					Source:   source,
+					Reason:   reason,
+				})
+			}
 		}
-		return
 	}
 }
 
@@ -201,7 +253,14 @@ func (lan *LAN) EachHost(cb func(mac string, e *Endpoint)) {
 	}
 }
 
+// AddIfNew adds a host found in the ARP table, see AddIfNewFrom.
 func (lan *LAN) AddIfNew(ip, mac string) *Endpoint {
+	return lan.AddIfNewFrom(ip, mac, SourceARP)
+}
+
+// AddIfNewFrom refreshes the host if it's already known and returns it,
+// otherwise it adds it as learned from the given source and returns nil.
+func (lan *LAN) AddIfNewFrom(ip, mac, source string) *Endpoint {
 	lan.Lock()
 	defer lan.Unlock()
 
@@ -210,16 +269,15 @@ func (lan *LAN) AddIfNew(ip, mac string) *Endpoint {
 	if lan.shouldIgnore(ip, mac) {
 		return nil
 	} else if t, found := lan.hosts[mac]; found {
-		if lan.ttl[mac] < LANDefaultttl {
-			lan.ttl[mac]++
-		}
+		lan.seen[mac] = time.Now()
 		return t
 	}
 
-	e := NewEndpointWithAlias(ip, mac, lan.aliases.GetOr(mac, ""))
+	e := NewEndpointWithAlias(ip, mac, lan.aliasFor(mac))
 
 	lan.hosts[mac] = e
-	lan.ttl[mac] = LANDefaultttl
+	lan.seen[mac] = time.Now()
+	lan.sources[mac] = source
 
 	lan.newCb(e)
 
@@ -227,12 +285,15 @@ func (lan *LAN) AddIfNew(ip, mac string) *Endpoint {
 }
 
 func (lan *LAN) GetAlias(mac string) string {
-	return lan.aliases.GetOr(mac, "")
+	lan.Lock()
+	defer lan.Unlock()
+	return lan.aliasFor(NormalizeMac(mac))
 }
 
 func (lan *LAN) Clear() {
 	lan.Lock()
 	defer lan.Unlock()
 	lan.hosts = make(map[string]*Endpoint)
-	lan.ttl = make(map[string]uint)
+	lan.seen = make(map[string]time.Time)
+	lan.sources = make(map[string]string)
 }
@@ -0,0 +1,133 @@
+package network
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// AliasSources are the protocols endpoint names can be learned from, in
+// their default order of precedence.
+var AliasSources = []string{"dhcp", "nbns", "mdns"}
+
+// AliasResolver returns the alias of a MAC address or an empty string.
+type AliasResolver interface {
+	GetAlias(mac string) string
+}
+
+// ParseAliasSources parses a comma separated list of alias sources, the
+// first ones take precedence.
+func ParseAliasSources(value string) ([]string, error) {
+	sources := make([]string, 0)
+	for _, source := range strings.Split(value, ",") {
+		if source = strings.ToLower(strings.TrimSpace(source)); source == "" {
+			continue
+		} else if source == "netbios" {
+			source = "nbns"
+		}
+
+		valid := false
+		for _, s := range AliasSources {
+			if s == source {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown alias source '%s', valid sources are %s", source, strings.Join(AliasSources, ", "))
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// aliasFor must be called with the lock held, aliases set by the user take
+// precedence over the learned ones.
+func (lan *LAN) aliasFor(mac string) string {
+	if alias := lan.aliases.GetOr(mac, ""); alias != "" {
+		return alias
+	}
+	if learned, found := lan.learned[mac]; found {
+		for _, source := range lan.aliasSources {
+			if name := learned[source]; name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// refreshAliases must be called with the lock held.
+func (lan *LAN) refreshAliases() {
+	for mac, e := range lan.hosts {
+		e.Alias = lan.aliasFor(mac)
+	}
+}
+
+// SetAliasSources sets which protocols endpoint aliases are learned from and
+// their order of precedence, no sources disables the learning.
+func (lan *LAN) SetAliasSources(sources []string) {
+	lan.Lock()
+	defer lan.Unlock()
+
+	lan.aliasSources = sources
+	lan.refreshAliases()
+}
+
+// LearnAliases stores the names found in the <source>:hostname metadata of
+// an endpoint, they're used as aliases when the user didn't set any.
+func (lan *LAN) LearnAliases(mac string, meta map[string]string) {
+	lan.Lock()
+	defer lan.Unlock()
+
+	mac = NormalizeMac(mac)
+	for _, source := range AliasSources {
+		name := strings.TrimSpace(strings.TrimSuffix(meta[source+":hostname"], "."))
+		if name == "" {
+			continue
+		}
+
+		if lan.learned[mac] == nil {
+			lan.learned[mac] = make(map[string]string)
+		}
+		lan.learned[mac][source] = name
+	}
+
+	if e, found := lan.hosts[mac]; found {
+		e.Alias = lan.aliasFor(mac)
+	}
+}
+
+// ParseAliasesCSV parses a CSV file of mac,alias rows, the header and
+// comments starting with # are optional.
+func ParseAliasesCSV(r io.Reader) (error, map[string]string) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("error parsing CSV: %v", err), nil
+	}
+
+	aliases := make(map[string]string)
+	for n, record := range records {
+		if len(record) < 2 {
+			return fmt.Errorf("line %d: expected mac,alias", n+1), nil
+		}
+
+		mac := strings.TrimSpace(record[0])
+		if n == 0 && strings.EqualFold(mac, "mac") {
+			continue
+		} else if _, err := net.ParseMAC(mac); err != nil {
+			return fmt.Errorf("line %d: invalid MAC address '%s'", n+1, mac), nil
+		}
+
+		aliases[NormalizeMac(mac)] = strings.TrimSpace(strings.Join(record[1:], ","))
+	}
+
+	return nil, aliases
+}
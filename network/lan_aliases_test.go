@@ -0,0 +1,108 @@
+package network
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evilsocket/islazy/data"
+)
+
+func buildAliasesLAN(t *testing.T) *LAN {
+	aliases, err := data.NewMemUnsortedKV()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &LAN{
+		iface:        NewEndpointNoResolve("192.168.1.2", "00:00:00:00:00:02", "", 24),
+		gateway:      NewEndpointNoResolve("192.168.1.1", "00:00:00:00:00:01", "", 24),
+		hosts:        make(map[string]*Endpoint),
+		seen:         make(map[string]time.Time),
+		sources:      make(map[string]string),
+		expiry:       NewExpiryPolicy(),
+		aliases:      aliases,
+		newCb:        func(e *Endpoint) {},
+		lostCb:       func(e *Endpoint) {},
+		learned:      make(map[string]map[string]string),
+		aliasSources: AliasSources,
+	}
+}
+
+func TestLANLearnAliases(t *testing.T) {
+	lan := buildAliasesLAN(t)
+
+	mac := "aa:bb:cc:dd:ee:ff"
+	lan.AddIfNew("192.168.1.10", mac)
+	e, _ := lan.Get(mac)
+
+	lan.LearnAliases(mac, map[string]string{"mdns:hostname": "printer.local."})
+	if e.Alias != "printer.local" {
+		t.Fatalf("expected mdns alias, got '%s'", e.Alias)
+	}
+
+	lan.LearnAliases(mac, map[string]string{"dhcp:hostname": "office-printer"})
+	if e.Alias != "office-printer" {
+		t.Fatalf("expected dhcp alias to take precedence, got '%s'", e.Alias)
+	}
+
+	lan.SetAliasSources([]string{"mdns", "dhcp"})
+	if e.Alias != "printer.local" {
+		t.Fatalf("expected mdns alias to take precedence, got '%s'", e.Alias)
+	}
+
+	lan.SetAliasFor(mac, "boss printer")
+	if e.Alias != "boss printer" || lan.GetAlias(mac) != "boss printer" {
+		t.Fatalf("expected user alias, got '%s'", e.Alias)
+	}
+
+	lan.SetAliasFor(mac, "")
+	if e.Alias != "printer.local" {
+		t.Fatalf("expected learned alias after reset, got '%s'", e.Alias)
+	}
+
+	lan.SetAliasSources([]string{})
+	if e.Alias != "" || lan.GetAlias(mac) != "" {
+		t.Fatalf("expected no alias, got '%s'", e.Alias)
+	}
+
+	// learned before the host is added
+	lan.SetAliasSources(AliasSources)
+	lan.LearnAliases("11:22:33:44:55:66", map[string]string{"nbns:hostname": "DESKTOP-1"})
+	lan.AddIfNew("192.168.1.11", "11:22:33:44:55:66")
+	if e, _ := lan.Get("11:22:33:44:55:66"); e.Alias != "DESKTOP-1" {
+		t.Fatalf("expected nbns alias, got '%s'", e.Alias)
+	}
+}
+
+func TestParseAliasSources(t *testing.T) {
+	sources, err := ParseAliasSources("mdns, NetBIOS,dhcp")
+	if err != nil {
+		t.Fatal(err)
+	} else if strings.Join(sources, ",") != "mdns,nbns,dhcp" {
+		t.Fatalf("unexpected sources %v", sources)
+	}
+
+	if sources, err = ParseAliasSources(""); err != nil || len(sources) != 0 {
+		t.Fatalf("expected no sources, got %v %v", sources, err)
+	} else if _, err = ParseAliasSources("dhcp,llmnr"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestParseAliasesCSV(t *testing.T) {
+	err, aliases := ParseAliasesCSV(strings.NewReader("mac,alias\n# the printer\nAA:BB:CC:DD:EE:FF, printer\n11:22:33:44:55:66,\"desk, 2nd floor\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(aliases) != 2 {
+		t.Fatalf("expected 2 aliases, got %v", aliases)
+	} else if aliases["aa:bb:cc:dd:ee:ff"] != "printer" || aliases["11:22:33:44:55:66"] != "desk, 2nd floor" {
+		t.Fatalf("unexpected aliases %v", aliases)
+	}
+
+	for _, csv := range []string{"not-a-mac,foo\n", "aa:bb:cc:dd:ee:ff\n"} {
+		if err, _ := ParseAliasesCSV(strings.NewReader(csv)); err == nil {
+			t.Fatalf("expected error for %q", csv)
+		}
+	}
+}
@@ -62,15 +62,12 @@ func NewEndpointNoResolve(ip, mac, name string, bits uint32) *Endpoint {
 
 func NewEndpoint(ip, mac string) *Endpoint {
 	e := NewEndpointNoResolve(ip, mac, "", 0)
-	// start resolver goroutine
-	go func() {
-		if names, err := net.LookupAddr(e.IpAddress); err == nil && len(names) > 0 {
-			e.Hostname = names[0]
-			if e.ResolvedCallback != nil {
-				e.ResolvedCallback(e)
-			}
+	ReverseDNS.Lookup(e.IpAddress, func(name string) {
+		e.Hostname = name
+		if e.ResolvedCallback != nil {
+			e.ResolvedCallback(e)
 		}
-	}()
+	})
 
 	return e
 }
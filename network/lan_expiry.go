@@ -0,0 +1,92 @@
+package network
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// the sources endpoints are learned from
+const (
+	// the ARP table of the operating system, read by net.recon
+	SourceARP = "arp"
+	// the traffic of the host seen by the packet queue
+	SourceSniffed = "sniffed"
+	// the replies to the probes sent by net.scan
+	SourceScan = "scan"
+	// the hosts imported with net.import
+	SourceImported = "imported"
+	// the access points and the clients of the wifi module
+	SourceWiFiAP     = "wifi.ap"
+	SourceWiFiClient = "wifi.client"
+)
+
+var defaultExpiry = map[string]time.Duration{
+	SourceARP:        LANDefaultttl * time.Second,
+	SourceSniffed:    LANDefaultttl * time.Second,
+	SourceScan:       LANDefaultttl * time.Second,
+	SourceImported:   0,
+	SourceWiFiAP:     5 * time.Minute,
+	SourceWiFiClient: 5 * time.Minute,
+}
+
+// ExpiredEndpoint is the endpoint.expired event, sent when a host is removed
+// for having been silent for longer than allowed by the expiry policy of its
+// source.
+type ExpiredEndpoint struct {
+	Endpoint *Endpoint `json:"endpoint"`
+	Source   string    `json:"source"`
+	Reason   string    `json:"reason"`
+}
+
+type EndpointExpiredCallback func(e ExpiredEndpoint)
+
+// ExpiryPolicy is how long the endpoints learned from each source can go
+// without being seen before they expire, zero meaning never.
+type ExpiryPolicy struct {
+	sync.RWMutex
+	ttls map[string]time.Duration
+}
+
+func NewExpiryPolicy() *ExpiryPolicy {
+	p := &ExpiryPolicy{
+		ttls: make(map[string]time.Duration),
+	}
+	for source, ttl := range defaultExpiry {
+		p.ttls[source] = ttl
+	}
+	return p
+}
+
+func (p *ExpiryPolicy) Set(source string, ttl time.Duration) {
+	p.Lock()
+	defer p.Unlock()
+	p.ttls[source] = ttl
+}
+
+// TTL returns the expiry of the source, the one of the ARP table for the
+// unknown ones.
+func (p *ExpiryPolicy) TTL(source string) time.Duration {
+	p.RLock()
+	defer p.RUnlock()
+
+	if ttl, found := p.ttls[source]; found {
+		return ttl
+	}
+	return p.ttls[SourceARP]
+}
+
+// Check returns whether an endpoint of the source last seen at the given
+// time has expired and why.
+func (p *ExpiryPolicy) Check(source string, lastSeen time.Time) (bool, string) {
+	ttl := p.TTL(source)
+	if ttl <= 0 {
+		return false, ""
+	}
+
+	silence := time.Since(lastSeen)
+	if silence <= ttl {
+		return false, ""
+	}
+	return true, fmt.Sprintf("not seen for %s, %s hosts expire after %s", silence.Round(time.Second), source, ttl)
+}
@@ -0,0 +1,65 @@
+package network
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpiryPolicyCheck(t *testing.T) {
+	p := NewExpiryPolicy()
+	p.Set(SourceSniffed, time.Minute)
+
+	if expired, _ := p.Check(SourceSniffed, time.Now().Add(-30*time.Second)); expired {
+		t.Fatal("expected not to be expired")
+	} else if expired, reason := p.Check(SourceSniffed, time.Now().Add(-2*time.Minute)); !expired {
+		t.Fatal("expected to be expired")
+	} else if !strings.Contains(reason, "sniffed hosts expire after 1m0s") {
+		t.Fatalf("unexpected reason '%s'", reason)
+	} else if expired, _ := p.Check(SourceImported, time.Now().Add(-24*time.Hour)); expired {
+		t.Fatal("imported hosts should never expire by default")
+	} else if got, exp := p.TTL("unknown"), p.TTL(SourceARP); got != exp {
+		t.Fatalf("expected %s, got %s", exp, got)
+	}
+}
+
+func TestLANExpiry(t *testing.T) {
+	lan := buildAliasesLAN(t)
+
+	lost := make([]string, 0)
+	lan.lostCb = func(e *Endpoint) {
+		lost = append(lost, e.HwAddress)
+	}
+	expired := make([]ExpiredEndpoint, 0)
+	lan.OnExpired(func(e ExpiredEndpoint) {
+		expired = append(expired, e)
+	})
+
+	lan.AddIfNew("192.168.1.10", "11:22:33:44:55:01")
+	lan.AddIfNewFrom("192.168.1.11", "11:22:33:44:55:02", SourceSniffed)
+	if _, _, err := lan.Import(&ImportedHost{IP: "192.168.1.12", MAC: "11:22:33:44:55:03"}, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := lan.Source("11:22:33:44:55:02"); got != SourceSniffed {
+		t.Fatalf("expected source %s, got %s", SourceSniffed, got)
+	}
+
+	// all of them have been silent for an hour
+	for mac := range lan.seen {
+		lan.seen[mac] = time.Now().Add(-time.Hour)
+	}
+	lan.expiry.Set(SourceSniffed, 2*time.Hour)
+
+	for _, e := range lan.List() {
+		lan.Remove(e.IpAddress, e.HwAddress)
+	}
+
+	if len(lost) != 1 || lost[0] != "11:22:33:44:55:01" {
+		t.Fatalf("unexpected lost hosts %v", lost)
+	} else if len(expired) != 1 || expired[0].Source != SourceARP || expired[0].Reason == "" {
+		t.Fatalf("unexpected expired hosts %+v", expired)
+	} else if len(lan.List()) != 2 {
+		t.Fatalf("expected 2 hosts, got %d", len(lan.List()))
+	}
+}
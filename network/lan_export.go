@@ -0,0 +1,229 @@
+package network
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type nmapExportRun struct {
+	XMLName  xml.Name         `xml:"nmaprun"`
+	Scanner  string           `xml:"scanner,attr"`
+	Args     string           `xml:"args,attr"`
+	Start    int64            `xml:"start,attr"`
+	Version  string           `xml:"version,attr"`
+	XMLVer   string           `xml:"xmloutputversion,attr"`
+	Hosts    []nmapExportHost `xml:"host"`
+	Finished struct {
+		Time int64 `xml:"time,attr"`
+	} `xml:"runstats>finished"`
+	Stats struct {
+		Up    int `xml:"up,attr"`
+		Down  int `xml:"down,attr"`
+		Total int `xml:"total,attr"`
+	} `xml:"runstats>hosts"`
+}
+
+type nmapExportAddress struct {
+	Addr   string `xml:"addr,attr"`
+	Type   string `xml:"addrtype,attr"`
+	Vendor string `xml:"vendor,attr,omitempty"`
+}
+
+type nmapExportHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type nmapExportPort struct {
+	Proto string `xml:"protocol,attr"`
+	Port  int    `xml:"portid,attr"`
+	State struct {
+		State  string `xml:"state,attr"`
+		Reason string `xml:"reason,attr"`
+	} `xml:"state"`
+	Service *nmapExportService `xml:"service,omitempty"`
+}
+
+type nmapExportService struct {
+	Name   string `xml:"name,attr"`
+	Method string `xml:"method,attr"`
+}
+
+type nmapExportOS struct {
+	Name     string `xml:"name,attr"`
+	Accuracy int    `xml:"accuracy,attr"`
+}
+
+type nmapExportHost struct {
+	StartTime int64 `xml:"starttime,attr"`
+	EndTime   int64 `xml:"endtime,attr"`
+	Status    struct {
+		State  string `xml:"state,attr"`
+		Reason string `xml:"reason,attr"`
+	} `xml:"status"`
+	Addresses []nmapExportAddress  `xml:"address"`
+	Hostnames []nmapExportHostname `xml:"hostnames>hostname"`
+	Ports     []nmapExportPort     `xml:"ports>port"`
+	OS        []nmapExportOS       `xml:"os>osmatch,omitempty"`
+}
+
+// EndpointPorts returns the sorted list of open ports for the protocol,
+// as saved in the endpoint metadata by syn.scan or net.import.
+func EndpointPorts(e *Endpoint, proto string) []int {
+	ports := make([]int, 0)
+	raw, _ := e.Meta.Get(proto + "-ports").(string)
+	for _, s := range strings.Split(raw, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			ports = append(ports, n)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// EndpointService returns the service description known for the port, if any.
+func EndpointService(e *Endpoint, proto string, port int) string {
+	svc, _ := e.Meta.Get(fmt.Sprintf("%s-port:%d", proto, port)).(string)
+	return svc
+}
+
+// EndpointOS returns the operating system guessed by fingerprinting or imported.
+func EndpointOS(e *Endpoint) string {
+	for _, key := range []string{"imported:os", "dhcp:os"} {
+		if os, ok := e.Meta.Get(key).(string); ok && os != "" {
+			return os
+		}
+	}
+	return ""
+}
+
+// the service name without the product and version details
+func serviceName(desc string) string {
+	if idx := strings.IndexByte(desc, ' '); idx != -1 {
+		return desc[:idx]
+	}
+	return desc
+}
+
+var exportProtocols = []string{"tcp", "udp"}
+
+// ExportNmapXML writes the hosts as a nmap -oX document that tools
+// like Metasploit's db_import can consume.
+func ExportNmapXML(w io.Writer, hosts []*Endpoint, start time.Time) error {
+	now := time.Now()
+	run := nmapExportRun{
+		Scanner: "nmap",
+		Args:    "bettercap",
+		Start:   start.Unix(),
+		Version: "7.80",
+		XMLVer:  "1.04",
+		Hosts:   make([]nmapExportHost, 0),
+	}
+
+	for _, e := range hosts {
+		h := nmapExportHost{
+			StartTime: e.FirstSeen.Unix(),
+			EndTime:   e.LastSeen.Unix(),
+			Addresses: []nmapExportAddress{{Addr: e.IpAddress, Type: "ipv4"}},
+			Hostnames: make([]nmapExportHostname, 0),
+			Ports:     make([]nmapExportPort, 0),
+		}
+		h.Status.State = "up"
+		h.Status.Reason = "arp-response"
+
+		if e.HwAddress != "" {
+			h.Addresses = append(h.Addresses, nmapExportAddress{
+				Addr:   strings.ToUpper(e.HwAddress),
+				Type:   "mac",
+				Vendor: e.Vendor,
+			})
+		}
+
+		if e.Hostname != "" {
+			h.Hostnames = append(h.Hostnames, nmapExportHostname{Name: e.Hostname, Type: "PTR"})
+		}
+
+		for _, proto := range exportProtocols {
+			for _, port := range EndpointPorts(e, proto) {
+				p := nmapExportPort{Proto: proto, Port: port}
+				p.State.State = "open"
+				p.State.Reason = "syn-ack"
+				if proto == "udp" {
+					p.State.Reason = "udp-response"
+				}
+				if svc := serviceName(EndpointService(e, proto, port)); svc != "" {
+					p.Service = &nmapExportService{Name: svc, Method: "table"}
+				}
+				h.Ports = append(h.Ports, p)
+			}
+		}
+
+		if os := EndpointOS(e); os != "" {
+			h.OS = []nmapExportOS{{Name: os, Accuracy: 100}}
+		}
+
+		run.Hosts = append(run.Hosts, h)
+	}
+
+	run.Finished.Time = now.Unix()
+	run.Stats.Up = len(run.Hosts)
+	run.Stats.Total = len(run.Hosts)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", " ")
+	if err := enc.Encode(run); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ExportServicesCSV writes one row per open port (or per host if no ports are
+// known) using the same columns accepted by net.import.
+func ExportServicesCSV(w io.Writer, hosts []*Endpoint) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"ip", "mac", "hostname", "os", "port", "proto", "service"}); err != nil {
+		return err
+	}
+
+	for _, e := range hosts {
+		os := EndpointOS(e)
+		rows := 0
+		for _, proto := range exportProtocols {
+			for _, port := range EndpointPorts(e, proto) {
+				if err := writer.Write([]string{
+					e.IpAddress,
+					e.HwAddress,
+					e.Hostname,
+					os,
+					strconv.Itoa(port),
+					proto,
+					EndpointService(e, proto, port),
+				}); err != nil {
+					return err
+				}
+				rows++
+			}
+		}
+
+		if rows == 0 {
+			if err := writer.Write([]string{e.IpAddress, e.HwAddress, e.Hostname, os, "", "", ""}); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
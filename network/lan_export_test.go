@@ -0,0 +1,57 @@
+package network
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func buildExportHosts() []*Endpoint {
+	e := NewEndpointNoResolve("10.0.0.1", "00:11:22:33:44:55", "web", 24)
+	e.Meta.SetInts("tcp-ports", []int{443, 80})
+	e.Meta.Set("tcp-port:80", "http (nginx 1.18)")
+	e.Meta.Set("dhcp:os", "Linux")
+
+	empty := NewEndpointNoResolve("10.0.0.2", "00:11:22:33:44:66", "", 24)
+
+	return []*Endpoint{e, empty}
+}
+
+func TestExportNmapXML(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := ExportNmapXML(&buf, buildExportHosts(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	err, hosts := ParseNmapXML(&buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(hosts) != 2 {
+		t.Fatalf("expected 2 hosts, got %d", len(hosts))
+	}
+
+	h := hosts[0]
+	if h.IP != "10.0.0.1" || h.MAC != "00:11:22:33:44:55" || h.Hostname != "web" || h.OS != "Linux" {
+		t.Fatalf("unexpected host %+v", h)
+	} else if len(h.Ports) != 2 || h.Ports[0].Port != 80 || h.Ports[0].Service != "http" {
+		t.Fatalf("unexpected ports %+v", h.Ports)
+	}
+}
+
+func TestExportServicesCSV(t *testing.T) {
+	buf := bytes.Buffer{}
+	if err := ExportServicesCSV(&buf, buildExportHosts()); err != nil {
+		t.Fatal(err)
+	}
+
+	err, hosts := ParseHostsCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(hosts) != 2 {
+		t.Fatalf("expected 2 hosts, got %d", len(hosts))
+	} else if len(hosts[0].Ports) != 2 || hosts[0].Ports[0].Service != "http (nginx 1.18)" || hosts[0].OS != "Linux" {
+		t.Fatalf("unexpected host %+v", hosts[0])
+	} else if len(hosts[1].Ports) != 0 {
+		t.Fatalf("unexpected ports for %+v", hosts[1])
+	}
+}
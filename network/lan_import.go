@@ -0,0 +1,289 @@
+package network
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImportedPort is an open port reported by an external tool.
+type ImportedPort struct {
+	Proto   string
+	Port    int
+	Service string
+	Product string
+	Version string
+}
+
+// Description returns the service name followed by product and version, if known.
+func (p ImportedPort) Description() string {
+	desc := p.Service
+	if extra := strings.TrimSpace(p.Product + " " + p.Version); extra != "" {
+		if desc != "" {
+			desc += " "
+		}
+		desc += "(" + extra + ")"
+	}
+	return desc
+}
+
+// ImportedHost is a host reported by an external tool.
+type ImportedHost struct {
+	IP       string
+	MAC      string
+	Hostname string
+	OS       string
+	Ports    []ImportedPort
+}
+
+type nmapRun struct {
+	Hosts []nmapHost `xml:"host"`
+}
+
+type nmapHost struct {
+	Status struct {
+		State string `xml:"state,attr"`
+	} `xml:"status"`
+	Addresses []struct {
+		Addr string `xml:"addr,attr"`
+		Type string `xml:"addrtype,attr"`
+	} `xml:"address"`
+	Hostnames []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"hostnames>hostname"`
+	Ports []struct {
+		Proto string `xml:"protocol,attr"`
+		Port  int    `xml:"portid,attr"`
+		State struct {
+			State string `xml:"state,attr"`
+		} `xml:"state"`
+		Service struct {
+			Name    string `xml:"name,attr"`
+			Product string `xml:"product,attr"`
+			Version string `xml:"version,attr"`
+		} `xml:"service"`
+	} `xml:"ports>port"`
+	OS []struct {
+		Name     string `xml:"name,attr"`
+		Accuracy int    `xml:"accuracy,attr"`
+	} `xml:"os>osmatch"`
+}
+
+// ParseNmapXML parses the output of nmap -oX, only hosts that are up
+// and ports that are open are returned.
+func ParseNmapXML(r io.Reader) (error, []*ImportedHost) {
+	var run nmapRun
+	if err := xml.NewDecoder(r).Decode(&run); err != nil {
+		return fmt.Errorf("error parsing nmap XML: %v", err), nil
+	}
+
+	hosts := make([]*ImportedHost, 0)
+	for _, h := range run.Hosts {
+		if h.Status.State != "" && h.Status.State != "up" {
+			continue
+		}
+
+		host := &ImportedHost{Ports: make([]ImportedPort, 0)}
+		for _, a := range h.Addresses {
+			switch a.Type {
+			case "ipv4":
+				host.IP = a.Addr
+			case "mac":
+				host.MAC = NormalizeMac(a.Addr)
+			}
+		}
+
+		if host.IP == "" {
+			continue
+		}
+
+		if len(h.Hostnames) > 0 {
+			host.Hostname = h.Hostnames[0].Name
+		}
+
+		best := -1
+		for _, os := range h.OS {
+			if os.Accuracy > best {
+				best = os.Accuracy
+				host.OS = os.Name
+			}
+		}
+
+		for _, p := range h.Ports {
+			if p.State.State != "open" {
+				continue
+			}
+			host.Ports = append(host.Ports, ImportedPort{
+				Proto:   p.Proto,
+				Port:    p.Port,
+				Service: p.Service.Name,
+				Product: p.Service.Product,
+				Version: p.Service.Version,
+			})
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	return nil, hosts
+}
+
+var csvDefaultColumns = []string{"ip", "mac", "hostname", "port", "proto", "service"}
+
+// ParseHostsCSV parses a CSV file with one host or port per row, columns are
+// taken from the header if present (ip, mac, hostname, os, port, proto, service),
+// otherwise they're expected in the ip,mac,hostname,port,proto,service order.
+func ParseHostsCSV(r io.Reader) (error, []*ImportedHost) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("error parsing CSV: %v", err), nil
+	}
+
+	columns := make(map[string]int)
+	if len(records) > 0 {
+		for i, name := range records[0] {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		if _, found := columns["ip"]; found {
+			records = records[1:]
+		} else {
+			columns = make(map[string]int)
+			for i, name := range csvDefaultColumns {
+				columns[name] = i
+			}
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, found := columns[name]; found && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	byIP := make(map[string]*ImportedHost)
+	order := make([]string, 0)
+	for n, record := range records {
+		ip := field(record, "ip")
+		if ip == "" {
+			continue
+		}
+
+		host, found := byIP[ip]
+		if !found {
+			host = &ImportedHost{IP: ip, Ports: make([]ImportedPort, 0)}
+			byIP[ip] = host
+			order = append(order, ip)
+		}
+
+		if mac := field(record, "mac"); mac != "" {
+			host.MAC = NormalizeMac(mac)
+		}
+		if name := field(record, "hostname"); name != "" {
+			host.Hostname = name
+		}
+		if os := field(record, "os"); os != "" {
+			host.OS = os
+		}
+
+		if port := field(record, "port"); port != "" {
+			num, err := strconv.Atoi(port)
+			if err != nil || num < 1 || num > 65535 {
+				return fmt.Errorf("line %d: invalid port '%s'", n+1, port), nil
+			}
+
+			proto := strings.ToLower(field(record, "proto"))
+			if proto == "" {
+				proto = "tcp"
+			}
+
+			host.Ports = append(host.Ports, ImportedPort{
+				Proto:   proto,
+				Port:    num,
+				Service: field(record, "service"),
+			})
+		}
+	}
+
+	hosts := make([]*ImportedHost, 0)
+	for _, ip := range order {
+		hosts = append(hosts, byIP[ip])
+	}
+
+	return nil, hosts
+}
+
+// Apply merges the host information into the endpoint metadata, ports are
+// stored in the same tcp-ports/udp-ports format used by syn.scan.
+func (h *ImportedHost) Apply(e *Endpoint, source string) {
+	if h.Hostname != "" && e.Hostname == "" {
+		e.Hostname = h.Hostname
+	}
+
+	e.Meta.Set("imported", source)
+	if h.OS != "" {
+		e.Meta.Set("imported:os", h.OS)
+	}
+
+	byProto := make(map[string][]int)
+	for _, p := range h.Ports {
+		byProto[p.Proto] = append(byProto[p.Proto], p.Port)
+		if desc := p.Description(); desc != "" {
+			e.Meta.Set(fmt.Sprintf("%s-port:%d", p.Proto, p.Port), desc)
+		}
+	}
+
+	for proto, ports := range byProto {
+		name := proto + "-ports"
+		for _, port := range ports {
+			merged := e.Meta.GetIntsWith(name, port, true)
+			e.Meta.SetInts(name, merged)
+		}
+	}
+}
+
+// Import adds the host to the LAN, or updates it if already known, and marks it as
+// imported so that it expires according to the policy of the imported hosts,
+// never by default.
+func (lan *LAN) Import(h *ImportedHost, source string) (isNew bool, e *Endpoint, err error) {
+	if h.MAC == "" {
+		return false, nil, fmt.Errorf("no MAC address for %s", h.IP)
+	}
+
+	if e, found := lan.Get(h.MAC); found {
+		h.Apply(e, source)
+		lan.Lock()
+		if _, isHost := lan.hosts[e.HwAddress]; isHost {
+			lan.sources[e.HwAddress] = SourceImported
+		}
+		lan.Unlock()
+		return false, e, nil
+	}
+
+	lan.Lock()
+	mac := NormalizeMac(h.MAC)
+	if lan.shouldIgnore(h.IP, mac) {
+		lan.Unlock()
+		return false, nil, fmt.Errorf("%s is not part of the LAN", h.IP)
+	}
+
+	e = NewEndpointWithAlias(h.IP, mac, lan.aliasFor(mac))
+	h.Apply(e, source)
+	lan.hosts[mac] = e
+	lan.seen[mac] = time.Now()
+	lan.sources[mac] = SourceImported
+	lan.Unlock()
+
+	lan.newCb(e)
+
+	return true, e, nil
+}
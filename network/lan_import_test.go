@@ -0,0 +1,86 @@
+package network
+
+import (
+	"strings"
+	"testing"
+)
+
+const testNmapXML = `<?xml version="1.0" encoding="UTF-8"?>
+<nmaprun scanner="nmap" args="nmap -sV -O -oX - 192.168.1.0/24">
+<host>
+<status state="up" reason="arp-response"/>
+<address addr="192.168.1.10" addrtype="ipv4"/>
+<address addr="AA:BB:CC:DD:EE:FF" addrtype="mac" vendor="Acme"/>
+<hostnames><hostname name="printer.lan" type="PTR"/></hostnames>
+<ports>
+<port protocol="tcp" portid="80"><state state="open"/><service name="http" product="lighttpd" version="1.4"/></port>
+<port protocol="tcp" portid="443"><state state="closed"/><service name="https"/></port>
+<port protocol="udp" portid="161"><state state="open"/><service name="snmp"/></port>
+</ports>
+<os><osmatch name="Linux 3.X" accuracy="90"/><osmatch name="Linux 4.X" accuracy="95"/></os>
+</host>
+<host>
+<status state="down"/>
+<address addr="192.168.1.11" addrtype="ipv4"/>
+</host>
+</nmaprun>`
+
+func TestParseNmapXML(t *testing.T) {
+	err, hosts := ParseNmapXML(strings.NewReader(testNmapXML))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(hosts) != 1 {
+		t.Fatalf("expected 1 host, got %d", len(hosts))
+	}
+
+	h := hosts[0]
+	if h.IP != "192.168.1.10" || h.MAC != "aa:bb:cc:dd:ee:ff" || h.Hostname != "printer.lan" || h.OS != "Linux 4.X" {
+		t.Fatalf("unexpected host %+v", h)
+	} else if len(h.Ports) != 2 {
+		t.Fatalf("expected 2 open ports, got %d", len(h.Ports))
+	} else if desc := h.Ports[0].Description(); desc != "http (lighttpd 1.4)" {
+		t.Fatalf("unexpected service description '%s'", desc)
+	}
+}
+
+func TestParseHostsCSV(t *testing.T) {
+	withHeader := "hostname,ip,port,service\nweb,10.0.0.1,80,http\nweb,10.0.0.1,22,ssh\n,10.0.0.2,,\n"
+	err, hosts := ParseHostsCSV(strings.NewReader(withHeader))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(hosts) != 2 {
+		t.Fatalf("expected 2 hosts, got %d", len(hosts))
+	} else if hosts[0].Hostname != "web" || len(hosts[0].Ports) != 2 || hosts[0].Ports[1].Proto != "tcp" {
+		t.Fatalf("unexpected host %+v", hosts[0])
+	}
+
+	positional := "10.0.0.3,00:11:22:33:44:55,nas,445,tcp,microsoft-ds\n"
+	if err, hosts = ParseHostsCSV(strings.NewReader(positional)); err != nil {
+		t.Fatal(err)
+	} else if len(hosts) != 1 || hosts[0].MAC != "00:11:22:33:44:55" || hosts[0].Ports[0].Port != 445 {
+		t.Fatalf("unexpected hosts %+v", hosts)
+	}
+
+	if err, _ = ParseHostsCSV(strings.NewReader("ip,port\n10.0.0.1,http\n")); err == nil {
+		t.Fatal("expected error for invalid port")
+	}
+}
+
+func TestImportedHostApply(t *testing.T) {
+	e := NewEndpointNoResolve("10.0.0.1", "00:11:22:33:44:55", "", 24)
+	e.Meta.SetInts("tcp-ports", []int{22})
+
+	h := &ImportedHost{
+		Hostname: "web",
+		Ports:    []ImportedPort{{Proto: "tcp", Port: 80, Service: "http"}},
+	}
+	h.Apply(e, "scan.xml")
+
+	if e.Hostname != "web" || e.Meta.Get("imported") != "scan.xml" {
+		t.Fatalf("unexpected endpoint %+v", e)
+	} else if ports := e.Meta.Get("tcp-ports"); ports != "22,80" {
+		t.Fatalf("unexpected ports %v", ports)
+	} else if svc := e.Meta.Get("tcp-port:80"); svc != "http" {
+		t.Fatalf("unexpected service %v", svc)
+	}
+}
@@ -3,16 +3,59 @@ import os
 import six
 import re
 import sys
+import csv
 
 base = os.path.dirname(os.path.realpath(__file__))
 # "https://code.wireshark.org/review/gitweb?p=wireshark.git;a=blob_plain;f=manuf;hb=HEAD"
+#
+# Alternatively, the database can be updated offline from the IEEE registries:
+#
+#   python make_manuf.py oui.csv mam.csv oas.csv
+#
+# "http://standards-oui.ieee.org/oui/oui.csv"
+# "http://standards-oui.ieee.org/oui28/mam.csv"
+# "http://standards-oui.ieee.org/oui36/oas.csv"
 
 with open(os.path.join(base, 'manuf.go.template')) as fp:
     template = fp.read()
 
-with open(os.path.join(base, 'manuf')) as fp:
-    lines = [l.strip() for l in fp.readlines()]
-    lines = [l for l in lines if l != "" and l[0] != '#']    
+def load_ieee_csv(filename):
+    # Registry,Assignment,Organization Name,Organization Address
+    entries = []
+    with open(filename) as fp:
+        for row in list(csv.reader(fp))[1:]:
+            if len(row) >= 3 and row[1] != "" and row[2].strip() != "":
+                entries.append(( row[1], row[2].strip() ))
+    return entries
+
+def load_wireshark(filename):
+    entries = []
+    with open(filename) as fp:
+        lines = [l.strip() for l in fp.readlines()]
+        lines = [l for l in lines if l != "" and l[0] != '#']    
+
+    for line in lines:
+        m = re.match( r'^([^\s]+)\s+([^\s]+)(.*)$', line, re.M)
+        parts = m.groups()
+        mac = parts[0]
+        short = parts[1]
+        manuf = parts[2].strip()
+        if manuf == "":
+            manuf  = short
+
+        m = re.match( r'^([^#]+)#.+$', manuf)
+        if m is not None:
+            manuf = m.groups()[0].strip()
+
+        entries.append(( mac, manuf ))
+    return entries
+
+if len(sys.argv) > 1:
+    entries = []
+    for filename in sys.argv[1:]:
+        entries += load_ieee_csv(filename)
+else:
+    entries = load_wireshark(os.path.join(base, 'manuf'))
 
 def get_mac_and_mask(mac):
     # simple case 
@@ -32,19 +75,7 @@ def get_mac_and_mask(mac):
 
 index = {}
 
-for line in lines:
-    m = re.match( r'^([^\s]+)\s+([^\s]+)(.*)$', line, re.M)
-    parts = m.groups()
-    mac = parts[0]
-    short = parts[1]
-    manuf = parts[2].strip()
-    if manuf == "":
-        manuf  = short
-
-    m = re.match( r'^([^#]+)#.+$', manuf)
-    if m is not None:
-        manuf = m.groups()[0].strip()
-
+for mac, manuf in entries:
     mac_int, mask = get_mac_and_mask(mac)
 
     key = "%d.%d" % ( mask, mac_int >> mask )
@@ -35148,6 +35148,10 @@ func ManufLookup(mac string) string {
 		return ""
 	}
 
+	if vendor := manufCustomLookup(macInt); vendor != "" {
+		return vendor
+	}
+
 	for mask := uint(0); mask < 48; mask++ {
 		shifted := new(big.Int).Rsh(macInt, mask)
 		key := fmt.Sprintf("%d.%s", mask, shifted)
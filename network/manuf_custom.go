@@ -0,0 +1,213 @@
+package network
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	manufLock = &sync.RWMutex{}
+	// vendors loaded from user files, they have precedence over the embedded database
+	manufUser = make(map[string]string)
+	// vendors explicitly assigned to a prefix, they have precedence over everything else
+	manufOverrides = make(map[string]string)
+)
+
+// ManufKey returns the lookup key of a MAC prefix in the XX:XX:XX, XXXXXX or
+// XX:XX:XX:XX:XX:XX/BITS forms, the same format used by the wireshark manuf file.
+func ManufKey(prefix string) (error, string) {
+	bits := -1
+	if parts := strings.SplitN(prefix, "/", 2); len(parts) == 2 {
+		var err error
+		if bits, err = strconv.Atoi(parts[1]); err != nil || bits <= 0 || bits > 48 {
+			return fmt.Errorf("invalid mask in prefix '%s'", prefix), ""
+		}
+		prefix = parts[0]
+	}
+
+	hex := strings.NewReplacer(":", "", "-", "", ".", "").Replace(strings.TrimSpace(prefix))
+	if hex == "" || len(hex) > 12 {
+		return fmt.Errorf("invalid prefix '%s'", prefix), ""
+	}
+
+	value, err := strconv.ParseUint(hex, 16, 64)
+	if err != nil {
+		return fmt.Errorf("invalid prefix '%s'", prefix), ""
+	}
+
+	// number of bits of the address that are not part of the prefix
+	mask := uint(48 - 4*len(hex))
+	value <<= mask
+	if bits != -1 {
+		mask = uint(48 - bits)
+	}
+
+	return nil, fmt.Sprintf("%d.%d", mask, value>>mask)
+}
+
+func manufCustomLookup(mac *big.Int) string {
+	manufLock.RLock()
+	defer manufLock.RUnlock()
+
+	for _, db := range []map[string]string{manufOverrides, manufUser} {
+		if len(db) == 0 {
+			continue
+		}
+		for mask := uint(0); mask < 48; mask++ {
+			key := fmt.Sprintf("%d.%s", mask, new(big.Int).Rsh(mac, mask))
+			if vendor, found := db[key]; found {
+				return vendor
+			}
+		}
+	}
+
+	return ""
+}
+
+// ManufOverride assigns a vendor name to a MAC prefix, an empty name removes the override.
+func ManufOverride(prefix, vendor string) error {
+	err, key := ManufKey(prefix)
+	if err != nil {
+		return err
+	}
+
+	manufLock.Lock()
+	defer manufLock.Unlock()
+
+	if vendor == "" {
+		delete(manufOverrides, key)
+	} else {
+		manufOverrides[key] = vendor
+	}
+	return nil
+}
+
+// ManufOverrides returns the vendor overrides indexed by lookup key.
+func ManufOverrides() map[string]string {
+	manufLock.RLock()
+	defer manufLock.RUnlock()
+
+	overrides := make(map[string]string)
+	for key, vendor := range manufOverrides {
+		overrides[key] = vendor
+	}
+	return overrides
+}
+
+func parseManufLine(line string) (prefix, vendor string) {
+	if idx := strings.Index(line, "#"); idx != -1 {
+		line = line[:idx]
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", ""
+	}
+
+	// wireshark format: PREFIX<tab>SHORT[<tab>LONG]
+	if strings.Contains(line, "\t") {
+		fields := make([]string, 0)
+		for _, f := range strings.Split(line, "\t") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, f)
+			}
+		}
+		if len(fields) < 2 {
+			return "", ""
+		}
+		return fields[0], fields[len(fields)-1]
+	}
+
+	// simple format: PREFIX VENDOR NAME
+	parts := strings.SplitN(line, " ", 2)
+	if len(parts) < 2 {
+		return "", ""
+	}
+	return parts[0], strings.TrimSpace(parts[1])
+}
+
+func loadManufText(r io.Reader, db map[string]string) (error, int) {
+	loaded := 0
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		prefix, vendor := parseManufLine(scanner.Text())
+		if prefix == "" {
+			continue
+		}
+
+		err, key := ManufKey(prefix)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", lineno, err), loaded
+		}
+		db[key] = vendor
+		loaded++
+	}
+	return scanner.Err(), loaded
+}
+
+// IEEE registries (oui.csv, mam.csv, oas.csv) use the Registry,Assignment,Organization Name,Organization Address format.
+func loadManufCSV(r io.Reader, db map[string]string) (error, int) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return err, 0
+	}
+
+	loaded := 0
+	for i, record := range records {
+		if i == 0 || len(record) < 3 {
+			continue
+		}
+
+		err, key := ManufKey(record[1])
+		if err != nil {
+			return fmt.Errorf("line %d: %s", i+1, err), loaded
+		}
+		db[key] = strings.TrimSpace(record[2])
+		loaded++
+	}
+	return nil, loaded
+}
+
+// ManufLoad loads additional vendors from a file either in the wireshark manuf
+// format (one PREFIX VENDOR per line) or in the IEEE registry CSV format,
+// returning the number of prefixes loaded.
+func ManufLoad(fileName string) (error, int) {
+	fp, err := os.Open(fileName)
+	if err != nil {
+		return err, 0
+	}
+	defer fp.Close()
+
+	reader := bufio.NewReader(fp)
+	header, _ := reader.Peek(len("Registry,Assignment"))
+
+	db := make(map[string]string)
+	var loaded int
+	if string(header) == "Registry,Assignment" {
+		err, loaded = loadManufCSV(reader, db)
+	} else {
+		err, loaded = loadManufText(reader, db)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error while loading %s: %s", fileName, err), 0
+	}
+
+	manufLock.Lock()
+	defer manufLock.Unlock()
+
+	for key, vendor := range db {
+		manufUser[key] = vendor
+	}
+
+	return nil, loaded
+}
@@ -0,0 +1,84 @@
+package network
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestManufKey(t *testing.T) {
+	cases := map[string]string{
+		"00:00:01":             "24.1",
+		"000001":               "24.1",
+		"00-00-01":             "24.1",
+		"00:1B:C5:00:10:00/36": "12.29118465",
+		"02:00:00:00:00:00/8":  "40.2",
+	}
+	for prefix, expected := range cases {
+		if err, key := ManufKey(prefix); err != nil {
+			t.Fatalf("unexpected error for %s: %s", prefix, err)
+		} else if key != expected {
+			t.Fatalf("expected key %s for %s, got %s", expected, prefix, key)
+		}
+	}
+
+	for _, prefix := range []string{"", "zz:zz:zz", "00:11:22/64", "00:11:22:33:44:55:66"} {
+		if err, _ := ManufKey(prefix); err == nil {
+			t.Fatalf("expected error for '%s'", prefix)
+		}
+	}
+}
+
+func TestManufOverride(t *testing.T) {
+	mac := "aa:bb:cc:00:11:22"
+	if err := ManufOverride("aa:bb:cc", "Test Vendor"); err != nil {
+		t.Fatal(err)
+	}
+	if vendor := ManufLookup(mac); vendor != "Test Vendor" {
+		t.Fatalf("expected override, got '%s'", vendor)
+	}
+
+	if err := ManufOverride("aa:bb:cc", ""); err != nil {
+		t.Fatal(err)
+	}
+	if vendor := ManufLookup(mac); vendor == "Test Vendor" {
+		t.Fatalf("override not removed")
+	}
+}
+
+func TestManufLoad(t *testing.T) {
+	files := map[string]string{
+		"text": "# comment\n" +
+			"ab:cd:ef\tShort\tLong Vendor Name # trailing comment\n" +
+			"ab:cd:ee Simple Vendor\n",
+		"csv": "Registry,Assignment,Organization Name,Organization Address\n" +
+			"MA-L,ABCDEF,Long Vendor Name,Somewhere\n" +
+			"MA-L,ABCDEE,\"Simple Vendor, Inc.\",Somewhere\n",
+	}
+	expected := map[string]string{
+		"text": "Simple Vendor",
+		"csv":  "Simple Vendor, Inc.",
+	}
+
+	for format, data := range files {
+		fp, err := ioutil.TempFile("", "manuf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fp.WriteString(data)
+		fp.Close()
+		defer os.Remove(fp.Name())
+
+		if err, loaded := ManufLoad(fp.Name()); err != nil {
+			t.Fatalf("%s: %s", format, err)
+		} else if loaded != 2 {
+			t.Fatalf("%s: expected 2 prefixes, got %d", format, loaded)
+		}
+
+		if vendor := ManufLookup("ab:cd:ef:01:02:03"); vendor != "Long Vendor Name" {
+			t.Fatalf("%s: unexpected vendor '%s'", format, vendor)
+		} else if vendor := ManufLookup("ab:cd:ee:01:02:03"); vendor != expected[format] {
+			t.Fatalf("%s: unexpected vendor '%s'", format, vendor)
+		}
+	}
+}
@@ -224,8 +224,9 @@ func matchByAddress(iface net.Interface, name string) bool {
 
 func findInterfaceByName(name string, ifaces []net.Interface) (*Endpoint, error) {
 	for _, iface := range ifaces {
+		// on Windows iface.Name is the friendly name of the adapter
 		ifName := getInterfaceName(iface)
-		if ifName == name || matchByAddress(iface, name) {
+		if ifName == name || strings.EqualFold(iface.Name, name) || matchByAddress(iface, name) {
 			return buildEndpointFromInterface(iface)
 		}
 	}
@@ -52,6 +52,11 @@ func SetInterfaceChannel(iface string, channel int) error {
 	return nil
 }
 
+// SetInterfaceManaged is a no-op on macOS, the monitor mode is released with the pcap handle.
+func SetInterfaceManaged(iface string) error {
+	return nil
+}
+
 func getFrequenciesFromChannels(output string) ([]int, error) {
 	freqs := make([]int, 0)
 	if output != "" {
@@ -73,3 +78,7 @@ func GetSupportedFrequencies(iface string) ([]int, error) {
 	}
 	return getFrequenciesFromChannels(out)
 }
+
+func GetWiFiCapabilities(iface string) (*WiFiCapabilities, error) {
+	return nil, fmt.Errorf("macOS does not support WiFi capabilities reporting.")
+}
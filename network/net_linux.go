@@ -3,7 +3,10 @@ package network
 import (
 	"bufio"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -17,6 +20,8 @@ var IPv4RouteTokens = 4
 var IPv4RouteCmd = "ip"
 var IPv4RouteCmdOpts = []string{"route"}
 var WiFiFreqParser = regexp.MustCompile(`^\s+Channel.([0-9]+)\s+:\s+([0-9\.]+)\s+GHz.*$`)
+var WiFiPhyBandParser = regexp.MustCompile(`^Band\s+[0-9]+:$`)
+var WiFiPhyFreqParser = regexp.MustCompile(`^\*\s+([0-9]+)(?:\.[0-9]+)?\s+MHz\s+\[([0-9]+)\](.*)$`)
 
 func IPv4RouteIsGateway(ifname string, tokens []string, f func(gateway string) (*Endpoint, error)) (*Endpoint, error) {
 	ifname2 := tokens[3]
@@ -52,6 +57,24 @@ func SetInterfaceChannel(iface string, channel int) error {
 	return nil
 }
 
+// SetInterfaceManaged puts back in managed mode an interface left in monitor mode.
+func SetInterfaceManaged(iface string) error {
+	if !core.HasBinary("iw") {
+		return fmt.Errorf("iw not found, can't set interface %s in managed mode", iface)
+	}
+
+	// the type can't be changed while the interface is up
+	if _, err := core.Exec("ifconfig", []string{iface, "down"}); err != nil {
+		return err
+	} else if out, err := core.Exec("iw", []string{"dev", iface, "set", "type", "managed"}); err != nil {
+		return err
+	} else if out != "" {
+		return fmt.Errorf("unexpected output while setting interface %s in managed mode: %s", iface, out)
+	}
+
+	return ActivateInterface(iface)
+}
+
 func processSupportedFrequencies(output string, err error) ([]int, error) {
 	freqs := make([]int, 0)
 	if err != nil {
@@ -75,3 +98,85 @@ func GetSupportedFrequencies(iface string) ([]int, error) {
 	out, err := core.Exec("iwlist", []string{iface, "freq"})
 	return processSupportedFrequencies(out, err)
 }
+
+func wifiBandName(freq int) string {
+	if freq < 3000 {
+		return "2.4GHz"
+	} else if freq < 5925 {
+		return "5GHz"
+	}
+	return "6GHz"
+}
+
+func processWiFiCapabilities(output string, err error) (*WiFiCapabilities, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	caps := &WiFiCapabilities{
+		Modes: make([]string, 0),
+		Bands: make([]*WiFiBand, 0),
+	}
+
+	var band *WiFiBand
+	section := ""
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if WiFiPhyBandParser.MatchString(line) {
+			band = &WiFiBand{Channels: make([]int, 0)}
+			caps.Bands = append(caps.Bands, band)
+			section = ""
+		} else if strings.HasPrefix(line, "* ") {
+			if section == "modes" {
+				caps.Modes = append(caps.Modes, strings.TrimSpace(line[2:]))
+			} else if section == "freqs" && band != nil {
+				matches := WiFiPhyFreqParser.FindStringSubmatch(line)
+				if len(matches) == 4 && !strings.Contains(matches[3], "disabled") {
+					freq, _ := strconv.Atoi(matches[1])
+					channel, _ := strconv.Atoi(matches[2])
+					if band.Name == "" {
+						band.Name = wifiBandName(freq)
+					}
+					band.Channels = append(band.Channels, channel)
+				}
+			}
+		} else {
+			section = ""
+			if line == "Supported interface modes:" {
+				section = "modes"
+			} else if line == "Frequencies:" {
+				section = "freqs"
+			} else if band != nil && strings.HasPrefix(line, "Capabilities: 0x") {
+				band.HT = true
+			} else if band != nil && strings.HasPrefix(line, "VHT Capabilities") {
+				band.VHT = true
+			}
+		}
+	}
+
+	return caps, nil
+}
+
+func GetWiFiCapabilities(iface string) (*WiFiCapabilities, error) {
+	sysPath := filepath.Join("/sys/class/net", iface)
+
+	raw, err := ioutil.ReadFile(filepath.Join(sysPath, "phy80211", "name"))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a wireless interface", iface)
+	}
+	phy := strings.TrimSpace(string(raw))
+
+	out, err := core.Exec("iw", []string{"phy", phy, "info"})
+	caps, err := processWiFiCapabilities(out, err)
+	if err != nil {
+		return nil, err
+	}
+
+	caps.Phy = phy
+	if driver, err := os.Readlink(filepath.Join(sysPath, "device", "driver")); err == nil {
+		caps.Driver = filepath.Base(driver)
+	}
+
+	return caps, nil
+}
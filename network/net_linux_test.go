@@ -57,3 +57,55 @@ func TestProcessSupportedFrequencies(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessWiFiCapabilities(t *testing.T) {
+	output := `Wiphy phy0
+	max # scan SSIDs: 4
+	Supported interface modes:
+		 * IBSS
+		 * managed
+		 * AP
+		 * monitor
+	Band 1:
+		Capabilities: 0x11ef
+			RX LDPC
+			HT20/HT40
+		Frequencies:
+			* 2412 MHz [1] (20.0 dBm)
+			* 2417 MHz [2] (20.0 dBm)
+			* 2467 MHz [12] (disabled)
+	Band 2:
+		Capabilities: 0x11ef
+			HT20/HT40
+		VHT Capabilities (0x038071a0):
+			Max MPDU length: 3895
+		Frequencies:
+			* 5180 MHz [36] (20.0 dBm)
+			* 5200 MHz [40] (20.0 dBm)
+	Supported commands:
+		 * new_interface
+`
+	caps, err := processWiFiCapabilities(output, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(caps.Modes, []string{"IBSS", "managed", "AP", "monitor"}) {
+		t.Errorf("unexpected modes %v", caps.Modes)
+	} else if !caps.HasMode("monitor") || caps.HasMode("mesh point") {
+		t.Error("unexpected HasMode result")
+	} else if len(caps.Bands) != 2 {
+		t.Fatalf("expected 2 bands, got %d", len(caps.Bands))
+	}
+
+	if b := caps.Bands[0]; b.Name != "2.4GHz" || !b.HT || b.VHT || !reflect.DeepEqual(b.Channels, []int{1, 2}) {
+		t.Errorf("unexpected first band %+v", b)
+	}
+	if b := caps.Bands[1]; b.Name != "5GHz" || !b.HT || !b.VHT || !reflect.DeepEqual(b.Channels, []int{36, 40}) {
+		t.Errorf("unexpected second band %+v", b)
+	}
+
+	if _, err := processWiFiCapabilities("", errors.New("iw must have failed")); err == nil {
+		t.Error("expected error, but got none")
+	}
+}
@@ -25,3 +25,28 @@ func SetInterfaceCurrentChannel(iface string, channel int) {
 	defer currChannelLock.Unlock()
 	currChannels[iface] = channel
 }
+
+// WiFiBand holds the capabilities of a wireless interface on a given band.
+type WiFiBand struct {
+	Name     string `json:"name"`
+	HT       bool   `json:"ht"`
+	VHT      bool   `json:"vht"`
+	Channels []int  `json:"channels"`
+}
+
+// WiFiCapabilities holds the information reported by the driver of a wireless interface.
+type WiFiCapabilities struct {
+	Phy    string      `json:"phy"`
+	Driver string      `json:"driver"`
+	Modes  []string    `json:"modes"`
+	Bands  []*WiFiBand `json:"bands"`
+}
+
+func (c *WiFiCapabilities) HasMode(mode string) bool {
+	for _, m := range c.Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
@@ -1,10 +1,13 @@
 package network
 
 import (
+	"bytes"
 	"fmt"
 	"net"
 	"regexp"
 	"strings"
+	"syscall"
+	"unsafe"
 
 	"github.com/google/gopacket/pcap"
 )
@@ -23,20 +26,45 @@ func IPv4RouteIsGateway(ifname string, tokens []string, f func(gateway string) (
 }
 
 /*
- * net.Interface does not have the correct name on Windows and pcap.Interface
- * does not have the hardware address for some reason ... so this is what I
- * had to do in Windows ... tnx Microsoft <3
- *
- * FIXME: Just to be clear *THIS IS SHIT*. Please someone test, find a more
- * elegant solution and refactor ... i'm seriously tired of this.
+ * net.Interface does not have the Npcap device name on Windows and pcap.Interface
+ * does not have the hardware address, so we map the index of the interface to the
+ * GUID of its adapter via GetAdaptersInfo, Npcap devices are named after it. The
+ * friendly name ("Ethernet", "Wi-Fi", ...) is the one of net.Interface and can be
+ * used with -iface as well.
  */
 
+const npcapDevicePrefix = "\\Device\\NPF_"
+
+func adapterNames() map[int]string {
+	names := make(map[int]string)
+	size := uint32(15000)
+	for i := 0; i < 3; i++ {
+		buf := make([]byte, size)
+		info := (*syscall.IpAdapterInfo)(unsafe.Pointer(&buf[0]))
+		if err := syscall.GetAdaptersInfo(info, &size); err == syscall.ERROR_BUFFER_OVERFLOW {
+			continue
+		} else if err != nil {
+			return names
+		}
+
+		for ; info != nil; info = info.Next {
+			name := string(bytes.TrimRight(info.AdapterName[:], "\x00"))
+			names[int(info.Index)] = npcapDevicePrefix + name
+		}
+		break
+	}
+	return names
+}
+
+// areTheSame is the fallback for adapters GetAdaptersInfo does not report.
 func areTheSame(iface net.Interface, piface pcap.Interface) bool {
 	if addrs, err := iface.Addrs(); err == nil {
 		for _, ia := range addrs {
-			for _, ib := range piface.Addresses {
-				if ia.String() == ib.IP.String() || strings.HasPrefix(ia.String(), ib.IP.String()) {
-					return true
+			if ip, _, err := net.ParseCIDR(ia.String()); err == nil {
+				for _, ib := range piface.Addresses {
+					if ip.Equal(ib.IP) {
+						return true
+					}
 				}
 			}
 		}
@@ -50,6 +78,14 @@ func getInterfaceName(iface net.Interface) string {
 		return iface.Name
 	}
 
+	if name, found := adapterNames()[iface.Index]; found {
+		for _, dev := range devs {
+			if strings.EqualFold(dev.Name, name) {
+				return dev.Name
+			}
+		}
+	}
+
 	for _, dev := range devs {
 		if areTheSame(iface, dev) {
 			return dev.Name
@@ -63,7 +99,15 @@ func SetInterfaceChannel(iface string, channel int) error {
 	return fmt.Errorf("Windows does not support WiFi channel hopping.")
 }
 
+func SetInterfaceManaged(iface string) error {
+	return fmt.Errorf("Windows does not support WiFi monitor mode.")
+}
+
 func GetSupportedFrequencies(iface string) ([]int, error) {
 	freqs := make([]int, 0)
 	return freqs, fmt.Errorf("Windows does not support WiFi channel hopping.")
 }
+
+func GetWiFiCapabilities(iface string) (*WiFiCapabilities, error) {
+	return nil, fmt.Errorf("Windows does not support WiFi capabilities reporting.")
+}
@@ -0,0 +1,228 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// RDNSDisabled as the server disables the reverse lookups
+	RDNSDisabled = "off"
+
+	rdnsTimeout   = 2 * time.Second
+	rdnsWorkers   = 4
+	rdnsQueueSize = 1024
+	rdnsCacheSize = 65536
+)
+
+type rdnsEntry struct {
+	name    string
+	expires time.Time
+}
+
+// ReverseResolver looks up the names of the endpoints in the background with
+// a bounded number of workers, at a limited rate and caching both the names
+// and the failures, so that recon never waits for it nor floods the resolver.
+type ReverseResolver struct {
+	sync.Mutex
+	disabled bool
+	lookup   func(ctx context.Context, address string) ([]string, error)
+	ttl      time.Duration
+	negative time.Duration
+	interval time.Duration
+	last     time.Time
+	cache    map[string]rdnsEntry
+	pending  map[string][]func(name string)
+	queue    chan string
+	started  sync.Once
+}
+
+// ReverseDNS is the resolver used for the names of the new endpoints.
+var ReverseDNS = NewReverseResolver()
+
+func NewReverseResolver() *ReverseResolver {
+	return &ReverseResolver{
+		lookup:   net.DefaultResolver.LookupAddr,
+		ttl:      time.Hour,
+		negative: 5 * time.Minute,
+		cache:    make(map[string]rdnsEntry),
+		pending:  make(map[string][]func(name string)),
+		queue:    make(chan string, rdnsQueueSize),
+	}
+}
+
+// SetServer makes the lookups go to the DNS server at the given address, with
+// port 53 if not specified, to the system resolver if empty or disables them
+// if RDNSDisabled.
+func (r *ReverseResolver) SetServer(server string) error {
+	lookup := net.DefaultResolver.LookupAddr
+	if server != "" && server != RDNSDisabled {
+		host, port, err := net.SplitHostPort(server)
+		if err != nil {
+			host, port = server, "53"
+		}
+
+		// a hostname would have to be resolved by the system resolver
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("'%s' is not an IP address", host)
+		}
+
+		address := net.JoinHostPort(host, port)
+		resolver := &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				d := net.Dialer{}
+				return d.DialContext(ctx, network, address)
+			},
+		}
+		lookup = resolver.LookupAddr
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.disabled = server == RDNSDisabled
+	r.lookup = lookup
+	// the failures of the previous server don't apply to this one
+	r.cache = make(map[string]rdnsEntry)
+	return nil
+}
+
+// SetTTL sets for how long the names are cached, zero disables the caching.
+func (r *ReverseResolver) SetTTL(ttl time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	r.ttl = ttl
+}
+
+// SetNegativeTTL sets for how long the addresses that couldn't be resolved
+// are not looked up again, zero disables the negative caching.
+func (r *ReverseResolver) SetNegativeTTL(ttl time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	r.negative = ttl
+}
+
+// SetRate limits the lookups per second, zero means no limit.
+func (r *ReverseResolver) SetRate(perSecond int) {
+	r.Lock()
+	defer r.Unlock()
+	if perSecond > 0 {
+		r.interval = time.Second / time.Duration(perSecond)
+	} else {
+		r.interval = 0
+	}
+}
+
+// Lookup calls cb with the name of address once and if it's resolved, right
+// away if it's cached. It never blocks, if too many lookups are queued the
+// address is skipped and will be tried again the next time it's seen.
+func (r *ReverseResolver) Lookup(address string, cb func(name string)) {
+	r.Lock()
+	if r.disabled {
+		r.Unlock()
+		return
+	} else if e, found := r.cache[address]; found && time.Now().Before(e.expires) {
+		r.Unlock()
+		if e.name != "" {
+			cb(e.name)
+		}
+		return
+	} else if cbs, found := r.pending[address]; found {
+		r.pending[address] = append(cbs, cb)
+		r.Unlock()
+		return
+	}
+	r.pending[address] = []func(name string){cb}
+	r.Unlock()
+
+	r.started.Do(func() {
+		for i := 0; i < rdnsWorkers; i++ {
+			go r.worker()
+		}
+	})
+
+	select {
+	case r.queue <- address:
+	default:
+		r.Lock()
+		delete(r.pending, address)
+		r.Unlock()
+	}
+}
+
+// wait reserves the next slot allowed by the rate limit and waits for it.
+func (r *ReverseResolver) wait() {
+	r.Lock()
+	now := time.Now()
+	next := r.last.Add(r.interval)
+	if next.Before(now) {
+		next = now
+	}
+	r.last = next
+	r.Unlock()
+
+	time.Sleep(time.Until(next))
+}
+
+func (r *ReverseResolver) resolve(address string) string {
+	r.Lock()
+	lookup, disabled := r.lookup, r.disabled
+	r.Unlock()
+
+	if disabled {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rdnsTimeout)
+	defer cancel()
+
+	if names, err := lookup(ctx, address); err == nil && len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+func (r *ReverseResolver) store(address string, name string) []func(name string) {
+	r.Lock()
+	defer r.Unlock()
+
+	ttl := r.ttl
+	if name == "" {
+		ttl = r.negative
+	}
+
+	if ttl > 0 {
+		if len(r.cache) >= rdnsCacheSize {
+			now := time.Now()
+			for addr, e := range r.cache {
+				if now.After(e.expires) {
+					delete(r.cache, addr)
+				}
+			}
+			if len(r.cache) >= rdnsCacheSize {
+				r.cache = make(map[string]rdnsEntry)
+			}
+		}
+		r.cache[address] = rdnsEntry{name: name, expires: time.Now().Add(ttl)}
+	}
+
+	cbs := r.pending[address]
+	delete(r.pending, address)
+	return cbs
+}
+
+func (r *ReverseResolver) worker() {
+	for address := range r.queue {
+		r.wait()
+		name := r.resolve(address)
+		if cbs := r.store(address, name); name != "" {
+			for _, cb := range cbs {
+				cb(name)
+			}
+		}
+	}
+}
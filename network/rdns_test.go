@@ -0,0 +1,127 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testReverseResolver(names map[string]string, lookups *int32) *ReverseResolver {
+	r := NewReverseResolver()
+	r.lookup = func(ctx context.Context, address string) ([]string, error) {
+		atomic.AddInt32(lookups, 1)
+		if name, found := names[address]; found {
+			return []string{name}, nil
+		}
+		return nil, errors.New("not found")
+	}
+	return r
+}
+
+// lookupSync waits for the callback of a lookup, returns an empty name if it
+// isn't called within the timeout.
+func lookupSync(r *ReverseResolver, address string) string {
+	done := make(chan string, 1)
+	r.Lookup(address, func(name string) {
+		done <- name
+	})
+
+	select {
+	case name := <-done:
+		return name
+	case <-time.After(200 * time.Millisecond):
+		return ""
+	}
+}
+
+func TestReverseResolverCache(t *testing.T) {
+	lookups := int32(0)
+	r := testReverseResolver(map[string]string{"192.168.1.1": "router.lan."}, &lookups)
+
+	if name := lookupSync(r, "192.168.1.1"); name != "router.lan." {
+		t.Fatalf("expected router.lan., got '%s'", name)
+	} else if name = lookupSync(r, "192.168.1.1"); name != "router.lan." {
+		t.Fatalf("expected the cached name, got '%s'", name)
+	} else if n := atomic.LoadInt32(&lookups); n != 1 {
+		t.Fatalf("expected 1 lookup, got %d", n)
+	}
+
+	// failures are cached too
+	lookupSync(r, "192.168.1.2")
+	lookupSync(r, "192.168.1.2")
+	if n := atomic.LoadInt32(&lookups); n != 2 {
+		t.Fatalf("expected 2 lookups, got %d", n)
+	}
+
+	r.SetNegativeTTL(0)
+	r.cache = make(map[string]rdnsEntry)
+	lookupSync(r, "192.168.1.2")
+	lookupSync(r, "192.168.1.2")
+	if n := atomic.LoadInt32(&lookups); n != 4 {
+		t.Fatalf("expected 4 lookups without negative caching, got %d", n)
+	}
+}
+
+func TestReverseResolverPending(t *testing.T) {
+	lookups := int32(0)
+	r := testReverseResolver(map[string]string{"192.168.1.1": "router.lan."}, &lookups)
+	lookup := r.lookup
+	r.lookup = func(ctx context.Context, address string) ([]string, error) {
+		time.Sleep(50 * time.Millisecond)
+		return lookup(ctx, address)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		r.Lookup("192.168.1.1", func(name string) {
+			wg.Done()
+		})
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&lookups); n != 1 {
+		t.Fatalf("expected concurrent requests for the same address to share 1 lookup, got %d", n)
+	}
+}
+
+func TestReverseResolverServer(t *testing.T) {
+	lookups := int32(0)
+	r := testReverseResolver(map[string]string{"192.168.1.1": "router.lan."}, &lookups)
+	lookup := r.lookup
+
+	if err := r.SetServer(RDNSDisabled); err != nil {
+		t.Fatal(err)
+	}
+	// SetServer replaces the lookup function with the one of the server
+	r.lookup = lookup
+	if name := lookupSync(r, "192.168.1.1"); name != "" || atomic.LoadInt32(&lookups) != 0 {
+		t.Fatalf("expected no lookups while disabled, got '%s'", name)
+	}
+
+	if err := r.SetServer("dns.google"); err == nil {
+		t.Fatal("expected a hostname to be refused")
+	} else if err = r.SetServer("9.9.9.9"); err != nil {
+		t.Fatal(err)
+	} else if err = r.SetServer("[2620:fe::fe]:5353"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReverseResolverRate(t *testing.T) {
+	lookups := int32(0)
+	r := testReverseResolver(map[string]string{}, &lookups)
+	r.SetRate(20)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		r.wait()
+	}
+	// the first slot is immediate, the other two are 50ms apart
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("expected the lookups to be rate limited, took %s", elapsed)
+	}
+}
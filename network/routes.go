@@ -0,0 +1,41 @@
+package network
+
+import (
+	"bytes"
+	"net"
+	"sort"
+)
+
+// Route is an entry of the routing table of the operating system.
+type Route struct {
+	Destination string `json:"destination"`
+	Gateway     string `json:"gateway"`
+	Interface   string `json:"interface"`
+	Metric      int    `json:"metric"`
+	Flags       string `json:"flags"`
+}
+
+// IsDefault returns true if this is a default route.
+func (r Route) IsDefault() bool {
+	return r.Destination == "default" || r.Destination == "0.0.0.0/0" || r.Destination == "::/0"
+}
+
+// Routes returns the IPv4 and IPv6 routing tables of the operating system.
+func Routes() ([]Route, error) {
+	routes, err := loadRoutes()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		if a, b := routes[i].IsDefault(), routes[j].IsDefault(); a != b {
+			return a
+		}
+
+		ipA, _, _ := net.ParseCIDR(routes[i].Destination)
+		ipB, _, _ := net.ParseCIDR(routes[j].Destination)
+		return bytes.Compare(ipA, ipB) < 0
+	})
+
+	return routes, nil
+}
@@ -0,0 +1,39 @@
+package network
+
+import (
+	"strings"
+
+	"github.com/bettercap/bettercap/core"
+)
+
+// parseNetstatRoutes parses the output of netstat -rn, the columns are
+// Destination, Gateway, Flags and Netif followed by optional ones.
+func parseNetstatRoutes(output string) []Route {
+	routes := make([]Route, 0)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] == "Destination" || strings.HasSuffix(fields[0], ":") {
+			continue
+		}
+
+		route := Route{
+			Destination: fields[0],
+			Flags:       fields[2],
+			Interface:   fields[3],
+		}
+		if strings.Contains(route.Flags, "G") {
+			route.Gateway = fields[1]
+		}
+
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+func loadRoutes() ([]Route, error) {
+	output, err := core.Exec("netstat", []string{"-r", "-n"})
+	if err != nil {
+		return nil, err
+	}
+	return parseNetstatRoutes(output), nil
+}
@@ -0,0 +1,120 @@
+package network
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	procRouteIPv4 = "/proc/net/route"
+	procRouteIPv6 = "/proc/net/ipv6_route"
+
+	rtfUp      = 0x0001
+	rtfGateway = 0x0002
+	rtfHost    = 0x0004
+)
+
+func routeFlags(flags uint64) string {
+	s := ""
+	if flags&rtfUp != 0 {
+		s += "U"
+	}
+	if flags&rtfGateway != 0 {
+		s += "G"
+	}
+	if flags&rtfHost != 0 {
+		s += "H"
+	}
+	return s
+}
+
+// parseProcIPv4 parses /proc/net/route, addresses are in host (little endian) byte order.
+func parseProcIPv4(data string) []Route {
+	routes := make([]Route, 0)
+	toIP := func(s string) net.IP {
+		n, _ := strconv.ParseUint(s, 16, 32)
+		ip := make(net.IP, 4)
+		binary.LittleEndian.PutUint32(ip, uint32(n))
+		return ip
+	}
+
+	for i, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		// skip the header
+		if i == 0 || len(fields) < 8 {
+			continue
+		}
+
+		flags, _ := strconv.ParseUint(fields[3], 16, 16)
+		metric, _ := strconv.Atoi(fields[6])
+		bits, _ := net.IPMask(toIP(fields[7])).Size()
+
+		route := Route{
+			Destination: fmt.Sprintf("%s/%d", toIP(fields[1]), bits),
+			Interface:   fields[0],
+			Metric:      metric,
+			Flags:       routeFlags(flags),
+		}
+		if flags&rtfGateway != 0 {
+			route.Gateway = toIP(fields[2]).String()
+		}
+
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+// parseProcIPv6 parses /proc/net/ipv6_route, skipping the loopback and local routes.
+func parseProcIPv6(data string) []Route {
+	routes := make([]Route, 0)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 10 || fields[9] == "lo" {
+			continue
+		}
+
+		dst, err := hex.DecodeString(fields[0])
+		if err != nil || len(dst) != 16 {
+			continue
+		}
+		bits, _ := strconv.ParseUint(fields[1], 16, 8)
+		gw, _ := hex.DecodeString(fields[4])
+		metric, _ := strconv.ParseUint(fields[5], 16, 32)
+		flags, _ := strconv.ParseUint(fields[8], 16, 32)
+
+		route := Route{
+			Destination: fmt.Sprintf("%s/%d", net.IP(dst), bits),
+			Interface:   fields[9],
+			Metric:      int(metric),
+			Flags:       routeFlags(flags),
+		}
+		if len(gw) == 16 && !net.IP(gw).IsUnspecified() {
+			route.Gateway = net.IP(gw).String()
+		}
+
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+func loadRoutes() ([]Route, error) {
+	raw, err := ioutil.ReadFile(procRouteIPv4)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := parseProcIPv4(string(raw))
+	// IPv6 might be disabled
+	if raw, err = ioutil.ReadFile(procRouteIPv6); err == nil {
+		routes = append(routes, parseProcIPv6(string(raw))...)
+	}
+
+	return routes, nil
+}
@@ -0,0 +1,36 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProcIPv4(t *testing.T) {
+	data := "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+		"eth0\t00000000\t010200C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n" +
+		"eth0\t000200C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\t0\t0\t0\n"
+
+	exp := []Route{
+		{Destination: "0.0.0.0/0", Gateway: "192.0.2.1", Interface: "eth0", Metric: 100, Flags: "UG"},
+		{Destination: "192.0.2.0/24", Interface: "eth0", Flags: "U"},
+	}
+
+	if got := parseProcIPv4(data); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expected %+v, got %+v", exp, got)
+	}
+}
+
+func TestParseProcIPv6(t *testing.T) {
+	data := "fd000000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0\n" +
+		"00000000000000000000000000000000 00 00000000000000000000000000000000 00 fd000000000000000000000000000001 00000400 00000001 00000000 00000003     eth0\n" +
+		"00000000000000000000000000000001 80 00000000000000000000000000000000 00 00000000000000000000000000000000 00000000 00000002 00000000 80200001       lo\n"
+
+	exp := []Route{
+		{Destination: "fd00::/64", Interface: "eth0", Metric: 256, Flags: "U"},
+		{Destination: "::/0", Gateway: "fd00::1", Interface: "eth0", Metric: 1024, Flags: "UG"},
+	}
+
+	if got := parseProcIPv6(data); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expected %+v, got %+v", exp, got)
+	}
+}
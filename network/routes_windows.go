@@ -0,0 +1,53 @@
+package network
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/bettercap/bettercap/core"
+)
+
+// parseNetshRoutes parses the output of netsh interface ipvX show route, the columns
+// are Publish, Type, Met, Prefix, Idx and Gateway/Interface Name.
+func parseNetshRoutes(output string) []Route {
+	routes := make([]Route, 0)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 6 || !strings.Contains(fields[3], "/") {
+			continue
+		}
+
+		metric, _ := strconv.Atoi(fields[2])
+		route := Route{
+			Destination: fields[3],
+			Metric:      metric,
+			Flags:       fields[1],
+		}
+
+		target := strings.Join(fields[5:], " ")
+		if ip := net.ParseIP(target); ip != nil {
+			route.Gateway = target
+		} else {
+			route.Interface = target
+		}
+
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+func loadRoutes() ([]Route, error) {
+	routes := make([]Route, 0)
+	for _, proto := range []string{"ipv4", "ipv6"} {
+		output, err := core.Exec("netsh", []string{"interface", proto, "show", "route"})
+		if err != nil {
+			if proto == "ipv4" {
+				return nil, err
+			}
+			continue
+		}
+		routes = append(routes, parseNetshRoutes(output)...)
+	}
+	return routes, nil
+}
@@ -0,0 +1,226 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Scope holds the networks, SSIDs and MAC address ranges of an engagement,
+// an empty list means that dimension is not restricted.
+type Scope struct {
+	sync.RWMutex
+	cidrs []*net.IPNet
+	ssids []string
+	macs  []*macRange
+
+	hasCIDRs bool
+	hasSSIDs bool
+	hasMACs  bool
+}
+
+type macRange struct {
+	prefix []byte
+	bits   int
+}
+
+func (r *macRange) contains(mac net.HardwareAddr) bool {
+	if len(mac) < len(r.prefix) {
+		return false
+	}
+	for i := 0; i < r.bits; i++ {
+		mask := byte(0x80) >> uint(i%8)
+		if mac[i/8]&mask != r.prefix[i/8]&mask {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *macRange) String() string {
+	parts := make([]string, len(r.prefix))
+	for i, b := range r.prefix {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	if r.bits == len(r.prefix)*8 {
+		return strings.Join(parts, ":")
+	}
+	return fmt.Sprintf("%s/%d", strings.Join(parts, ":"), r.bits)
+}
+
+func NewScope() *Scope {
+	return &Scope{}
+}
+
+func scopeEntries(value string) []string {
+	entries := make([]string, 0)
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// parseMACRange parses a full address, a prefix like aa:bb:cc or aa:bb:cc:*
+// and the aa:bb:cc:00:00:00/24 notation.
+func parseMACRange(value string) (*macRange, error) {
+	bits := -1
+	if idx := strings.IndexRune(value, '/'); idx != -1 {
+		n, err := strconv.Atoi(value[idx+1:])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid MAC range %s", value)
+		}
+		bits = n
+		value = value[:idx]
+	}
+
+	value = NormalizeMac(strings.TrimRight(value, ":-*"))
+	prefix := make([]byte, 0)
+	for _, part := range strings.Split(value, ":") {
+		b, err := strconv.ParseUint(part, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAC range %s", value)
+		}
+		prefix = append(prefix, byte(b))
+	}
+
+	if bits == -1 {
+		bits = len(prefix) * 8
+	} else if bits > len(prefix)*8 {
+		return nil, fmt.Errorf("invalid MAC range %s/%d", value, bits)
+	}
+
+	return &macRange{prefix: prefix, bits: bits}, nil
+}
+
+// SetCIDRs parses a comma separated list of networks and addresses, on error
+// the valid entries are kept so that the scope never gets wider than asked.
+func (s *Scope) SetCIDRs(value string) error {
+	var lastErr error
+	cidrs := make([]*net.IPNet, 0)
+	for _, entry := range scopeEntries(value) {
+		if !strings.ContainsRune(entry, '/') {
+			if ip := net.ParseIP(entry); ip == nil {
+				lastErr = fmt.Errorf("invalid address %s", entry)
+				continue
+			} else if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+
+		if _, cidr, err := net.ParseCIDR(entry); err != nil {
+			lastErr = err
+		} else {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.cidrs = cidrs
+	s.hasCIDRs = strings.TrimSpace(value) != ""
+	return lastErr
+}
+
+func (s *Scope) SetSSIDs(value string) {
+	s.Lock()
+	defer s.Unlock()
+	s.ssids = scopeEntries(value)
+	s.hasSSIDs = len(s.ssids) > 0
+}
+
+func (s *Scope) SetMACs(value string) error {
+	var lastErr error
+	macs := make([]*macRange, 0)
+	for _, entry := range scopeEntries(value) {
+		if r, err := parseMACRange(entry); err != nil {
+			lastErr = err
+		} else {
+			macs = append(macs, r)
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.macs = macs
+	s.hasMACs = strings.TrimSpace(value) != ""
+	return lastErr
+}
+
+// Restricted returns true if at least one of the dimensions is restricted.
+func (s *Scope) Restricted() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.hasCIDRs || s.hasSSIDs || s.hasMACs
+}
+
+func (s *Scope) IPAllowed(ip net.IP) bool {
+	s.RLock()
+	defer s.RUnlock()
+	if !s.hasCIDRs {
+		return true
+	}
+	for _, cidr := range s.cidrs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scope) SSIDAllowed(ssid string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	if !s.hasSSIDs {
+		return true
+	}
+	for _, allowed := range s.ssids {
+		if allowed == ssid {
+			return true
+		}
+	}
+	return false
+}
+
+// MACAllowed also accepts the shorter addresses of HID devices.
+func (s *Scope) MACAllowed(address string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	if !s.hasMACs {
+		return true
+	}
+
+	r, err := parseMACRange(address)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range s.macs {
+		if allowed.contains(r.prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scope) String() string {
+	s.RLock()
+	defer s.RUnlock()
+
+	parts := make([]string, 0)
+	for _, cidr := range s.cidrs {
+		parts = append(parts, cidr.String())
+	}
+	for _, ssid := range s.ssids {
+		parts = append(parts, fmt.Sprintf("'%s'", ssid))
+	}
+	for _, r := range s.macs {
+		parts = append(parts, r.String())
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,82 @@
+package network
+
+import (
+	"net"
+	"testing"
+)
+
+func TestScopeUnrestricted(t *testing.T) {
+	s := NewScope()
+	if s.Restricted() {
+		t.Fatal("expected an empty scope to be unrestricted")
+	} else if !s.IPAllowed(net.ParseIP("8.8.8.8")) || !s.SSIDAllowed("whatever") || !s.MACAllowed("aa:bb:cc:dd:ee:ff") {
+		t.Fatal("expected everything to be allowed")
+	}
+}
+
+func TestScopeCIDRs(t *testing.T) {
+	s := NewScope()
+	if err := s.SetCIDRs("192.168.1.0/24, 10.0.0.1, fe80::/64"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]bool{
+		"192.168.1.1":   true,
+		"192.168.1.254": true,
+		"192.168.2.1":   false,
+		"10.0.0.1":      true,
+		"10.0.0.2":      false,
+		"fe80::1":       true,
+		"2001:db8::1":   false,
+	}
+	for addr, exp := range cases {
+		if got := s.IPAllowed(net.ParseIP(addr)); got != exp {
+			t.Fatalf("expected '%t' for %s, got '%t'", exp, addr, got)
+		}
+	}
+}
+
+func TestScopeInvalidEntriesNarrow(t *testing.T) {
+	s := NewScope()
+	if err := s.SetCIDRs("nope"); err == nil {
+		t.Fatal("expected an error")
+	} else if s.IPAllowed(net.ParseIP("192.168.1.1")) {
+		t.Fatal("expected a scope with only invalid entries to block everything")
+	}
+
+	if err := s.SetMACs("zz:zz"); err == nil {
+		t.Fatal("expected an error")
+	} else if s.MACAllowed("aa:bb:cc:dd:ee:ff") {
+		t.Fatal("expected a scope with only invalid entries to block everything")
+	}
+}
+
+func TestScopeMACs(t *testing.T) {
+	s := NewScope()
+	if err := s.SetMACs("AA-BB-CC-DD-EE-FF, 00:11:22:*, 12:34:50:00:00:00/20"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]bool{
+		"aa:bb:cc:dd:ee:ff": true,
+		"aa:bb:cc:dd:ee:fe": false,
+		"00:11:22:33:44:55": true,
+		"00:11:23:33:44:55": false,
+		"12:34:5f:00:00:01": true,
+		"12:34:60:00:00:01": false,
+		"00:11:22:33:44":    true,
+	}
+	for addr, exp := range cases {
+		if got := s.MACAllowed(addr); got != exp {
+			t.Fatalf("expected '%t' for %s, got '%t'", exp, addr, got)
+		}
+	}
+}
+
+func TestScopeSSIDs(t *testing.T) {
+	s := NewScope()
+	s.SetSSIDs("Corp WiFi,Guest")
+	if !s.SSIDAllowed("Corp WiFi") || !s.SSIDAllowed("Guest") || s.SSIDAllowed("Neighbor") {
+		t.Fatal("unexpected SSID scope")
+	}
+}
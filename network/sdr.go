@@ -0,0 +1,256 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SDRDevNewCallback func(dev *SDRDevice)
+type SDRDevLostCallback func(dev *SDRDevice)
+
+// fields of rtl_433 messages that are metadata rather than readings
+var sdrMetaFields = map[string]bool{
+	"time":     true,
+	"model":    true,
+	"id":       true,
+	"channel":  true,
+	"mic":      true,
+	"mod":      true,
+	"freq":     true,
+	"freq1":    true,
+	"freq2":    true,
+	"rssi":     true,
+	"snr":      true,
+	"noise":    true,
+	"protocol": true,
+}
+
+// SDRDevice is a sub-GHz transmitter identified by the model, id and
+// channel rtl_433 decoded from its messages.
+type SDRDevice struct {
+	sync.Mutex
+	Key       string
+	Model     string
+	ID        string
+	Channel   string
+	Frequency float64
+	RSSI      float64
+	Readings  map[string]interface{}
+	Messages  uint64
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+type sdrDeviceJSON struct {
+	Key       string                 `json:"key"`
+	Model     string                 `json:"model"`
+	ID        string                 `json:"id"`
+	Channel   string                 `json:"channel"`
+	Frequency float64                `json:"frequency"`
+	RSSI      float64                `json:"rssi"`
+	Readings  map[string]interface{} `json:"readings"`
+	Messages  uint64                 `json:"messages"`
+	FirstSeen time.Time              `json:"first_seen"`
+	LastSeen  time.Time              `json:"last_seen"`
+}
+
+func sdrField(fields map[string]interface{}, name string) string {
+	if v, found := fields[name]; found && v != nil {
+		if f, ok := v.(float64); ok {
+			return fmt.Sprintf("%v", int64(f))
+		}
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
+
+func sdrFloat(fields map[string]interface{}, names ...string) float64 {
+	for _, name := range names {
+		if f, ok := fields[name].(float64); ok {
+			return f
+		}
+	}
+	return 0
+}
+
+// ParseRTL433 parses a JSON message as printed by rtl_433 -F json, syslog
+// headers (-F syslog:host:port) are skipped.
+func ParseRTL433(line string) (error, map[string]interface{}) {
+	start := strings.IndexByte(line, '{')
+	if start == -1 {
+		return fmt.Errorf("no JSON object found"), nil
+	}
+
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(line[start:]), &fields); err != nil {
+		return err, nil
+	} else if sdrField(fields, "model") == "" {
+		return fmt.Errorf("message without model"), nil
+	}
+
+	return nil, fields
+}
+
+// SDRDeviceKey returns the inventory key for the message fields.
+func SDRDeviceKey(fields map[string]interface{}) string {
+	parts := []string{sdrField(fields, "model")}
+	if id := sdrField(fields, "id"); id != "" {
+		parts = append(parts, id)
+	}
+	if ch := sdrField(fields, "channel"); ch != "" {
+		parts = append(parts, ch)
+	}
+	return strings.Join(parts, "/")
+}
+
+func NewSDRDevice(fields map[string]interface{}) *SDRDevice {
+	now := time.Now()
+	dev := &SDRDevice{
+		Key:       SDRDeviceKey(fields),
+		Model:     sdrField(fields, "model"),
+		ID:        sdrField(fields, "id"),
+		Channel:   sdrField(fields, "channel"),
+		Readings:  make(map[string]interface{}),
+		FirstSeen: now,
+	}
+	dev.update(fields)
+	return dev
+}
+
+func (dev *SDRDevice) update(fields map[string]interface{}) {
+	dev.Lock()
+	defer dev.Unlock()
+
+	dev.LastSeen = time.Now()
+	dev.Messages++
+	if freq := sdrFloat(fields, "freq", "freq1"); freq != 0 {
+		dev.Frequency = freq
+	}
+	if rssi := sdrFloat(fields, "rssi"); rssi != 0 {
+		dev.RSSI = rssi
+	}
+	for k, v := range fields {
+		if !sdrMetaFields[k] {
+			dev.Readings[k] = v
+		}
+	}
+}
+
+// ReadingsString returns the last readings as sorted key=value pairs.
+func (dev *SDRDevice) ReadingsString() string {
+	dev.Lock()
+	defer dev.Unlock()
+
+	pairs := make([]string, 0)
+	for k, v := range dev.Readings {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+func (dev *SDRDevice) MarshalJSON() ([]byte, error) {
+	dev.Lock()
+	defer dev.Unlock()
+
+	doc := sdrDeviceJSON{
+		Key:       dev.Key,
+		Model:     dev.Model,
+		ID:        dev.ID,
+		Channel:   dev.Channel,
+		Frequency: dev.Frequency,
+		RSSI:      dev.RSSI,
+		Readings:  dev.Readings,
+		Messages:  dev.Messages,
+		FirstSeen: dev.FirstSeen,
+		LastSeen:  dev.LastSeen,
+	}
+
+	return json.Marshal(doc)
+}
+
+type SDR struct {
+	sync.RWMutex
+	devices map[string]*SDRDevice
+	newCb   SDRDevNewCallback
+	lostCb  SDRDevLostCallback
+}
+
+type sdrJSON struct {
+	Devices []*SDRDevice `json:"devices"`
+}
+
+func NewSDR(newcb SDRDevNewCallback, lostcb SDRDevLostCallback) *SDR {
+	return &SDR{
+		devices: make(map[string]*SDRDevice),
+		newCb:   newcb,
+		lostCb:  lostcb,
+	}
+}
+
+func (s *SDR) MarshalJSON() ([]byte, error) {
+	doc := sdrJSON{
+		Devices: s.Devices(),
+	}
+	return json.Marshal(doc)
+}
+
+func (s *SDR) Get(key string) (dev *SDRDevice, found bool) {
+	s.RLock()
+	defer s.RUnlock()
+	dev, found = s.devices[key]
+	return
+}
+
+func (s *SDR) AddIfNew(fields map[string]interface{}) (bool, *SDRDevice) {
+	s.Lock()
+	defer s.Unlock()
+
+	key := SDRDeviceKey(fields)
+	if dev, found := s.devices[key]; found {
+		dev.update(fields)
+		return false, dev
+	}
+
+	newDev := NewSDRDevice(fields)
+	s.devices[key] = newDev
+
+	if s.newCb != nil {
+		s.newCb(newDev)
+	}
+
+	return true, newDev
+}
+
+func (s *SDR) Remove(key string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if dev, found := s.devices[key]; found {
+		delete(s.devices, key)
+		if s.lostCb != nil {
+			s.lostCb(dev)
+		}
+	}
+}
+
+func (s *SDR) Devices() (devices []*SDRDevice) {
+	s.RLock()
+	defer s.RUnlock()
+
+	devices = make([]*SDRDevice, 0)
+	for _, dev := range s.devices {
+		devices = append(devices, dev)
+	}
+	return
+}
+
+func (s *SDR) Clear() {
+	s.Lock()
+	defer s.Unlock()
+	s.devices = make(map[string]*SDRDevice)
+}
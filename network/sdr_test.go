@@ -0,0 +1,49 @@
+package network
+
+import (
+	"testing"
+)
+
+func TestParseRTL433(t *testing.T) {
+	line := `{"time" : "2019-03-14 10:00:00", "model" : "Acurite-Tower", "id" : 2508, "channel" : "A", "battery_ok" : 1, "temperature_C" : 21.5, "humidity" : 40, "rssi" : -12.1}`
+
+	err, fields := ParseRTL433(line)
+	if err != nil {
+		t.Fatal(err)
+	} else if key := SDRDeviceKey(fields); key != "Acurite-Tower/2508/A" {
+		t.Fatalf("unexpected key %s", key)
+	}
+
+	syslog := `<13>1 2019-03-14T10:00:00Z host rtl_433 - - - {"model" : "Generic-Remote", "id" : 123, "cmd" : 8}`
+	if err, fields = ParseRTL433(syslog); err != nil {
+		t.Fatal(err)
+	} else if key := SDRDeviceKey(fields); key != "Generic-Remote/123" {
+		t.Fatalf("unexpected key %s", key)
+	}
+
+	for _, bad := range []string{"", "rtl_433 version 21.12", `{"time" : "now"}`, `{"model" : `} {
+		if err, _ := ParseRTL433(bad); err == nil {
+			t.Fatalf("expected error for '%s'", bad)
+		}
+	}
+}
+
+func TestSDRAddIfNew(t *testing.T) {
+	news := 0
+	sdr := NewSDR(func(dev *SDRDevice) { news++ }, nil)
+
+	_, fields := ParseRTL433(`{"model" : "Acurite-Tower", "id" : 1, "temperature_C" : 20.0, "rssi" : -10.0}`)
+	if isNew, _ := sdr.AddIfNew(fields); !isNew {
+		t.Fatal("expected new device")
+	}
+
+	_, fields = ParseRTL433(`{"model" : "Acurite-Tower", "id" : 1, "temperature_C" : 20.5, "humidity" : 42}`)
+	isNew, dev := sdr.AddIfNew(fields)
+	if isNew || news != 1 {
+		t.Fatal("expected existing device")
+	} else if dev.Messages != 2 || dev.RSSI != -10.0 {
+		t.Fatalf("unexpected device %+v", dev)
+	} else if r := dev.ReadingsString(); r != "humidity=42 temperature_C=20.5" {
+		t.Fatalf("unexpected readings %s", r)
+	}
+}
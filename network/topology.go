@@ -0,0 +1,176 @@
+package network
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+type TopologyNodeNewCallback func(node *TopologyNode)
+
+type TopologyNode struct {
+	sync.Mutex
+	Address    string    `json:"mac"`
+	Vendor     string    `json:"vendor"`
+	Name       string    `json:"name"`
+	Platform   string    `json:"platform"`
+	Port       string    `json:"port"`
+	Addresses  []string  `json:"addresses"`
+	NativeVLAN uint16    `json:"native_vlan"`
+	VLANs      []uint16  `json:"vlans"`
+	Protocols  []string  `json:"protocols"`
+	BridgeID   string    `json:"bridge_id"`
+	RootID     string    `json:"root_id"`
+	RootCost   uint32    `json:"root_cost"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+
+	vlans  map[uint16]bool
+	protos map[string]bool
+}
+
+func NewTopologyNode(mac string) *TopologyNode {
+	now := time.Now()
+	mac = NormalizeMac(mac)
+	return &TopologyNode{
+		Address:   mac,
+		Vendor:    ManufLookup(mac),
+		Addresses: make([]string, 0),
+		VLANs:     make([]uint16, 0),
+		Protocols: make([]string, 0),
+		FirstSeen: now,
+		LastSeen:  now,
+		vlans:     make(map[uint16]bool),
+		protos:    make(map[string]bool),
+	}
+}
+
+func (n *TopologyNode) AddProtocol(proto string) {
+	if _, found := n.protos[proto]; !found {
+		n.protos[proto] = true
+		n.Protocols = append(n.Protocols, proto)
+		sort.Strings(n.Protocols)
+	}
+}
+
+func (n *TopologyNode) AddVLAN(vlan uint16) {
+	if vlan == 0 {
+		return
+	} else if _, found := n.vlans[vlan]; !found {
+		n.vlans[vlan] = true
+		n.VLANs = append(n.VLANs, vlan)
+		sort.Slice(n.VLANs, func(i, j int) bool {
+			return n.VLANs[i] < n.VLANs[j]
+		})
+	}
+}
+
+func (n *TopologyNode) AddAddress(addr string) {
+	for _, a := range n.Addresses {
+		if a == addr {
+			return
+		}
+	}
+	n.Addresses = append(n.Addresses, addr)
+}
+
+type Topology struct {
+	sync.RWMutex
+	nodes    map[string]*TopologyNode
+	rootID   string
+	rootAddr string
+	newCb    TopologyNodeNewCallback
+}
+
+type topologyJSON struct {
+	Root  string          `json:"root"`
+	Nodes []*TopologyNode `json:"nodes"`
+}
+
+func NewTopology(newcb TopologyNodeNewCallback) *Topology {
+	return &Topology{
+		nodes: make(map[string]*TopologyNode),
+		newCb: newcb,
+	}
+}
+
+func (t *Topology) MarshalJSON() ([]byte, error) {
+	t.RLock()
+	defer t.RUnlock()
+
+	doc := topologyJSON{
+		Root:  t.rootID,
+		Nodes: make([]*TopologyNode, 0),
+	}
+
+	for _, node := range t.nodes {
+		doc.Nodes = append(doc.Nodes, node)
+	}
+
+	return json.Marshal(doc)
+}
+
+func (t *Topology) Get(mac string) (node *TopologyNode, found bool) {
+	t.RLock()
+	defer t.RUnlock()
+	node, found = t.nodes[NormalizeMac(mac)]
+	return
+}
+
+func (t *Topology) AddIfNew(mac string) (bool, *TopologyNode) {
+	t.Lock()
+	defer t.Unlock()
+
+	mac = NormalizeMac(mac)
+	if node, found := t.nodes[mac]; found {
+		node.LastSeen = time.Now()
+		return false, node
+	}
+
+	node := NewTopologyNode(mac)
+	t.nodes[mac] = node
+
+	if t.newCb != nil {
+		t.newCb(node)
+	}
+
+	return true, node
+}
+
+// SetRoot stores the identifier and address of the bridge
+// currently elected as root of the spanning tree.
+func (t *Topology) SetRoot(id, mac string) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	changed := t.rootID != id
+	t.rootID = id
+	t.rootAddr = NormalizeMac(mac)
+	return changed
+}
+
+func (t *Topology) Root() (id string, mac string) {
+	t.RLock()
+	defer t.RUnlock()
+	return t.rootID, t.rootAddr
+}
+
+func (t *Topology) Nodes() (nodes []*TopologyNode) {
+	t.RLock()
+	defer t.RUnlock()
+
+	nodes = make([]*TopologyNode, 0)
+	for _, node := range t.nodes {
+		nodes = append(nodes, node)
+	}
+	return
+}
+
+func (t *Topology) Clear() {
+	t.Lock()
+	defer t.Unlock()
+	t.nodes = make(map[string]*TopologyNode)
+	t.rootID = ""
+	t.rootAddr = ""
+}
@@ -0,0 +1,205 @@
+package network
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// UPNPArgument is an input or output argument of a service action.
+type UPNPArgument struct {
+	Name      string `xml:"name" json:"name"`
+	Direction string `xml:"direction" json:"direction"`
+	Variable  string `xml:"relatedStateVariable" json:"variable"`
+}
+
+// UPNPAction is an action exposed by a service and invoked with SOAP.
+type UPNPAction struct {
+	Name      string         `xml:"name" json:"name"`
+	Arguments []UPNPArgument `xml:"argumentList>argument" json:"arguments"`
+}
+
+// UPNPService is a service of a device as declared in its description.
+type UPNPService struct {
+	Type       string       `xml:"serviceType" json:"type"`
+	ID         string       `xml:"serviceId" json:"id"`
+	ControlURL string       `xml:"controlURL" json:"control_url"`
+	EventURL   string       `xml:"eventSubURL" json:"event_url"`
+	SCPDURL    string       `xml:"SCPDURL" json:"scpd_url"`
+	Actions    []UPNPAction `xml:"-" json:"actions"`
+}
+
+// UPNPDevice is a device, or an embedded device, of a UPnP description.
+type UPNPDevice struct {
+	Type         string         `xml:"deviceType" json:"type"`
+	FriendlyName string         `xml:"friendlyName" json:"friendly_name"`
+	Manufacturer string         `xml:"manufacturer" json:"manufacturer"`
+	ModelName    string         `xml:"modelName" json:"model_name"`
+	ModelNumber  string         `xml:"modelNumber" json:"model_number"`
+	SerialNumber string         `xml:"serialNumber" json:"serial_number"`
+	UDN          string         `xml:"UDN" json:"udn"`
+	Services     []*UPNPService `xml:"serviceList>service" json:"services"`
+	Devices      []*UPNPDevice  `xml:"deviceList>device" json:"devices"`
+}
+
+// UPNPRoot is the description document fetched from the Location of a device.
+type UPNPRoot struct {
+	Location string     `xml:"-" json:"location"`
+	URLBase  string     `xml:"URLBase" json:"url_base"`
+	Device   UPNPDevice `xml:"device" json:"device"`
+}
+
+// UPNPArg is the name and value of an argument of a SOAP request or response.
+type UPNPArg struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ParseUPNPDescription parses the description document of a device, the URLs
+// of the services are resolved against the URLBase or the location itself.
+func ParseUPNPDescription(location string, raw []byte) (*UPNPRoot, error) {
+	root := &UPNPRoot{}
+	if err := xml.Unmarshal(raw, root); err != nil {
+		return nil, err
+	}
+
+	root.Location = location
+	base, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	} else if root.URLBase != "" {
+		if base, err = url.Parse(root.URLBase); err != nil {
+			return nil, err
+		}
+	}
+
+	root.Device.Each(func(d *UPNPDevice, s *UPNPService) {
+		s.ControlURL = resolveUPNPURL(base, s.ControlURL)
+		s.EventURL = resolveUPNPURL(base, s.EventURL)
+		s.SCPDURL = resolveUPNPURL(base, s.SCPDURL)
+	})
+
+	return root, nil
+}
+
+func resolveUPNPURL(base *url.URL, ref string) string {
+	if ref = strings.TrimSpace(ref); ref == "" {
+		return ""
+	} else if u, err := url.Parse(ref); err == nil {
+		return base.ResolveReference(u).String()
+	}
+	return ref
+}
+
+// ParseUPNPSCPD parses the service description document and returns its actions.
+func ParseUPNPSCPD(raw []byte) ([]UPNPAction, error) {
+	scpd := struct {
+		Actions []UPNPAction `xml:"actionList>action"`
+	}{}
+	if err := xml.Unmarshal(raw, &scpd); err != nil {
+		return nil, err
+	}
+	return scpd.Actions, nil
+}
+
+// Each calls cb for every service of the device and of its embedded devices.
+func (d *UPNPDevice) Each(cb func(d *UPNPDevice, s *UPNPService)) {
+	for _, s := range d.Services {
+		cb(d, s)
+	}
+	for _, sub := range d.Devices {
+		sub.Each(cb)
+	}
+}
+
+// Find returns the first service whose type or id contains name, like
+// WANIPConnection for urn:schemas-upnp-org:service:WANIPConnection:1.
+func (d *UPNPDevice) Find(name string) *UPNPService {
+	var found *UPNPService
+	d.Each(func(_ *UPNPDevice, s *UPNPService) {
+		if found == nil && (strings.Contains(s.Type, name) || strings.Contains(s.ID, name)) {
+			found = s
+		}
+	})
+	return found
+}
+
+// NewUPNPSOAPRequest creates the SOAP envelope to invoke an action of a service.
+func NewUPNPSOAPRequest(serviceType string, action string, args []UPNPArg) []byte {
+	buf := bytes.Buffer{}
+	buf.WriteString(`<?xml version="1.0"?>` + "\n")
+	buf.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">`)
+	buf.WriteString(`<s:Body>`)
+	fmt.Fprintf(&buf, `<u:%s xmlns:u="%s">`, action, escapeUPNP(serviceType))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "<%s>%s</%s>", arg.Name, escapeUPNP(arg.Value), arg.Name)
+	}
+	fmt.Fprintf(&buf, `</u:%s>`, action)
+	buf.WriteString(`</s:Body></s:Envelope>`)
+	return buf.Bytes()
+}
+
+func escapeUPNP(s string) string {
+	buf := bytes.Buffer{}
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// ParseUPNPSOAPResponse returns the output arguments of a SOAP response, or
+// the UPnP error if the device returned a fault.
+func ParseUPNPSOAPResponse(raw []byte) ([]UPNPArg, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(raw))
+	args := make([]UPNPArg, 0)
+	// Envelope > Body > ActionResponse|Fault > argument
+	depth := 0
+	fault := false
+	current := ""
+	faultCode := ""
+	faultDesc := ""
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			current = t.Name.Local
+			if depth == 3 && current == "Fault" {
+				fault = true
+			} else if depth == 4 && !fault {
+				args = append(args, UPNPArg{Name: current})
+			}
+		case xml.EndElement:
+			depth--
+			current = ""
+		case xml.CharData:
+			value := strings.TrimSpace(string(t))
+			if fault {
+				if current == "errorCode" {
+					faultCode = value
+				} else if current == "errorDescription" || (current == "faultstring" && faultDesc == "") {
+					faultDesc = value
+				}
+			} else if depth == 4 && current != "" {
+				args[len(args)-1].Value = value
+			}
+		}
+	}
+
+	if fault {
+		if faultCode != "" {
+			return nil, fmt.Errorf("UPnP error %s: %s", faultCode, faultDesc)
+		}
+		return nil, fmt.Errorf("SOAP fault: %s", faultDesc)
+	}
+
+	return args, nil
+}
@@ -0,0 +1,115 @@
+package network
+
+import (
+	"strings"
+	"testing"
+)
+
+const testUPNPDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+    <friendlyName>Router</friendlyName>
+    <manufacturer>ACME</manufacturer>
+    <modelName>R1000</modelName>
+    <UDN>uuid:1234</UDN>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:WANConnectionDevice:1</deviceType>
+        <friendlyName>WAN</friendlyName>
+        <serviceList>
+          <service>
+            <serviceType>urn:schemas-upnp-org:service:WANIPConnection:1</serviceType>
+            <serviceId>urn:upnp-org:serviceId:WANIPConn1</serviceId>
+            <controlURL>/ctl/IPConn</controlURL>
+            <eventSubURL>/evt/IPConn</eventSubURL>
+            <SCPDURL>WANIPCn.xml</SCPDURL>
+          </service>
+        </serviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`
+
+const testUPNPSCPD = `<?xml version="1.0"?>
+<scpd xmlns="urn:schemas-upnp-org:service-1-0">
+  <actionList>
+    <action>
+      <name>GetExternalIPAddress</name>
+      <argumentList>
+        <argument>
+          <name>NewExternalIPAddress</name>
+          <direction>out</direction>
+          <relatedStateVariable>ExternalIPAddress</relatedStateVariable>
+        </argument>
+      </argumentList>
+    </action>
+  </actionList>
+</scpd>`
+
+func TestParseUPNPDescription(t *testing.T) {
+	root, err := ParseUPNPDescription("http://192.168.1.1:5000/rootDesc.xml", []byte(testUPNPDescription))
+	if err != nil {
+		t.Fatal(err)
+	} else if root.Device.FriendlyName != "Router" {
+		t.Fatalf("unexpected name '%s'", root.Device.FriendlyName)
+	}
+
+	s := root.Device.Find("WANIPConnection")
+	if s == nil {
+		t.Fatal("expected WANIPConnection service")
+	} else if s.ControlURL != "http://192.168.1.1:5000/ctl/IPConn" {
+		t.Fatalf("unexpected control url '%s'", s.ControlURL)
+	} else if s.SCPDURL != "http://192.168.1.1:5000/WANIPCn.xml" {
+		t.Fatalf("unexpected scpd url '%s'", s.SCPDURL)
+	}
+
+	if root.Device.Find("WANPPPConnection") != nil {
+		t.Fatal("unexpected WANPPPConnection service")
+	}
+}
+
+func TestParseUPNPSCPD(t *testing.T) {
+	actions, err := ParseUPNPSCPD([]byte(testUPNPSCPD))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(actions) != 1 {
+		t.Fatalf("expected 1 action, got %d", len(actions))
+	} else if actions[0].Name != "GetExternalIPAddress" || len(actions[0].Arguments) != 1 {
+		t.Fatalf("unexpected action %+v", actions[0])
+	} else if arg := actions[0].Arguments[0]; arg.Direction != "out" || arg.Variable != "ExternalIPAddress" {
+		t.Fatalf("unexpected argument %+v", arg)
+	}
+}
+
+func TestUPNPSOAP(t *testing.T) {
+	req := string(NewUPNPSOAPRequest("urn:schemas-upnp-org:service:WANIPConnection:1", "GetGenericPortMappingEntry", []UPNPArg{
+		{"NewPortMappingIndex", "0"},
+	}))
+	if !strings.Contains(req, `<u:GetGenericPortMappingEntry xmlns:u="urn:schemas-upnp-org:service:WANIPConnection:1"><NewPortMappingIndex>0</NewPortMappingIndex></u:GetGenericPortMappingEntry>`) {
+		t.Fatalf("unexpected request %s", req)
+	}
+
+	args, err := ParseUPNPSOAPResponse([]byte(`<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>
+<u:GetGenericPortMappingEntryResponse xmlns:u="urn:schemas-upnp-org:service:WANIPConnection:1">
+<NewRemoteHost></NewRemoteHost><NewExternalPort>8080</NewExternalPort><NewProtocol>TCP</NewProtocol>
+</u:GetGenericPortMappingEntryResponse></s:Body></s:Envelope>`))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(args) != 3 {
+		t.Fatalf("expected 3 arguments, got %v", args)
+	} else if args[0].Name != "NewRemoteHost" || args[0].Value != "" || args[1].Value != "8080" {
+		t.Fatalf("unexpected arguments %v", args)
+	}
+
+	_, err = ParseUPNPSOAPResponse([]byte(`<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault>
+<faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring>
+<detail><UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>713</errorCode>
+<errorDescription>SpecifiedArrayIndexInvalid</errorDescription></UPnPError></detail>
+</s:Fault></s:Body></s:Envelope>`))
+	if err == nil || !strings.Contains(err.Error(), "713") {
+		t.Fatalf("expected UPnP error 713, got %v", err)
+	}
+}
@@ -1,12 +1,14 @@
 package network
 
 import (
+	"bytes"
 	"encoding/json"
-	"os"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/bettercap/bettercap/loot"
+
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 	"github.com/google/gopacket/pcapgo"
@@ -44,6 +46,7 @@ type WiFi struct {
 	sync.Mutex
 
 	aps    map[string]*AccessPoint
+	p2p    map[string]*P2PDevice
 	iface  *Endpoint
 	newCb  APNewCallback
 	lostCb APLostCallback
@@ -51,11 +54,13 @@ type WiFi struct {
 
 type wifiJSON struct {
 	AccessPoints []*AccessPoint `json:"aps"`
+	P2PDevices   []*P2PDevice   `json:"p2p"`
 }
 
 func NewWiFi(iface *Endpoint, newcb APNewCallback, lostcb APLostCallback) *WiFi {
 	return &WiFi{
 		aps:    make(map[string]*AccessPoint),
+		p2p:    make(map[string]*P2PDevice),
 		iface:  iface,
 		newCb:  newcb,
 		lostCb: lostcb,
@@ -65,12 +70,17 @@ func NewWiFi(iface *Endpoint, newcb APNewCallback, lostcb APLostCallback) *WiFi
 func (w *WiFi) MarshalJSON() ([]byte, error) {
 	doc := wifiJSON{
 		AccessPoints: make([]*AccessPoint, 0),
+		P2PDevices:   make([]*P2PDevice, 0),
 	}
 
 	for _, ap := range w.aps {
 		doc.AccessPoints = append(doc.AccessPoints, ap)
 	}
 
+	for _, dev := range w.p2p {
+		doc.P2PDevices = append(doc.P2PDevices, dev)
+	}
+
 	return json.Marshal(doc)
 }
 
@@ -183,6 +193,7 @@ func (w *WiFi) Clear() {
 	w.Lock()
 	defer w.Unlock()
 	w.aps = make(map[string]*AccessPoint)
+	w.p2p = make(map[string]*P2PDevice)
 }
 
 func (w *WiFi) NumHandshakes() int {
@@ -201,22 +212,19 @@ func (w *WiFi) NumHandshakes() int {
 	return sum
 }
 
+// SaveHandshakesTo appends the unsaved handshake packets to fileName in pcap
+// format, as a new encrypted record if the loot encryption is enabled.
 func (w *WiFi) SaveHandshakesTo(fileName string, linkType layers.LinkType) error {
 	w.Lock()
 	defer w.Unlock()
 
-	doHead := !fs.Exists(fileName)
+	doHead := !fs.Exists(loot.FileName(fileName))
 
-	fp, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0666)
-	if err != nil {
-		return err
-	}
-	defer fp.Close()
-
-	writer := pcapgo.NewWriter(fp)
+	buf := bytes.NewBuffer(nil)
+	writer := pcapgo.NewWriter(buf)
 
 	if doHead {
-		if err = writer.WriteFileHeader(65536, linkType); err != nil {
+		if err := writer.WriteFileHeader(65536, linkType); err != nil {
 			return err
 		}
 	}
@@ -224,7 +232,7 @@ func (w *WiFi) SaveHandshakesTo(fileName string, linkType layers.LinkType) error
 	for _, ap := range w.aps {
 		for _, station := range ap.Clients() {
 			if station.Handshake.Complete() || station.Handshake.HasPMKID() {
-				err = nil
+				var err error
 				station.Handshake.EachUnsavedPacket(func(pkt gopacket.Packet) {
 					if err == nil {
 						err = writer.WritePacket(pkt.Metadata().CaptureInfo, pkt.Data())
@@ -237,5 +245,10 @@ func (w *WiFi) SaveHandshakesTo(fileName string, linkType layers.LinkType) error
 		}
 	}
 
-	return nil
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	_, err := loot.AppendFile(fileName, buf.Bytes(), 0666)
+	return err
 }
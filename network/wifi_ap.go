@@ -4,8 +4,6 @@ import (
 	"encoding/json"
 	"sync"
 	"time"
-
-	"github.com/evilsocket/islazy/data"
 )
 
 type AccessPoint struct {
@@ -67,7 +65,7 @@ func (ap *AccessPoint) RemoveClient(mac string) {
 	}
 }
 
-func (ap *AccessPoint) AddClientIfNew(bssid string, frequency int, rssi int8, aliases *data.UnsortedKV) (*Station, bool) {
+func (ap *AccessPoint) AddClientIfNew(bssid string, frequency int, rssi int8, aliases AliasResolver) (*Station, bool) {
 	ap.Lock()
 	defer ap.Unlock()
 
@@ -80,7 +78,7 @@ func (ap *AccessPoint) AddClientIfNew(bssid string, frequency int, rssi int8, al
 		s.LastSeen = time.Now()
 
 		if aliases != nil {
-			s.Alias = aliases.GetOr(bssid, "")
+			s.Alias = aliases.GetAlias(bssid)
 		}
 
 		return s, false
@@ -88,7 +86,7 @@ func (ap *AccessPoint) AddClientIfNew(bssid string, frequency int, rssi int8, al
 
 	s := NewStation("", bssid, frequency, rssi)
 	if aliases != nil {
-		s.Alias = aliases.GetOr(bssid, "")
+		s.Alias = aliases.GetAlias(bssid)
 	}
 	ap.clients[bssid] = s
 
@@ -1,6 +1,8 @@
 package network
 
 import (
+	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/google/gopacket"
@@ -14,6 +16,7 @@ type Handshake struct {
 	Challenges    []gopacket.Packet
 	Responses     []gopacket.Packet
 	Confirmations []gopacket.Packet
+	Completions   []gopacket.Packet
 	hasPMKID      bool
 	unsaved       []gopacket.Packet
 }
@@ -23,6 +26,7 @@ func NewHandshake() *Handshake {
 		Challenges:    make([]gopacket.Packet, 0),
 		Responses:     make([]gopacket.Packet, 0),
 		Confirmations: make([]gopacket.Packet, 0),
+		Completions:   make([]gopacket.Packet, 0),
 		unsaved:       make([]gopacket.Packet, 0),
 	}
 }
@@ -74,20 +78,49 @@ func (h *Handshake) AddFrame(n int, pkt gopacket.Packet) {
 		h.Responses = append(h.Responses, pkt)
 	case 2:
 		h.Confirmations = append(h.Confirmations, pkt)
+	case 3:
+		h.Completions = append(h.Completions, pkt)
 	}
 
 	h.unsaved = append(h.unsaved, pkt)
 }
 
-func (h *Handshake) Complete() bool {
+// Messages returns which of the four EAPOL messages have been captured.
+func (h *Handshake) Messages() [4]bool {
 	h.Lock()
 	defer h.Unlock()
 
-	nChal := len(h.Challenges)
-	nResp := len(h.Responses)
-	nConf := len(h.Confirmations)
+	return [4]bool{
+		len(h.Challenges) > 0,
+		len(h.Responses) > 0,
+		len(h.Confirmations) > 0,
+		len(h.Completions) > 0,
+	}
+}
+
+// State returns a representation of the captured messages such as "M1,M2".
+func (h *Handshake) State() string {
+	parts := make([]string, 0)
+	for i, captured := range h.Messages() {
+		if captured {
+			parts = append(parts, fmt.Sprintf("M%d", i+1))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Complete returns true if the captured messages can be cracked, meaning
+// we have the MIC from M2 and the ANonce from either M1 or M3.
+func (h *Handshake) Complete() bool {
+	m := h.Messages()
+	return m[1] && (m[0] || m[2])
+}
 
-	return nChal > 0 && nResp > 0 && nConf > 0
+// Half returns true if some messages have been captured but not enough
+// of them to crack the handshake.
+func (h *Handshake) Half() bool {
+	m := h.Messages()
+	return (m[0] || m[1] || m[2] || m[3]) && !h.Complete()
 }
 
 func (h *Handshake) HasPMKID() bool {
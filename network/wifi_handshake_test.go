@@ -0,0 +1,52 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func buildExamplePacket() gopacket.Packet {
+	return gopacket.NewPacket(make([]byte, 64), layers.LayerTypeEthernet, gopacket.Default)
+}
+
+func TestHandshakeStates(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Frames   []int
+		State    string
+		Complete bool
+		Half     bool
+	}{
+		{"empty", []int{}, "", false, false},
+		{"only M1", []int{0}, "M1", false, true},
+		{"only M2", []int{1}, "M2", false, true},
+		{"M1 and M2", []int{0, 1}, "M1,M2", true, false},
+		{"M2 and M3", []int{1, 2}, "M2,M3", true, false},
+		{"M3 and M4", []int{2, 3}, "M3,M4", false, true},
+		{"full", []int{0, 1, 2, 3}, "M1,M2,M3,M4", true, false},
+	}
+
+	for _, test := range cases {
+		t.Run(test.Name, func(t *testing.T) {
+			h := NewHandshake()
+			for _, n := range test.Frames {
+				h.AddFrame(n, buildExamplePacket())
+			}
+
+			if got := h.State(); got != test.State {
+				t.Errorf("expected state '%s', got '%s'", test.State, got)
+			}
+			if got := h.Complete(); got != test.Complete {
+				t.Errorf("expected complete %v, got %v", test.Complete, got)
+			}
+			if got := h.Half(); got != test.Half {
+				t.Errorf("expected half %v, got %v", test.Half, got)
+			}
+			if got := h.NumUnsaved(); got != len(test.Frames) {
+				t.Errorf("expected %d unsaved frames, got %d", len(test.Frames), got)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package network
+
+import (
+	"time"
+)
+
+const (
+	P2PRoleDevice     = "device"
+	P2PRoleGroupOwner = "go"
+	P2PRoleClient     = "client"
+)
+
+// P2PDevice is a Wi-Fi Direct device, HwAddress is its P2P device address and
+// Hostname the device name it advertises.
+type P2PDevice struct {
+	*Endpoint
+	Type      string `json:"type"`
+	Role      string `json:"role"`
+	Group     string `json:"group"`
+	Frequency int    `json:"frequency"`
+	Channel   int    `json:"channel"`
+	RSSI      int8   `json:"rssi"`
+}
+
+func NewP2PDevice(mac, name string, frequency int, rssi int8) *P2PDevice {
+	return &P2PDevice{
+		Endpoint:  NewEndpointNoResolve(MonitorModeAddress, mac, cleanESSID(name), 0),
+		Role:      P2PRoleDevice,
+		Frequency: frequency,
+		Channel:   Dot11Freq2Chan(frequency),
+		RSSI:      rssi,
+	}
+}
+
+func (d *P2PDevice) Name() string {
+	return d.Hostname
+}
+
+func (w *WiFi) P2PDevices() (list []*P2PDevice) {
+	w.Lock()
+	defer w.Unlock()
+
+	list = make([]*P2PDevice, 0, len(w.p2p))
+	for _, dev := range w.p2p {
+		list = append(list, dev)
+	}
+	return
+}
+
+func (w *WiFi) GetP2P(mac string) (*P2PDevice, bool) {
+	w.Lock()
+	defer w.Unlock()
+
+	dev, found := w.p2p[NormalizeMac(mac)]
+	return dev, found
+}
+
+func (w *WiFi) RemoveP2P(mac string) {
+	w.Lock()
+	defer w.Unlock()
+	delete(w.p2p, NormalizeMac(mac))
+}
+
+// AddP2PIfNew adds or updates a Wi-Fi Direct device, an empty name doesn't
+// replace the known one.
+func (w *WiFi) AddP2PIfNew(mac, name string, frequency int, rssi int8) (*P2PDevice, bool) {
+	w.Lock()
+	defer w.Unlock()
+
+	mac = NormalizeMac(mac)
+	if dev, found := w.p2p[mac]; found {
+		dev.LastSeen = time.Now()
+		if rssi != 0 {
+			dev.RSSI = rssi
+		}
+		if frequency != 0 {
+			dev.Frequency = frequency
+			dev.Channel = Dot11Freq2Chan(frequency)
+		}
+		if name != "" && !isBogusMacESSID(name) {
+			dev.Hostname = name
+		}
+		return dev, false
+	}
+
+	dev := NewP2PDevice(mac, name, frequency, rssi)
+	w.p2p[mac] = dev
+	return dev, true
+}
@@ -0,0 +1,201 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// directories where wpa_supplicant creates its control sockets, the first
+// ones are used by Android, the last one by most GNU/Linux distributions.
+var WPAControlDirs = []string{
+	"/data/vendor/wifi/wpa/sockets",
+	"/data/misc/wifi/sockets",
+	"/data/system/wpa_supplicant",
+	"/var/run/wpa_supplicant",
+}
+
+const wpaControlTimeout = 5 * time.Second
+
+// WPAScanResult is an entry of the SCAN_RESULTS reply of wpa_supplicant.
+type WPAScanResult struct {
+	BSSID     string
+	Frequency int
+	RSSI      int8
+	Flags     string
+	ESSID     string
+}
+
+// Encryption returns the encryption, cipher and authentication of the
+// network as they are reported by the 802.11 parsers.
+func (r WPAScanResult) Encryption() (enc string, cipher string, auth string) {
+	enc = "OPEN"
+	for _, flag := range strings.Split(strings.Trim(r.Flags, "[]"), "][") {
+		parts := strings.Split(flag, "-")
+		switch parts[0] {
+		case "WEP":
+			if enc == "OPEN" {
+				enc = "WEP"
+			}
+		case "WPA", "WPA2", "RSN":
+			if parts[0] == "WPA" && enc == "WPA2" {
+				continue
+			}
+
+			enc = "WPA2"
+			if parts[0] == "WPA" {
+				enc = "WPA"
+			}
+
+			if len(parts) >= 3 {
+				auth = parts[1]
+				cipher = parts[2]
+				// TKIP+CCMP, the strongest one is the last
+				if idx := strings.LastIndex(cipher, "+"); idx != -1 {
+					cipher = cipher[idx+1:]
+				}
+			}
+
+			if auth == "SAE" {
+				enc = "WPA3"
+			}
+		}
+	}
+	return
+}
+
+// WPSEnabled returns true if the network is advertising WPS.
+func (r WPAScanResult) WPSEnabled() bool {
+	return strings.Contains(r.Flags, "[WPS")
+}
+
+// WPAControl is a client of the wpa_supplicant control interface.
+type WPAControl struct {
+	sync.Mutex
+	path  string
+	local string
+	conn  *net.UnixConn
+}
+
+// WPAControlSocket returns the path of the wpa_supplicant control socket of the interface.
+func WPAControlSocket(iface string) (string, error) {
+	for _, dir := range WPAControlDirs {
+		path := filepath.Join(dir, iface)
+		if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("could not find the wpa_supplicant control socket of %s in %s", iface, strings.Join(WPAControlDirs, ", "))
+}
+
+// NewWPAControl connects to the wpa_supplicant control socket at the given path.
+func NewWPAControl(path string) (*WPAControl, error) {
+	ctrl := &WPAControl{
+		path:  path,
+		local: filepath.Join(os.TempDir(), fmt.Sprintf("bettercap-wpa-%d", os.Getpid())),
+	}
+
+	// replies are sent back to the address we're bound to
+	os.Remove(ctrl.local)
+	laddr := &net.UnixAddr{Name: ctrl.local, Net: "unixgram"}
+	raddr := &net.UnixAddr{Name: path, Net: "unixgram"}
+
+	conn, err := net.DialUnix("unixgram", laddr, raddr)
+	if err != nil {
+		return nil, err
+	}
+	ctrl.conn = conn
+
+	if reply, err := ctrl.Request("PING"); err != nil {
+		ctrl.Close()
+		return nil, err
+	} else if reply != "PONG" {
+		ctrl.Close()
+		return nil, fmt.Errorf("unexpected reply to PING from %s: %s", path, reply)
+	}
+
+	return ctrl, nil
+}
+
+// Request sends a command to wpa_supplicant and returns its reply.
+func (c *WPAControl) Request(cmd string) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.conn.SetDeadline(time.Now().Add(wpaControlTimeout))
+	if _, err := c.conn.Write([]byte(cmd)); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 65536)
+	for {
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			return "", err
+		}
+
+		reply := string(buf[:n])
+		// skip unsolicited event messages
+		if !strings.HasPrefix(reply, "<") {
+			return strings.TrimRight(reply, "\n"), nil
+		}
+	}
+}
+
+// Scan asks wpa_supplicant to start a new scan, a scan already in progress is not an error.
+func (c *WPAControl) Scan() error {
+	if reply, err := c.Request("SCAN"); err != nil {
+		return err
+	} else if reply != "OK" && reply != "FAIL-BUSY" {
+		return fmt.Errorf("SCAN failed: %s", reply)
+	}
+	return nil
+}
+
+// ScanResults returns the networks found by the last scans.
+func (c *WPAControl) ScanResults() ([]WPAScanResult, error) {
+	reply, err := c.Request("SCAN_RESULTS")
+	if err != nil {
+		return nil, err
+	}
+	return parseWPAScanResults(reply), nil
+}
+
+func parseWPAScanResults(reply string) []WPAScanResult {
+	results := make([]WPAScanResult, 0)
+	for _, line := range strings.Split(reply, "\n") {
+		// bssid / frequency / signal level / flags / ssid
+		fields := strings.SplitN(strings.TrimRight(line, "\r"), "\t", 5)
+		if len(fields) < 4 {
+			continue
+		} else if _, err := net.ParseMAC(fields[0]); err != nil {
+			continue
+		}
+
+		freq, _ := strconv.Atoi(fields[1])
+		rssi, _ := strconv.Atoi(fields[2])
+		result := WPAScanResult{
+			BSSID:     NormalizeMac(fields[0]),
+			Frequency: freq,
+			RSSI:      int8(rssi),
+			Flags:     fields[3],
+		}
+		if len(fields) == 5 {
+			result.ESSID = fields[4]
+		}
+
+		results = append(results, result)
+	}
+	return results
+}
+
+// Close closes the connection and removes the local socket.
+func (c *WPAControl) Close() {
+	c.conn.Close()
+	os.Remove(c.local)
+}
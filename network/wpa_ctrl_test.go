@@ -0,0 +1,45 @@
+package network
+
+import (
+	"testing"
+)
+
+func TestParseWPAScanResults(t *testing.T) {
+	reply := "bssid / frequency / signal level / flags / ssid\n" +
+		"00:11:22:33:44:55\t2437\t-45\t[WPA2-PSK-CCMP][WPS][ESS]\tHome\n" +
+		"00:11:22:33:44:66\t5180\t-70\t[WPA-PSK-TKIP][WPA2-PSK-TKIP+CCMP][ESS]\tOffice Net\n" +
+		"00:11:22:33:44:77\t2412\t-80\t[RSN-SAE-CCMP][ESS]\t\n" +
+		"00:11:22:33:44:88\t2462\t-60\t[WEP][ESS]\tOld\n" +
+		"00:11:22:33:44:99\t2462\t-61\t[ESS]\tFree\n"
+
+	results := parseWPAScanResults(reply)
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+
+	exp := []struct {
+		essid  string
+		enc    string
+		cipher string
+		auth   string
+		wps    bool
+	}{
+		{"Home", "WPA2", "CCMP", "PSK", true},
+		{"Office Net", "WPA2", "CCMP", "PSK", false},
+		{"", "WPA3", "CCMP", "SAE", false},
+		{"Old", "WEP", "", "", false},
+		{"Free", "OPEN", "", "", false},
+	}
+
+	for i, e := range exp {
+		r := results[i]
+		enc, cipher, auth := r.Encryption()
+		if r.ESSID != e.essid || enc != e.enc || cipher != e.cipher || auth != e.auth || r.WPSEnabled() != e.wps {
+			t.Fatalf("unexpected result %d: %+v (%s %s %s)", i, r, enc, cipher, auth)
+		}
+	}
+
+	if results[1].Frequency != 5180 || results[1].RSSI != -70 {
+		t.Fatalf("unexpected frequency or rssi: %+v", results[1])
+	}
+}
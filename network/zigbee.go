@@ -0,0 +1,105 @@
+package network
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+type ZigbeeDevNewCallback func(dev *ZigbeeDevice)
+type ZigbeeDevLostCallback func(dev *ZigbeeDevice)
+
+type Zigbee struct {
+	sync.RWMutex
+	devices map[string]*ZigbeeDevice
+	newCb   ZigbeeDevNewCallback
+	lostCb  ZigbeeDevLostCallback
+}
+
+type zigbeeJSON struct {
+	Devices []*ZigbeeDevice `json:"devices"`
+}
+
+func NewZigbee(newcb ZigbeeDevNewCallback, lostcb ZigbeeDevLostCallback) *Zigbee {
+	return &Zigbee{
+		devices: make(map[string]*ZigbeeDevice),
+		newCb:   newcb,
+		lostCb:  lostcb,
+	}
+}
+
+func (z *Zigbee) MarshalJSON() ([]byte, error) {
+	doc := zigbeeJSON{
+		Devices: z.Devices(),
+	}
+	return json.Marshal(doc)
+}
+
+func (z *Zigbee) Get(address string) (dev *ZigbeeDevice, found bool) {
+	z.RLock()
+	defer z.RUnlock()
+	dev, found = z.devices[address]
+	return
+}
+
+func (z *Zigbee) AddIfNew(address string, pan uint16, channel int) (bool, *ZigbeeDevice) {
+	z.Lock()
+	defer z.Unlock()
+
+	if dev, found := z.devices[address]; found {
+		dev.Lock()
+		dev.LastSeen = time.Now()
+		dev.PAN = pan
+		dev.Channel = channel
+		dev.Frames++
+		dev.Unlock()
+		return false, dev
+	}
+
+	newDev := NewZigbeeDevice(address, pan, channel)
+	z.devices[address] = newDev
+
+	if z.newCb != nil {
+		z.newCb(newDev)
+	}
+
+	return true, newDev
+}
+
+func (z *Zigbee) Remove(address string) {
+	z.Lock()
+	defer z.Unlock()
+
+	if dev, found := z.devices[address]; found {
+		delete(z.devices, address)
+		if z.lostCb != nil {
+			z.lostCb(dev)
+		}
+	}
+}
+
+func (z *Zigbee) Devices() (devices []*ZigbeeDevice) {
+	z.RLock()
+	defer z.RUnlock()
+
+	devices = make([]*ZigbeeDevice, 0)
+	for _, dev := range z.devices {
+		devices = append(devices, dev)
+	}
+	return
+}
+
+func (z *Zigbee) EachDevice(cb func(address string, d *ZigbeeDevice)) {
+	z.RLock()
+	defer z.RUnlock()
+
+	for a, dev := range z.devices {
+		cb(a, dev)
+	}
+}
+
+func (z *Zigbee) Clear() {
+	z.Lock()
+	defer z.Unlock()
+	z.devices = make(map[string]*ZigbeeDevice)
+}
@@ -0,0 +1,75 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type ZigbeeDevice struct {
+	sync.Mutex
+	FirstSeen   time.Time
+	LastSeen    time.Time
+	Address     string
+	PAN         uint16
+	ExtPAN      string
+	Channel     int
+	Coordinator bool
+	PermitJoin  bool
+	Encrypted   bool
+	Frames      uint64
+}
+
+type zigbeeDeviceJSON struct {
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	Address     string    `json:"address"`
+	PAN         string    `json:"pan"`
+	ExtPAN      string    `json:"ext_pan"`
+	Channel     int       `json:"channel"`
+	Coordinator bool      `json:"coordinator"`
+	PermitJoin  bool      `json:"permit_join"`
+	Encrypted   bool      `json:"encrypted"`
+	Frames      uint64    `json:"frames"`
+}
+
+func ZigbeePAN(pan uint16) string {
+	return fmt.Sprintf("0x%04x", pan)
+}
+
+func NewZigbeeDevice(address string, pan uint16, channel int) *ZigbeeDevice {
+	now := time.Now()
+	return &ZigbeeDevice{
+		FirstSeen: now,
+		LastSeen:  now,
+		Address:   address,
+		PAN:       pan,
+		Channel:   channel,
+		Frames:    1,
+	}
+}
+
+func (dev *ZigbeeDevice) PANString() string {
+	return ZigbeePAN(dev.PAN)
+}
+
+func (dev *ZigbeeDevice) MarshalJSON() ([]byte, error) {
+	dev.Lock()
+	defer dev.Unlock()
+
+	doc := zigbeeDeviceJSON{
+		FirstSeen:   dev.FirstSeen,
+		LastSeen:    dev.LastSeen,
+		Address:     dev.Address,
+		PAN:         ZigbeePAN(dev.PAN),
+		ExtPAN:      dev.ExtPAN,
+		Channel:     dev.Channel,
+		Coordinator: dev.Coordinator,
+		PermitJoin:  dev.PermitJoin,
+		Encrypted:   dev.Encrypted,
+		Frames:      dev.Frames,
+	}
+
+	return json.Marshal(doc)
+}
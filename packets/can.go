@@ -0,0 +1,127 @@
+package packets
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	CANFrameSize = 16
+	CANMaxDLC    = 8
+
+	CANEffFlag = 0x80000000
+	CANRtrFlag = 0x40000000
+	CANErrFlag = 0x20000000
+
+	CANSffMask = 0x000007ff
+	CANEffMask = 0x1fffffff
+)
+
+// CANFrame is a classic CAN frame, with the same layout of
+// the linux SocketCAN can_frame structure when serialized.
+type CANFrame struct {
+	ID       uint32
+	Extended bool
+	Remote   bool
+	Error    bool
+	Data     []byte
+}
+
+func (f CANFrame) IDString() string {
+	if f.Extended {
+		return fmt.Sprintf("%08x", f.ID)
+	}
+	return fmt.Sprintf("%03x", f.ID)
+}
+
+// String returns the frame in the candump/cansend ID#DATA notation.
+func (f CANFrame) String() string {
+	if f.Remote {
+		return fmt.Sprintf("%s#R", f.IDString())
+	}
+	return fmt.Sprintf("%s#%s", f.IDString(), strings.ToUpper(hex.EncodeToString(f.Data)))
+}
+
+func (f CANFrame) Marshal() []byte {
+	raw := make([]byte, CANFrameSize)
+	id := f.ID & CANSffMask
+	if f.Extended {
+		id = (f.ID & CANEffMask) | CANEffFlag
+	}
+	if f.Remote {
+		id |= CANRtrFlag
+	}
+	// SocketCAN uses host byte order, which is little endian on every supported platform
+	binary.LittleEndian.PutUint32(raw[0:4], id)
+	raw[4] = byte(len(f.Data))
+	copy(raw[8:], f.Data)
+	return raw
+}
+
+func CANUnmarshal(raw []byte) (error, *CANFrame) {
+	if len(raw) < CANFrameSize {
+		return fmt.Errorf("CAN frame too short (%d bytes)", len(raw)), nil
+	}
+
+	id := binary.LittleEndian.Uint32(raw[0:4])
+	dlc := int(raw[4])
+	if dlc > CANMaxDLC {
+		return fmt.Errorf("invalid CAN frame DLC %d", dlc), nil
+	}
+
+	f := &CANFrame{
+		Extended: id&CANEffFlag != 0,
+		Remote:   id&CANRtrFlag != 0,
+		Error:    id&CANErrFlag != 0,
+		Data:     make([]byte, dlc),
+	}
+
+	if f.Extended {
+		f.ID = id & CANEffMask
+	} else {
+		f.ID = id & CANSffMask
+	}
+	copy(f.Data, raw[8:8+dlc])
+
+	return nil, f
+}
+
+// CANParse parses a frame in the cansend notation, e.g. 123#DEADBEEF,
+// 1F334455#11.22.33 or 123#R for remote transmission requests.
+func CANParse(s string) (error, *CANFrame) {
+	parts := strings.SplitN(strings.TrimSpace(s), "#", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("invalid CAN frame '%s', expected ID#DATA", s), nil
+	}
+
+	id, err := strconv.ParseUint(parts[0], 16, 32)
+	if err != nil {
+		return fmt.Errorf("invalid CAN id '%s': %v", parts[0], err), nil
+	}
+
+	f := &CANFrame{
+		ID:       uint32(id),
+		Extended: len(parts[0]) > 3 || id > CANSffMask,
+		Data:     []byte{},
+	}
+
+	if f.ID > CANEffMask {
+		return fmt.Errorf("CAN id %s out of range", parts[0]), nil
+	}
+
+	if payload := strings.ToUpper(parts[1]); payload == "R" {
+		f.Remote = true
+	} else if payload != "" {
+		payload = strings.Replace(payload, ".", "", -1)
+		if f.Data, err = hex.DecodeString(payload); err != nil {
+			return fmt.Errorf("invalid CAN data '%s': %v", parts[1], err), nil
+		} else if len(f.Data) > CANMaxDLC {
+			return fmt.Errorf("CAN data can't be longer than %d bytes", CANMaxDLC), nil
+		}
+	}
+
+	return nil, f
+}
@@ -0,0 +1,56 @@
+package packets
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCANParse(t *testing.T) {
+	cases := []struct {
+		in       string
+		id       uint32
+		extended bool
+		remote   bool
+		data     []byte
+		out      string
+	}{
+		{"123#DEADBEEF", 0x123, false, false, []byte{0xde, 0xad, 0xbe, 0xef}, "123#DEADBEEF"},
+		{"1F334455#11.22.33", 0x1f334455, true, false, []byte{0x11, 0x22, 0x33}, "1f334455#112233"},
+		{"7df#R", 0x7df, false, true, []byte{}, "7df#R"},
+		{"001#", 0x001, false, false, []byte{}, "001#"},
+	}
+
+	for _, c := range cases {
+		err, f := CANParse(c.in)
+		if err != nil {
+			t.Fatalf("%s: %v", c.in, err)
+		} else if f.ID != c.id || f.Extended != c.extended || f.Remote != c.remote || !bytes.Equal(f.Data, c.data) {
+			t.Fatalf("%s: unexpected frame %+v", c.in, f)
+		} else if f.String() != c.out {
+			t.Fatalf("%s: expected %s, got %s", c.in, c.out, f.String())
+		}
+	}
+
+	for _, bad := range []string{"", "123", "#00", "xyz#00", "123#001122334455667788", "123#0"} {
+		if err, _ := CANParse(bad); err == nil {
+			t.Fatalf("expected error for '%s'", bad)
+		}
+	}
+}
+
+func TestCANMarshal(t *testing.T) {
+	_, f := CANParse("1F334455#0102")
+	raw := f.Marshal()
+	if len(raw) != CANFrameSize {
+		t.Fatalf("unexpected size %d", len(raw))
+	} else if raw[3]&0x80 == 0 || raw[4] != 2 {
+		t.Fatalf("unexpected encoding %x", raw)
+	}
+
+	err, back := CANUnmarshal(raw)
+	if err != nil {
+		t.Fatal(err)
+	} else if back.String() != f.String() {
+		t.Fatalf("expected %s, got %s", f, back)
+	}
+}
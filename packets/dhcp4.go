@@ -0,0 +1,196 @@
+package packets
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// DHCP4Lease holds the parameters of an address lease offered to a client.
+type DHCP4Lease struct {
+	ServerIP net.IP
+	ServerHW net.HardwareAddr
+	ClientIP net.IP
+	Mask     net.IPMask
+	Router   net.IP
+	DNS      net.IP
+	Duration time.Duration
+}
+
+// DHCP4Option returns the value of the first option of the given type or nil.
+func DHCP4Option(req *layers.DHCPv4, t layers.DHCPOpt) []byte {
+	for _, opt := range req.Options {
+		if opt.Type == t {
+			return opt.Data
+		}
+	}
+	return nil
+}
+
+// DHCP4MessageType returns the message type of a DHCPv4 packet or 0.
+func DHCP4MessageType(req *layers.DHCPv4) layers.DHCPMsgType {
+	if data := DHCP4Option(req, layers.DHCPOptMessageType); len(data) == 1 {
+		return layers.DHCPMsgType(data[0])
+	}
+	return layers.DHCPMsgTypeUnspecified
+}
+
+// DHCP4RequestedIP returns the address a client is asking for or nil.
+func DHCP4RequestedIP(req *layers.DHCPv4) net.IP {
+	if data := DHCP4Option(req, layers.DHCPOptRequestIP); len(data) == net.IPv4len {
+		return net.IP(data)
+	} else if !req.ClientIP.Equal(net.IPv4zero) {
+		return req.ClientIP
+	}
+	return nil
+}
+
+// DHCP4Fingerprint returns the parameter request list of a DHCPv4 packet as a
+// comma separated list of option numbers, the format used to fingerprint clients.
+func DHCP4Fingerprint(req *layers.DHCPv4) string {
+	params := DHCP4Option(req, layers.DHCPOptParamsRequest)
+	options := make([]string, len(params))
+	for i, opt := range params {
+		options[i] = strconv.Itoa(int(opt))
+	}
+	return strings.Join(options, ",")
+}
+
+// DHCP4GetMeta returns the address and the fingerprinting metadata of
+// a client sending a DISCOVER or REQUEST, or nil if it's not one.
+func DHCP4GetMeta(pkt gopacket.Packet) (net.IP, map[string]string) {
+	ldhcp := pkt.Layer(layers.LayerTypeDHCPv4)
+	if ldhcp == nil {
+		return nil, nil
+	}
+
+	req := ldhcp.(*layers.DHCPv4)
+	if req.Operation != layers.DHCPOpRequest {
+		return nil, nil
+	} else if t := DHCP4MessageType(req); t != layers.DHCPMsgTypeDiscover && t != layers.DHCPMsgTypeRequest {
+		return nil, nil
+	}
+
+	meta := make(map[string]string)
+	fingerprint := DHCP4Fingerprint(req)
+	vendor := string(DHCP4Option(req, layers.DHCPOptClassID))
+
+	if fingerprint != "" {
+		meta["dhcp:fingerprint"] = fingerprint
+	}
+	if vendor != "" {
+		meta["dhcp:vendor"] = vendor
+	}
+	if hostname := DHCP4Option(req, layers.DHCPOptHostname); len(hostname) > 0 {
+		meta["dhcp:hostname"] = string(hostname)
+	}
+	if fp := network.DHCPFingerprintLookup(fingerprint, vendor); fp != nil {
+		meta["dhcp:device"] = fp.Device
+		meta["dhcp:os"] = fp.OS
+	}
+
+	if len(meta) == 0 {
+		return nil, nil
+	}
+	return DHCP4RequestedIP(req), meta
+}
+
+// NewDHCP4Reply creates a broadcast DHCPv4 reply of the given type to a client request.
+func NewDHCP4Reply(req *layers.DHCPv4, t layers.DHCPMsgType, lease DHCP4Lease) (error, []byte) {
+	seconds := make([]byte, 4)
+	binary.BigEndian.PutUint32(seconds, uint32(lease.Duration/time.Second))
+
+	eth := layers.Ethernet{
+		SrcMAC:       lease.ServerHW,
+		DstMAC:       net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    lease.ServerIP,
+		DstIP:    net.IPv4bcast,
+	}
+
+	udp := layers.UDP{
+		SrcPort: 67,
+		DstPort: 68,
+	}
+	udp.SetNetworkLayerForChecksum(&ip4)
+
+	reply := layers.DHCPv4{
+		Operation:    layers.DHCPOpReply,
+		HardwareType: layers.LinkTypeEthernet,
+		Xid:          req.Xid,
+		Flags:        req.Flags,
+		RelayAgentIP: req.RelayAgentIP,
+		ClientHWAddr: req.ClientHWAddr,
+		Options: layers.DHCPOptions{
+			layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(t)}),
+			layers.NewDHCPOption(layers.DHCPOptServerID, lease.ServerIP.To4()),
+		},
+	}
+
+	if t != layers.DHCPMsgTypeNak {
+		reply.YourClientIP = lease.ClientIP
+		reply.Options = append(reply.Options,
+			layers.NewDHCPOption(layers.DHCPOptLeaseTime, seconds),
+			layers.NewDHCPOption(layers.DHCPOptSubnetMask, lease.Mask),
+			layers.NewDHCPOption(layers.DHCPOptRouter, lease.Router.To4()),
+			layers.NewDHCPOption(layers.DHCPOptDNS, lease.DNS.To4()))
+	}
+
+	return Serialize(&eth, &ip4, &udp, &reply)
+}
+
+// NewDHCP4Discover creates a broadcast DHCPv4 DISCOVER from the given address,
+// asking the servers to broadcast their offers.
+func NewDHCP4Discover(hw net.HardwareAddr, xid uint32) (error, []byte) {
+	eth := layers.Ethernet{
+		SrcMAC:       hw,
+		DstMAC:       net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.IPv4zero,
+		DstIP:    net.IPv4bcast,
+	}
+
+	udp := layers.UDP{
+		SrcPort: 68,
+		DstPort: 67,
+	}
+	udp.SetNetworkLayerForChecksum(&ip4)
+
+	discover := layers.DHCPv4{
+		Operation:    layers.DHCPOpRequest,
+		HardwareType: layers.LinkTypeEthernet,
+		HardwareLen:  6,
+		Xid:          xid,
+		Flags:        0x8000,
+		ClientHWAddr: hw,
+		Options: layers.DHCPOptions{
+			layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(layers.DHCPMsgTypeDiscover)}),
+			layers.NewDHCPOption(layers.DHCPOptParamsRequest, []byte{
+				byte(layers.DHCPOptSubnetMask),
+				byte(layers.DHCPOptRouter),
+				byte(layers.DHCPOptDNS),
+			}),
+		},
+	}
+
+	return Serialize(&eth, &ip4, &udp, &discover)
+}
@@ -0,0 +1,182 @@
+package packets
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestNewDHCP4Reply(t *testing.T) {
+	server, _ := net.ParseMAC("01:23:45:67:89:ab")
+	client, _ := net.ParseMAC("01:23:45:67:89:ac")
+	req := &layers.DHCPv4{
+		Operation:    layers.DHCPOpRequest,
+		Xid:          0xdeadbeef,
+		ClientIP:     net.IPv4zero,
+		ClientHWAddr: client,
+		Options: layers.DHCPOptions{
+			layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(layers.DHCPMsgTypeRequest)}),
+			layers.NewDHCPOption(layers.DHCPOptRequestIP, []byte{10, 0, 0, 23}),
+		},
+	}
+
+	if mt := DHCP4MessageType(req); mt != layers.DHCPMsgTypeRequest {
+		t.Fatalf("expected request message type, got %s", mt)
+	} else if ip := DHCP4RequestedIP(req); !ip.Equal(net.IP{10, 0, 0, 23}) {
+		t.Fatalf("unexpected requested address %s", ip)
+	}
+
+	lease := DHCP4Lease{
+		ServerIP: net.IP{10, 0, 0, 1},
+		ServerHW: server,
+		ClientIP: net.IP{10, 0, 0, 23},
+		Mask:     net.CIDRMask(24, 32),
+		Router:   net.IP{10, 0, 0, 1},
+		DNS:      net.IP{10, 0, 0, 1},
+		Duration: time.Hour,
+	}
+
+	err, raw := NewDHCP4Reply(req, layers.DHCPMsgTypeAck, lease)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	layer := pkt.Layer(layers.LayerTypeDHCPv4)
+	if layer == nil {
+		t.Fatal("expected DHCPv4 layer")
+	}
+
+	reply := layer.(*layers.DHCPv4)
+	if reply.Xid != req.Xid {
+		t.Fatalf("expected xid 0x%x, got 0x%x", req.Xid, reply.Xid)
+	} else if !reply.YourClientIP.Equal(lease.ClientIP) {
+		t.Fatalf("expected address %s, got %s", lease.ClientIP, reply.YourClientIP)
+	} else if mt := DHCP4MessageType(reply); mt != layers.DHCPMsgTypeAck {
+		t.Fatalf("expected ack message type, got %s", mt)
+	} else if router := DHCP4Option(reply, layers.DHCPOptRouter); !net.IP(router).Equal(lease.Router) {
+		t.Fatalf("unexpected router option %v", router)
+	}
+}
+
+func TestNewDHCP4Nak(t *testing.T) {
+	server, _ := net.ParseMAC("01:23:45:67:89:ab")
+	client, _ := net.ParseMAC("01:23:45:67:89:ac")
+	req := &layers.DHCPv4{
+		Operation:    layers.DHCPOpRequest,
+		Xid:          0xdeadbeef,
+		ClientHWAddr: client,
+	}
+
+	// a NAK is sent when there's no address to lease
+	lease := DHCP4Lease{
+		ServerIP: net.IP{10, 0, 0, 1},
+		ServerHW: server,
+	}
+
+	err, raw := NewDHCP4Reply(req, layers.DHCPMsgTypeNak, lease)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default).Layer(layers.LayerTypeDHCPv4)
+	if layer == nil {
+		t.Fatal("expected DHCPv4 layer")
+	}
+
+	reply := layer.(*layers.DHCPv4)
+	if mt := DHCP4MessageType(reply); mt != layers.DHCPMsgTypeNak {
+		t.Fatalf("expected nak message type, got %s", mt)
+	} else if reply.YourClientIP != nil && !reply.YourClientIP.Equal(net.IPv4zero) {
+		t.Fatalf("unexpected address %s", reply.YourClientIP)
+	}
+}
+
+func TestDHCP4GetMeta(t *testing.T) {
+	client, _ := net.ParseMAC("01:23:45:67:89:ac")
+
+	eth := layers.Ethernet{
+		SrcMAC:       client,
+		DstMAC:       net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip4 := layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.IPv4zero,
+		DstIP:    net.IPv4bcast,
+	}
+	udp := layers.UDP{
+		SrcPort: 68,
+		DstPort: 67,
+	}
+	udp.SetNetworkLayerForChecksum(&ip4)
+	req := layers.DHCPv4{
+		Operation:    layers.DHCPOpRequest,
+		HardwareType: layers.LinkTypeEthernet,
+		Xid:          0xdeadbeef,
+		ClientHWAddr: client,
+		Options: layers.DHCPOptions{
+			layers.NewDHCPOption(layers.DHCPOptMessageType, []byte{byte(layers.DHCPMsgTypeRequest)}),
+			layers.NewDHCPOption(layers.DHCPOptRequestIP, []byte{10, 0, 0, 23}),
+			layers.NewDHCPOption(layers.DHCPOptHostname, []byte("workstation")),
+			layers.NewDHCPOption(layers.DHCPOptClassID, []byte("MSFT 5.0")),
+			layers.NewDHCPOption(layers.DHCPOptParamsRequest, []byte{1, 3, 6, 15, 31, 33, 43, 44, 46, 47, 119, 121, 249, 252}),
+		},
+	}
+
+	err, raw := Serialize(&eth, &ip4, &udp, &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, meta := DHCP4GetMeta(gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default))
+	if meta == nil {
+		t.Fatal("expected metadata")
+	} else if !addr.Equal(net.IP{10, 0, 0, 23}) {
+		t.Fatalf("unexpected address %s", addr)
+	}
+
+	expected := map[string]string{
+		"dhcp:fingerprint": "1,3,6,15,31,33,43,44,46,47,119,121,249,252",
+		"dhcp:vendor":      "MSFT 5.0",
+		"dhcp:hostname":    "workstation",
+		"dhcp:device":      "Desktop",
+		"dhcp:os":          "Windows 10",
+	}
+	for name, value := range expected {
+		if meta[name] != value {
+			t.Fatalf("expected %s for %s, got '%s'", value, name, meta[name])
+		}
+	}
+}
+
+func TestNewDHCP4Discover(t *testing.T) {
+	client, _ := net.ParseMAC("01:23:45:67:89:ac")
+
+	err, raw := NewDHCP4Discover(client, 0xcafebabe)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	layer := pkt.Layer(layers.LayerTypeDHCPv4)
+	if layer == nil {
+		t.Fatal("expected DHCPv4 layer")
+	}
+
+	discover := layer.(*layers.DHCPv4)
+	if discover.Xid != 0xcafebabe {
+		t.Fatalf("expected xid 0xcafebabe, got 0x%x", discover.Xid)
+	} else if mt := DHCP4MessageType(discover); mt != layers.DHCPMsgTypeDiscover {
+		t.Fatalf("expected discover message type, got %s", mt)
+	} else if discover.Flags&0x8000 == 0 {
+		t.Fatal("expected the broadcast flag")
+	} else if discover.ClientHWAddr.String() != client.String() {
+		t.Fatalf("unexpected client address %s", discover.ClientHWAddr)
+	}
+}
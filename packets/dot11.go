@@ -52,6 +52,12 @@ func Dot11Info(id layers.Dot11InformationElementID, info []byte) *layers.Dot11In
 }
 
 func NewDot11Beacon(conf Dot11ApConfig, seq uint16) (error, []byte) {
+	return NewDot11BeaconAt(conf, seq, 0)
+}
+
+// NewDot11BeaconAt creates a beacon frame advertising the tsf
+// timestamp (microseconds since the access point started).
+func NewDot11BeaconAt(conf Dot11ApConfig, seq uint16, tsf uint64) (error, []byte) {
 	flags := openFlags
 	if conf.Encryption {
 		flags = wpaFlags
@@ -70,8 +76,9 @@ func NewDot11Beacon(conf Dot11ApConfig, seq uint16) (error, []byte) {
 			SequenceNumber: seq,
 		},
 		&layers.Dot11MgmtBeacon{
-			Flags:    uint16(flags),
-			Interval: 100,
+			Timestamp: tsf,
+			Flags:     uint16(flags),
+			Interval:  100,
 		},
 		Dot11Info(layers.Dot11InformationElementIDSSID, []byte(conf.SSID)),
 		Dot11Info(layers.Dot11InformationElementIDRates, fakeApRates),
@@ -156,6 +163,22 @@ func NewDot11AssociationRequest(sta net.HardwareAddr, apBSSID net.HardwareAddr,
 	)
 }
 
+func NewDot11ProbeRequest(sta net.HardwareAddr, apBSSID net.HardwareAddr, apESSID string, seq uint16) (error, []byte) {
+	return Serialize(
+		&layers.RadioTap{},
+		&layers.Dot11{
+			Address1:       apBSSID,
+			Address2:       sta,
+			Address3:       apBSSID,
+			Type:           layers.Dot11TypeMgmtProbeReq,
+			SequenceNumber: seq,
+		},
+		// the probe request body is made of information elements only
+		Dot11Info(layers.Dot11InformationElementIDSSID, []byte(apESSID)),
+		Dot11Info(layers.Dot11InformationElementIDRates, assocRates),
+	)
+}
+
 func Dot11Parse(packet gopacket.Packet) (ok bool, radiotap *layers.RadioTap, dot11 *layers.Dot11) {
 	ok = false
 	radiotap = nil
@@ -291,3 +314,21 @@ func Dot11ParseEAPOL(packet gopacket.Packet, dot11 *layers.Dot11) (ok bool, key
 	}
 	return
 }
+
+// Dot11EAPOLRaw returns the EAPOL-Key layer of the packet and the raw EAPOL
+// frame it belongs to, as needed to verify its MIC.
+func Dot11EAPOLRaw(pkt gopacket.Packet) (*layers.EAPOLKey, []byte) {
+	lkey := pkt.Layer(layers.LayerTypeEAPOLKey)
+	leapol := pkt.Layer(layers.LayerTypeEAPOL)
+	if lkey == nil || leapol == nil {
+		return nil, nil
+	}
+
+	eapol := leapol.(*layers.EAPOL)
+	raw := append(append([]byte{}, eapol.Contents...), eapol.Payload...)
+	if size := 4 + int(eapol.Length); size <= len(raw) {
+		raw = raw[:size]
+	}
+
+	return lkey.(*layers.EAPOLKey), raw
+}
@@ -0,0 +1,341 @@
+package packets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	// offset and size of the MIC field inside a raw EAPOL-Key frame
+	eapolMICOffset = 81
+	eapolMICSize   = 16
+
+	ccmpHeaderSize = 8
+	ccmpMICSize    = 8
+)
+
+var (
+	gtkKDESelector = []byte{0x00, 0x0f, 0xac, 0x01}
+	llcSNAPHeader  = []byte{0xaa, 0xaa, 0x03, 0x00, 0x00, 0x00}
+)
+
+// Dot11Keys holds the keys derived from a WPA2-PSK 4-way handshake.
+type Dot11Keys struct {
+	PMK []byte
+	KCK []byte
+	KEK []byte
+	TK  []byte
+}
+
+func pbkdf2SHA1(password, salt []byte, iterations, size int) []byte {
+	prf := hmac.New(sha1.New, password)
+	out := make([]byte, 0, size)
+	for block := uint32(1); len(out) < size; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(nil)
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		out = append(out, t...)
+	}
+	return out[:size]
+}
+
+// Dot11PMK derives the pairwise master key from a WPA passphrase and the network SSID.
+func Dot11PMK(passphrase, ssid string) []byte {
+	return pbkdf2SHA1([]byte(passphrase), []byte(ssid), 4096, 32)
+}
+
+// Dot11PMKID computes the PMKID an access point sends in the first message of
+// the handshake, which can be used to verify a PMK without a client.
+func Dot11PMKID(pmk []byte, ap, sta net.HardwareAddr) []byte {
+	mac := hmac.New(sha1.New, pmk)
+	mac.Write([]byte("PMK Name"))
+	mac.Write(ap)
+	mac.Write(sta)
+	return mac.Sum(nil)[:16]
+}
+
+func prf512(key []byte, label string, data []byte) []byte {
+	out := make([]byte, 0, 64)
+	for i := byte(0); len(out) < 64; i++ {
+		mac := hmac.New(sha1.New, key)
+		mac.Write([]byte(label))
+		mac.Write([]byte{0x00})
+		mac.Write(data)
+		mac.Write([]byte{i})
+		out = append(out, mac.Sum(nil)...)
+	}
+	return out[:64]
+}
+
+func minMax(a, b []byte) ([]byte, []byte) {
+	if bytes.Compare(a, b) < 0 {
+		return a, b
+	}
+	return b, a
+}
+
+// Dot11DeriveKeys derives the pairwise transient keys given the PMK, the addresses
+// of the access point and the station and the nonces of the handshake.
+func Dot11DeriveKeys(pmk []byte, ap, sta net.HardwareAddr, anonce, snonce []byte) *Dot11Keys {
+	loMac, hiMac := minMax(ap, sta)
+	loNonce, hiNonce := minMax(anonce, snonce)
+
+	data := make([]byte, 0, 76)
+	data = append(data, loMac...)
+	data = append(data, hiMac...)
+	data = append(data, loNonce...)
+	data = append(data, hiNonce...)
+
+	ptk := prf512(pmk, "Pairwise key expansion", data)
+	return &Dot11Keys{
+		PMK: pmk,
+		KCK: ptk[0:16],
+		KEK: ptk[16:32],
+		TK:  ptk[32:48],
+	}
+}
+
+// Dot11VerifyMIC checks the MIC of a raw EAPOL-Key frame (HMAC-SHA1 descriptors only),
+// which tells us whether the PSK used to derive the keys is correct.
+func Dot11VerifyMIC(kck []byte, eapol []byte) (error, bool) {
+	if len(eapol) < eapolMICOffset+eapolMICSize+2 {
+		return fmt.Errorf("EAPOL frame too short (%d bytes)", len(eapol)), false
+	} else if version := eapol[6] & 0x07; version != 2 {
+		return fmt.Errorf("unsupported key descriptor version %d", version), false
+	}
+
+	frame := make([]byte, len(eapol))
+	copy(frame, eapol)
+	mic := make([]byte, eapolMICSize)
+	copy(mic, frame[eapolMICOffset:])
+	copy(frame[eapolMICOffset:eapolMICOffset+eapolMICSize], make([]byte, eapolMICSize))
+
+	h := hmac.New(sha1.New, kck)
+	h.Write(frame)
+
+	return nil, hmac.Equal(h.Sum(nil)[:eapolMICSize], mic)
+}
+
+// Dot11AESUnwrap implements the RFC 3394 AES key unwrap algorithm.
+func Dot11AESUnwrap(kek, wrapped []byte) (error, []byte) {
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return fmt.Errorf("invalid wrapped key size %d", len(wrapped)), nil
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return err, nil
+	}
+
+	n := len(wrapped)/8 - 1
+	a := make([]byte, 8)
+	copy(a, wrapped[:8])
+	r := make([]byte, n*8)
+	copy(r, wrapped[8:])
+
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(a)^t)
+			copy(buf[8:], r[(i-1)*8:i*8])
+			block.Decrypt(buf, buf)
+			copy(a, buf[:8])
+			copy(r[(i-1)*8:i*8], buf[8:])
+		}
+	}
+
+	for _, b := range a {
+		if b != 0xa6 {
+			return fmt.Errorf("key unwrap integrity check failed"), nil
+		}
+	}
+
+	return nil, r
+}
+
+// Dot11ParseGTK extracts the group temporal key and its index from the decrypted key data of M3.
+func Dot11ParseGTK(keyData []byte) (error, []byte, int) {
+	for len(keyData) >= 2 {
+		t, size := keyData[0], int(keyData[1])
+		if 2+size > len(keyData) {
+			break
+		}
+
+		// skip the RSN information element and any other non KDE element
+		kde := keyData[2 : 2+size]
+		if t == 0xdd && len(kde) > 6 && bytes.Equal(kde[:4], gtkKDESelector) {
+			return nil, kde[6:], int(kde[4] & 0x03)
+		}
+
+		keyData = keyData[2+size:]
+	}
+
+	return fmt.Errorf("GTK not found in key data"), nil, 0
+}
+
+func ccmpNonce(a2 net.HardwareAddr, pn uint64) []byte {
+	nonce := make([]byte, 13)
+	copy(nonce[1:7], a2)
+	for i := 0; i < 6; i++ {
+		nonce[7+i] = byte(pn >> uint(8*(5-i)))
+	}
+	return nonce
+}
+
+// ccmEncrypt implements AES-CCM with an 8 bytes MIC and a 2 bytes length field.
+func ccmEncrypt(key, nonce, aad, plaintext []byte) (error, []byte) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err, nil
+	}
+
+	// CBC-MAC over B0, the additional authenticated data and the plaintext
+	mac := make([]byte, 16)
+	b0 := make([]byte, 16)
+	b0[0] = 0x59
+	copy(b0[1:14], nonce)
+	binary.BigEndian.PutUint16(b0[14:], uint16(len(plaintext)))
+	block.Encrypt(mac, b0)
+
+	authData := make([]byte, 2, 2+len(aad))
+	binary.BigEndian.PutUint16(authData, uint16(len(aad)))
+	authData = append(authData, aad...)
+
+	cbc := func(data []byte) {
+		for off := 0; off < len(data); off += 16 {
+			for i := 0; i < 16 && off+i < len(data); i++ {
+				mac[i] ^= data[off+i]
+			}
+			block.Encrypt(mac, mac)
+		}
+	}
+	cbc(authData)
+	cbc(plaintext)
+
+	// CTR encryption of the plaintext and of the MIC
+	ctr := make([]byte, 16)
+	ctr[0] = 0x01
+	copy(ctr[1:14], nonce)
+	stream := make([]byte, 16)
+
+	out := make([]byte, len(plaintext)+ccmpMICSize)
+	for off, counter := 0, uint16(1); off < len(plaintext); off, counter = off+16, counter+1 {
+		binary.BigEndian.PutUint16(ctr[14:], counter)
+		block.Encrypt(stream, ctr)
+		for i := 0; i < 16 && off+i < len(plaintext); i++ {
+			out[off+i] = plaintext[off+i] ^ stream[i]
+		}
+	}
+
+	binary.BigEndian.PutUint16(ctr[14:], 0)
+	block.Encrypt(stream, ctr)
+	for i := 0; i < ccmpMICSize; i++ {
+		out[len(plaintext)+i] = mac[i] ^ stream[i]
+	}
+
+	return nil, out
+}
+
+// Dot11CCMPEncrypt protects a non QoS 802.11 data frame (24 bytes header followed
+// by the plaintext body) with CCMP using the given temporal key, packet number and key index.
+func Dot11CCMPEncrypt(tk []byte, frame []byte, pn uint64, keyIdx int) (error, []byte) {
+	if len(frame) < 24 {
+		return fmt.Errorf("frame too short (%d bytes)", len(frame)), nil
+	}
+
+	header := make([]byte, 24)
+	copy(header, frame[:24])
+	// set the protected flag
+	header[1] |= 0x40
+
+	aad := make([]byte, 22)
+	aad[0] = header[0] & 0x8f
+	aad[1] = header[1] & 0xc7
+	copy(aad[2:20], header[4:22])
+	aad[20] = header[22] & 0x0f
+
+	err, encrypted := ccmEncrypt(tk, ccmpNonce(header[10:16], pn), aad, frame[24:])
+	if err != nil {
+		return err, nil
+	}
+
+	ccmp := []byte{
+		byte(pn),
+		byte(pn >> 8),
+		0x00,
+		0x20 | byte(keyIdx&0x03)<<6,
+		byte(pn >> 16),
+		byte(pn >> 24),
+		byte(pn >> 32),
+		byte(pn >> 40),
+	}
+
+	out := make([]byte, 0, len(header)+ccmpHeaderSize+len(encrypted))
+	out = append(out, header...)
+	out = append(out, ccmp...)
+	return nil, append(out, encrypted...)
+}
+
+// Dot11CCMPPacketNumber returns the packet number from the CCMP header of a protected frame body.
+func Dot11CCMPPacketNumber(body []byte) (bool, uint64) {
+	if len(body) < ccmpHeaderSize || body[3]&0x20 == 0 {
+		return false, 0
+	}
+	pn := uint64(body[0]) |
+		uint64(body[1])<<8 |
+		uint64(body[4])<<16 |
+		uint64(body[5])<<24 |
+		uint64(body[6])<<32 |
+		uint64(body[7])<<40
+	return true, pn
+}
+
+// NewDot11GroupData creates a group addressed data frame sent from the access point
+// with the given BSSID, carrying an ethernet payload and protected with the GTK.
+func NewDot11GroupData(bssid, src, dst net.HardwareAddr, etherType layers.EthernetType, payload []byte, gtk []byte, keyIdx int, pn uint64, seq uint16) (error, []byte) {
+	header := make([]byte, 24)
+	// data frame, FromDS
+	header[0] = 0x08
+	header[1] = 0x02
+	copy(header[4:10], dst)
+	copy(header[10:16], bssid)
+	copy(header[16:22], src)
+	binary.LittleEndian.PutUint16(header[22:24], seq<<4)
+
+	frame := make([]byte, 0, len(header)+len(llcSNAPHeader)+2+len(payload))
+	frame = append(frame, header...)
+	frame = append(frame, llcSNAPHeader...)
+	frame = append(frame, byte(etherType>>8), byte(etherType))
+	frame = append(frame, payload...)
+
+	err, protected := Dot11CCMPEncrypt(gtk, frame, pn, keyIdx)
+	if err != nil {
+		return err, nil
+	}
+
+	err, radiotap := Serialize(&layers.RadioTap{})
+	if err != nil {
+		return err, nil
+	}
+
+	return nil, append(radiotap, protected...)
+}
@@ -0,0 +1,114 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestDot11PMK(t *testing.T) {
+	// IEEE 802.11i-2004 test vector
+	exp := unhex(t, "f42c6fc52df0ebef9ebb4b90b38a5f902e83fe1b135a70e23aed762e9710a12e")
+	if got := Dot11PMK("password", "IEEE"); !bytes.Equal(got, exp) {
+		t.Fatalf("expected %x, got %x", exp, got)
+	}
+}
+
+func TestDot11PMKID(t *testing.T) {
+	// hashcat example hash for mode 22000 (WPA*01*...)
+	pmk := Dot11PMK("hashcat!", "hashcat-essid")
+	ap, _ := net.ParseMAC("fc:69:0c:15:82:64")
+	sta, _ := net.ParseMAC("f4:74:7f:87:f9:f4")
+	exp := unhex(t, "4d4fe7aac3a2cecab195321ceb99a7d0")
+	if got := Dot11PMKID(pmk, ap, sta); !bytes.Equal(got, exp) {
+		t.Fatalf("expected %x, got %x", exp, got)
+	}
+}
+
+func TestDot11AESUnwrap(t *testing.T) {
+	// RFC 3394 section 4.1
+	kek := unhex(t, "000102030405060708090a0b0c0d0e0f")
+	wrapped := unhex(t, "1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5")
+	exp := unhex(t, "00112233445566778899aabbccddeeff")
+
+	if err, got := Dot11AESUnwrap(kek, wrapped); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(got, exp) {
+		t.Fatalf("expected %x, got %x", exp, got)
+	}
+
+	wrapped[0] ^= 0xff
+	if err, _ := Dot11AESUnwrap(kek, wrapped); err == nil {
+		t.Fatal("expected integrity check error")
+	}
+}
+
+func TestDot11ParseGTK(t *testing.T) {
+	gtk := unhex(t, "00112233445566778899aabbccddeeff")
+	// RSN IE is not a KDE, so it must be skipped
+	data := []byte{0x30, 0x02, 0x01, 0x00}
+	data = append(data, 0xdd, 0x16, 0x00, 0x0f, 0xac, 0x01, 0x02, 0x00)
+	data = append(data, gtk...)
+	data = append(data, 0xdd, 0x00)
+
+	if err, got, idx := Dot11ParseGTK(data); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(got, gtk) {
+		t.Fatalf("expected %x, got %x", gtk, got)
+	} else if idx != 2 {
+		t.Fatalf("expected key index 2, got %d", idx)
+	}
+}
+
+func TestDot11CCMPEncrypt(t *testing.T) {
+	// IEEE 802.11-2012 M.6.4 test vector
+	tk := unhex(t, "c97c1f67ce371185514a8a19f2bdd52f")
+	frame := unhex(t, "0848c32c0fd2e128a57c5030f1844408abaea5b8fcba8033f8ba1a55d02f85ae967bb62fb6cda8eb7e78a050")
+	exp := unhex(t, "0848c32c0fd2e128a57c5030f1844408abaea5b8fcba80330ce70020769703b5"+
+		"f3d0a2fe9a3dbf2342a643e43246e80c3c04d0197845ce0b16f97623")
+
+	err, got := Dot11CCMPEncrypt(tk, frame, 0xb5039776e70c, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the protected bit is set in the output frame
+	exp[1] |= 0x40
+	if !bytes.Equal(got, exp) {
+		t.Fatalf("expected %x, got %x", exp, got)
+	} else if ok, pn := Dot11CCMPPacketNumber(got[24:]); !ok || pn != 0xb5039776e70c {
+		t.Fatalf("unexpected packet number %x", pn)
+	}
+}
+
+func TestNewDot11GroupData(t *testing.T) {
+	bssid, _ := net.ParseMAC("01:23:45:67:89:ab")
+	src, _ := net.ParseMAC("01:23:45:67:89:ac")
+	gtk := make([]byte, 16)
+
+	err, raw := NewDot11GroupData(bssid, src, net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, layers.EthernetTypeARP, make([]byte, 28), gtk, 1, 42, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packet := gopacket.NewPacket(raw, layers.LayerTypeRadioTap, gopacket.Default)
+	if ok, _, dot11 := Dot11Parse(packet); !ok {
+		t.Fatal("unable to parse group data frame")
+	} else if !dot11.Flags.FromDS() || !dot11.Flags.WEP() {
+		t.Fatalf("unexpected flags %s", dot11.Flags)
+	} else if !bytes.Equal(dot11.Address2, bssid) || !bytes.Equal(dot11.Address3, src) {
+		t.Fatalf("unexpected addresses %s %s", dot11.Address2, dot11.Address3)
+	}
+}
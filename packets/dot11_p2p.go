@@ -0,0 +1,182 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	p2pAttrCapability = 2
+	p2pAttrDeviceID   = 3
+	p2pAttrDeviceInfo = 13
+	p2pAttrGroupInfo  = 14
+
+	// bit of the group capability
+	p2pGroupOwner = 0x01
+
+	wpsAttrDeviceName        = 0x1011
+	wpsAttrPrimaryDeviceType = 0x1054
+)
+
+var (
+	p2pSignatureBytes = []byte{0x50, 0x6f, 0x9a, 0x09}
+)
+
+// Dot11P2PDevice is a Wi-Fi Direct device, Address is its P2P device address
+// which is not necessarily the one it transmits from.
+type Dot11P2PDevice struct {
+	Address    net.HardwareAddr
+	Name       string
+	Type       string
+	GroupOwner bool
+}
+
+// Dot11P2P is the content of the P2P information elements of a frame, the
+// clients are listed by group owners in their probe responses.
+type Dot11P2P struct {
+	Device  Dot11P2PDevice
+	Clients []Dot11P2PDevice
+}
+
+func wpsDeviceTypeName(data []byte) string {
+	if len(data) < 8 {
+		return ""
+	}
+
+	catId := binary.BigEndian.Uint16(data[0:2])
+	subCatId := binary.BigEndian.Uint16(data[6:8])
+	if cat, found := wpsDeviceTypes[catId]; found {
+		if sub, found := cat.Subcats[subCatId]; found {
+			return sub
+		}
+		return cat.Category
+	}
+	return ""
+}
+
+// p2pDeviceInfo parses the device info attribute and the client info
+// descriptors of the group info one, which have the same layout after the
+// interface address and capability.
+func p2pDeviceInfo(data []byte) (dev Dot11P2PDevice, ok bool) {
+	// address, config methods, primary type and number of secondary types
+	if len(data) < 17 {
+		return
+	}
+
+	dev.Address = net.HardwareAddr(append([]byte{}, data[0:6]...))
+	dev.Type = wpsDeviceTypeName(data[8:16])
+
+	off := 17 + int(data[16])*8
+	if off+4 > len(data) || binary.BigEndian.Uint16(data[off:]) != wpsAttrDeviceName {
+		return dev, true
+	}
+
+	size := int(binary.BigEndian.Uint16(data[off+2:]))
+	if off+4+size <= len(data) {
+		dev.Name = string(data[off+4 : off+4+size])
+	}
+	return dev, true
+}
+
+func p2pGroupInfo(data []byte) []Dot11P2PDevice {
+	clients := make([]Dot11P2PDevice, 0)
+	for off := 0; off < len(data); {
+		size := int(data[off])
+		if size < 13 || off+1+size > len(data) {
+			break
+		}
+
+		desc := data[off+1 : off+1+size]
+		// skip the interface address and the device capability
+		info := append(append([]byte{}, desc[0:6]...), desc[13:]...)
+		if dev, ok := p2pDeviceInfo(info); ok {
+			clients = append(clients, dev)
+		}
+		off += 1 + size
+	}
+	return clients
+}
+
+// wpsDevice fills the name and type of the device from the WPS element if the
+// P2P one didn't have them (probe requests).
+func wpsDevice(data []byte, dev *Dot11P2PDevice) {
+	for off := 0; off+4 <= len(data); {
+		id := binary.BigEndian.Uint16(data[off:])
+		size := int(binary.BigEndian.Uint16(data[off+2:]))
+		if off+4+size > len(data) {
+			return
+		}
+
+		value := data[off+4 : off+4+size]
+		if id == wpsAttrDeviceName && dev.Name == "" {
+			dev.Name = string(value)
+		} else if id == wpsAttrPrimaryDeviceType && dev.Type == "" {
+			dev.Type = wpsDeviceTypeName(value)
+		}
+		off += 4 + size
+	}
+}
+
+// Dot11ParseP2P returns the Wi-Fi Direct information of beacons, probes and
+// association requests.
+func Dot11ParseP2P(packet gopacket.Packet) (ok bool, p2p *Dot11P2P) {
+	// a P2P element can be fragmented in several vendor specific ones
+	data, wps := []byte{}, []byte{}
+	for _, layer := range packet.Layers() {
+		if layer.LayerType() == layers.LayerTypeDot11InformationElement {
+			if info, infoOk := layer.(*layers.Dot11InformationElement); infoOk && info.ID == layers.Dot11InformationElementIDVendor {
+				if bytes.Equal(info.OUI, p2pSignatureBytes) {
+					ok = true
+					data = append(data, info.Info...)
+				} else if bytes.Equal(info.OUI, wpsSignatureBytes) {
+					wps = append(wps, info.Info...)
+				}
+			}
+		}
+	}
+
+	if !ok {
+		return
+	}
+
+	p2p = &Dot11P2P{
+		Clients: make([]Dot11P2PDevice, 0),
+	}
+
+	for off := 0; off+3 <= len(data); {
+		id := data[off]
+		size := int(binary.LittleEndian.Uint16(data[off+1:]))
+		if off+3+size > len(data) {
+			break
+		}
+
+		value := data[off+3 : off+3+size]
+		switch id {
+		case p2pAttrCapability:
+			if size >= 2 {
+				p2p.Device.GroupOwner = value[1]&p2pGroupOwner != 0
+			}
+		case p2pAttrDeviceID:
+			if size >= 6 && p2p.Device.Address == nil {
+				p2p.Device.Address = net.HardwareAddr(append([]byte{}, value[0:6]...))
+			}
+		case p2pAttrDeviceInfo:
+			if dev, found := p2pDeviceInfo(value); found {
+				p2p.Device.Address = dev.Address
+				p2p.Device.Name = dev.Name
+				p2p.Device.Type = dev.Type
+			}
+		case p2pAttrGroupInfo:
+			p2p.Clients = p2pGroupInfo(value)
+		}
+		off += 3 + size
+	}
+
+	wpsDevice(wps, &p2p.Device)
+
+	return
+}
@@ -0,0 +1,89 @@
+package packets
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func p2pAttr(id byte, value []byte) []byte {
+	attr := []byte{id, 0, 0}
+	binary.LittleEndian.PutUint16(attr[1:], uint16(len(value)))
+	return append(attr, value...)
+}
+
+func p2pDeviceInfoValue(mac net.HardwareAddr, category, subcategory uint16, name string) []byte {
+	value := append([]byte{}, mac...)
+	value = append(value, 0x01, 0x88)
+	value = append(value, byte(category>>8), byte(category), 0x00, 0x50, 0xf2, 0x04, byte(subcategory>>8), byte(subcategory))
+	// no secondary device types
+	value = append(value, 0)
+	value = append(value, 0x10, 0x11, byte(len(name)>>8), byte(len(name)))
+	return append(value, name...)
+}
+
+func TestDot11ParseP2P(t *testing.T) {
+	goAddr, _ := net.ParseMAC("02:11:22:33:44:55")
+	goIface, _ := net.ParseMAC("06:11:22:33:44:55")
+	client, _ := net.ParseMAC("02:aa:bb:cc:dd:ee")
+
+	// client info descriptor: device and interface address, capability,
+	// then the same layout of the device info attribute
+	clientInfo := p2pDeviceInfoValue(client, 0x0003, 0x0001, "Printer")
+	desc := append(append([]byte{}, clientInfo[:6]...), client...)
+	desc = append(desc, 0x25)
+	desc = append(desc, clientInfo[6:]...)
+	groupInfo := append([]byte{byte(len(desc))}, desc...)
+
+	attrs := p2pAttr(p2pAttrCapability, []byte{0x25, p2pGroupOwner})
+	attrs = append(attrs, p2pAttr(p2pAttrDeviceInfo, p2pDeviceInfoValue(goAddr, 0x0007, 0x0001, "[TV] Living Room"))...)
+	attrs = append(attrs, p2pAttr(p2pAttrGroupInfo, groupInfo)...)
+
+	// split the P2P element in two like big ones are
+	_, raw := Serialize(
+		&layers.RadioTap{},
+		&layers.Dot11{
+			Address1: client,
+			Address2: goIface,
+			Address3: goIface,
+			Type:     layers.Dot11TypeMgmtProbeResp,
+		},
+		&layers.Dot11MgmtProbeResp{},
+		Dot11Info(layers.Dot11InformationElementIDSSID, []byte("DIRECT-xy-Living Room")),
+		&layers.Dot11InformationElement{
+			ID:   layers.Dot11InformationElementIDVendor,
+			OUI:  p2pSignatureBytes,
+			Info: attrs[:20],
+		},
+		&layers.Dot11InformationElement{
+			ID:   layers.Dot11InformationElementIDVendor,
+			OUI:  p2pSignatureBytes,
+			Info: attrs[20:],
+		},
+	)
+
+	packet := gopacket.NewPacket(raw, layers.LayerTypeRadioTap, gopacket.Default)
+	ok, p2p := Dot11ParseP2P(packet)
+	if !ok {
+		t.Fatal("expected P2P information")
+	}
+
+	dev := p2p.Device
+	if dev.Address.String() != goAddr.String() || dev.Name != "[TV] Living Room" || dev.Type != "TV" || !dev.GroupOwner {
+		t.Fatalf("unexpected device %+v", dev)
+	} else if len(p2p.Clients) != 1 {
+		t.Fatalf("expected 1 client, got %d", len(p2p.Clients))
+	}
+
+	c := p2p.Clients[0]
+	if c.Address.String() != client.String() || c.Name != "Printer" || c.Type != "Printer" {
+		t.Fatalf("unexpected client %+v", c)
+	}
+
+	if ok, _ := Dot11ParseP2P(BuildDot11Packet()); ok {
+		t.Fatal("unexpected P2P information in a deauth frame")
+	}
+}
@@ -1,6 +1,7 @@
 package packets
 
 import (
+	"bytes"
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
 	"net"
@@ -15,8 +16,8 @@ func TestDot11Vars(t *testing.T) {
 	}{
 		{openFlags, 1057},
 		{wpaFlags, 1041},
-		{supportedRates, []byte{0x82, 0x84, 0x8b, 0x96, 0x24, 0x30, 0x48, 0x6c, 0x03, 0x01}},
-		{wpaRSN, []byte{
+		{fakeApRates, []byte{0x82, 0x84, 0x8b, 0x96, 0x24, 0x30, 0x48, 0x6c, 0x03, 0x01}},
+		{fakeApWpaRSN, []byte{
 			0x01, 0x00, // RSN Version 1
 			0x00, 0x0f, 0xac, 0x02, // Group Cipher Suite : 00-0f-ac TKIP
 			0x02, 0x00, // 2 Pairwise Cipher Suites (next two lines)
@@ -118,6 +119,23 @@ func TestNewDot11Beacon(t *testing.T) {
 	}
 }
 
+func TestNewDot11BeaconAt(t *testing.T) {
+	conf := BuildDot11ApConfig()
+	tsf := uint64(0x0102030405)
+
+	err, raw := NewDot11BeaconAt(conf, 42, tsf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packet := gopacket.NewPacket(raw, layers.LayerTypeRadioTap, gopacket.Default)
+	if layer := packet.Layer(layers.LayerTypeDot11MgmtBeacon); layer == nil {
+		t.Fatal("expected beacon layer")
+	} else if beacon := layer.(*layers.Dot11MgmtBeacon); beacon.Timestamp != tsf {
+		t.Fatalf("expected timestamp %x, got %x", tsf, beacon.Timestamp)
+	}
+}
+
 func TestNewDot11Deauth(t *testing.T) {
 	mac, _ := net.ParseMAC("00:00:00:00:00:00")
 	seq := uint16(0)
@@ -133,6 +151,25 @@ func TestNewDot11Deauth(t *testing.T) {
 	}
 }
 
+func TestNewDot11ProbeRequest(t *testing.T) {
+	sta, _ := net.ParseMAC("01:23:45:67:89:ab")
+	ap, _ := net.ParseMAC("01:23:45:67:89:ac")
+
+	err, raw := NewDot11ProbeRequest(sta, ap, "test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packet := gopacket.NewPacket(raw, layers.LayerTypeRadioTap, gopacket.Default)
+	if ok, _, dot11 := Dot11Parse(packet); !ok {
+		t.Fatal("unable to parse dot11 probe request")
+	} else if dot11.Type != layers.Dot11TypeMgmtProbeReq {
+		t.Fatalf("expected probe request, got %s", dot11.Type)
+	} else if !bytes.Equal(dot11.Address1, ap) || !bytes.Equal(dot11.Address2, sta) {
+		t.Fatalf("unexpected addresses %s -> %s", dot11.Address2, dot11.Address1)
+	}
+}
+
 func BuildDot11Packet() gopacket.Packet {
 	mac, _ := net.ParseMAC("00:00:00:00:00:00")
 	seq := uint16(0)
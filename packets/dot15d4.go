@@ -0,0 +1,210 @@
+package packets
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const (
+	Dot15d4TypeBeacon  = 0
+	Dot15d4TypeData    = 1
+	Dot15d4TypeAck     = 2
+	Dot15d4TypeCommand = 3
+
+	Dot15d4AddrNone     = 0
+	Dot15d4AddrShort    = 2
+	Dot15d4AddrExtended = 3
+
+	Dot15d4CmdAssociationRequest = 0x01
+	Dot15d4CmdBeaconRequest      = 0x07
+
+	// the PAN and short address used for broadcasts
+	Dot15d4Broadcast = 0xffff
+	// ZigBee network layer protocol identifier inside beacon payloads
+	zigbeeProtocolID = 0x00
+)
+
+// Dot15d4Beacon is the superframe specification of a beacon and, if present,
+// the ZigBee beacon payload advertising the network.
+type Dot15d4Beacon struct {
+	BeaconOrder    uint8
+	SuperframeOrd  uint8
+	PANCoordinator bool
+	// the association permit bit is how ZigBee coordinators and routers
+	// advertise that the network is accepting new devices (permit join)
+	AssociationPermit bool
+	Zigbee            bool
+	StackProfile      uint8
+	ProtocolVersion   uint8
+	RouterCapacity    bool
+	EndDeviceCapacity bool
+	ExtendedPANID     string
+}
+
+// Dot15d4Frame is an IEEE 802.15.4 MAC frame without the FCS.
+type Dot15d4Frame struct {
+	Type           uint8
+	Security       bool
+	FramePending   bool
+	AckRequest     bool
+	PANCompression bool
+	Version        uint8
+	Sequence       uint8
+	DstPAN         uint16
+	DstAddr        string
+	SrcPAN         uint16
+	SrcAddr        string
+	Command        uint8
+	Beacon         *Dot15d4Beacon
+	Payload        []byte
+}
+
+func (f Dot15d4Frame) TypeName() string {
+	switch f.Type {
+	case Dot15d4TypeBeacon:
+		return "beacon"
+	case Dot15d4TypeData:
+		return "data"
+	case Dot15d4TypeAck:
+		return "ack"
+	case Dot15d4TypeCommand:
+		return "command"
+	}
+	return fmt.Sprintf("type %d", f.Type)
+}
+
+// Dot15d4Address formats a short address as 0x1234 and an extended
+// one, which is transmitted little endian, as a colon separated EUI-64.
+func Dot15d4Address(raw []byte) string {
+	if len(raw) == 2 {
+		return fmt.Sprintf("0x%04x", binary.LittleEndian.Uint16(raw))
+	}
+
+	octets := make([]string, len(raw))
+	for i := range raw {
+		octets[len(raw)-1-i] = fmt.Sprintf("%02x", raw[i])
+	}
+	return strings.Join(octets, ":")
+}
+
+func dot15d4AddrSize(mode uint16) int {
+	switch mode {
+	case Dot15d4AddrShort:
+		return 2
+	case Dot15d4AddrExtended:
+		return 8
+	}
+	return 0
+}
+
+// Dot15d4Parse decodes a MAC frame, the FCS must already be stripped.
+func Dot15d4Parse(raw []byte) (error, *Dot15d4Frame) {
+	if len(raw) < 3 {
+		return fmt.Errorf("802.15.4 frame too short (%d bytes)", len(raw)), nil
+	}
+
+	fc := binary.LittleEndian.Uint16(raw[0:2])
+	f := &Dot15d4Frame{
+		Type:           uint8(fc & 0x07),
+		Security:       fc&(1<<3) != 0,
+		FramePending:   fc&(1<<4) != 0,
+		AckRequest:     fc&(1<<5) != 0,
+		PANCompression: fc&(1<<6) != 0,
+		Version:        uint8((fc >> 12) & 0x03),
+		Sequence:       raw[2],
+	}
+
+	dstMode := (fc >> 10) & 0x03
+	srcMode := (fc >> 14) & 0x03
+	off := 3
+
+	need := func(n int) error {
+		if off+n > len(raw) {
+			return fmt.Errorf("802.15.4 frame truncated at offset %d", off)
+		}
+		return nil
+	}
+
+	if dstMode != Dot15d4AddrNone {
+		sz := dot15d4AddrSize(dstMode)
+		if err := need(2 + sz); err != nil {
+			return err, nil
+		}
+		f.DstPAN = binary.LittleEndian.Uint16(raw[off:])
+		f.DstAddr = Dot15d4Address(raw[off+2 : off+2+sz])
+		off += 2 + sz
+	}
+
+	if srcMode != Dot15d4AddrNone {
+		if f.PANCompression && dstMode != Dot15d4AddrNone {
+			f.SrcPAN = f.DstPAN
+		} else {
+			if err := need(2); err != nil {
+				return err, nil
+			}
+			f.SrcPAN = binary.LittleEndian.Uint16(raw[off:])
+			off += 2
+		}
+
+		sz := dot15d4AddrSize(srcMode)
+		if err := need(sz); err != nil {
+			return err, nil
+		}
+		f.SrcAddr = Dot15d4Address(raw[off : off+sz])
+		off += sz
+	}
+
+	f.Payload = raw[off:]
+
+	if f.Security {
+		// auxiliary security header and encrypted payload, nothing else to decode
+		return nil, f
+	} else if f.Type == Dot15d4TypeCommand && len(f.Payload) > 0 {
+		f.Command = f.Payload[0]
+	} else if f.Type == Dot15d4TypeBeacon {
+		f.Beacon = dot15d4ParseBeacon(f.Payload)
+	}
+
+	return nil, f
+}
+
+func dot15d4ParseBeacon(raw []byte) *Dot15d4Beacon {
+	if len(raw) < 4 {
+		return nil
+	}
+
+	sf := binary.LittleEndian.Uint16(raw[0:2])
+	b := &Dot15d4Beacon{
+		BeaconOrder:       uint8(sf & 0x0f),
+		SuperframeOrd:     uint8((sf >> 4) & 0x0f),
+		PANCoordinator:    sf&(1<<14) != 0,
+		AssociationPermit: sf&(1<<15) != 0,
+	}
+
+	// GTS specification and, if any, direction and list
+	off := 2
+	if gts := int(raw[off] & 0x07); gts > 0 {
+		off += 1 + 3*gts
+	}
+	off++
+
+	// pending addresses specification and list
+	if off >= len(raw) {
+		return b
+	}
+	pending := raw[off]
+	off += 1 + 2*int(pending&0x07) + 8*int((pending>>4)&0x07)
+
+	// ZigBee beacon payload: protocol id, stack profile and version, capacities, extended PAN id
+	if off+11 <= len(raw) && raw[off] == zigbeeProtocolID {
+		b.Zigbee = true
+		b.StackProfile = raw[off+1] & 0x0f
+		b.ProtocolVersion = raw[off+1] >> 4
+		b.RouterCapacity = raw[off+2]&(1<<2) != 0
+		b.EndDeviceCapacity = raw[off+2]&(1<<7) != 0
+		b.ExtendedPANID = Dot15d4Address(raw[off+3 : off+11])
+	}
+
+	return b
+}
@@ -0,0 +1,69 @@
+package packets
+
+import (
+	"testing"
+)
+
+func TestDot15d4ParseBeacon(t *testing.T) {
+	raw := []byte{
+		// frame control: beacon, short source address, seq
+		0x00, 0x80, 0x42,
+		// source PAN and address
+		0x34, 0x12, 0x00, 0x00,
+		// superframe: BO=15 SO=15 coordinator, association permit
+		0xff, 0xcf,
+		// GTS and pending addresses
+		0x00, 0x00,
+		// zigbee: protocol id, stack profile 2 version 2, router and end device capacity
+		0x00, 0x22, 0x84,
+		// extended PAN id
+		0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01,
+	}
+
+	err, f := Dot15d4Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	} else if f.Type != Dot15d4TypeBeacon || f.Sequence != 0x42 {
+		t.Fatalf("unexpected frame %+v", f)
+	} else if f.SrcPAN != 0x1234 || f.SrcAddr != "0x0000" {
+		t.Fatalf("unexpected source %04x %s", f.SrcPAN, f.SrcAddr)
+	} else if f.Beacon == nil {
+		t.Fatal("expected beacon")
+	}
+
+	b := f.Beacon
+	if !b.PANCoordinator || !b.AssociationPermit {
+		t.Fatalf("expected joinable coordinator %+v", b)
+	} else if !b.Zigbee || b.StackProfile != 2 || !b.RouterCapacity || !b.EndDeviceCapacity {
+		t.Fatalf("unexpected zigbee payload %+v", b)
+	} else if b.ExtendedPANID != "01:02:03:04:05:06:07:08" {
+		t.Fatalf("unexpected extended PAN id %s", b.ExtendedPANID)
+	}
+}
+
+func TestDot15d4ParseData(t *testing.T) {
+	raw := []byte{
+		// data, PAN id compression, short destination, extended source
+		0x41, 0xc8, 0x01,
+		0xcd, 0xab, 0xff, 0xff,
+		0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88,
+		0xde, 0xad,
+	}
+
+	err, f := Dot15d4Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	} else if f.Type != Dot15d4TypeData || !f.PANCompression {
+		t.Fatalf("unexpected frame %+v", f)
+	} else if f.DstPAN != 0xabcd || f.SrcPAN != 0xabcd || f.DstAddr != "0xffff" {
+		t.Fatalf("unexpected addressing %+v", f)
+	} else if f.SrcAddr != "88:77:66:55:44:33:22:11" {
+		t.Fatalf("unexpected source %s", f.SrcAddr)
+	} else if len(f.Payload) != 2 {
+		t.Fatalf("unexpected payload %x", f.Payload)
+	}
+
+	if err, _ := Dot15d4Parse(raw[:9]); err == nil {
+		t.Fatal("expected error for truncated frame")
+	}
+}
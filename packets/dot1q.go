@@ -0,0 +1,90 @@
+package packets
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	Dot1QMaxVLAN = 4094
+
+	DTPStatusAccess    = 0x02
+	DTPStatusDesirable = 0x03
+	DTPStatusAuto      = 0x04
+	DTPTypeDot1Q       = 0xa5
+
+	dtpTLVDomain   = 0x0001
+	dtpTLVStatus   = 0x0002
+	dtpTLVType     = 0x0003
+	dtpTLVNeighbor = 0x0004
+)
+
+var (
+	DTPDestMac = CDPDestMac
+	// SNAP header: Cisco OUI and DTP protocol identifier
+	dtpSNAP = []byte{0x00, 0x00, 0x0c, 0x20, 0x04}
+)
+
+// Dot1QEncapsulate inserts an 802.1Q tag with the given VLAN identifier
+// right after the source and destination addresses of an ethernet frame.
+func Dot1QEncapsulate(raw []byte, vlan uint16) (error, []byte) {
+	if vlan == 0 || vlan > Dot1QMaxVLAN {
+		return fmt.Errorf("invalid VLAN identifier %d", vlan), nil
+	} else if len(raw) < 14 {
+		return fmt.Errorf("frame too short (%d bytes)", len(raw)), nil
+	}
+
+	tagged := make([]byte, len(raw)+4)
+	copy(tagged[0:12], raw[0:12])
+	binary.BigEndian.PutUint16(tagged[12:14], uint16(layers.EthernetTypeDot1Q))
+	binary.BigEndian.PutUint16(tagged[14:16], vlan&0x0fff)
+	copy(tagged[16:], raw[12:])
+
+	return nil, tagged
+}
+
+// Dot1QGetVLAN returns the VLAN identifier of a tagged packet or 0.
+func Dot1QGetVLAN(pkt gopacket.Packet) uint16 {
+	if layer := pkt.Layer(layers.LayerTypeDot1Q); layer != nil {
+		return layer.(*layers.Dot1Q).VLANIdentifier
+	}
+	return 0
+}
+
+func dtpTLV(t uint16, value []byte) []byte {
+	tlv := make([]byte, 4+len(value))
+	binary.BigEndian.PutUint16(tlv[0:2], t)
+	binary.BigEndian.PutUint16(tlv[2:4], uint16(4+len(value)))
+	copy(tlv[4:], value)
+	return tlv
+}
+
+// NewDTPProbe creates a Dynamic Trunking Protocol frame asking the
+// switch port on the other side to negotiate an 802.1Q trunk.
+func NewDTPProbe(from net.HardwareAddr, domain string, status byte) (error, []byte) {
+	dtp := []byte{0x01}
+	dtp = append(dtp, dtpTLV(dtpTLVDomain, append([]byte(domain), 0x00))...)
+	dtp = append(dtp, dtpTLV(dtpTLVStatus, []byte{status})...)
+	dtp = append(dtp, dtpTLV(dtpTLVType, []byte{DTPTypeDot1Q})...)
+	dtp = append(dtp, dtpTLV(dtpTLVNeighbor, from)...)
+
+	eth := layers.Ethernet{
+		SrcMAC:       from,
+		DstMAC:       DTPDestMac,
+		EthernetType: layers.EthernetTypeLLC,
+	}
+
+	llc := layers.LLC{
+		DSAP:    0xaa,
+		SSAP:    0xaa,
+		Control: 0x03,
+	}
+
+	payload := gopacket.Payload(append(append([]byte{}, dtpSNAP...), dtp...))
+
+	return Serialize(&eth, &llc, &payload)
+}
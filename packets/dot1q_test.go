@@ -0,0 +1,59 @@
+package packets
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestDot1QEncapsulate(t *testing.T) {
+	from, _ := net.ParseMAC("01:23:45:67:89:ab")
+	err, raw := NewARPRequest(net.IP{192, 168, 1, 2}, from, net.IP{192, 168, 1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err, tagged := Dot1QEncapsulate(raw, 42)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(tagged) != len(raw)+4 {
+		t.Fatalf("expected %d bytes, got %d", len(raw)+4, len(tagged))
+	} else if !bytes.Equal(tagged[16:], raw[12:]) {
+		t.Fatal("payload mismatch after tagging")
+	}
+
+	pkt := gopacket.NewPacket(tagged, layers.LayerTypeEthernet, gopacket.Default)
+	if vlan := Dot1QGetVLAN(pkt); vlan != 42 {
+		t.Fatalf("expected VLAN 42, got %d", vlan)
+	} else if pkt.Layer(layers.LayerTypeARP) == nil {
+		t.Fatal("expected ARP layer after the 802.1Q tag")
+	}
+}
+
+func TestDot1QEncapsulateInvalid(t *testing.T) {
+	if err, _ := Dot1QEncapsulate(make([]byte, 64), 0); err == nil {
+		t.Fatal("expected error for VLAN 0")
+	} else if err, _ := Dot1QEncapsulate(make([]byte, 64), Dot1QMaxVLAN+1); err == nil {
+		t.Fatal("expected error for VLAN out of range")
+	} else if err, _ := Dot1QEncapsulate(make([]byte, 4), 1); err == nil {
+		t.Fatal("expected error for short frame")
+	}
+}
+
+func TestNewDTPProbe(t *testing.T) {
+	from, _ := net.ParseMAC("01:23:45:67:89:ab")
+	err, raw := NewDTPProbe(from, "", DTPStatusDesirable)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(raw[0:6], DTPDestMac) {
+		t.Fatalf("unexpected destination %x", raw[0:6])
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	if snap := pkt.Layer(layers.LayerTypeSNAP); snap == nil {
+		t.Fatal("expected SNAP layer")
+	}
+}
@@ -0,0 +1,219 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// HCI packet types
+const (
+	HCICommandPkt = 0x01
+	HCIEventPkt   = 0x04
+)
+
+// HCI commands used for the BR/EDR discovery, opcodes are OGF << 10 | OCF
+const (
+	HCIOpInquiry           = 0x0401
+	HCIOpInquiryCancel     = 0x0402
+	HCIOpRemoteNameRequest = 0x0419
+	HCIOpWriteInquiryMode  = 0x0c45
+)
+
+// HCI events
+const (
+	HCIEventInquiryComplete       = 0x01
+	HCIEventInquiryResult         = 0x02
+	HCIEventRemoteNameComplete    = 0x07
+	HCIEventCommandComplete       = 0x0e
+	HCIEventCommandStatus         = 0x0f
+	HCIEventInquiryResultRSSI     = 0x22
+	HCIEventExtendedInquiryResult = 0x2f
+)
+
+const (
+	// general inquiry access code
+	HCIGIAC = 0x9e8b33
+	// inquiry results with RSSI or extended inquiry results
+	HCIInquiryModeExtended = 0x02
+
+	eirShortName    = 0x08
+	eirCompleteName = 0x09
+)
+
+// HCIInquiryResult is a device answering an inquiry, Name is only set by
+// extended inquiry results.
+type HCIInquiryResult struct {
+	Address      net.HardwareAddr
+	PageScanMode byte
+	Class        uint32
+	ClockOffset  uint16
+	RSSI         int8
+	Name         string
+}
+
+// bluetooth addresses are sent little endian
+func hciAddress(data []byte) net.HardwareAddr {
+	addr := make(net.HardwareAddr, 6)
+	for i := 0; i < 6; i++ {
+		addr[i] = data[5-i]
+	}
+	return addr
+}
+
+func hciClass(data []byte) uint32 {
+	return uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16
+}
+
+// HCICommand returns the HCI command packet for the opcode and parameters.
+func HCICommand(opcode uint16, params ...byte) []byte {
+	pkt := []byte{HCICommandPkt, byte(opcode), byte(opcode >> 8), byte(len(params))}
+	return append(pkt, params...)
+}
+
+// HCIInquiry starts a general inquiry lasting length * 1.28 seconds, with no
+// limit on the number of responses if responses is 0.
+func HCIInquiry(length, responses byte) []byte {
+	return HCICommand(HCIOpInquiry, HCIGIAC&0xff, (HCIGIAC>>8)&0xff, HCIGIAC>>16, length, responses)
+}
+
+func HCIInquiryCancel() []byte {
+	return HCICommand(HCIOpInquiryCancel)
+}
+
+func HCIWriteInquiryMode(mode byte) []byte {
+	return HCICommand(HCIOpWriteInquiryMode, mode)
+}
+
+// HCIRemoteNameRequest asks the controller to page the device and read its
+// name, the page scan mode and clock offset of its inquiry result speed it up.
+func HCIRemoteNameRequest(addr net.HardwareAddr, pageScanMode byte, clockOffset uint16) []byte {
+	params := make([]byte, 10)
+	for i := 0; i < 6; i++ {
+		params[i] = addr[5-i]
+	}
+	params[6] = pageScanMode
+	// the clock offset is only valid with its most significant bit set
+	binary.LittleEndian.PutUint16(params[8:], clockOffset|0x8000)
+	return HCICommand(HCIOpRemoteNameRequest, params...)
+}
+
+// HCIParseEvent returns the code and the parameters of an HCI event packet.
+func HCIParseEvent(data []byte) (code byte, params []byte, err error) {
+	if len(data) < 3 || data[0] != HCIEventPkt {
+		return 0, nil, fmt.Errorf("not an HCI event packet")
+	} else if size := int(data[2]); len(data) < 3+size {
+		return 0, nil, fmt.Errorf("HCI event truncated (%d of %d bytes)", len(data)-3, size)
+	} else {
+		return data[1], data[3 : 3+size], nil
+	}
+}
+
+// HCIParseCommandStatus returns the status and the opcode of a command
+// status event.
+func HCIParseCommandStatus(params []byte) (ok bool, status byte, opcode uint16) {
+	if len(params) < 4 {
+		return
+	}
+	return true, params[0], binary.LittleEndian.Uint16(params[2:])
+}
+
+// eirName returns the complete or shortened name of extended inquiry
+// response data.
+func eirName(data []byte) (name string) {
+	for off := 0; off < len(data); {
+		size := int(data[off])
+		if size == 0 || off+1+size > len(data) {
+			break
+		}
+
+		field := data[off+1 : off+1+size]
+		if field[0] == eirCompleteName {
+			return string(field[1:])
+		} else if field[0] == eirShortName {
+			name = string(field[1:])
+		}
+		off += 1 + size
+	}
+	return
+}
+
+// HCIParseInquiryResults parses the parameters of the three kinds of inquiry
+// result events.
+func HCIParseInquiryResults(code byte, params []byte) []HCIInquiryResult {
+	results := make([]HCIInquiryResult, 0)
+	if len(params) < 1 || params[0] == 0 {
+		return results
+	}
+
+	num := int(params[0])
+	data := params[1:]
+
+	switch code {
+	case HCIEventInquiryResult:
+		// address, page scan repetition mode, two reserved, class, clock offset
+		for i := 0; i < num && (i+1)*14 <= len(data); i++ {
+			r := data[i*14:]
+			results = append(results, HCIInquiryResult{
+				Address:      hciAddress(r),
+				PageScanMode: r[6],
+				Class:        hciClass(r[9:]),
+				ClockOffset:  binary.LittleEndian.Uint16(r[12:]),
+			})
+		}
+
+	case HCIEventInquiryResultRSSI:
+		// some controllers still send the page scan mode, like in the plain
+		// inquiry result
+		size := 14
+		if len(data) == num*15 {
+			size = 15
+		}
+		for i := 0; i < num && (i+1)*size <= len(data); i++ {
+			r := data[i*size:]
+			off := 8 + size - 14
+			results = append(results, HCIInquiryResult{
+				Address:      hciAddress(r),
+				PageScanMode: r[6],
+				Class:        hciClass(r[off:]),
+				ClockOffset:  binary.LittleEndian.Uint16(r[off+3:]),
+				RSSI:         int8(r[off+5]),
+			})
+		}
+
+	case HCIEventExtendedInquiryResult:
+		// always a single response followed by 240 bytes of EIR data
+		if len(data) >= 14 {
+			results = append(results, HCIInquiryResult{
+				Address:      hciAddress(data),
+				PageScanMode: data[6],
+				Class:        hciClass(data[8:]),
+				ClockOffset:  binary.LittleEndian.Uint16(data[11:]),
+				RSSI:         int8(data[13]),
+				Name:         eirName(data[14:]),
+			})
+		}
+	}
+
+	return results
+}
+
+// HCIParseRemoteName parses a remote name request complete event, ok is
+// false if the device could not be paged.
+func HCIParseRemoteName(params []byte) (ok bool, addr net.HardwareAddr, name string) {
+	if len(params) < 7 {
+		return
+	}
+
+	addr = hciAddress(params[1:])
+	if params[0] != 0 {
+		return
+	}
+
+	raw := params[7:]
+	if end := bytes.IndexByte(raw, 0); end != -1 {
+		raw = raw[:end]
+	}
+	return true, addr, string(raw)
+}
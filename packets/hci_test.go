@@ -0,0 +1,66 @@
+package packets
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHCICommands(t *testing.T) {
+	if pkt := HCIInquiry(8, 0); !bytes.Equal(pkt, []byte{0x01, 0x01, 0x04, 0x05, 0x33, 0x8b, 0x9e, 0x08, 0x00}) {
+		t.Fatalf("unexpected inquiry % x", pkt)
+	}
+
+	addr := hciAddress([]byte{0x66, 0x55, 0x44, 0x33, 0x22, 0x11})
+	if addr.String() != "11:22:33:44:55:66" {
+		t.Fatalf("unexpected address %s", addr)
+	}
+
+	pkt := HCIRemoteNameRequest(addr, 0x01, 0x1234)
+	expected := []byte{0x01, 0x19, 0x04, 0x0a, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01, 0x00, 0x34, 0x92}
+	if !bytes.Equal(pkt, expected) {
+		t.Fatalf("unexpected remote name request % x", pkt)
+	}
+}
+
+func TestHCIParseInquiryResults(t *testing.T) {
+	raw := []byte{HCIEventPkt, HCIEventInquiryResultRSSI, 15,
+		1, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01, 0x00, 0x04, 0x04, 0x24, 0x34, 0x12, 0xc4}
+
+	code, params, err := HCIParseEvent(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := HCIParseInquiryResults(code, params)
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	} else if r := results[0]; r.Address.String() != "11:22:33:44:55:66" || r.Class != 0x240404 || r.ClockOffset != 0x1234 || r.RSSI != -60 {
+		t.Fatalf("unexpected result %+v", r)
+	}
+
+	eir := []byte{0x02, 0x01, 0x06, 0x05, eirShortName, 'C', 'a', 'r', ' ', 0x08, eirCompleteName, 'M', 'y', ' ', 'C', 'a', 'r', '!'}
+	params = append([]byte{1, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01, 0x00, 0x08, 0x04, 0x20, 0x34, 0x12, 0xc4}, eir...)
+	params = append(params, make([]byte, 240-len(eir))...)
+
+	results = HCIParseInquiryResults(HCIEventExtendedInquiryResult, params)
+	if len(results) != 1 || results[0].Name != "My Car!" || results[0].Class != 0x200408 {
+		t.Fatalf("unexpected extended result %+v", results)
+	}
+
+	if _, _, err := HCIParseEvent([]byte{HCIEventPkt, HCIEventInquiryResult, 15, 1}); err == nil {
+		t.Fatal("expected error for a truncated event")
+	}
+}
+
+func TestHCIParseRemoteName(t *testing.T) {
+	params := append([]byte{0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11}, []byte("Headset\x00garbage")...)
+	if ok, addr, name := HCIParseRemoteName(params); !ok || addr.String() != "11:22:33:44:55:66" || name != "Headset" {
+		t.Fatalf("unexpected name '%s' for %s", name, addr)
+	}
+
+	// page timeout
+	params[0] = 0x04
+	if ok, _, _ := HCIParseRemoteName(params); ok {
+		t.Fatal("expected a failed name request")
+	}
+}
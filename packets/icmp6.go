@@ -0,0 +1,156 @@
+package packets
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	RAFlagManaged = 0x80
+	RAFlagOther   = 0x40
+
+	raPrefixFlagOnLink     = 0x80
+	raPrefixFlagAutonomous = 0x40
+	raOptRDNSS             = layers.ICMPv6Opt(25)
+)
+
+var (
+	IPv6AllNodesMac = net.HardwareAddr{0x33, 0x33, 0x00, 0x00, 0x00, 0x01}
+	IPv6AllNodesIP  = net.ParseIP("ff02::1")
+
+	IPv6AllRoutersMac = net.HardwareAddr{0x33, 0x33, 0x00, 0x00, 0x00, 0x02}
+	IPv6AllRoutersIP  = net.ParseIP("ff02::2")
+)
+
+// RouterAdvertisement describes the fields of an ICMPv6 router advertisement.
+type RouterAdvertisement struct {
+	Managed           bool
+	Other             bool
+	RouterLifetime    uint16
+	Prefix            *net.IPNet
+	ValidLifetime     uint32
+	PreferredLifetime uint32
+	DNS               net.IP
+	DNSLifetime       uint32
+}
+
+func raPrefixOption(ra RouterAdvertisement) (error, layers.ICMPv6Option) {
+	bits, size := ra.Prefix.Mask.Size()
+	if size != 128 {
+		return fmt.Errorf("%s is not an IPv6 prefix", ra.Prefix), layers.ICMPv6Option{}
+	}
+
+	data := make([]byte, 30)
+	data[0] = byte(bits)
+	data[1] = raPrefixFlagOnLink | raPrefixFlagAutonomous
+	binary.BigEndian.PutUint32(data[2:6], ra.ValidLifetime)
+	binary.BigEndian.PutUint32(data[6:10], ra.PreferredLifetime)
+	copy(data[14:30], ra.Prefix.IP.To16())
+
+	return nil, layers.ICMPv6Option{
+		Type: layers.ICMPv6OptPrefixInfo,
+		Data: data,
+	}
+}
+
+func raRDNSSOption(ra RouterAdvertisement) layers.ICMPv6Option {
+	data := make([]byte, 22)
+	binary.BigEndian.PutUint32(data[2:6], ra.DNSLifetime)
+	copy(data[6:22], ra.DNS.To16())
+	return layers.ICMPv6Option{
+		Type: raOptRDNSS,
+		Data: data,
+	}
+}
+
+// NewRouterAdvertisement creates an ICMPv6 router advertisement
+// sent to all the nodes of the link.
+func NewRouterAdvertisement(from net.IP, from_hw net.HardwareAddr, ra RouterAdvertisement) (error, []byte) {
+	eth := layers.Ethernet{
+		SrcMAC:       from_hw,
+		DstMAC:       IPv6AllNodesMac,
+		EthernetType: layers.EthernetTypeIPv6,
+	}
+
+	ip6 := layers.IPv6{
+		Version:    6,
+		NextHeader: layers.IPProtocolICMPv6,
+		HopLimit:   255,
+		SrcIP:      from,
+		DstIP:      IPv6AllNodesIP,
+	}
+
+	icmp6 := layers.ICMPv6{
+		TypeCode: layers.CreateICMPv6TypeCode(layers.ICMPv6TypeRouterAdvertisement, 0),
+	}
+	icmp6.SetNetworkLayerForChecksum(&ip6)
+
+	adv := layers.ICMPv6RouterAdvertisement{
+		HopLimit:       64,
+		RouterLifetime: ra.RouterLifetime,
+		Options: layers.ICMPv6Options{
+			{
+				Type: layers.ICMPv6OptSourceAddress,
+				Data: from_hw,
+			},
+		},
+	}
+
+	if ra.Managed {
+		adv.Flags |= RAFlagManaged
+	}
+	if ra.Other {
+		adv.Flags |= RAFlagOther
+	}
+
+	if ra.Prefix != nil {
+		err, opt := raPrefixOption(ra)
+		if err != nil {
+			return err, nil
+		}
+		adv.Options = append(adv.Options, opt)
+	}
+
+	if ra.DNS != nil {
+		adv.Options = append(adv.Options, raRDNSSOption(ra))
+	}
+
+	return Serialize(&eth, &ip6, &icmp6, &adv)
+}
+
+// NewRouterSolicitation creates an ICMPv6 router solicitation sent to all
+// the routers of the link, which answer with their advertisements.
+func NewRouterSolicitation(from net.IP, from_hw net.HardwareAddr) (error, []byte) {
+	eth := layers.Ethernet{
+		SrcMAC:       from_hw,
+		DstMAC:       IPv6AllRoutersMac,
+		EthernetType: layers.EthernetTypeIPv6,
+	}
+
+	ip6 := layers.IPv6{
+		Version:    6,
+		NextHeader: layers.IPProtocolICMPv6,
+		HopLimit:   255,
+		SrcIP:      from,
+		DstIP:      IPv6AllRoutersIP,
+	}
+
+	icmp6 := layers.ICMPv6{
+		TypeCode: layers.CreateICMPv6TypeCode(layers.ICMPv6TypeRouterSolicitation, 0),
+	}
+	icmp6.SetNetworkLayerForChecksum(&ip6)
+
+	sol := layers.ICMPv6RouterSolicitation{
+		Options: layers.ICMPv6Options{
+			{
+				Type: layers.ICMPv6OptSourceAddress,
+				Data: from_hw,
+			},
+		},
+	}
+
+	return Serialize(&eth, &ip6, &icmp6, &sol)
+}
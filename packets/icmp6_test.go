@@ -0,0 +1,85 @@
+package packets
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestNewRouterAdvertisement(t *testing.T) {
+	from, _ := net.ParseMAC("01:23:45:67:89:ab")
+	_, prefix, _ := net.ParseCIDR("fd00::/64")
+	ra := RouterAdvertisement{
+		Managed:           true,
+		Other:             true,
+		RouterLifetime:    1800,
+		Prefix:            prefix,
+		ValidLifetime:     300,
+		PreferredLifetime: 120,
+		DNS:               net.ParseIP("fe80::1"),
+		DNSLifetime:       30,
+	}
+
+	err, raw := NewRouterAdvertisement(net.ParseIP("fe80::1"), from, ra)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	layer := pkt.Layer(layers.LayerTypeICMPv6RouterAdvertisement)
+	if layer == nil {
+		t.Fatal("expected router advertisement layer")
+	}
+
+	adv := layer.(*layers.ICMPv6RouterAdvertisement)
+	if !adv.ManagedAddressConfig() || !adv.OtherConfig() {
+		t.Fatalf("unexpected flags 0x%02x", adv.Flags)
+	} else if adv.RouterLifetime != 1800 {
+		t.Fatalf("expected router lifetime 1800, got %d", adv.RouterLifetime)
+	} else if len(adv.Options) != 3 {
+		t.Fatalf("expected 3 options, got %d", len(adv.Options))
+	}
+
+	found := false
+	for _, opt := range adv.Options {
+		if opt.Type == layers.ICMPv6OptPrefixInfo {
+			found = true
+			if opt.Data[0] != 64 {
+				t.Fatalf("expected prefix length 64, got %d", opt.Data[0])
+			} else if got := net.IP(opt.Data[14:30]); !got.Equal(prefix.IP) {
+				t.Fatalf("expected prefix %s, got %s", prefix.IP, got)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("prefix information option not found")
+	}
+}
+
+func TestNewRouterAdvertisementInvalidPrefix(t *testing.T) {
+	from, _ := net.ParseMAC("01:23:45:67:89:ab")
+	_, prefix, _ := net.ParseCIDR("192.168.1.0/24")
+	if err, _ := NewRouterAdvertisement(net.ParseIP("fe80::1"), from, RouterAdvertisement{Prefix: prefix}); err == nil {
+		t.Fatal("expected error for IPv4 prefix")
+	}
+}
+
+func TestNewRouterSolicitation(t *testing.T) {
+	from, _ := net.ParseMAC("01:23:45:67:89:ab")
+
+	err, raw := NewRouterSolicitation(net.ParseIP("fe80::2"), from)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	if ip6 := pkt.Layer(layers.LayerTypeIPv6).(*layers.IPv6); !ip6.DstIP.Equal(IPv6AllRoutersIP) || ip6.HopLimit != 255 {
+		t.Fatalf("unexpected IPv6 layer %+v", ip6)
+	} else if layer := pkt.Layer(layers.LayerTypeICMPv6RouterSolicitation); layer == nil {
+		t.Fatal("expected a router solicitation")
+	} else if opts := layer.(*layers.ICMPv6RouterSolicitation).Options; len(opts) != 1 || opts[0].Type != layers.ICMPv6OptSourceAddress {
+		t.Fatalf("unexpected options %v", opts)
+	}
+}
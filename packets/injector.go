@@ -0,0 +1,26 @@
+package packets
+
+import (
+	"fmt"
+	"net"
+)
+
+// Injector is implemented by the devices we can write raw link layer frames to,
+// a *pcap.Handle is one and so are the native AF_PACKET sockets and BPF devices.
+type Injector interface {
+	WritePacketData(data []byte) error
+	Close()
+}
+
+// ErrNoNativeInjector is returned by NewNativeInjector on platforms where only libpcap can be used.
+var ErrNoNativeInjector = fmt.Errorf("native packet injection is not supported on this platform")
+
+// NewNativeInjector opens an AF_PACKET socket (on Linux) or a BPF device (on macOS and BSD)
+// bound to the given interface in order to send frames without going through libpcap.
+func NewNativeInjector(ifName string) (Injector, error) {
+	iface, err := net.InterfaceByName(ifName)
+	if err != nil {
+		return nil, err
+	}
+	return openNativeInjector(iface)
+}
@@ -0,0 +1,69 @@
+// +build darwin freebsd netbsd openbsd
+
+package packets
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+const maxBPFDevices = 256
+
+type bpfDevice struct {
+	fd int
+}
+
+type bpfIfreq struct {
+	Name [syscall.IFNAMSIZ]byte
+	Pad  [16]byte
+}
+
+func ioctl(fd int, req uint, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(req), uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func openNativeInjector(iface *net.Interface) (Injector, error) {
+	fd := -1
+	for i := 0; i < maxBPFDevices && fd == -1; i++ {
+		var err error
+		if fd, err = syscall.Open(fmt.Sprintf("/dev/bpf%d", i), syscall.O_WRONLY, 0); err == syscall.EBUSY {
+			fd = -1
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if fd == -1 {
+		return nil, fmt.Errorf("no BPF device available")
+	}
+
+	ifr := bpfIfreq{}
+	copy(ifr.Name[:], iface.Name)
+	if err := ioctl(fd, syscall.BIOCSETIF, unsafe.Pointer(&ifr)); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("could not bind BPF device to %s: %s", iface.Name, err)
+	}
+
+	// do not let the kernel fill in the source address of our frames
+	complete := 1
+	if err := ioctl(fd, syscall.BIOCSHDRCMPLT, unsafe.Pointer(&complete)); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	return &bpfDevice{fd: fd}, nil
+}
+
+func (d *bpfDevice) WritePacketData(data []byte) error {
+	_, err := syscall.Write(d.fd, data)
+	return err
+}
+
+func (d *bpfDevice) Close() {
+	syscall.Close(d.fd)
+}
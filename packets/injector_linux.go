@@ -0,0 +1,39 @@
+package packets
+
+import (
+	"net"
+	"syscall"
+)
+
+type packetSocket struct {
+	fd int
+}
+
+// the socket is bound with protocol 0 so that the kernel never queues
+// received frames to it, we only use it to write.
+func openNativeInjector(iface *net.Interface) (Injector, error) {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := &syscall.SockaddrLinklayer{
+		Protocol: 0,
+		Ifindex:  iface.Index,
+	}
+	if err = syscall.Bind(fd, addr); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	return &packetSocket{fd: fd}, nil
+}
+
+func (s *packetSocket) WritePacketData(data []byte) error {
+	_, err := syscall.Write(s.fd, data)
+	return err
+}
+
+func (s *packetSocket) Close() {
+	syscall.Close(s.fd)
+}
@@ -0,0 +1,11 @@
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package packets
+
+import (
+	"net"
+)
+
+func openNativeInjector(iface *net.Interface) (Injector, error) {
+	return nil, ErrNoNativeInjector
+}
@@ -0,0 +1,219 @@
+package packets
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+const (
+	LDAPPort = 389
+
+	// NETLOGON_SAM_LOGON_RESPONSE_EX opcodes
+	NetlogonLogonResponseEx     = 23
+	NetlogonUserUnknownEx       = 25
+	netlogonResponseExNamesFrom = 24
+
+	// the responding server is the PDC of the domain
+	NetlogonFlagPDC = 0x1
+
+	berClassUniversal = 0
+	berClassContext   = 2
+	berOctetString    = 4
+	berSet            = 17
+	berEqualityMatch  = 3
+)
+
+var (
+	ErrBERTruncated        = errors.New("truncated BER element")
+	ErrBERUnsupported      = errors.New("unsupported BER encoding")
+	ErrNetlogonTruncated   = errors.New("truncated netlogon response")
+	ErrNetlogonUnsupported = errors.New("unsupported netlogon response")
+)
+
+type berElement struct {
+	class    int
+	tag      int
+	compound bool
+	data     []byte
+}
+
+// berParse returns the sibling elements encoded in data, LDAP uses the long
+// form of definite lengths even for short values so this is more lenient
+// than encoding/asn1.
+func berParse(data []byte) ([]berElement, error) {
+	elements := make([]berElement, 0)
+	for off := 0; off < len(data); {
+		if off+2 > len(data) {
+			return elements, ErrBERTruncated
+		}
+
+		id := data[off]
+		e := berElement{
+			class:    int(id >> 6),
+			compound: id&0x20 != 0,
+			tag:      int(id & 0x1f),
+		}
+		if e.tag == 0x1f {
+			return elements, ErrBERUnsupported
+		}
+
+		size := int(data[off+1])
+		off += 2
+		if size&0x80 != 0 {
+			n := size & 0x7f
+			if n == 0 || n > 4 {
+				return elements, ErrBERUnsupported
+			} else if off+n > len(data) {
+				return elements, ErrBERTruncated
+			}
+			size = 0
+			for _, b := range data[off : off+n] {
+				size = size<<8 | int(b)
+			}
+			off += n
+		}
+
+		if size < 0 || off+size > len(data) {
+			return elements, ErrBERTruncated
+		}
+
+		e.data = data[off : off+size]
+		elements = append(elements, e)
+		off += size
+	}
+	return elements, nil
+}
+
+func (e berElement) is(class, tag int) bool {
+	return e.class == class && e.tag == tag
+}
+
+func ldapWalk(data []byte, attrs map[string][]string, depth int) {
+	elements, _ := berParse(data)
+	for _, e := range elements {
+		if !e.compound || depth > 16 {
+			continue
+		}
+
+		children, _ := berParse(e.data)
+		if len(children) == 2 && children[0].is(berClassUniversal, berOctetString) {
+			name := strings.ToLower(string(children[0].data))
+			// search filter assertion
+			if e.is(berClassContext, berEqualityMatch) && children[1].is(berClassUniversal, berOctetString) {
+				attrs[name] = append(attrs[name], string(children[1].data))
+				continue
+			}
+			// attribute of a search result
+			if children[1].compound && children[1].is(berClassUniversal, berSet) {
+				values, _ := berParse(children[1].data)
+				for _, v := range values {
+					if v.is(berClassUniversal, berOctetString) {
+						attrs[name] = append(attrs[name], string(v.data))
+					}
+				}
+				continue
+			}
+		}
+
+		ldapWalk(e.data, attrs, depth+1)
+	}
+}
+
+// LDAPAttributes returns the attributes of the search results and the
+// equality assertions of the search filters found in a LDAP or CLDAP payload,
+// names are lowercase.
+func LDAPAttributes(data []byte) map[string][]string {
+	attrs := make(map[string][]string)
+	ldapWalk(data, attrs, 0)
+	return attrs
+}
+
+// NetlogonResponse is the NETLOGON_SAM_LOGON_RESPONSE_EX a domain controller
+// sends back to a CLDAP ping.
+type NetlogonResponse struct {
+	Flags         uint32
+	Forest        string
+	Domain        string
+	Host          string
+	NetBIOSDomain string
+	NetBIOSHost   string
+	User          string
+	Site          string
+	ClientSite    string
+}
+
+// netlogonName decodes a RFC 1035 compressed name, pointers are relative to
+// the start of the response.
+func netlogonName(data []byte, off int) (string, int, error) {
+	labels := make([]string, 0)
+	next := -1
+	for jumps := 0; ; {
+		if off >= len(data) {
+			return "", 0, ErrNetlogonTruncated
+		}
+
+		size := int(data[off])
+		if size == 0 {
+			off++
+			break
+		} else if size&0xc0 == 0xc0 {
+			if off+2 > len(data) {
+				return "", 0, ErrNetlogonTruncated
+			} else if jumps++; jumps > 16 {
+				return "", 0, ErrNetlogonUnsupported
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(data[off:]) & 0x3fff)
+			continue
+		} else if off+1+size > len(data) {
+			return "", 0, ErrNetlogonTruncated
+		}
+
+		labels = append(labels, string(data[off+1:off+1+size]))
+		off += 1 + size
+	}
+
+	if next < 0 {
+		next = off
+	}
+	return strings.Join(labels, "."), next, nil
+}
+
+// ParseNetlogonResponse decodes the Netlogon attribute of a CLDAP ping
+// response.
+func ParseNetlogonResponse(data []byte) (*NetlogonResponse, error) {
+	if len(data) < netlogonResponseExNamesFrom {
+		return nil, ErrNetlogonTruncated
+	}
+
+	opcode := binary.LittleEndian.Uint16(data)
+	if opcode != NetlogonLogonResponseEx && opcode != NetlogonUserUnknownEx {
+		return nil, ErrNetlogonUnsupported
+	}
+
+	resp := &NetlogonResponse{
+		Flags: binary.LittleEndian.Uint32(data[4:]),
+	}
+
+	var err error
+	off := netlogonResponseExNamesFrom
+	for _, dst := range []*string{
+		&resp.Forest,
+		&resp.Domain,
+		&resp.Host,
+		&resp.NetBIOSDomain,
+		&resp.NetBIOSHost,
+		&resp.User,
+		&resp.Site,
+		&resp.ClientSite,
+	} {
+		if *dst, off, err = netlogonName(data, off); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,99 @@
+package packets
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// ber encodes an element using the four bytes long form of the length, as
+// Windows does.
+func ber(id byte, children ...[]byte) []byte {
+	data := make([]byte, 0)
+	for _, c := range children {
+		data = append(data, c...)
+	}
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(data)))
+	return append(append([]byte{id, 0x84}, size...), data...)
+}
+
+func octets(s string) []byte {
+	return append([]byte{0x04, byte(len(s))}, s...)
+}
+
+func netlogonLabels(labels ...string) []byte {
+	data := make([]byte, 0)
+	for _, l := range labels {
+		data = append(data, byte(len(l)))
+		data = append(data, l...)
+	}
+	return append(data, 0)
+}
+
+func TestLDAPAttributesFilter(t *testing.T) {
+	// CLDAP ping: (&(DnsDomain=corp.local)(Host=WS01)(NtVer=\x16\x00\x00\x00))
+	filter := ber(0xa0,
+		ber(0xa3, octets("DnsDomain"), octets("corp.local")),
+		ber(0xa3, octets("Host"), octets("WS01")),
+		ber(0xa3, octets("NtVer"), octets("\x16\x00\x00\x00")))
+	search := ber(0x63, octets(""), []byte{0x0a, 1, 0}, []byte{0x0a, 1, 0}, []byte{0x02, 1, 0}, []byte{0x02, 1, 0},
+		[]byte{0x01, 1, 0}, filter, ber(0x30, octets("Netlogon")))
+	msg := ber(0x30, []byte{0x02, 1, 1}, search)
+
+	attrs := LDAPAttributes(msg)
+	if v := attrs["dnsdomain"]; len(v) != 1 || v[0] != "corp.local" {
+		t.Fatalf("unexpected dnsdomain %v", v)
+	} else if v := attrs["host"]; len(v) != 1 || v[0] != "WS01" {
+		t.Fatalf("unexpected host %v", v)
+	} else if _, found := attrs["netlogon"]; found {
+		t.Fatal("the requested attributes are not values")
+	}
+}
+
+func TestParseNetlogonResponse(t *testing.T) {
+	blob := make([]byte, netlogonResponseExNamesFrom)
+	binary.LittleEndian.PutUint16(blob, NetlogonLogonResponseEx)
+	binary.LittleEndian.PutUint32(blob[4:], 0x3f1fd|NetlogonFlagPDC)
+
+	// forest and domain, then names pointing to them
+	blob = append(blob, netlogonLabels("corp", "local")...)
+	blob = append(blob, 0xc0, byte(netlogonResponseExNamesFrom))
+	blob = append(blob, 4, 'd', 'c', '0', '1', 0xc0, byte(netlogonResponseExNamesFrom))
+	blob = append(blob, netlogonLabels("CORP")...)
+	blob = append(blob, netlogonLabels("DC01")...)
+	blob = append(blob, 0)
+	site := len(blob)
+	blob = append(blob, netlogonLabels("Default-First-Site-Name")...)
+	blob = append(blob, 0xc0, byte(site))
+
+	entry := ber(0x64, octets(""), ber(0x30, ber(0x30, octets("Netlogon"), ber(0x31, octets(string(blob))))))
+	msg := ber(0x30, []byte{0x02, 1, 1}, entry)
+
+	attrs := LDAPAttributes(msg)
+	if v := attrs["netlogon"]; len(v) != 1 {
+		t.Fatalf("unexpected netlogon %v", v)
+	}
+
+	resp, err := ParseNetlogonResponse([]byte(attrs["netlogon"][0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := NetlogonResponse{
+		Flags:         0x3f1fd | NetlogonFlagPDC,
+		Forest:        "corp.local",
+		Domain:        "corp.local",
+		Host:          "dc01.corp.local",
+		NetBIOSDomain: "CORP",
+		NetBIOSHost:   "DC01",
+		Site:          "Default-First-Site-Name",
+		ClientSite:    "Default-First-Site-Name",
+	}
+	if *resp != expected {
+		t.Fatalf("unexpected response %+v", *resp)
+	}
+
+	if _, err := ParseNetlogonResponse(blob[:30]); err == nil {
+		t.Fatal("expected error for truncated response")
+	}
+}
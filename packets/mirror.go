@@ -0,0 +1,51 @@
+package packets
+
+import (
+	"encoding/binary"
+)
+
+const (
+	VXLANPort = 4789
+
+	vxlanHeaderSize = 8
+	vxlanFlagVNI    = 0x08
+
+	// GRE with the sequence number bit set, as ERSPAN type II requires
+	greHeaderSize    = 8
+	greFlagSequence  = 0x1000
+	greProtoERSPANII = 0x88be
+
+	erspanHeaderSize = 8
+	erspanVersionII  = 1
+	// untagged frames, the VLAN is whatever the original frame carries
+	erspanEncapUntagged = 0
+)
+
+// NewVXLANFrame encapsulates an ethernet frame in a VXLAN header, to be sent
+// over UDP to VXLANPort of the remote endpoint.
+func NewVXLANFrame(vni uint32, frame []byte) []byte {
+	raw := make([]byte, vxlanHeaderSize+len(frame))
+	raw[0] = vxlanFlagVNI
+	// the VNI takes the upper 24 bits of the second word
+	binary.BigEndian.PutUint32(raw[4:], (vni&0xffffff)<<8)
+	copy(raw[vxlanHeaderSize:], frame)
+	return raw
+}
+
+// NewERSPANFrame encapsulates an ethernet frame in the GRE and ERSPAN type II
+// headers, the IP header is left to the raw socket it's sent from.
+func NewERSPANFrame(session uint16, seq uint32, index uint32, frame []byte) []byte {
+	raw := make([]byte, greHeaderSize+erspanHeaderSize+len(frame))
+
+	binary.BigEndian.PutUint16(raw[0:], greFlagSequence)
+	binary.BigEndian.PutUint16(raw[2:], greProtoERSPANII)
+	binary.BigEndian.PutUint32(raw[4:], seq)
+
+	hdr := raw[greHeaderSize:]
+	binary.BigEndian.PutUint16(hdr[0:], erspanVersionII<<12)
+	binary.BigEndian.PutUint16(hdr[2:], erspanEncapUntagged<<11|session&0x3ff)
+	binary.BigEndian.PutUint32(hdr[4:], index&0xfffff)
+
+	copy(raw[greHeaderSize+erspanHeaderSize:], frame)
+	return raw
+}
@@ -0,0 +1,57 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+var testMirroredFrame = []byte{
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	0x00, 0x11, 0x22, 0x33, 0x44, 0x55,
+	0x08, 0x06,
+}
+
+func TestNewVXLANFrame(t *testing.T) {
+	raw := NewVXLANFrame(0x123456, testMirroredFrame)
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeVXLAN, gopacket.Default)
+	vxlan, ok := pkt.Layer(layers.LayerTypeVXLAN).(*layers.VXLAN)
+	if !ok {
+		t.Fatalf("expected a VXLAN layer, got %v", pkt)
+	} else if !vxlan.ValidIDFlag {
+		t.Fatal("expected the VNI flag to be set")
+	} else if vxlan.VNI != 0x123456 {
+		t.Fatalf("expected VNI 0x123456, got 0x%x", vxlan.VNI)
+	} else if !bytes.Equal(vxlan.Payload, testMirroredFrame) {
+		t.Fatalf("unexpected payload %x", vxlan.Payload)
+	}
+}
+
+func TestNewERSPANFrame(t *testing.T) {
+	raw := NewERSPANFrame(0x3ff, 42, 7, testMirroredFrame)
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeGRE, gopacket.Default)
+	gre, ok := pkt.Layer(layers.LayerTypeGRE).(*layers.GRE)
+	if !ok {
+		t.Fatalf("expected a GRE layer, got %v", pkt)
+	} else if !gre.SeqPresent || gre.Seq != 42 {
+		t.Fatalf("expected sequence 42, got %v/%d", gre.SeqPresent, gre.Seq)
+	} else if gre.Protocol != greProtoERSPANII {
+		t.Fatalf("expected protocol 0x%x, got 0x%x", greProtoERSPANII, uint16(gre.Protocol))
+	}
+
+	hdr := raw[greHeaderSize:]
+	if version := hdr[0] >> 4; version != erspanVersionII {
+		t.Fatalf("expected ERSPAN version %d, got %d", erspanVersionII, version)
+	} else if session := binary.BigEndian.Uint16(hdr[2:]) & 0x3ff; session != 0x3ff {
+		t.Fatalf("expected session 0x3ff, got 0x%x", session)
+	} else if index := binary.BigEndian.Uint32(hdr[4:]) & 0xfffff; index != 7 {
+		t.Fatalf("expected index 7, got %d", index)
+	} else if !bytes.Equal(raw[greHeaderSize+erspanHeaderSize:], testMirroredFrame) {
+		t.Fatalf("unexpected payload %x", raw[greHeaderSize+erspanHeaderSize:])
+	}
+}
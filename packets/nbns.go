@@ -1,7 +1,11 @@
 package packets
 
 import (
+	"encoding/binary"
+	"errors"
+	"net"
 	"strconv"
+	"strings"
 
 	"github.com/evilsocket/islazy/str"
 
@@ -12,8 +16,45 @@ import (
 const (
 	NBNSPort        = 137
 	NBNSMinRespSize = 73
+
+	NBNSOpcodeQuery        = 0
+	NBNSOpcodeRegistration = 5
+	NBNSOpcodeRefresh      = 8
+	NBNSOpcodeMultiHomed   = 15
+
+	// name suffixes
+	NBNSWorkstation      = 0x00
+	NBNSDomainMaster     = 0x1b
+	NBNSDomainController = 0x1c
+	NBNSFileServer       = 0x20
+
+	nbnsHeaderSize = 12
+	nbnsTypeNB     = 0x20
 )
 
+var ErrNBNSTruncated = errors.New("truncated NBNS packet")
+
+// NBNSName is a decoded NetBIOS name and its type suffix.
+type NBNSName struct {
+	Name   string
+	Suffix byte
+}
+
+// NBNSRecord is a name to address mapping of a registration or of a name query
+// response.
+type NBNSRecord struct {
+	NBNSName
+	Group bool
+	IP    net.IP
+}
+
+type NBNSPacket struct {
+	Opcode    int
+	Response  bool
+	Questions []NBNSName
+	Records   []NBNSRecord
+}
+
 var (
 	// NBNS hostname resolution request buffer.
 	NBNSRequest = []byte{
@@ -39,3 +80,110 @@ func NBNSGetMeta(pkt gopacket.Packet) map[string]string {
 	}
 	return nil
 }
+
+func nbnsDecodeName(data []byte, off int, depth int) (NBNSName, int, error) {
+	name := NBNSName{}
+	if off >= len(data) {
+		return name, 0, ErrNBNSTruncated
+	}
+
+	// compressed name (registrations point to the question)
+	if data[off]&0xc0 == 0xc0 {
+		if off+2 > len(data) || depth > 0 {
+			return name, 0, ErrNBNSTruncated
+		}
+		ptr := int(binary.BigEndian.Uint16(data[off:]) & 0x3fff)
+		name, _, err := nbnsDecodeName(data, ptr, depth+1)
+		return name, off + 2, err
+	}
+
+	if data[off] != 32 || off+34 > len(data) {
+		return name, 0, ErrNBNSTruncated
+	}
+
+	// first level encoding, each nibble is a letter from 'A'
+	raw := make([]byte, 16)
+	for i := range raw {
+		hi, lo := data[off+1+i*2]-'A', data[off+2+i*2]-'A'
+		if hi > 15 || lo > 15 {
+			return name, 0, ErrNBNSTruncated
+		}
+		raw[i] = hi<<4 | lo
+	}
+	name.Name = strings.TrimRight(string(raw[:15]), " \x00")
+	name.Suffix = raw[15]
+
+	// skip the scope labels
+	off += 33
+	for off < len(data) && data[off] != 0 {
+		off += int(data[off]) + 1
+	}
+	if off >= len(data) {
+		return name, 0, ErrNBNSTruncated
+	}
+	return name, off + 1, nil
+}
+
+// NBNSParse decodes the names and addresses of a NetBIOS name service packet.
+func NBNSParse(data []byte) (*NBNSPacket, error) {
+	if len(data) < nbnsHeaderSize {
+		return nil, ErrNBNSTruncated
+	}
+
+	flags := binary.BigEndian.Uint16(data[2:])
+	p := &NBNSPacket{
+		Opcode:    int(flags>>11) & 0xf,
+		Response:  flags&0x8000 != 0,
+		Questions: make([]NBNSName, 0),
+		Records:   make([]NBNSRecord, 0),
+	}
+
+	questions := int(binary.BigEndian.Uint16(data[4:]))
+	records := int(binary.BigEndian.Uint16(data[6:])) +
+		int(binary.BigEndian.Uint16(data[8:])) +
+		int(binary.BigEndian.Uint16(data[10:]))
+
+	var err error
+	var name NBNSName
+	off := nbnsHeaderSize
+	for i := 0; i < questions; i++ {
+		if name, off, err = nbnsDecodeName(data, off, 0); err != nil {
+			return nil, err
+		} else if off += 4; off > len(data) {
+			return nil, ErrNBNSTruncated
+		}
+		p.Questions = append(p.Questions, name)
+	}
+
+	// negative responses have no addresses
+	if rcode := flags & 0xf; rcode != 0 {
+		return p, nil
+	}
+
+	for i := 0; i < records; i++ {
+		if name, off, err = nbnsDecodeName(data, off, 0); err != nil {
+			return nil, err
+		} else if off+10 > len(data) {
+			return nil, ErrNBNSTruncated
+		}
+
+		rtype := binary.BigEndian.Uint16(data[off:])
+		size := int(binary.BigEndian.Uint16(data[off+8:]))
+		if off += 10; off+size > len(data) {
+			return nil, ErrNBNSTruncated
+		}
+
+		if rtype == nbnsTypeNB {
+			for e := off; e+6 <= off+size; e += 6 {
+				p.Records = append(p.Records, NBNSRecord{
+					NBNSName: name,
+					Group:    data[e]&0x80 != 0,
+					IP:       net.IP(append([]byte{}, data[e+2:e+6]...)),
+				})
+			}
+		}
+		off += size
+	}
+
+	return p, nil
+}
@@ -0,0 +1,65 @@
+package packets
+
+import (
+	"net"
+	"testing"
+)
+
+func nbnsEncodeName(name string, suffix byte) []byte {
+	raw := []byte(name)
+	for len(raw) < 15 {
+		raw = append(raw, ' ')
+	}
+	raw = append(raw, suffix)
+
+	encoded := []byte{32}
+	for _, b := range raw {
+		encoded = append(encoded, 'A'+(b>>4), 'A'+(b&0xf))
+	}
+	return append(encoded, 0)
+}
+
+func TestNBNSParseRegistration(t *testing.T) {
+	// registration request of CORP<1C>, the record points to the question
+	data := []byte{0x12, 0x34, 0x29, 0x10, 0, 1, 0, 0, 0, 0, 0, 1}
+	data = append(data, nbnsEncodeName("CORP", NBNSDomainController)...)
+	data = append(data, 0, 0x20, 0, 1)
+	data = append(data, 0xc0, 0x0c, 0, 0x20, 0, 1, 0, 0, 0x0e, 0x10, 0, 6, 0x80, 0, 10, 0, 0, 1)
+
+	p, err := NBNSParse(data)
+	if err != nil {
+		t.Fatal(err)
+	} else if p.Opcode != NBNSOpcodeRegistration || p.Response {
+		t.Fatalf("unexpected opcode %d (response=%v)", p.Opcode, p.Response)
+	} else if len(p.Questions) != 1 || p.Questions[0].Name != "CORP" || p.Questions[0].Suffix != NBNSDomainController {
+		t.Fatalf("unexpected questions %+v", p.Questions)
+	} else if len(p.Records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(p.Records))
+	}
+
+	r := p.Records[0]
+	if r.Name != "CORP" || r.Suffix != NBNSDomainController || !r.Group || !r.IP.Equal(net.ParseIP("10.0.0.1")) {
+		t.Fatalf("unexpected record %+v", r)
+	}
+}
+
+func TestNBNSParseResponse(t *testing.T) {
+	// positive name query response with two addresses
+	data := []byte{0x12, 0x34, 0x85, 0x00, 0, 0, 0, 1, 0, 0, 0, 0}
+	data = append(data, nbnsEncodeName("WS01", NBNSWorkstation)...)
+	data = append(data, 0, 0x20, 0, 1, 0, 0, 0, 0, 0, 12)
+	data = append(data, 0, 0, 192, 168, 1, 10, 0, 0, 10, 0, 0, 10)
+
+	p, err := NBNSParse(data)
+	if err != nil {
+		t.Fatal(err)
+	} else if !p.Response || p.Opcode != NBNSOpcodeQuery {
+		t.Fatalf("unexpected opcode %d (response=%v)", p.Opcode, p.Response)
+	} else if len(p.Records) != 2 || p.Records[0].Group || p.Records[1].IP.String() != "10.0.0.10" {
+		t.Fatalf("unexpected records %+v", p.Records)
+	}
+
+	if _, err := NBNSParse(data[:len(data)-4]); err == nil {
+		t.Fatal("expected error for truncated packet")
+	}
+}
@@ -0,0 +1,296 @@
+package packets
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	NetFlowV9 = 9
+	IPFIX     = 10
+
+	// keep the messages below the usual MTU, collectors don't reassemble
+	netflowMaxSize = 1400
+
+	netflowV9HeaderSize = 20
+	ipfixHeaderSize     = 16
+	netflowSetHeader    = 4
+
+	netflowTemplateIPv4 = 256
+	netflowTemplateIPv6 = 257
+)
+
+// information elements, v9 and IPFIX share the ids of these ones
+const (
+	ieOctetDeltaCount          = 1
+	iePacketDeltaCount         = 2
+	ieProtocolIdentifier       = 4
+	ieSourceTransportPort      = 7
+	ieSourceIPv4Address        = 8
+	ieDestinationTransportPort = 11
+	ieDestinationIPv4Address   = 12
+	ieLastSwitched             = 21
+	ieFirstSwitched            = 22
+	ieSourceIPv6Address        = 27
+	ieDestinationIPv6Address   = 28
+	ieFlowStartMilliseconds    = 152
+	ieFlowEndMilliseconds      = 153
+)
+
+type netflowField struct {
+	id   uint16
+	size uint16
+}
+
+// FlowRecord is an unidirectional flow, or the part of it seen since it was
+// last exported.
+type FlowRecord struct {
+	Proto   uint8
+	SrcIP   net.IP
+	SrcPort uint16
+	DstIP   net.IP
+	DstPort uint16
+	Packets uint64
+	Bytes   uint64
+	Start   time.Time
+	End     time.Time
+}
+
+func (r *FlowRecord) isIPv4() bool {
+	return r.SrcIP.To4() != nil && r.DstIP.To4() != nil
+}
+
+// FlowProtocolNumber returns the IP protocol number of the flow protocols
+// tracked by the queue.
+func FlowProtocolNumber(proto string) uint8 {
+	switch proto {
+	case "icmp":
+		return 1
+	case "tcp":
+		return 6
+	case "udp":
+		return 17
+	case "icmp6":
+		return 58
+	case "sctp":
+		return 132
+	}
+	return 0
+}
+
+// NetFlowEncoder serializes flow records as NetFlow v9 or IPFIX messages,
+// keeping the sequence numbers across calls.
+type NetFlowEncoder struct {
+	Version  int
+	SourceID uint32
+
+	boot     time.Time
+	sequence uint32
+}
+
+func NewNetFlowEncoder(version int, sourceID uint32) (*NetFlowEncoder, error) {
+	if version != NetFlowV9 && version != IPFIX {
+		return nil, fmt.Errorf("unsupported flow export version %d", version)
+	}
+	return &NetFlowEncoder{
+		Version:  version,
+		SourceID: sourceID,
+		boot:     time.Now(),
+	}, nil
+}
+
+func (e *NetFlowEncoder) fields(ipv4 bool) []netflowField {
+	fields := make([]netflowField, 0, 9)
+	if ipv4 {
+		fields = append(fields, netflowField{ieSourceIPv4Address, 4}, netflowField{ieDestinationIPv4Address, 4})
+	} else {
+		fields = append(fields, netflowField{ieSourceIPv6Address, 16}, netflowField{ieDestinationIPv6Address, 16})
+	}
+
+	fields = append(fields,
+		netflowField{ieSourceTransportPort, 2},
+		netflowField{ieDestinationTransportPort, 2},
+		netflowField{ieProtocolIdentifier, 1},
+		netflowField{iePacketDeltaCount, 8},
+		netflowField{ieOctetDeltaCount, 8})
+
+	if e.Version == NetFlowV9 {
+		fields = append(fields, netflowField{ieFirstSwitched, 4}, netflowField{ieLastSwitched, 4})
+	} else {
+		fields = append(fields, netflowField{ieFlowStartMilliseconds, 8}, netflowField{ieFlowEndMilliseconds, 8})
+	}
+	return fields
+}
+
+func recordSize(fields []netflowField) int {
+	size := 0
+	for _, f := range fields {
+		size += int(f.size)
+	}
+	return size
+}
+
+func (e *NetFlowEncoder) templateSet() []byte {
+	setID := uint16(0)
+	if e.Version == IPFIX {
+		setID = 2
+	}
+
+	set := []byte{0, 0, 0, 0}
+	binary.BigEndian.PutUint16(set[0:], setID)
+	for _, tpl := range []struct {
+		id   uint16
+		ipv4 bool
+	}{{netflowTemplateIPv4, true}, {netflowTemplateIPv6, false}} {
+		fields := e.fields(tpl.ipv4)
+		set = append(set, byte(tpl.id>>8), byte(tpl.id), byte(len(fields)>>8), byte(len(fields)))
+		for _, f := range fields {
+			set = append(set, byte(f.id>>8), byte(f.id), byte(f.size>>8), byte(f.size))
+		}
+	}
+	binary.BigEndian.PutUint16(set[2:], uint16(len(set)))
+	return set
+}
+
+// uptime returns the milliseconds since the encoder was created, the v9
+// timestamps are relative to it.
+func (e *NetFlowEncoder) uptime(t time.Time) uint32 {
+	if t.Before(e.boot) {
+		return 0
+	}
+	return uint32(t.Sub(e.boot) / time.Millisecond)
+}
+
+func (e *NetFlowEncoder) appendRecord(buf []byte, r *FlowRecord) []byte {
+	if r.isIPv4() {
+		buf = append(buf, r.SrcIP.To4()...)
+		buf = append(buf, r.DstIP.To4()...)
+	} else {
+		buf = append(buf, r.SrcIP.To16()...)
+		buf = append(buf, r.DstIP.To16()...)
+	}
+
+	var tmp [8]byte
+	binary.BigEndian.PutUint16(tmp[:], r.SrcPort)
+	buf = append(buf, tmp[:2]...)
+	binary.BigEndian.PutUint16(tmp[:], r.DstPort)
+	buf = append(buf, tmp[:2]...)
+	buf = append(buf, r.Proto)
+	binary.BigEndian.PutUint64(tmp[:], r.Packets)
+	buf = append(buf, tmp[:]...)
+	binary.BigEndian.PutUint64(tmp[:], r.Bytes)
+	buf = append(buf, tmp[:]...)
+
+	if e.Version == NetFlowV9 {
+		binary.BigEndian.PutUint32(tmp[:], e.uptime(r.Start))
+		buf = append(buf, tmp[:4]...)
+		binary.BigEndian.PutUint32(tmp[:], e.uptime(r.End))
+		buf = append(buf, tmp[:4]...)
+	} else {
+		binary.BigEndian.PutUint64(tmp[:], uint64(r.Start.UnixNano()/int64(time.Millisecond)))
+		buf = append(buf, tmp[:]...)
+		binary.BigEndian.PutUint64(tmp[:], uint64(r.End.UnixNano()/int64(time.Millisecond)))
+		buf = append(buf, tmp[:]...)
+	}
+	return buf
+}
+
+func (e *NetFlowEncoder) headerSize() int {
+	if e.Version == NetFlowV9 {
+		return netflowV9HeaderSize
+	}
+	return ipfixHeaderSize
+}
+
+// finish fills the header of the message, count is the number of templates
+// and records (v9) or of data records (IPFIX) it contains.
+func (e *NetFlowEncoder) finish(msg []byte, templates, records int, now time.Time) []byte {
+	binary.BigEndian.PutUint16(msg[0:], uint16(e.Version))
+	if e.Version == NetFlowV9 {
+		binary.BigEndian.PutUint16(msg[2:], uint16(templates+records))
+		binary.BigEndian.PutUint32(msg[4:], e.uptime(now))
+		binary.BigEndian.PutUint32(msg[8:], uint32(now.Unix()))
+		binary.BigEndian.PutUint32(msg[12:], e.sequence)
+		binary.BigEndian.PutUint32(msg[16:], e.SourceID)
+		e.sequence++
+	} else {
+		binary.BigEndian.PutUint16(msg[2:], uint16(len(msg)))
+		binary.BigEndian.PutUint32(msg[4:], uint32(now.Unix()))
+		binary.BigEndian.PutUint32(msg[8:], e.sequence)
+		binary.BigEndian.PutUint32(msg[12:], e.SourceID)
+		e.sequence += uint32(records)
+	}
+	return msg
+}
+
+// Encode returns the messages for the records, if withTemplates is true the
+// templates are sent in the first one (collectors need them to decode the
+// records and they have to be refreshed periodically over UDP).
+func (e *NetFlowEncoder) Encode(records []FlowRecord, withTemplates bool) [][]byte {
+	messages := make([][]byte, 0)
+	now := time.Now()
+
+	var msg, set []byte
+	templates, count := 0, 0
+	setTemplate := uint16(0)
+
+	newMessage := func() {
+		msg = make([]byte, e.headerSize(), netflowMaxSize)
+		templates, count = 0, 0
+	}
+	closeSet := func() {
+		if len(set) > netflowSetHeader {
+			// v9 flowsets are padded to 32 bits
+			for e.Version == NetFlowV9 && len(set)%4 != 0 {
+				set = append(set, 0)
+			}
+			binary.BigEndian.PutUint16(set[0:], setTemplate)
+			binary.BigEndian.PutUint16(set[2:], uint16(len(set)))
+			msg = append(msg, set...)
+		}
+		set = nil
+	}
+	flush := func() {
+		closeSet()
+		if templates+count > 0 {
+			messages = append(messages, e.finish(msg, templates, count, now))
+		}
+		newMessage()
+	}
+
+	newMessage()
+	if withTemplates {
+		msg = append(msg, e.templateSet()...)
+		templates = 2
+	}
+
+	for i := range records {
+		r := &records[i]
+		tpl := uint16(netflowTemplateIPv6)
+		if r.isIPv4() {
+			tpl = netflowTemplateIPv4
+		}
+		size := recordSize(e.fields(r.isIPv4()))
+
+		if set != nil && tpl != setTemplate {
+			closeSet()
+		}
+		// worst case, including a new set header and the padding
+		extra := size + netflowSetHeader + 3
+		if len(msg)+len(set)+extra > netflowMaxSize {
+			flush()
+		}
+		if set == nil {
+			set = make([]byte, netflowSetHeader, netflowMaxSize)
+			setTemplate = tpl
+		}
+
+		set = e.appendRecord(set, r)
+		count++
+	}
+	flush()
+
+	return messages
+}
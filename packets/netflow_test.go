@@ -0,0 +1,128 @@
+package packets
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+func testFlowRecords(n int, src string) []FlowRecord {
+	records := make([]FlowRecord, n)
+	for i := range records {
+		records[i] = FlowRecord{
+			Proto:   FlowProtocolNumber("tcp"),
+			SrcIP:   net.ParseIP(src),
+			SrcPort: uint16(40000 + i),
+			DstIP:   net.ParseIP("10.0.0.1"),
+			DstPort: 443,
+			Packets: 10,
+			Bytes:   1500,
+			Start:   time.Now().Add(-time.Minute),
+			End:     time.Now(),
+		}
+	}
+	return records
+}
+
+// sets returns the id and size of the sets of a message.
+func netflowSets(t *testing.T, msg []byte, headerSize int) [][2]int {
+	sets := make([][2]int, 0)
+	for off := headerSize; off < len(msg); {
+		if off+4 > len(msg) {
+			t.Fatalf("truncated set at offset %d", off)
+		}
+		id, size := int(binary.BigEndian.Uint16(msg[off:])), int(binary.BigEndian.Uint16(msg[off+2:]))
+		if size < 4 || off+size > len(msg) {
+			t.Fatalf("invalid set size %d at offset %d", size, off)
+		}
+		sets = append(sets, [2]int{id, size})
+		off += size
+	}
+	return sets
+}
+
+func TestNetFlowV9Encode(t *testing.T) {
+	enc, err := NewNetFlowEncoder(NetFlowV9, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := append(testFlowRecords(2, "192.168.1.10"), testFlowRecords(1, "fe80::1")...)
+	records[2].DstIP = net.ParseIP("fe80::2")
+
+	msgs := enc.Encode(records, true)
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(msgs))
+	}
+
+	msg := msgs[0]
+	if v := binary.BigEndian.Uint16(msg[0:]); v != 9 {
+		t.Fatalf("unexpected version %d", v)
+	} else if count := binary.BigEndian.Uint16(msg[2:]); count != 5 {
+		t.Fatalf("expected 2 templates and 3 records, got %d", count)
+	} else if id := binary.BigEndian.Uint32(msg[16:]); id != 42 {
+		t.Fatalf("unexpected source id %d", id)
+	}
+
+	sets := netflowSets(t, msg, netflowV9HeaderSize)
+	if len(sets) != 3 || sets[0][0] != 0 || sets[1][0] != netflowTemplateIPv4 || sets[2][0] != netflowTemplateIPv6 {
+		t.Fatalf("unexpected sets %v", sets)
+	}
+	for _, set := range sets[1:] {
+		if set[1]%4 != 0 {
+			t.Fatalf("flowset %d is not padded: %d bytes", set[0], set[1])
+		}
+	}
+
+	if next := enc.Encode(records[:1], false); binary.BigEndian.Uint32(next[0][12:]) != 1 {
+		t.Fatalf("expected sequence 1, got %d", binary.BigEndian.Uint32(next[0][12:]))
+	}
+}
+
+func TestIPFIXEncode(t *testing.T) {
+	enc, err := NewNetFlowEncoder(IPFIX, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := testFlowRecords(100, "192.168.1.10")
+	msgs := enc.Encode(records, true)
+	if len(msgs) < 2 {
+		t.Fatalf("expected the records to be split, got %d message", len(msgs))
+	}
+
+	total := 0
+	size := recordSize(enc.fields(true))
+	for i, msg := range msgs {
+		if len(msg) > netflowMaxSize {
+			t.Fatalf("message %d is too big: %d bytes", i, len(msg))
+		} else if v := binary.BigEndian.Uint16(msg[0:]); v != 10 {
+			t.Fatalf("unexpected version %d", v)
+		} else if l := binary.BigEndian.Uint16(msg[2:]); int(l) != len(msg) {
+			t.Fatalf("message length %d, expected %d", l, len(msg))
+		} else if seq := binary.BigEndian.Uint32(msg[8:]); int(seq) != total {
+			t.Fatalf("message %d sequence %d, expected %d", i, seq, total)
+		}
+
+		for _, set := range netflowSets(t, msg, ipfixHeaderSize) {
+			if set[0] == 2 {
+				if i != 0 {
+					t.Fatal("templates are only expected in the first message")
+				}
+			} else if set[0] == netflowTemplateIPv4 {
+				total += (set[1] - netflowSetHeader) / size
+			} else {
+				t.Fatalf("unexpected set %d", set[0])
+			}
+		}
+	}
+
+	if total != len(records) {
+		t.Fatalf("expected %d records, got %d", len(records), total)
+	}
+
+	if _, err := NewNetFlowEncoder(5, 0); err == nil {
+		t.Fatal("expected error for unsupported version")
+	}
+}
@@ -0,0 +1,205 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// NTLMSSP message types
+const (
+	NTLMNegotiate    = 1
+	NTLMChallenge    = 2
+	NTLMAuthenticate = 3
+)
+
+// negotiate flags
+const (
+	ntlmNegotiateUnicode    = 0x00000001
+	ntlmRequestTarget       = 0x00000004
+	ntlmNegotiateNTLM       = 0x00000200
+	ntlmNegotiateAlwaysSign = 0x00008000
+	ntlmTargetTypeDomain    = 0x00010000
+	ntlmNegotiateTargetInfo = 0x00800000
+	ntlmNegotiate128        = 0x20000000
+	ntlmNegotiate56         = 0x80000000
+)
+
+// ids of the target info AV pairs
+const (
+	ntlmAvNbComputerName  = 1
+	ntlmAvNbDomainName    = 2
+	ntlmAvDNSComputerName = 3
+	ntlmAvDNSDomainName   = 4
+)
+
+const (
+	ntlmChallengeHeaderSize    = 48
+	ntlmAuthenticateHeaderSize = 64
+)
+
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+// NTLMHash is the NTLM material captured from an AUTHENTICATE message.
+type NTLMHash struct {
+	Domain      string
+	Username    string
+	Workstation string
+	Version     string
+	// hashcat / john compatible representation
+	Hash string
+}
+
+func decodeUTF16(b []byte) string {
+	chars := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		chars = append(chars, binary.LittleEndian.Uint16(b[i:]))
+	}
+	return strings.TrimRight(string(utf16.Decode(chars)), "\x00")
+}
+
+func encodeUTF16(s string) []byte {
+	chars := utf16.Encode([]rune(s))
+	b := make([]byte, len(chars)*2)
+	for i, c := range chars {
+		binary.LittleEndian.PutUint16(b[i*2:], c)
+	}
+	return b
+}
+
+// FindNTLM looks for an NTLMSSP message of the given type inside data and
+// returns it, the message references its fields with offsets so everything
+// from the signature onwards is returned.
+func FindNTLM(data []byte, msgType uint32) []byte {
+	for off := 0; off < len(data); {
+		idx := bytes.Index(data[off:], ntlmSignature)
+		if idx < 0 {
+			return nil
+		}
+
+		msg := data[off+idx:]
+		if len(msg) >= 12 && binary.LittleEndian.Uint32(msg[8:]) == msgType {
+			return msg
+		}
+		off += idx + len(ntlmSignature)
+	}
+	return nil
+}
+
+// NTLMServerChallenge returns the server challenge of a CHALLENGE message.
+func NTLMServerChallenge(msg []byte) []byte {
+	if len(msg) < 32 {
+		return nil
+	}
+	return msg[24:32]
+}
+
+func ntlmField(msg []byte, offset int) []byte {
+	if len(msg) < offset+8 {
+		return nil
+	}
+
+	size := int(binary.LittleEndian.Uint16(msg[offset:]))
+	start := int(binary.LittleEndian.Uint32(msg[offset+4:]))
+	if start+size > len(msg) {
+		return nil
+	}
+	return msg[start : start+size]
+}
+
+func putNTLMField(msg []byte, offset int, size int, start int) {
+	binary.LittleEndian.PutUint16(msg[offset:], uint16(size))
+	binary.LittleEndian.PutUint16(msg[offset+2:], uint16(size))
+	binary.LittleEndian.PutUint32(msg[offset+4:], uint32(start))
+}
+
+// NewNTLMChallenge builds the CHALLENGE message of a server of the given
+// domain, extended session security is not offered so that NTLMv1 clients
+// reply with responses that can be cracked with the challenge alone.
+func NewNTLMChallenge(challenge []byte, domain string, computer string) []byte {
+	target := encodeUTF16(domain)
+
+	info := new(bytes.Buffer)
+	for _, av := range []struct {
+		id    uint16
+		value string
+	}{
+		{ntlmAvNbDomainName, domain},
+		{ntlmAvNbComputerName, computer},
+		{ntlmAvDNSDomainName, domain},
+		{ntlmAvDNSComputerName, computer},
+	} {
+		value := encodeUTF16(av.value)
+		binary.Write(info, binary.LittleEndian, av.id)
+		binary.Write(info, binary.LittleEndian, uint16(len(value)))
+		info.Write(value)
+	}
+	// MsvAvEOL
+	info.Write([]byte{0, 0, 0, 0})
+
+	msg := make([]byte, ntlmChallengeHeaderSize, ntlmChallengeHeaderSize+len(target)+info.Len())
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], NTLMChallenge)
+	putNTLMField(msg, 12, len(target), ntlmChallengeHeaderSize)
+	binary.LittleEndian.PutUint32(msg[20:], ntlmNegotiateUnicode|ntlmRequestTarget|ntlmNegotiateNTLM|
+		ntlmNegotiateAlwaysSign|ntlmTargetTypeDomain|ntlmNegotiateTargetInfo|ntlmNegotiate128|ntlmNegotiate56)
+	copy(msg[24:32], challenge)
+	putNTLMField(msg, 40, info.Len(), ntlmChallengeHeaderSize+len(target))
+
+	msg = append(msg, target...)
+	return append(msg, info.Bytes()...)
+}
+
+// ParseNTLMAuthenticate builds the crackable representation of an
+// AUTHENTICATE message given the server challenge.
+func ParseNTLMAuthenticate(msg, challenge []byte) (error, *NTLMHash) {
+	if len(msg) < ntlmAuthenticateHeaderSize {
+		return fmt.Errorf("AUTHENTICATE message too short (%d bytes)", len(msg)), nil
+	} else if len(challenge) != 8 {
+		return fmt.Errorf("server challenge not captured"), nil
+	}
+
+	lm := ntlmField(msg, 12)
+	nt := ntlmField(msg, 20)
+	flags := binary.LittleEndian.Uint32(msg[60:])
+
+	decode := func(b []byte) string { return string(b) }
+	if flags&ntlmNegotiateUnicode != 0 {
+		decode = decodeUTF16
+	}
+
+	h := &NTLMHash{
+		Domain:      decode(ntlmField(msg, 28)),
+		Username:    decode(ntlmField(msg, 36)),
+		Workstation: decode(ntlmField(msg, 44)),
+	}
+
+	if h.Username == "" {
+		return fmt.Errorf("anonymous authentication"), nil
+	}
+
+	if len(nt) > 24 {
+		h.Version = "NTLMv2"
+		h.Hash = fmt.Sprintf("%s::%s:%s:%s:%s",
+			h.Username,
+			h.Domain,
+			hex.EncodeToString(challenge),
+			hex.EncodeToString(nt[:16]),
+			hex.EncodeToString(nt[16:]))
+	} else if len(nt) == 24 {
+		h.Version = "NTLMv1"
+		h.Hash = fmt.Sprintf("%s::%s:%s:%s:%s",
+			h.Username,
+			h.Domain,
+			hex.EncodeToString(lm),
+			hex.EncodeToString(nt),
+			hex.EncodeToString(challenge))
+	} else {
+		return fmt.Errorf("unexpected NT response size %d", len(nt)), nil
+	}
+
+	return nil, h
+}
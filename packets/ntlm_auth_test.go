@@ -0,0 +1,69 @@
+package packets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+var testServerChallenge = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+// buildTestAuthenticate returns an unicode AUTHENTICATE message with the
+// given responses.
+func buildTestAuthenticate(domain, user, host string, lm, nt []byte) []byte {
+	fields := [][]byte{lm, nt, encodeUTF16(domain), encodeUTF16(user), encodeUTF16(host)}
+	msg := make([]byte, ntlmAuthenticateHeaderSize)
+	copy(msg, ntlmSignature)
+	binary.LittleEndian.PutUint32(msg[8:], NTLMAuthenticate)
+	binary.LittleEndian.PutUint32(msg[60:], ntlmNegotiateUnicode)
+
+	for i, field := range fields {
+		putNTLMField(msg, 12+i*8, len(field), len(msg))
+		msg = append(msg, field...)
+	}
+	return msg
+}
+
+func TestNewNTLMChallenge(t *testing.T) {
+	msg := NewNTLMChallenge(testServerChallenge, "CORP", "FILESRV")
+
+	if found := FindNTLM(append([]byte("garbage"), msg...), NTLMChallenge); !bytes.Equal(found, msg) {
+		t.Fatal("expected the CHALLENGE message to be found")
+	} else if challenge := NTLMServerChallenge(msg); !bytes.Equal(challenge, testServerChallenge) {
+		t.Fatalf("expected challenge %x, got %x", testServerChallenge, challenge)
+	} else if target := decodeUTF16(ntlmField(msg, 12)); target != "CORP" {
+		t.Fatalf("expected target CORP, got '%s'", target)
+	} else if info := ntlmField(msg, 40); !bytes.Contains(info, encodeUTF16("FILESRV")) || !bytes.HasSuffix(info, []byte{0, 0, 0, 0}) {
+		t.Fatalf("unexpected target info %x", info)
+	}
+}
+
+func TestParseNTLMAuthenticate(t *testing.T) {
+	nt := bytes.Repeat([]byte{0xaa}, 16)
+	nt = append(nt, bytes.Repeat([]byte{0xbb}, 32)...)
+	msg := buildTestAuthenticate("CORP", "alice", "LAPTOP", make([]byte, 24), nt)
+
+	err, h := ParseNTLMAuthenticate(msg, testServerChallenge)
+	if err != nil {
+		t.Fatal(err)
+	} else if h.Version != "NTLMv2" || h.Domain != "CORP" || h.Username != "alice" || h.Workstation != "LAPTOP" {
+		t.Fatalf("unexpected hash %+v", h)
+	} else if !strings.HasPrefix(h.Hash, "alice::CORP:0102030405060708:aaaaaaaa") {
+		t.Fatalf("unexpected representation %s", h.Hash)
+	}
+
+	msg = buildTestAuthenticate("CORP", "bob", "LAPTOP", bytes.Repeat([]byte{0xcc}, 24), bytes.Repeat([]byte{0xdd}, 24))
+	if err, h = ParseNTLMAuthenticate(msg, testServerChallenge); err != nil {
+		t.Fatal(err)
+	} else if h.Version != "NTLMv1" || !strings.HasSuffix(h.Hash, ":0102030405060708") {
+		t.Fatalf("unexpected hash %+v", h)
+	}
+
+	msg = buildTestAuthenticate("", "", "LAPTOP", nil, nil)
+	if err, _ = ParseNTLMAuthenticate(msg, testServerChallenge); err == nil {
+		t.Fatal("expected anonymous authentication to be refused")
+	} else if err, _ = ParseNTLMAuthenticate(msg[:32], testServerChallenge); err == nil {
+		t.Fatal("expected a truncated message to be refused")
+	}
+}
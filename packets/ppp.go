@@ -0,0 +1,139 @@
+package packets
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	PPPTypePAP  layers.PPPType = 0xc023
+	PPPTypeCHAP layers.PPPType = 0xc223
+
+	PPPAuthRequest  = 1
+	PPPAuthResponse = 2
+	PPPAuthSuccess  = 3
+	PPPAuthFailure  = 4
+
+	PPPoETagServiceName = 0x0101
+	PPPoETagACName      = 0x0102
+	PPPoETagHostUniq    = 0x0103
+)
+
+var PPPoECodeNames = map[layers.PPPoECode]string{
+	layers.PPPoECodePADI: "PADI",
+	layers.PPPoECodePADO: "PADO",
+	layers.PPPoECodePADR: "PADR",
+	layers.PPPoECodePADS: "PADS",
+	layers.PPPoECodePADT: "PADT",
+}
+
+// PPPAuth holds a decoded PAP or CHAP message.
+type PPPAuth struct {
+	Type     layers.PPPType
+	Code     uint8
+	ID       uint8
+	Name     string
+	Password string
+	Value    []byte
+	Message  string
+}
+
+func (a *PPPAuth) Protocol() string {
+	if a.Type == PPPTypePAP {
+		return "pap"
+	}
+	return "chap"
+}
+
+func pppReadString(raw []byte, offset int) (error, []byte, int) {
+	if offset >= len(raw) {
+		return fmt.Errorf("unexpected end of buffer"), nil, offset
+	}
+	size := int(raw[offset])
+	offset++
+	if offset+size > len(raw) {
+		return fmt.Errorf("field size %d exceeds buffer", size), nil, offset
+	}
+	return nil, raw[offset : offset+size], offset + size
+}
+
+// PPPParseAuth decodes the payload of a PAP or CHAP PPP frame.
+func PPPParseAuth(t layers.PPPType, raw []byte) (error, *PPPAuth) {
+	if t != PPPTypePAP && t != PPPTypeCHAP {
+		return fmt.Errorf("unsupported PPP protocol 0x%04x", uint16(t)), nil
+	} else if len(raw) < 4 {
+		return fmt.Errorf("PPP authentication frame too short (%d bytes)", len(raw)), nil
+	}
+
+	auth := &PPPAuth{
+		Type: t,
+		Code: raw[0],
+		ID:   raw[1],
+	}
+
+	size := int(binary.BigEndian.Uint16(raw[2:4]))
+	if size < 4 || size > len(raw) {
+		return fmt.Errorf("invalid PPP authentication length %d", size), nil
+	}
+	raw = raw[:size]
+
+	var err error
+	var field []byte
+	offset := 4
+
+	if auth.Code == PPPAuthSuccess || auth.Code == PPPAuthFailure {
+		if t == PPPTypePAP {
+			if err, field, _ = pppReadString(raw, offset); err == nil {
+				auth.Message = string(field)
+			}
+		} else {
+			auth.Message = string(raw[offset:])
+		}
+		return nil, auth
+	}
+
+	if t == PPPTypePAP {
+		if auth.Code != PPPAuthRequest {
+			return fmt.Errorf("unexpected PAP code %d", auth.Code), nil
+		} else if err, field, offset = pppReadString(raw, offset); err != nil {
+			return err, nil
+		}
+		auth.Name = string(field)
+		if err, field, offset = pppReadString(raw, offset); err != nil {
+			return err, nil
+		}
+		auth.Password = string(field)
+	} else {
+		if err, field, offset = pppReadString(raw, offset); err != nil {
+			return err, nil
+		}
+		auth.Value = field
+		auth.Name = string(raw[offset:])
+	}
+
+	return nil, auth
+}
+
+// PPPoEParseTags decodes the tags of a PPPoE discovery frame.
+func PPPoEParseTags(raw []byte) map[uint16][]byte {
+	tags := make(map[uint16][]byte)
+	for len(raw) >= 4 {
+		t := binary.BigEndian.Uint16(raw[0:2])
+		size := int(binary.BigEndian.Uint16(raw[2:4]))
+		if 4+size > len(raw) {
+			break
+		}
+		tags[t] = raw[4 : 4+size]
+		raw = raw[4+size:]
+	}
+	return tags
+}
+
+// CHAPHash returns a john the ripper compatible representation of
+// a CHAP exchange given the challenge and the response.
+func CHAPHash(id uint8, challenge, response []byte) string {
+	return fmt.Sprintf("$chap$%d*%s*%s", id, hex.EncodeToString(challenge), hex.EncodeToString(response))
+}
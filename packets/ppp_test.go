@@ -0,0 +1,67 @@
+package packets
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPPPParseAuthPAP(t *testing.T) {
+	raw := []byte{
+		PPPAuthRequest, 0x01, 0x00, 0x0e,
+		0x04, 'u', 's', 'e', 'r',
+		0x04, 'p', 'a', 's', 's',
+	}
+
+	err, auth := PPPParseAuth(PPPTypePAP, raw)
+	if err != nil {
+		t.Fatal(err)
+	} else if auth.Protocol() != "pap" {
+		t.Fatalf("expected pap, got %s", auth.Protocol())
+	} else if auth.Name != "user" || auth.Password != "pass" {
+		t.Fatalf("unexpected credentials %s:%s", auth.Name, auth.Password)
+	}
+}
+
+func TestPPPParseAuthCHAP(t *testing.T) {
+	raw := []byte{
+		PPPAuthResponse, 0x07, 0x00, 0x0d,
+		0x04, 0xde, 0xad, 0xbe, 0xef,
+		'u', 's', 'e', 'r',
+	}
+
+	err, auth := PPPParseAuth(PPPTypeCHAP, raw)
+	if err != nil {
+		t.Fatal(err)
+	} else if auth.ID != 7 || auth.Name != "user" {
+		t.Fatalf("unexpected id=%d name=%s", auth.ID, auth.Name)
+	} else if !bytes.Equal(auth.Value, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Fatalf("unexpected value %x", auth.Value)
+	}
+
+	exp := "$chap$7*01020304*deadbeef"
+	if got := CHAPHash(auth.ID, []byte{1, 2, 3, 4}, auth.Value); got != exp {
+		t.Fatalf("expected %s, got %s", exp, got)
+	}
+}
+
+func TestPPPParseAuthInvalid(t *testing.T) {
+	if err, _ := PPPParseAuth(PPPTypePAP, []byte{PPPAuthRequest, 0x01, 0x00, 0x20, 0x01}); err == nil {
+		t.Fatal("expected error for invalid length")
+	} else if err, _ := PPPParseAuth(0x0021, []byte{0, 0, 0, 4}); err == nil {
+		t.Fatal("expected error for unsupported protocol")
+	}
+}
+
+func TestPPPoEParseTags(t *testing.T) {
+	raw := []byte{
+		0x01, 0x01, 0x00, 0x00,
+		0x01, 0x02, 0x00, 0x03, 'i', 's', 'p',
+	}
+
+	tags := PPPoEParseTags(raw)
+	if name, found := tags[PPPoETagACName]; !found || string(name) != "isp" {
+		t.Fatalf("unexpected AC name %v", tags)
+	} else if _, found := tags[PPPoETagServiceName]; !found {
+		t.Fatal("expected empty service name tag")
+	}
+}
@@ -14,6 +14,9 @@ import (
 	"github.com/google/gopacket/pcap"
 )
 
+// CaptureSnapLen is the number of bytes captured of each packet.
+const CaptureSnapLen = 1024
+
 type Activity struct {
 	IP     net.IP
 	MAC    net.HardwareAddr
@@ -35,48 +38,79 @@ type Stats struct {
 
 type PacketCallback func(pkt gopacket.Packet)
 
+// Queue captures the packets of an interface, keeping the stats of the
+// traffic and publishing them to its subscribers, and sends packets.
 type Queue struct {
 	sync.RWMutex
 
 	Activities chan Activity
-	Stats      Stats
+	// the number of packets of each protocol, int values
 	Protos     sync.Map
 	Traffic    sync.Map
+	Redundancy RedundancyGroups
 
 	iface      *network.Endpoint
 	handle     *pcap.Handle
+	injector   Injector
+	native     bool
 	source     *gopacket.PacketSource
 	srcChannel chan gopacket.Packet
 	writes     *sync.WaitGroup
-	pktCb      PacketCallback
+	stats      queueStats
+	subs       atomic.Value
+	subsLock   sync.Mutex
+	pktSub     *Subscriber
+	filter     func(raw []byte) bool
+	dryRun     func(raw []byte)
 	active     bool
+
+	injectLock  sync.Mutex
+	jobs        chan sendJob
+	sendersOnce sync.Once
+	flows       *network.Flows
+	procLock    sync.Mutex
 }
 
 type queueJSON struct {
-	Stats   Stats               `json:"stats"`
-	Protos  map[string]int      `json:"protos"`
-	Traffic map[string]*Traffic `json:"traffic"`
+	Stats       Stats               `json:"stats"`
+	Protos      map[string]int      `json:"protos"`
+	Traffic     map[string]*Traffic `json:"traffic"`
+	Redundancy  []*RedundancyGroup  `json:"redundancy"`
+	Subscribers []SubscriberStats   `json:"subscribers"`
 }
 
-func NewQueue(iface *network.Endpoint) (q *Queue, err error) {
+// NewQueue opens the interface for capturing and, unless pcapInject is true, a native
+// injector to send packets with, falling back to libpcap if it can't be opened.
+func NewQueue(iface *network.Endpoint, pcapInject bool) (q *Queue, err error) {
 	q = &Queue{
 		Protos:     sync.Map{},
 		Traffic:    sync.Map{},
 		Activities: make(chan Activity),
 
 		writes: &sync.WaitGroup{},
+		jobs:   make(chan sendJob, sendBacklog),
 		iface:  iface,
 		active: !iface.IsMonitor(),
-		pktCb:  nil,
 	}
 
 	if q.active {
-		if q.handle, err = pcap.OpenLive(iface.Name(), 1024, true, pcap.BlockForever); err != nil {
+		if q.handle, err = pcap.OpenLive(iface.Name(), CaptureSnapLen, true, pcap.BlockForever); err != nil {
 			return
 		}
 
+		q.injector = q.handle
+		if !pcapInject {
+			if injector, err := NewNativeInjector(iface.Name()); err == nil {
+				q.injector = injector
+				q.native = true
+			}
+		}
+
 		q.source = gopacket.NewPacketSource(q.handle, q.handle.LinkType())
 		q.srcChannel = q.source.Packets()
+		// the queue itself is just another subscriber, in order not to delay
+		// the others when the activities are consumed slowly
+		q.Subscribe("queue", coreBuffer, q.process)
 		go q.worker()
 	}
 
@@ -87,9 +121,11 @@ func (q *Queue) MarshalJSON() ([]byte, error) {
 	q.Lock()
 	defer q.Unlock()
 	doc := queueJSON{
-		Stats:   q.Stats,
-		Protos:  make(map[string]int),
-		Traffic: make(map[string]*Traffic),
+		Stats:       q.Stats(),
+		Protos:      make(map[string]int),
+		Traffic:     make(map[string]*Traffic),
+		Redundancy:  q.Redundancy.List(),
+		Subscribers: q.Subscribers(),
 	}
 
 	q.Protos.Range(func(k, v interface{}) bool {
@@ -98,25 +134,28 @@ func (q *Queue) MarshalJSON() ([]byte, error) {
 	})
 
 	q.Traffic.Range(func(k, v interface{}) bool {
-		doc.Traffic[k.(string)] = v.(*Traffic)
+		doc.Traffic[k.(string)] = v.(*Traffic).Load()
 		return true
 	})
 
 	return json.Marshal(doc)
 }
 
+// OnPacket sets the subscriber used by the scanners to collect the replies
+// to their probes, nil removes it.
 func (q *Queue) OnPacket(cb PacketCallback) {
-	q.Lock()
-	defer q.Unlock()
-	q.pktCb = cb
-}
-
-func (q *Queue) onPacketCallback(pkt gopacket.Packet) {
-	q.RLock()
-	defer q.RUnlock()
-
-	if q.pktCb != nil {
-		q.pktCb(pkt)
+	q.subsLock.Lock()
+	prev := q.pktSub
+	q.pktSub = nil
+	q.subsLock.Unlock()
+
+	q.Unsubscribe(prev)
+
+	if cb != nil {
+		sub := q.Subscribe("callback", DefaultSubscriberBuffer, cb)
+		q.subsLock.Lock()
+		q.pktSub = sub
+		q.subsLock.Unlock()
 	}
 }
 
@@ -129,6 +168,7 @@ func (q *Queue) trackProtocols(pkt gopacket.Packet) {
 			continue
 		}
 
+		// process is the only writer, holding procLock
 		name := proto.String()
 		if v, found := q.Protos.Load(name); !found {
 			q.Protos.Store(name, 1)
@@ -149,32 +189,42 @@ func (q *Queue) trackActivity(eth *layers.Ethernet, ip4 *layers.IPv4, address ne
 
 	// initialize or update stats
 	addr := address.String()
-	if v, found := q.Traffic.Load(addr); !found {
-		if isSent {
-			q.Traffic.Store(addr, &Traffic{Sent: pktSize})
-		} else {
-			q.Traffic.Store(addr, &Traffic{Received: pktSize})
-		}
+	v, found := q.Traffic.Load(addr)
+	if !found {
+		v, _ = q.Traffic.LoadOrStore(addr, &Traffic{})
+	}
+	if isSent {
+		atomic.AddUint64(&v.(*Traffic).Sent, pktSize)
 	} else {
-		if isSent {
-			v.(*Traffic).Sent += pktSize
-		} else {
-			v.(*Traffic).Received += pktSize
-		}
+		atomic.AddUint64(&v.(*Traffic).Received, pktSize)
+	}
+}
+
+// Load returns a copy of the traffic that is safe to read while it's updated.
+func (t *Traffic) Load() *Traffic {
+	return &Traffic{
+		Sent:     atomic.LoadUint64(&t.Sent),
+		Received: atomic.LoadUint64(&t.Received),
 	}
 }
 
+// Stats returns the totals of the traffic seen and sent by the queue. It
+// replaces the Stats field of the previous versions, the counters are now
+// sharded by CPU and can only be read as a whole through this snapshot.
+func (q *Queue) Stats() Stats {
+	return q.stats.snapshot()
+}
+
 func (q *Queue) TrackPacket(size uint64) {
-	atomic.AddUint64(&q.Stats.PktReceived, 1)
-	atomic.AddUint64(&q.Stats.Received, size)
+	q.stats.addReceived(size)
 }
 
 func (q *Queue) TrackSent(size uint64) {
-	atomic.AddUint64(&q.Stats.Sent, size)
+	q.stats.addSent(size)
 }
 
 func (q *Queue) TrackError() {
-	atomic.AddUint64(&q.Stats.Errors, 1)
+	q.stats.addError()
 }
 
 func (q *Queue) getPacketMeta(pkt gopacket.Packet) map[string]string {
@@ -185,6 +235,8 @@ func (q *Queue) getPacketMeta(pkt gopacket.Packet) map[string]string {
 		meta = nbns
 	} else if upnp := UPNPGetMeta(pkt); upnp != nil {
 		meta = upnp
+	} else if _, dhcp := DHCP4GetMeta(pkt); dhcp != nil {
+		meta = dhcp
 	}
 	return meta
 }
@@ -195,62 +247,121 @@ func (q *Queue) worker() {
 			return
 		}
 
-		q.trackProtocols(pkt)
+		q.TrackPacket(uint64(len(pkt.Data())))
+		q.publish(pkt)
+	}
+}
+
+// Feed processes a packet read from somewhere else than the interface, like
+// an offline capture, as if it was captured live, except that it's not
+// published to the subscribers.
+func (q *Queue) Feed(pkt gopacket.Packet) {
+	q.TrackPacket(uint64(len(pkt.Data())))
+	q.process(pkt)
+}
 
-		pktSize := uint64(len(pkt.Data()))
+// process tracks the protocols, flows, traffic and activities of a packet,
+// the lock is only contended when packets are fed while capturing.
+func (q *Queue) process(pkt gopacket.Packet) {
+	q.procLock.Lock()
+	defer q.procLock.Unlock()
 
-		q.TrackPacket(pktSize)
-		q.onPacketCallback(pkt)
+	q.trackProtocols(pkt)
 
-		// decode eth and ipv4 layers
-		leth := pkt.Layer(layers.LayerTypeEthernet)
-		lip4 := pkt.Layer(layers.LayerTypeIPv4)
-		if leth != nil && lip4 != nil {
-			eth := leth.(*layers.Ethernet)
-			ip4 := lip4.(*layers.IPv4)
+	pktSize := uint64(len(pkt.Data()))
 
-			// here we try to discover new hosts
-			// on this lan by inspecting packets
-			// we manage to sniff
+	q.trackFlow(pkt, pktSize)
 
-			// something coming from someone on the LAN
-			isFromMe := q.iface.IP.Equal(ip4.SrcIP)
-			isFromLAN := q.iface.Net.Contains(ip4.SrcIP)
-			if !isFromMe && isFromLAN {
-				meta := q.getPacketMeta(pkt)
+	// decode eth and ipv4 layers
+	leth := pkt.Layer(layers.LayerTypeEthernet)
+	lip4 := pkt.Layer(layers.LayerTypeIPv4)
+	if leth != nil && lip4 != nil {
+		eth := leth.(*layers.Ethernet)
+		ip4 := lip4.(*layers.IPv4)
 
-				q.trackActivity(eth, ip4, ip4.SrcIP, meta, pktSize, true)
-			}
+		// VRRP and HSRP routers sharing a virtual gateway address
+		q.Redundancy.Track(pkt)
 
-			// something going to someone on the LAN
-			isToMe := q.iface.IP.Equal(ip4.DstIP)
-			isToLAN := q.iface.Net.Contains(ip4.DstIP)
-			if !isToMe && isToLAN {
-				q.trackActivity(eth, ip4, ip4.DstIP, nil, pktSize, false)
+		// here we try to discover new hosts
+		// on this lan by inspecting packets
+		// we manage to sniff
+
+		// something coming from someone on the LAN
+		isFromMe := q.iface.IP.Equal(ip4.SrcIP)
+		isFromLAN := q.iface.Net.Contains(ip4.SrcIP)
+		if !isFromMe && isFromLAN {
+			meta := q.getPacketMeta(pkt)
+
+			q.trackActivity(eth, ip4, ip4.SrcIP, meta, pktSize, true)
+		}
+
+		// DHCP clients without an address yet, use the one they're asking for
+		if ip4.SrcIP.Equal(net.IPv4zero) {
+			if address, meta := DHCP4GetMeta(pkt); address != nil && q.iface.Net.Contains(address) {
+				q.trackActivity(eth, ip4, address, meta, pktSize, true)
 			}
 		}
+
+		// something going to someone on the LAN
+		isToMe := q.iface.IP.Equal(ip4.DstIP)
+		isToLAN := q.iface.Net.Contains(ip4.DstIP)
+		if !isToMe && isToLAN {
+			q.trackActivity(eth, ip4, ip4.DstIP, nil, pktSize, false)
+		}
 	}
 }
 
 func (q *Queue) Send(raw []byte) error {
-	q.Lock()
-	defer q.Unlock()
+	q.RLock()
+	defer q.RUnlock()
 
 	if !q.active {
 		return fmt.Errorf("Packet queue is not active.")
 	}
 
-	q.writes.Add(1)
-	defer q.writes.Done()
+	return q.write(raw)
+}
 
-	if err := q.handle.WritePacketData(raw); err != nil {
-		q.TrackError()
-		return err
-	} else {
-		q.TrackSent(uint64(len(raw)))
+// Native returns true if packets are sent with a native injector instead of libpcap.
+func (q *Queue) Native() bool {
+	return q.native
+}
+
+// SetFilter sets a callback deciding whether a packet can be sent, the ones it rejects are silently dropped.
+func (q *Queue) SetFilter(cb func(raw []byte) bool) {
+	q.Lock()
+	defer q.Unlock()
+	q.filter = cb
+}
+
+// SetDryRun makes the queue pass the packets to the callback instead of sending them, nil disables it.
+func (q *Queue) SetDryRun(cb func(raw []byte)) {
+	q.Lock()
+	defer q.Unlock()
+	q.dryRun = cb
+}
+
+// CaptureStats returns the packets received and dropped by libpcap, nil if
+// the queue is not capturing.
+func (q *Queue) CaptureStats() (*pcap.Stats, error) {
+	q.RLock()
+	defer q.RUnlock()
+
+	if !q.active {
+		return nil, nil
 	}
+	return q.handle.Stats()
+}
 
-	return nil
+// LinkType returns the link type of the captured packets.
+func (q *Queue) LinkType() layers.LinkType {
+	q.RLock()
+	defer q.RUnlock()
+
+	if q.handle == nil {
+		return layers.LinkTypeEthernet
+	}
+	return q.handle.LinkType()
 }
 
 func (q *Queue) Stop() {
@@ -258,11 +369,16 @@ func (q *Queue) Stop() {
 	defer q.Unlock()
 
 	if q.active {
+		q.stopSubscribers()
 		// wait for write operations to be completed
 		q.writes.Wait()
 		// signal the main loop to exit and close the handle
 		q.active = false
+		close(q.jobs)
 		q.srcChannel <- nil
+		if q.native {
+			q.injector.Close()
+		}
 		q.handle.Close()
 	}
 }
@@ -0,0 +1,78 @@
+package packets
+
+import (
+	"net"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// TrackFlows makes the queue update the connection tracking table with every
+// packet it sees, nil disables it.
+func (q *Queue) TrackFlows(flows *network.Flows) {
+	q.Lock()
+	defer q.Unlock()
+	q.flows = flows
+}
+
+// FlowOf returns the protocol, addresses, ports and TCP flags of a packet
+// for connection tracking, ok is false for non IP packets.
+func FlowOf(pkt gopacket.Packet) (proto string, srcIP net.IP, srcPort int, dstIP net.IP, dstPort int, flags int, ok bool) {
+	if l := pkt.Layer(layers.LayerTypeIPv4); l != nil {
+		ip4 := l.(*layers.IPv4)
+		srcIP, dstIP = ip4.SrcIP, ip4.DstIP
+	} else if l := pkt.Layer(layers.LayerTypeIPv6); l != nil {
+		ip6 := l.(*layers.IPv6)
+		srcIP, dstIP = ip6.SrcIP, ip6.DstIP
+	} else {
+		return
+	}
+
+	ok = true
+	if l := pkt.Layer(layers.LayerTypeTCP); l != nil {
+		tcp := l.(*layers.TCP)
+		proto = "tcp"
+		srcPort, dstPort = int(tcp.SrcPort), int(tcp.DstPort)
+		if tcp.SYN {
+			flags |= network.FlowSYN
+		}
+		if tcp.ACK {
+			flags |= network.FlowACK
+		}
+		if tcp.FIN {
+			flags |= network.FlowFIN
+		}
+		if tcp.RST {
+			flags |= network.FlowRST
+		}
+	} else if l := pkt.Layer(layers.LayerTypeUDP); l != nil {
+		udp := l.(*layers.UDP)
+		proto = "udp"
+		srcPort, dstPort = int(udp.SrcPort), int(udp.DstPort)
+	} else if pkt.Layer(layers.LayerTypeICMPv4) != nil {
+		proto = "icmp"
+	} else if pkt.Layer(layers.LayerTypeICMPv6) != nil {
+		proto = "icmp6"
+	} else if pkt.Layer(layers.LayerTypeSCTP) != nil {
+		sctp := pkt.Layer(layers.LayerTypeSCTP).(*layers.SCTP)
+		proto = "sctp"
+		srcPort, dstPort = int(sctp.SrcPort), int(sctp.DstPort)
+	} else {
+		ok = false
+	}
+	return
+}
+
+func (q *Queue) trackFlow(pkt gopacket.Packet, size uint64) {
+	q.RLock()
+	flows := q.flows
+	q.RUnlock()
+
+	if flows == nil {
+		return
+	} else if proto, srcIP, srcPort, dstIP, dstPort, flags, ok := FlowOf(pkt); ok {
+		flows.Track(proto, srcIP, srcPort, dstIP, dstPort, int(size), flags)
+	}
+}
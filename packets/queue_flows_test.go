@@ -0,0 +1,48 @@
+package packets
+
+import (
+	"net"
+	"testing"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func TestFlowOf(t *testing.T) {
+	from := net.ParseIP("192.168.1.10").To4()
+	to := net.ParseIP("10.0.0.1").To4()
+	hw, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+
+	err, raw := NewTCPSyn(from, hw, to, hw, 50000, 443)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkt := gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	proto, srcIP, srcPort, dstIP, dstPort, flags, ok := FlowOf(pkt)
+	if !ok {
+		t.Fatal("expected a flow")
+	} else if proto != "tcp" || !srcIP.Equal(from) || srcPort != 50000 || !dstIP.Equal(to) || dstPort != 443 {
+		t.Fatalf("unexpected flow %s %s:%d -> %s:%d", proto, srcIP, srcPort, dstIP, dstPort)
+	} else if flags != network.FlowSYN {
+		t.Fatalf("expected only SYN, got %d", flags)
+	}
+
+	if err, raw = NewUDPProbe(from, hw, to, 53); err != nil {
+		t.Fatal(err)
+	}
+	pkt = gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	if proto, _, _, _, dstPort, _, ok = FlowOf(pkt); !ok || proto != "udp" || dstPort != 53 {
+		t.Fatalf("unexpected flow %s %d %v", proto, dstPort, ok)
+	}
+
+	if err, raw = NewARPRequest(from, hw, to); err != nil {
+		t.Fatal(err)
+	}
+	pkt = gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default)
+	if _, _, _, _, _, _, ok = FlowOf(pkt); ok {
+		t.Fatal("ARP packets are not flows")
+	}
+}
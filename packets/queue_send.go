@@ -0,0 +1,117 @@
+package packets
+
+import (
+	"fmt"
+	"runtime"
+)
+
+const (
+	// how many batches can be waiting for a sender goroutine
+	sendBacklog = 256
+	// the maximum number of sender goroutines per interface
+	maxSendWorkers = 4
+)
+
+// SendCallback is called once a batch of frames submitted with SendAsync
+// has been sent, err is the last error that occurred, if any.
+type SendCallback func(sent int, err error)
+
+type sendJob struct {
+	frames [][]byte
+	cb     SendCallback
+}
+
+// write must be called with the read lock held, pcap handles are not safe to
+// be written concurrently while native sockets are.
+func (q *Queue) write(raw []byte) error {
+	if q.filter != nil && !q.filter(raw) {
+		return nil
+	} else if q.dryRun != nil {
+		q.dryRun(raw)
+		return nil
+	}
+
+	q.writes.Add(1)
+	defer q.writes.Done()
+
+	if !q.native {
+		q.injectLock.Lock()
+		defer q.injectLock.Unlock()
+	}
+
+	if err := q.injector.WritePacketData(raw); err != nil {
+		q.TrackError()
+		return err
+	}
+
+	q.TrackSent(uint64(len(raw)))
+	return nil
+}
+
+func (q *Queue) sendBatch(frames [][]byte) (sent int, err error) {
+	if !q.active {
+		return 0, fmt.Errorf("Packet queue is not active.")
+	}
+
+	for _, raw := range frames {
+		if werr := q.write(raw); werr != nil {
+			err = werr
+		} else {
+			sent++
+		}
+	}
+	return
+}
+
+// SendBatch sends the frames in order acquiring the queue only once, frames
+// that fail are counted as errors without interrupting the batch.
+func (q *Queue) SendBatch(frames [][]byte) (int, error) {
+	q.RLock()
+	defer q.RUnlock()
+	return q.sendBatch(frames)
+}
+
+func (q *Queue) startSenders() {
+	workers := 1
+	if q.native {
+		if workers = runtime.NumCPU(); workers > maxSendWorkers {
+			workers = maxSendWorkers
+		}
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range q.jobs {
+				sent, err := q.SendBatch(job.frames)
+				if job.cb != nil {
+					job.cb(sent, err)
+				}
+			}
+		}()
+	}
+}
+
+// SendAsync hands the batch to the sender goroutines of the interface, or
+// sends it right away if all of them are busy, cb can be nil.
+func (q *Queue) SendAsync(frames [][]byte, cb SendCallback) {
+	q.RLock()
+	defer q.RUnlock()
+
+	if !q.active {
+		if cb != nil {
+			cb(0, fmt.Errorf("Packet queue is not active."))
+		}
+		return
+	}
+
+	q.sendersOnce.Do(q.startSenders)
+
+	select {
+	case q.jobs <- sendJob{frames: frames, cb: cb}:
+	default:
+		sent, err := q.sendBatch(frames)
+		if cb != nil {
+			cb(sent, err)
+		}
+	}
+}
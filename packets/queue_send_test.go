@@ -0,0 +1,82 @@
+package packets
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+type fakeInjector struct {
+	sync.Mutex
+	frames [][]byte
+	fail   bool
+}
+
+func (f *fakeInjector) WritePacketData(data []byte) error {
+	f.Lock()
+	defer f.Unlock()
+	if f.fail && len(data) == 1 {
+		return fmt.Errorf("write failed")
+	}
+	f.frames = append(f.frames, data)
+	return nil
+}
+
+func (f *fakeInjector) Close() {}
+
+func newFakeQueue(injector Injector) *Queue {
+	return &Queue{
+		injector: injector,
+		native:   true,
+		active:   true,
+		writes:   &sync.WaitGroup{},
+		jobs:     make(chan sendJob, sendBacklog),
+	}
+}
+
+func TestQueueSendBatch(t *testing.T) {
+	injector := &fakeInjector{fail: true}
+	q := newFakeQueue(injector)
+
+	sent, err := q.SendBatch([][]byte{{1, 2}, {3}, {4, 5}})
+	if err == nil {
+		t.Error("expected error")
+	} else if sent != 2 {
+		t.Errorf("expected 2 frames sent, got %d", sent)
+	} else if len(injector.frames) != 2 {
+		t.Errorf("expected 2 frames written, got %d", len(injector.frames))
+	} else if stats := q.Stats(); stats.Errors != 1 || stats.Sent != 4 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestQueueSendAsync(t *testing.T) {
+	injector := &fakeInjector{}
+	q := newFakeQueue(injector)
+	q.filter = func(raw []byte) bool {
+		return raw[0] != 0
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		q.SendAsync([][]byte{{byte(i)}, {0}}, func(sent int, err error) {
+			defer wg.Done()
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if len(injector.frames) != 10 {
+		t.Errorf("expected 10 frames written, got %d", len(injector.frames))
+	}
+
+	q.active = false
+	q.SendAsync([][]byte{{1}}, func(sent int, err error) {
+		if err == nil || sent != 0 {
+			t.Errorf("expected error on inactive queue, got %d %v", sent, err)
+		}
+	})
+}
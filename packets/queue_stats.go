@@ -0,0 +1,91 @@
+package packets
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// the size of a cache line on most CPUs
+const cacheLineSize = 64
+
+// statsShard is padded to its own cache line so that the shards updated by
+// different CPUs don't invalidate each other.
+type statsShard struct {
+	sent        uint64
+	received    uint64
+	pktReceived uint64
+	errors      uint64
+	_           [cacheLineSize - 32]byte
+}
+
+// queueStats are the counters of the queue sharded by CPU: the shards are
+// handed out by a sync.Pool, whose objects are local to the processor getting
+// them, so that the counters are updated without any lock and each CPU mostly
+// touches its own shard. The zero value is ready to be used.
+type queueStats struct {
+	once   sync.Once
+	pool   sync.Pool
+	lock   sync.Mutex
+	shards []*statsShard
+	next   int
+}
+
+// newShard is called by the pool when the processor has no shard, which can
+// happen again after a garbage collection empties the pool: once there's one
+// shard per processor the existing ones are reused.
+func (s *queueStats) newShard() interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.shards) < runtime.GOMAXPROCS(0) {
+		shard := &statsShard{}
+		s.shards = append(s.shards, shard)
+		return shard
+	}
+
+	shard := s.shards[s.next%len(s.shards)]
+	s.next++
+	return shard
+}
+
+func (s *queueStats) get() *statsShard {
+	s.once.Do(func() {
+		s.pool.New = s.newShard
+	})
+	return s.pool.Get().(*statsShard)
+}
+
+func (s *queueStats) addReceived(size uint64) {
+	shard := s.get()
+	atomic.AddUint64(&shard.pktReceived, 1)
+	atomic.AddUint64(&shard.received, size)
+	s.pool.Put(shard)
+}
+
+func (s *queueStats) addSent(size uint64) {
+	shard := s.get()
+	atomic.AddUint64(&shard.sent, size)
+	s.pool.Put(shard)
+}
+
+func (s *queueStats) addError() {
+	shard := s.get()
+	atomic.AddUint64(&shard.errors, 1)
+	s.pool.Put(shard)
+}
+
+// snapshot sums the counters of all the shards.
+func (s *queueStats) snapshot() Stats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stats := Stats{}
+	for _, shard := range s.shards {
+		stats.Sent += atomic.LoadUint64(&shard.sent)
+		stats.Received += atomic.LoadUint64(&shard.received)
+		stats.PktReceived += atomic.LoadUint64(&shard.pktReceived)
+		stats.Errors += atomic.LoadUint64(&shard.errors)
+	}
+	return stats
+}
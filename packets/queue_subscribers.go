@@ -0,0 +1,253 @@
+package packets
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/gopacket"
+)
+
+const (
+	// DefaultSubscriberBuffer is the number of packets buffered for a subscriber
+	// if no size is given.
+	DefaultSubscriberBuffer = 1024
+	// the buffer of the queue itself, tracking protocols, traffic and hosts
+	coreBuffer = 4096
+)
+
+// SubscriberStats are the packets a subscriber processed and the ones it
+// missed because it was too slow.
+type SubscriberStats struct {
+	Name      string `json:"name"`
+	Buffer    int    `json:"buffer"`
+	Pending   int    `json:"pending"`
+	Delivered uint64 `json:"delivered"`
+	Dropped   uint64 `json:"dropped"`
+}
+
+// Subscriber receives the captured packets from its own ring buffer and
+// goroutine: when the buffer is full the oldest packets are dropped, so that
+// a slow subscriber never stalls the capture or the other subscribers.
+type Subscriber struct {
+	name string
+	cb   PacketCallback
+
+	lock  sync.Mutex
+	ring  []gopacket.Packet
+	head  int
+	count int
+
+	delivered uint64
+	dropped   uint64
+
+	wake     chan struct{}
+	quit     chan struct{}
+	done     chan struct{}
+	quitOnce sync.Once
+	// the goroutine running the callback
+	goid uint64
+}
+
+// goroutineID returns the id of the calling goroutine, parsed from the first
+// line of its stack trace: "goroutine 18 [running]:".
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = bytes.TrimPrefix(buf[:runtime.Stack(buf, false)], []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
+
+func newSubscriber(name string, size int, cb PacketCallback) *Subscriber {
+	if size <= 0 {
+		size = DefaultSubscriberBuffer
+	}
+
+	s := &Subscriber{
+		name: name,
+		cb:   cb,
+		ring: make([]gopacket.Packet, size),
+		wake: make(chan struct{}, 1),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go s.worker()
+
+	return s
+}
+
+func (s *Subscriber) Name() string {
+	return s.name
+}
+
+func (s *Subscriber) Stats() SubscriberStats {
+	s.lock.Lock()
+	pending := s.count
+	s.lock.Unlock()
+
+	return SubscriberStats{
+		Name:      s.name,
+		Buffer:    len(s.ring),
+		Pending:   pending,
+		Delivered: atomic.LoadUint64(&s.delivered),
+		Dropped:   atomic.LoadUint64(&s.dropped),
+	}
+}
+
+// push never blocks, if the ring is full the oldest packet is overwritten.
+func (s *Subscriber) push(pkt gopacket.Packet) {
+	s.lock.Lock()
+	if s.count == len(s.ring) {
+		s.ring[s.head] = nil
+		s.head = (s.head + 1) % len(s.ring)
+		s.count--
+		atomic.AddUint64(&s.dropped, 1)
+	}
+	s.ring[(s.head+s.count)%len(s.ring)] = pkt
+	s.count++
+	s.lock.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Subscriber) pop() gopacket.Packet {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.count == 0 {
+		return nil
+	}
+
+	pkt := s.ring[s.head]
+	s.ring[s.head] = nil
+	s.head = (s.head + 1) % len(s.ring)
+	s.count--
+	return pkt
+}
+
+func (s *Subscriber) worker() {
+	defer close(s.done)
+
+	atomic.StoreUint64(&s.goid, goroutineID())
+
+	for {
+		for pkt := s.pop(); pkt != nil; pkt = s.pop() {
+			select {
+			case <-s.quit:
+				return
+			default:
+			}
+
+			s.cb(pkt)
+			atomic.AddUint64(&s.delivered, 1)
+		}
+
+		select {
+		case <-s.quit:
+			return
+		case <-s.wake:
+		}
+	}
+}
+
+// stop signals the worker to exit without waiting for it.
+func (s *Subscriber) stop() {
+	s.quitOnce.Do(func() {
+		close(s.quit)
+	})
+}
+
+// Close stops the subscriber and waits for the callback to return, the
+// packets still buffered are discarded. If called by the callback itself,
+// like when a scanner is done, it doesn't wait since it would never return.
+func (s *Subscriber) Close() {
+	s.stop()
+	if atomic.LoadUint64(&s.goid) != goroutineID() {
+		<-s.done
+	}
+}
+
+func (q *Queue) subscribers() []*Subscriber {
+	if subs, ok := q.subs.Load().([]*Subscriber); ok {
+		return subs
+	}
+	return nil
+}
+
+// Subscribe makes the callback receive every captured packet through a
+// buffer of size packets, or DefaultSubscriberBuffer if size is not greater
+// than 0. The callback runs on its own goroutine.
+func (q *Queue) Subscribe(name string, size int, cb PacketCallback) *Subscriber {
+	sub := newSubscriber(name, size, cb)
+
+	q.subsLock.Lock()
+	defer q.subsLock.Unlock()
+
+	// copy on write, so that the capture never takes a lock to publish
+	prev := q.subscribers()
+	subs := make([]*Subscriber, 0, len(prev)+1)
+	subs = append(subs, prev...)
+	subs = append(subs, sub)
+	q.subs.Store(subs)
+
+	return sub
+}
+
+// Unsubscribe removes the subscriber and, unless called by its callback,
+// waits for it to return.
+func (q *Queue) Unsubscribe(sub *Subscriber) {
+	if sub == nil {
+		return
+	}
+
+	q.subsLock.Lock()
+	prev := q.subscribers()
+	subs := make([]*Subscriber, 0, len(prev))
+	for _, s := range prev {
+		if s != sub {
+			subs = append(subs, s)
+		}
+	}
+	q.subs.Store(subs)
+	q.subsLock.Unlock()
+
+	sub.Close()
+}
+
+// Subscribers returns the stats of the subscribers of the queue.
+func (q *Queue) Subscribers() []SubscriberStats {
+	subs := q.subscribers()
+	stats := make([]SubscriberStats, 0, len(subs))
+	for _, sub := range subs {
+		stats = append(stats, sub.Stats())
+	}
+	return stats
+}
+
+func (q *Queue) publish(pkt gopacket.Packet) {
+	for _, sub := range q.subscribers() {
+		sub.push(pkt)
+	}
+}
+
+// stopSubscribers signals all the subscribers to exit, without waiting for
+// their callbacks in case they're using the queue.
+func (q *Queue) stopSubscribers() {
+	q.subsLock.Lock()
+	subs := q.subscribers()
+	q.subs.Store([]*Subscriber{})
+	q.subsLock.Unlock()
+
+	for _, sub := range subs {
+		sub.stop()
+	}
+}
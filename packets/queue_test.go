@@ -3,7 +3,14 @@ package packets
 import (
 	"net"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
 )
 
 func TestQueueActivity(t *testing.T) {
@@ -62,4 +69,200 @@ func TestQueueStats(t *testing.T) {
 	}
 }
 
+func TestQueueFeed(t *testing.T) {
+	q := &Queue{
+		Protos:     sync.Map{},
+		Traffic:    sync.Map{},
+		Activities: make(chan Activity, 2),
+		iface:      network.NewEndpointNoResolve("192.168.1.2", "aa:bb:cc:dd:ee:01", "eth0", 24),
+	}
+	q.TrackFlows(network.NewFlows())
+
+	called := false
+	q.OnPacket(func(pkt gopacket.Packet) {
+		called = true
+	})
+
+	from := net.ParseIP("192.168.1.10").To4()
+	to := net.ParseIP("10.0.0.1").To4()
+	hw, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	err, raw := NewTCPSyn(from, hw, to, hw, 50000, 443)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q.Feed(gopacket.NewPacket(raw, layers.LayerTypeEthernet, gopacket.Default))
+
+	if called {
+		t.Fatal("fed packets should not reach the packet callback")
+	} else if q.Stats().PktReceived != 1 || q.Stats().Received != uint64(len(raw)) {
+		t.Fatalf("unexpected stats %+v", q.Stats())
+	} else if v, found := q.Protos.Load("TCP"); !found || v.(int) != 1 {
+		t.Fatalf("unexpected TCP protocol count %v", v)
+	} else if q.flows.Len() != 1 {
+		t.Fatalf("expected one flow, got %d", q.flows.Len())
+	} else if len(q.Activities) != 1 {
+		t.Fatalf("expected one activity, got %d", len(q.Activities))
+	} else if a := <-q.Activities; !a.IP.Equal(from) || !a.Source {
+		t.Fatalf("unexpected activity %+v", a)
+	}
+}
+
+func TestQueueStatsConcurrent(t *testing.T) {
+	q := &Queue{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				q.TrackPacket(10)
+				q.TrackSent(2)
+				q.TrackError()
+			}
+		}()
+	}
+	wg.Wait()
+
+	exp := Stats{Sent: 16000, Received: 80000, PktReceived: 8000, Errors: 8000}
+	if got := q.Stats(); got != exp {
+		t.Fatalf("expected %+v, got %+v", exp, got)
+	}
+}
+
+func TestQueueSubscribers(t *testing.T) {
+	q := &Queue{}
+
+	started := make(chan bool, 5)
+	block := make(chan bool)
+	slow := q.Subscribe("slow", 2, func(pkt gopacket.Packet) {
+		started <- true
+		<-block
+	})
+
+	received := make(chan gopacket.Packet, 10)
+	fast := q.Subscribe("fast", 10, func(pkt gopacket.Packet) {
+		received <- pkt
+	})
+
+	for i := 0; i < 5; i++ {
+		q.publish(gopacket.NewPacket([]byte{byte(i)}, layers.LayerTypeEthernet, gopacket.Default))
+		if i == 0 {
+			<-started
+		}
+	}
+
+	// the slow subscriber must not stall the fast one
+	for i := 0; i < 5; i++ {
+		select {
+		case pkt := <-received:
+			if pkt.Data()[0] != byte(i) {
+				t.Fatalf("expected packet %d, got %d", i, pkt.Data()[0])
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("fast subscriber received %d packets of 5", i)
+		}
+	}
+
+	// one packet is being processed by the slow subscriber, two are buffered
+	// and the others have been dropped
+	if stats := slow.Stats(); stats.Dropped != 2 || stats.Pending != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	close(block)
+	q.Unsubscribe(slow)
+	q.Unsubscribe(fast)
+
+	if stats := fast.Stats(); stats.Delivered != 5 || stats.Dropped != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	} else if subs := q.Subscribers(); len(subs) != 0 {
+		t.Fatalf("expected no subscribers, got %+v", subs)
+	}
+}
+
+func TestQueueOnPacket(t *testing.T) {
+	q := &Queue{}
+
+	received := make(chan bool, 1)
+	q.OnPacket(func(pkt gopacket.Packet) {
+		received <- true
+	})
+	q.publish(gopacket.NewPacket([]byte{0}, layers.LayerTypeEthernet, gopacket.Default))
+
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("packet not received by the callback")
+	}
+
+	q.OnPacket(nil)
+	if subs := q.Subscribers(); len(subs) != 0 {
+		t.Fatalf("expected no subscribers, got %+v", subs)
+	}
+}
+
+func TestQueueOnPacketFromCallback(t *testing.T) {
+	q := &Queue{}
+
+	done := make(chan bool)
+	q.OnPacket(func(pkt gopacket.Packet) {
+		// the scanners remove their callback once they got their reply
+		q.OnPacket(nil)
+		close(done)
+	})
+	q.publish(gopacket.NewPacket([]byte{0}, layers.LayerTypeEthernet, gopacket.Default))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("removing the callback from itself deadlocked")
+	}
+
+	if subs := q.Subscribers(); len(subs) != 0 {
+		t.Fatalf("expected no subscribers, got %+v", subs)
+	}
+}
+
+func TestSubscriberCloseWaits(t *testing.T) {
+	q := &Queue{}
+
+	running := make(chan bool)
+	release := make(chan bool)
+	returned := false
+	sub := q.Subscribe("slow", 1, func(pkt gopacket.Packet) {
+		running <- true
+		<-release
+		returned = true
+	})
+	q.publish(gopacket.NewPacket([]byte{0}, layers.LayerTypeEthernet, gopacket.Default))
+	<-running
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+
+	// from another goroutine it must wait for the callback
+	q.Unsubscribe(sub)
+	if !returned {
+		t.Fatal("expected Unsubscribe to wait for the callback")
+	}
+}
+
+func TestGoroutineID(t *testing.T) {
+	id := goroutineID()
+	if id == 0 {
+		t.Fatal("could not parse the goroutine id")
+	}
+
+	other := make(chan uint64)
+	go func() {
+		other <- goroutineID()
+	}()
+	if <-other == id {
+		t.Fatal("expected different goroutine ids")
+	}
+}
+
 // TODO: add tests for the rest of queue.go
@@ -0,0 +1,341 @@
+package packets
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	HSRPPort = 1985
+
+	vrrpHeaderSize  = 8
+	hsrpV1Size      = 20
+	hsrpV2GroupTLV  = 1
+	hsrpV2GroupSize = 40
+
+	hsrpV1StateActive = 16
+	hsrpV2StateActive = 6
+)
+
+var (
+	ErrVRRPInvalid = errors.New("invalid VRRP advertisement")
+	ErrHSRPInvalid = errors.New("invalid HSRP hello")
+)
+
+// RedundancyAdvert is a VRRP advertisement or an HSRP hello sent by one of
+// the physical routers sharing a virtual gateway address.
+type RedundancyAdvert struct {
+	Protocol string
+	Version  uint8
+	Group    uint16
+	Priority uint32
+	Active   bool
+	Virtual  []net.IP
+}
+
+// VirtualMAC returns the MAC address the virtual router answers ARP
+// requests with.
+func (a *RedundancyAdvert) VirtualMAC() net.HardwareAddr {
+	if a.Protocol == "vrrp" {
+		return net.HardwareAddr{0x00, 0x00, 0x5e, 0x00, 0x01, byte(a.Group)}
+	} else if a.Version == 2 {
+		return net.HardwareAddr{0x00, 0x00, 0x0c, 0x9f, 0xf0 | byte(a.Group>>8&0x0f), byte(a.Group)}
+	}
+	return net.HardwareAddr{0x00, 0x00, 0x0c, 0x07, 0xac, byte(a.Group)}
+}
+
+// VRRPParse parses a VRRP v2 or v3 advertisement, only the master router of
+// a group sends them.
+func VRRPParse(raw []byte) (error, *RedundancyAdvert) {
+	if len(raw) < vrrpHeaderSize {
+		return ErrVRRPInvalid, nil
+	}
+
+	version := raw[0] >> 4
+	if (version != 2 && version != 3) || raw[0]&0x0f != 1 {
+		return ErrVRRPInvalid, nil
+	}
+
+	count := int(raw[3])
+	if count == 0 || len(raw) < vrrpHeaderSize+count*net.IPv4len {
+		return ErrVRRPInvalid, nil
+	}
+
+	adv := &RedundancyAdvert{
+		Protocol: "vrrp",
+		Version:  version,
+		Group:    uint16(raw[1]),
+		Priority: uint32(raw[2]),
+		Active:   true,
+		Virtual:  make([]net.IP, 0, count),
+	}
+	for i := 0; i < count; i++ {
+		off := vrrpHeaderSize + i*net.IPv4len
+		adv.Virtual = append(adv.Virtual, net.IP(append([]byte{}, raw[off:off+net.IPv4len]...)))
+	}
+	return nil, adv
+}
+
+// HSRPParse parses an HSRP v1 hello or the group state TLV of an HSRP v2
+// one, both the active and the standby routers send them.
+func HSRPParse(raw []byte) (error, *RedundancyAdvert) {
+	if len(raw) >= hsrpV1Size && raw[0] == 0 {
+		// hello opcode only, coups and resigns are not periodic
+		if raw[1] != 0 {
+			return ErrHSRPInvalid, nil
+		}
+		return nil, &RedundancyAdvert{
+			Protocol: "hsrp",
+			Version:  1,
+			Group:    uint16(raw[6]),
+			Priority: uint32(raw[5]),
+			Active:   raw[2] == hsrpV1StateActive,
+			Virtual:  []net.IP{net.IP(append([]byte{}, raw[16:20]...))},
+		}
+	}
+
+	for off := 0; off+2 <= len(raw); {
+		kind, size := raw[off], int(raw[off+1])
+		tlv := raw[off+2:]
+		if size > len(tlv) {
+			break
+		} else if kind == hsrpV2GroupTLV && size == hsrpV2GroupSize && tlv[0] == 2 && tlv[1] == 0 && tlv[3] == 4 {
+			return nil, &RedundancyAdvert{
+				Protocol: "hsrp",
+				Version:  2,
+				Group:    binary.BigEndian.Uint16(tlv[4:6]),
+				Priority: binary.BigEndian.Uint32(tlv[12:16]),
+				Active:   tlv[2] == hsrpV2StateActive,
+				Virtual:  []net.IP{net.IP(append([]byte{}, tlv[24:28]...))},
+			}
+		}
+		off += 2 + size
+	}
+
+	return ErrHSRPInvalid, nil
+}
+
+// RedundancyParse returns the VRRP or HSRP advertisement carried by the
+// packet, if any, and the address of the physical router that sent it.
+func RedundancyParse(pkt gopacket.Packet) (bool, net.IP, *RedundancyAdvert) {
+	lip4 := pkt.Layer(layers.LayerTypeIPv4)
+	if lip4 == nil {
+		return false, nil, nil
+	}
+	ip4 := lip4.(*layers.IPv4)
+
+	var err error
+	var adv *RedundancyAdvert
+	if ip4.Protocol == layers.IPProtocolVRRP {
+		err, adv = VRRPParse(ip4.Payload)
+	} else if ludp := pkt.Layer(layers.LayerTypeUDP); ludp != nil {
+		udp := ludp.(*layers.UDP)
+		if udp.SrcPort != HSRPPort || udp.DstPort != HSRPPort {
+			return false, nil, nil
+		}
+		err, adv = HSRPParse(udp.Payload)
+	} else {
+		return false, nil, nil
+	}
+
+	if err != nil {
+		return false, nil, nil
+	}
+	return true, ip4.SrcIP, adv
+}
+
+// RedundancyRouter is a physical router of a group.
+type RedundancyRouter struct {
+	IP       net.IP
+	Priority uint32
+	Active   bool
+	LastSeen time.Time
+}
+
+// RedundancyGroup is a virtual router shared by one or more physical ones.
+type RedundancyGroup struct {
+	Protocol   string
+	Version    uint8
+	Group      uint16
+	Virtual    []net.IP
+	VirtualMAC net.HardwareAddr
+	Routers    map[string]*RedundancyRouter
+}
+
+type redundancyRouterJSON struct {
+	IP       string    `json:"ip"`
+	Priority uint32    `json:"priority"`
+	Active   bool      `json:"active"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+type redundancyGroupJSON struct {
+	Protocol   string                 `json:"protocol"`
+	Version    uint8                  `json:"version"`
+	Group      uint16                 `json:"group"`
+	Virtual    []string               `json:"virtual"`
+	VirtualMAC string                 `json:"virtual_mac"`
+	Routers    []redundancyRouterJSON `json:"routers"`
+}
+
+func (g *RedundancyGroup) String() string {
+	return fmt.Sprintf("%s group %d", g.Protocol, g.Group)
+}
+
+func (g *RedundancyGroup) clone() *RedundancyGroup {
+	c := *g
+	c.Routers = make(map[string]*RedundancyRouter, len(g.Routers))
+	for k, r := range g.Routers {
+		router := *r
+		c.Routers[k] = &router
+	}
+	return &c
+}
+
+// Has returns true if ip is one of the virtual addresses of the group.
+func (g *RedundancyGroup) Has(ip net.IP) bool {
+	for _, v := range g.Virtual {
+		if v.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RoutersList returns the physical routers sorted by address, the active one
+// first.
+func (g *RedundancyGroup) RoutersList() []*RedundancyRouter {
+	list := make([]*RedundancyRouter, 0, len(g.Routers))
+	for _, r := range g.Routers {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Active != list[j].Active {
+			return list[i].Active
+		}
+		return binary.BigEndian.Uint32(list[i].IP.To4()) < binary.BigEndian.Uint32(list[j].IP.To4())
+	})
+	return list
+}
+
+func (g *RedundancyGroup) MarshalJSON() ([]byte, error) {
+	doc := redundancyGroupJSON{
+		Protocol:   g.Protocol,
+		Version:    g.Version,
+		Group:      g.Group,
+		Virtual:    make([]string, 0, len(g.Virtual)),
+		VirtualMAC: g.VirtualMAC.String(),
+		Routers:    make([]redundancyRouterJSON, 0, len(g.Routers)),
+	}
+	for _, v := range g.Virtual {
+		doc.Virtual = append(doc.Virtual, v.String())
+	}
+	for _, r := range g.RoutersList() {
+		doc.Routers = append(doc.Routers, redundancyRouterJSON{
+			IP:       r.IP.String(),
+			Priority: r.Priority,
+			Active:   r.Active,
+			LastSeen: r.LastSeen,
+		})
+	}
+	return json.Marshal(doc)
+}
+
+// RedundancyGroups keeps track of the VRRP and HSRP groups seen on the
+// network, the zero value is ready to use.
+type RedundancyGroups struct {
+	sync.RWMutex
+	groups map[string]*RedundancyGroup
+}
+
+// Track updates the groups with the advertisement in the packet, if any,
+// returning true if it's the first one seen from its router.
+func (rg *RedundancyGroups) Track(pkt gopacket.Packet) bool {
+	ok, from, adv := RedundancyParse(pkt)
+	if !ok {
+		return false
+	}
+	return rg.track(from, adv, pkt.Metadata().Timestamp)
+}
+
+func (rg *RedundancyGroups) track(from net.IP, adv *RedundancyAdvert, seen time.Time) bool {
+	rg.Lock()
+	defer rg.Unlock()
+
+	if rg.groups == nil {
+		rg.groups = make(map[string]*RedundancyGroup)
+	}
+
+	if seen.IsZero() {
+		seen = time.Now()
+	}
+
+	key := fmt.Sprintf("%s/%d/%d", adv.Protocol, adv.Version, adv.Group)
+	group, found := rg.groups[key]
+	if !found {
+		group = &RedundancyGroup{
+			Protocol:   adv.Protocol,
+			Version:    adv.Version,
+			Group:      adv.Group,
+			VirtualMAC: adv.VirtualMAC(),
+			Routers:    make(map[string]*RedundancyRouter),
+		}
+		rg.groups[key] = group
+	}
+	group.Virtual = adv.Virtual
+
+	if adv.Active {
+		// the previous active router (if any) is now a backup
+		for _, r := range group.Routers {
+			r.Active = false
+		}
+	}
+
+	router, found := group.Routers[from.String()]
+	if !found {
+		router = &RedundancyRouter{IP: net.IP(append([]byte{}, from...))}
+		group.Routers[from.String()] = router
+	}
+	router.Priority = adv.Priority
+	router.Active = adv.Active
+	router.LastSeen = seen
+
+	return !found
+}
+
+// List returns a copy of the groups sorted by protocol and group number.
+func (rg *RedundancyGroups) List() []*RedundancyGroup {
+	rg.RLock()
+	defer rg.RUnlock()
+
+	list := make([]*RedundancyGroup, 0, len(rg.groups))
+	for _, g := range rg.groups {
+		list = append(list, g.clone())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Protocol != list[j].Protocol {
+			return list[i].Protocol < list[j].Protocol
+		}
+		return list[i].Group < list[j].Group
+	})
+	return list
+}
+
+// ByVirtual returns the group having ip as one of its virtual addresses.
+func (rg *RedundancyGroups) ByVirtual(ip net.IP) (*RedundancyGroup, bool) {
+	for _, g := range rg.List() {
+		if g.Has(ip) {
+			return g, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,108 @@
+package packets
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestVRRPParse(t *testing.T) {
+	raw := []byte{
+		0x21, 0x0a, 0x64, 0x02, 0x00, 0x01, 0x00, 0x00,
+		192, 168, 1, 1,
+		192, 168, 1, 254,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+
+	err, adv := VRRPParse(raw)
+	if err != nil {
+		t.Fatal(err)
+	} else if adv.Group != 10 || adv.Priority != 100 || !adv.Active || adv.Version != 2 {
+		t.Fatalf("unexpected advertisement %+v", adv)
+	} else if len(adv.Virtual) != 2 || !adv.Virtual[1].Equal(net.ParseIP("192.168.1.254")) {
+		t.Fatalf("unexpected virtual addresses %v", adv.Virtual)
+	} else if exp := "00:00:5e:00:01:0a"; adv.VirtualMAC().String() != exp {
+		t.Fatalf("expected virtual mac %s, got %s", exp, adv.VirtualMAC())
+	}
+
+	if err, _ := VRRPParse(raw[:10]); err == nil {
+		t.Fatal("expected error for truncated advertisement")
+	} else if err, _ := VRRPParse(append([]byte{0x22}, raw[1:]...)); err == nil {
+		t.Fatal("expected error for unknown type")
+	}
+}
+
+func TestHSRPParse(t *testing.T) {
+	v1 := []byte{
+		0x00, 0x00, 0x10, 0x03, 0x0a, 0x6e, 0x01, 0x00,
+		'c', 'i', 's', 'c', 'o', 0x00, 0x00, 0x00,
+		10, 0, 0, 1,
+	}
+
+	err, adv := HSRPParse(v1)
+	if err != nil {
+		t.Fatal(err)
+	} else if adv.Version != 1 || adv.Group != 1 || adv.Priority != 110 || !adv.Active {
+		t.Fatalf("unexpected hello %+v", adv)
+	} else if !adv.Virtual[0].Equal(net.ParseIP("10.0.0.1")) {
+		t.Fatalf("unexpected virtual address %v", adv.Virtual)
+	} else if exp := "00:00:0c:07:ac:01"; adv.VirtualMAC().String() != exp {
+		t.Fatalf("expected virtual mac %s, got %s", exp, adv.VirtualMAC())
+	}
+
+	v2 := []byte{
+		0x01, 0x28,
+		0x02, 0x00, 0x05, 0x04, 0x01, 0x2c,
+		0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff,
+		0x00, 0x00, 0x00, 0x64,
+		0x00, 0x00, 0x0b, 0xb8,
+		0x00, 0x00, 0x27, 0x10,
+		10, 0, 0, 254, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	}
+
+	if err, adv = HSRPParse(v2); err != nil {
+		t.Fatal(err)
+	} else if adv.Version != 2 || adv.Group != 300 || adv.Priority != 100 || adv.Active {
+		t.Fatalf("unexpected hello %+v", adv)
+	} else if !adv.Virtual[0].Equal(net.ParseIP("10.0.0.254")) {
+		t.Fatalf("unexpected virtual address %v", adv.Virtual)
+	} else if exp := "00:00:0c:9f:f1:2c"; adv.VirtualMAC().String() != exp {
+		t.Fatalf("expected virtual mac %s, got %s", exp, adv.VirtualMAC())
+	}
+
+	if err, _ := HSRPParse(v2[:20]); err == nil {
+		t.Fatal("expected error for truncated hello")
+	}
+}
+
+func TestRedundancyGroupsTrack(t *testing.T) {
+	rg := RedundancyGroups{}
+	virtual := []net.IP{net.ParseIP("10.0.0.1").To4()}
+	active := net.ParseIP("10.0.0.2").To4()
+	standby := net.ParseIP("10.0.0.3").To4()
+
+	if !rg.track(active, &RedundancyAdvert{Protocol: "hsrp", Version: 1, Group: 1, Active: true, Virtual: virtual}, time.Now()) {
+		t.Fatal("expected new router")
+	} else if !rg.track(standby, &RedundancyAdvert{Protocol: "hsrp", Version: 1, Group: 1, Virtual: virtual}, time.Now()) {
+		t.Fatal("expected new router")
+	} else if rg.track(active, &RedundancyAdvert{Protocol: "hsrp", Version: 1, Group: 1, Active: true, Virtual: virtual}, time.Now()) {
+		t.Fatal("expected known router")
+	}
+
+	group, found := rg.ByVirtual(net.ParseIP("10.0.0.1"))
+	if !found {
+		t.Fatal("expected group for the virtual address")
+	} else if routers := group.RoutersList(); len(routers) != 2 || !routers[0].IP.Equal(active) || !routers[0].Active || routers[1].Active {
+		t.Fatalf("unexpected routers %+v", routers)
+	}
+
+	// the standby router took over
+	rg.track(standby, &RedundancyAdvert{Protocol: "hsrp", Version: 1, Group: 1, Active: true, Virtual: virtual}, time.Now())
+	if group, _ = rg.ByVirtual(virtual[0]); !group.RoutersList()[0].IP.Equal(standby) {
+		t.Fatalf("expected %s to be active, got %+v", standby, group.RoutersList()[0])
+	}
+
+	if _, found := rg.ByVirtual(active); found {
+		t.Fatal("unexpected group for a physical address")
+	}
+}
@@ -0,0 +1,115 @@
+package packets
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	STPTypeConfig = 0x00
+	STPTypeRST    = 0x02
+	STPTypeTCN    = 0x80
+
+	stpConfigSize = 35
+)
+
+var (
+	STPDestMac  = net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x00}
+	CDPDestMac  = net.HardwareAddr{0x01, 0x00, 0x0c, 0xcc, 0xcc, 0xcc}
+	LLDPDestMac = net.HardwareAddr{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e}
+)
+
+// STPBridgeID is the 8 bytes bridge identifier made of a
+// priority and the MAC address of the bridge itself.
+type STPBridgeID struct {
+	Priority uint16
+	Address  net.HardwareAddr
+}
+
+func (id STPBridgeID) String() string {
+	return fmt.Sprintf("%d/%s", id.Priority, id.Address)
+}
+
+// Less returns true if this bridge identifier would win
+// the root bridge election against the other one.
+func (id STPBridgeID) Less(other STPBridgeID) bool {
+	if id.Priority != other.Priority {
+		return id.Priority < other.Priority
+	}
+	for i := 0; i < len(id.Address) && i < len(other.Address); i++ {
+		if id.Address[i] != other.Address[i] {
+			return id.Address[i] < other.Address[i]
+		}
+	}
+	return false
+}
+
+type STPBPDU struct {
+	ProtocolID   uint16
+	Version      uint8
+	Type         uint8
+	Flags        uint8
+	RootID       STPBridgeID
+	RootPathCost uint32
+	BridgeID     STPBridgeID
+	PortID       uint16
+	MessageAge   uint16
+	MaxAge       uint16
+	HelloTime    uint16
+	ForwardDelay uint16
+}
+
+func parseBridgeID(raw []byte) STPBridgeID {
+	hw := make(net.HardwareAddr, 6)
+	copy(hw, raw[2:8])
+	return STPBridgeID{
+		Priority: binary.BigEndian.Uint16(raw[0:2]),
+		Address:  hw,
+	}
+}
+
+func STPParse(raw []byte) (error, *STPBPDU) {
+	if len(raw) < 4 {
+		return fmt.Errorf("BPDU too short (%d bytes)", len(raw)), nil
+	}
+
+	bpdu := &STPBPDU{
+		ProtocolID: binary.BigEndian.Uint16(raw[0:2]),
+		Version:    raw[2],
+		Type:       raw[3],
+	}
+
+	if bpdu.ProtocolID != 0 {
+		return fmt.Errorf("unexpected BPDU protocol identifier %d", bpdu.ProtocolID), nil
+	} else if bpdu.Type == STPTypeTCN {
+		return nil, bpdu
+	} else if len(raw) < stpConfigSize {
+		return fmt.Errorf("configuration BPDU too short (%d bytes)", len(raw)), nil
+	}
+
+	bpdu.Flags = raw[4]
+	bpdu.RootID = parseBridgeID(raw[5:13])
+	bpdu.RootPathCost = binary.BigEndian.Uint32(raw[13:17])
+	bpdu.BridgeID = parseBridgeID(raw[17:25])
+	bpdu.PortID = binary.BigEndian.Uint16(raw[25:27])
+	// timers are expressed in 1/256th of a second
+	bpdu.MessageAge = binary.BigEndian.Uint16(raw[27:29]) / 256
+	bpdu.MaxAge = binary.BigEndian.Uint16(raw[29:31]) / 256
+	bpdu.HelloTime = binary.BigEndian.Uint16(raw[31:33]) / 256
+	bpdu.ForwardDelay = binary.BigEndian.Uint16(raw[33:35]) / 256
+
+	return nil, bpdu
+}
+
+func STPGetBPDU(pkt gopacket.Packet) *STPBPDU {
+	if layer := pkt.Layer(layers.LayerTypeSTP); layer != nil {
+		if err, bpdu := STPParse(layer.LayerContents()); err == nil {
+			return bpdu
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package packets
+
+import (
+	"testing"
+)
+
+func TestSTPParse(t *testing.T) {
+	raw := []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00,
+		0x80, 0x00, 0x00, 0x1c, 0x0e, 0x87, 0x78, 0x00,
+		0x00, 0x00, 0x00, 0x04,
+		0x80, 0x00, 0x00, 0x1c, 0x0e, 0x87, 0x85, 0x00,
+		0x80, 0x04,
+		0x01, 0x00, 0x14, 0x00, 0x02, 0x00, 0x0f, 0x00,
+	}
+
+	err, bpdu := STPParse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bpdu.Type != STPTypeConfig {
+		t.Fatalf("expected type %d, got %d", STPTypeConfig, bpdu.Type)
+	} else if exp := "32768/00:1c:0e:87:78:00"; bpdu.RootID.String() != exp {
+		t.Fatalf("expected root '%s', got '%s'", exp, bpdu.RootID)
+	} else if bpdu.RootPathCost != 4 {
+		t.Fatalf("expected root path cost 4, got %d", bpdu.RootPathCost)
+	} else if bpdu.PortID != 0x8004 {
+		t.Fatalf("expected port 0x8004, got 0x%04x", bpdu.PortID)
+	} else if bpdu.MaxAge != 20 || bpdu.HelloTime != 2 || bpdu.ForwardDelay != 15 {
+		t.Fatalf("unexpected timers %d %d %d", bpdu.MaxAge, bpdu.HelloTime, bpdu.ForwardDelay)
+	} else if !bpdu.RootID.Less(bpdu.BridgeID) {
+		t.Fatalf("expected root %s to be less than bridge %s", bpdu.RootID, bpdu.BridgeID)
+	}
+}
+
+func TestSTPParseShort(t *testing.T) {
+	if err, _ := STPParse([]byte{0x00, 0x00, 0x00, 0x00, 0x00}); err == nil {
+		t.Fatal("expected error for short configuration BPDU")
+	}
+
+	if err, bpdu := STPParse([]byte{0x00, 0x00, 0x00, STPTypeTCN}); err != nil {
+		t.Fatal(err)
+	} else if bpdu.Type != STPTypeTCN {
+		t.Fatalf("expected TCN, got %d", bpdu.Type)
+	}
+}
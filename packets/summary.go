@@ -0,0 +1,109 @@
+package packets
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+func tcpFlags(tcp *layers.TCP) string {
+	flags := []string{}
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{tcp.SYN, "SYN"},
+		{tcp.ACK, "ACK"},
+		{tcp.FIN, "FIN"},
+		{tcp.RST, "RST"},
+		{tcp.PSH, "PSH"},
+	} {
+		if f.set {
+			flags = append(flags, f.name)
+		}
+	}
+	return strings.Join(flags, ",")
+}
+
+func networkAddresses(pkt gopacket.Packet) (string, string) {
+	if nl := pkt.NetworkLayer(); nl != nil {
+		return nl.NetworkFlow().Src().String(), nl.NetworkFlow().Dst().String()
+	} else if link := pkt.LinkLayer(); link != nil {
+		return link.LinkFlow().Src().String(), link.LinkFlow().Dst().String()
+	}
+	return "?", "?"
+}
+
+// Summarize returns a short human readable description of a raw packet
+// starting with the given layer, used to report what would be sent.
+func Summarize(raw []byte, first gopacket.LayerType) string {
+	pkt := gopacket.NewPacket(raw, first, gopacket.NoCopy)
+	src, dst := networkAddresses(pkt)
+	desc := ""
+
+	if l := pkt.Layer(layers.LayerTypeARP); l != nil {
+		arp := l.(*layers.ARP)
+		if arp.Operation == layers.ARPReply {
+			desc = fmt.Sprintf("ARP reply %s is-at %s to %s (%s)",
+				net.IP(arp.SourceProtAddress), net.HardwareAddr(arp.SourceHwAddress),
+				net.IP(arp.DstProtAddress), net.HardwareAddr(arp.DstHwAddress))
+		} else {
+			desc = fmt.Sprintf("ARP request who-has %s tell %s",
+				net.IP(arp.DstProtAddress), net.IP(arp.SourceProtAddress))
+		}
+	} else if l := pkt.Layer(layers.LayerTypeDot11); l != nil {
+		dot11 := l.(*layers.Dot11)
+		desc = fmt.Sprintf("802.11 %s %s -> %s (bssid %s)", dot11.Type, dot11.Address2, dot11.Address1, dot11.Address3)
+	} else if l := pkt.Layer(layers.LayerTypeDNS); l != nil {
+		dns := l.(*layers.DNS)
+		names := []string{}
+		for _, q := range dns.Questions {
+			names = append(names, string(q.Name))
+		}
+		answers := []string{}
+		for _, a := range dns.Answers {
+			if a.IP != nil {
+				answers = append(answers, a.IP.String())
+			}
+		}
+		kind := "query"
+		if dns.QR {
+			kind = "reply"
+		}
+		desc = fmt.Sprintf("DNS %s %s %s -> %s", kind, strings.Join(names, ","), src, dst)
+		if len(answers) > 0 {
+			desc += " answering " + strings.Join(answers, ",")
+		}
+	} else if l := pkt.Layer(layers.LayerTypeDHCPv4); l != nil {
+		dhcp := l.(*layers.DHCPv4)
+		desc = fmt.Sprintf("DHCPv4 %s for %s", dhcp.Operation, dhcp.ClientHWAddr)
+		for _, opt := range dhcp.Options {
+			if opt.Type == layers.DHCPOptMessageType && len(opt.Data) == 1 {
+				desc = fmt.Sprintf("DHCPv4 %s for %s", layers.DHCPMsgType(opt.Data[0]), dhcp.ClientHWAddr)
+			}
+		}
+	} else if l := pkt.Layer(layers.LayerTypeICMPv6); l != nil {
+		desc = fmt.Sprintf("ICMPv6 %s %s -> %s", l.(*layers.ICMPv6).TypeCode, src, dst)
+	} else if l := pkt.Layer(layers.LayerTypeTCP); l != nil {
+		tcp := l.(*layers.TCP)
+		desc = fmt.Sprintf("TCP %s:%d -> %s:%d [%s]", src, tcp.SrcPort, dst, tcp.DstPort, tcpFlags(tcp))
+	} else if l := pkt.Layer(layers.LayerTypeUDP); l != nil {
+		udp := l.(*layers.UDP)
+		desc = fmt.Sprintf("UDP %s:%d -> %s:%d", src, udp.SrcPort, dst, udp.DstPort)
+	} else {
+		names := []string{}
+		for _, l := range pkt.Layers() {
+			names = append(names, l.LayerType().String())
+		}
+		desc = fmt.Sprintf("%s %s -> %s", strings.Join(names, "/"), src, dst)
+	}
+
+	if l := pkt.Layer(layers.LayerTypeDot1Q); l != nil {
+		desc += fmt.Sprintf(" vlan %d", l.(*layers.Dot1Q).VLANIdentifier)
+	}
+
+	return fmt.Sprintf("%s (%d bytes)", desc, len(raw))
+}
@@ -0,0 +1,59 @@
+package packets
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/google/gopacket/layers"
+)
+
+func TestSummarizeARP(t *testing.T) {
+	gw := net.ParseIP("192.168.1.1")
+	target := net.ParseIP("192.168.1.10")
+	ourHW, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	targetHW, _ := net.ParseMAC("11:22:33:44:55:66")
+
+	err, raw := NewARPReply(gw, ourHW, target, targetHW)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := Summarize(raw, layers.LayerTypeEthernet)
+	exp := "ARP reply 192.168.1.1 is-at aa:bb:cc:dd:ee:ff to 192.168.1.10 (11:22:33:44:55:66)"
+	if !strings.HasPrefix(got, exp) {
+		t.Fatalf("expected '%s', got '%s'", exp, got)
+	}
+}
+
+func TestSummarizeVLAN(t *testing.T) {
+	gw := net.ParseIP("192.168.1.1")
+	target := net.ParseIP("192.168.1.10")
+	ourHW, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	targetHW, _ := net.ParseMAC("11:22:33:44:55:66")
+
+	_, raw := NewARPReply(gw, ourHW, target, targetHW)
+	err, tagged := Dot1QEncapsulate(raw, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Summarize(tagged, layers.LayerTypeEthernet); !strings.Contains(got, " vlan 42 ") {
+		t.Fatalf("expected the VLAN in '%s'", got)
+	}
+}
+
+func TestSummarizeDeauth(t *testing.T) {
+	ap, _ := net.ParseMAC("aa:bb:cc:dd:ee:ff")
+	client, _ := net.ParseMAC("11:22:33:44:55:66")
+
+	err, raw := NewDot11Deauth(ap, client, ap, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := Summarize(raw, layers.LayerTypeRadioTap)
+	if !strings.Contains(got, "Deauthentication") || !strings.Contains(got, "11:22:33:44:55:66 -> aa:bb:cc:dd:ee:ff") {
+		t.Fatalf("unexpected summary '%s'", got)
+	}
+}
@@ -0,0 +1,69 @@
+package packets
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	// ZigBee Encapsulation Protocol, used by Wireshark and most
+	// 802.15.4 sniffer bridges to stream frames captured over UDP.
+	ZEPPort = 17754
+
+	zepV1HeaderSize = 16
+	zepV2HeaderSize = 32
+	zepTypeData     = 1
+	zepTypeAck      = 2
+)
+
+// ZEPFrame is a captured 802.15.4 frame as carried by a ZEP datagram,
+// Frame still includes the two trailing FCS (or LQI/RSSI) bytes.
+type ZEPFrame struct {
+	Version  uint8
+	Channel  int
+	DeviceID uint16
+	LQI      uint8
+	Frame    []byte
+}
+
+func ZEPParse(raw []byte) (error, *ZEPFrame) {
+	if len(raw) < 4 || raw[0] != 'E' || raw[1] != 'X' {
+		return fmt.Errorf("not a ZEP datagram"), nil
+	}
+
+	z := &ZEPFrame{Version: raw[2]}
+	hdrSize := 0
+
+	switch z.Version {
+	case 1:
+		if len(raw) < zepV1HeaderSize {
+			return fmt.Errorf("ZEPv1 datagram too short (%d bytes)", len(raw)), nil
+		}
+		hdrSize = zepV1HeaderSize
+		z.Channel = int(raw[3])
+		z.DeviceID = binary.BigEndian.Uint16(raw[4:6])
+		z.LQI = raw[7]
+	case 2:
+		if raw[3] == zepTypeAck {
+			return fmt.Errorf("ZEPv2 ack datagrams carry no frame"), nil
+		} else if raw[3] != zepTypeData {
+			return fmt.Errorf("unknown ZEPv2 type %d", raw[3]), nil
+		} else if len(raw) < zepV2HeaderSize {
+			return fmt.Errorf("ZEPv2 datagram too short (%d bytes)", len(raw)), nil
+		}
+		hdrSize = zepV2HeaderSize
+		z.Channel = int(raw[4])
+		z.DeviceID = binary.BigEndian.Uint16(raw[5:7])
+		z.LQI = raw[8]
+	default:
+		return fmt.Errorf("unsupported ZEP version %d", z.Version), nil
+	}
+
+	size := int(raw[hdrSize-1])
+	if hdrSize+size > len(raw) {
+		return fmt.Errorf("ZEP frame truncated (%d of %d bytes)", len(raw)-hdrSize, size), nil
+	}
+	z.Frame = raw[hdrSize : hdrSize+size]
+
+	return nil, z
+}
@@ -0,0 +1,29 @@
+package packets
+
+import (
+	"testing"
+)
+
+func TestZEPParse(t *testing.T) {
+	frame := []byte{0x41, 0x88, 0x01, 0xcd, 0xab, 0xff, 0xff, 0x00, 0x00, 0x11, 0x22}
+
+	v2 := []byte{'E', 'X', 2, 1, 15, 0x00, 0x01, 0x00, 0xff}
+	v2 = append(v2, make([]byte, zepV2HeaderSize-len(v2)-1)...)
+	v2 = append(v2, byte(len(frame)))
+	v2 = append(v2, frame...)
+
+	err, z := ZEPParse(v2)
+	if err != nil {
+		t.Fatal(err)
+	} else if z.Version != 2 || z.Channel != 15 || z.DeviceID != 1 || z.LQI != 0xff {
+		t.Fatalf("unexpected header %+v", z)
+	} else if len(z.Frame) != len(frame) {
+		t.Fatalf("unexpected frame %x", z.Frame)
+	}
+
+	if err, _ := ZEPParse(v2[:len(v2)-2]); err == nil {
+		t.Fatal("expected error for truncated datagram")
+	} else if err, _ := ZEPParse([]byte("GET / HTTP/1.1")); err == nil {
+		t.Fatal("expected error for non ZEP datagram")
+	}
+}
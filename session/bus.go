@@ -0,0 +1,207 @@
+package session
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the topics of the records published by the modules, a subscription to a
+// topic also receives the records of its children (http gets http.request).
+const (
+	TopicHTTPRequest  = "http.request"
+	TopicHTTPResponse = "http.response"
+	TopicDNSQuery     = "dns.query"
+	TopicCredential   = "credential"
+	// subscribing to it receives every record
+	TopicAll = "*"
+)
+
+// records buffered for each subscriber before the new ones are dropped
+const busBuffer = 256
+
+// Record is published on a topic of the bus by the module named in Source,
+// Data is a typed value like a Credential or a DNSQuery.
+type Record struct {
+	Topic  string      `json:"topic"`
+	Source string      `json:"source"`
+	Time   time.Time   `json:"time"`
+	Data   interface{} `json:"data"`
+}
+
+// Credential is the record of the credential topic, published by every
+// module capturing clear text credentials or hashes.
+type Credential struct {
+	Protocol string `json:"protocol"`
+	Client   string `json:"client"`
+	Server   string `json:"server"`
+	Domain   string `json:"domain,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// DNSQuery is the record of the dns.query topic, a question or the answers
+// for a hostname seen on the network.
+type DNSQuery struct {
+	Client   string   `json:"client"`
+	Server   string   `json:"server"`
+	Hostname string   `json:"hostname"`
+	Answers  []string `json:"answers,omitempty"`
+	Rcode    string   `json:"rcode,omitempty"`
+}
+
+// Object returns the data of the record decoded as a JSON object, in order
+// to pass it to the scripts with the same field names of the API.
+func (r Record) Object() (map[string]interface{}, error) {
+	raw, err := json.Marshal(r.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := make(map[string]interface{})
+	if err = json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+type RecordCallback func(r Record)
+
+type busSubscriber struct {
+	name      string
+	topic     string
+	records   chan Record
+	delivered uint64
+	dropped   uint64
+}
+
+// BusStats are the records published on a topic and, for each subscriber,
+// the ones delivered to it and the ones dropped because it was too slow.
+type BusStats struct {
+	Topics      map[string]uint64    `json:"topics"`
+	Subscribers []BusSubscriberStats `json:"subscribers"`
+}
+
+type BusSubscriberStats struct {
+	Name      string `json:"name"`
+	Topic     string `json:"topic"`
+	Delivered uint64 `json:"delivered"`
+	Dropped   uint64 `json:"dropped"`
+}
+
+// Bus delivers the records published by the modules to the modules and the
+// scripts subscribed to their topics, each subscriber on its own goroutine
+// so that the publishers are never slowed down.
+type Bus struct {
+	sync.RWMutex
+	subs      map[int]*busSubscriber
+	nextID    int
+	published map[string]uint64
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subs:      make(map[int]*busSubscriber),
+		published: make(map[string]uint64),
+	}
+}
+
+func topicMatches(sub, topic string) bool {
+	return sub == TopicAll || sub == topic || strings.HasPrefix(topic, sub+".")
+}
+
+// Publish sends the record to the subscribers of the topic, it does nothing
+// on a nil bus in order not to require one in the tests of the publishers.
+func (b *Bus) Publish(topic string, source string, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	r := Record{
+		Topic:  topic,
+		Source: source,
+		Time:   time.Now(),
+		Data:   data,
+	}
+
+	// the write lock protects the counters, the channels are never blocking
+	b.Lock()
+	defer b.Unlock()
+
+	b.published[topic]++
+	for _, sub := range b.subs {
+		if !topicMatches(sub.topic, topic) {
+			continue
+		}
+		select {
+		case sub.records <- r:
+			sub.delivered++
+		default:
+			sub.dropped++
+		}
+	}
+}
+
+// Subscribe calls cb for every record of the topic and returns the function
+// to unsubscribe, name identifies the subscriber in the stats.
+func (b *Bus) Subscribe(name string, topic string, cb RecordCallback) func() {
+	sub := &busSubscriber{
+		name:    name,
+		topic:   topic,
+		records: make(chan Record, busBuffer),
+	}
+
+	b.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = sub
+	b.Unlock()
+
+	go func() {
+		for r := range sub.records {
+			cb(r)
+		}
+	}()
+
+	once := sync.Once{}
+	return func() {
+		once.Do(func() {
+			b.Lock()
+			defer b.Unlock()
+			delete(b.subs, id)
+			close(sub.records)
+		})
+	}
+}
+
+func (b *Bus) Stats() BusStats {
+	b.RLock()
+	defer b.RUnlock()
+
+	stats := BusStats{
+		Topics:      make(map[string]uint64),
+		Subscribers: make([]BusSubscriberStats, 0, len(b.subs)),
+	}
+	for topic, n := range b.published {
+		stats.Topics[topic] = n
+	}
+	for _, sub := range b.subs {
+		stats.Subscribers = append(stats.Subscribers, BusSubscriberStats{
+			Name:      sub.name,
+			Topic:     sub.topic,
+			Delivered: sub.delivered,
+			Dropped:   sub.dropped,
+		})
+	}
+	sort.Slice(stats.Subscribers, func(i, j int) bool {
+		return stats.Subscribers[i].Name < stats.Subscribers[j].Name
+	})
+	return stats
+}
+
+func (b *Bus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.Stats())
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func waitRecord(t *testing.T, ch chan Record) Record {
+	select {
+	case r := <-ch:
+		return r
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the record")
+	}
+	return Record{}
+}
+
+func TestBusTopics(t *testing.T) {
+	b := NewBus()
+
+	http := make(chan Record, 10)
+	all := make(chan Record, 10)
+	defer b.Subscribe("http", "http", func(r Record) { http <- r })()
+	defer b.Subscribe("all", TopicAll, func(r Record) { all <- r })()
+
+	b.Publish(TopicDNSQuery, "test", DNSQuery{Hostname: "example.com"})
+	b.Publish(TopicHTTPRequest, "test", "GET /")
+
+	if r := waitRecord(t, http); r.Topic != TopicHTTPRequest || r.Source != "test" {
+		t.Fatalf("unexpected record %+v", r)
+	} else if r := waitRecord(t, all); r.Topic != TopicDNSQuery {
+		t.Fatalf("unexpected record %+v", r)
+	} else if dns, ok := r.Data.(DNSQuery); !ok || dns.Hostname != "example.com" {
+		t.Fatalf("unexpected data %+v", r.Data)
+	} else if r := waitRecord(t, all); r.Topic != TopicHTTPRequest {
+		t.Fatalf("unexpected record %+v", r)
+	}
+
+	select {
+	case r := <-http:
+		t.Fatalf("unexpected record %+v", r)
+	default:
+	}
+
+	stats := b.Stats()
+	if stats.Topics[TopicDNSQuery] != 1 || stats.Topics[TopicHTTPRequest] != 1 {
+		t.Fatalf("unexpected topics %+v", stats.Topics)
+	} else if len(stats.Subscribers) != 2 || stats.Subscribers[0].Name != "all" || stats.Subscribers[0].Delivered != 2 {
+		t.Fatalf("unexpected subscribers %+v", stats.Subscribers)
+	}
+}
+
+func TestBusSlowSubscriber(t *testing.T) {
+	b := NewBus()
+
+	block := make(chan bool)
+	unsubscribe := b.Subscribe("slow", TopicCredential, func(r Record) { <-block })
+
+	// nor the publisher nor the other subscribers wait for it
+	for i := 0; i < busBuffer*2; i++ {
+		b.Publish(TopicCredential, "test", Credential{Protocol: "ftp"})
+	}
+
+	if stats := b.Stats(); stats.Subscribers[0].Dropped == 0 {
+		t.Fatalf("expected dropped records, got %+v", stats.Subscribers[0])
+	}
+
+	close(block)
+	unsubscribe()
+	unsubscribe()
+
+	if stats := b.Stats(); len(stats.Subscribers) != 0 {
+		t.Fatalf("expected no subscribers, got %+v", stats.Subscribers)
+	}
+	// publishing after the unsubscription must not panic
+	b.Publish(TopicCredential, "test", Credential{Protocol: "ftp"})
+}
+
+func TestRecordObject(t *testing.T) {
+	r := Record{Data: Credential{Protocol: "ssh", Username: "root"}}
+	if obj, err := r.Object(); err != nil {
+		t.Fatal(err)
+	} else if obj["username"] != "root" || obj["protocol"] != "ssh" {
+		t.Fatalf("unexpected object %+v", obj)
+	} else if _, found := obj["hash"]; found {
+		t.Fatalf("unexpected empty hash in %+v", obj)
+	}
+}
@@ -13,6 +13,10 @@ type CommandHandler struct {
 	Completer   *readline.PrefixCompleter
 	Parser      *regexp.Regexp
 	exec        func(args []string, s *Session) error
+	// set for the handlers that need to know who's running the command
+	execAs func(args []string, s *Session, who string) error
+	// set for the handlers that can run themselves, like include
+	reentrant bool
 }
 
 func NewCommandHandler(name string, expr string, desc string, exec func(args []string, s *Session) error) CommandHandler {
@@ -34,8 +38,30 @@ func (h *CommandHandler) Parse(line string) (bool, []string) {
 	}
 }
 
+func newCommandHandlerAs(name string, expr string, desc string, execAs func(args []string, s *Session, who string) error) CommandHandler {
+	h := NewCommandHandler(name, expr, desc, nil)
+	h.execAs = execAs
+	return h
+}
+
+func newReentrantHandler(name string, expr string, desc string, execAs func(args []string, s *Session, who string) error) CommandHandler {
+	h := newCommandHandlerAs(name, expr, desc, execAs)
+	h.reentrant = true
+	return h
+}
+
 func (h *CommandHandler) Exec(args []string, s *Session) error {
-	h.Lock()
-	defer h.Unlock()
+	return h.ExecAs(args, s, "")
+}
+
+// ExecAs runs the handler on behalf of who, see Session.RunAs .
+func (h *CommandHandler) ExecAs(args []string, s *Session, who string) error {
+	if !h.reentrant {
+		h.Lock()
+		defer h.Unlock()
+	}
+	if h.execAs != nil {
+		return h.execAs(args, s, who)
+	}
 	return h.exec(args, s)
 }
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/evilsocket/islazy/fs"
 )
@@ -17,12 +18,15 @@ type Environment struct {
 	sync.Mutex
 	Data map[string]string `json:"data"`
 	cbs  map[string]EnvironmentChangedCallback
+	// types of the variables declared with the var command
+	types map[string]ParamType
 }
 
 func NewEnvironment(envFile string) (*Environment, error) {
 	env := &Environment{
-		Data: make(map[string]string),
-		cbs:  make(map[string]EnvironmentChangedCallback),
+		Data:  make(map[string]string),
+		cbs:   make(map[string]EnvironmentChangedCallback),
+		types: make(map[string]ParamType),
 	}
 
 	if envFile != "" {
@@ -99,6 +103,19 @@ func (env *Environment) Set(name, value string) string {
 	return old
 }
 
+func (env *Environment) SetType(name string, t ParamType) {
+	env.Lock()
+	defer env.Unlock()
+	env.types[name] = t
+}
+
+func (env *Environment) GetType(name string) (bool, ParamType) {
+	env.Lock()
+	defer env.Unlock()
+	t, found := env.types[name]
+	return found, t
+}
+
 func (env *Environment) GetUnlocked(name string) (bool, string) {
 	if value, found := env.Data[name]; found {
 		return true, value
@@ -124,6 +141,30 @@ func (env *Environment) GetInt(name string) (error, int) {
 	return fmt.Errorf("Not found."), 0
 }
 
+func (env *Environment) GetBool(name string) (error, bool) {
+	if found, value := env.Get(name); found {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return nil, b
+		} else {
+			return err, false
+		}
+	}
+
+	return fmt.Errorf("Not found."), false
+}
+
+func (env *Environment) GetDuration(name string) (error, time.Duration) {
+	if found, value := env.Get(name); found {
+		if d, err := time.ParseDuration(value); err == nil {
+			return nil, d
+		} else {
+			return err, 0
+		}
+	}
+
+	return fmt.Errorf("Not found."), 0
+}
+
 func (env *Environment) Sorted() []string {
 	env.Lock()
 	defer env.Unlock()
@@ -6,6 +6,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 )
 
 var (
@@ -269,6 +270,41 @@ func TestSessionEnvironmentGetInt(t *testing.T) {
 	}
 }
 
+func TestSessionEnvironmentGetDuration(t *testing.T) {
+	env, _ := NewEnvironment("")
+
+	env.Set("timeout", "1m30s")
+	if err, d := env.GetDuration("timeout"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if d != 90*time.Second {
+		t.Fatalf("unexpected duration: %v", d)
+	}
+
+	env.Set("timeout", "soon")
+	if err, _ := env.GetDuration("timeout"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if err, _ := env.GetDuration("unknown"); err == nil {
+		t.Fatal("expected error (unknown key)")
+	}
+}
+
+func TestSessionEnvironmentTypes(t *testing.T) {
+	env, _ := NewEnvironment("")
+
+	if found, _ := env.GetType("port"); found {
+		t.Fatal("unexpected type for undeclared variable")
+	}
+
+	env.SetType("port", INT)
+	if found, typ := env.GetType("port"); !found {
+		t.Fatal("expected type")
+	} else if typ != INT {
+		t.Fatalf("unexpected type: %s", typ)
+	}
+}
+
 func TestSessionEnvironmentSorted(t *testing.T) {
 	setup(t, true, true)
 	defer teardown(t)
@@ -7,6 +7,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/bettercap/bettercap/journal"
+
 	"github.com/evilsocket/islazy/log"
 	"github.com/evilsocket/islazy/tui"
 )
@@ -44,6 +46,7 @@ type EventPool struct {
 	silent    bool
 	events    []Event
 	listeners []chan Event
+	retention map[string]int
 }
 
 func NewEventPool(debug bool, silent bool) *EventPool {
@@ -108,6 +111,7 @@ func (p *EventPool) Add(tag string, data interface{}) {
 
 	e := NewEvent(tag, data)
 	p.events = append([]Event{e}, p.events...)
+	p.enforceRetention(tag)
 
 	// broadcast the event to every listener
 	for _, l := range p.listeners {
@@ -131,6 +135,10 @@ func (p *EventPool) Log(level log.Verbosity, format string, args ...interface{})
 
 	if level == log.FATAL {
 		fmt.Fprintf(os.Stderr, "%s\n", message)
+		// we're not going through Session.Close
+		for _, err := range journal.Restore() {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+		}
 		os.Exit(1)
 	}
 }
@@ -141,6 +149,22 @@ func (p *EventPool) Clear() {
 	p.events = make([]Event, 0)
 }
 
+// Since returns the events added after t, the oldest first.
+func (p *EventPool) Since(t time.Time) []Event {
+	p.Lock()
+	defer p.Unlock()
+
+	since := make([]Event, 0)
+	// events are kept the newest first
+	for _, e := range p.events {
+		if e.Time.Before(t) {
+			break
+		}
+		since = append([]Event{e}, since...)
+	}
+	return since
+}
+
 func (p *EventPool) Sorted() []Event {
 	p.Lock()
 	defer p.Unlock()
@@ -0,0 +1,142 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const EventsRetentionParam = "events.retention"
+
+// EventMatchesTag returns true if the rule is "*", the tag itself or one of
+// its parents, for instance "net.sniff" matches "net.sniff.http.request".
+func EventMatchesTag(rule, tag string) bool {
+	return rule == "*" || tag == rule || strings.HasPrefix(tag, rule+".")
+}
+
+// ParseEventsRetention parses a comma separated list of TAG:MAX couples,
+// a MAX of 0 means all the events matching TAG are kept.
+func ParseEventsRetention(spec string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, part := range strings.Split(spec, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+
+		idx := strings.LastIndex(part, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("'%s' is not a valid TAG:MAX couple", part)
+		}
+
+		tag := strings.TrimSpace(part[:idx])
+		max, err := strconv.Atoi(strings.TrimSpace(part[idx+1:]))
+		if err != nil || max < 0 {
+			return nil, fmt.Errorf("'%s' is not a valid maximum number of events for %s", part[idx+1:], tag)
+		}
+		limits[tag] = max
+	}
+	return limits, nil
+}
+
+// EventFilter selects events by tag and time range, empty fields match
+// everything.
+type EventFilter struct {
+	Tags []string
+	From time.Time
+	To   time.Time
+}
+
+func (f EventFilter) Matches(e Event) bool {
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	} else if !f.To.IsZero() && e.Time.After(f.To) {
+		return false
+	} else if len(f.Tags) == 0 {
+		return true
+	}
+
+	for _, tag := range f.Tags {
+		if EventMatchesTag(tag, e.Tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// the most specific rule wins, "*" is used for tags without one.
+func (p *EventPool) retentionRuleFor(tag string) (string, int) {
+	rule, max := "", 0
+	for r, m := range p.retention {
+		if EventMatchesTag(r, tag) && (rule == "" || rule == "*" || len(r) > len(rule)) {
+			rule, max = r, m
+		}
+	}
+	return rule, max
+}
+
+// enforceRetention removes the oldest event governed by the same rule as
+// tag if the rule's limit has been exceeded, must be called with the lock.
+func (p *EventPool) enforceRetention(tag string) {
+	rule, max := p.retentionRuleFor(tag)
+	if max == 0 {
+		return
+	}
+
+	count := 0
+	oldest := -1
+	for i, e := range p.events {
+		if r, _ := p.retentionRuleFor(e.Tag); r == rule {
+			count++
+			if oldest == -1 || e.Time.Before(p.events[oldest].Time) {
+				oldest = i
+			}
+		}
+	}
+
+	if count > max {
+		p.events = append(p.events[:oldest], p.events[oldest+1:]...)
+	}
+}
+
+// SetRetention sets the maximum number of events to keep for each tag and
+// drops the oldest events exceeding the new limits.
+func (p *EventPool) SetRetention(limits map[string]int) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.retention = limits
+
+	sort.Slice(p.events, func(i, j int) bool {
+		return p.events[i].Time.After(p.events[j].Time)
+	})
+
+	counts := make(map[string]int)
+	kept := make([]Event, 0, len(p.events))
+	for _, e := range p.events {
+		rule, max := p.retentionRuleFor(e.Tag)
+		if counts[rule]++; max == 0 || counts[rule] <= max {
+			kept = append(kept, e)
+		}
+	}
+	p.events = kept
+}
+
+// ClearFiltered removes the events matching the filter and returns how
+// many of them were removed.
+func (p *EventPool) ClearFiltered(filter EventFilter) int {
+	p.Lock()
+	defer p.Unlock()
+
+	kept := make([]Event, 0, len(p.events))
+	for _, e := range p.events {
+		if !filter.Matches(e) {
+			kept = append(kept, e)
+		}
+	}
+
+	removed := len(p.events) - len(kept)
+	p.events = kept
+	return removed
+}
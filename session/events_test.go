@@ -299,3 +299,71 @@ func TestEventPool_Add(t *testing.T) {
 		})
 	}
 }
+
+func TestParseEventsRetention(t *testing.T) {
+	limits, err := ParseEventsRetention("net.sniff:10000, wifi.handshake:0,*:500")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(limits) != 3 || limits["net.sniff"] != 10000 || limits["wifi.handshake"] != 0 || limits["*"] != 500 {
+		t.Fatalf("unexpected limits %v", limits)
+	}
+
+	for _, bad := range []string{"net.sniff", "net.sniff:x", ":10", "net.sniff:-1"} {
+		if _, err := ParseEventsRetention(bad); err == nil {
+			t.Errorf("expected error for '%s'", bad)
+		}
+	}
+}
+
+func TestEventPoolRetention(t *testing.T) {
+	p := NewEventPool(false, false)
+	p.SetRetention(map[string]int{"net.sniff": 2, "*": 3})
+
+	for i := 0; i < 5; i++ {
+		p.Add("net.sniff.http.request", i)
+		p.Add("wifi.client.handshake", i)
+		p.Add("sys.log", i)
+	}
+
+	sniff, other := 0, 0
+	for _, e := range p.Sorted() {
+		if EventMatchesTag("net.sniff", e.Tag) {
+			sniff++
+			if e.Data.(int) < 3 {
+				t.Errorf("expected oldest net.sniff events to be removed, found %v", e.Data)
+			}
+		} else {
+			other++
+		}
+	}
+
+	if sniff != 2 {
+		t.Errorf("expected 2 net.sniff events, got %d", sniff)
+	} else if other != 3 {
+		t.Errorf("expected 3 other events, got %d", other)
+	}
+
+	p.SetRetention(map[string]int{"net.sniff": 1})
+	if n := len(p.Sorted()); n != 4 {
+		t.Errorf("expected 4 events after lowering the limit, got %d", n)
+	}
+}
+
+func TestEventPoolClearFiltered(t *testing.T) {
+	p := NewEventPool(false, false)
+	p.Add("net.sniff.dns", nil)
+	p.Add("net.sniffer", nil)
+	p.Add("wifi.client.handshake", nil)
+
+	if n := p.ClearFiltered(EventFilter{Tags: []string{"net.sniff"}}); n != 1 {
+		t.Errorf("expected 1 event cleared, got %d", n)
+	}
+
+	if n := p.ClearFiltered(EventFilter{From: time.Now().Add(time.Hour)}); n != 0 {
+		t.Errorf("expected no event cleared, got %d", n)
+	}
+
+	if n := p.ClearFiltered(EventFilter{To: time.Now()}); n != 2 {
+		t.Errorf("expected 2 events cleared, got %d", n)
+	}
+}
@@ -117,10 +117,12 @@ func (m *SessionModule) Info(format string, args ...interface{}) {
 }
 
 func (m *SessionModule) Warning(format string, args ...interface{}) {
+	m.Session.healthError(m.Name, true, fmt.Sprintf(format, args...))
 	m.Session.Events.Log(log.WARNING, m.tag+format, args...)
 }
 
 func (m *SessionModule) Error(format string, args ...interface{}) {
+	m.Session.healthError(m.Name, false, fmt.Sprintf(format, args...))
 	m.Session.Events.Log(log.ERROR, m.tag+format, args...)
 }
 
@@ -128,6 +130,12 @@ func (m *SessionModule) Fatal(format string, args ...interface{}) {
 	m.Session.Events.Log(log.FATAL, m.tag+format, args...)
 }
 
+// Meter records an action of the module in the session timeline, like the
+// targets it attacked or the answers it spoofed.
+func (m *SessionModule) Meter(action, format string, args ...interface{}) {
+	m.Session.Meter(m.Name, action, format, args...)
+}
+
 func (m *SessionModule) Handlers() []ModuleHandler {
 	return m.handlers
 }
@@ -239,17 +247,26 @@ func (m *SessionModule) SetRunning(running bool, cb func()) error {
 	m.StatusLock.Lock()
 	m.Started = running
 	m.StatusLock.Unlock()
+	m.Session.healthRunning(m.Name, running)
 
 	if running {
+		m.Session.timelineAdd(m.Name, TimelineStarted, "", false)
 		m.Session.Events.Add("mod.started", m.Name)
+		m.Session.fireModuleHooks(HookStart, m.Name, nil)
 	} else {
+		m.Session.cancelTimeBox(m.Name)
+		m.Session.timelineAdd(m.Name, TimelineStopped, "", false)
 		m.Session.Events.Add("mod.stopped", m.Name)
+		m.Session.fireModuleHooks(HookStop, m.Name, nil)
 	}
 
 	if cb != nil {
 		if running {
 			// this is the worker, start async
-			go cb()
+			go func() {
+				defer m.recoverWorker()
+				cb()
+			}()
 		} else {
 			// stop callback, this is sync with a 10 seconds timeout
 			done := make(chan bool, 1)
@@ -0,0 +1,207 @@
+package session
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/evilsocket/islazy/log"
+)
+
+const (
+	WatchdogParam      = "mod.watchdog"
+	WatchdogDelayParam = "mod.watchdog.delay"
+	WatchdogMaxParam   = "mod.watchdog.max"
+)
+
+// ModuleHealth is a snapshot of the lifecycle and error counters of a module.
+type ModuleHealth struct {
+	Name        string                 `json:"name"`
+	Running     bool                   `json:"running"`
+	StartedAt   time.Time              `json:"started_at"`
+	StoppedAt   time.Time              `json:"stopped_at"`
+	Errors      int                    `json:"errors"`
+	Warnings    int                    `json:"warnings"`
+	LastError   string                 `json:"last_error"`
+	LastErrorAt time.Time              `json:"last_error_at"`
+	Panics      int                    `json:"panics"`
+	LastPanic   string                 `json:"last_panic"`
+	Restarts    int                    `json:"restarts"`
+	State       map[string]interface{} `json:"state"`
+}
+
+// ModuleCrashEvent is the data of the module.crashed and module.restarted events.
+type ModuleCrashEvent struct {
+	Module   string `json:"module"`
+	Error    string `json:"error"`
+	Restarts int    `json:"restarts"`
+}
+
+type moduleHealthLog struct {
+	sync.Mutex
+	modules map[string]*ModuleHealth
+}
+
+// withHealth gives access to the health of a module, it's created the
+// first time it's needed.
+func (s *Session) withHealth(module string, cb func(h *ModuleHealth)) {
+	s.health.Lock()
+	defer s.health.Unlock()
+
+	if s.health.modules == nil {
+		s.health.modules = make(map[string]*ModuleHealth)
+	}
+
+	h, found := s.health.modules[module]
+	if !found {
+		h = &ModuleHealth{Name: module}
+		s.health.modules[module] = h
+	}
+	cb(h)
+}
+
+func (s *Session) healthError(module string, warning bool, message string) {
+	s.withHealth(module, func(h *ModuleHealth) {
+		if warning {
+			h.Warnings++
+		} else {
+			h.Errors++
+			h.LastError = message
+			h.LastErrorAt = time.Now()
+		}
+	})
+}
+
+func (s *Session) healthRunning(module string, running bool) {
+	s.withHealth(module, func(h *ModuleHealth) {
+		if running {
+			h.StartedAt = time.Now()
+		} else {
+			h.StoppedAt = time.Now()
+		}
+	})
+}
+
+// ModulesHealth returns the health of every registered module sorted by name.
+func (s *Session) ModulesHealth() []ModuleHealth {
+	list := make([]ModuleHealth, 0, len(s.Modules))
+	for _, m := range s.Modules {
+		var snapshot ModuleHealth
+		s.withHealth(m.Name(), func(h *ModuleHealth) {
+			snapshot = *h
+		})
+		snapshot.Running = m.Running()
+		snapshot.State = m.Extra()
+		list = append(list, snapshot)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+// recoverWorker is deferred by the worker goroutine of every module, if the
+// worker panics the module is flagged as stopped instead of crashing the
+// whole process and, if mod.watchdog is true, it's restarted.
+func (m *SessionModule) recoverWorker() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	m.StatusLock.Lock()
+	m.Started = false
+	m.StatusLock.Unlock()
+
+	err := fmt.Errorf("%v", r)
+	restarts := 0
+	m.Session.withHealth(m.Name, func(h *ModuleHealth) {
+		h.Panics++
+		h.LastPanic = err.Error()
+		h.StoppedAt = time.Now()
+		restarts = h.Restarts
+	})
+
+	m.Session.timelineAdd(m.Name, TimelineCrashed, err.Error(), false)
+	m.Error("crashed: %v", err)
+	m.Debug("%s", debug.Stack())
+
+	m.Session.cancelTimeBox(m.Name)
+	m.Session.Events.Add("module.crashed", ModuleCrashEvent{
+		Module:   m.Name,
+		Error:    err.Error(),
+		Restarts: restarts,
+	})
+	m.Session.fireModuleHooks(HookError, m.Name, err)
+	m.Session.watchdog(m.Name, err)
+}
+
+func (s *Session) watchdog(module string, cause error) {
+	if err, enabled := s.Env.GetBool(WatchdogParam); err != nil || !enabled {
+		return
+	}
+
+	err, max := s.Env.GetInt(WatchdogMaxParam)
+	if err != nil {
+		max = 0
+	}
+
+	restarts := 0
+	s.withHealth(module, func(h *ModuleHealth) {
+		restarts = h.Restarts
+	})
+
+	if max > 0 && restarts >= max {
+		s.Events.Log(log.WARNING, "%s crashed and has already been restarted %d times, giving up.", module, restarts)
+		return
+	}
+
+	err, delay := s.Env.GetInt(WatchdogDelayParam)
+	if err != nil {
+		delay = 0
+	}
+
+	go func() {
+		time.Sleep(time.Duration(delay) * time.Second)
+
+		err, mod := s.Module(module)
+		if err != nil || mod.Running() {
+			return
+		}
+
+		s.withHealth(module, func(h *ModuleHealth) {
+			h.Restarts++
+			restarts = h.Restarts
+		})
+
+		s.Events.Log(log.INFO, "watchdog is restarting %s (attempt %d) ...", module, restarts)
+		if err := mod.Start(); err != nil {
+			s.moduleError(module, err)
+			s.watchdog(module, err)
+			return
+		}
+
+		s.Events.Add("module.restarted", ModuleCrashEvent{
+			Module:   module,
+			Error:    cause.Error(),
+			Restarts: restarts,
+		})
+	}()
+}
+
+func (s *Session) setupWatchdog() {
+	defaults := map[string]string{
+		WatchdogParam:      "false",
+		WatchdogDelayParam: "5",
+		WatchdogMaxParam:   "5",
+	}
+	for name, value := range defaults {
+		if !s.Env.Has(name) {
+			s.SetParam(name, value, OriginSession)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func newHealthTestSession(t *testing.T) *Session {
+	env, err := NewEnvironment("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Session{
+		Env:    env,
+		Events: NewEventPool(false, false),
+		hooks:  newModuleHooks(),
+	}
+	s.setupWatchdog()
+	return s
+}
+
+func waitForHealth(t *testing.T, s *Session, module string, cond func(h *ModuleHealth) bool) {
+	for i := 0; i < 100; i++ {
+		done := false
+		s.withHealth(module, func(h *ModuleHealth) {
+			done = cond(h)
+		})
+		if done {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timeout while waiting for the health of %s", module)
+}
+
+func TestModuleHealthErrors(t *testing.T) {
+	s := newHealthTestSession(t)
+	m := NewSessionModule("test", s)
+
+	m.Warning("first %s", "warning")
+	m.Error("something %s", "failed")
+
+	s.withHealth("test", func(h *ModuleHealth) {
+		if h.Warnings != 1 {
+			t.Errorf("expected 1 warning, got %d", h.Warnings)
+		} else if h.Errors != 1 {
+			t.Errorf("expected 1 error, got %d", h.Errors)
+		} else if h.LastError != "something failed" {
+			t.Errorf("unexpected last error '%s'", h.LastError)
+		}
+	})
+}
+
+func TestModuleHealthRecoverPanic(t *testing.T) {
+	s := newHealthTestSession(t)
+	m := NewSessionModule("test", s)
+
+	if err := m.SetRunning(true, func() { panic("boom") }); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForHealth(t, s, "test", func(h *ModuleHealth) bool {
+		return h.Panics == 1
+	})
+
+	if m.Running() {
+		t.Error("expected crashed module to be flagged as stopped")
+	}
+
+	crashed := false
+	for _, e := range s.Events.Sorted() {
+		if e.Tag == "module.crashed" && e.Data.(ModuleCrashEvent).Error == "boom" {
+			crashed = true
+		}
+	}
+
+	if !crashed {
+		t.Error("expected a module.crashed event")
+	}
+}
@@ -1,13 +1,12 @@
 package session
 
 import (
-	"crypto/rand"
 	"encoding/json"
 	"fmt"
-	"net"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/evilsocket/islazy/tui"
 )
@@ -15,12 +14,39 @@ import (
 type ParamType int
 
 const (
-	STRING ParamType = iota
-	BOOL             = iota
-	INT              = iota
-	FLOAT            = iota
+	STRING   ParamType = iota
+	BOOL               = iota
+	INT                = iota
+	FLOAT              = iota
+	DURATION           = iota
+	LIST               = iota
 )
 
+var paramTypeNames = map[ParamType]string{
+	STRING:   "string",
+	BOOL:     "bool",
+	INT:      "int",
+	FLOAT:    "float",
+	DURATION: "duration",
+	LIST:     "list",
+}
+
+func (t ParamType) String() string {
+	if name, found := paramTypeNames[t]; found {
+		return name
+	}
+	return fmt.Sprintf("type %d", int(t))
+}
+
+func ParseParamType(name string) (error, ParamType) {
+	for t, tname := range paramTypeNames {
+		if tname == strings.ToLower(name) {
+			return nil, t
+		}
+	}
+	return fmt.Errorf("unknown variable type '%s'", name), STRING
+}
+
 type ModuleParam struct {
 	Name        string
 	Type        ParamType
@@ -62,6 +88,15 @@ func NewDecimalParameter(name string, def_value string, desc string) *ModulePara
 	return NewModuleParameter(name, def_value, FLOAT, "^[\\d]+(\\.\\d+)?$", desc)
 }
 
+func NewDurationParameter(name string, def_value string, desc string) *ModuleParam {
+	return NewModuleParameter(name, def_value, DURATION, "", desc)
+}
+
+// NewListParameter creates a parameter for a comma separated list of values.
+func NewListParameter(name string, def_value string, desc string) *ModuleParam {
+	return NewModuleParameter(name, def_value, LIST, "", desc)
+}
+
 func (p ModuleParam) Validate(value string) (error, interface{}) {
 	if p.Validator != nil {
 		if !p.Validator.MatchString(value) {
@@ -87,6 +122,17 @@ func (p ModuleParam) Validate(value string) (error, interface{}) {
 	case FLOAT:
 		i, err := strconv.ParseFloat(value, 64)
 		return err, i
+	case DURATION:
+		d, err := time.ParseDuration(value)
+		return err, d
+	case LIST:
+		list := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return nil, list
 	}
 
 	return fmt.Errorf("Unhandled module parameter type %d.", p.Type), nil
@@ -106,12 +152,11 @@ func (p ModuleParam) parse(s *Session, v string) string {
 	case ParamSubnet:
 		v = s.Interface.CIDR()
 	case ParamRandomMAC:
-		hw := make([]byte, 6)
-		rand.Read(hw)
-		v = net.HardwareAddr(hw).String()
+		v = randomMAC()
+	default:
+		v = s.parseComputedTokens(v)
 	}
 	return v
-
 }
 
 func (p ModuleParam) getUnlocked(s *Session) string {
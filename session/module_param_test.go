@@ -0,0 +1,59 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseParamType(t *testing.T) {
+	for name, expected := range map[string]ParamType{
+		"string":   STRING,
+		"bool":     BOOL,
+		"INT":      INT,
+		"float":    FLOAT,
+		"duration": DURATION,
+		"list":     LIST,
+	} {
+		if err, typ := ParseParamType(name); err != nil {
+			t.Fatalf("unexpected error for %s: %v", name, err)
+		} else if typ != expected {
+			t.Fatalf("expected %s for %s, got %s", expected, name, typ)
+		}
+	}
+
+	if err, _ := ParseParamType("map"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestModuleParamValidate(t *testing.T) {
+	var tests = []struct {
+		Param    *ModuleParam
+		Value    string
+		Expected interface{}
+		Error    bool
+	}{
+		{NewBoolParameter("b", "false", ""), "true", true, false},
+		{NewBoolParameter("b", "false", ""), "yes", nil, true},
+		{NewIntParameter("i", "0", ""), "-42", -42, false},
+		{NewIntParameter("i", "0", ""), "4.2", nil, true},
+		{NewDurationParameter("d", "1s", ""), "500ms", 500 * time.Millisecond, false},
+		{NewDurationParameter("d", "1s", ""), "500", nil, true},
+		{NewListParameter("l", "", ""), "a, b,,c ", []string{"a", "b", "c"}, false},
+		{NewListParameter("l", "", ""), "", []string{}, false},
+	}
+
+	for _, test := range tests {
+		err, v := test.Param.Validate(test.Value)
+		if test.Error {
+			if err == nil {
+				t.Fatalf("expected error for %s '%s'", test.Param.Type, test.Value)
+			}
+		} else if err != nil {
+			t.Fatalf("unexpected error for %s '%s': %v", test.Param.Type, test.Value, err)
+		} else if !reflect.DeepEqual(v, test.Expected) {
+			t.Fatalf("expected %v for %s '%s', got %v", test.Expected, test.Param.Type, test.Value, v)
+		}
+	}
+}
@@ -22,26 +22,61 @@ var (
 			return s.Interface.CIDR()
 		},
 		"{net.sent}": func(s *Session) string {
-			return fmt.Sprintf("%d", s.Queue.Stats.Sent)
+			return fmt.Sprintf("%d", s.Queue.Stats().Sent)
 		},
 		"{net.sent.human}": func(s *Session) string {
-			return humanize.Bytes(s.Queue.Stats.Sent)
+			return humanize.Bytes(s.Queue.Stats().Sent)
 		},
 		"{net.received}": func(s *Session) string {
-			return fmt.Sprintf("%d", s.Queue.Stats.Received)
+			return fmt.Sprintf("%d", s.Queue.Stats().Received)
 		},
 		"{net.received.human}": func(s *Session) string {
-			return humanize.Bytes(s.Queue.Stats.Received)
+			return humanize.Bytes(s.Queue.Stats().Received)
 		},
 		"{net.packets}": func(s *Session) string {
-			return fmt.Sprintf("%d", s.Queue.Stats.PktReceived)
+			return fmt.Sprintf("%d", s.Queue.Stats().PktReceived)
 		},
 		"{net.errors}": func(s *Session) string {
-			return fmt.Sprintf("%d", s.Queue.Stats.Errors)
+			return fmt.Sprintf("%d", s.Queue.Stats().Errors)
+		},
+		"{lan.hosts}": func(s *Session) string {
+			return fmt.Sprintf("%d", len(s.Lan.List()))
+		},
+		"{wifi.aps}": func(s *Session) string {
+			return fmt.Sprintf("%d", len(s.WiFi.List()))
+		},
+		"{wifi.clients}": func(s *Session) string {
+			return fmt.Sprintf("%d", len(s.WiFi.Stations()))
+		},
+		"{wifi.handshakes}": func(s *Session) string {
+			return fmt.Sprintf("%d", s.WiFi.NumHandshakes())
+		},
+		"{wifi.channel}": func(s *Session) string {
+			return s.moduleState("wifi", "channel", "-")
+		},
+		"{ble.devices}": func(s *Session) string {
+			return fmt.Sprintf("%d", len(s.BLE.Devices()))
+		},
+		"{hid.devices}": func(s *Session) string {
+			return fmt.Sprintf("%d", len(s.HID.Devices()))
+		},
+		"{arp.spoof.targets}": func(s *Session) string {
+			return s.moduleState("arp.spoof", "targets", "0")
 		},
 	}
 )
 
+// moduleState returns the value of a module state key or def if the module
+// is not loaded or the key is not set.
+func (s *Session) moduleState(name, key, def string) string {
+	if err, m := s.Module(name); err == nil {
+		if value, found := m.Extra()[key]; found && value != nil {
+			return fmt.Sprintf("%v", value)
+		}
+	}
+	return def
+}
+
 type Prompt struct {
 }
 
@@ -77,8 +112,11 @@ func (p Prompt) Render(s *Session) string {
 		prompt = strings.Replace(prompt, tok, effect, -1)
 	}
 
+	// counters are computed at every render but only if they're used
 	for tok, cb := range PromptCallbacks {
-		prompt = strings.Replace(prompt, tok, cb(s), -1)
+		if strings.Contains(prompt, tok) {
+			prompt = strings.Replace(prompt, tok, cb(s), -1)
+		}
 	}
 
 	// make sure an user error does not screw all terminal
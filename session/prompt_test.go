@@ -0,0 +1,66 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+type promptTestModule struct {
+	SessionModule
+}
+
+func (m promptTestModule) Name() string        { return m.SessionModule.Name }
+func (m promptTestModule) Description() string { return "" }
+func (m promptTestModule) Author() string      { return "" }
+func (m promptTestModule) Start() error        { return nil }
+func (m promptTestModule) Stop() error         { return nil }
+
+func TestPromptModuleState(t *testing.T) {
+	env, err := NewEnvironment("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Session{Env: env}
+	p := NewPrompt()
+
+	s.Env.Set(PromptVariable, "ch:{wifi.channel} targets:{arp.spoof.targets}")
+	if got, exp := p.Render(s), "ch:- targets:0"+tui.RESET; got != exp {
+		t.Fatalf("expected '%s', got '%s'", exp, got)
+	}
+
+	wifi := &promptTestModule{NewSessionModule("wifi", s)}
+	wifi.InitState("channel")
+	s.Register(wifi)
+
+	if got, exp := p.Render(s), "ch:- targets:0"+tui.RESET; got != exp {
+		t.Fatalf("expected '%s', got '%s'", exp, got)
+	}
+
+	wifi.State.Store("channel", 11)
+	if got, exp := p.Render(s), "ch:11 targets:0"+tui.RESET; got != exp {
+		t.Fatalf("expected '%s', got '%s'", exp, got)
+	}
+}
+
+// the device counters must render on every platform, BLE included where
+// it's not supported
+func TestPromptDevices(t *testing.T) {
+	env, err := NewEnvironment("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Session{
+		Env: env,
+		BLE: network.NewBLE(nil, nil),
+		HID: network.NewHID(nil, nil),
+	}
+	p := NewPrompt()
+
+	s.Env.Set(PromptVariable, "ble:{ble.devices} hid:{hid.devices}")
+	if got, exp := p.Render(s), "ble:0 hid:0"+tui.RESET; got != exp {
+		t.Fatalf("expected '%s', got '%s'", exp, got)
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"runtime/pprof"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bettercap/readline"
@@ -62,8 +64,20 @@ type Session struct {
 	Lan       *network.LAN
 	WiFi      *network.WiFi
 	BLE       *network.BLE
+	BT        *network.BT
 	HID       *network.HID
+	Zigbee    *network.Zigbee
+	CAN       *network.CAN
+	SDR       *network.SDR
+	Topology  *network.Topology
+	DNS       *network.DNSLog
+	AD        *network.AD
+	Flows     *network.Flows
+	Expiry    *network.ExpiryPolicy
+	GeoIP     *network.GeoIP
+	Scope     *network.Scope
 	Queue     *packets.Queue
+	Bus       *Bus
 	StartedAt time.Time
 	Active    bool
 	GPS       GPS
@@ -75,6 +89,19 @@ type Session struct {
 	Events         *EventPool
 	UnkCmdCallback UnknownCommandCallback
 	Firewall       firewall.FirewallManager
+
+	dryRun     dryRunLog
+	output     outputState
+	outOfScope outOfScopeLog
+	timeBoxes  timeBoxes
+	hooks      *moduleHooks
+	health     moduleHealthLog
+	timeline   timelineLog
+	capletLock sync.Mutex
+	// the folders of the caplets being evaluated, innermost last
+	capletDirs []string
+	// set for the sessions simulating caplets
+	simulation *CapletSimulation
 }
 
 func New() (*Session, error) {
@@ -94,6 +121,10 @@ func New() (*Session, error) {
 		Env:     nil,
 		Active:  false,
 		Queue:   nil,
+		Scope:   network.NewScope(),
+		Expiry:  network.NewExpiryPolicy(),
+		Bus:     NewBus(),
+		hooks:   newModuleHooks(),
 
 		CoreHandlers:   make([]CommandHandler, 0),
 		Modules:        make([]Module, 0),
@@ -161,7 +192,9 @@ func (s *Session) Close() {
 		}
 	}
 
+	s.dryRunFlush()
 	s.Firewall.Restore()
+	s.restoreJournal()
 
 	if *s.Options.EnvFile != "" {
 		envFile, _ := fs.Expand(*s.Options.EnvFile)
@@ -205,12 +238,17 @@ func (s *Session) Start() error {
 		return s.Modules[i].Name() < s.Modules[j].Name()
 	})
 
+	// before the interface and the gateway endpoints look up their names
+	s.setupRDNS()
+
 	if s.Interface, err = network.FindInterface(*s.Options.InterfaceName); err != nil {
 		return err
 	}
 
-	if s.Queue, err = packets.NewQueue(s.Interface); err != nil {
+	if s.Queue, err = packets.NewQueue(s.Interface, *s.Options.PcapInject); err != nil {
 		return err
+	} else if s.Queue.Native() {
+		s.Events.Log(log.DEBUG, "using native packet injection on %s", s.Interface.Name())
 	}
 
 	if *s.Options.Gateway != "" {
@@ -233,18 +271,61 @@ func (s *Session) Start() error {
 
 	s.Firewall = firewall.Make(s.Interface)
 
+	if err := s.openJournal(); err != nil {
+		s.Events.Log(log.ERROR, "%s", err.Error())
+	}
+
 	s.HID = network.NewHID(func(dev *network.HIDDevice) {
 		s.Events.Add("hid.device.new", dev)
 	}, func(dev *network.HIDDevice) {
 		s.Events.Add("hid.device.lost", dev)
 	})
 
+	s.Zigbee = network.NewZigbee(func(dev *network.ZigbeeDevice) {
+		s.Events.Add("zigbee.device.new", dev)
+	}, func(dev *network.ZigbeeDevice) {
+		s.Events.Add("zigbee.device.lost", dev)
+	})
+
+	s.CAN = network.NewCAN(func(msg *network.CANMessage) {
+		s.Events.Add("can.message.new", msg)
+	}, func(msg *network.CANMessage) {
+		s.Events.Add("can.message.lost", msg)
+	})
+
+	s.SDR = network.NewSDR(func(dev *network.SDRDevice) {
+		s.Events.Add("sdr.device.new", dev)
+	}, func(dev *network.SDRDevice) {
+		s.Events.Add("sdr.device.lost", dev)
+	})
+
 	s.BLE = network.NewBLE(func(dev *network.BLEDevice) {
 		s.Events.Add("ble.device.new", dev)
 	}, func(dev *network.BLEDevice) {
 		s.Events.Add("ble.device.lost", dev)
 	})
 
+	s.BT = network.NewBT(func(dev *network.BTDevice) {
+		s.Events.Add("bt.device.new", dev)
+	}, func(dev *network.BTDevice) {
+		s.Events.Add("bt.device.lost", dev)
+	})
+
+	s.Topology = network.NewTopology(func(node *network.TopologyNode) {
+		s.Events.Add("topology.node.new", node)
+	})
+
+	s.DNS = network.NewDNSLog()
+	s.AD = network.NewAD(func(domain network.ADDomain) {
+		s.Events.Add("ad.domain.new", domain)
+	}, func(dc network.ADController) {
+		s.Events.Add("ad.controller.new", dc)
+	})
+	s.GeoIP = network.NewGeoIP()
+	s.Flows = network.NewFlows()
+	s.Flows.SetGeoIP(s.GeoIP)
+	s.Queue.TrackFlows(s.Flows)
+
 	s.WiFi = network.NewWiFi(s.Interface, func(ap *network.AccessPoint) {
 		s.Events.Add("wifi.ap.new", ap)
 	}, func(ap *network.AccessPoint) {
@@ -256,6 +337,10 @@ func (s *Session) Start() error {
 	}, func(e *network.Endpoint) {
 		s.Events.Add("endpoint.lost", e)
 	})
+	s.Lan.SetExpiry(s.Expiry)
+	s.Lan.OnExpired(func(e network.ExpiredEndpoint) {
+		s.Events.Add("endpoint.expired", e)
+	})
 
 	s.setupEnv()
 
@@ -342,14 +427,64 @@ func (s *Session) ReadLine() (string, error) {
 }
 
 func (s *Session) RunCaplet(filename string) error {
+	return s.runCaplet(filename, nil, fmt.Sprintf("caplet %s", filename))
+}
+
+func (s *Session) runCaplet(filename string, params map[string]string, who string) error {
+	s.capletLock.Lock()
+	nested := len(s.capletDirs) > 0
+	if !filepath.IsAbs(filename) {
+		// caplets including other caplets by their path relative to themselves
+		dir := "."
+		if nested {
+			dir = s.capletDirs[len(s.capletDirs)-1]
+		}
+		if path, err := filepath.Abs(filepath.Join(dir, filename)); err == nil && (fs.Exists(path) || fs.Exists(path+caplets.Suffix)) {
+			filename = path
+		}
+	}
+	s.capletLock.Unlock()
+
 	err, caplet := caplets.Load(filename)
 	if err != nil {
 		return err
 	}
+	return s.evalCaplet(caplet, nil, params, who)
+}
 
-	return caplet.Eval(nil, func(line string) error {
-		return s.Run(line + "\n")
-	})
+// evalCaplet runs the caplet commands on behalf of who, caplets run by other
+// caplets are evaluated in the working directory of the outermost one.
+func (s *Session) evalCaplet(caplet *caplets.Caplet, argv []string, params map[string]string, who string) error {
+	s.capletLock.Lock()
+	nested := len(s.capletDirs) > 0
+	s.capletDirs = append(s.capletDirs, filepath.Dir(caplet.Path))
+	s.capletLock.Unlock()
+
+	defer func() {
+		s.capletLock.Lock()
+		s.capletDirs = s.capletDirs[:len(s.capletDirs)-1]
+		s.capletLock.Unlock()
+	}()
+
+	lineCb := func(line string) error {
+		return s.RunAs(line+"\n", who)
+	}
+	if s.simulation != nil {
+		if err := s.simulation.enter(caplet.Name); err != nil {
+			return err
+		}
+		defer s.simulation.leave()
+
+		lineCb = func(line string) error {
+			return s.simulation.evalLine(s, line, who)
+		}
+	}
+	if nested {
+		// the working directory can't be changed again until the evaluation
+		// of the outermost caplet is over
+		return caplet.EvalHere(argv, params, lineCb)
+	}
+	return caplet.EvalWith(argv, params, lineCb)
 }
 
 func parseCapletCommand(line string) (is bool, caplet *caplets.Caplet, argv []string) {
@@ -370,7 +505,11 @@ func parseCapletCommand(line string) (is bool, caplet *caplets.Caplet, argv []st
 	return false, nil, nil
 }
 
-func (s *Session) Run(line string) error {
+// resolve finds the handler of a command line and returns the function to
+// execute it, so that commands can be validated without running them. The
+// {env.NAME} tokens are only replaced if expand is true, since variables
+// might be defined by the commands that run before this one.
+func (s *Session) resolve(line string, who string, expand bool) (func() error, error) {
 	line = str.TrimRight(line)
 	// remove extra spaces after the first command
 	// so that 'arp.spoof      on' is normalized
@@ -378,15 +517,46 @@ func (s *Session) Run(line string) error {
 	line = reCmdSpaceCleaner.ReplaceAllString(line, "$1 $2")
 
 	// replace all {env.something} with their values
-	line, err := s.parseEnvTokens(line)
-	if err != nil {
-		return err
+	if expand {
+		var err error
+		if line, err = s.parseEnvTokens(line); err != nil {
+			return nil, err
+		}
 	}
 
 	// is it a core command?
 	for _, h := range s.CoreHandlers {
 		if parsed, args := h.Parse(line); parsed {
-			return h.Exec(args, s)
+			if s.simulation != nil {
+				return s.simulation.coreCommand(s, &h, args, who), nil
+			}
+			return func() error {
+				return h.ExecAs(args, s, who)
+			}, nil
+		}
+	}
+
+	// is it a time boxed module command like 'arp.spoof on for 10m'?
+	if isTimeBox, cmd, d := parseTimeBox(line); isTimeBox {
+		for _, m := range s.Modules {
+			for _, h := range m.Handlers() {
+				if parsed, args := h.Parse(cmd); parsed {
+					if s.simulation != nil {
+						return s.simulation.moduleCommand(m), nil
+					}
+					return func() error {
+						if err := h.Exec(args); err != nil {
+							s.moduleError(m.Name(), err)
+							return err
+						} else if !startsModule(h.Name) {
+							s.Events.Log(log.WARNING, "'%s' doesn't start %s, ignoring 'for %s'.", cmd, m.Name(), d)
+							return nil
+						}
+						s.timeBox(m, d)
+						return nil
+					}, nil
+				}
+			}
 		}
 	}
 
@@ -394,22 +564,60 @@ func (s *Session) Run(line string) error {
 	for _, m := range s.Modules {
 		for _, h := range m.Handlers() {
 			if parsed, args := h.Parse(line); parsed {
-				return h.Exec(args)
+				if s.simulation != nil {
+					return s.simulation.moduleCommand(m), nil
+				}
+				return func() error {
+					err := h.Exec(args)
+					if err != nil {
+						s.moduleError(m.Name(), err)
+					}
+					return err
+				}, nil
 			}
 		}
 	}
 
 	// is it a caplet command?
 	if parsed, caplet, argv := parseCapletCommand(line); parsed {
-		return caplet.Eval(argv, func(line string) error {
-			return s.Run(line + "\n")
-		})
+		return func() error {
+			return s.evalCaplet(caplet, argv, nil, who)
+		}, nil
 	}
 
-	// is it a proxy module custom command?
-	if s.UnkCmdCallback != nil && s.UnkCmdCallback(line) {
-		return nil
+	unknown := fmt.Errorf("unknown or invalid syntax \"%s%s%s\", type %shelp%s for the help menu.", tui.BOLD, line, tui.RESET, tui.BOLD, tui.RESET)
+
+	// is it a proxy module custom command? this can only be known by running it.
+	if s.UnkCmdCallback != nil {
+		return func() error {
+			if s.UnkCmdCallback(line) {
+				return nil
+			}
+			return unknown
+		}, nil
 	}
 
-	return fmt.Errorf("unknown or invalid syntax \"%s%s%s\", type %shelp%s for the help menu.", tui.BOLD, line, tui.RESET, tui.BOLD, tui.RESET)
+	return nil, unknown
+}
+
+// Validate returns an error if the command line doesn't match any command,
+// without executing it, {env.NAME} tokens are left as they are.
+func (s *Session) Validate(line string) error {
+	_, err := s.resolve(line, "", false)
+	return err
+}
+
+// Run executes a command line on behalf of the interactive console.
+func (s *Session) Run(line string) error {
+	return s.RunAs(line, OriginConsole)
+}
+
+// RunAs executes a command line on behalf of who (an API client, a caplet,
+// a module ...), which is reported by the param.changed events.
+func (s *Session) RunAs(line string, who string) error {
+	exec, err := s.resolve(line, who, true)
+	if err != nil {
+		return err
+	}
+	return exec()
 }
@@ -0,0 +1,70 @@
+package session
+
+import (
+	"strings"
+)
+
+// the origins of the commands run by the session itself
+const (
+	OriginConsole = "console"
+	OriginHook    = "hook"
+	OriginSession = "session"
+)
+
+// ParamChangedEvent is emitted every time the set command changes the value
+// of a parameter or variable.
+type ParamChangedEvent struct {
+	Who      string `json:"who"`
+	Name     string `json:"name"`
+	Old      string `json:"old"`
+	New      string `json:"new"`
+	Declared bool   `json:"declared"`
+}
+
+// the values of parameters with these words in their names are not reported
+var auditRedacted = []string{"password", "passwd", "passphrase", "secret", "token"}
+
+func auditValue(name, value string) string {
+	if value != "" {
+		name = strings.ToLower(name)
+		for _, word := range auditRedacted {
+			if strings.Contains(name, word) {
+				return "********"
+			}
+		}
+	}
+	return value
+}
+
+// auditParam emits a param.changed event if the value changed.
+func (s *Session) auditParam(who string, name string, declared bool, old string, value string) {
+	if declared && old == value {
+		return
+	}
+
+	if who == "" {
+		who = OriginConsole
+	}
+
+	s.Events.Add("param.changed", ParamChangedEvent{
+		Who:      who,
+		Name:     name,
+		Old:      auditValue(name, old),
+		New:      auditValue(name, value),
+		Declared: declared,
+	})
+}
+
+// SetParam validates and sets the value of a parameter or variable on behalf
+// of who, emitting a param.changed event if it changed, modules should use it
+// instead of Env.Set for anything the user might be changing.
+func (s *Session) SetParam(name string, value string, who string) error {
+	if err := s.validateVariable(name, value); err != nil {
+		return err
+	}
+
+	declared, old := s.Env.Get(name)
+	s.Env.Set(name, value)
+	s.auditParam(who, name, declared, old, value)
+	return nil
+}
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"testing"
+)
+
+func auditSession(t *testing.T) *Session {
+	env, err := NewEnvironment("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Session{
+		Env:    env,
+		Events: NewEventPool(false, false),
+	}
+	s.registerCoreHandlers()
+	return s
+}
+
+func paramEvents(s *Session) []ParamChangedEvent {
+	changes := make([]ParamChangedEvent, 0)
+	for _, e := range s.Events.Sorted() {
+		if e.Tag == "param.changed" {
+			changes = append(changes, e.Data.(ParamChangedEvent))
+		}
+	}
+	return changes
+}
+
+func TestSetAudit(t *testing.T) {
+	s := auditSession(t)
+
+	if err := s.RunAs("set arp.spoof.targets 192.168.1.10", "api.rest admin@10.0.0.1"); err != nil {
+		t.Fatal(err)
+	} else if err := s.Run("set arp.spoof.targets 192.168.1.20"); err != nil {
+		t.Fatal(err)
+	} else if err := s.Run("set arp.spoof.targets 192.168.1.20"); err != nil {
+		t.Fatal(err)
+	} else if err := s.Run("set api.rest.password hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := paramEvents(s)
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+
+	exp := []ParamChangedEvent{
+		{Who: "api.rest admin@10.0.0.1", Name: "arp.spoof.targets", Old: "", New: "192.168.1.10", Declared: false},
+		{Who: OriginConsole, Name: "arp.spoof.targets", Old: "192.168.1.10", New: "192.168.1.20", Declared: true},
+		{Who: OriginConsole, Name: "api.rest.password", Old: "", New: "********", Declared: false},
+	}
+	for i, change := range changes {
+		// events are sorted by time
+		if change != exp[i] {
+			t.Fatalf("expected %+v, got %+v", exp[i], change)
+		}
+	}
+}
+
+func TestSetAuditParallel(t *testing.T) {
+	s := auditSession(t)
+
+	if err := s.RunAs("parallel { set a 1; set b 2 }", "ticker"); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := paramEvents(s)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", changes)
+	}
+	for _, change := range changes {
+		if change.Who != "ticker" {
+			t.Fatalf("expected the parallel commands to be run as ticker, got %+v", change)
+		}
+	}
+}
+
+func TestSetParamAudit(t *testing.T) {
+	s := auditSession(t)
+
+	if err := s.RunAs("var retries int 3", "tester"); err != nil {
+		t.Fatal(err)
+	} else if err := s.SetParam("retries", "nope", "wifi"); err == nil {
+		t.Fatal("expected error")
+	} else if err := s.SetParam("retries", "5", "wifi"); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []ParamChangedEvent{
+		{Who: "tester", Name: "retries", Old: "", New: "3", Declared: false},
+		{Who: "wifi", Name: "retries", Old: "3", New: "5", Declared: true},
+	}
+	changes := paramEvents(s)
+	if len(changes) != len(exp) {
+		t.Fatalf("expected %d changes, got %+v", len(exp), changes)
+	}
+	for i, change := range changes {
+		if change != exp[i] {
+			t.Fatalf("expected %+v, got %+v", exp[i], change)
+		}
+	}
+}
@@ -59,6 +59,14 @@ func (s *Session) HIDCompleter(prefix string) []string {
 	return macs
 }
 
+func (s *Session) CANCompleter(prefix string) []string {
+	ids := []string{""}
+	s.CAN.EachMessage(func(address string, msg *network.CANMessage) {
+		addIfMatches(&ids, prefix, address)
+	})
+	return ids
+}
+
 func (s *Session) EventsCompleter(prefix string) []string {
 	events := []string{""}
 	all := []string{
@@ -68,12 +76,19 @@ func (s *Session) EventsCompleter(prefix string) []string {
 		"update.available",
 		"mod.started",
 		"mod.stopped",
+		"mod.error",
+		"module.crashed",
+		"module.restarted",
 		"endpoint.new",
 		"endpoint.lost",
+		"endpoint.expired",
+		"endpoint.fingerprint",
 		"wifi.client.lost",
 		"wifi.client.probe",
 		"wifi.client.new",
 		"wifi.client.handshake",
+		"wifi.p2p.new",
+		"wifi.p2p.lost",
 		"wifi.ap.new",
 		"wifi.ap.lost",
 		"ble.device.service.discovered",
@@ -82,8 +97,20 @@ func (s *Session) EventsCompleter(prefix string) []string {
 		"ble.device.new",
 		"ble.device.lost",
 		"ble.connection.timeout",
+		"bt.device.new",
+		"bt.device.lost",
 		"hid.device.new",
 		"hid.device.lost",
+		"zigbee.device.new",
+		"zigbee.device.lost",
+		"zigbee.permit.join",
+		"can.message.new",
+		"can.message.lost",
+		"sdr.device.new",
+		"sdr.device.lost",
+		"sdr.message",
+		"ad.domain.new",
+		"ad.controller.new",
 		"http.spoofed-request",
 		"http.spoofed-response",
 		"https.spoofed-request",
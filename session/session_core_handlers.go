@@ -10,10 +10,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bettercap/bettercap/caplets"
 	"github.com/bettercap/bettercap/core"
 	"github.com/bettercap/bettercap/network"
 
 	"github.com/bettercap/readline"
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/log"
 	"github.com/evilsocket/islazy/str"
 	"github.com/evilsocket/islazy/tui"
 )
@@ -55,7 +58,7 @@ func (s *Session) generalHelp() {
 		fmt.Printf("  "+tui.Yellow(pad)+" > %s\n", m.Name(), status)
 	}
 
-	fmt.Println()
+	fmt.Printf("\n%s\n\n", tui.Dim("Append 'for <duration>' to a command starting a module to stop it after that time, e.g. 'arp.spoof on for 10m'."))
 }
 
 func (s *Session) moduleHelp(filter string) error {
@@ -219,7 +222,7 @@ func (s *Session) getHandler(args []string, sess *Session) error {
 	return nil
 }
 
-func (s *Session) setHandler(args []string, sess *Session) error {
+func (s *Session) setHandler(args []string, sess *Session, who string) error {
 	key := args[0]
 	value := args[1]
 
@@ -227,11 +230,10 @@ func (s *Session) setHandler(args []string, sess *Session) error {
 		value = ""
 	}
 
-	s.Env.Set(key, value)
-	return nil
+	return s.SetParam(key, value, who)
 }
 
-func (s *Session) readHandler(args []string, sess *Session) error {
+func (s *Session) readHandler(args []string, sess *Session, who string) error {
 	key := args[0]
 	prompt := args[1]
 
@@ -244,8 +246,7 @@ func (s *Session) readHandler(args []string, sess *Session) error {
 		value = ""
 	}
 
-	s.Env.Set(key, value)
-	return nil
+	return s.SetParam(key, value, who)
 }
 
 func (s *Session) clsHandler(args []string, sess *Session) error {
@@ -259,8 +260,17 @@ func (s *Session) clsHandler(args []string, sess *Session) error {
 	return nil
 }
 
-func (s *Session) includeHandler(args []string, sess *Session) error {
-	return s.RunCaplet(args[0])
+func (s *Session) includeHandler(args []string, sess *Session, who string) error {
+	filename := str.Trim(args[0])
+	params, err := caplets.ParseParams(args[1])
+	if err != nil {
+		return fmt.Errorf("error parsing the parameters of %s: %v", filename, err)
+	}
+
+	if who == "" {
+		who = fmt.Sprintf("caplet %s", filename)
+	}
+	return s.runCaplet(filename, params, who)
 }
 
 func (s *Session) shHandler(args []string, sess *Session) error {
@@ -281,6 +291,91 @@ func (s *Session) aliasHandler(args []string, sess *Session) error {
 	return nil
 }
 
+func (s *Session) aliasImportHandler(args []string, sess *Session) error {
+	fileName, err := fs.Expand(str.Trim(args[0]))
+	if err != nil {
+		return err
+	}
+
+	fp, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	err, aliases := network.ParseAliasesCSV(fp)
+	if err != nil {
+		return fmt.Errorf("%s: %v", fileName, err)
+	}
+
+	found := 0
+	for mac, alias := range aliases {
+		if s.Lan.SetAliasFor(mac, alias) {
+			found++
+		}
+	}
+
+	s.Events.Log(log.INFO, "imported %d aliases from %s (%d known hosts)", len(aliases), fileName, found)
+	return nil
+}
+
+// refreshVendors updates the vendor of every known endpoint after the vendors database changed.
+func (s *Session) refreshVendors() {
+	refresh := func(e *network.Endpoint) {
+		if e != nil {
+			e.Vendor = network.ManufLookup(e.HwAddress)
+		}
+	}
+
+	refresh(s.Interface)
+	refresh(s.Gateway)
+
+	if s.Lan != nil {
+		s.Lan.EachHost(func(mac string, e *network.Endpoint) {
+			refresh(e)
+		})
+	}
+
+	if s.WiFi != nil {
+		s.WiFi.EachAccessPoint(func(mac string, ap *network.AccessPoint) {
+			refresh(ap.Endpoint)
+			ap.EachClient(func(mac string, station *network.Station) {
+				refresh(station.Endpoint)
+			})
+		})
+	}
+
+	if s.Topology != nil {
+		for _, node := range s.Topology.Nodes() {
+			node.Lock()
+			node.Vendor = network.ManufLookup(node.Address)
+			node.Unlock()
+		}
+	}
+}
+
+func (s *Session) vendorHandler(args []string, sess *Session) error {
+	vendor := str.Trim(args[1])
+	if vendor == "\"\"" || vendor == "''" {
+		vendor = ""
+	}
+	if err := network.ManufOverride(args[0], vendor); err != nil {
+		return err
+	}
+	s.refreshVendors()
+	return nil
+}
+
+func (s *Session) vendorLoadHandler(args []string, sess *Session) error {
+	err, loaded := network.ManufLoad(str.Trim(args[0]))
+	if err != nil {
+		return err
+	}
+	s.refreshVendors()
+	fmt.Printf("loaded %d vendor prefixes from %s\n", loaded, args[0])
+	return nil
+}
+
 func (s *Session) addHandler(h CommandHandler, c *readline.PrefixCompleter) {
 	h.Completer = c
 	s.CoreHandlers = append(s.CoreHandlers, h)
@@ -314,12 +409,36 @@ func (s *Session) registerCoreHandlers() {
 		s.exitHandler),
 		readline.PcItem("quit"))
 
+	s.addHandler(NewCommandHandler("killswitch",
+		"^killswitch$",
+		"Immediately stop every module and revert every change made to the network state.",
+		s.killSwitchHandler),
+		readline.PcItem("killswitch"))
+
+	s.addHandler(NewCommandHandler("journal",
+		"^journal$",
+		"Show the changes to the network state that will be reverted on exit.",
+		s.journalHandler),
+		readline.PcItem("journal"))
+
+	s.addHandler(NewCommandHandler("session.timeline FILENAME?",
+		"^session\\.timeline\\s*(.*)$",
+		"Show the modules started and stopped and the actions they took in chronological order, or save them to FILENAME as CSV (JSON if it ends with .json).",
+		s.timelineHandler),
+		readline.PcItem("session.timeline"))
+
 	s.addHandler(NewCommandHandler("sleep SECONDS",
 		"^sleep\\s+(\\d+)$",
 		"Sleep for the given amount of seconds.",
 		s.sleepHandler),
 		readline.PcItem("sleep"))
 
+	s.addHandler(newCommandHandlerAs("parallel { COMMANDS }",
+		`^parallel\s*\{(.+)\}$`,
+		"Run the ; separated COMMANDS concurrently and wait for all of them to complete.",
+		s.parallelHandler),
+		readline.PcItem("parallel"))
+
 	s.addHandler(NewCommandHandler("get NAME",
 		"^get\\s+(.+)",
 		"Get the value of variable NAME, use * alone for all, or NAME* as a wildcard.",
@@ -335,7 +454,7 @@ func (s *Session) registerCoreHandlers() {
 			return varNames
 		})))
 
-	s.addHandler(NewCommandHandler("set NAME VALUE",
+	s.addHandler(newCommandHandlerAs("set NAME VALUE",
 		"^set\\s+([^\\s]+)\\s+(.+)",
 		"Set the VALUE of variable NAME.",
 		s.setHandler),
@@ -350,7 +469,19 @@ func (s *Session) registerCoreHandlers() {
 			return varNames
 		})))
 
-	s.addHandler(NewCommandHandler("read VARIABLE PROMPT",
+	s.addHandler(newCommandHandlerAs("var NAME TYPE VALUE?",
+		`^var\s+([^\s]+)\s+([a-z]+)\s*(.*)$`,
+		"Declare NAME as a variable of TYPE (string, int, bool, float, duration or list) so that its values are validated when set, optionally with an initial VALUE.",
+		s.varHandler),
+		readline.PcItem("var"))
+
+	s.addHandler(NewCommandHandler("vars",
+		"^vars$",
+		"Show the typed variables and the values of the computed ones like {gateway.ip}, {iface.mac} or {random.mac}.",
+		s.varsHandler),
+		readline.PcItem("vars"))
+
+	s.addHandler(newCommandHandlerAs("read VARIABLE PROMPT",
 		`^read\s+([^\s]+)\s+(.+)$`,
 		"Show a PROMPT to ask the user for input that will be saved inside VARIABLE.",
 		s.readHandler),
@@ -362,9 +493,9 @@ func (s *Session) registerCoreHandlers() {
 		s.clsHandler),
 		readline.PcItem("clear"))
 
-	s.addHandler(NewCommandHandler("include CAPLET",
-		"^include\\s+(.+)",
-		"Load and run this caplet in the current session.",
+	s.addHandler(newReentrantHandler("include CAPLET [with NAME=VALUE ...]",
+		`^include\s+(.+?)(?:\s+with\s+(.+))?$`,
+		"Load and run this caplet in the current session, every {{NAME}} in the caplet will be replaced with the VALUE given for it.",
 		s.includeHandler),
 		readline.PcItem("include", readline.PcItemDynamic(func(prefix string) []string {
 			prefix = str.Trim(prefix[8:])
@@ -397,4 +528,94 @@ func (s *Session) registerCoreHandlers() {
 			return macs
 		})))
 
+	s.addHandler(NewCommandHandler("alias.import FILENAME",
+		"^alias\\.import\\s+(.+)",
+		"Import the aliases of a CSV file of mac,alias rows.",
+		s.aliasImportHandler),
+		readline.PcItem("alias.import", readline.PcItemDynamic(func(prefix string) []string {
+			prefix = str.Trim(prefix[12:])
+			if prefix == "" {
+				prefix = "."
+			}
+
+			files, _ := filepath.Glob(prefix + "*")
+			return files
+		})))
+
+	s.addHandler(newCommandHandlerAs("profile save|load|delete NAME PATTERNS?",
+		`^profile\s+(save|load|delete)\s+([a-zA-Z0-9_\.\-]+)\s*(.*)$`,
+		"Save the values of the parameters matching the glob PATTERNS (all of them if empty) as the profile NAME, load them back or delete the profile.",
+		s.profileHandler),
+		readline.PcItem("profile",
+			readline.PcItem("save"),
+			readline.PcItem("load", readline.PcItemDynamic(func(prefix string) []string {
+				return profileNames()
+			})),
+			readline.PcItem("delete", readline.PcItemDynamic(func(prefix string) []string {
+				return profileNames()
+			}))))
+
+	s.addHandler(NewCommandHandler("profiles",
+		"^profiles$",
+		"Show the saved parameter profiles.",
+		s.profilesHandler),
+		readline.PcItem("profiles"))
+
+	s.addHandler(NewCommandHandler("loot.passphrase",
+		`^loot\.passphrase$`,
+		"Encrypt the saved handshakes, cookie jars, reports, mails, carved files, cracked keys and timelines with a passphrase asked without echoing it (empty to disable) unless loot.recipient is set, the passphrase is read from the "+LootPassphraseEnv+" environment variable at startup or when there's no interactive session.",
+		s.lootPassphraseHandler),
+		readline.PcItem("loot.passphrase"))
+
+	s.addHandler(NewCommandHandler("loot.decrypt FILENAME KEYFILE?",
+		`^loot\.decrypt\s+([^\s]+)\s*(.*)$`,
+		"Decrypt a saved artifact with the loot.passphrase or with the RSA private KEYFILE of its loot.recipient.",
+		s.lootDecryptHandler),
+		readline.PcItem("loot.decrypt", readline.PcItemDynamic(func(prefix string) []string {
+			prefix = str.Trim(prefix[12:])
+			if prefix == "" {
+				prefix = "."
+			}
+
+			files, _ := filepath.Glob(prefix + "*")
+			return files
+		})))
+
+	s.addHandler(NewCommandHandler("on MODULE start|stop|error COMMANDS",
+		`^on\s+([^\s]+)\s+(start|stop|error)\s+(.+)$`,
+		"Run COMMANDS every time MODULE (or any module if *) starts, stops or fails, {{module}} and {{error}} are replaced with the module name and the error.",
+		s.onHandler),
+		readline.PcItem("on"))
+
+	s.addHandler(NewCommandHandler("hooks",
+		"^hooks$",
+		"Show the module hooks created with the on command.",
+		s.hooksHandler),
+		readline.PcItem("hooks"))
+
+	s.addHandler(NewCommandHandler("hooks.clear",
+		"^hooks\\.clear$",
+		"Remove every module hook.",
+		s.hooksClearHandler),
+		readline.PcItem("hooks.clear"))
+
+	s.addHandler(NewCommandHandler("vendor PREFIX NAME",
+		"^vendor\\s+([a-fA-F0-9:\\-]+(?:/\\d+)?)\\s*(.*)",
+		"Override the vendor name of a MAC prefix (XX:XX:XX or XX:XX:XX:XX:XX:XX/BITS), use an empty name to remove the override.",
+		s.vendorHandler),
+		readline.PcItem("vendor"))
+
+	s.addHandler(NewCommandHandler("vendor.load FILE",
+		"^vendor\\.load\\s+(.+)",
+		"Load additional vendor names from FILE, either in the wireshark manuf format or as an IEEE registry CSV (oui.csv, mam.csv, oas.csv).",
+		s.vendorLoadHandler),
+		readline.PcItem("vendor.load", readline.PcItemDynamic(func(prefix string) []string {
+			prefix = str.Trim(prefix[11:])
+			if prefix == "" {
+				prefix = "."
+			}
+
+			files, _ := filepath.Glob(prefix + "*")
+			return files
+		})))
 }
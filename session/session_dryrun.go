@@ -0,0 +1,124 @@
+package session
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/log"
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	DryRunParam    = "offense.dryrun"
+	dryRunInterval = 1 * time.Second
+)
+
+// dryRunLog collapses the identical actions logged within the same interval,
+// otherwise floods and deauth loops would make the output unreadable.
+type dryRunLog struct {
+	sync.Mutex
+	counts map[string]int
+	order  []string
+	stop   chan struct{}
+}
+
+// IsDryRun returns true if offense.dryrun is enabled and modules should not transmit.
+func (s *Session) IsDryRun() bool {
+	s.dryRun.Lock()
+	defer s.dryRun.Unlock()
+	return s.dryRun.stop != nil
+}
+
+// DryRunPacket logs a packet that would have been injected, starting from the given layer.
+func (s *Session) DryRunPacket(raw []byte, first gopacket.LayerType) {
+	s.dryRunTrack(packets.Summarize(raw, first))
+}
+
+// DryRunAction logs any other offensive action that would have been performed.
+func (s *Session) DryRunAction(format string, args ...interface{}) {
+	s.dryRunTrack(fmt.Sprintf(format, args...))
+}
+
+func (s *Session) dryRunTrack(what string) {
+	s.dryRun.Lock()
+	defer s.dryRun.Unlock()
+
+	if s.dryRun.counts == nil {
+		s.dryRun.counts = make(map[string]int)
+	}
+	if _, found := s.dryRun.counts[what]; !found {
+		s.dryRun.order = append(s.dryRun.order, what)
+	}
+	s.dryRun.counts[what]++
+}
+
+func (s *Session) dryRunFlush() {
+	s.dryRun.Lock()
+	counts, order := s.dryRun.counts, s.dryRun.order
+	s.dryRun.counts, s.dryRun.order = nil, nil
+	s.dryRun.Unlock()
+
+	for _, what := range order {
+		if n := counts[what]; n > 1 {
+			s.Events.Log(log.INFO, "%s would send %dx %s", tui.Yellow("[dry-run]"), n, what)
+		} else {
+			s.Events.Log(log.INFO, "%s would send %s", tui.Yellow("[dry-run]"), what)
+		}
+	}
+}
+
+func (s *Session) setDryRun(enabled bool) {
+	s.dryRun.Lock()
+	running := s.dryRun.stop != nil
+	s.dryRun.Unlock()
+
+	if enabled == running {
+		return
+	}
+
+	if enabled {
+		stop := make(chan struct{})
+		s.dryRun.Lock()
+		s.dryRun.stop = stop
+		s.dryRun.Unlock()
+
+		if s.Queue != nil {
+			s.Queue.SetDryRun(func(raw []byte) {
+				s.DryRunPacket(raw, layers.LayerTypeEthernet)
+			})
+		}
+
+		go func() {
+			ticker := time.NewTicker(dryRunInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					s.dryRunFlush()
+				case <-stop:
+					return
+				}
+			}
+		}()
+
+		s.Events.Log(log.WARNING, "%s is enabled, injected packets and offensive actions will be logged and not performed.", DryRunParam)
+	} else {
+		if s.Queue != nil {
+			s.Queue.SetDryRun(nil)
+		}
+
+		s.dryRun.Lock()
+		close(s.dryRun.stop)
+		s.dryRun.stop = nil
+		s.dryRun.Unlock()
+
+		s.dryRunFlush()
+		s.Events.Log(log.WARNING, "%s is disabled, packets will be sent.", DryRunParam)
+	}
+}
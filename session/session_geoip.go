@@ -0,0 +1,30 @@
+package session
+
+import (
+	"strings"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/log"
+)
+
+const GeoIPParam = "geoip.db"
+
+// loadGeoIP (re)loads the comma separated list of MaxMind databases used to
+// annotate external addresses, an empty value disables the lookups.
+func (s *Session) loadGeoIP(value string) {
+	paths := make([]string, 0)
+	for _, path := range strings.Split(value, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		} else if expanded, err := fs.Expand(path); err == nil {
+			path = expanded
+		}
+		paths = append(paths, path)
+	}
+
+	if err := s.GeoIP.Load(paths...); err != nil {
+		s.Events.Log(log.ERROR, "%s: %v", GeoIPParam, err)
+	} else if len(paths) > 0 {
+		s.Events.Log(log.INFO, "loaded geoip databases: %s", strings.Join(s.GeoIP.Databases(), ", "))
+	}
+}
@@ -0,0 +1,167 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/evilsocket/islazy/log"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	HookStart = "start"
+	HookStop  = "stop"
+	HookError = "error"
+)
+
+// ModuleErrorEvent is the data of the mod.error event.
+type ModuleErrorEvent struct {
+	Module string `json:"module"`
+	Error  string `json:"error"`
+}
+
+// ModuleEventCallback is called every time a module starts, stops or returns
+// an error, err is only set for the HookError event.
+type ModuleEventCallback func(event string, module string, err error)
+
+type moduleHook struct {
+	Module   string
+	Event    string
+	Commands string
+}
+
+type moduleHooks struct {
+	sync.Mutex
+	hooks     []moduleHook
+	callbacks map[int]ModuleEventCallback
+	nextID    int
+}
+
+func newModuleHooks() *moduleHooks {
+	return &moduleHooks{
+		hooks:     make([]moduleHook, 0),
+		callbacks: make(map[int]ModuleEventCallback),
+	}
+}
+
+func (h moduleHook) matches(event, module string) bool {
+	return h.Event == event && (h.Module == "*" || h.Module == module)
+}
+
+// AddModuleHook runs COMMANDS every time the module (or any module if '*') is started,
+// stopped or returns an error, {{module}} and {{error}} are replaced in the commands.
+func (s *Session) AddModuleHook(module, event, commands string) error {
+	if event != HookStart && event != HookStop && event != HookError {
+		return fmt.Errorf("invalid event '%s', expected %s, %s or %s", event, HookStart, HookStop, HookError)
+	} else if module != "*" {
+		if err, _ := s.Module(module); err != nil {
+			return err
+		}
+	}
+
+	s.hooks.Lock()
+	defer s.hooks.Unlock()
+	s.hooks.hooks = append(s.hooks.hooks, moduleHook{
+		Module:   module,
+		Event:    event,
+		Commands: commands,
+	})
+	return nil
+}
+
+// OnModuleEvent registers a callback for the lifecycle events of every module, used
+// by the scripting engine, and returns the function to unregister it.
+func (s *Session) OnModuleEvent(cb ModuleEventCallback) func() {
+	s.hooks.Lock()
+	defer s.hooks.Unlock()
+
+	id := s.hooks.nextID
+	s.hooks.nextID++
+	s.hooks.callbacks[id] = cb
+
+	return func() {
+		s.hooks.Lock()
+		defer s.hooks.Unlock()
+		delete(s.hooks.callbacks, id)
+	}
+}
+
+// fireModuleHooks is asynchronous since the commands of the hooks can start
+// and stop modules themselves.
+func (s *Session) fireModuleHooks(event string, module string, err error) {
+	s.hooks.Lock()
+	cmds := make([]string, 0)
+	for _, h := range s.hooks.hooks {
+		if h.matches(event, module) {
+			cmds = append(cmds, h.Commands)
+		}
+	}
+	callbacks := make([]ModuleEventCallback, 0, len(s.hooks.callbacks))
+	for _, cb := range s.hooks.callbacks {
+		callbacks = append(callbacks, cb)
+	}
+	s.hooks.Unlock()
+
+	if len(cmds) == 0 && len(callbacks) == 0 {
+		return
+	}
+
+	errDesc := ""
+	if err != nil {
+		errDesc = err.Error()
+	}
+
+	go func() {
+		for _, cb := range callbacks {
+			cb(event, module, err)
+		}
+
+		for _, commands := range cmds {
+			commands = strings.Replace(commands, "{{module}}", module, -1)
+			commands = strings.Replace(commands, "{{error}}", errDesc, -1)
+			for _, cmd := range ParseCommands(commands) {
+				if err := s.RunAs(cmd, OriginHook); err != nil {
+					s.Events.Log(log.ERROR, "error while running %s hook of %s: %s", event, module, err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *Session) moduleError(module string, err error) {
+	s.healthError(module, false, err.Error())
+	s.Events.Add("mod.error", ModuleErrorEvent{
+		Module: module,
+		Error:  err.Error(),
+	})
+	s.fireModuleHooks(HookError, module, err)
+}
+
+func (s *Session) onHandler(args []string, sess *Session) error {
+	return s.AddModuleHook(args[0], args[1], args[2])
+}
+
+func (s *Session) hooksHandler(args []string, sess *Session) error {
+	s.hooks.Lock()
+	defer s.hooks.Unlock()
+
+	rows := make([][]string, 0)
+	for _, h := range s.hooks.hooks {
+		rows = append(rows, []string{tui.Bold(h.Module), tui.Green(h.Event), h.Commands})
+	}
+
+	if len(rows) > 0 {
+		s.Table([]string{"Module", "Event", "Commands"}, rows)
+	}
+
+	return nil
+}
+
+func (s *Session) hooksClearHandler(args []string, sess *Session) error {
+	s.hooks.Lock()
+	defer s.hooks.Unlock()
+	s.hooks.hooks = make([]moduleHook, 0)
+	return nil
+}
@@ -0,0 +1,83 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bettercap/bettercap/journal"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/log"
+	"github.com/evilsocket/islazy/tui"
+)
+
+// openJournal persists the changes to the network state and reverts the ones
+// left by a previous session that didn't exit cleanly, module restorers must
+// be registered at this point.
+func (s *Session) openJournal() error {
+	fileName, err := fs.Expand(*s.Options.Journal)
+	if err != nil {
+		return err
+	}
+
+	err, leftovers := journal.Open(fileName)
+	if err != nil {
+		return err
+	} else if len(leftovers) > 0 {
+		s.Events.Log(log.WARNING, "reverting %d changes left by a previous session that did not exit cleanly ...", len(leftovers))
+		s.restoreJournal()
+	}
+
+	return nil
+}
+
+// restoreJournal reverts every change still tracked by the journal.
+func (s *Session) restoreJournal() {
+	for _, err := range journal.Restore() {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+	}
+}
+
+func (s *Session) journalHandler(args []string, sess *Session) error {
+	actions := journal.Actions()
+	if len(actions) == 0 {
+		fmt.Printf("\nno changes to revert.\n\n")
+		return nil
+	}
+
+	rows := make([][]string, 0)
+	for _, a := range actions {
+		rows = append(rows, []string{
+			a.Time.Format("15:04:05"),
+			a.Kind,
+			a.ID,
+			tui.Dim(time.Since(a.Time).Round(time.Second).String()),
+		})
+	}
+
+	s.Println()
+	s.Table([]string{"Time", "Kind", "ID", "Age"}, rows)
+	s.Println()
+	return nil
+}
+
+func (s *Session) killSwitchHandler(args []string, sess *Session) error {
+	for _, mod := range s.Modules {
+		if mod.Running() {
+			if err := mod.Stop(); err != nil {
+				s.Events.Log(log.ERROR, "error while stopping %s: %s", mod.Name(), err)
+			}
+		}
+	}
+
+	s.Firewall.Restore()
+	s.restoreJournal()
+
+	if left := len(journal.Actions()); left > 0 {
+		return fmt.Errorf("%d changes could not be reverted, see the journal command", left)
+	}
+
+	s.Events.Log(log.WARNING, "all modules stopped and network state restored.")
+	return nil
+}
@@ -48,8 +48,17 @@ type sessionJSON struct {
 	Lan        *network.LAN      `json:"lan"`
 	WiFi       *network.WiFi     `json:"wifi"`
 	BLE        *network.BLE      `json:"ble"`
+	BT         *network.BT       `json:"bt"`
 	HID        *network.HID      `json:"hid"`
+	Zigbee     *network.Zigbee   `json:"zigbee"`
+	CAN        *network.CAN      `json:"can"`
+	SDR        *network.SDR      `json:"sdr"`
+	Topology   *network.Topology `json:"topology"`
+	DNS        *network.DNSLog   `json:"dns"`
+	AD         *network.AD       `json:"ad"`
+	Flows      *network.Flows    `json:"flows"`
 	Queue      *packets.Queue    `json:"packets"`
+	Bus        *Bus              `json:"bus"`
 	StartedAt  time.Time         `json:"started_at"`
 	Active     bool              `json:"active"`
 	GPS        GPS               `json:"gps"`
@@ -71,8 +80,17 @@ func (s *Session) MarshalJSON() ([]byte, error) {
 		Lan:        s.Lan,
 		WiFi:       s.WiFi,
 		BLE:        s.BLE,
+		BT:         s.BT,
 		HID:        s.HID,
+		Zigbee:     s.Zigbee,
+		CAN:        s.CAN,
+		SDR:        s.SDR,
+		Topology:   s.Topology,
+		DNS:        s.DNS,
+		AD:         s.AD,
+		Flows:      s.Flows,
 		Queue:      s.Queue,
+		Bus:        s.Bus,
 		StartedAt:  s.StartedAt,
 		Active:     s.Active,
 		GPS:        s.GPS,
@@ -0,0 +1,113 @@
+package session
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/bettercap/bettercap/loot"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/log"
+	"github.com/evilsocket/islazy/str"
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	LootRecipientParam = "loot.recipient"
+	// the passphrase is never kept in the environment, that is saved to disk
+	LootPassphraseEnv = "BETTERCAP_LOOT_PASSPHRASE"
+)
+
+func (s *Session) setLootRecipient(value string) {
+	fileName := str.Trim(value)
+	if fileName != "" {
+		if expanded, err := fs.Expand(fileName); err == nil {
+			fileName = expanded
+		}
+	}
+
+	if err := loot.SetRecipient(fileName); err != nil {
+		s.Events.Log(log.ERROR, "%s: %v", LootRecipientParam, err)
+	} else if fileName != "" {
+		s.Events.Log(log.INFO, "saved artifacts will be encrypted for the recipient of %s", fileName)
+	}
+}
+
+func (s *Session) setupLoot() {
+	if pass := os.Getenv(LootPassphraseEnv); pass != "" {
+		loot.SetPassphrase(pass)
+	}
+
+	recipient := ""
+	if found, v := s.Env.Get(LootRecipientParam); found {
+		recipient = v
+	}
+	s.Env.WithCallback(LootRecipientParam, recipient, s.setLootRecipient)
+}
+
+// the passphrase is never accepted as an argument, that would be saved in the
+// readline history and in the journal.
+func (s *Session) lootPassphraseHandler(args []string, sess *Session) error {
+	pass := ""
+	if s.Input != nil {
+		raw, err := s.Input.ReadPassword("passphrase (empty to disable): ")
+		if err != nil {
+			return err
+		}
+		pass = string(raw)
+	} else if pass = os.Getenv(LootPassphraseEnv); pass == "" {
+		return fmt.Errorf("no interactive session to read the passphrase from and %s is not set", LootPassphraseEnv)
+	}
+
+	loot.SetPassphrase(pass)
+	if pass == "" {
+		fmt.Println("loot passphrase removed.")
+	} else {
+		fmt.Println("loot passphrase set.")
+	}
+	return nil
+}
+
+func (s *Session) lootDecryptHandler(args []string, sess *Session) error {
+	var key *rsa.PrivateKey
+
+	fileName, err := fs.Expand(str.Trim(args[0]))
+	if err != nil {
+		return err
+	}
+
+	if keyFile := str.Trim(args[1]); keyFile != "" {
+		if keyFile, err = fs.Expand(keyFile); err != nil {
+			return err
+		} else if key, err = loot.LoadPrivateKey(keyFile); err != nil {
+			return err
+		}
+	}
+
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return err
+	} else if !loot.IsEncrypted(raw) {
+		return fmt.Errorf("%s is not encrypted", fileName)
+	}
+
+	plain, err := loot.Decrypt(raw, loot.Passphrase(), key)
+	if err != nil {
+		return err
+	}
+
+	outName := strings.TrimSuffix(fileName, loot.Extension)
+	if outName == fileName {
+		outName += ".dec"
+	}
+
+	if err = ioutil.WriteFile(outName, plain, 0600); err != nil {
+		return err
+	}
+
+	fmt.Printf("%d bytes decrypted to %s\n", len(plain), tui.Bold(outName))
+	return nil
+}
@@ -0,0 +1,164 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/evilsocket/islazy/log"
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	OutputFormatParam = "output.format"
+	OutputText        = "text"
+	OutputJSON        = "json"
+)
+
+var (
+	reANSI    = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+	reKeyChar = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// outputState keeps the format of the tables printed by the show commands
+// and the buffers of whoever is capturing them.
+type outputState struct {
+	sync.Mutex
+	json     bool
+	out      io.Writer
+	captures []*[]json.RawMessage
+}
+
+func (s *Session) setOutputFormat(value string) {
+	switch value {
+	case OutputText, OutputJSON:
+		s.output.Lock()
+		s.output.json = value == OutputJSON
+		s.output.Unlock()
+	default:
+		s.Events.Log(log.ERROR, "%s: unknown format '%s', use %s or %s", OutputFormatParam, value, OutputText, OutputJSON)
+	}
+}
+
+// IsJSONOutput returns true if output.format is json.
+func (s *Session) IsJSONOutput() bool {
+	s.output.Lock()
+	defer s.output.Unlock()
+	return s.output.json
+}
+
+func (s *Session) writer() io.Writer {
+	if s.output.out != nil {
+		return s.output.out
+	}
+	return os.Stdout
+}
+
+// columnKey turns the header of a column in the key of its JSON values.
+func columnKey(column string) string {
+	key := reKeyChar.ReplaceAllString(strings.ToLower(reANSI.ReplaceAllString(column, "")), "_")
+	return strings.Trim(key, "_")
+}
+
+// tableJSON returns the rows as a JSON array of objects keyed by column, with
+// their colors removed.
+func tableJSON(columns []string, rows [][]string) json.RawMessage {
+	keys := make([]string, len(columns))
+	for i, column := range columns {
+		keys[i] = columnKey(column)
+	}
+
+	objects := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		obj := make(map[string]string, len(keys))
+		for i, value := range row {
+			if i < len(keys) {
+				obj[keys[i]] = strings.TrimSpace(reANSI.ReplaceAllString(value, ""))
+			}
+		}
+		objects = append(objects, obj)
+	}
+
+	raw, _ := json.Marshal(objects)
+	return raw
+}
+
+func (s *Session) emitJSON(raw json.RawMessage) {
+	for _, capture := range s.output.captures {
+		*capture = append(*capture, raw)
+	}
+	fmt.Fprintf(s.writer(), "%s\n", raw)
+}
+
+// Table prints the rows of a show command, as a JSON array on a single line
+// if output.format is json, with the same columns as the table otherwise.
+func (s *Session) Table(columns []string, rows [][]string) {
+	s.output.Lock()
+	defer s.output.Unlock()
+
+	if s.output.json {
+		s.emitJSON(tableJSON(columns, rows))
+	} else {
+		tui.Table(s.writer(), columns, rows)
+	}
+}
+
+// ShowJSON prints v on a single line and returns true if output.format is
+// json, for the show commands that don't print a table.
+func (s *Session) ShowJSON(v interface{}) bool {
+	s.output.Lock()
+	defer s.output.Unlock()
+
+	if !s.output.json {
+		return false
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		raw, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	s.emitJSON(raw)
+	return true
+}
+
+// CaptureTables runs cb and returns the JSON documents printed meanwhile by
+// Table and ShowJSON, including the ones printed by other goroutines.
+func (s *Session) CaptureTables(cb func() error) ([]json.RawMessage, error) {
+	tables := make([]json.RawMessage, 0)
+
+	s.output.Lock()
+	s.output.captures = append(s.output.captures, &tables)
+	s.output.Unlock()
+
+	err := cb()
+
+	s.output.Lock()
+	for i, capture := range s.output.captures {
+		if capture == &tables {
+			s.output.captures = append(s.output.captures[:i], s.output.captures[i+1:]...)
+			break
+		}
+	}
+	s.output.Unlock()
+
+	return tables, err
+}
+
+// Printf prints the decorations of the show commands, like titles and
+// footers, which are omitted if output.format is json.
+func (s *Session) Printf(format string, args ...interface{}) {
+	if !s.IsJSONOutput() {
+		fmt.Fprintf(s.writer(), format, args...)
+	}
+}
+
+// Println is like Printf for a single line.
+func (s *Session) Println(args ...interface{}) {
+	if !s.IsJSONOutput() {
+		fmt.Fprintln(s.writer(), args...)
+	}
+}
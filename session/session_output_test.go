@@ -0,0 +1,80 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+func TestColumnKey(t *testing.T) {
+	for column, expected := range map[string]string{
+		"IP Address":    "ip_address",
+		"Expires In":    "expires_in",
+		"RSSI":          "rssi",
+		tui.Bold("MAC"): "mac",
+		"Sent/Recvd":    "sent_recvd",
+	} {
+		if got := columnKey(column); got != expected {
+			t.Fatalf("expected '%s' for '%s', got '%s'", expected, column, got)
+		}
+	}
+}
+
+func TestTableJSON(t *testing.T) {
+	s := auditSession(t)
+	out := bytes.NewBuffer(nil)
+	s.output.out = out
+
+	s.setOutputFormat(OutputJSON)
+	s.Printf("decorations are omitted\n")
+
+	tables, err := s.CaptureTables(func() error {
+		s.Table([]string{"IP Address", "Name"}, [][]string{
+			{tui.Green("192.168.1.1"), "gateway"},
+			{"192.168.1.2", ""},
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(tables) != 1 {
+		t.Fatalf("expected 1 captured table, got %d", len(tables))
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected a single line of output, got %q", out.String())
+	}
+
+	var rows []map[string]string
+	if err := json.Unmarshal([]byte(lines[0]), &rows); err != nil {
+		t.Fatal(err)
+	} else if len(rows) != 2 || rows[0]["ip_address"] != "192.168.1.1" || rows[0]["name"] != "gateway" {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+}
+
+func TestTableText(t *testing.T) {
+	s := auditSession(t)
+	out := bytes.NewBuffer(nil)
+	s.output.out = out
+
+	s.setOutputFormat(OutputText)
+	tables, _ := s.CaptureTables(func() error {
+		s.Table([]string{"Name"}, [][]string{{"gateway"}})
+		return nil
+	})
+
+	if len(tables) != 0 {
+		t.Fatalf("expected no captured tables, got %d", len(tables))
+	} else if !strings.Contains(out.String(), "gateway") || strings.HasPrefix(out.String(), "[") {
+		t.Fatalf("unexpected text output %q", out.String())
+	}
+
+	if s.setOutputFormat("xml"); s.IsJSONOutput() {
+		t.Fatal("an unknown format must not change the output")
+	}
+}
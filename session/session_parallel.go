@@ -0,0 +1,46 @@
+package session
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/evilsocket/islazy/log"
+)
+
+// runParallel runs every command in its own goroutine and waits for all of
+// them to complete, the error of each failing branch is reported separately.
+func (s *Session) runParallel(cmds []string, who string) error {
+	var wg sync.WaitGroup
+
+	errs := make([]error, len(cmds))
+	for i, cmd := range cmds {
+		wg.Add(1)
+		go func(i int, cmd string) {
+			defer wg.Done()
+			errs[i] = s.RunAs(cmd, who)
+		}(i, cmd)
+	}
+
+	wg.Wait()
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			s.Events.Log(log.ERROR, "parallel command '%s' failed: %v", cmds[i], err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d parallel commands failed", failed, len(cmds))
+	}
+	return nil
+}
+
+func (s *Session) parallelHandler(args []string, sess *Session, who string) error {
+	cmds := ParseCommands(args[0])
+	if len(cmds) == 0 {
+		return fmt.Errorf("no commands to run in parallel")
+	}
+	return s.runParallel(cmds, who)
+}
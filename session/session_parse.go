@@ -14,13 +14,15 @@ func ParseCommands(line string) []string {
 	singleQuoted := false
 	doubleQuoted := false
 	finish := false
+	// commands grouped in { } blocks (see parallel) are not split
+	blocks := 0
 
 	line = strings.Replace(line, `""`, `"<empty>"`, -1)
 	line = strings.Replace(line, `''`, `"<empty>"`, -1)
 	for _, c := range line {
 		switch c {
 		case ';':
-			if !singleQuoted && !doubleQuoted {
+			if !singleQuoted && !doubleQuoted && blocks == 0 {
 				finish = true
 			} else {
 				buf += string(c)
@@ -47,6 +49,18 @@ func ParseCommands(line string) []string {
 				singleQuoted = true
 			}
 
+		case '{':
+			if !singleQuoted && !doubleQuoted {
+				blocks++
+			}
+			buf += string(c)
+
+		case '}':
+			if !singleQuoted && !doubleQuoted && blocks > 0 {
+				blocks--
+			}
+			buf += string(c)
+
 		default:
 			buf += string(c)
 		}
@@ -0,0 +1,209 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+var (
+	// ProfilesFile is where the parameter profiles are kept across sessions.
+	ProfilesFile = "~/.bettercap-profiles.json"
+)
+
+// Profile is a named snapshot of parameter values, restored with profile load.
+type Profile map[string]string
+
+func loadProfiles() (map[string]Profile, error) {
+	profiles := make(map[string]Profile)
+
+	fileName, err := fs.Expand(ProfilesFile)
+	if err != nil {
+		return nil, err
+	} else if !fs.Exists(fileName) {
+		return profiles, nil
+	}
+
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	} else if len(raw) > 0 {
+		if err = json.Unmarshal(raw, &profiles); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", fileName, err)
+		}
+	}
+	return profiles, nil
+}
+
+func saveProfiles(profiles map[string]Profile) error {
+	fileName, err := fs.Expand(ProfilesFile)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, raw, 0644)
+}
+
+// SaveProfile snapshots the values of the parameters matching any of the glob
+// patterns, or of every parameter if none is given, as the profile name.
+func (s *Session) SaveProfile(name string, patterns []string) (Profile, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"*"}
+	}
+
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %v", pattern, err)
+		}
+	}
+
+	profile := make(Profile)
+	s.Env.Lock()
+	for key, value := range s.Env.Data {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, key); matched {
+				profile[key] = value
+				break
+			}
+		}
+	}
+	s.Env.Unlock()
+
+	if len(profile) == 0 {
+		return nil, fmt.Errorf("no parameter matches %s", strings.Join(patterns, " "))
+	}
+
+	profiles, err := loadProfiles()
+	if err != nil {
+		return nil, err
+	}
+	profiles[name] = profile
+
+	return profile, saveProfiles(profiles)
+}
+
+// LoadProfile sets the parameters saved in the profile name on behalf of who,
+// the values that don't validate anymore are skipped and reported.
+func (s *Session) LoadProfile(name string, who string) (Profile, error) {
+	profiles, err := loadProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	profile, found := profiles[name]
+	if !found {
+		return nil, fmt.Errorf("profile %s not found", name)
+	}
+
+	keys := make([]string, 0, len(profile))
+	for key := range profile {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	failed := make([]string, 0)
+	for _, key := range keys {
+		if err := s.SetParam(key, profile[key], who); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+
+	if len(failed) > 0 {
+		return profile, fmt.Errorf("%d of %d parameters of profile %s not set: %s", len(failed), len(profile), name, strings.Join(failed, ", "))
+	}
+	return profile, nil
+}
+
+// DeleteProfile removes the profile name.
+func (s *Session) DeleteProfile(name string) error {
+	profiles, err := loadProfiles()
+	if err != nil {
+		return err
+	} else if _, found := profiles[name]; !found {
+		return fmt.Errorf("profile %s not found", name)
+	}
+
+	delete(profiles, name)
+	return saveProfiles(profiles)
+}
+
+func profileNames() []string {
+	names := make([]string, 0)
+	if profiles, err := loadProfiles(); err == nil {
+		for name := range profiles {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Session) profileHandler(args []string, sess *Session, who string) error {
+	action, name := args[0], args[1]
+	patterns := strings.Fields(args[2])
+
+	if action != "save" && len(patterns) > 0 {
+		return fmt.Errorf("parameter patterns are only accepted by profile save")
+	}
+
+	switch action {
+	case "save":
+		profile, err := s.SaveProfile(name, patterns)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d parameters saved to profile %s\n", len(profile), tui.Bold(name))
+
+	case "load":
+		profile, err := s.LoadProfile(name, who)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d parameters loaded from profile %s\n", len(profile), tui.Bold(name))
+
+	case "delete":
+		return s.DeleteProfile(name)
+	}
+
+	return nil
+}
+
+func (s *Session) profilesHandler(args []string, sess *Session) error {
+	profiles, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rows := make([][]string, 0)
+	for _, name := range names {
+		keys := make([]string, 0, len(profiles[name]))
+		for key := range profiles[name] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		rows = append(rows, []string{tui.Bold(name), fmt.Sprintf("%d", len(keys)), strings.Join(keys, ", ")})
+	}
+
+	if len(rows) > 0 {
+		s.Table([]string{"Profile", "Count", "Parameters"}, rows)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func profilesSession(t *testing.T) (*Session, func()) {
+	dir, err := ioutil.TempDir("", "bettercap-profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := ProfilesFile
+	ProfilesFile = filepath.Join(dir, "profiles.json")
+
+	return auditSession(t), func() {
+		ProfilesFile = prev
+		os.RemoveAll(dir)
+	}
+}
+
+func TestProfileSaveLoad(t *testing.T) {
+	s, cleanup := profilesSession(t)
+	defer cleanup()
+
+	s.Env.Set("wifi.deauth.open", "true")
+	s.Env.Set("wifi.assoc.max", "10")
+	s.Env.Set("arp.spoof.targets", "192.168.1.10")
+
+	if err := s.Run("profile save aggressive wifi.*"); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Env.Set("wifi.deauth.open", "false")
+	s.Env.Set("wifi.assoc.max", "1")
+	s.Env.Set("arp.spoof.targets", "192.168.1.20")
+
+	if err := s.Run("profile load aggressive"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"wifi.deauth.open":  "true",
+		"wifi.assoc.max":    "10",
+		"arp.spoof.targets": "192.168.1.20",
+	}
+	for name, value := range expected {
+		if _, got := s.Env.Get(name); got != value {
+			t.Errorf("expected %s to be '%s', got '%s'", name, value, got)
+		}
+	}
+
+	if names := profileNames(); len(names) != 1 || names[0] != "aggressive" {
+		t.Errorf("unexpected profiles %v", names)
+	}
+}
+
+func TestProfileErrors(t *testing.T) {
+	s, cleanup := profilesSession(t)
+	defer cleanup()
+
+	if err := s.Run("profile load missing"); err == nil {
+		t.Error("expected an error loading a missing profile")
+	} else if err := s.Run("profile save empty nothing.*"); err == nil {
+		t.Error("expected an error saving a profile matching no parameter")
+	} else if err := s.Run("profile load missing wifi.*"); err == nil {
+		t.Error("expected an error for patterns given to profile load")
+	}
+
+	s.Env.Set("wifi.assoc.max", "10")
+	if err := s.Run("profile save conservative wifi.*"); err != nil {
+		t.Fatal(err)
+	} else if err := s.Run("profile delete conservative"); err != nil {
+		t.Fatal(err)
+	} else if names := profileNames(); len(names) != 0 {
+		t.Errorf("unexpected profiles %v", names)
+	}
+}
@@ -0,0 +1,60 @@
+package session
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/log"
+)
+
+const (
+	RDNSServerParam   = "net.rdns.server"
+	RDNSCacheParam    = "net.rdns.cache"
+	RDNSNegativeParam = "net.rdns.cache.negative"
+	RDNSRateParam     = "net.rdns.rate"
+)
+
+// onRDNSNumber returns a callback parsing the positive number of a reverse
+// lookups parameter, or logging why it can't.
+func (s *Session) onRDNSNumber(name string, cb func(n int)) EnvironmentChangedCallback {
+	return func(newValue string) {
+		if n, err := strconv.Atoi(newValue); err != nil || n < 0 {
+			s.Events.Log(log.ERROR, "%s: '%s' is not a positive number", name, newValue)
+		} else {
+			cb(n)
+		}
+	}
+}
+
+// setupRDNS configures the reverse lookups of the names of the new hosts,
+// which can go to a specific server instead of the system resolver, or be
+// disabled so that no query leaks from the interface.
+func (s *Session) setupRDNS() {
+	values := map[string]string{
+		RDNSServerParam:   "",
+		RDNSCacheParam:    "3600",
+		RDNSNegativeParam: "300",
+		RDNSRateParam:     "10",
+	}
+	// keep the values set by the env file
+	for name := range values {
+		if found, v := s.Env.Get(name); found {
+			values[name] = v
+		}
+	}
+
+	s.Env.WithCallback(RDNSServerParam, values[RDNSServerParam], func(newValue string) {
+		if err := network.ReverseDNS.SetServer(newValue); err != nil {
+			s.Events.Log(log.ERROR, "%s: %v", RDNSServerParam, err)
+		}
+	})
+	s.Env.WithCallback(RDNSCacheParam, values[RDNSCacheParam], s.onRDNSNumber(RDNSCacheParam, func(secs int) {
+		network.ReverseDNS.SetTTL(time.Duration(secs) * time.Second)
+	}))
+	s.Env.WithCallback(RDNSNegativeParam, values[RDNSNegativeParam], s.onRDNSNumber(RDNSNegativeParam, func(secs int) {
+		network.ReverseDNS.SetNegativeTTL(time.Duration(secs) * time.Second)
+	}))
+	s.Env.WithCallback(RDNSRateParam, values[RDNSRateParam], s.onRDNSNumber(RDNSRateParam, network.ReverseDNS.SetRate))
+}
@@ -0,0 +1,200 @@
+package session
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/bettercap/bettercap/network"
+	"github.com/bettercap/bettercap/packets"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/evilsocket/islazy/log"
+)
+
+const (
+	ScopeCIDRsParam = "scope.cidrs"
+	ScopeSSIDsParam = "scope.ssids"
+	ScopeMACsParam  = "scope.macs"
+)
+
+// outOfScopeLog makes sure every blocked target is only reported once.
+type outOfScopeLog struct {
+	sync.Mutex
+	reported map[string]bool
+}
+
+func (s *Session) reportOutOfScope(what string, format string, args ...interface{}) {
+	s.outOfScope.Lock()
+	if s.outOfScope.reported == nil {
+		s.outOfScope.reported = make(map[string]bool)
+	}
+	reported := s.outOfScope.reported[what]
+	s.outOfScope.reported[what] = true
+	s.outOfScope.Unlock()
+
+	if reported {
+		s.Events.Log(log.DEBUG, "blocked out of scope %s: %s", what, fmt.Sprintf(format, args...))
+	} else {
+		s.Events.Log(log.WARNING, "%s is out of scope, blocking: %s", what, fmt.Sprintf(format, args...))
+	}
+}
+
+func (s *Session) onScopeChanged(name string, err error) {
+	if err != nil {
+		s.Events.Log(log.ERROR, "%s: %v", name, err)
+	}
+
+	s.outOfScope.Lock()
+	s.outOfScope.reported = nil
+	s.outOfScope.Unlock()
+
+	if s.Queue != nil {
+		if s.Scope.Restricted() {
+			s.Queue.SetFilter(func(raw []byte) bool {
+				return s.PacketInScope(raw, layers.LayerTypeEthernet)
+			})
+		} else {
+			s.Queue.SetFilter(nil)
+		}
+	}
+}
+
+func (s *Session) setupScope() {
+	s.Env.WithCallback(ScopeCIDRsParam, "", func(newValue string) {
+		s.onScopeChanged(ScopeCIDRsParam, s.Scope.SetCIDRs(newValue))
+	})
+	s.Env.WithCallback(ScopeSSIDsParam, "", func(newValue string) {
+		s.Scope.SetSSIDs(newValue)
+		s.onScopeChanged(ScopeSSIDsParam, nil)
+	})
+	s.Env.WithCallback(ScopeMACsParam, "", func(newValue string) {
+		s.onScopeChanged(ScopeMACsParam, s.Scope.SetMACs(newValue))
+	})
+}
+
+func (s *Session) isOwnAddress(ip net.IP) bool {
+	return s.Interface != nil && (ip.Equal(s.Interface.IP) || ip.Equal(s.Interface.IPv6))
+}
+
+// IPInScope checks an address against scope.cidrs and reports it if blocked.
+func (s *Session) IPInScope(ip net.IP, what string) bool {
+	if s.Scope.IPAllowed(ip) {
+		return true
+	}
+	s.reportOutOfScope(ip.String(), "%s", what)
+	return false
+}
+
+// MACInScope checks a MAC, BLE or HID address against scope.macs and reports it if blocked.
+func (s *Session) MACInScope(address string, what string) bool {
+	if s.Scope.MACAllowed(address) {
+		return true
+	}
+	s.reportOutOfScope(address, "%s", what)
+	return false
+}
+
+// SSIDInScope checks a network name against scope.ssids and reports it if blocked.
+func (s *Session) SSIDInScope(ssid string, what string) bool {
+	if s.Scope.SSIDAllowed(ssid) {
+		return true
+	}
+	s.reportOutOfScope(fmt.Sprintf("SSID '%s'", ssid), "%s", what)
+	return false
+}
+
+func (s *Session) ipOutOfScope(ip net.IP) string {
+	if ip == nil || ip.IsUnspecified() || ip.IsMulticast() || ip.Equal(net.IPv4bcast) || s.isOwnAddress(ip) {
+		return ""
+	} else if !s.Scope.IPAllowed(ip) {
+		return ip.String()
+	}
+	return ""
+}
+
+func (s *Session) macOutOfScope(mac net.HardwareAddr) string {
+	if len(mac) == 0 || network.IsZeroMac(mac) || mac[0]&0x01 != 0 {
+		return ""
+	} else if s.Interface != nil && mac.String() == s.Interface.HW.String() {
+		return ""
+	} else if !s.Scope.MACAllowed(mac.String()) {
+		return mac.String()
+	}
+	return ""
+}
+
+func (s *Session) ssidOutOfScope(ssid string) string {
+	if !s.Scope.SSIDAllowed(ssid) {
+		return fmt.Sprintf("SSID '%s'", ssid)
+	}
+	return ""
+}
+
+func (s *Session) bssidOutOfScope(bssid net.HardwareAddr) string {
+	if len(bssid) == 0 || bssid[0]&0x01 != 0 {
+		return ""
+	} else if ap, found := s.WiFi.Get(bssid.String()); found {
+		return s.ssidOutOfScope(ap.ESSID())
+	} else if !s.Scope.SSIDAllowed("") {
+		// we can't tell what network an unknown BSSID belongs to
+		return fmt.Sprintf("unknown BSSID %s", bssid)
+	}
+	return ""
+}
+
+func (s *Session) dot11OutOfScope(pkt gopacket.Packet, dot11 *layers.Dot11) string {
+	if what := s.macOutOfScope(dot11.Address1); what != "" {
+		return what
+	}
+
+	// beacons and probes carry the network name, everything else is checked via the BSSID
+	for _, l := range pkt.Layers() {
+		if info, ok := l.(*layers.Dot11InformationElement); ok && info.ID == layers.Dot11InformationElementIDSSID {
+			return s.ssidOutOfScope(string(info.Info))
+		}
+	}
+
+	return s.bssidOutOfScope(dot11.Address3)
+}
+
+// PacketInScope checks the destination addresses (and the network of 802.11 frames)
+// of a packet about to be injected, starting from the given layer, against the scope.
+func (s *Session) PacketInScope(raw []byte, first gopacket.LayerType) bool {
+	if !s.Scope.Restricted() {
+		return true
+	}
+
+	pkt := gopacket.NewPacket(raw, first, gopacket.NoCopy)
+	what := ""
+
+	if layer := pkt.Layer(layers.LayerTypeEthernet); layer != nil {
+		what = s.macOutOfScope(layer.(*layers.Ethernet).DstMAC)
+	}
+
+	if layer := pkt.Layer(layers.LayerTypeARP); what == "" && layer != nil {
+		arp := layer.(*layers.ARP)
+		if what = s.macOutOfScope(arp.DstHwAddress); what == "" {
+			what = s.ipOutOfScope(net.IP(arp.DstProtAddress))
+		}
+	}
+
+	if layer := pkt.Layer(layers.LayerTypeIPv4); what == "" && layer != nil {
+		what = s.ipOutOfScope(layer.(*layers.IPv4).DstIP)
+	} else if layer := pkt.Layer(layers.LayerTypeIPv6); what == "" && layer != nil {
+		what = s.ipOutOfScope(layer.(*layers.IPv6).DstIP)
+	}
+
+	if layer := pkt.Layer(layers.LayerTypeDot11); what == "" && layer != nil {
+		what = s.dot11OutOfScope(pkt, layer.(*layers.Dot11))
+	}
+
+	if what != "" {
+		s.reportOutOfScope(what, "%s", packets.Summarize(raw, first))
+		return false
+	}
+
+	return true
+}
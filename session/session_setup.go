@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/bettercap/bettercap/caplets"
+	"github.com/bettercap/bettercap/network"
 
 	"github.com/bettercap/readline"
 
@@ -16,6 +17,9 @@ import (
 	"github.com/evilsocket/islazy/log"
 )
 
+// the protocols endpoint aliases are learned from, by precedence
+const AliasSourcesParam = "alias.sources"
+
 func containsCapitals(s string) bool {
 	for _, ch := range s {
 		if ch < 133 && ch > 101 {
@@ -98,7 +102,7 @@ func (s *Session) startNetMon() {
 				addr := event.IP.String()
 				mac := event.MAC.String()
 
-				existing := s.Lan.AddIfNew(addr, mac)
+				existing := s.Lan.AddIfNewFrom(addr, mac, network.SourceSniffed)
 				if existing != nil {
 					existing.LastSeen = time.Now()
 				} else {
@@ -106,7 +110,12 @@ func (s *Session) startNetMon() {
 				}
 
 				if existing != nil && event.Meta != nil {
+					fingerprint := existing.Meta.Get("dhcp:fingerprint")
 					existing.OnMeta(event.Meta)
+					s.Lan.LearnAliases(mac, event.Meta)
+					if fp, found := event.Meta["dhcp:fingerprint"]; found && fp != fingerprint {
+						s.Events.Add("endpoint.fingerprint", existing)
+					}
 				}
 			}
 		}
@@ -165,4 +174,51 @@ func (s *Session) setupEnv() {
 		}
 		s.Events.SetSilent(newSilent)
 	})
+
+	s.Env.WithCallback(DryRunParam, "false", func(newValue string) {
+		s.setDryRun(newValue == "true")
+	})
+
+	s.Env.WithCallback(EventsRetentionParam, "", func(newValue string) {
+		if limits, err := ParseEventsRetention(newValue); err != nil {
+			s.Events.Log(log.ERROR, "%s: %v", EventsRetentionParam, err)
+		} else {
+			s.Events.SetRetention(limits)
+		}
+	})
+
+	// keep the databases set by the env file
+	geoDB := ""
+	if found, v := s.Env.Get(GeoIPParam); found {
+		geoDB = v
+	}
+	s.Env.WithCallback(GeoIPParam, geoDB, s.loadGeoIP)
+
+	aliasSources := strings.Join(network.AliasSources, ",")
+	if found, v := s.Env.Get(AliasSourcesParam); found {
+		aliasSources = v
+	}
+	s.Env.WithCallback(AliasSourcesParam, aliasSources, func(newValue string) {
+		if sources, err := network.ParseAliasSources(newValue); err != nil {
+			s.Events.Log(log.ERROR, "%s: %v", AliasSourcesParam, err)
+		} else {
+			s.Lan.SetAliasSources(sources)
+		}
+	})
+
+	ttlNormalize := "false"
+	if found, v := s.Env.Get(TTLNormalizeParam); found {
+		ttlNormalize = v
+	}
+	s.Env.WithCallback(TTLNormalizeParam, ttlNormalize, s.setTTLNormalization)
+
+	outputFormat := OutputText
+	if found, v := s.Env.Get(OutputFormatParam); found {
+		outputFormat = v
+	}
+	s.Env.WithCallback(OutputFormatParam, outputFormat, s.setOutputFormat)
+
+	s.setupLoot()
+	s.setupScope()
+	s.setupWatchdog()
 }
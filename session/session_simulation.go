@@ -0,0 +1,375 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bettercap/bettercap/caplets"
+	"github.com/bettercap/bettercap/network"
+
+	"github.com/evilsocket/islazy/data"
+	"github.com/evilsocket/islazy/str"
+)
+
+const (
+	CapletError   = "error"
+	CapletWarning = "warning"
+
+	maxSimulatedDepth = 16
+)
+
+var (
+	reSimulatedMAC = regexp.MustCompile(`(?i)\b([a-f0-9]{2}:){5}[a-f0-9]{2}\b`)
+	reSimulatedSet = regexp.MustCompile(`^set\s+([^\s]+)\s+(.+)`)
+)
+
+// SimulatedHost is a host of the network of a simulated session.
+type SimulatedHost struct {
+	IP    string `json:"ip"`
+	MAC   string `json:"mac"`
+	Alias string `json:"alias"`
+}
+
+// SimulatedInterface is the interface of a simulated session.
+type SimulatedInterface struct {
+	Name string `json:"name"`
+	CIDR string `json:"cidr"`
+	MAC  string `json:"mac"`
+}
+
+// SimulatedAP is an access point, and its clients, seen by a simulated session.
+type SimulatedAP struct {
+	BSSID   string   `json:"bssid"`
+	ESSID   string   `json:"essid"`
+	Clients []string `json:"clients"`
+}
+
+// SimulationFixture describes the network caplets are simulated against.
+type SimulationFixture struct {
+	Interface SimulatedInterface `json:"interface"`
+	Gateway   SimulatedHost      `json:"gateway"`
+	Env       map[string]string  `json:"env"`
+	Hosts     []SimulatedHost    `json:"hosts"`
+	APs       []SimulatedAP      `json:"aps"`
+}
+
+// DefaultSimulationFixture is the network used if no fixture file is given.
+func DefaultSimulationFixture() *SimulationFixture {
+	return &SimulationFixture{
+		Interface: SimulatedInterface{
+			Name: "eth0",
+			CIDR: "192.168.1.100/24",
+			MAC:  "de:ad:be:ef:00:01",
+		},
+		Gateway: SimulatedHost{
+			IP:  "192.168.1.1",
+			MAC: "de:ad:be:ef:00:fe",
+		},
+		Env:   make(map[string]string),
+		Hosts: make([]SimulatedHost, 0),
+		APs:   make([]SimulatedAP, 0),
+	}
+}
+
+// LoadSimulationFixture loads a JSON fixture, the fields it doesn't set keep
+// the values of the default one.
+func LoadSimulationFixture(fileName string) (*SimulationFixture, error) {
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	fixture := DefaultSimulationFixture()
+	if err = json.Unmarshal(raw, fixture); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", fileName, err)
+	}
+	return fixture, nil
+}
+
+// CapletIssue is a problem found while simulating a caplet.
+type CapletIssue struct {
+	Caplet  string `json:"caplet"`
+	Command string `json:"command"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// CapletSimulation is the result of running a caplet against a simulated
+// session, where the commands are parsed and the variables set but no module
+// command is executed.
+type CapletSimulation struct {
+	Caplet   string        `json:"caplet"`
+	Caplets  []string      `json:"caplets"`
+	Commands int           `json:"commands"`
+	Modules  []string      `json:"modules"`
+	Issues   []CapletIssue `json:"issues"`
+
+	fixture *SimulationFixture
+	strict  bool
+	known   map[string]bool
+	aliases *data.UnsortedKV
+	caplets map[string]bool
+	modules map[string]bool
+	stack   []string
+}
+
+func newCapletSimulation(name string, fixture *SimulationFixture, strict bool) *CapletSimulation {
+	aliases, _ := data.NewMemUnsortedKV()
+	sim := &CapletSimulation{
+		Caplet:  name,
+		Issues:  make([]CapletIssue, 0),
+		fixture: fixture,
+		strict:  strict,
+		known:   make(map[string]bool),
+		aliases: aliases,
+		caplets: make(map[string]bool),
+		modules: make(map[string]bool),
+		stack:   make([]string, 0),
+	}
+
+	sim.addKnown(fixture.Interface.MAC)
+	sim.addKnown(fixture.Gateway.MAC)
+	for _, h := range fixture.Hosts {
+		sim.addKnown(h.MAC)
+		if h.Alias != "" {
+			aliases.Set(network.NormalizeMac(h.MAC), h.Alias)
+		}
+	}
+	for _, ap := range fixture.APs {
+		sim.addKnown(ap.BSSID)
+		for _, client := range ap.Clients {
+			sim.addKnown(client)
+		}
+	}
+
+	return sim
+}
+
+func (sim *CapletSimulation) addKnown(mac string) {
+	if mac != "" {
+		sim.known[network.NormalizeMac(mac)] = true
+	}
+}
+
+func (sim *CapletSimulation) current() string {
+	if len(sim.stack) > 0 {
+		return sim.stack[len(sim.stack)-1]
+	}
+	return sim.Caplet
+}
+
+func (sim *CapletSimulation) issue(level string, command string, format string, args ...interface{}) {
+	sim.Issues = append(sim.Issues, CapletIssue{
+		Caplet:  sim.current(),
+		Command: command,
+		Level:   level,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Errors returns the number of issues that would make the caplet fail.
+func (sim *CapletSimulation) Errors() int {
+	n := 0
+	for _, i := range sim.Issues {
+		if i.Level == CapletError {
+			n++
+		}
+	}
+	return n
+}
+
+// coreCommand returns what a core command does in the simulation: the ones
+// changing variables and including caplets are executed, the others are
+// only parsed.
+func (sim *CapletSimulation) coreCommand(s *Session, h *CommandHandler, args []string, who string) func() error {
+	switch strings.Fields(h.Name)[0] {
+	case "set", "var", "include", "parallel":
+		return func() error {
+			return h.ExecAs(args, s, who)
+		}
+	case "alias":
+		return func() error {
+			sim.aliases.Set(network.NormalizeMac(args[0]), str.Trim(args[1]))
+			sim.addKnown(args[0])
+			return nil
+		}
+	}
+	return func() error {
+		return nil
+	}
+}
+
+// moduleCommand returns what a module command does in the simulation.
+func (sim *CapletSimulation) moduleCommand(m Module) func() error {
+	return func() error {
+		sim.modules[m.Name()] = true
+		return nil
+	}
+}
+
+// checkLine looks for the mistakes that are only found at runtime, like the
+// typos in the names of the parameters and the unknown hosts.
+func (sim *CapletSimulation) checkLine(s *Session, line string) {
+	expanded, err := s.parseEnvTokens(line)
+	if err != nil {
+		// reported when the command is resolved
+		return
+	}
+
+	if sim.strict {
+		for _, mac := range reSimulatedMAC.FindAllString(expanded, -1) {
+			if !sim.known[network.NormalizeMac(mac)] {
+				sim.issue(CapletWarning, line, "%s is not a host or access point of the fixture", mac)
+			}
+		}
+	}
+
+	m := reSimulatedSet.FindStringSubmatch(str.Trim(expanded))
+	if m == nil {
+		return
+	}
+
+	name, value := m[1], str.Trim(m[2])
+	owner := ""
+	for _, mod := range s.Modules {
+		if _, found := mod.Parameters()[name]; found {
+			if strings.HasSuffix(name, ".targets") {
+				sim.checkTargets(s, line, value)
+			}
+			return
+		} else if strings.HasPrefix(name, mod.Name()+".") && len(mod.Name()) > len(owner) {
+			owner = mod.Name()
+		}
+	}
+
+	if owner != "" {
+		sim.issue(CapletWarning, line, "%s is not a parameter of the %s module", name, owner)
+	}
+}
+
+// checkTargets makes sure the addresses and aliases of a list of targets can
+// be resolved, the aliases can be the ones of a real network unless a fixture
+// is given.
+func (sim *CapletSimulation) checkTargets(s *Session, line string, targets string) {
+	if _, _, err := network.ParseTargets(s.parseComputedTokens(targets), sim.aliases); err != nil {
+		if sim.strict {
+			sim.issue(CapletError, line, "%v", err)
+		} else {
+			sim.issue(CapletWarning, line, "%v", err)
+		}
+	}
+}
+
+func (sim *CapletSimulation) evalLine(s *Session, line string, who string) error {
+	sim.Commands++
+	sim.checkLine(s, line)
+	if err := s.RunAs(line+"\n", who); err != nil {
+		sim.issue(CapletError, line, "%v", err)
+	}
+	// keep going in order to report all the issues
+	return nil
+}
+
+func (sim *CapletSimulation) enter(name string) error {
+	if len(sim.stack) >= maxSimulatedDepth {
+		return fmt.Errorf("more than %d nested caplets, is %s including itself?", maxSimulatedDepth, name)
+	}
+	sim.stack = append(sim.stack, name)
+	sim.caplets[name] = true
+	return nil
+}
+
+func (sim *CapletSimulation) leave() {
+	sim.stack = sim.stack[:len(sim.stack)-1]
+}
+
+func (sim *CapletSimulation) finish() {
+	sim.Caplets = make([]string, 0, len(sim.caplets))
+	for name := range sim.caplets {
+		sim.Caplets = append(sim.Caplets, name)
+	}
+	sort.Strings(sim.Caplets)
+
+	sim.Modules = make([]string, 0, len(sim.modules))
+	for name := range sim.modules {
+		sim.Modules = append(sim.Modules, name)
+	}
+	sort.Strings(sim.Modules)
+}
+
+// newSimulatedSession creates a session with the modules of s, the default
+// values of their parameters and the network of the fixture.
+func (s *Session) newSimulatedSession(sim *CapletSimulation) (*Session, error) {
+	fixture := sim.fixture
+
+	ip, ipNet, err := net.ParseCIDR(fixture.Interface.CIDR)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interface address '%s': %v", fixture.Interface.CIDR, err)
+	}
+	bits, _ := ipNet.Mask.Size()
+
+	env, err := NewEnvironment("")
+	if err != nil {
+		return nil, err
+	}
+
+	sess := &Session{
+		Options:      s.Options,
+		Interface:    network.NewEndpointNoResolve(ip.String(), fixture.Interface.MAC, fixture.Interface.Name, uint32(bits)),
+		Gateway:      network.NewEndpointNoResolve(fixture.Gateway.IP, fixture.Gateway.MAC, "gateway", uint32(bits)),
+		Env:          env,
+		Events:       NewEventPool(false, false),
+		Scope:        network.NewScope(),
+		Modules:      s.Modules,
+		CoreHandlers: make([]CommandHandler, 0),
+		hooks:        newModuleHooks(),
+		simulation:   sim,
+	}
+	sess.registerCoreHandlers()
+
+	for _, m := range s.Modules {
+		for name, p := range m.Parameters() {
+			env.Set(name, p.Value)
+		}
+	}
+	for name, value := range fixture.Env {
+		env.Set(name, value)
+	}
+
+	return sess, nil
+}
+
+// SimulateCaplet runs the caplet against a session simulating the network of
+// the fixture, or the default one if nil, and returns the issues found: the
+// commands that don't parse, the undefined variables, the invalid values of
+// the parameters and, if a fixture is given, the addresses that are not part
+// of it. No module command is executed.
+func (s *Session) SimulateCaplet(name string, fixture *SimulationFixture) (*CapletSimulation, error) {
+	err, caplet := caplets.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	strict := fixture != nil
+	if fixture == nil {
+		fixture = DefaultSimulationFixture()
+	}
+
+	sim := newCapletSimulation(caplet.Name, fixture, strict)
+	sess, err := s.newSimulatedSession(sim)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = sess.evalCaplet(caplet, nil, nil, fmt.Sprintf("caplet %s", caplet.Name)); err != nil {
+		sim.issue(CapletError, caplet.Name, "%v", err)
+	}
+	sim.finish()
+
+	return sim, nil
+}
@@ -0,0 +1,122 @@
+package session
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func simulationSession(t *testing.T) *Session {
+	s := auditSession(t)
+
+	mod := &promptTestModule{NewSessionModule("sim", s)}
+	mod.AddParam(NewIntParameter("sim.value", "1", "A value."))
+	mod.AddParam(NewStringParameter("sim.targets", "", "", "Some targets."))
+	mod.AddHandler(NewModuleHandler("sim on", "", "Start.", func(args []string) error {
+		t.Fatal("module command executed by the simulation")
+		return nil
+	}))
+	s.Register(mod)
+
+	return s
+}
+
+func simulationCaplet(t *testing.T, code string) (string, func()) {
+	dir, err := ioutil.TempDir("", "bettercap-simulation")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileName := filepath.Join(dir, "test.cap")
+	if err = ioutil.WriteFile(fileName, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return fileName, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func simulationIssues(sim *CapletSimulation, level string) []string {
+	issues := make([]string, 0)
+	for _, i := range sim.Issues {
+		if i.Level == level {
+			issues = append(issues, i.Command)
+		}
+	}
+	return issues
+}
+
+func TestSimulateCaplet(t *testing.T) {
+	s := simulationSession(t)
+	fileName, cleanup := simulationCaplet(t, strings.Join([]string{
+		"set sim.value 5",
+		"set sim.valeu 5",
+		"set foo {env.undefined}",
+		"not.a.command",
+		"sim on",
+		"set bar {env.sim.value}",
+	}, "\n"))
+	defer cleanup()
+
+	sim, err := s.SimulateCaplet(fileName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sim.Commands != 6 {
+		t.Errorf("expected 6 commands, got %d", sim.Commands)
+	}
+	if len(sim.Modules) != 1 || sim.Modules[0] != "sim" {
+		t.Errorf("unexpected modules %v", sim.Modules)
+	}
+
+	errors := simulationIssues(sim, CapletError)
+	if len(errors) != 2 || errors[0] != "set foo {env.undefined}" || errors[1] != "not.a.command" {
+		t.Errorf("unexpected errors %v", errors)
+	} else if sim.Errors() != 2 {
+		t.Errorf("expected 2 errors, got %d", sim.Errors())
+	}
+
+	warnings := simulationIssues(sim, CapletWarning)
+	if len(warnings) != 1 || warnings[0] != "set sim.valeu 5" {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+
+	// the variables are only set in the simulated session
+	if found, _ := s.Env.Get("bar"); found {
+		t.Error("the simulation changed the environment of the session")
+	}
+}
+
+func TestSimulateCapletWithFixture(t *testing.T) {
+	s := simulationSession(t)
+	fileName, cleanup := simulationCaplet(t, strings.Join([]string{
+		"set sim.targets 192.168.1.10, de:ad:be:ef:00:02",
+		"set sim.targets de:ad:be:ef:00:03",
+		"set sim.targets router",
+		"set sim.targets nobody",
+	}, "\n"))
+	defer cleanup()
+
+	fixture := DefaultSimulationFixture()
+	fixture.Hosts = append(fixture.Hosts, SimulatedHost{
+		IP:    "192.168.1.2",
+		MAC:   "de:ad:be:ef:00:02",
+		Alias: "router",
+	})
+
+	sim, err := s.SimulateCaplet(fileName, fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if errors := simulationIssues(sim, CapletError); len(errors) != 1 || errors[0] != "set sim.targets nobody" {
+		t.Errorf("unexpected errors %v", errors)
+	}
+	if warnings := simulationIssues(sim, CapletWarning); len(warnings) != 1 || warnings[0] != "set sim.targets de:ad:be:ef:00:03" {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
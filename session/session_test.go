@@ -2,6 +2,9 @@ package session
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -87,4 +90,61 @@ func TestParseCommands(t *testing.T) {
 			t.Fatalf("expected %s got %s", cmd, got)
 		}
 	})
+	t.Run("handles semicolon inside blocks", func(t *testing.T) {
+		cmd := "parallel { syn.scan {env.target}; net.probe on }; net.show"
+		commands := ParseCommands(cmd)
+		if l := len(commands); l != 2 {
+			t.Fatalf("expected 2 commands, got %d", l)
+		}
+		expected := "parallel { syn.scan {env.target}; net.probe on }"
+		if got := commands[0]; got != expected {
+			t.Fatalf("expected %s got %s", expected, got)
+		}
+	})
+}
+
+func TestIncludeWithParams(t *testing.T) {
+	folder, err := ioutil.TempDir("", "caplets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+
+	ioutil.WriteFile(filepath.Join(folder, "base.cap"), []byte("# base\n"+
+		"set target {{target}}\n"+
+		"set hook \"events.show {{module}}\"\n"+
+		"include inner.cap with name='a b' other=1\n"), 0644)
+	ioutil.WriteFile(filepath.Join(folder, "inner.cap"), []byte("set inner {{name}}{{other}}\n"), 0644)
+
+	s := auditSession(t)
+	if err := s.RunAs(fmt.Sprintf("include %s with target=10.0.0.1", filepath.Join(folder, "base.cap")), "tester"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, exp := range map[string]string{
+		"target": "10.0.0.1",
+		"hook":   "\"events.show {{module}}\"",
+		"inner":  "a b1",
+	} {
+		if _, got := s.Env.Get(name); got != exp {
+			t.Fatalf("expected %s to be '%s', got '%s'", name, exp, got)
+		}
+	}
+
+	for _, change := range paramEvents(s) {
+		if change.Who != "tester" {
+			t.Fatalf("unexpected origin %+v", change)
+		}
+	}
+
+	// run as a caplet command, inner.cap is still included relative to it
+	if err := s.Run(filepath.Join(folder, "base.cap")); err != nil {
+		t.Fatal(err)
+	} else if _, got := s.Env.Get("target"); got != "{{target}}" {
+		t.Fatalf("expected target to be '{{target}}', got '%s'", got)
+	}
+
+	if err := s.Run("include base.cap with target"); err == nil {
+		t.Fatal("expected error")
+	}
 }
@@ -0,0 +1,96 @@
+package session
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evilsocket/islazy/log"
+	"github.com/evilsocket/islazy/tui"
+)
+
+var reTimeBox = regexp.MustCompile(`^(.+)\s+for\s+([0-9][0-9a-z\.]*)$`)
+
+// TimeBoxEvent is fired when a module started with '<module> on for <duration>' is stopped.
+type TimeBoxEvent struct {
+	Module   string        `json:"module"`
+	Duration time.Duration `json:"duration"`
+}
+
+type timeBoxes struct {
+	sync.Mutex
+	timers map[string]*time.Timer
+}
+
+// parseTimeBox splits '<command> for <duration>' into the command and the duration.
+func parseTimeBox(line string) (bool, string, time.Duration) {
+	m := reTimeBox.FindStringSubmatch(line)
+	if m == nil {
+		return false, line, 0
+	} else if d, err := time.ParseDuration(m[2]); err != nil || d <= 0 {
+		return false, line, 0
+	} else {
+		return true, m[1], d
+	}
+}
+
+// startsModule returns true for the '<module> on' handlers, the only ones
+// that can be time boxed, the other commands run once.
+func startsModule(handlerName string) bool {
+	return strings.HasSuffix(handlerName, " on")
+}
+
+// timeBox stops the module after the given time, if it doesn't get stopped before.
+func (s *Session) timeBox(m Module, d time.Duration) {
+	if !m.Running() {
+		s.Events.Log(log.WARNING, "%s is not running, ignoring 'for %s'.", m.Name(), d)
+		return
+	}
+
+	s.Events.Log(log.INFO, "%s will be stopped in %s.", tui.Bold(m.Name()), d)
+
+	s.timeBoxes.Lock()
+	defer s.timeBoxes.Unlock()
+
+	if s.timeBoxes.timers == nil {
+		s.timeBoxes.timers = make(map[string]*time.Timer)
+	} else if prev, found := s.timeBoxes.timers[m.Name()]; found {
+		prev.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		s.timeBoxes.Lock()
+		current := s.timeBoxes.timers[m.Name()] == timer
+		if current {
+			delete(s.timeBoxes.timers, m.Name())
+		}
+		s.timeBoxes.Unlock()
+
+		if !current || !m.Running() {
+			return
+		}
+
+		s.Events.Log(log.INFO, "stopping %s after %s.", tui.Bold(m.Name()), d)
+		if err := m.Stop(); err != nil {
+			s.Events.Log(log.ERROR, "error while stopping %s: %v", m.Name(), err)
+		}
+		s.Events.Add("mod.expired", TimeBoxEvent{
+			Module:   m.Name(),
+			Duration: d,
+		})
+	})
+	s.timeBoxes.timers[m.Name()] = timer
+}
+
+// cancelTimeBox is called when a module is stopped so its timer won't stop a later run.
+func (s *Session) cancelTimeBox(name string) {
+	s.timeBoxes.Lock()
+	defer s.timeBoxes.Unlock()
+
+	if timer, found := s.timeBoxes.timers[name]; found {
+		timer.Stop()
+		delete(s.timeBoxes.timers, name)
+	}
+}
@@ -0,0 +1,79 @@
+package session
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseTimeBox(t *testing.T) {
+	tests := []struct {
+		line string
+		box  bool
+		cmd  string
+		d    time.Duration
+	}{
+		{"arp.spoof on for 10m", true, "arp.spoof on", 10 * time.Minute},
+		{"wifi.deauth all for 1h30m", true, "wifi.deauth all", 90 * time.Minute},
+		{"arp.spoof on", false, "arp.spoof on", 0},
+		{"arp.spoof on for ever", false, "arp.spoof on for ever", 0},
+		{"arp.spoof on for 0s", false, "arp.spoof on for 0s", 0},
+	}
+
+	for _, tt := range tests {
+		if box, cmd, d := parseTimeBox(tt.line); box != tt.box || cmd != tt.cmd || d != tt.d {
+			t.Errorf("'%s': unexpected %v '%s' %s", tt.line, box, cmd, d)
+		}
+	}
+}
+
+func TestTimeBoxOnlyStarts(t *testing.T) {
+	s := auditSession(t)
+	s.hooks = newModuleHooks()
+
+	runs := 0
+	mod := &promptTestModule{NewSessionModule("tb", s)}
+	mod.AddHandler(NewModuleHandler("tb on", "", "Start.", func(args []string) error {
+		return mod.SetRunning(true, nil)
+	}))
+	mod.AddHandler(NewModuleHandler("tb.once", "", "One shot.", func(args []string) error {
+		runs++
+		return nil
+	}))
+	s.Register(mod)
+
+	hasTimer := func() bool {
+		s.timeBoxes.Lock()
+		defer s.timeBoxes.Unlock()
+		_, found := s.timeBoxes.timers["tb"]
+		return found
+	}
+
+	if err := s.Run("tb.once for 1h"); err != nil {
+		t.Fatal(err)
+	} else if runs != 1 {
+		t.Fatalf("expected the command to run once, got %d", runs)
+	} else if hasTimer() {
+		t.Fatal("a one shot command must not stop its module")
+	}
+
+	if err := s.Run("tb on for 1h"); err != nil {
+		t.Fatal(err)
+	} else if !hasTimer() {
+		t.Fatal("expected the module to be time boxed")
+	}
+
+	mod.SetRunning(false, nil)
+	if hasTimer() {
+		t.Fatal("expected the time box to be cancelled with the module stopped")
+	}
+}
+
+func TestTimeBoxEventJSON(t *testing.T) {
+	raw, err := json.Marshal(TimeBoxEvent{Module: "arp.spoof", Duration: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	} else if string(raw) != `{"module":"arp.spoof","duration":1000000000}` {
+		t.Fatalf("unexpected JSON %s", raw)
+	}
+}
@@ -0,0 +1,205 @@
+package session
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/bettercap/loot"
+
+	"github.com/evilsocket/islazy/fs"
+	"github.com/evilsocket/islazy/tui"
+)
+
+const (
+	// identical actions of a module within this interval are counted in the
+	// same timeline entry instead of adding a new one
+	timelineMergeInterval = time.Minute
+	// the oldest entries are dropped beyond this size
+	timelineMaxEntries = 10000
+)
+
+// the module lifecycle actions, they're never merged
+const (
+	TimelineStarted = "started"
+	TimelineStopped = "stopped"
+	TimelineCrashed = "crashed"
+)
+
+// TimelineEntry is an action taken by a module, Count is how many times it
+// was repeated between Time and Last.
+type TimelineEntry struct {
+	Time    time.Time `json:"time"`
+	Last    time.Time `json:"last"`
+	Module  string    `json:"module"`
+	Action  string    `json:"action"`
+	Details string    `json:"details"`
+	Count   int       `json:"count"`
+}
+
+type timelineLog struct {
+	sync.Mutex
+	entries []*TimelineEntry
+	// the last entry of every module, action and details to merge into
+	merge map[string]*TimelineEntry
+}
+
+func (s *Session) timelineAdd(module, action, details string, mergeable bool) {
+	s.timeline.Lock()
+	defer s.timeline.Unlock()
+
+	if s.timeline.merge == nil {
+		s.timeline.merge = make(map[string]*TimelineEntry)
+	}
+
+	now := time.Now()
+	key := module + "\x00" + action + "\x00" + details
+	if mergeable {
+		if e, found := s.timeline.merge[key]; found && now.Sub(e.Last) <= timelineMergeInterval {
+			e.Last = now
+			e.Count++
+			return
+		}
+	} else {
+		// don't merge actions across a restart of the module
+		for k := range s.timeline.merge {
+			if strings.HasPrefix(k, module+"\x00") {
+				delete(s.timeline.merge, k)
+			}
+		}
+	}
+
+	e := &TimelineEntry{
+		Time:    now,
+		Last:    now,
+		Module:  module,
+		Action:  action,
+		Details: details,
+		Count:   1,
+	}
+
+	if mergeable {
+		s.timeline.merge[key] = e
+	}
+
+	s.timeline.entries = append(s.timeline.entries, e)
+	if drop := len(s.timeline.entries) - timelineMaxEntries; drop > 0 {
+		for _, old := range s.timeline.entries[:drop] {
+			oldKey := old.Module + "\x00" + old.Action + "\x00" + old.Details
+			if s.timeline.merge[oldKey] == old {
+				delete(s.timeline.merge, oldKey)
+			}
+		}
+		s.timeline.entries = s.timeline.entries[drop:]
+	}
+}
+
+// Meter records an action a module took in the session timeline, nothing is
+// recorded in dry-run mode since no action is actually taken.
+func (s *Session) Meter(module, action, format string, args ...interface{}) {
+	if s.IsDryRun() {
+		return
+	}
+	s.timelineAdd(module, action, fmt.Sprintf(format, args...), true)
+}
+
+// Timeline returns a copy of the timeline entries in chronological order.
+func (s *Session) Timeline() []TimelineEntry {
+	s.timeline.Lock()
+	defer s.timeline.Unlock()
+
+	list := make([]TimelineEntry, 0, len(s.timeline.entries))
+	for _, e := range s.timeline.entries {
+		list = append(list, *e)
+	}
+	return list
+}
+
+func timelineCSV(entries []TimelineEntry) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	w := csv.NewWriter(buf)
+	w.Write([]string{"time", "last", "module", "action", "details", "count"})
+	for _, e := range entries {
+		w.Write([]string{
+			e.Time.Format(time.RFC3339),
+			e.Last.Format(time.RFC3339),
+			e.Module,
+			e.Action,
+			e.Details,
+			fmt.Sprintf("%d", e.Count),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func (s *Session) timelineHandler(args []string, sess *Session) error {
+	entries := s.Timeline()
+
+	if fileName := strings.TrimSpace(args[0]); fileName != "" {
+		var err error
+		if fileName, err = fs.Expand(fileName); err != nil {
+			return err
+		}
+
+		var data []byte
+		if strings.ToLower(filepath.Ext(fileName)) == ".json" {
+			data, err = json.MarshalIndent(entries, "", "  ")
+		} else {
+			data, err = timelineCSV(entries)
+		}
+
+		if err != nil {
+			return err
+		} else if fileName, err = loot.WriteFile(fileName, data, 0644); err != nil {
+			return err
+		}
+
+		fmt.Printf("%d timeline entries saved to %s\n", len(entries), fileName)
+		return nil
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("\nno activity recorded yet.\n\n")
+		return nil
+	}
+
+	rows := make([][]string, 0)
+	for _, e := range entries {
+		action := e.Action
+		switch action {
+		case TimelineStarted:
+			action = tui.Green(action)
+		case TimelineStopped:
+			action = tui.Dim(action)
+		case TimelineCrashed:
+			action = tui.Red(action)
+		default:
+			action = tui.Yellow(action)
+		}
+
+		last := ""
+		if e.Count > 1 {
+			last = e.Last.Format("15:04:05")
+		}
+
+		rows = append(rows, []string{
+			e.Time.Format("2006-01-02 15:04:05"),
+			last,
+			tui.Bold(e.Module),
+			action,
+			e.Details,
+			fmt.Sprintf("%d", e.Count),
+		})
+	}
+
+	s.Println()
+	s.Table([]string{"Time", "Until", "Module", "Action", "Details", "Count"}, rows)
+	s.Println()
+	return nil
+}
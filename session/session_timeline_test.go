@@ -0,0 +1,65 @@
+package session
+
+import (
+	"testing"
+)
+
+func TestTimelineMerge(t *testing.T) {
+	s := newHealthTestSession(t)
+	m := NewSessionModule("test", s)
+
+	m.Meter("deauth", "%s from %s", "aa:bb:cc:dd:ee:ff", "home")
+	m.Meter("deauth", "%s from %s", "aa:bb:cc:dd:ee:ff", "home")
+	m.Meter("deauth", "%s from %s", "11:22:33:44:55:66", "home")
+
+	entries := s.Timeline()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 timeline entries, got %d", len(entries))
+	} else if entries[0].Count != 2 {
+		t.Errorf("expected the repeated action to be counted twice, got %d", entries[0].Count)
+	} else if entries[1].Details != "11:22:33:44:55:66 from home" {
+		t.Errorf("unexpected details '%s'", entries[1].Details)
+	}
+}
+
+func TestTimelineLifecycle(t *testing.T) {
+	s := newHealthTestSession(t)
+	m := NewSessionModule("test", s)
+
+	if err := m.SetRunning(true, nil); err != nil {
+		t.Fatal(err)
+	}
+	m.Meter("spoofed", "example.com")
+	if err := m.SetRunning(false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetRunning(true, nil); err != nil {
+		t.Fatal(err)
+	}
+	m.Meter("spoofed", "example.com")
+
+	expected := []string{TimelineStarted, "spoofed", TimelineStopped, TimelineStarted, "spoofed"}
+	entries := s.Timeline()
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d timeline entries, got %d", len(expected), len(entries))
+	}
+
+	for i, e := range entries {
+		if e.Action != expected[i] {
+			t.Errorf("expected action #%d to be '%s', got '%s'", i, expected[i], e.Action)
+		} else if e.Count != 1 {
+			t.Errorf("expected action #%d to be counted once, got %d", i, e.Count)
+		}
+	}
+}
+
+func TestTimelineDryRun(t *testing.T) {
+	s := newHealthTestSession(t)
+	s.dryRun.stop = make(chan struct{})
+
+	s.Meter("test", "deauth", "aa:bb:cc:dd:ee:ff")
+
+	if entries := s.Timeline(); len(entries) != 0 {
+		t.Errorf("expected no timeline entries in dry-run mode, got %d", len(entries))
+	}
+}
@@ -0,0 +1,19 @@
+package session
+
+import (
+	"github.com/evilsocket/islazy/log"
+)
+
+const TTLNormalizeParam = "net.ttl.normalize"
+
+// setTTLNormalization makes the firewall keep the TTL and hop limit of the
+// packets we forward while in the middle, so that TTL based detection can't
+// spot the extra hop.
+func (s *Session) setTTLNormalization(value string) {
+	enabled := value == "true"
+	if err := s.Firewall.EnableTTLNormalization(enabled); err != nil {
+		s.Events.Log(log.ERROR, "%s: %v", TTLNormalizeParam, err)
+	} else if enabled {
+		s.Events.Log(log.INFO, "TTL and hop limit of the forwarded packets are now preserved")
+	}
+}
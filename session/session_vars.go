@@ -0,0 +1,136 @@
+package session
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/evilsocket/islazy/tui"
+)
+
+var (
+	reComputedVarCapture = regexp.MustCompile(`{[a-z0-9]+\.[a-z0-9\.]+}`)
+
+	// ComputedVariables are evaluated every time they're used inside a parameter
+	// value, so that caplets don't need to know the network they're running on.
+	ComputedVariables = map[string]func(s *Session) string{
+		"{iface.name}": func(s *Session) string {
+			return s.Interface.Name()
+		},
+		"{iface.ip}": func(s *Session) string {
+			return s.Interface.IpAddress
+		},
+		"{iface.ipv6}": func(s *Session) string {
+			return s.Interface.Ip6Address
+		},
+		"{iface.mac}": func(s *Session) string {
+			return s.Interface.HwAddress
+		},
+		"{iface.cidr}": func(s *Session) string {
+			return s.Interface.CIDR()
+		},
+		"{gateway.ip}": func(s *Session) string {
+			return s.Gateway.IpAddress
+		},
+		"{gateway.mac}": func(s *Session) string {
+			return s.Gateway.HwAddress
+		},
+		"{random.mac}": func(s *Session) string {
+			return randomMAC()
+		},
+	}
+)
+
+func randomMAC() string {
+	hw := make([]byte, 6)
+	rand.Read(hw)
+	return net.HardwareAddr(hw).String()
+}
+
+func (s *Session) parseComputedTokens(str string) string {
+	if s.Interface == nil || s.Gateway == nil {
+		return str
+	}
+
+	for _, m := range reComputedVarCapture.FindAllString(str, -1) {
+		if cb, found := ComputedVariables[m]; found {
+			str = strings.Replace(str, m, cb(s), -1)
+		}
+	}
+	return str
+}
+
+// validateVariable checks the value against the type of the variable declared
+// with the var command or, for module parameters, against the parameter rules.
+func (s *Session) validateVariable(name, value string) error {
+	if found, t := s.Env.GetType(name); found {
+		if err, _ := NewModuleParameter(name, "", t, "", "").Validate(value); err != nil {
+			return fmt.Errorf("can't set %s variable %s to '%s': %v", t, tui.Bold(name), value, err)
+		}
+		return nil
+	}
+
+	for _, m := range s.Modules {
+		if p, found := m.Parameters()[name]; found {
+			if err, _ := p.Validate(p.parse(s, value)); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+func (s *Session) varHandler(args []string, sess *Session, who string) error {
+	name := args[0]
+	err, t := ParseParamType(args[1])
+	if err != nil {
+		return err
+	}
+
+	value := args[2]
+	if value == "" {
+		var found bool
+		if found, value = s.Env.Get(name); !found {
+			s.Env.SetType(name, t)
+			return nil
+		}
+	} else if value == "\"\"" || value == "''" {
+		value = ""
+	}
+
+	if err, _ := NewModuleParameter(name, "", t, "", "").Validate(value); err != nil {
+		return fmt.Errorf("can't declare %s variable %s as '%s': %v", t, tui.Bold(name), value, err)
+	}
+
+	s.Env.SetType(name, t)
+	return s.SetParam(name, value, who)
+}
+
+func (s *Session) varsHandler(args []string, sess *Session) error {
+	rows := make([][]string, 0)
+
+	names := make([]string, 0, len(ComputedVariables))
+	for name := range ComputedVariables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rows = append(rows, []string{tui.Bold(name), tui.Dim("computed"), s.parseComputedTokens(name)})
+	}
+
+	for _, name := range s.Env.Sorted() {
+		if found, t := s.Env.GetType(name); found {
+			_, value := s.Env.Get(name)
+			rows = append(rows, []string{tui.Bold(name), tui.Green(t.String()), value})
+		}
+	}
+
+	s.Table([]string{"Name", "Type", "Value"}, rows)
+	return nil
+}
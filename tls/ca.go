@@ -0,0 +1,50 @@
+package tls
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/bettercap/bettercap/log"
+
+	"github.com/evilsocket/islazy/fs"
+)
+
+// the local certification authority, shared by https.proxy and by the modules
+// generating certificates with <prefix>.certificate.ca set to true
+const (
+	DefaultCACertFile = "~/.bettercap-ca.cert.pem"
+	DefaultCAKeyFile  = "~/.bettercap-ca.key.pem"
+)
+
+// LoadCA loads a certification authority key pair.
+func LoadCA(certPath, keyPath string) (error, *tls.Certificate) {
+	ca, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return fmt.Errorf("could not load certification authority from %s: %v", certPath, err), nil
+	}
+	return nil, &ca
+}
+
+// LoadOrCreateCA loads the local certification authority, creating it with the given configuration if needed.
+func LoadOrCreateCA(cfg CertConfig) (error, *tls.Certificate) {
+	certPath, err := fs.Expand(DefaultCACertFile)
+	if err != nil {
+		return err, nil
+	}
+
+	keyPath, err := fs.Expand(DefaultCAKeyFile)
+	if err != nil {
+		return err, nil
+	}
+
+	if !fs.Exists(certPath) || !fs.Exists(keyPath) {
+		log.Info("generating local certification authority to %s", certPath)
+
+		cfg.CA = false
+		if err := Generate(cfg, certPath, keyPath); err != nil {
+			return err, nil
+		}
+	}
+
+	return LoadCA(certPath, keyPath)
+}
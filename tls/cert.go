@@ -1,14 +1,18 @@
 package tls
 
 import (
+	"crypto"
 	"crypto/rand"
-	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"fmt"
 	"math/big"
+	"net"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bettercap/bettercap/session"
@@ -16,6 +20,10 @@ import (
 
 type CertConfig struct {
 	Bits               int
+	KeyType            string
+	Days               int
+	SANs               []string
+	CA                 bool
 	Country            string
 	Locality           string
 	Organization       string
@@ -26,6 +34,8 @@ type CertConfig struct {
 var (
 	DefaultLegitConfig = CertConfig{
 		Bits:               4096,
+		KeyType:            KeyRSA,
+		Days:               365,
 		Country:            "US",
 		Locality:           "",
 		Organization:       "bettercap devteam",
@@ -34,6 +44,8 @@ var (
 	}
 	DefaultSpoofConfig = CertConfig{
 		Bits:               4096,
+		KeyType:            KeyRSA,
+		Days:               365,
 		Country:            "US",
 		Locality:           "Scottsdale",
 		Organization:       "GoDaddy.com, Inc.",
@@ -45,6 +57,12 @@ var (
 func CertConfigToModule(prefix string, m *session.SessionModule, defaults CertConfig) {
 	m.AddParam(session.NewIntParameter(prefix+".certificate.bits", strconv.Itoa(defaults.Bits),
 		"Number of bits of the RSA private key of the generated HTTPS certificate."))
+	m.AddParam(session.NewStringParameter(prefix+".certificate.keytype", defaults.KeyType, "^(rsa|ecdsa|ed25519)$",
+		"Type of the private key of the generated HTTPS certificate, rsa, ecdsa (P-256) or ed25519."))
+	m.AddParam(session.NewIntParameter(prefix+".certificate.days", strconv.Itoa(defaults.Days),
+		"Validity in days of the generated HTTPS certificate."))
+	m.AddParam(session.NewStringParameter(prefix+".certificate.sans", strings.Join(defaults.SANs, ","), "",
+		"Comma separated list of hostnames and IP addresses for the Subject Alternative Name of the generated HTTPS certificate."))
 	m.AddParam(session.NewStringParameter(prefix+".certificate.country", defaults.Country, ".*",
 		"Country field of the generated HTTPS certificate."))
 	m.AddParam(session.NewStringParameter(prefix+".certificate.locality", defaults.Locality, ".*",
@@ -57,9 +75,24 @@ func CertConfigToModule(prefix string, m *session.SessionModule, defaults CertCo
 		"Common Name field of the generated HTTPS certificate."))
 }
 
+// SignedCertConfigToModule adds the parameter to have the generated certificate
+// signed by the local certification authority instead of being self signed.
+func SignedCertConfigToModule(prefix string, m *session.SessionModule) {
+	m.AddParam(session.NewBoolParameter(prefix+".certificate.ca", "false",
+		"If true the generated HTTPS certificate will be signed by the local certification authority in "+DefaultCACertFile+", which is also used by https.proxy and can be installed on the test devices."))
+}
+
 func CertConfigFromModule(prefix string, m session.SessionModule) (err error, cfg CertConfig) {
+	var sans string
+
 	if err, cfg.Bits = m.IntParam(prefix + ".certificate.bits"); err != nil {
 		return err, cfg
+	} else if err, cfg.KeyType = m.StringParam(prefix + ".certificate.keytype"); err != nil {
+		return err, cfg
+	} else if err, cfg.Days = m.IntParam(prefix + ".certificate.days"); err != nil {
+		return err, cfg
+	} else if err, sans = m.StringParam(prefix + ".certificate.sans"); err != nil {
+		return err, cfg
 	} else if err, cfg.Country = m.StringParam(prefix + ".certificate.country"); err != nil {
 		return err, cfg
 	} else if err, cfg.Locality = m.StringParam(prefix + ".certificate.locality"); err != nil {
@@ -71,42 +104,93 @@ func CertConfigFromModule(prefix string, m session.SessionModule) (err error, cf
 	} else if err, cfg.CommonName = m.StringParam(prefix + ".certificate.commonname"); err != nil {
 		return err, cfg
 	}
+
+	if m.Param(prefix+".certificate.ca") != nil {
+		if err, cfg.CA = m.BoolParam(prefix + ".certificate.ca"); err != nil {
+			return err, cfg
+		}
+	}
+
+	if cfg.Days <= 0 {
+		return fmt.Errorf("%s.certificate.days must be greater than 0", prefix), cfg
+	}
+
+	for _, san := range strings.Split(sans, ",") {
+		if san = strings.TrimSpace(san); san != "" {
+			cfg.SANs = append(cfg.SANs, san)
+		}
+	}
+
 	return nil, cfg
 }
 
-func CreateCertificate(cfg CertConfig) (error, *rsa.PrivateKey, []byte) {
-	priv, err := rsa.GenerateKey(rand.Reader, cfg.Bits)
+func newSerialNumber() (*big.Int, error) {
+	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
+	return rand.Int(rand.Reader, serialNumberLimit)
+}
+
+func (cfg CertConfig) subject() pkix.Name {
+	return pkix.Name{
+		Country:            []string{cfg.Country},
+		Locality:           []string{cfg.Locality},
+		Organization:       []string{cfg.Organization},
+		OrganizationalUnit: []string{cfg.OrganizationalUnit},
+		CommonName:         cfg.CommonName,
+	}
+}
+
+func (cfg CertConfig) validity() (time.Time, time.Time) {
+	days := cfg.Days
+	if days <= 0 {
+		days = 365
+	}
+	notBefore := time.Now()
+	return notBefore, notBefore.Add(time.Duration(days*24) * time.Hour)
+}
+
+// addSANs sets the DNS names and IP addresses of the certificate, leaf certificates
+// without an explicit list get their common name so that clients will accept them.
+func (cfg CertConfig) addSANs(template *x509.Certificate, isLeaf bool) {
+	sans := cfg.SANs
+	if len(sans) == 0 && isLeaf && cfg.CommonName != "" {
+		sans = []string{cfg.CommonName}
+	}
+
+	for _, san := range sans {
+		if ip := net.ParseIP(san); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, san)
+		}
+	}
+}
+
+// CreateCertificate creates a self signed certification authority certificate.
+func CreateCertificate(cfg CertConfig) (error, crypto.Signer, []byte) {
+	err, priv := GenerateKey(cfg.KeyType, cfg.Bits)
 	if err != nil {
 		return err, nil, nil
 	}
 
-	notBefore := time.Now()
-	aYear := time.Duration(365*24) * time.Hour
-	notAfter := notBefore.Add(aYear)
-	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
-	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
+	serialNumber, err := newSerialNumber()
 	if err != nil {
 		return err, nil, nil
 	}
 
+	notBefore, notAfter := cfg.validity()
 	template := x509.Certificate{
-		SerialNumber: serialNumber,
-		Subject: pkix.Name{
-			Country:            []string{cfg.Country},
-			Locality:           []string{cfg.Locality},
-			Organization:       []string{cfg.Organization},
-			OrganizationalUnit: []string{cfg.OrganizationalUnit},
-			CommonName:         cfg.CommonName,
-		},
+		SerialNumber:          serialNumber,
+		Subject:               cfg.subject(),
 		NotBefore:             notBefore,
 		NotAfter:              notAfter,
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		KeyUsage:              keyUsage(priv) | x509.KeyUsageCertSign,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
 		BasicConstraintsValid: true,
-		IsCA: true,
+		IsCA:                  true,
 	}
+	cfg.addSANs(&template, false)
 
-	cert, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	cert, err := x509.CreateCertificate(rand.Reader, &template, &template, priv.Public(), priv)
 	if err != nil {
 		return err, nil, nil
 	}
@@ -114,8 +198,54 @@ func CreateCertificate(cfg CertConfig) (error, *rsa.PrivateKey, []byte) {
 	return nil, priv, cert
 }
 
-func Generate(cfg CertConfig, certPath string, keyPath string) error {
-	keyFile, err := os.Create(keyPath)
+// CreateSignedCertificate creates a leaf certificate signed by the given certification authority.
+func CreateSignedCertificate(ca *tls.Certificate, cfg CertConfig) (error, crypto.Signer, []byte) {
+	x509ca, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return err, nil, nil
+	}
+
+	err, priv := GenerateKey(cfg.KeyType, cfg.Bits)
+	if err != nil {
+		return err, nil, nil
+	}
+
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return err, nil, nil
+	}
+
+	notBefore, notAfter := cfg.validity()
+	if notAfter.After(x509ca.NotAfter) {
+		notAfter = x509ca.NotAfter
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               cfg.subject(),
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              keyUsage(priv),
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	cfg.addSANs(&template, true)
+
+	cert, err := x509.CreateCertificate(rand.Reader, &template, x509ca, priv.Public(), ca.PrivateKey)
+	if err != nil {
+		return err, nil, nil
+	}
+
+	return nil, priv, cert
+}
+
+func writeKeyPair(certPath, keyPath string, priv crypto.Signer, chain ...[]byte) error {
+	err, block := EncodeKey(priv)
+	if err != nil {
+		return err
+	}
+
+	keyFile, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
 	}
@@ -127,14 +257,40 @@ func Generate(cfg CertConfig, certPath string, keyPath string) error {
 	}
 	defer certFile.Close()
 
-	err, priv, cert := CreateCertificate(cfg)
-	if err != nil {
+	if err := pem.Encode(keyFile, block); err != nil {
 		return err
 	}
 
-	if err := pem.Encode(keyFile, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)}); err != nil {
+	for _, der := range chain {
+		if err := pem.Encode(certFile, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Generate creates the certificate and key files, either self signed or signed
+// by the local certification authority if cfg.CA is true.
+func Generate(cfg CertConfig, certPath string, keyPath string) error {
+	if cfg.CA {
+		err, ca := LoadOrCreateCA(DefaultSpoofConfig)
+		if err != nil {
+			return err
+		}
+
+		err, priv, cert := CreateSignedCertificate(ca, cfg)
+		if err != nil {
+			return err
+		}
+
+		return writeKeyPair(certPath, keyPath, priv, cert, ca.Certificate[0])
+	}
+
+	err, priv, cert := CreateCertificate(cfg)
+	if err != nil {
 		return err
 	}
 
-	return pem.Encode(certFile, &pem.Block{Type: "CERTIFICATE", Bytes: cert})
+	return writeKeyPair(certPath, keyPath, priv, cert)
 }
@@ -0,0 +1,117 @@
+package tls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/bettercap/bettercap/log"
+)
+
+const fetchTimeout = 10 * time.Second
+
+// FetchCertificate connects to a TLS server and returns its leaf certificate.
+func FetchCertificate(host string, port int) (error, *x509.Certificate) {
+	log.Debug("Fetching TLS certificate from %s:%d ...", host, port)
+
+	config := tls.Config{
+		InsecureSkipVerify: true,
+	}
+	// virtual hosts need the SNI to return the right certificate
+	if net.ParseIP(host) == nil {
+		config.ServerName = host
+	}
+
+	dialer := &net.Dialer{Timeout: fetchTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, strconv.Itoa(port)), &config)
+	if err != nil {
+		return err, nil
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("%s:%d did not send any certificate", host, port), nil
+	}
+
+	return nil, state.PeerCertificates[0]
+}
+
+// cloneKey generates a key of the same type and size of the original one.
+func cloneKey(pub crypto.PublicKey) (error, crypto.Signer) {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		bits := key.N.BitLen()
+		if bits < 2048 {
+			bits = 2048
+		}
+		return GenerateKey(KeyRSA, bits)
+	case *ecdsa.PublicKey:
+		priv, err := ecdsa.GenerateKey(key.Curve, rand.Reader)
+		return err, priv
+	case ed25519.PublicKey:
+		return GenerateKey(KeyEd25519, 0)
+	}
+	return GenerateKey(KeyRSA, 2048)
+}
+
+// CloneCertificate creates a lookalike of the original certificate, with the same
+// subject, serial number, alternative names and validity, signed by the given CA.
+func CloneCertificate(ca *tls.Certificate, orig *x509.Certificate) (error, *tls.Certificate) {
+	x509ca, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return err, nil
+	}
+
+	err, priv := cloneKey(orig.PublicKey)
+	if err != nil {
+		return err, nil
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          orig.SerialNumber,
+		Subject:               orig.Subject,
+		NotBefore:             orig.NotBefore,
+		NotAfter:              orig.NotAfter,
+		KeyUsage:              orig.KeyUsage,
+		ExtKeyUsage:           orig.ExtKeyUsage,
+		DNSNames:              orig.DNSNames,
+		IPAddresses:           orig.IPAddresses,
+		EmailAddresses:        orig.EmailAddresses,
+		URIs:                  orig.URIs,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, x509ca, priv.Public(), ca.PrivateKey)
+	if err != nil {
+		return err, nil
+	}
+
+	return nil, &tls.Certificate{
+		Certificate: [][]byte{der, ca.Certificate[0]},
+		PrivateKey:  priv,
+	}
+}
+
+// CloneToFiles clones the certificate of a live server and saves it with its key.
+func CloneToFiles(ca *tls.Certificate, host string, port int, certPath string, keyPath string) (error, *x509.Certificate) {
+	err, orig := FetchCertificate(host, port)
+	if err != nil {
+		return err, nil
+	}
+
+	err, cert := CloneCertificate(ca, orig)
+	if err != nil {
+		return err, nil
+	}
+
+	return writeKeyPair(certPath, keyPath, cert.PrivateKey.(crypto.Signer), cert.Certificate...), orig
+}
@@ -0,0 +1,64 @@
+package tls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+)
+
+const (
+	KeyRSA     = "rsa"
+	KeyECDSA   = "ecdsa"
+	KeyEd25519 = "ed25519"
+)
+
+// GenerateKey creates a private key of the given type, bits is only used for RSA.
+func GenerateKey(keyType string, bits int) (error, crypto.Signer) {
+	switch keyType {
+	case KeyRSA, "":
+		priv, err := rsa.GenerateKey(rand.Reader, bits)
+		return err, priv
+	case KeyECDSA:
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		return err, priv
+	case KeyEd25519:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return err, priv
+	}
+	return fmt.Errorf("unsupported key type %s", keyType), nil
+}
+
+// EncodeKey returns the PEM block of a private key, RSA keys are still
+// saved as PKCS1 for compatibility with the files generated so far.
+func EncodeKey(priv crypto.Signer) (error, *pem.Block) {
+	switch key := priv.(type) {
+	case *rsa.PrivateKey:
+		return nil, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	case *ecdsa.PrivateKey:
+		raw, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return err, nil
+		}
+		return nil, &pem.Block{Type: "EC PRIVATE KEY", Bytes: raw}
+	}
+
+	raw, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return err, nil
+	}
+	return nil, &pem.Block{Type: "PRIVATE KEY", Bytes: raw}
+}
+
+// only RSA keys can be used for key encipherment
+func keyUsage(priv crypto.Signer) x509.KeyUsage {
+	if _, isRSA := priv.(*rsa.PrivateKey); isRSA {
+		return x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature
+	}
+	return x509.KeyUsageDigitalSignature
+}